@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -30,6 +31,15 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 )
 
+var (
+	// errNoEventSignature is returned if a log to unpack has no topics.
+	errNoEventSignature = errors.New("no event signature")
+
+	// errEventSignatureMismatch is returned if a log to unpack was raised by a
+	// different event.
+	errEventSignatureMismatch = errors.New("event signature mismatch")
+)
+
 // SignerFn is a signer function callback when a contract requires a method to
 // sign the transaction before submission.
 type SignerFn func(types.Signer, common.Address, *types.Transaction) (*types.Transaction, error)
@@ -68,8 +78,9 @@ type FilterOpts struct {
 // WatchOpts is the collection of options to fine tune subscribing for events
 // within a bound contract.
 type WatchOpts struct {
-	Start   *uint64         // Start of the queried range (nil = latest)
-	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
+	Start       *uint64         // Start of the queried range (nil = latest)
+	Resubscribe time.Duration   // Maximum backoff between attempts to reestablish a failed subscription (0 = fail on error)
+	Context     context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 }
 
 // BoundContract is the base wrapper object that reflects a contract on the
@@ -295,6 +306,11 @@ func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]int
 
 // WatchLogs filters subscribes to contract logs for future blocks, returning a
 // subscription object that can be used to tear down the watcher.
+//
+// If resubscription is requested in the options, failures of the subscription
+// (e.g. due to a dropped backend connection) are not reported, rather the logs
+// are retrieved again from the block of the last delivered one as soon as the
+// subscription can be reestablished, skipping the ones already delivered.
 func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
 	// Don't crash on a lazy user
 	if opts == nil {
@@ -317,15 +333,119 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 	if opts.Start != nil {
 		config.FromBlock = new(big.Int).SetUint64(*opts.Start)
 	}
-	sub, err := c.filterer.SubscribeFilterLogs(ensureContext(opts.Context), config, logs)
+	if opts.Resubscribe == 0 {
+		sub, err := c.filterer.SubscribeFilterLogs(ensureContext(opts.Context), config, logs)
+		if err != nil {
+			return nil, nil, err
+		}
+		return logs, sub, nil
+	}
+	// Resubscription requested, track the position of the next log to deliver
+	// and establish the first subscription to report any immediate failure
+	next := new(logPosition)
+	if opts.Start != nil {
+		next.set, next.block = true, *opts.Start
+	}
+	first, err := c.subscribeLogs(ensureContext(opts.Context), ensureContext(opts.Context), config, logs, next)
 	if err != nil {
 		return nil, nil, err
 	}
+	sub := event.Resubscribe(opts.Resubscribe, func(ctx context.Context) (event.Subscription, error) {
+		if first != nil {
+			sub := first
+			first = nil
+			return sub, nil
+		}
+		return c.subscribeLogs(ctx, ensureContext(opts.Context), config, logs, next)
+	})
 	return logs, sub, nil
 }
 
+// logPosition is the position of a log within the chain.
+type logPosition struct {
+	set   bool   // Whether the position is known
+	block uint64 // Number of the block containing the log
+	index uint   // Index of the log within the block
+}
+
+// reached reports whether a log is at or beyond the position.
+func (pos *logPosition) reached(log *types.Log) bool {
+	if !pos.set || log.BlockNumber > pos.block {
+		return true
+	}
+	return log.BlockNumber == pos.block && log.Index >= pos.index
+}
+
+// subscribeLogs establishes a log subscription, first delivering the logs
+// missed since the given position (if known) and then the live ones, skipping
+// any log before the position and advancing it with every delivered log.
+func (c *BoundContract) subscribeLogs(subCtx, filterCtx context.Context, config ethereum.FilterQuery, logs chan types.Log, next *logPosition) (event.Subscription, error) {
+	live := make(chan types.Log, 128)
+	sub, err := c.filterer.SubscribeFilterLogs(subCtx, config, live)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+
+		deliver := func(log types.Log) bool {
+			if log.Removed {
+				// Log reverted by a reorg, make sure its replacements are delivered
+				if !next.reached(&log) {
+					next.block, next.index = log.BlockNumber, log.Index
+				}
+			} else {
+				if !next.reached(&log) {
+					return true
+				}
+				next.set, next.block, next.index = true, log.BlockNumber, log.Index+1
+			}
+			select {
+			case logs <- log:
+				return true
+			case <-quit:
+				return false
+			}
+		}
+		// Retrieve the logs missed while the subscription was down
+		if next.set {
+			query := config
+			query.FromBlock, query.ToBlock = new(big.Int).SetUint64(next.block), nil
+
+			missed, err := c.filterer.FilterLogs(filterCtx, query)
+			if err != nil {
+				return err
+			}
+			for _, log := range missed {
+				if !deliver(log) {
+					return nil
+				}
+			}
+		}
+		// Forward the live logs until the subscription fails
+		for {
+			select {
+			case log := <-live:
+				if !deliver(log) {
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
 // UnpackLog unpacks a retrieved log into the provided output structure.
 func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log) error {
+	if len(log.Topics) == 0 {
+		return errNoEventSignature
+	}
+	if !c.abi.Events[event].Anonymous && log.Topics[0] != c.abi.Events[event].Id() {
+		return errEventSignatureMismatch
+	}
 	if len(log.Data) > 0 {
 		if err := c.abi.Unpack(out, event, log.Data); err != nil {
 			return err
@@ -0,0 +1,151 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testLogSub is a log subscription which can be failed on demand.
+type testLogSub struct {
+	logs chan<- types.Log
+	err  chan error
+}
+
+func (s *testLogSub) Unsubscribe()      {}
+func (s *testLogSub) Err() <-chan error { return s.err }
+
+// testLogFilterer is a contract filterer serving logs from a static history and
+// exposing its subscriptions to inject live logs into.
+type testLogFilterer struct {
+	history []types.Log
+	subs    chan *testLogSub
+	lock    sync.Mutex
+}
+
+func (f *testLogFilterer) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var logs []types.Log
+	for _, log := range f.history {
+		if query.FromBlock == nil || log.BlockNumber >= query.FromBlock.Uint64() {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (f *testLogFilterer) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	sub := &testLogSub{logs: ch, err: make(chan error, 1)}
+	f.subs <- sub
+	return sub, nil
+}
+
+// Tests that resubscribing log watchers retrieve the logs missed while their
+// subscription was down, without delivering any log twice.
+func TestWatchLogsResubscribe(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"anonymous":false,"inputs":[],"name":"Event","type":"event"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	var (
+		topics = []common.Hash{parsed.Events["Event"].Id()}
+		logAt  = func(block uint64, index uint) types.Log {
+			return types.Log{Topics: topics, BlockNumber: block, Index: index}
+		}
+		filterer = &testLogFilterer{subs: make(chan *testLogSub, 4)}
+		contract = NewBoundContract(common.Address{}, parsed, nil, nil, filterer)
+	)
+	logs, sub, err := contract.WatchLogs(&WatchOpts{Resubscribe: 10 * time.Millisecond}, "Event")
+	if err != nil {
+		t.Fatalf("failed to watch logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// expect checks that exactly the logs at the given positions are delivered
+	expect := func(positions ...[2]int) {
+		for _, pos := range positions {
+			select {
+			case log := <-logs:
+				if log.BlockNumber != uint64(pos[0]) || log.Index != uint(pos[1]) {
+					t.Fatalf("log position mismatch: have %d/%d, want %d/%d", log.BlockNumber, log.Index, pos[0], pos[1])
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("log %d/%d not delivered", pos[0], pos[1])
+			}
+		}
+		select {
+		case log := <-logs:
+			t.Fatalf("unexpected log delivered: %d/%d", log.BlockNumber, log.Index)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	first := <-filterer.subs
+	first.logs <- logAt(1, 0)
+	first.logs <- logAt(2, 0)
+	expect([2]int{1, 0}, [2]int{2, 0})
+
+	// Fail the subscription while new logs are being mined
+	filterer.lock.Lock()
+	filterer.history = []types.Log{logAt(1, 0), logAt(2, 0), logAt(2, 1), logAt(3, 0)}
+	filterer.lock.Unlock()
+
+	first.err <- errors.New("connection lost")
+
+	var second *testLogSub
+	select {
+	case second = <-filterer.subs:
+	case <-time.After(time.Second):
+		t.Fatalf("subscription not reestablished")
+	}
+	expect([2]int{2, 1}, [2]int{3, 0})
+
+	// Deliver an already retrieved log and a new one on the live subscription
+	second.logs <- logAt(3, 0)
+	second.logs <- logAt(4, 0)
+	expect([2]int{4, 0})
+}
+
+// Tests that logs raised by other events are not unpacked.
+func TestUnpackLogSignature(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"anonymous":false,"inputs":[],"name":"Event","type":"event"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	contract := NewBoundContract(common.Address{}, parsed, nil, nil, nil)
+
+	if err := contract.UnpackLog(new(struct{}), "Event", types.Log{}); err != errNoEventSignature {
+		t.Errorf("missing signature error mismatch: have %v, want %v", err, errNoEventSignature)
+	}
+	if err := contract.UnpackLog(new(struct{}), "Event", types.Log{Topics: []common.Hash{{0x01}}}); err != errEventSignatureMismatch {
+		t.Errorf("foreign signature error mismatch: have %v, want %v", err, errEventSignatureMismatch)
+	}
+	if err := contract.UnpackLog(new(struct{}), "Event", types.Log{Topics: []common.Hash{parsed.Events["Event"].Id()}}); err != nil {
+		t.Errorf("failed to unpack log: %v", err)
+	}
+}
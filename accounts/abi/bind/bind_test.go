@@ -660,6 +660,14 @@ var bindTests = []struct {
 			if err = sit.Error(); err != nil {
 				t.Fatalf("simple event iteration failed: %v", err)
 			}
+			// Test parsing a raw log into a typed event
+			parsed, err := eventer.ParseSimpleEvent(sit.Event.Raw)
+			if err != nil {
+				t.Fatalf("failed to parse simple event: %v", err)
+			}
+			if parsed.Value.Uint64() != 33 || parsed.Addr != (common.Address{3}) {
+				t.Errorf("parsed simple log content mismatch: have %v, want {33, 0x03}", parsed)
+			}
 			// Test raising and filtering for an event with no data component
 			if _, err := eventer.RaiseNodataEvent(auth, big.NewInt(314), 141, 271); err != nil {
 				t.Fatalf("failed to raise nodata event: %v", err)
@@ -740,6 +748,26 @@ var bindTests = []struct {
 				t.Fatalf("unsubscribed simple event arrived: %v", event)
 			case <-time.After(250 * time.Millisecond):
 			}
+			// Test subscribing with automatic resubscription
+			rsub, err := eventer.WatchSimpleEvent(&bind.WatchOpts{Resubscribe: time.Second}, ch, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to resubscribe to simple events: %v", err)
+			}
+			defer rsub.Unsubscribe()
+
+			if _, err := eventer.RaiseSimpleEvent(auth, common.Address{253}, [32]byte{253}, true, big.NewInt(253)); err != nil {
+				t.Fatalf("failed to raise resubscribed simple event: %v", err)
+			}
+			sim.Commit()
+
+			select {
+			case event := <-ch:
+				if event.Value.Uint64() != 253 {
+					t.Errorf("resubscribed simple log content mismatch: have %v, want 253", event)
+				}
+			case <-time.After(250 * time.Millisecond):
+				t.Fatalf("resubscribed simple event didn't arrive")
+			}
 		`,
 	},
 	{
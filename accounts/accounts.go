@@ -75,17 +75,21 @@ type Wallet interface {
 	// to the wallet's tracked account list.
 	Derive(path DerivationPath, pin bool) (Account, error)
 
-	// SelfDerive sets a base account derivation path from which the wallet attempts
+	// SelfDerive sets the base account derivation paths from which the wallet attempts
 	// to discover non zero accounts and automatically add them to list of tracked
 	// accounts.
 	//
-	// Note, self derivaton will increment the last component of the specified path
+	// Note, self derivaton will increment the last component of the specified paths
 	// opposed to decending into a child path to allow discovering accounts starting
 	// from non zero components.
 	//
+	// Some hardware wallets switched derivation paths through their evolution, so
+	// this method supports providing multiple bases to discover old user accounts
+	// too. Only the last base will be used to derive the next empty account.
+	//
 	// You can disable automatic account discovery by calling SelfDerive with a nil
 	// chain state reader.
-	SelfDerive(base DerivationPath, chain ethereum.ChainStateReader)
+	SelfDerive(bases []DerivationPath, chain ethereum.ChainStateReader)
 
 	// SignHash requests the wallet to sign the given hash.
 	//
@@ -46,6 +46,10 @@ type Key struct {
 	// we only store privkey as pubkey/address can be derived from it
 	// privkey in this struct is always in plaintext
 	PrivateKey *ecdsa.PrivateKey
+	// BIP-39 entropy and derivation path of keys derived from a mnemonic, kept to
+	// be able to export the mnemonic again
+	MnemonicEntropy []byte
+	MnemonicPath    accounts.DerivationPath
 }
 
 type keyStore interface {
@@ -58,17 +62,20 @@ type keyStore interface {
 }
 
 type plainKeyJSON struct {
-	Address    string `json:"address"`
-	PrivateKey string `json:"privatekey"`
-	Id         string `json:"id"`
-	Version    int    `json:"version"`
+	Address      string `json:"address"`
+	PrivateKey   string `json:"privatekey"`
+	Mnemonic     string `json:"mnemonic,omitempty"`
+	MnemonicPath string `json:"mnemonicpath,omitempty"`
+	Id           string `json:"id"`
+	Version      int    `json:"version"`
 }
 
 type encryptedKeyJSONV3 struct {
-	Address string     `json:"address"`
-	Crypto  cryptoJSON `json:"crypto"`
-	Id      string     `json:"id"`
-	Version int        `json:"version"`
+	Address  string        `json:"address"`
+	Crypto   cryptoJSON    `json:"crypto"`
+	Mnemonic *mnemonicJSON `json:"mnemonic,omitempty"`
+	Id       string        `json:"id"`
+	Version  int           `json:"version"`
 }
 
 type encryptedKeyJSONV1 struct {
@@ -91,12 +98,27 @@ type cipherparamsJSON struct {
 	IV string `json:"iv"`
 }
 
+// mnemonicJSON is the mnemonic entropy of a key, encrypted along the private key
+// with the same derived key but its own initialization vector.
+type mnemonicJSON struct {
+	CipherText   string           `json:"ciphertext"`
+	CipherParams cipherparamsJSON `json:"cipherparams"`
+	MAC          string           `json:"mac"`
+	Path         string           `json:"path"`
+}
+
 func (k *Key) MarshalJSON() (j []byte, err error) {
 	jStruct := plainKeyJSON{
-		hex.EncodeToString(k.Address[:]),
-		hex.EncodeToString(crypto.FromECDSA(k.PrivateKey)),
-		k.Id.String(),
-		version,
+		Address:    hex.EncodeToString(k.Address[:]),
+		PrivateKey: hex.EncodeToString(crypto.FromECDSA(k.PrivateKey)),
+		Id:         k.Id.String(),
+		Version:    version,
+	}
+	if k.MnemonicEntropy != nil {
+		if jStruct.Mnemonic, err = entropyToMnemonic(k.MnemonicEntropy); err != nil {
+			return nil, err
+		}
+		jStruct.MnemonicPath = k.MnemonicPath.String()
 	}
 	j, err = json.Marshal(jStruct)
 	return j, err
@@ -124,6 +146,14 @@ func (k *Key) UnmarshalJSON(j []byte) (err error) {
 	k.Address = common.BytesToAddress(addr)
 	k.PrivateKey = privkey
 
+	if keyJSON.Mnemonic != "" {
+		if k.MnemonicEntropy, err = mnemonicToEntropy(keyJSON.Mnemonic); err != nil {
+			return err
+		}
+		if k.MnemonicPath, err = accounts.ParseDerivationPath(keyJSON.MnemonicPath); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
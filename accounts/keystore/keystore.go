@@ -77,8 +77,14 @@ type unlocked struct {
 
 // NewKeyStore creates a keystore for the given directory.
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
+	return NewKeyStoreWithParams(keydir, ScryptParams{N: scryptN, R: StandardScryptR, P: scryptP})
+}
+
+// NewKeyStoreWithParams creates a keystore for the given directory, encrypting
+// new keys with the given scrypt parameters.
+func NewKeyStoreWithParams(keydir string, params ScryptParams) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
-	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP}}
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, params.N, params.R, params.P}}
 	ks.init(keydir)
 	return ks
 }
@@ -417,19 +423,72 @@ func (ks *KeyStore) NewAccount(passphrase string) (accounts.Account, error) {
 	return account, nil
 }
 
+// NewMnemonicAccount generates a new BIP-39 mnemonic with the given entropy
+// strength, derives the key at the default derivation path from it and stores it
+// into the key directory, encrypting it with the passphrase. The mnemonic is
+// returned for the user to back up.
+func (ks *KeyStore) NewMnemonicAccount(bits int, passphrase string) (accounts.Account, string, error) {
+	mnemonic, err := NewMnemonic(bits)
+	if err != nil {
+		return accounts.Account{}, "", err
+	}
+	account, err := ks.ImportMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath, passphrase)
+	if err != nil {
+		return accounts.Account{}, "", err
+	}
+	return account, mnemonic, nil
+}
+
+// ImportMnemonic derives the key at the given path from a BIP-39 mnemonic and its
+// optional password, and stores it into the key directory, encrypting it with the
+// passphrase.
+//
+// Note, the mnemonic password is not stored, it's needed along the mnemonic to
+// restore the account elsewhere.
+func (ks *KeyStore) ImportMnemonic(mnemonic, password string, path accounts.DerivationPath, passphrase string) (accounts.Account, error) {
+	key, err := newKeyFromMnemonic(mnemonic, password, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	if ks.cache.hasAddress(key.Address) {
+		return accounts.Account{}, fmt.Errorf("account already exists")
+	}
+	return ks.importKey(key, passphrase)
+}
+
+// ExportMnemonic returns the BIP-39 mnemonic an account was derived from along
+// with its derivation path. ErrNoMnemonic is returned for accounts not created
+// from a mnemonic.
+func (ks *KeyStore) ExportMnemonic(a accounts.Account, passphrase string) (string, accounts.DerivationPath, error) {
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	if key.MnemonicEntropy == nil {
+		return "", nil, ErrNoMnemonic
+	}
+	mnemonic, err := entropyToMnemonic(key.MnemonicEntropy)
+	if err != nil {
+		return "", nil, err
+	}
+	return mnemonic, key.MnemonicPath, nil
+}
+
 // Export exports as a JSON key, encrypted with newPassphrase.
 func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string) (keyJSON []byte, err error) {
 	_, key, err := ks.getDecryptedKey(a, passphrase)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
+	params := ScryptParams{N: StandardScryptN, R: StandardScryptR, P: StandardScryptP}
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		params = ScryptParams{N: store.scryptN, R: store.scryptR, P: store.scryptP}
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return EncryptKeyWithParams(key, newPassphrase, params)
 }
 
 // Import stores the given encrypted JSON key into the key directory.
@@ -35,7 +35,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -64,13 +66,52 @@ const (
 	// memory and taking approximately 100ms CPU time on a modern processor.
 	LightScryptP = 6
 
-	scryptR     = 8
+	// StandardScryptR is the R parameter (block size) of Scrypt encryption
+	// algorithm, used by both the standard and the light settings.
+	StandardScryptR = 8
+
 	scryptDKLen = 32
 )
 
+// ScryptParams are the cost parameters of the scrypt key derivation function
+// used to encrypt keys. The memory needed to derive a key is 128*N*R bytes.
+type ScryptParams struct {
+	N int // CPU/memory cost parameter, a power of two
+	R int // Block size parameter
+	P int // Parallelization parameter
+}
+
+// Validate checks whether the parameters are accepted by the scrypt algorithm.
+func (params ScryptParams) Validate() error {
+	if params.N <= 1 || params.N&(params.N-1) != 0 {
+		return fmt.Errorf("invalid scrypt N %d: must be a power of two greater than 1", params.N)
+	}
+	if params.R <= 0 || params.P <= 0 {
+		return fmt.Errorf("invalid scrypt R %d or P %d: must be positive", params.R, params.P)
+	}
+	if uint64(params.R)*uint64(params.P) >= 1<<30 || params.R > math.MaxInt32/128/params.P || params.R > math.MaxInt32/256 || params.N > math.MaxInt32/128/params.R {
+		return fmt.Errorf("scrypt parameters N %d, R %d, P %d are too large", params.N, params.R, params.P)
+	}
+	return nil
+}
+
+// Benchmark measures the time it takes to derive a key with the parameters on
+// the local machine.
+func (params ScryptParams) Benchmark() (time.Duration, error) {
+	if err := params.Validate(); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	if _, err := scrypt.Key([]byte("benchmark"), make([]byte, 32), params.N, params.R, params.P, scryptDKLen); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
 type keyStorePassphrase struct {
 	keysDirPath string
 	scryptN     int
+	scryptR     int
 	scryptP     int
 }
 
@@ -93,12 +134,18 @@ func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string)
 
 // StoreKey generates a key, encrypts with 'auth' and stores in the given directory
 func StoreKey(dir, auth string, scryptN, scryptP int) (common.Address, error) {
-	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP}, crand.Reader, auth)
+	return StoreKeyWithParams(dir, auth, ScryptParams{N: scryptN, R: StandardScryptR, P: scryptP})
+}
+
+// StoreKeyWithParams generates a key, encrypts it with 'auth' using the given
+// scrypt parameters and stores it in the given directory.
+func StoreKeyWithParams(dir, auth string, params ScryptParams) (common.Address, error) {
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, params.N, params.R, params.P}, crand.Reader, auth)
 	return a.Address, err
 }
 
 func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	keyjson, err := EncryptKeyWithParams(key, auth, ScryptParams{N: ks.scryptN, R: ks.scryptR, P: ks.scryptP})
 	if err != nil {
 		return err
 	}
@@ -115,12 +162,50 @@ func (ks keyStorePassphrase) JoinPath(filename string) string {
 // EncryptKey encrypts a key using the specified scrypt parameters into a json
 // blob that can be decrypted later on.
 func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+	return EncryptKeyWithParams(key, auth, ScryptParams{N: scryptN, R: StandardScryptR, P: scryptP})
+}
+
+// EncryptKeyWithParams encrypts a key using the specified scrypt parameters,
+// including the block size, into a json blob that can be decrypted later on.
+func EncryptKeyWithParams(key *Key, auth string, params ScryptParams) ([]byte, error) {
 	authArray := []byte(auth)
 	salt := randentropy.GetEntropyCSPRNG(32)
-	derivedKey, err := scrypt.Key(authArray, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	derivedKey, err := scrypt.Key(authArray, salt, params.N, params.R, params.P, scryptDKLen)
 	if err != nil {
 		return nil, err
 	}
+	scryptParamsJSON := make(map[string]interface{}, 5)
+	scryptParamsJSON["n"] = params.N
+	scryptParamsJSON["r"] = params.R
+	scryptParamsJSON["p"] = params.P
+	scryptParamsJSON["dklen"] = scryptDKLen
+	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return encryptKey(key, derivedKey, keyHeaderKDF, scryptParamsJSON)
+}
+
+// EncryptKeyPBKDF2 encrypts a key using PBKDF2-HMAC-SHA256 with the specified
+// iteration count into a json blob that can be decrypted later on. It is meant
+// for exporting keys to tools not supporting scrypt, which is preferable.
+func EncryptKeyPBKDF2(key *Key, auth string, iterations int) ([]byte, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("invalid PBKDF2 iteration count: %d", iterations)
+	}
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey := pbkdf2.Key([]byte(auth), salt, iterations, scryptDKLen, sha256.New)
+
+	pbkdf2ParamsJSON := make(map[string]interface{}, 4)
+	pbkdf2ParamsJSON["c"] = iterations
+	pbkdf2ParamsJSON["prf"] = "hmac-sha256"
+	pbkdf2ParamsJSON["dklen"] = scryptDKLen
+	pbkdf2ParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return encryptKey(key, derivedKey, "pbkdf2", pbkdf2ParamsJSON)
+}
+
+// encryptKey encrypts a key with the key derived from the passphrase by the
+// named key derivation function into a version 3 json blob.
+func encryptKey(key *Key, derivedKey []byte, kdf string, kdfParams map[string]interface{}) ([]byte, error) {
 	encryptKey := derivedKey[:16]
 	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
 
@@ -131,13 +216,6 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
-	scryptParamsJSON := make(map[string]interface{}, 5)
-	scryptParamsJSON["n"] = scryptN
-	scryptParamsJSON["r"] = scryptR
-	scryptParamsJSON["p"] = scryptP
-	scryptParamsJSON["dklen"] = scryptDKLen
-	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
-
 	cipherParamsJSON := cipherparamsJSON{
 		IV: hex.EncodeToString(iv),
 	}
@@ -146,15 +224,29 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 		Cipher:       "aes-128-ctr",
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
-		KDF:          keyHeaderKDF,
-		KDFParams:    scryptParamsJSON,
+		KDF:          kdf,
+		KDFParams:    kdfParams,
 		MAC:          hex.EncodeToString(mac),
 	}
 	encryptedKeyJSONV3 := encryptedKeyJSONV3{
-		hex.EncodeToString(key.Address[:]),
-		cryptoStruct,
-		key.Id.String(),
-		version,
+		Address: hex.EncodeToString(key.Address[:]),
+		Crypto:  cryptoStruct,
+		Id:      key.Id.String(),
+		Version: version,
+	}
+	// Encrypt the mnemonic entropy too if the key was derived from one
+	if key.MnemonicEntropy != nil {
+		iv := randentropy.GetEntropyCSPRNG(aes.BlockSize)
+		cipherText, err := aesCTRXOR(encryptKey, key.MnemonicEntropy, iv)
+		if err != nil {
+			return nil, err
+		}
+		encryptedKeyJSONV3.Mnemonic = &mnemonicJSON{
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherparamsJSON{IV: hex.EncodeToString(iv)},
+			MAC:          hex.EncodeToString(crypto.Keccak256(derivedKey[16:32], cipherText)),
+			Path:         key.MnemonicPath.String(),
+		}
 	}
 	return json.Marshal(encryptedKeyJSONV3)
 }
@@ -168,8 +260,9 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	}
 	// Depending on the version try to parse one way or another
 	var (
-		keyBytes, keyId []byte
-		err             error
+		keyBytes, keyId, entropy []byte
+		path                     accounts.DerivationPath
+		err                      error
 	)
 	if version, ok := m["version"].(string); ok && version == "1" {
 		k := new(encryptedKeyJSONV1)
@@ -182,7 +275,10 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 		if err := json.Unmarshal(keyjson, k); err != nil {
 			return nil, err
 		}
-		keyBytes, keyId, err = decryptKeyV3(k, auth)
+		keyBytes, keyId, entropy, err = decryptKeyV3(k, auth)
+		if err == nil && k.Mnemonic != nil {
+			path, err = accounts.ParseDerivationPath(k.Mnemonic.Path)
+		}
 	}
 	// Handle any decryption errors and return the key
 	if err != nil {
@@ -191,52 +287,82 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	key := crypto.ToECDSAUnsafe(keyBytes)
 
 	return &Key{
-		Id:         uuid.UUID(keyId),
-		Address:    crypto.PubkeyToAddress(key.PublicKey),
-		PrivateKey: key,
+		Id:              uuid.UUID(keyId),
+		Address:         crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey:      key,
+		MnemonicEntropy: entropy,
+		MnemonicPath:    path,
 	}, nil
 }
 
-func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, err error) {
+// decryptKeyV3 decrypts a version 3 key, along with the entropy of the mnemonic
+// it was derived from, if any.
+func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, entropy []byte, err error) {
 	if keyProtected.Version != version {
-		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
+		return nil, nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
 	}
 
 	if keyProtected.Crypto.Cipher != "aes-128-ctr" {
-		return nil, nil, fmt.Errorf("Cipher not supported: %v", keyProtected.Crypto.Cipher)
+		return nil, nil, nil, fmt.Errorf("Cipher not supported: %v", keyProtected.Crypto.Cipher)
 	}
 
 	keyId = uuid.Parse(keyProtected.Id)
 	mac, err := hex.DecodeString(keyProtected.Crypto.MAC)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	iv, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
 	if !bytes.Equal(calculatedMAC, mac) {
-		return nil, nil, ErrDecrypt
+		return nil, nil, nil, ErrDecrypt
 	}
 
 	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	return plainText, keyId, err
+	if keyProtected.Mnemonic != nil {
+		if entropy, err = decryptMnemonic(keyProtected.Mnemonic, derivedKey); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return plainText, keyId, entropy, err
+}
+
+// decryptMnemonic decrypts the mnemonic entropy of a key with the key derived
+// from the passphrase.
+func decryptMnemonic(mnemonic *mnemonicJSON, derivedKey []byte) ([]byte, error) {
+	mac, err := hex.DecodeString(mnemonic.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(mnemonic.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(mnemonic.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+	return aesCTRXOR(derivedKey[:16], cipherText, iv)
 }
 
 func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byte, keyId []byte, err error) {
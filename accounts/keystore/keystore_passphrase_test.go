@@ -17,6 +17,7 @@
 package keystore
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"testing"
 
@@ -58,3 +59,86 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that keys encrypted with PBKDF2 instead of scrypt can be decrypted.
+func TestKeyEncryptDecryptPBKDF2(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if keyjson, err = EncryptKeyPBKDF2(key, "foo", 1024); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	if _, err := DecryptKey(keyjson, "bar"); err != ErrDecrypt {
+		t.Errorf("decryption error mismatch with bad password: have %v, want %v", err, ErrDecrypt)
+	}
+	recovered, err := DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if recovered.Address != key.Address {
+		t.Errorf("key address mismatch: have %x, want %x", recovered.Address, key.Address)
+	}
+	if _, err := EncryptKeyPBKDF2(key, "foo", 0); err == nil {
+		t.Errorf("key encrypted with zero iterations")
+	}
+}
+
+// Tests that keys can be encrypted with custom scrypt parameters and that the
+// parameters are stored along with the key for decryption.
+func TestKeyEncryptDecryptScryptParams(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	params := ScryptParams{N: 4, R: 2, P: 3}
+	if keyjson, err = EncryptKeyWithParams(key, "foo", params); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	var stored encryptedKeyJSONV3
+	if err := json.Unmarshal(keyjson, &stored); err != nil {
+		t.Fatalf("failed to parse encrypted key: %v", err)
+	}
+	if n, r, p := ensureInt(stored.Crypto.KDFParams["n"]), ensureInt(stored.Crypto.KDFParams["r"]), ensureInt(stored.Crypto.KDFParams["p"]); n != params.N || r != params.R || p != params.P {
+		t.Errorf("stored parameters mismatch: have N=%d R=%d P=%d, want %+v", n, r, p, params)
+	}
+	recovered, err := DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if recovered.Address != key.Address {
+		t.Errorf("recovered address mismatch: have %x, want %x", recovered.Address, key.Address)
+	}
+}
+
+// Tests that invalid scrypt parameters are rejected.
+func TestScryptParamsValidate(t *testing.T) {
+	tests := []struct {
+		params ScryptParams
+		valid  bool
+	}{
+		{ScryptParams{N: StandardScryptN, R: StandardScryptR, P: StandardScryptP}, true},
+		{ScryptParams{N: LightScryptN, R: StandardScryptR, P: LightScryptP}, true},
+		{ScryptParams{N: 2, R: 1, P: 1}, true},
+		{ScryptParams{N: 0, R: 8, P: 1}, false},
+		{ScryptParams{N: 1, R: 8, P: 1}, false},
+		{ScryptParams{N: 1000, R: 8, P: 1}, false},
+		{ScryptParams{N: 1024, R: 0, P: 1}, false},
+		{ScryptParams{N: 1024, R: 8, P: -1}, false},
+		{ScryptParams{N: 1 << 30, R: 8, P: 1}, false},
+		{ScryptParams{N: 1024, R: 1 << 20, P: 1 << 10}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.params.Validate(); (err == nil) != tt.valid {
+			t.Errorf("test %d: %+v validity mismatch: have %v, want valid %v", i, tt.params, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMnemonicBits is the entropy strength of newly generated mnemonics,
+// resulting in 24 word phrases.
+const DefaultMnemonicBits = 256
+
+var (
+	ErrNoMnemonic       = errors.New("key not derived from a mnemonic")
+	errInvalidEntropy   = errors.New("mnemonic entropy must be 128 to 256 bits, in multiples of 32")
+	errInvalidChecksum  = errors.New("invalid mnemonic checksum")
+	errInvalidChildKey  = errors.New("invalid derived child key")
+	errInvalidMasterKey = errors.New("invalid master key")
+)
+
+// mnemonicIndices maps the words of the mnemonic wordlist to their positions.
+var mnemonicIndices = make(map[string]int, len(mnemonicWords))
+
+func init() {
+	for i, word := range mnemonicWords {
+		mnemonicIndices[word] = i
+	}
+}
+
+// NewMnemonic generates a random BIP-39 mnemonic with the given entropy strength
+// in bits, which must be between 128 and 256 and a multiple of 32.
+func NewMnemonic(bits int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", errInvalidEntropy
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := io.ReadFull(crand.Reader, entropy); err != nil {
+		return "", err
+	}
+	return entropyToMnemonic(entropy)
+}
+
+// entropyToMnemonic encodes the given entropy into a BIP-39 mnemonic, appending
+// a checksum of the entropy and mapping each 11 bit group onto a word.
+func entropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", errInvalidEntropy
+	}
+	checksum := sha256.Sum256(entropy)
+
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(bits/32))
+	data.Or(data, big.NewInt(int64(checksum[0]>>uint(8-bits/32))))
+
+	words := make([]string, (bits+bits/32)/11)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = mnemonicWords[data.Uint64()&2047]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// mnemonicToEntropy decodes the entropy from a BIP-39 mnemonic, verifying its
+// checksum.
+func mnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("invalid mnemonic length: %d words", len(words))
+	}
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := mnemonicIndices[strings.ToLower(word)]
+		if !ok {
+			return nil, fmt.Errorf("invalid mnemonic word: %q", word)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	var (
+		checksumBits = uint(len(words) / 3)
+		checksum     = new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	)
+	entropy := math.PaddedBigBytes(data.Rsh(data, checksumBits), len(words)*4/3)
+
+	if want := sha256.Sum256(entropy); uint64(want[0]>>(8-checksumBits)) != checksum.Uint64() {
+		return nil, errInvalidChecksum
+	}
+	return entropy, nil
+}
+
+// mnemonicSeed generates the BIP-39 seed of a mnemonic, protected by an optional
+// password.
+func mnemonicSeed(mnemonic string, password string) []byte {
+	var (
+		phrase = strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+		salt   = "mnemonic" + norm.NFKD.String(password)
+	)
+	return pbkdf2.Key([]byte(strings.ToLower(phrase)), []byte(salt), 2048, 64, sha512.New)
+}
+
+// deriveKey derives the private key located at the given path of the BIP-32
+// hierarchical deterministic wallet generated by the seed.
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, err := crypto.ToECDSA(sum[:32])
+	if err != nil {
+		return nil, errInvalidMasterKey
+	}
+	chain := sum[32:]
+
+	for _, index := range path {
+		// Hardened children commit to the private key, normal ones to the public
+		var data []byte
+		if index >= 0x80000000 {
+			data = append([]byte{0}, math.PaddedBigBytes(key.D, 32)...)
+		} else {
+			data = crypto.CompressPubkey(&key.PublicKey)
+		}
+		data = append(data, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		// Tweak the parent key with the left half, failing on the unlikely invalid ones
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(crypto.S256().Params().N) >= 0 {
+			return nil, errInvalidChildKey
+		}
+		child := tweak.Add(tweak, key.D)
+		child.Mod(child, crypto.S256().Params().N)
+
+		if key, err = crypto.ToECDSA(math.PaddedBigBytes(child, 32)); err != nil {
+			return nil, errInvalidChildKey
+		}
+		chain = sum[32:]
+	}
+	return key, nil
+}
+
+// newKeyFromMnemonic derives the key located at the given path of the wallet
+// generated by a BIP-39 mnemonic and its optional password.
+func newKeyFromMnemonic(mnemonic string, password string, path accounts.DerivationPath) (*Key, error) {
+	entropy, err := mnemonicToEntropy(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	privateKeyECDSA, err := deriveKey(mnemonicSeed(mnemonic, password), path)
+	if err != nil {
+		return nil, err
+	}
+	key := newKeyFromECDSA(privateKeyECDSA)
+	key.MnemonicEntropy = entropy
+	key.MnemonicPath = path
+
+	return key, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that entropy is encoded into and decoded from mnemonics according to the
+// BIP-39 test vectors.
+func TestMnemonicVectors(t *testing.T) {
+	tests := []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+		{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above"},
+		{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will"},
+		{"808080808080808080808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always"},
+		{"ffffffffffffffffffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo when"},
+		{"0000000000000000000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"},
+		{"9e885d952ad362caeb4efe34a8e91bd2", "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic"},
+		{"6610b25967cdcca9d59875f5cb50b0ea75433311869e930b", "gravity machine north sort system female filter attitude volume fold club stay feature office ecology stable narrow fog"},
+		{"68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c", "hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length"},
+		{"c0ba5a8e914111210f2bd131f3d5e08d", "scheme spot photo card baby mountain device kick cradle pact join borrow"},
+		{"6d9be1ee6ebd27a258115aad99b7317b9c8d28b6d76431c3", "horn tenant knee talent sponsor spell gate clip pulse soap slush warm silver nephew swap uncle crack brave"},
+		{"9f6a2878b2520799a44ef18bc7df394e7061a224d2c33cd015b157d746869863", "panda eyebrow bullet gorilla call smoke muffin taste mesh discover soft ostrich alcohol speed nation flash devote level hobby quick inner drive ghost inside"},
+		{"f30f8c1da665478f49b001d94c5fc452", "vessel ladder alter error federal sibling chat ability sun glass valve picture"},
+		{"c10ec20dc3cd9f652c7fac2f1230f7a3c828389a14392f05", "scissors invite lock maple supreme raw rapid void congress muscle digital elegant little brisk hair mango congress clump"},
+		{"f585c11aec520db57dd353c69554b21a89b20fb0650966fa0a9d6f74fd989d8f", "void come effort suffer camp survey warrior heavy shoot primary clutch crush open amazing screen patrol group space point ten exist slush involve unfold"},
+	}
+	for i, tt := range tests {
+		entropy := common.FromHex(tt.entropy)
+
+		mnemonic, err := entropyToMnemonic(entropy)
+		if err != nil {
+			t.Errorf("test %d: failed to encode entropy: %v", i, err)
+			continue
+		}
+		if mnemonic != tt.mnemonic {
+			t.Errorf("test %d: mnemonic mismatch: have %q, want %q", i, mnemonic, tt.mnemonic)
+		}
+		decoded, err := mnemonicToEntropy(tt.mnemonic)
+		if err != nil {
+			t.Errorf("test %d: failed to decode mnemonic: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(decoded, entropy) {
+			t.Errorf("test %d: entropy mismatch: have %x, want %x", i, decoded, entropy)
+		}
+	}
+}
+
+// Tests that invalid mnemonics are rejected.
+func TestMnemonicInvalid(t *testing.T) {
+	tests := []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon gethx",
+	}
+	for i, mnemonic := range tests {
+		if _, err := mnemonicToEntropy(mnemonic); err == nil {
+			t.Errorf("test %d: invalid mnemonic accepted", i)
+		}
+	}
+	for _, bits := range []int{0, 96, 144, 288} {
+		if _, err := NewMnemonic(bits); err != errInvalidEntropy {
+			t.Errorf("%d bits: error mismatch: have %v, want %v", bits, err, errInvalidEntropy)
+		}
+	}
+}
+
+// Tests that mnemonic seeds are generated according to the BIP-39 test vectors.
+func TestMnemonicSeed(t *testing.T) {
+	seed := mnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "TREZOR")
+	want := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if hex.EncodeToString(seed) != want {
+		t.Errorf("seed mismatch: have %x, want %s", seed, want)
+	}
+}
+
+// Tests that private keys are derived according to the BIP-32 test vectors.
+func TestDeriveKey(t *testing.T) {
+	seed := common.FromHex("000102030405060708090a0b0c0d0e0f")
+
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for i, tt := range tests {
+		var path accounts.DerivationPath
+		if tt.path != "m" {
+			var err error
+			if path, err = accounts.ParseDerivationPath(tt.path); err != nil {
+				t.Fatalf("test %d: invalid path %s: %v", i, tt.path, err)
+			}
+		}
+		key, err := deriveKey(seed, path)
+		if err != nil {
+			t.Errorf("test %d: failed to derive key: %v", i, err)
+			continue
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key)); have != tt.key {
+			t.Errorf("test %d: key mismatch: have %s, want %s", i, have, tt.key)
+		}
+	}
+}
+
+// Tests that accounts can be imported from mnemonics and that the mnemonic can be
+// exported again, surviving both the plain and encrypted key formats.
+func TestMnemonicImportExport(t *testing.T) {
+	testMnemonicImportExport(t, false)
+	testMnemonicImportExport(t, true)
+}
+
+func testMnemonicImportExport(t *testing.T, encrypted bool) {
+	dir, ks := tmpKeyStore(t, encrypted)
+	defer os.RemoveAll(dir)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	account, err := ks.ImportMnemonic(strings.ToUpper(mnemonic), "", accounts.DefaultBaseDerivationPath, "foo")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	if want := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94"); account.Address != want {
+		t.Fatalf("address mismatch: have %x, want %x", account.Address, want)
+	}
+	if _, err := ks.ImportMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath, "foo"); err == nil {
+		t.Fatalf("imported duplicate account")
+	}
+	if _, _, err := ks.ExportMnemonic(account, "bar"); encrypted && err != ErrDecrypt {
+		t.Fatalf("export with wrong passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	exported, path, err := ks.ExportMnemonic(account, "foo")
+	if err != nil {
+		t.Fatalf("failed to export mnemonic: %v", err)
+	}
+	if exported != mnemonic {
+		t.Errorf("mnemonic mismatch: have %q, want %q", exported, mnemonic)
+	}
+	if !reflect.DeepEqual(path, accounts.DefaultBaseDerivationPath) {
+		t.Errorf("path mismatch: have %v, want %v", path, accounts.DefaultBaseDerivationPath)
+	}
+	// Changing the passphrase must retain the mnemonic
+	if err := ks.Update(account, "foo", "bar"); err != nil {
+		t.Fatalf("failed to update passphrase: %v", err)
+	}
+	if exported, _, err = ks.ExportMnemonic(account, "bar"); err != nil || exported != mnemonic {
+		t.Errorf("mnemonic lost on update: have %q, %v", exported, err)
+	}
+	// Accounts not derived from mnemonics must refuse the export
+	plain, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if _, _, err := ks.ExportMnemonic(plain, "foo"); err != ErrNoMnemonic {
+		t.Errorf("export error mismatch: have %v, want %v", err, ErrNoMnemonic)
+	}
+}
+
+// Tests that newly generated mnemonic accounts can be recreated from the mnemonic.
+func TestNewMnemonicAccount(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	account, mnemonic, err := ks.NewMnemonicAccount(DefaultMnemonicBits, "foo")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if words := len(strings.Fields(mnemonic)); words != 24 {
+		t.Fatalf("mnemonic length mismatch: have %d, want %d", words, 24)
+	}
+	key, err := newKeyFromMnemonic(mnemonic, "", accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	if key.Address != account.Address {
+		t.Errorf("address mismatch: have %x, want %x", key.Address, account.Address)
+	}
+}
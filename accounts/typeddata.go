@@ -0,0 +1,374 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// typedDataDomain is the name of the struct type describing the signing domain
+// of an EIP-712 typed data message.
+const typedDataDomain = "EIP712Domain"
+
+// TypedDataField is a single named member of an EIP-712 struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is a structured message signable according to EIP-712, consisting
+// of the struct type definitions, the signing domain and the message itself.
+//
+// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-712.md
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// TypedDataSigner is an optional interface implemented by wallets which need to
+// see the components of an EIP-712 message instead of its final hash to sign it
+// (e.g. hardware wallets displaying them for confirmation).
+type TypedDataSigner interface {
+	// SignTypedData requests the wallet to sign the EIP-712 message identified
+	// by its domain separator and message struct hash. The returned signature is
+	// in the [R || S || V] format where V is 0 or 1.
+	SignTypedData(account Account, domainSeparator, messageHash []byte) ([]byte, error)
+}
+
+// UnmarshalJSON parses a typed data message, retaining the exact value of the
+// numbers within the domain and the message instead of rounding them to floats.
+func (typed *TypedData) UnmarshalJSON(input []byte) error {
+	type typedData TypedData
+
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+
+	var data typedData
+	if err := dec.Decode(&data); err != nil {
+		return err
+	}
+	*typed = TypedData(data)
+	return nil
+}
+
+// Hash computes the EIP-712 digest of the typed data that needs to be signed:
+//
+//   keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func (typed *TypedData) Hash() ([]byte, error) {
+	domain, err := typed.DomainSeparator()
+	if err != nil {
+		return nil, err
+	}
+	message, err := typed.MessageHash()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte{0x19, 0x01}, domain, message), nil
+}
+
+// DomainSeparator computes the struct hash of the signing domain.
+func (typed *TypedData) DomainSeparator() ([]byte, error) {
+	return typed.HashStruct(typedDataDomain, typed.Domain)
+}
+
+// MessageHash computes the struct hash of the message, interpreted as the
+// primary type.
+func (typed *TypedData) MessageHash() ([]byte, error) {
+	if typed.PrimaryType == "" {
+		return nil, fmt.Errorf("missing primary type")
+	}
+	return typed.HashStruct(typed.PrimaryType, typed.Message)
+}
+
+// HashStruct computes the EIP-712 hash of a struct value of the given type.
+func (typed *TypedData) HashStruct(name string, data map[string]interface{}) ([]byte, error) {
+	enc, err := typed.encodeData(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(enc), nil
+}
+
+// TypeHash computes the hash of the canonical encoding of a struct type.
+func (typed *TypedData) TypeHash(name string) ([]byte, error) {
+	enc, err := typed.EncodeType(name)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte(enc)), nil
+}
+
+// EncodeType generates the canonical encoding of a struct type, which is the
+// signature of the type followed by those of all the struct types it references,
+// sorted by name, e.g. "Mail(Person from,Person to,string contents)Person(string
+// name,address wallet)".
+func (typed *TypedData) EncodeType(name string) (string, error) {
+	deps := make(map[string]bool)
+	if err := typed.dependencies(name, deps); err != nil {
+		return "", err
+	}
+	delete(deps, name)
+
+	names := make([]string, 0, len(deps))
+	for dep := range deps {
+		names = append(names, dep)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	for _, name := range append([]string{name}, names...) {
+		buffer.WriteString(name)
+		buffer.WriteString("(")
+		for i, field := range typed.Types[name] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String(), nil
+}
+
+// dependencies collects the struct types transitively referenced by the named
+// one, including itself.
+func (typed *TypedData) dependencies(name string, found map[string]bool) error {
+	if found[name] {
+		return nil
+	}
+	fields, ok := typed.Types[name]
+	if !ok {
+		return fmt.Errorf("unknown type %q", name)
+	}
+	found[name] = true
+	for _, field := range fields {
+		if base := baseType(field.Type); typed.isStruct(base) {
+			if err := typed.dependencies(base, found); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeData encodes a struct value of the given type as its type hash followed
+// by the 32 byte encoding of each of its members.
+func (typed *TypedData) encodeData(name string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := typed.Types[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", name)
+	}
+	if len(data) > len(fields) {
+		return nil, fmt.Errorf("%s: more values (%d) than fields (%d)", name, len(data), len(fields))
+	}
+	hash, err := typed.TypeHash(name)
+	if err != nil {
+		return nil, err
+	}
+	enc := append([]byte{}, hash...)
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing value for field %q", name, field.Name)
+		}
+		word, err := typed.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", name, field.Name, err)
+		}
+		enc = append(enc, word...)
+	}
+	return enc, nil
+}
+
+// encodeValue encodes a single value of the given type into a 32 byte word.
+func (typed *TypedData) encodeValue(kind string, value interface{}) ([]byte, error) {
+	// Arrays are encoded as the hash of their concatenated item encodings
+	if strings.HasSuffix(kind, "]") {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", kind, value)
+		}
+		elem := kind[:strings.LastIndex(kind, "[")]
+		if size := kind[len(elem)+1 : len(kind)-1]; size != "" {
+			if n, err := strconv.Atoi(size); err != nil || n != len(items) {
+				return nil, fmt.Errorf("invalid %s length %d", kind, len(items))
+			}
+		}
+		var enc []byte
+		for _, item := range items {
+			word, err := typed.encodeValue(elem, item)
+			if err != nil {
+				return nil, err
+			}
+			enc = append(enc, word...)
+		}
+		return crypto.Keccak256(enc), nil
+	}
+	// Structs are encoded as their struct hash
+	if typed.isStruct(kind) {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", kind, value)
+		}
+		return typed.HashStruct(kind, data)
+	}
+	// Atomic and dynamic types are encoded directly
+	switch {
+	case kind == "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case kind == "bytes":
+		blob, err := parseTypedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+
+	case kind == "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value %v", value)
+		}
+		word := make([]byte, 32)
+		if flag {
+			word[31] = 1
+		}
+		return word, nil
+
+	case kind == "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, fmt.Errorf("invalid address value %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(str).Bytes(), 32), nil
+
+	case strings.HasPrefix(kind, "bytes"):
+		size, err := strconv.Atoi(kind[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("unknown type %q", kind)
+		}
+		blob, err := parseTypedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) != size {
+			return nil, fmt.Errorf("invalid %s length %d", kind, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), nil
+
+	case strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "int"):
+		signed := strings.HasPrefix(kind, "int")
+
+		bits, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(kind, "u"), "int"))
+		if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("unknown type %q", kind)
+		}
+		number, err := parseTypedInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		if signed {
+			limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+			if number.Cmp(limit) >= 0 || number.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, fmt.Errorf("%s overflow: %v", kind, number)
+			}
+		} else if number.Sign() < 0 || number.BitLen() > bits {
+			return nil, fmt.Errorf("%s overflow: %v", kind, number)
+		}
+		return math.PaddedBigBytes(math.U256(number), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", kind)
+}
+
+// isStruct returns whether the given type name references a defined struct type.
+func (typed *TypedData) isStruct(kind string) bool {
+	_, ok := typed.Types[kind]
+	return ok
+}
+
+// baseType strips all the array suffixes of a type, e.g. "Person[][2]" -> "Person".
+func baseType(kind string) string {
+	if idx := strings.Index(kind, "["); idx >= 0 {
+		return kind[:idx]
+	}
+	return kind
+}
+
+// parseTypedBytes interprets a value as a 0x prefixed hex encoded byte slice.
+func parseTypedBytes(value interface{}) ([]byte, error) {
+	switch value := value.(type) {
+	case string:
+		return hexutil.Decode(value)
+	case []byte:
+		return value, nil
+	}
+	return nil, fmt.Errorf("invalid bytes value %v", value)
+}
+
+// parseTypedInteger interprets a value as an integer. Hex and decimal strings
+// are accepted, as well as JSON numbers without a fractional part.
+func parseTypedInteger(value interface{}) (*big.Int, error) {
+	switch value := value.(type) {
+	case json.Number:
+		if number, ok := new(big.Int).SetString(string(value), 10); ok {
+			return number, nil
+		}
+	case string:
+		str, neg := value, strings.HasPrefix(value, "-")
+		if neg {
+			str = str[1:]
+		}
+		if number, ok := math.ParseBig256(str); ok {
+			if neg {
+				number.Neg(number)
+			}
+			return number, nil
+		}
+	case float64:
+		if number, acc := big.NewFloat(value).Int(nil); acc == big.Exact {
+			return number, nil
+		}
+	case int:
+		return big.NewInt(int64(value)), nil
+	case int64:
+		return big.NewInt(value), nil
+	case uint64:
+		return new(big.Int).SetUint64(value), nil
+	case *big.Int:
+		return new(big.Int).Set(value), nil
+	}
+	return nil, fmt.Errorf("invalid integer value %v", value)
+}
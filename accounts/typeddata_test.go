@@ -0,0 +1,153 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mailTypedData is the example message from the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// Tests that typed data is hashed and signed as defined by the EIP-712 reference
+// example.
+func TestTypedDataHash(t *testing.T) {
+	var typed TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &typed); err != nil {
+		t.Fatalf("failed to parse typed data: %v", err)
+	}
+	if enc, err := typed.EncodeType("Mail"); err != nil || enc != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Errorf("type encoding mismatch: have %q, %v", enc, err)
+	}
+	checks := []struct {
+		name string
+		hash func() ([]byte, error)
+		want string
+	}{
+		{"type hash", func() ([]byte, error) { return typed.TypeHash("Mail") }, "0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"},
+		{"domain separator", typed.DomainSeparator, "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"},
+		{"message hash", typed.MessageHash, "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"},
+		{"digest", typed.Hash, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"},
+	}
+	for _, check := range checks {
+		hash, err := check.hash()
+		if err != nil {
+			t.Fatalf("%s: failed to hash: %v", check.name, err)
+		}
+		if have := hexutil.Encode(hash); have != check.want {
+			t.Errorf("%s mismatch: have %s, want %s", check.name, have, check.want)
+		}
+	}
+	// Sign the digest with the reference key and check against the EIP signature
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	if addr := crypto.PubkeyToAddress(key.PublicKey); addr.Hex() != "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826" {
+		t.Fatalf("reference key mismatch: %s", addr.Hex())
+	}
+	hash, _ := typed.Hash()
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	want := "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b9156201"
+	if have := hexutil.Encode(sig); have != want {
+		t.Errorf("signature mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that the individual value types are encoded correctly and that invalid
+// values are rejected.
+func TestTypedDataValueEncoding(t *testing.T) {
+	typed := &TypedData{Types: map[string][]TypedDataField{
+		"Person": {{Name: "name", Type: "string"}},
+	}}
+	tests := []struct {
+		kind  string
+		value interface{}
+		want  string // empty if the encoding should fail
+	}{
+		{"bool", true, "0x0000000000000000000000000000000000000000000000000000000000000001"},
+		{"bool", "true", ""},
+		{"uint8", json.Number("255"), "0x00000000000000000000000000000000000000000000000000000000000000ff"},
+		{"uint8", json.Number("256"), ""},
+		{"uint8", "-1", ""},
+		{"uint256", "0x10", "0x0000000000000000000000000000000000000000000000000000000000000010"},
+		{"uint256", float64(1.5), ""},
+		{"int8", "-1", "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"int8", json.Number("-129"), ""},
+		{"int7", json.Number("1"), ""},
+		{"bytes2", "0x0102", "0x0102000000000000000000000000000000000000000000000000000000000000"},
+		{"bytes2", "0x01", ""},
+		{"bytes33", "0x01", ""},
+		{"address", "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000000000000000000000000000001"},
+		{"address", "0x01", ""},
+		{"bytes", "0x", hexutil.Encode(crypto.Keccak256(nil))},
+		{"string", "", hexutil.Encode(crypto.Keccak256(nil))},
+		{"uint8[2]", []interface{}{json.Number("1")}, ""},
+		{"uint8[]", []interface{}{json.Number("1"), json.Number("2")}, hexutil.Encode(crypto.Keccak256(
+			hexutil.MustDecode("0x0000000000000000000000000000000000000000000000000000000000000001"),
+			hexutil.MustDecode("0x0000000000000000000000000000000000000000000000000000000000000002"),
+		))},
+		{"Person", map[string]interface{}{}, ""},
+		{"Person", map[string]interface{}{"name": "", "age": "1"}, ""},
+		{"Animal", map[string]interface{}{}, ""},
+	}
+	for i, tt := range tests {
+		enc, err := typed.encodeValue(tt.kind, tt.value)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("test %d: %s value %v: expected failure, got %x", i, tt.kind, tt.value, enc)
+		case tt.want != "" && err != nil:
+			t.Errorf("test %d: %s value %v: failed to encode: %v", i, tt.kind, tt.value, err)
+		case tt.want != "" && hexutil.Encode(enc) != tt.want:
+			t.Errorf("test %d: %s value %v: encoding mismatch: have %x, want %s", i, tt.kind, tt.value, enc, tt.want)
+		}
+	}
+}
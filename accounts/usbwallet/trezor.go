@@ -159,6 +159,13 @@ func (w *trezorDriver) SignTx(path accounts.DerivationPath, tx *types.Transactio
 	return w.trezorSign(path, tx, chainID)
 }
 
+// SignTypedMessage implements usbwallet.driver, however signing EIP-712 messages
+// is not supported by the Trezor firmware, so this method will always return an
+// error.
+func (w *trezorDriver) SignTypedMessage(path accounts.DerivationPath, domainSeparator, messageHash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
 // trezorDerive sends a derivation request to the Trezor device and returns the
 // Ethereum address located on that path.
 func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
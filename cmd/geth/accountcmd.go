@@ -17,18 +17,73 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"gopkg.in/urfave/cli.v1"
 )
 
+var (
+	mnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Derive the new account from a freshly generated BIP-39 mnemonic",
+	}
+	hdPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "Derivation path of the account within the wallet of the mnemonic",
+		Value: accounts.DefaultBaseDerivationPath.String(),
+	}
+	kdfFlag = cli.StringFlag{
+		Name:  "kdf",
+		Usage: "Key derivation function to encrypt the exported keys with (scrypt, pbkdf2)",
+		Value: "scrypt",
+	}
+	kdfScryptNFlag = cli.IntFlag{
+		Name:  "kdf.scrypt.n",
+		Usage: "Scrypt CPU/memory cost parameter of the exported keys",
+		Value: keystore.StandardScryptN,
+	}
+	kdfScryptRFlag = cli.IntFlag{
+		Name:  "kdf.scrypt.r",
+		Usage: "Scrypt block size parameter of the exported keys",
+		Value: keystore.StandardScryptR,
+	}
+	kdfScryptPFlag = cli.IntFlag{
+		Name:  "kdf.scrypt.p",
+		Usage: "Scrypt parallelization parameter of the exported keys",
+		Value: keystore.StandardScryptP,
+	}
+	kdfPBKDF2IterationsFlag = cli.IntFlag{
+		Name:  "kdf.pbkdf2.c",
+		Usage: "PBKDF2 iteration count of the exported keys",
+		Value: 262144,
+	}
+	kdfTargetTimeFlag = cli.DurationFlag{
+		Name:  "time",
+		Usage: "Target time to unlock an account",
+		Value: time.Second,
+	}
+	kdfMemoryFlag = cli.IntFlag{
+		Name:  "memory",
+		Usage: "Maximum memory to unlock an account in megabytes",
+		Value: 1024,
+	}
+)
+
 var (
 	walletCommand = cli.Command{
 		Name:      "wallet",
@@ -54,6 +109,9 @@ passwordfile as argument containing the wallet password in plaintext.`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptRFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				Description: `
 	geth wallet [options] /path/to/my/presale.wallet
@@ -110,12 +168,21 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptRFlag,
+					utils.KeyStoreScryptPFlag,
+					mnemonicFlag,
 				},
 				Description: `
     geth account new
 
 Creates a new account and prints the address.
 
+With the --mnemonic flag, the account is derived from a newly generated 24 word
+BIP-39 mnemonic at the standard m/44'/60'/0'/0/0 path, and the mnemonic is printed
+too. Write it down and keep it safe, it's enough to restore the account in geth
+or any other wallet supporting BIP-39.
+
 The account is saved in encrypted format, you are prompted for a passphrase.
 
 You must remember this passphrase to unlock your account in the future.
@@ -135,6 +202,9 @@ password to file or expose in any other way.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptRFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				Description: `
     geth account update <address>
@@ -164,6 +234,9 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptRFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
@@ -186,6 +259,150 @@ Note:
 As you can directly copy your encrypted accounts to another ethereum instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:   "import-mnemonic",
+				Usage:  "Import an account derived from a BIP-39 mnemonic",
+				Action: utils.MigrateFlags(accountImportMnemonic),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptRFlag,
+					utils.KeyStoreScryptPFlag,
+					hdPathFlag,
+				},
+				ArgsUsage: "<mnemonicFile>",
+				Description: `
+    geth account import-mnemonic <mnemonicfile>
+
+Imports the account derived from the BIP-39 mnemonic in <mnemonicfile> and prints
+the address.
+
+The first line of the file must contain the mnemonic, an optional second line the
+mnemonic password (sometimes called the 25th word). The account is derived at the
+standard m/44'/60'/0'/0/0 path, other paths can be specified with --hdpath.
+
+The account is saved in encrypted format, along with the mnemonic to be able to
+export it again, you are prompted for a passphrase.
+
+You must remember this passphrase to unlock your account in the future.
+
+For non-interactive use the passphrase can be specified with the --password flag:
+
+    geth account import-mnemonic [options] <mnemonicfile>
+`,
+			},
+			{
+				Name:   "import-batch",
+				Usage:  "Import multiple keys from key files, directories or raw key lists",
+				Action: utils.MigrateFlags(accountImportBatch),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptRFlag,
+					utils.KeyStoreScryptPFlag,
+				},
+				ArgsUsage: "<file or directory>...",
+				Description: `
+    geth account import-batch <file or directory>...
+
+Imports all the keys contained in the given files and directories into new
+accounts and prints their addresses.
+
+Each file either contains a single encrypted JSON key in the standard keystore
+format (using scrypt or PBKDF2 key derivation), or a list of unencrypted private
+keys in hexadecimal format, one per line. Empty lines and lines starting with #
+are ignored. Directories are scanned for such files in alphabetical order.
+
+Every key is saved in encrypted format with its own passphrase, which in case of
+encrypted JSON keys is the one the key is already encrypted with. For
+non-interactive use the passphrases can be specified with the --password flag,
+the file containing one passphrase per line for each key in order of import.
+
+Keys of accounts already present in the keystore are skipped.
+`,
+			},
+			{
+				Name:   "export-batch",
+				Usage:  "Export multiple accounts as encrypted JSON keys into a directory",
+				Action: utils.MigrateFlags(accountExportBatch),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					kdfFlag,
+					kdfScryptNFlag,
+					kdfScryptRFlag,
+					kdfScryptPFlag,
+					kdfPBKDF2IterationsFlag,
+				},
+				ArgsUsage: "<directory> [address...]",
+				Description: `
+    geth account export-batch <directory> [address...]
+
+Exports the given accounts, or all of them if none are specified, as encrypted
+JSON keys in the standard keystore format into <directory>, which may be copied
+into the keystore of another node or imported with import-batch.
+
+The keys are re-encrypted with their current passphrases, using the key
+derivation function selected with --kdf along with its parameters, which allows
+exporting keys for tools not supporting the default scrypt settings. For
+non-interactive use the passphrases can be specified with the --password flag,
+the file containing one passphrase per line for each account in order of export.
+
+Existing files in <directory> are never overwritten.
+`,
+			},
+			{
+				Name:      "export-mnemonic",
+				Usage:     "Print the BIP-39 mnemonic of an existing account",
+				Action:    utils.MigrateFlags(accountExportMnemonic),
+				ArgsUsage: "<address>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+				},
+				Description: `
+    geth account export-mnemonic <address>
+
+Prints the BIP-39 mnemonic and derivation path of an account created from or
+imported with a mnemonic, after unlocking it. Accounts of plain private keys
+cannot be exported as mnemonics.
+
+Anyone knowing the mnemonic (and its password, if any) has full control over the
+account, do not expose it.
+`,
+			},
+			{
+				Name:   "benchmark-kdf",
+				Usage:  "Suggest key store scrypt parameters for a target unlock time",
+				Action: utils.MigrateFlags(accountBenchmarkKDF),
+				Flags: []cli.Flag{
+					utils.KeyStoreScryptRFlag,
+					utils.KeyStoreScryptPFlag,
+					kdfTargetTimeFlag,
+					kdfMemoryFlag,
+				},
+				Description: `
+    geth account benchmark-kdf [--time 1s] [--memory 1024]
+
+Measures how long unlocking an account takes on this machine with increasing
+scrypt CPU/memory cost (N), and suggests the strongest parameters unlocking an
+account within the target time and memory allowance. The block size (R) and
+parallelization (P) parameters can be fixed with --keystore.scrypt.r and
+--keystore.scrypt.p.
+
+The suggested parameters can be passed to any command creating keys via the
+--keystore.scrypt.n, --keystore.scrypt.r and --keystore.scrypt.p flags. They
+only apply to new keys or keys whose password is updated.
 `,
 			},
 		},
@@ -299,7 +516,7 @@ func accountCreate(ctx *cli.Context) error {
 		}
 	}
 	utils.SetNodeConfig(ctx, &cfg.Node)
-	scryptN, scryptP, keydir, err := cfg.Node.AccountConfig()
+	scrypt, keydir, err := cfg.Node.AccountConfig()
 
 	if err != nil {
 		utils.Fatalf("Failed to read configuration: %v", err)
@@ -307,7 +524,18 @@ func accountCreate(ctx *cli.Context) error {
 
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	address, err := keystore.StoreKey(keydir, password, scryptN, scryptP)
+	if ctx.GlobalBool(mnemonicFlag.Name) {
+		ks := keystore.NewKeyStoreWithParams(keydir, scrypt)
+		account, mnemonic, err := ks.NewMnemonicAccount(keystore.DefaultMnemonicBits, password)
+		if err != nil {
+			utils.Fatalf("Failed to create account: %v", err)
+		}
+		fmt.Printf("Address: {%x}\n", account.Address)
+		fmt.Printf("Mnemonic: %s\n", mnemonic)
+		fmt.Printf("Path: %s\n", accounts.DefaultBaseDerivationPath)
+		return nil
+	}
+	address, err := keystore.StoreKeyWithParams(keydir, password, scrypt)
 
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
@@ -377,3 +605,297 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%x}\n", acct.Address)
 	return nil
 }
+
+func accountImportMnemonic(ctx *cli.Context) error {
+	mnemonicfile := ctx.Args().First()
+	if len(mnemonicfile) == 0 {
+		utils.Fatalf("mnemonicfile must be given as argument")
+	}
+	blob, err := ioutil.ReadFile(mnemonicfile)
+	if err != nil {
+		utils.Fatalf("Failed to read the mnemonic: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(blob), "\r\n"), "\n")
+
+	mnemonic, password := strings.TrimSpace(lines[0]), ""
+	if len(lines) > 1 {
+		password = strings.TrimRight(lines[1], "\r")
+	}
+	path := accounts.DefaultBaseDerivationPath
+	if ctx.GlobalIsSet(hdPathFlag.Name) {
+		if path, err = accounts.ParseDerivationPath(ctx.GlobalString(hdPathFlag.Name)); err != nil {
+			utils.Fatalf("Invalid derivation path: %v", err)
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	acct, err := ks.ImportMnemonic(mnemonic, password, path, passphrase)
+	if err != nil {
+		utils.Fatalf("Could not create the account: %v", err)
+	}
+	fmt.Printf("Address: {%x}\n", acct.Address)
+	return nil
+}
+
+func accountExportMnemonic(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("No account specified to export")
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	account, password := unlockAccount(ctx, ks, ctx.Args().First(), 0, utils.MakePasswordList(ctx))
+	mnemonic, path, err := ks.ExportMnemonic(account, password)
+	if err != nil {
+		utils.Fatalf("Could not export the mnemonic: %v", err)
+	}
+	fmt.Printf("Mnemonic: %s\n", mnemonic)
+	fmt.Printf("Path: %s\n", path)
+	return nil
+}
+
+// batchKey is a key to import in a batch, either in encrypted JSON format or as
+// an unencrypted private key, along with a description of where it was found.
+type batchKey struct {
+	source  string
+	keyJSON []byte
+	key     *ecdsa.PrivateKey
+}
+
+// loadBatchKeys reads the keys contained in the given files and directories, in
+// the order of the arguments and of the directory listings.
+func loadBatchKeys(paths []string) ([]*batchKey, error) {
+	var keys []*batchKey
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			loaded, err := loadBatchKeyFile(path)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, loaded...)
+			continue
+		}
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+				continue
+			}
+			loaded, err := loadBatchKeyFile(filepath.Join(path, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, loaded...)
+		}
+	}
+	return keys, nil
+}
+
+// loadBatchKeyFile reads either a single encrypted JSON key or a list of hex
+// encoded private keys from a file.
+func loadBatchKeyFile(path string) ([]*batchKey, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if content := bytes.TrimSpace(blob); bytes.HasPrefix(content, []byte("{")) {
+		return []*batchKey{{source: path, keyJSON: content}}, nil
+	}
+	var keys []*batchKey
+	for i, line := range strings.Split(string(blob), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(line, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid private key: %v", path, i+1, err)
+		}
+		keys = append(keys, &batchKey{source: fmt.Sprintf("%s:%d", path, i+1), key: key})
+	}
+	return keys, nil
+}
+
+func accountImportBatch(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("Key files or directories must be given as arguments")
+	}
+	keys, err := loadBatchKeys(ctx.Args())
+	if err != nil {
+		utils.Fatalf("Failed to load the keys: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	var (
+		passwords = utils.MakePasswordList(ctx)
+		failed    int
+	)
+	for i, key := range keys {
+		var (
+			acct accounts.Account
+			err  error
+		)
+		if key.keyJSON != nil {
+			// Skip keys of known accounts without an expensive decryption
+			var header struct {
+				Address string `json:"address"`
+			}
+			if json.Unmarshal(key.keyJSON, &header) == nil && common.IsHexAddress(header.Address) && ks.HasAddress(common.HexToAddress(header.Address)) {
+				fmt.Printf("Skipped %s: account {%x} already exists\n", key.source, common.HexToAddress(header.Address))
+				continue
+			}
+			password := getPassPhrase(fmt.Sprintf("Unlocking key %s", key.source), false, i, passwords)
+			acct, err = ks.Import(key.keyJSON, password, password)
+		} else {
+			address := crypto.PubkeyToAddress(key.key.PublicKey)
+			if ks.HasAddress(address) {
+				fmt.Printf("Skipped %s: account {%x} already exists\n", key.source, address)
+				continue
+			}
+			password := getPassPhrase(fmt.Sprintf("Please give a password for key %s. Do not forget this password.", key.source), true, i, passwords)
+			acct, err = ks.ImportECDSA(key.key, password)
+		}
+		if err != nil {
+			fmt.Printf("Failed %s: %v\n", key.source, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Address: {%x}\n", acct.Address)
+	}
+	if failed > 0 {
+		utils.Fatalf("Failed to import %d of %d keys", failed, len(keys))
+	}
+	return nil
+}
+
+func accountExportBatch(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("Export directory must be given as argument")
+	}
+	dir := ctx.Args().First()
+
+	// Assemble the key encrypter requested by the user
+	var encrypt func(key *keystore.Key, password string) ([]byte, error)
+	switch kdf := ctx.String(kdfFlag.Name); kdf {
+	case "scrypt":
+		params := keystore.ScryptParams{N: ctx.Int(kdfScryptNFlag.Name), R: ctx.Int(kdfScryptRFlag.Name), P: ctx.Int(kdfScryptPFlag.Name)}
+		if err := params.Validate(); err != nil {
+			utils.Fatalf("%v", err)
+		}
+		encrypt = func(key *keystore.Key, password string) ([]byte, error) {
+			return keystore.EncryptKeyWithParams(key, password, params)
+		}
+	case "pbkdf2":
+		c := ctx.Int(kdfPBKDF2IterationsFlag.Name)
+		encrypt = func(key *keystore.Key, password string) ([]byte, error) {
+			return keystore.EncryptKeyPBKDF2(key, password, c)
+		}
+	default:
+		utils.Fatalf("Unknown key derivation function %q", kdf)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	accs := ks.Accounts()
+	if addrs := ctx.Args().Tail(); len(addrs) > 0 {
+		accs = accs[:0]
+		for _, addr := range addrs {
+			acct, err := utils.MakeAddress(ks, addr)
+			if err != nil {
+				utils.Fatalf("Could not find account %s: %v", addr, err)
+			}
+			if acct, err = ks.Find(acct); err != nil {
+				utils.Fatalf("Could not find account %s: %v", addr, err)
+			}
+			accs = append(accs, acct)
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		utils.Fatalf("Failed to create export directory: %v", err)
+	}
+	passwords := utils.MakePasswordList(ctx)
+	for i, acct := range accs {
+		keyJSON, err := ioutil.ReadFile(acct.URL.Path)
+		if err != nil {
+			utils.Fatalf("Failed to read key of account %x: %v", acct.Address, err)
+		}
+		password := getPassPhrase(fmt.Sprintf("Unlocking account %x", acct.Address), false, i, passwords)
+		key, err := keystore.DecryptKey(keyJSON, password)
+		if err != nil {
+			utils.Fatalf("Failed to unlock account %x: %v", acct.Address, err)
+		}
+		if keyJSON, err = encrypt(key, password); err != nil {
+			utils.Fatalf("Failed to encrypt key of account %x: %v", acct.Address, err)
+		}
+		path := filepath.Join(dir, filepath.Base(acct.URL.Path))
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			utils.Fatalf("Failed to export account %x: %v", acct.Address, err)
+		}
+		if _, err = file.Write(keyJSON); err == nil {
+			err = file.Close()
+		}
+		if err != nil {
+			utils.Fatalf("Failed to export account %x: %v", acct.Address, err)
+		}
+		fmt.Printf("Exported {%x} to %s\n", acct.Address, path)
+	}
+	return nil
+}
+
+// accountBenchmarkKDF measures the key derivation time of increasingly strong
+// scrypt parameters, suggesting the strongest within the requested limits.
+func accountBenchmarkKDF(ctx *cli.Context) error {
+	r, p := keystore.StandardScryptR, keystore.StandardScryptP
+	if ctx.GlobalIsSet(utils.KeyStoreScryptRFlag.Name) {
+		r = ctx.GlobalInt(utils.KeyStoreScryptRFlag.Name)
+	}
+	if ctx.GlobalIsSet(utils.KeyStoreScryptPFlag.Name) {
+		p = ctx.GlobalInt(utils.KeyStoreScryptPFlag.Name)
+	}
+	var (
+		target = ctx.Duration(kdfTargetTimeFlag.Name)
+		memory = uint64(ctx.Int(kdfMemoryFlag.Name)) * 1024 * 1024
+
+		best    keystore.ScryptParams
+		elapsed time.Duration
+	)
+	fmt.Printf("Benchmarking scrypt with R=%d, P=%d (target %v, memory %d MB)\n\n", r, p, target, memory/1024/1024)
+	for n := 1 << 10; ; n <<= 1 {
+		params := keystore.ScryptParams{N: n, R: r, P: p}
+		if err := params.Validate(); err != nil {
+			if n == 1<<10 {
+				utils.Fatalf("%v", err)
+			}
+			break
+		}
+		needed := 128 * uint64(n) * uint64(r)
+		if needed > memory {
+			break
+		}
+		took, err := params.Benchmark()
+		if err != nil {
+			utils.Fatalf("Failed to benchmark scrypt: %v", err)
+		}
+		fmt.Printf("N=%-10d memory %8.1f MB  unlock %v\n", n, float64(needed)/1024/1024, took)
+		if took > target {
+			break
+		}
+		best, elapsed = params, took
+	}
+	if best.N == 0 {
+		utils.Fatalf("No scrypt parameters unlock an account within %v", target)
+	}
+	fmt.Printf("\nSuggested parameters (unlock in ~%v):\n", elapsed)
+	fmt.Printf("  --keystore.scrypt.n %d --keystore.scrypt.r %d --keystore.scrypt.p %d\n", best.N, best.R, best.P)
+	return nil
+}
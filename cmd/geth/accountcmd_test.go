@@ -294,3 +294,66 @@ Fatal: None of the listed files could be unlocked.
 `)
 	geth.ExpectExit()
 }
+
+func TestAccountImportExportMnemonic(t *testing.T) {
+	datadir := tmpdir(t)
+	mnemonic := filepath.Join(datadir, "mnemonic.txt")
+	if err := ioutil.WriteFile(mnemonic, []byte("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	geth := runGeth(t, "account", "import-mnemonic", "--datadir", datadir, "--lightkdf", mnemonic)
+	geth.Expect(`
+Your new account is locked with a password. Please give a password. Do not forget this password.
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Repeat passphrase: {{.InputLine "foobar"}}
+Address: {9858effd232b4033e47d90003d41ec34ecaeda94}
+`)
+	geth.ExpectExit()
+
+	geth = runGeth(t, "account", "export-mnemonic", "--datadir", datadir, "9858effd232b4033e47d90003d41ec34ecaeda94")
+	defer geth.ExpectExit()
+	geth.Expect(`
+Unlocking account 9858effd232b4033e47d90003d41ec34ecaeda94 | Attempt 1/3
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Mnemonic: abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about
+Path: m/44'/60'/0'/0/0
+`)
+}
+
+func TestAccountImportExportBatch(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	exportdir := filepath.Join(datadir, "export")
+	passwords := filepath.Join(datadir, "passwords.txt")
+	if err := ioutil.WriteFile(passwords, []byte("foobar\nfoobar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	geth := runGeth(t, "account", "export-batch", "--datadir", datadir, "--password", passwords,
+		"--kdf", "pbkdf2", "--kdf.pbkdf2.c", "1024", exportdir, "f466859ead1932d743d622cb74fc058882e8648a")
+	geth.Expect(`
+Exported {f466859ead1932d743d622cb74fc058882e8648a} to ` + filepath.Join(exportdir, "aaa") + `
+`)
+	geth.ExpectExit()
+
+	// Import the exported key along with a raw key list into a fresh keystore
+	keylist := filepath.Join(datadir, "keys.txt")
+	if err := ioutil.WriteFile(keylist, []byte("# test keys\nb71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	importdir := tmpdir(t)
+	geth = runGeth(t, "account", "import-batch", "--datadir", importdir, "--lightkdf", "--password", passwords, exportdir, keylist)
+	geth.Expect(`
+Address: {f466859ead1932d743d622cb74fc058882e8648a}
+Address: {71562b71999873db5b286df957af199ec94617f7}
+`)
+	geth.ExpectExit()
+
+	// Importing the same keys again must skip them
+	geth = runGeth(t, "account", "import-batch", "--datadir", importdir, "--lightkdf", "--password", passwords, exportdir, keylist)
+	defer geth.ExpectExit()
+	geth.Expect(`
+Skipped ` + filepath.Join(exportdir, "aaa") + `: account {f466859ead1932d743d622cb74fc058882e8648a} already exists
+Skipped ` + keylist + `:2: account {71562b71999873db5b286df957af199ec94617f7} already exists
+`)
+}
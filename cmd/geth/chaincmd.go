@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync/atomic"
@@ -29,7 +30,9 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -48,6 +51,7 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DatabaseEngineFlag,
 			utils.LightModeFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
@@ -62,14 +66,18 @@ It expects the genesis file as argument.`,
 		Action:    utils.MigrateFlags(importChain),
 		Name:      "import",
 		Usage:     "Import a blockchain file",
-		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
+		ArgsUsage: "<filename|dirname> (<filename 2> ... <filename N>) ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DatabaseEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 			utils.GCModeFlag,
+			utils.TxLookupLimitFlag,
+			utils.NoTxLookupFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.ChainWorkersFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -77,24 +85,36 @@ The import command imports blocks from an RLP-encoded form. The form can be one
 with several RLP-encoded blocks, or several files can be used.
 
 If only one file is used, import error will result in failure. If several files are used,
-processing will proceed even if an individual RLP-file import failure occurs.`,
+processing will proceed even if an individual RLP-file import failure occurs.
+
+Directories created by a segmented export are imported by verifying the checksums
+in their manifest and decoding the segments with --workers parallel workers.`,
 	}
 	exportCommand = cli.Command{
 		Action:    utils.MigrateFlags(exportChain),
 		Name:      "export",
 		Usage:     "Export blockchain into file",
-		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
+		ArgsUsage: "<filename|dirname> [<blockNumFirst> <blockNumLast>]",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DatabaseEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.ExportSegmentFlag,
+			utils.ExportCompressFlag,
+			utils.ChainWorkersFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing.
+
+If --segment is set, the blocks are instead exported into the given
+directory as segment files of that many blocks each, encoded (and
+with --compress gzipped) by --workers parallel workers, along with
+a manifest holding the checksum of every segment.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -103,6 +123,7 @@ if already existing.`,
 		ArgsUsage: "<datafile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DatabaseEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -117,12 +138,45 @@ if already existing.`,
 		ArgsUsage: "<dumpfile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DatabaseEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The export-preimages command export hash preimages to an RLP encoded stream`,
+	}
+	importTxsCommand = cli.Command{
+		Action:    utils.MigrateFlags(importTransactions),
+		Name:      "import-txs",
+		Usage:     "Import signed transactions from an RLP stream into the local journal",
+		ArgsUsage: "<datafile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.TxPoolJournalFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-txs command appends the signed transactions of an RLP encoded stream
+(as exported by export-txs or admin.exportTransactions) to the local transaction
+journal, injecting them into the transaction pool the next time the node starts.
+The node must not be running while importing.`,
+	}
+	exportTxsCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportTransactions),
+		Name:      "export-txs",
+		Usage:     "Export the local transaction journal into an RLP stream",
+		ArgsUsage: "<dumpfile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.TxPoolJournalFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-txs command exports the signed transactions of the local transaction
+journal to an RLP encoded stream, gzipped if the file name ends in .gz. It is
+meant to migrate the local transactions of a stopped node to another one, use
+admin.exportTransactions to export the transaction pool of a running node.`,
 	}
 	copydbCommand = cli.Command{
 		Action:    utils.MigrateFlags(copyDb),
@@ -131,6 +185,7 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 		ArgsUsage: "<sourceChaindataDir>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DatabaseEngineFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.FakePoWFlag,
@@ -153,6 +208,28 @@ The first argument must be the directory containing the blockchain to download f
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Remove blockchain and state databases`,
+	}
+	pruneStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Remove the historical state from the database of a full node",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DatabaseEngineFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The prune-state command deletes the state trie nodes and contract code which are
+not referenced by the state of the head block, the recent blocks or the genesis
+block, reclaiming the space taken by the historical state of a full node. The
+node must be stopped while pruning, and can no longer serve the pruned states.
+
+The retained states are verified to be complete before anything is deleted, and
+the head state is verified again afterwards. If pruning is interrupted, running
+the command again resumes it.`,
 	}
 	dumpCommand = cli.Command{
 		Action:    utils.MigrateFlags(dump),
@@ -161,6 +238,7 @@ Remove blockchain and state databases`,
 		ArgsUsage: "[<blockHash> | <blockNum>]...",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DatabaseEngineFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
 		},
@@ -231,13 +309,19 @@ func importChain(ctx *cli.Context) error {
 	// Import the chain
 	start := time.Now()
 
+	importer := func(fn string) error {
+		if info, err := os.Stat(fn); err == nil && info.IsDir() {
+			return utils.ImportChainSegments(chain, fn, ctx.GlobalInt(utils.ChainWorkersFlag.Name))
+		}
+		return utils.ImportChain(chain, fn)
+	}
 	if len(ctx.Args()) == 1 {
-		if err := utils.ImportChain(chain, ctx.Args().First()); err != nil {
+		if err := importer(ctx.Args().First()); err != nil {
 			log.Error("Import error", "err", err)
 		}
 	} else {
 		for _, arg := range ctx.Args() {
-			if err := utils.ImportChain(chain, arg); err != nil {
+			if err := importer(arg); err != nil {
 				log.Error("Import error", "file", arg, "err", err)
 			}
 		}
@@ -246,7 +330,7 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*ethdb.LDBDatabase)
+	db := rawdb.KeyValueStore(chainDb).(*ethdb.LDBDatabase)
 
 	stats, err := db.LDB().GetProperty("leveldb.stats")
 	if err != nil {
@@ -309,8 +393,13 @@ func exportChain(ctx *cli.Context) error {
 
 	var err error
 	fp := ctx.Args().First()
+	segment := ctx.GlobalUint64(utils.ExportSegmentFlag.Name)
 	if len(ctx.Args()) < 3 {
-		err = utils.ExportChain(chain, fp)
+		if segment > 0 {
+			err = utils.ExportChainSegments(chain, fp, 0, chain.CurrentBlock().NumberU64(), segment, ctx.GlobalInt(utils.ChainWorkersFlag.Name), ctx.GlobalBool(utils.ExportCompressFlag.Name))
+		} else {
+			err = utils.ExportChain(chain, fp)
+		}
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
 		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
@@ -321,7 +410,11 @@ func exportChain(ctx *cli.Context) error {
 		if first < 0 || last < 0 {
 			utils.Fatalf("Export error: block number must be greater than 0\n")
 		}
-		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		if segment > 0 {
+			err = utils.ExportChainSegments(chain, fp, uint64(first), uint64(last), segment, ctx.GlobalInt(utils.ChainWorkersFlag.Name), ctx.GlobalBool(utils.ExportCompressFlag.Name))
+		} else {
+			err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		}
 	}
 
 	if err != nil {
@@ -337,7 +430,7 @@ func importPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := rawdb.KeyValueStore(utils.MakeChainDatabase(ctx, stack)).(*ethdb.LDBDatabase)
 
 	start := time.Now()
 	if err := utils.ImportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -353,7 +446,7 @@ func exportPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*ethdb.LDBDatabase)
+	diskdb := rawdb.KeyValueStore(utils.MakeChainDatabase(ctx, stack)).(*ethdb.LDBDatabase)
 
 	start := time.Now()
 	if err := utils.ExportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -363,6 +456,80 @@ func exportPreimages(ctx *cli.Context) error {
 	return nil
 }
 
+// txJournalPath resolves the location of the local transaction journal.
+func txJournalPath(ctx *cli.Context) string {
+	stack, cfg := makeConfigNode(ctx)
+	if cfg.Eth.TxPool.Journal == "" {
+		utils.Fatalf("The transaction journal is disabled")
+	}
+	return stack.ResolvePath(cfg.Eth.TxPool.Journal)
+}
+
+// uniqueTransactions drops the duplicates from a list of transactions, retaining
+// the order of their first occurrences.
+func uniqueTransactions(txs types.Transactions) types.Transactions {
+	seen := make(map[common.Hash]bool)
+	unique := make(types.Transactions, 0, len(txs))
+	for _, tx := range txs {
+		if !seen[tx.Hash()] {
+			seen[tx.Hash()] = true
+			unique = append(unique, tx)
+		}
+	}
+	return unique
+}
+
+// importTransactions merges the transactions of the specified file into the
+// local transaction journal.
+func importTransactions(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	journal := txJournalPath(ctx)
+
+	txs, err := utils.ImportTransactions(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Import error: %v", err)
+	}
+	var existing types.Transactions
+	if common.FileExist(journal) {
+		if existing, err = utils.ImportTransactions(journal); err != nil {
+			utils.Fatalf("Failed to read transaction journal: %v", err)
+		}
+	}
+	existing = uniqueTransactions(existing)
+	merged := uniqueTransactions(append(existing, txs...))
+	if err := os.MkdirAll(filepath.Dir(journal), 0700); err != nil {
+		utils.Fatalf("Failed to create instance directory: %v", err)
+	}
+	if err := utils.ExportTransactions(merged, journal); err != nil {
+		utils.Fatalf("Failed to write transaction journal: %v", err)
+	}
+	fmt.Printf("Imported %d transactions, %d in journal\n", len(merged)-len(existing), len(merged))
+	return nil
+}
+
+// exportTransactions dumps the transactions of the local journal into the
+// specified file.
+func exportTransactions(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	var txs types.Transactions
+	if journal := txJournalPath(ctx); common.FileExist(journal) {
+		var err error
+		if txs, err = utils.ImportTransactions(journal); err != nil {
+			utils.Fatalf("Failed to read transaction journal: %v", err)
+		}
+	}
+	txs = uniqueTransactions(txs)
+	if err := utils.ExportTransactions(txs, ctx.Args().First()); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Exported %d transactions\n", len(txs))
+	return nil
+}
+
 func copyDb(ctx *cli.Context) error {
 	// Ensure we have a source chain directory to copy
 	if len(ctx.Args()) != 1 {
@@ -376,7 +543,7 @@ func copyDb(ctx *cli.Context) error {
 	dl := downloader.New(syncmode, chainDb, new(event.TypeMux), chain, nil, nil)
 
 	// Create a source peer to satisfy downloader requests from
-	db, err := ethdb.NewLDBDatabase(ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name), 256)
+	db, err := ethdb.Open("", ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name), 256)
 	if err != nil {
 		return err
 	}
@@ -403,7 +570,7 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	if err = rawdb.KeyValueStore(chainDb).(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -466,6 +633,25 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+// pruneState deletes the historical state from the chain database.
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	pruner, err := pruner.New(chainDb, stack.ResolvePath("prunestate"))
+	if err != nil {
+		utils.Fatalf("Failed to create state pruner: %v", err)
+	}
+	start := time.Now()
+	if err := pruner.Prune(); err != nil {
+		utils.Fatalf("State pruning failed: %v", err)
+	}
+	fmt.Printf("State pruning done in %v\n", time.Since(start))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	bzzapi "github.com/ethereum/go-ethereum/swarm/api"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
 	"github.com/naoina/toml"
 )
@@ -42,7 +43,7 @@ var (
 		Name:        "dumpconfig",
 		Usage:       "Show configuration values",
 		ArgsUsage:   "",
-		Flags:       append(append(nodeFlags, rpcFlags...), whisperFlags...),
+		Flags:       append(append(append(nodeFlags, rpcFlags...), whisperFlags...), swarmFlags...),
 		Category:    "MISCELLANEOUS COMMANDS",
 		Description: `The dumpconfig command shows configuration values.`,
 	}
@@ -80,6 +81,7 @@ type gethConfig struct {
 	Node      node.Config
 	Ethstats  ethstatsConfig
 	Dashboard dashboard.Config
+	Swarm     bzzapi.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -114,6 +116,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 		Shh:       whisper.DefaultConfig,
 		Node:      defaultNodeConfig(),
 		Dashboard: dashboard.DefaultConfig,
+		Swarm:     *bzzapi.NewConfig(),
 	}
 
 	// Load config file.
@@ -136,6 +139,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
 	utils.SetDashboardConfig(ctx, &cfg.Dashboard)
+	setSwarmConfig(ctx, &cfg)
 
 	return stack, cfg
 }
@@ -169,6 +173,19 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 			cfg.Shh.MinimumAcceptedPOW = ctx.Float64(utils.WhisperMinPOWFlag.Name)
 		}
 		utils.RegisterShhService(stack, &cfg.Shh)
+		if ctx.GlobalBool(utils.WhisperMailServerFlag.Name) {
+			utils.RegisterShhMailServerService(stack, ctx, &cfg.Shh)
+		}
+	}
+
+	// Add the GraphQL server if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack, ctx)
+	}
+
+	// Add the Swarm service if requested, sharing the node's account and p2p server.
+	if ctx.GlobalBool(swarmEnabledFlag.Name) {
+		registerSwarmService(ctx, stack, &cfg)
 	}
 
 	// Add the Ethereum Stats daemon if requested.
@@ -56,8 +56,11 @@ var (
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.DatabaseEngineFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
+		utils.USBPathsFlag,
 		utils.DashboardEnabledFlag,
 		utils.DashboardAddrFlag,
 		utils.DashboardPortFlag,
@@ -73,6 +76,7 @@ var (
 		utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
+		utils.TxPoolMaxTxSizeFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
@@ -81,10 +85,22 @@ var (
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
+		utils.DownloaderStateRangesFlag,
+		utils.DownloaderTargetRTTFlag,
+		utils.DownloaderThroughputImpactFlag,
+		utils.DownloaderMinThroughputFlag,
 		utils.GCModeFlag,
+		utils.LogIndexFlag,
+		utils.TxLookupLimitFlag,
+		utils.NoTxLookupFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.ULCServersFlag,
+		utils.ULCFractionFlag,
 		utils.LightKDFFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptRFlag,
+		utils.KeyStoreScryptPFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
@@ -111,12 +127,20 @@ var (
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCTokensFlag,
+		utils.RPCLimitsFlag,
+		utils.RPCReadTimeoutFlag,
+		utils.RPCWriteTimeoutFlag,
+		utils.RPCIdleTimeoutFlag,
+		utils.RPCNoKeepAliveFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoMaxGasPriceFlag,
 		utils.ExtraDataFlag,
 		configFileFlag,
 	}
@@ -130,7 +154,14 @@ var (
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
+		utils.WSSubscriptionBufferFlag,
+		utils.WSSubscriptionOverflowFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLListenAddrFlag,
+		utils.GraphQLPortFlag,
+		utils.GraphQLCORSDomainFlag,
+		utils.GraphQLVirtualHostsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -139,6 +170,9 @@ var (
 		utils.WhisperEnabledFlag,
 		utils.WhisperMaxMessageSizeFlag,
 		utils.WhisperMinPOWFlag,
+		utils.WhisperMailServerFlag,
+		utils.WhisperMailServerPasswordFlag,
+		utils.WhisperMailServerRetentionFlag,
 	}
 )
 
@@ -154,8 +188,11 @@ func init() {
 		exportCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
+		importTxsCommand,
+		exportTxsCommand,
 		copydbCommand,
 		removedbCommand,
+		pruneStateCommand,
 		dumpCommand,
 		// See monitorcmd.go:
 		monitorCommand,
@@ -182,6 +219,7 @@ func init() {
 	app.Flags = append(app.Flags, consoleFlags...)
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, whisperFlags...)
+	app.Flags = append(app.Flags, swarmFlags...)
 
 	app.Before = func(ctx *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -267,11 +305,7 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 				status, _ := event.Wallet.Status()
 				log.Info("New wallet appeared", "url", event.Wallet.URL(), "status", status)
 
-				if event.Wallet.URL().Scheme == "ledger" {
-					event.Wallet.SelfDerive(accounts.DefaultLedgerBaseDerivationPath, stateReader)
-				} else {
-					event.Wallet.SelfDerive(accounts.DefaultBaseDerivationPath, stateReader)
-				}
+				event.Wallet.SelfDerive(utils.MakeDerivationPaths(ctx, event.Wallet.URL().Scheme), stateReader)
 
 			case accounts.WalletDropped:
 				log.Info("Old wallet dropped", "url", event.Wallet.URL())
@@ -0,0 +1,153 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/ens"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/les"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/swarm"
+	bzzapi "github.com/ethereum/go-ethereum/swarm/api"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	swarmEnabledFlag = cli.BoolFlag{
+		Name:  "swarm",
+		Usage: "Enable the Swarm service inside the node",
+	}
+	swarmAccountFlag = cli.StringFlag{
+		Name:  "swarm.account",
+		Usage: "Keystore account the Swarm node is identified with (default = etherbase or first account)",
+	}
+	swarmPortFlag = cli.StringFlag{
+		Name:  "swarm.port",
+		Usage: "Swarm HTTP gateway listening port",
+		Value: bzzapi.DefaultHTTPPort,
+	}
+
+	swarmFlags = []cli.Flag{
+		swarmEnabledFlag,
+		swarmAccountFlag,
+		swarmPortFlag,
+	}
+)
+
+// setSwarmConfig applies the swarm related command line flags to the config.
+func setSwarmConfig(ctx *cli.Context, cfg *gethConfig) {
+	if ctx.GlobalIsSet(swarmAccountFlag.Name) {
+		cfg.Swarm.BzzAccount = ctx.GlobalString(swarmAccountFlag.Name)
+	}
+	if ctx.GlobalIsSet(swarmPortFlag.Name) {
+		cfg.Swarm.Port = ctx.GlobalString(swarmPortFlag.Name)
+	}
+	// Resolve names against the ENS deployment of the chain being followed
+	if cfg.Eth.NetworkId == params.MainnetChainConfig.ChainId.Uint64() && cfg.Swarm.EnsRoot == ens.TestNetAddress {
+		cfg.Swarm.EnsRoot = ens.MainNetAddress
+	}
+}
+
+// registerSwarmService unlocks the swarm account from the node keystore and adds
+// a swarm service to the node, sharing its p2p server and resolving ENS names
+// through the Ethereum service of the node.
+func registerSwarmService(ctx *cli.Context, stack *node.Node, cfg *gethConfig) {
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	address := cfg.Swarm.BzzAccount
+	if address == "" {
+		if cfg.Eth.Etherbase != (common.Address{}) {
+			address = cfg.Eth.Etherbase.Hex()
+		} else if accs := ks.Accounts(); len(accs) > 0 {
+			address = accs[0].Address.Hex()
+		} else {
+			utils.Fatalf("Swarm requires an account, create one with `geth account new` or set --%s", swarmAccountFlag.Name)
+		}
+	}
+	key := decryptSwarmKey(ks, address, utils.MakePasswordList(ctx))
+
+	config := &cfg.Swarm
+	config.BzzAccount = address
+	config.Path = stack.InstanceDir()
+	config.Init(key)
+
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Resolve ENS names through the eth service, or the les service in light mode
+		var ethServ node.Service
+		var fullServ *eth.Ethereum
+		if err := ctx.Service(&fullServ); err == nil {
+			ethServ = fullServ
+		} else {
+			var lightServ *les.LightEthereum
+			if err := ctx.Service(&lightServ); err != nil {
+				return nil, errors.New("no Ethereum service")
+			}
+			ethServ = lightServ
+		}
+		server := rpc.NewServer()
+		for _, api := range ethServ.APIs() {
+			if api.Namespace != "eth" {
+				continue
+			}
+			if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+				return nil, err
+			}
+		}
+		return swarm.NewEmbeddedSwarm(config, rpc.DialInProc(server))
+	}); err != nil {
+		utils.Fatalf("Failed to register the Swarm service: %v", err)
+	}
+}
+
+// decryptSwarmKey retrieves the private key of the swarm account from the
+// keystore, without unlocking the account for the rest of the node.
+func decryptSwarmKey(ks *keystore.KeyStore, address string, passwords []string) *ecdsa.PrivateKey {
+	account, err := utils.MakeAddress(ks, address)
+	if err != nil {
+		utils.Fatalf("Option %q: %v", swarmAccountFlag.Name, err)
+	}
+	account, err = ks.Find(accounts.Account{Address: account.Address})
+	if err != nil {
+		utils.Fatalf("Can't find swarm account %s: %v", address, err)
+	}
+	keyjson, err := ioutil.ReadFile(account.URL.Path)
+	if err != nil {
+		utils.Fatalf("Can't load swarm account key: %v", err)
+	}
+	for trials := 0; trials < 3; trials++ {
+		prompt := fmt.Sprintf("Unlocking swarm account %s | Attempt %d/%d", account.Address.Hex(), trials+1, 3)
+		key, err := keystore.DecryptKey(keyjson, getPassPhrase(prompt, false, 0, passwords))
+		if err == nil {
+			log.Info("Unlocked swarm account", "address", account.Address.Hex())
+			return key.PrivateKey
+		}
+	}
+	utils.Fatalf("Failed to decrypt swarm account key %s", account.Address.Hex())
+	return nil
+}
@@ -69,18 +69,33 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DatabaseEngineFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
+			utils.USBPathsFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.RinkebyFlag,
 			utils.SyncModeFlag,
+			utils.DownloaderStateRangesFlag,
+			utils.DownloaderTargetRTTFlag,
+			utils.DownloaderThroughputImpactFlag,
+			utils.DownloaderMinThroughputFlag,
 			utils.GCModeFlag,
+			utils.LogIndexFlag,
+			utils.TxLookupLimitFlag,
+			utils.NoTxLookupFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.ULCServersFlag,
+			utils.ULCFractionFlag,
 			utils.LightKDFFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptRFlag,
+			utils.KeyStoreScryptPFlag,
 		},
 	},
 	{Name: "DEVELOPER CHAIN",
@@ -118,6 +133,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolRejournalFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
+			utils.TxPoolMaxTxSizeFlag,
 			utils.TxPoolAccountSlotsFlag,
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
@@ -152,11 +168,25 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
+			utils.WSSubscriptionBufferFlag,
+			utils.WSSubscriptionOverflowFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLListenAddrFlag,
+			utils.GraphQLPortFlag,
+			utils.GraphQLCORSDomainFlag,
+			utils.GraphQLVirtualHostsFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCTokensFlag,
+			utils.RPCLimitsFlag,
+			utils.RPCReadTimeoutFlag,
+			utils.RPCWriteTimeoutFlag,
+			utils.RPCIdleTimeoutFlag,
+			utils.RPCNoKeepAliveFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -195,6 +225,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoMaxGasPriceFlag,
 		},
 	},
 	{
@@ -215,6 +246,10 @@ var AppHelpFlagGroups = []flagGroup{
 		Name:  "WHISPER (EXPERIMENTAL)",
 		Flags: whisperFlags,
 	},
+	{
+		Name:  "SWARM",
+		Flags: swarmFlags,
+	},
 	{
 		Name: "DEPRECATED",
 		Flags: []cli.Flag{
@@ -0,0 +1,151 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func accessNew(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		utils.Fatalf("Usage: swarm access new <ref> [<grantee-pubkey>...]")
+	}
+	ref, err := hex.DecodeString(args[0])
+	if err != nil || (len(ref) != storage.KeyLength && len(ref) != 2*storage.KeyLength) {
+		utils.Fatalf("Invalid reference %s", args[0])
+	}
+	m, err := api.NewAccessManifest(loadAccountKey(ctx), ref, parseGrantees(args[1:]))
+	if err != nil {
+		utils.Fatalf("Error creating access manifest: %v", err)
+	}
+	uploadAccessManifest(ctx, m)
+}
+
+func accessGrant(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		utils.Fatalf("Usage: swarm access grant <access-manifest> <grantee-pubkey>...")
+	}
+	m := downloadAccessManifest(ctx, args[0])
+	if err := api.GrantAccess(m, loadAccountKey(ctx), parseGrantees(args[1:])); err != nil {
+		utils.Fatalf("Error granting access: %v", err)
+	}
+	uploadAccessManifest(ctx, m)
+}
+
+func accessRevoke(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		utils.Fatalf("Usage: swarm access revoke <access-manifest> <grantee-pubkey>...")
+	}
+	m := downloadAccessManifest(ctx, args[0])
+	if err := api.RevokeAccess(m, loadAccountKey(ctx), parseGrantees(args[1:])); err != nil {
+		utils.Fatalf("Error revoking access: %v", err)
+	}
+	uploadAccessManifest(ctx, m)
+}
+
+func accessResolve(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm access resolve <access-manifest>")
+	}
+	ref, err := api.ResolveAccess(downloadAccessManifest(ctx, args[0]), loadAccountKey(ctx))
+	if err != nil {
+		utils.Fatalf("Error resolving access manifest: %v", err)
+	}
+	fmt.Println(hex.EncodeToString(ref))
+}
+
+func accessPubkey(ctx *cli.Context) {
+	fmt.Println(hexutil.Encode(crypto.CompressPubkey(&loadAccountKey(ctx).PublicKey)))
+}
+
+func downloadAccessManifest(ctx *cli.Context, hash string) *api.Manifest {
+	m, _, err := accessClient(ctx).DownloadManifest(hash)
+	if err != nil {
+		utils.Fatalf("Error downloading access manifest: %v", err)
+	}
+	return m
+}
+
+func uploadAccessManifest(ctx *cli.Context, m *api.Manifest) {
+	hash, err := accessClient(ctx).UploadManifest(m, false)
+	if err != nil {
+		utils.Fatalf("Error uploading access manifest: %v", err)
+	}
+	addHistory(ctx, hash)
+	fmt.Println(hash)
+}
+
+func accessClient(ctx *cli.Context) *swarm.Client {
+	return swarm.NewClient(strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/"))
+}
+
+// parseGrantees parses hex encoded compressed or uncompressed public keys
+func parseGrantees(args []string) []*ecdsa.PublicKey {
+	var grantees []*ecdsa.PublicKey
+	for _, arg := range args {
+		data, err := hex.DecodeString(strings.TrimPrefix(arg, "0x"))
+		if err != nil {
+			utils.Fatalf("Invalid public key %s: %v", arg, err)
+		}
+		var key *ecdsa.PublicKey
+		if len(data) == 33 {
+			key, err = crypto.DecompressPubkey(data)
+		} else if key = crypto.ToECDSAPub(data); key == nil {
+			err = fmt.Errorf("invalid length %d", len(data))
+		}
+		if err != nil {
+			utils.Fatalf("Invalid public key %s: %v", arg, err)
+		}
+		grantees = append(grantees, key)
+	}
+	return grantees
+}
+
+// loadAccountKey returns the private key of the bzzaccount, which is either
+// a hex key file or an account of the keystore
+func loadAccountKey(ctx *cli.Context) *ecdsa.PrivateKey {
+	bzzaccount := ctx.GlobalString(SwarmAccountFlag.Name)
+	if bzzaccount == "" {
+		utils.Fatalf(SWARM_ERR_NO_BZZACCOUNT)
+	}
+	if key, err := crypto.LoadECDSA(bzzaccount); err == nil {
+		return key
+	}
+	keydir := ctx.GlobalString(utils.KeyStoreDirFlag.Name)
+	if keydir == "" {
+		keydir = filepath.Join(utils.MakeDataDir(ctx), "keystore")
+	}
+	ks := keystore.NewKeyStore(keydir, keystore.StandardScryptN, keystore.StandardScryptP)
+	return decryptStoreAccount(ks, bzzaccount, utils.MakePasswordList(ctx))
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+)
+
+// TestCLISwarmAccess tests sharing an encrypted upload with 'swarm access'
+func TestCLISwarmAccess(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-access-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFiles := make(map[string]string)
+	for _, name := range []string{"publisher", "alice", "bob"} {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyFiles[name] = filepath.Join(dir, name+".key")
+		if err := crypto.SaveECDSA(keyFiles[name], key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := swarm.NewClient(cluster.Nodes[0].URL)
+	data := []byte("private data")
+	ref, err := client.Upload(&swarm.File{
+		ReadCloser:    ioutil.NopCloser(bytes.NewReader(data)),
+		ManifestEntry: api.ManifestEntry{ContentType: "text/plain", Size: int64(len(data))},
+	}, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access := func(account string, args ...string) string {
+		args = append([]string{"--bzzapi", cluster.Nodes[0].URL, "--bzzaccount", keyFiles[account], "access"}, args...)
+		cmd := runSwarm(t, args...)
+		_, matches := cmd.ExpectRegexp(`(?:0x)?[a-f\d]{64,}`)
+		cmd.ExpectExit()
+		return matches[0]
+	}
+	checkDenied := func(account, manifest string) {
+		cmd := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "--bzzaccount", keyFiles[account], "access", "resolve", manifest)
+		cmd.ExpectRegexp("Fatal: Error resolving access manifest: no access granted to this key\n")
+		cmd.ExpectExit()
+	}
+	alice, bob := access("alice", "pubkey"), access("bob", "pubkey")
+
+	manifest := access("publisher", "new", ref, alice)
+	if resolved := access("alice", "resolve", manifest); resolved != ref {
+		t.Fatalf("expected alice to resolve %s, got %s", ref, resolved)
+	}
+	checkDenied("bob", manifest)
+
+	manifest = access("publisher", "grant", manifest, bob)
+	if resolved := access("bob", "resolve", manifest); resolved != ref {
+		t.Fatalf("expected bob to resolve %s, got %s", ref, resolved)
+	}
+
+	manifest = access("publisher", "revoke", manifest, alice)
+	checkDenied("alice", manifest)
+	if resolved := access("bob", "resolve", manifest); resolved != ref {
+		t.Fatalf("expected bob to resolve %s, got %s", ref, resolved)
+	}
+
+	// the resolved reference gives access to the content
+	file, err := client.Download(access("bob", "resolve", manifest), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if got, err := ioutil.ReadAll(file); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("expected %q, got %q (%v)", data, got, err)
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func completion(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout, app)
+	case "zsh":
+		writeZshCompletion(os.Stdout, app)
+	case "fish":
+		writeFishCompletion(os.Stdout, app)
+	case "hashes":
+		// used by the completion scripts
+		for _, hash := range readHistory(ctx) {
+			fmt.Println(hash)
+		}
+	default:
+		utils.Fatalf("Unsupported shell %s, expected bash, zsh or fish", args[0])
+	}
+}
+
+// completionCommand is a command of the CLI with the names of its options
+type completionCommand struct {
+	name        string
+	usage       string
+	flags       []string
+	hashes      bool // whether the arguments are hashes or manifests
+	subcommands []*completionCommand
+}
+
+// completionSpec is the description of the CLI the completion scripts are
+// generated from
+type completionSpec struct {
+	flags      []string // global options
+	valueFlags []string // options taking a value
+	commands   []*completionCommand
+}
+
+func newCompletionSpec(app *cli.App) *completionSpec {
+	spec := &completionSpec{}
+	values := make(map[string]bool)
+	var addFlags func(flags []cli.Flag) []string
+	addFlags = func(flags []cli.Flag) []string {
+		var names []string
+		for _, flag := range flags {
+			_, isBool := flag.(cli.BoolFlag)
+			_, isBoolT := flag.(cli.BoolTFlag)
+			for _, name := range strings.Split(flag.GetName(), ",") {
+				name = strings.TrimSpace(name)
+				if len(name) == 1 {
+					name = "-" + name
+				} else {
+					name = "--" + name
+				}
+				names = append(names, name)
+				if !isBool && !isBoolT {
+					values[name] = true
+				}
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+	var addCommands func(commands []cli.Command) []*completionCommand
+	addCommands = func(commands []cli.Command) []*completionCommand {
+		var result []*completionCommand
+		for _, cmd := range commands {
+			if cmd.Hidden {
+				continue
+			}
+			result = append(result, &completionCommand{
+				name:        cmd.Name,
+				usage:       cmd.Usage,
+				flags:       addFlags(cmd.Flags),
+				hashes:      takesHashes(cmd.ArgsUsage),
+				subcommands: addCommands(cmd.Subcommands),
+			})
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+		return result
+	}
+	spec.flags = addFlags(app.Flags)
+	spec.commands = addCommands(app.Commands)
+	for name := range values {
+		spec.valueFlags = append(spec.valueFlags, name)
+	}
+	sort.Strings(spec.valueFlags)
+	return spec
+}
+
+// takesHashes reports whether the usage of the arguments of a command names
+// hashes or manifests
+func takesHashes(argsUsage string) bool {
+	argsUsage = strings.ToLower(argsUsage)
+	for _, arg := range []string{"hash", "manifest", "uri", "ref"} {
+		if strings.Contains(argsUsage, arg) {
+			return true
+		}
+	}
+	return false
+}
+
+func (spec *completionSpec) commandNames() string {
+	var names []string
+	for _, cmd := range spec.commands {
+		names = append(names, cmd.name)
+	}
+	return strings.Join(names, " ")
+}
+
+func (cmd *completionCommand) subcommandNames() string {
+	var names []string
+	for _, sub := range cmd.subcommands {
+		names = append(names, sub.name)
+	}
+	return strings.Join(names, " ")
+}
+
+// writeShellCases writes the cases of a bash or zsh case statement on
+// "$cmd $sub" setting the options and the subcommands of each command, and
+// whether hashes from the history complete its arguments
+func writeShellCases(w io.Writer, spec *completionSpec, indent string) {
+	writeCase := func(pattern string, flags []string, cmds string, hashes bool) {
+		fmt.Fprintf(w, "%s%s) opts=\"%s\"", indent, pattern, strings.Join(flags, " "))
+		if cmds != "" {
+			fmt.Fprintf(w, " cmds=\"%s\"", cmds)
+		}
+		if hashes {
+			fmt.Fprint(w, " hashes=1")
+		}
+		fmt.Fprintln(w, " ;;")
+	}
+	for _, cmd := range spec.commands {
+		if len(cmd.subcommands) == 0 {
+			writeCase(fmt.Sprintf(`"%s "*`, cmd.name), cmd.flags, "", cmd.hashes)
+			continue
+		}
+		for _, sub := range cmd.subcommands {
+			writeCase(fmt.Sprintf(`"%s %s"`, cmd.name, sub.name), sub.flags, "", sub.hashes)
+		}
+		writeCase(fmt.Sprintf(`"%s "`, cmd.name), cmd.flags, cmd.subcommandNames(), false)
+	}
+	writeCase(`" "`, spec.flags, spec.commandNames(), false)
+}
+
+// completionArgsLoop is the bash and zsh loop finding the command and
+// subcommand on the command line, skipping options and their values
+const completionArgsLoop = `		word=%s
+		if [[ -n $skip ]]; then
+			skip=""
+			continue
+		fi
+		case "$word" in
+		%s) skip=1 ;;
+		-*) ;;
+		*)
+			if [[ -z $cmd ]]; then
+				cmd=$word
+			elif [[ -z $sub ]]; then
+				sub=$word
+			fi
+			;;
+		esac
+`
+
+func writeBashCompletion(w io.Writer, app *cli.App) {
+	spec := newCompletionSpec(app)
+	buf := new(bytes.Buffer)
+	fmt.Fprint(buf, `# bash completion for swarm, generated by 'swarm completion bash'
+# load it with: source <(swarm completion bash)
+
+_swarm() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" cmd="" sub="" skip="" word="" opts="" cmds="" hashes="" i
+	for ((i = 1; i < COMP_CWORD; i++)); do
+`)
+	fmt.Fprintf(buf, completionArgsLoop, `"${COMP_WORDS[i]}"`, strings.Join(spec.valueFlags, "|"))
+	fmt.Fprint(buf, `	done
+	if [[ -n $skip ]]; then
+		# the value of an option
+		return
+	fi
+	case "$cmd $sub" in
+`)
+	writeShellCases(buf, spec, "\t")
+	fmt.Fprint(buf, `	esac
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "$opts" -- "$cur"))
+		return
+	fi
+	if [[ -n $hashes ]]; then
+		cmds="$(swarm completion hashes 2>/dev/null)"
+	fi
+	# complete file names if nothing matches
+	COMPREPLY=($(compgen -W "$cmds" -- "$cur"))
+}
+
+complete -o default -F _swarm swarm
+`)
+	w.Write(buf.Bytes())
+}
+
+func writeZshCompletion(w io.Writer, app *cli.App) {
+	spec := newCompletionSpec(app)
+	buf := new(bytes.Buffer)
+	fmt.Fprint(buf, `#compdef swarm
+# zsh completion for swarm, generated by 'swarm completion zsh'
+# load it with: source <(swarm completion zsh)
+
+_swarm() {
+	local cmd="" sub="" skip="" word="" opts="" cmds="" hashes="" i
+	for ((i = 2; i < CURRENT; i++)); do
+`)
+	fmt.Fprintf(buf, completionArgsLoop, `"${words[i]}"`, strings.Join(spec.valueFlags, "|"))
+	fmt.Fprint(buf, `	done
+	if [[ -n $skip ]]; then
+		_files
+		return
+	fi
+	local -a candidates
+	case "$cmd $sub" in
+`)
+	writeShellCases(buf, spec, "\t")
+	fmt.Fprint(buf, `	esac
+	if [[ $PREFIX == -* ]]; then
+		candidates=(${=opts})
+		compadd -a candidates
+		return
+	fi
+	candidates=(${=cmds})
+	if [[ -n $hashes ]]; then
+		candidates=($(swarm completion hashes 2>/dev/null))
+	fi
+	compadd -a candidates
+	if [[ -z $cmds ]]; then
+		_files
+	fi
+}
+
+compdef _swarm swarm
+`)
+	w.Write(buf.Bytes())
+}
+
+func writeFishCompletion(w io.Writer, app *cli.App) {
+	spec := newCompletionSpec(app)
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, `# fish completion for swarm, generated by 'swarm completion fish'
+# load it with: swarm completion fish | source
+
+# __swarm_args prints the commands and arguments on the command line,
+# skipping options and their values
+function __swarm_args
+	set -l tokens (commandline -opc)
+	set -e tokens[1]
+	set -l skip 0
+	for token in $tokens
+		if test $skip = 1
+			set skip 0
+			continue
+		end
+		switch $token
+			case %s
+				set skip 1
+			case '-*'
+			case '*'
+				echo $token
+		end
+	end
+end
+
+function __swarm_needs_command
+	test (count (__swarm_args)) -eq 0
+end
+
+function __swarm_needs_subcommand
+	set -l args (__swarm_args)
+	test (count $args) -eq 1; and test "$args[1]" = "$argv[1]"
+end
+
+# __swarm_using succeeds if the command line starts with the given command
+# and subcommand
+function __swarm_using
+	set -l args (__swarm_args)
+	test (count $args) -ge (count $argv); or return 1
+	for i in (seq (count $argv))
+		test "$args[$i]" = "$argv[$i]"; or return 1
+	end
+end
+
+`, strings.Join(spec.valueFlags, " "))
+
+	valueFlags := make(map[string]bool)
+	for _, name := range spec.valueFlags {
+		valueFlags[name] = true
+	}
+	writeFlags := func(cond string, flags []string) {
+		for _, name := range flags {
+			opt := "-l " + strings.TrimPrefix(name, "--")
+			if !strings.HasPrefix(name, "--") {
+				opt = "-s " + strings.TrimPrefix(name, "-")
+			}
+			if valueFlags[name] {
+				opt += " -r"
+			}
+			fmt.Fprintf(buf, "complete -c swarm -n %s %s\n", fishQuote(cond), opt)
+		}
+	}
+	writeFlags("__swarm_needs_command", spec.flags)
+	for _, cmd := range spec.commands {
+		fmt.Fprintf(buf, "complete -c swarm -f -n __swarm_needs_command -a %s -d %s\n", cmd.name, fishQuote(cmd.usage))
+	}
+	for _, cmd := range spec.commands {
+		fmt.Fprintf(buf, "\n# %s\n", cmd.name)
+		cond := "__swarm_using " + cmd.name
+		if len(cmd.subcommands) == 0 {
+			writeFlags(cond, cmd.flags)
+			if cmd.hashes {
+				fmt.Fprintf(buf, "complete -c swarm -n %s -a '(swarm completion hashes 2>/dev/null)'\n", fishQuote(cond))
+			}
+			continue
+		}
+		writeFlags("__swarm_needs_subcommand "+cmd.name, cmd.flags)
+		for _, sub := range cmd.subcommands {
+			fmt.Fprintf(buf, "complete -c swarm -f -n %s -a %s -d %s\n", fishQuote("__swarm_needs_subcommand "+cmd.name), sub.name, fishQuote(sub.usage))
+		}
+		for _, sub := range cmd.subcommands {
+			subCond := cond + " " + sub.name
+			writeFlags(subCond, sub.flags)
+			if sub.hashes {
+				fmt.Fprintf(buf, "complete -c swarm -n %s -a '(swarm completion hashes 2>/dev/null)'\n", fishQuote(subCond))
+			}
+		}
+	}
+	w.Write(buf.Bytes())
+}
+
+// fishQuote quotes s as a single quoted fish string
+func fishQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
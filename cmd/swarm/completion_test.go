@@ -0,0 +1,114 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCLISwarmCompletionHashes tests that the hashes returned by 'swarm up'
+// are kept in the history listed by 'swarm completion hashes', except for the
+// encrypted references which hold the decryption key
+func TestCLISwarmCompletionHashes(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-completion-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	datadir := filepath.Join(dir, "data")
+
+	up := func(name string, args ...string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args = append([]string{"--bzzapi", cluster.Nodes[0].URL, "--datadir", datadir, "up"}, args...)
+		cmd := runSwarm(t, append(args, path)...)
+		_, matches := cmd.ExpectRegexp(`[a-f\d]{64,128}`)
+		cmd.ExpectExit()
+		return matches[0]
+	}
+	first := up("first.txt")
+	second := up("second.txt")
+	if up("first.txt") != first {
+		t.Fatal("expected the same hash uploading the same file")
+	}
+	if encrypted := up("encrypted.txt", "--encrypt"); len(encrypted) != 128 {
+		t.Fatalf("expected an encrypted reference, got %s", encrypted)
+	}
+
+	// the most recent hash is listed first and hashes are not repeated
+	cmd := runSwarm(t, "--datadir", datadir, "completion", "hashes")
+	cmd.ExpectRegexp("^" + first + "\n" + second + "\n")
+	cmd.ExpectExit()
+}
+
+// TestBashCompletion tests the completions of the bash completion script
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	script := new(bytes.Buffer)
+	writeBashCompletion(script, app)
+	// the history is listed by a stub of the swarm command
+	script.WriteString(`
+swarm() { printf 'aaaa\nbbbb\n'; }
+complete_line() {
+	COMP_WORDS=("$@")
+	COMP_CWORD=$((${#COMP_WORDS[@]} - 1))
+	COMPREPLY=()
+	_swarm
+	echo "${COMPREPLY[*]}"
+}
+`)
+	for _, test := range []struct {
+		line     []string
+		expected string
+	}{
+		{[]string{"swarm", "ma"}, "manifest"},
+		{[]string{"swarm", "manifest", ""}, "add remove set-type update"},
+		{[]string{"swarm", "--bzzapi", "http://localhost:8500", "manifest", "r"}, "remove"},
+		{[]string{"swarm", "manifest", "add", ""}, "aaaa bbbb"},
+		{[]string{"swarm", "ls", "--recursive", "b"}, "bbbb"},
+		{[]string{"swarm", "verify", "--l"}, "--local"},
+		{[]string{"swarm", "up", ""}, ""},
+		{[]string{"swarm", "--bzzapi", ""}, ""},
+	} {
+		quoted := make([]string, len(test.line))
+		for i, word := range test.line {
+			quoted[i] = "'" + word + "'"
+		}
+		cmd := exec.Command(bash, "-c", script.String()+"\ncomplete_line "+strings.Join(quoted, " "))
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%q: %v: %s", test.line, err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != test.expected {
+			t.Fatalf("%q: expected completions %q, got %q", test.line, test.expected, got)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"strconv"
@@ -33,7 +34,11 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
+	swarmmetrics "github.com/ethereum/go-ethereum/swarm/metrics"
+	"github.com/ethereum/go-ethereum/swarm/network"
+	"github.com/ethereum/go-ethereum/swarm/services/swap/swap"
 	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
 
 	bzzapi "github.com/ethereum/go-ethereum/swarm/api"
 )
@@ -41,13 +46,18 @@ import (
 var (
 	//flag definition for the dumpconfig command
 	DumpConfigCommand = cli.Command{
-		Action:      utils.MigrateFlags(dumpConfig),
-		Name:        "dumpconfig",
-		Usage:       "Show configuration values",
-		ArgsUsage:   "",
-		Flags:       app.Flags,
-		Category:    "MISCELLANEOUS COMMANDS",
-		Description: `The dumpconfig command shows configuration values.`,
+		Action:    utils.MigrateFlags(dumpConfig),
+		Name:      "dumpconfig",
+		Usage:     "Show configuration values",
+		ArgsUsage: "",
+		Flags:     app.Flags,
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `The dumpconfig command shows configuration values.
+
+The output can be used as a configuration file passed with --config. The swarm
+settings are at the top level of the file, the settings of the underlying node
+(p2p networking, RPC endpoints) are in the [Node] table and the metrics export
+settings in the [Metrics] table.`,
 	}
 
 	//flag definition for the config file command
@@ -66,17 +76,32 @@ const (
 	SWARM_ENV_NETWORK_ID           = "SWARM_NETWORK_ID"
 	SWARM_ENV_SWAP_ENABLE          = "SWARM_SWAP_ENABLE"
 	SWARM_ENV_SWAP_API             = "SWARM_SWAP_API"
+	SWARM_ENV_SWAP_PAY_AT          = "SWARM_SWAP_PAY_AT"
+	SWARM_ENV_SWAP_DROP_AT         = "SWARM_SWAP_DROP_AT"
+	SWARM_ENV_PRICE_ORACLE         = "SWARM_PRICE_ORACLE"
 	SWARM_ENV_SYNC_DISABLE         = "SWARM_SYNC_DISABLE"
 	SWARM_ENV_SYNC_UPDATE_DELAY    = "SWARM_ENV_SYNC_UPDATE_DELAY"
 	SWARM_ENV_DELIVERY_SKIP_CHECK  = "SWARM_DELIVERY_SKIP_CHECK"
+	SWARM_ENV_DEDUP                = "SWARM_DEDUP"
+	SWARM_ENV_COMPRESSION          = "SWARM_COMPRESSION"
 	SWARM_ENV_ENS_API              = "SWARM_ENS_API"
 	SWARM_ENV_ENS_ADDR             = "SWARM_ENS_ADDR"
 	SWARM_ENV_CORS                 = "SWARM_CORS"
 	SWARM_ENV_BOOTNODES            = "SWARM_BOOTNODES"
+	SWARM_ENV_BOOTNODES_URL        = "SWARM_BOOTNODES_URL"
+	SWARM_ENV_BOOTNODES_PUBKEY     = "SWARM_BOOTNODES_PUBKEY"
+	SWARM_ENV_BOOTNODES_REFRESH    = "SWARM_BOOTNODES_REFRESH"
+	SWARM_ENV_SWARMFS_CACHE        = "SWARM_SWARMFS_CACHE"
+	SWARM_ENV_SWARMFS_READAHEAD    = "SWARM_SWARMFS_READAHEAD"
 	SWARM_ENV_PSS_ENABLE           = "SWARM_PSS_ENABLE"
 	SWARM_ENV_STORE_PATH           = "SWARM_STORE_PATH"
 	SWARM_ENV_STORE_CAPACITY       = "SWARM_STORE_CAPACITY"
+	SWARM_ENV_STORE_BACKEND        = "SWARM_STORE_BACKEND"
+	SWARM_ENV_STORE_GC_POLICY      = "SWARM_STORE_GC_POLICY"
 	SWARM_ENV_STORE_CACHE_CAPACITY = "SWARM_STORE_CACHE_CAPACITY"
+	SWARM_ENV_KAD_BIN_SIZE         = "SWARM_KAD_BIN_SIZE"
+	SWARM_ENV_KAD_PROX_BIN_SIZE    = "SWARM_KAD_PROX_BIN_SIZE"
+	SWARM_ENV_KAD_MAX_CONNS        = "SWARM_KAD_MAX_CONNS"
 	GETH_ENV_DATADIR               = "GETH_DATADIR"
 )
 
@@ -131,32 +156,124 @@ func initSwarmNode(config *bzzapi.Config, stack *node.Node, ctx *cli.Context) {
 	log.Debug(printConfig(config))
 }
 
+// tables of the TOML configuration file which do not hold swarm settings,
+// the swarm settings are at the top level of the file
+const (
+	nodeConfigTable    = "Node"
+	metricsConfigTable = "Metrics"
+)
+
+// configFile holds the tables of a parsed TOML configuration file
+type configFile struct {
+	path    string
+	swarm   *ast.Table
+	node    *ast.Table // nil if the file has no node settings
+	metrics *ast.Table // nil if the file has no metrics settings
+}
+
+// parse the config file set with the --config flag, returns nil if no config file has been provided
+func loadConfigFile(ctx *cli.Context) (*configFile, error) {
+	if !ctx.GlobalIsSet(SwarmTomlConfigPathFlag.Name) {
+		return nil, nil
+	}
+	path := ctx.GlobalString(SwarmTomlConfigPathFlag.Name)
+	if path == "" {
+		utils.Fatalf("Config file flag provided with invalid file path")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	table, err := toml.Parse(data)
+	if err != nil {
+		return nil, errors.New(path + ", " + err.Error())
+	}
+	file := &configFile{path: path, swarm: table}
+	for name, t := range map[string]**ast.Table{nodeConfigTable: &file.node, metricsConfigTable: &file.metrics} {
+		v, ok := table.Fields[name]
+		if !ok {
+			continue
+		}
+		if *t, ok = v.(*ast.Table); !ok {
+			return nil, fmt.Errorf("%s, %s is not a table", path, name)
+		}
+		delete(table.Fields, name)
+	}
+	return file, nil
+}
+
+// decode a table of the config file into v
+// note that we are decoding into the existing value of v;
+// if an entry is not present in the table, the existing entry is kept
+func (f *configFile) decode(table *ast.Table, v interface{}) error {
+	if table == nil {
+		return nil
+	}
+	err := tomlSettings.UnmarshalTable(table, v)
+	// Add file name to errors that have a line number.
+	if _, ok := err.(*toml.LineError); ok {
+		err = errors.New(f.path + ", " + err.Error())
+	}
+	return err
+}
+
 //override the current config with whatever is in the config file, if a config file has been provided
 func configFileOverride(config *bzzapi.Config, ctx *cli.Context) (*bzzapi.Config, error) {
-	var err error
+	file, err := loadConfigFile(ctx)
+	if err != nil || file == nil {
+		return config, err
+	}
+	return config, file.decode(file.swarm, config)
+}
 
-	//only do something if the -config flag has been set
-	if ctx.GlobalIsSet(SwarmTomlConfigPathFlag.Name) {
-		var filepath string
-		if filepath = ctx.GlobalString(SwarmTomlConfigPathFlag.Name); filepath == "" {
-			utils.Fatalf("Config file flag provided with invalid file path")
-		}
-		f, err := os.Open(filepath)
-		if err != nil {
+// build the configuration of the node running the swarm service from the
+// defaults, the [Node] table of the config file and the command line
+func buildNodeConfig(ctx *cli.Context, bzzconfig *bzzapi.Config) (*node.Config, error) {
+	cfg := defaultNodeConfig
+	file, err := loadConfigFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		if err := file.decode(file.node, &cfg); err != nil {
 			return nil, err
 		}
-		defer f.Close()
-
-		//decode the TOML file into a Config struct
-		//note that we are decoding into the existing defaultConfig;
-		//if an entry is not present in the file, the default entry is kept
-		err = tomlSettings.NewDecoder(f).Decode(&config)
-		// Add file name to errors that have a line number.
-		if _, ok := err.(*toml.LineError); ok {
-			err = errors.New(filepath + ", " + err.Error())
+	}
+
+	//pss operates on ws
+	hasPss := false
+	for _, module := range cfg.WSModules {
+		hasPss = hasPss || module == "pss"
+	}
+	if !hasPss {
+		cfg.WSModules = append(cfg.WSModules[:len(cfg.WSModules):len(cfg.WSModules)], "pss")
+	}
+
+	//geth only supports --datadir via command line
+	//in order to be consistent within swarm, if we pass --datadir via environment variable
+	//or via config file, we get the same directory for geth and swarm
+	if _, err := os.Stat(bzzconfig.Path); err == nil {
+		cfg.DataDir = bzzconfig.Path
+	}
+	utils.SetNodeConfig(ctx, &cfg)
+	return &cfg, nil
+}
+
+// build the metrics export configuration from the defaults, the [Metrics]
+// table of the config file and the command line
+func buildMetricsConfig(ctx *cli.Context) (*swarmmetrics.Config, error) {
+	cfg := swarmmetrics.DefaultConfig
+	file, err := loadConfigFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		if err := file.decode(file.metrics, &cfg); err != nil {
+			return nil, err
 		}
 	}
-	return config, err
+	swarmmetrics.SetConfig(ctx, &cfg)
+	return &cfg, nil
 }
 
 //override the current config with whatever is provided through the command line
@@ -196,6 +313,14 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.SwapEnabled = true
 	}
 
+	if payAt := ctx.GlobalInt(SwarmSwapPayAtFlag.Name); payAt > 0 {
+		currentConfig.Swap.PayAt = uint(payAt)
+	}
+
+	if dropAt := ctx.GlobalInt(SwarmSwapDropAtFlag.Name); dropAt > 0 {
+		currentConfig.Swap.DropAt = uint(dropAt)
+	}
+
 	if ctx.GlobalIsSet(SwarmSyncDisabledFlag.Name) {
 		currentConfig.SyncEnabled = false
 	}
@@ -208,6 +333,18 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.DeliverySkipCheck = true
 	}
 
+	if ctx.GlobalIsSet(SwarmDedupFlag.Name) {
+		currentConfig.FileStoreParams.Dedup = true
+	}
+
+	if ctx.GlobalIsSet(SwarmCompressionFlag.Name) {
+		currentConfig.Compression = true
+	}
+
+	if oracle := ctx.GlobalString(SwarmPriceOracleFlag.Name); oracle != "" {
+		currentConfig.PriceOracle = oracle
+	}
+
 	currentConfig.SwapApi = ctx.GlobalString(SwarmSwapAPIFlag.Name)
 	if currentConfig.SwapEnabled && currentConfig.SwapApi == "" {
 		utils.Fatalf(SWARM_ERR_SWAP_SET_NO_API)
@@ -230,10 +367,38 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.BootNodes = ctx.GlobalString(utils.BootnodesFlag.Name)
 	}
 
+	if url := ctx.GlobalString(SwarmBootnodesURLFlag.Name); url != "" {
+		currentConfig.BootnodesURL = url
+	}
+
+	if pubkey := ctx.GlobalString(SwarmBootnodesPubKeyFlag.Name); pubkey != "" {
+		currentConfig.BootnodesPubKey = pubkey
+	}
+
+	if d := ctx.GlobalDuration(SwarmBootnodesRefreshFlag.Name); d > 0 {
+		currentConfig.BootnodesRefresh = d
+	}
+
+	if ctx.GlobalIsSet(SwarmFSCacheFlag.Name) {
+		currentConfig.FuseCacheSize = ctx.GlobalInt(SwarmFSCacheFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(SwarmFSReadaheadFlag.Name) {
+		currentConfig.FuseReadahead = ctx.GlobalInt(SwarmFSReadaheadFlag.Name)
+	}
+
 	if storePath := ctx.GlobalString(SwarmStorePath.Name); storePath != "" {
 		currentConfig.LocalStoreParams.ChunkDbPath = storePath
 	}
 
+	if backend := ctx.GlobalString(SwarmStoreBackend.Name); backend != "" {
+		currentConfig.LocalStoreParams.Backend = backend
+	}
+
+	if policy := ctx.GlobalString(SwarmStoreGCPolicy.Name); policy != "" {
+		currentConfig.LocalStoreParams.GCPolicy = policy
+	}
+
 	if storeCapacity := ctx.GlobalUint64(SwarmStoreCapacity.Name); storeCapacity != 0 {
 		currentConfig.LocalStoreParams.DbCapacity = storeCapacity
 	}
@@ -242,6 +407,18 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.LocalStoreParams.CacheCapacity = storeCacheCapacity
 	}
 
+	if binSize := ctx.GlobalInt(SwarmKadBinSize.Name); binSize != 0 {
+		currentConfig.KadParams.MinBinSize = binSize
+	}
+
+	if proxBinSize := ctx.GlobalInt(SwarmKadProxBinSize.Name); proxBinSize != 0 {
+		currentConfig.KadParams.MinProxBinSize = proxBinSize
+	}
+
+	if maxConns := ctx.GlobalInt(SwarmKadMaxConns.Name); maxConns != 0 {
+		currentConfig.KadParams.MaxConns = maxConns
+	}
+
 	return currentConfig
 
 }
@@ -295,12 +472,66 @@ func envVarsOverride(currentConfig *bzzapi.Config) (config *bzzapi.Config) {
 		}
 	}
 
+	if v := os.Getenv(SWARM_ENV_DEDUP); v != "" {
+		if dedup, err := strconv.ParseBool(v); err == nil {
+			currentConfig.FileStoreParams.Dedup = dedup
+		}
+	}
+
+	if v := os.Getenv(SWARM_ENV_COMPRESSION); v != "" {
+		if compression, err := strconv.ParseBool(v); err == nil {
+			currentConfig.Compression = compression
+		}
+	}
+
+	if url := os.Getenv(SWARM_ENV_BOOTNODES_URL); url != "" {
+		currentConfig.BootnodesURL = url
+	}
+
+	if pubkey := os.Getenv(SWARM_ENV_BOOTNODES_PUBKEY); pubkey != "" {
+		currentConfig.BootnodesPubKey = pubkey
+	}
+
+	if v := os.Getenv(SWARM_ENV_BOOTNODES_REFRESH); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			currentConfig.BootnodesRefresh = d
+		}
+	}
+
+	if v := os.Getenv(SWARM_ENV_SWARMFS_CACHE); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			currentConfig.FuseCacheSize = size
+		}
+	}
+
+	if v := os.Getenv(SWARM_ENV_SWARMFS_READAHEAD); v != "" {
+		if readahead, err := strconv.Atoi(v); err == nil {
+			currentConfig.FuseReadahead = readahead
+		}
+	}
+
 	if v := os.Getenv(SWARM_ENV_SYNC_UPDATE_DELAY); v != "" {
 		if d, err := time.ParseDuration(v); err != nil {
 			currentConfig.SyncUpdateDelay = d
 		}
 	}
 
+	if v := os.Getenv(SWARM_ENV_SWAP_PAY_AT); v != "" {
+		if payAt, err := strconv.ParseUint(v, 10, 64); err == nil {
+			currentConfig.Swap.PayAt = uint(payAt)
+		}
+	}
+
+	if v := os.Getenv(SWARM_ENV_SWAP_DROP_AT); v != "" {
+		if dropAt, err := strconv.ParseUint(v, 10, 64); err == nil {
+			currentConfig.Swap.DropAt = uint(dropAt)
+		}
+	}
+
+	if oracle := os.Getenv(SWARM_ENV_PRICE_ORACLE); oracle != "" {
+		currentConfig.PriceOracle = oracle
+	}
+
 	if swapapi := os.Getenv(SWARM_ENV_SWAP_API); swapapi != "" {
 		currentConfig.SwapApi = swapapi
 	}
@@ -329,24 +560,61 @@ func envVarsOverride(currentConfig *bzzapi.Config) (config *bzzapi.Config) {
 }
 
 // dumpConfig is the dumpconfig command.
-// writes a default config to STDOUT
+// writes the effective config to STDOUT
 func dumpConfig(ctx *cli.Context) error {
 	cfg, err := buildConfig(ctx)
 	if err != nil {
 		utils.Fatalf(fmt.Sprintf("Uh oh - dumpconfig triggered an error %v", err))
 	}
+	nodeCfg, err := buildNodeConfig(ctx, cfg)
+	if err != nil {
+		utils.Fatalf(fmt.Sprintf("Uh oh - dumpconfig triggered an error %v", err))
+	}
+	metricsCfg, err := buildMetricsConfig(ctx)
+	if err != nil {
+		utils.Fatalf(fmt.Sprintf("Uh oh - dumpconfig triggered an error %v", err))
+	}
 	comment := ""
 	out, err := tomlSettings.Marshal(&cfg)
 	if err != nil {
 		return err
 	}
+	tables, err := tomlSettings.Marshal(&configTables{Node: nodeCfg, Metrics: metricsCfg})
+	if err != nil {
+		return err
+	}
 	io.WriteString(os.Stdout, comment)
 	os.Stdout.Write(out)
+	io.WriteString(os.Stdout, "\n")
+	os.Stdout.Write(tables)
 	return nil
 }
 
+// configTables is the layout of the config file tables holding the node and
+// metrics settings
+type configTables struct {
+	Node    *node.Config
+	Metrics *swarmmetrics.Config
+}
+
 //validate configuration parameters
 func validateConfig(cfg *bzzapi.Config) (err error) {
+	if cfg.KadParams != nil {
+		if err := validateKadParams(cfg.KadParams); err != nil {
+			return err
+		}
+	}
+	if cfg.SwapEnabled && cfg.Swap != nil && cfg.Swap.Params != nil && cfg.Swap.Profile != nil {
+		if err := validateSwapProfile(cfg.Swap.Profile); err != nil {
+			return err
+		}
+	}
+	if cfg.BootnodesURL != "" && cfg.BootnodesPubKey == "" {
+		return errors.New("bootnode list url set without the public key it is signed with")
+	}
+	if cfg.BootnodesURL != "" && cfg.BootnodesRefresh <= 0 {
+		return fmt.Errorf("invalid bootnode list refresh interval %v: must be positive", cfg.BootnodesRefresh)
+	}
 	for _, ensAPI := range cfg.EnsAPIs {
 		if ensAPI != "" {
 			if err := validateEnsAPIs(ensAPI); err != nil {
@@ -357,6 +625,34 @@ func validateConfig(cfg *bzzapi.Config) (err error) {
 	return nil
 }
 
+//validate kademlia connection policy parameters
+func validateKadParams(params *network.KadParams) error {
+	if params.MinBinSize < 1 {
+		return fmt.Errorf("invalid kademlia bin size %d: must be at least 1", params.MinBinSize)
+	}
+	if params.MinProxBinSize < 1 {
+		return fmt.Errorf("invalid kademlia proximity bin size %d: must be at least 1", params.MinProxBinSize)
+	}
+	if params.MaxConns < 0 {
+		return fmt.Errorf("invalid kademlia connection budget %d: must not be negative", params.MaxConns)
+	}
+	if params.MaxConns > 0 && params.MaxConns < params.MinProxBinSize {
+		return fmt.Errorf("invalid kademlia connection budget %d: must be at least the proximity bin size %d", params.MaxConns, params.MinProxBinSize)
+	}
+	return nil
+}
+
+//validate SWAP payment and disconnect thresholds
+func validateSwapProfile(profile *swap.Profile) error {
+	if profile.PayAt == 0 {
+		return errors.New("invalid SWAP payment threshold 0: must be at least 1")
+	}
+	if profile.DropAt <= profile.PayAt {
+		return fmt.Errorf("invalid SWAP disconnect threshold %d: must be greater than the payment threshold %d", profile.DropAt, profile.PayAt)
+	}
+	return nil
+}
+
 //validate EnsAPIs configuration parameter
 func validateEnsAPIs(s string) (err error) {
 	// missing contract address
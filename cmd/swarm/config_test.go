@@ -22,12 +22,16 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/swarm"
 	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/network"
+	swapsvc "github.com/ethereum/go-ethereum/swarm/services/swap"
+	"github.com/ethereum/go-ethereum/swarm/services/swap/swap"
 
 	"github.com/docker/docker/pkg/reexec"
 )
@@ -39,10 +43,48 @@ func TestDumpConfig(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	swarm.Expect(string(out))
+	swarm.Expect(string(out) + "\n")
+	swarm.ExpectRegexp(`(?s)^\[Node\]\n.*\n\[Node.P2P\]\n.*\n\[Metrics\]\n.*`)
 	swarm.ExpectExit()
 }
 
+func TestDumpConfigFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "testconfig.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`
+NetworkId = 54
+
+[Node.P2P]
+MaxPeers = 7
+
+[Metrics]
+InfluxDBHostTag = "node7"
+`)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the swarm, node and metrics settings of the file are used and
+	// command line flags take precedence over them
+	swarm := runSwarm(t, "--config", f.Name(), "--metrics.influxdb.database", "swarm", "dumpconfig")
+	swarm.ExpectRegexp(`(?s)\nNetworkId = 54\n.*\n\[Node.P2P\]\nMaxPeers = 7\n.*\n\[Metrics\]\n.*InfluxDBDatabase = "swarm"\n.*InfluxDBHostTag = "node7"\n`)
+	swarm.ExpectExit()
+
+	// node and metrics tables must hold settings
+	if err := ioutil.WriteFile(f.Name(), []byte("Node = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	swarm = runSwarm(t, "--config", f.Name(), "dumpconfig")
+	swarm.WaitExit()
+	if !strings.Contains(swarm.StderrText(), "Node is not a table") {
+		t.Fatalf("expected invalid node table error, got %q", swarm.StderrText())
+	}
+}
+
 func TestConfigFailsSwapEnabledNoSwapApi(t *testing.T) {
 	flags := []string{
 		fmt.Sprintf("--%s", SwarmNetworkIdFlag.Name), "42",
@@ -549,6 +591,32 @@ func TestValidateConfig(t *testing.T) {
 			}},
 			err: "invalid format [tld:][contract-addr@]url for ENS API endpoint configuration \"@/data/testnet/geth.ipc\": missing contract address",
 		},
+		{
+			cfg: &api.Config{KadParams: &network.KadParams{MinBinSize: 4, MinProxBinSize: 2, MaxConns: 64}},
+		},
+		{
+			cfg: &api.Config{KadParams: &network.KadParams{MinBinSize: 0, MinProxBinSize: 2}},
+			err: "invalid kademlia bin size 0: must be at least 1",
+		},
+		{
+			cfg: &api.Config{KadParams: &network.KadParams{MinBinSize: 2, MinProxBinSize: 4, MaxConns: 3}},
+			err: "invalid kademlia connection budget 3: must be at least the proximity bin size 4",
+		},
+		{
+			cfg: &api.Config{BootnodesURL: "dns://bootnodes.example.org", BootnodesPubKey: "0x04", BootnodesRefresh: time.Hour},
+		},
+		{
+			cfg: &api.Config{BootnodesURL: "dns://bootnodes.example.org", BootnodesPubKey: "0x04", BootnodesRefresh: -time.Minute},
+			err: "invalid bootnode list refresh interval -1m0s: must be positive",
+		},
+		{
+			cfg: &api.Config{SwapEnabled: true, Swap: &swapsvc.LocalProfile{Params: &swap.Params{Profile: &swap.Profile{PayAt: 100, DropAt: 50}}}},
+			err: "invalid SWAP disconnect threshold 50: must be greater than the payment threshold 100",
+		},
+		{
+			cfg: &api.Config{BootnodesURL: "dns://bootnodes.example.org"},
+			err: "bootnode list url set without the public key it is signed with",
+		},
 	} {
 		err := validateConfig(c.cfg)
 		if c.err != "" && err.Error() != c.err {
@@ -0,0 +1,61 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func cp(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 2 {
+		utils.Fatalf("Usage: swarm cp bzz:/<hash|ens>/<path> bzz:/<manifest>/<path>")
+	}
+
+	src := parseCopyURI(args[0])
+	dst := parseCopyURI(args[1])
+
+	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+	client := swarm.NewClient(bzzapi)
+	newManifest, err := client.Copy(src.Addr, src.Path, dst.Addr, dst.Path)
+	if err != nil {
+		utils.Fatalf("Could not copy %s to %s: %v", args[0], args[1], err)
+	}
+	addHistory(ctx, newManifest)
+	fmt.Println(newManifest)
+}
+
+// parseCopyURI parses a bzz URI or a <hash|ens>/<path> locator
+func parseCopyURI(locator string) *api.URI {
+	if !strings.Contains(locator, ":") {
+		locator = "bzz:/" + locator
+	}
+	uri, err := api.Parse(locator)
+	if err != nil {
+		utils.Fatalf("Could not parse %s: %v", locator, err)
+	}
+	if uri.Scheme != "bzz" {
+		utils.Fatalf("Unsupported scheme %s, only bzz: URIs can be copied", uri.Scheme)
+	}
+	return uri
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+)
+
+// TestCLISwarmCp tests that 'swarm cp' copies content between manifests
+func TestCLISwarmCp(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-cp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"index.html":     "index",
+		"css/style.css":  "style",
+		"css/print.css":  "print",
+		"img/logo.png":   "logo",
+		"img/banner.png": "banner",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := swarm.NewClient(cluster.Nodes[0].URL)
+	src, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	site, err := client.UploadManifest(&api.Manifest{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copyPath := func(from, to string) string {
+		cmd := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "cp", from, to)
+		_, matches := cmd.ExpectRegexp(`[a-f\d]{64}`)
+		cmd.ExpectExit()
+		return matches[0]
+	}
+	site = copyPath("bzz:/"+src+"/index.html", "bzz:/"+site+"/")
+	site = copyPath(src+"/css", site+"/static/css")
+
+	checkFile := func(path, expected string) {
+		file, err := client.Download(site, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, []byte(expected)) {
+			t.Fatalf("expected %s to contain %q, got %q", path, expected, data)
+		}
+	}
+	checkFile("index.html", "index")
+	checkFile("static/css/style.css", "style")
+	checkFile("static/css/print.css", "print")
+	entries, err := client.ListTree(site, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries in the new manifest, got %d", len(entries))
+	}
+
+	cmd := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "cp", src+"/missing", site+"/")
+	cmd.ExpectRegexp(`Fatal: Could not copy .*404`)
+	cmd.ExpectExit()
+}
@@ -21,6 +21,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -53,12 +54,17 @@ func dbExport(ctx *cli.Context) {
 		out = f
 	}
 
-	count, err := store.Export(out)
+	export := store.Export
+	if ctx.Bool(SwarmPinnedFlag.Name) {
+		export = store.ExportPinned
+	}
+	progress := newDBProgress("exporting local chunk database", 0)
+	count, err := export(&progressWriter{out, progress})
 	if err != nil {
 		utils.Fatalf("error exporting local chunk database: %s", err)
 	}
 
-	log.Info(fmt.Sprintf("successfully exported %d chunks", count))
+	log.Info(fmt.Sprintf("successfully exported %d chunks", count), "bytes", progress.count, "elapsed", common.PrettyDuration(time.Since(progress.start)))
 }
 
 func dbImport(ctx *cli.Context) {
@@ -73,7 +79,10 @@ func dbImport(ctx *cli.Context) {
 	}
 	defer store.Close()
 
-	var in io.Reader
+	var (
+		in   io.Reader
+		size int64
+	)
 	if args[1] == "-" {
 		in = os.Stdin
 	} else {
@@ -82,15 +91,19 @@ func dbImport(ctx *cli.Context) {
 			utils.Fatalf("error opening input file: %s", err)
 		}
 		defer f.Close()
+		if fi, err := f.Stat(); err == nil {
+			size = fi.Size()
+		}
 		in = f
 	}
 
-	count, err := store.Import(in)
+	progress := newDBProgress("importing local chunk database", size)
+	count, err := store.Import(&progressReader{in, progress})
 	if err != nil {
 		utils.Fatalf("error importing local chunk database: %s", err)
 	}
 
-	log.Info(fmt.Sprintf("successfully imported %d chunks", count))
+	log.Info(fmt.Sprintf("successfully imported %d chunks", count), "bytes", progress.count, "elapsed", common.PrettyDuration(time.Since(progress.start)))
 }
 
 func dbClean(ctx *cli.Context) {
@@ -118,3 +131,59 @@ func openLDBStore(path string, basekey []byte) (*storage.LDBStore, error) {
 	ldbparams.BaseKey = basekey
 	return storage.NewLDBStore(ldbparams)
 }
+
+// dbProgressInterval is the minimum interval between two progress reports of
+// the db export and import commands
+var dbProgressInterval = 5 * time.Second
+
+// dbProgress periodically logs the number of bytes written to or read from
+// a chunk archive
+type dbProgress struct {
+	msg   string
+	total int64 // size of the archive, 0 if unknown
+	count int64
+	start time.Time
+	last  time.Time
+}
+
+func newDBProgress(msg string, total int64) *dbProgress {
+	now := time.Now()
+	return &dbProgress{msg: msg, total: total, start: now, last: now}
+}
+
+func (p *dbProgress) add(n int) {
+	p.count += int64(n)
+	if time.Since(p.last) < dbProgressInterval {
+		return
+	}
+	p.last = time.Now()
+	ctx := []interface{}{"bytes", p.count, "elapsed", common.PrettyDuration(time.Since(p.start))}
+	if p.total > 0 {
+		ctx = append(ctx, "percent", fmt.Sprintf("%.1f", float64(p.count)*100/float64(p.total)))
+	}
+	log.Info(p.msg, ctx...)
+}
+
+// progressWriter reports the progress of writes to the underlying writer
+type progressWriter struct {
+	io.Writer
+	progress *dbProgress
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.progress.add(n)
+	return n, err
+}
+
+// progressReader reports the progress of reads from the underlying reader
+type progressReader struct {
+	io.Reader
+	progress *dbProgress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	r.progress.add(n)
+	return n, err
+}
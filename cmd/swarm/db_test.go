@@ -0,0 +1,109 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// recordProgress collects the progress messages logged by the db commands
+func recordProgress() (*[]*log.Record, func()) {
+	var records []*log.Record
+	handler := log.Root().GetHandler()
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		records = append(records, r)
+		return nil
+	}))
+	return &records, func() { log.Root().SetHandler(handler) }
+}
+
+// progressValue returns the value of key in the context of the record
+func progressValue(r *log.Record, key string) interface{} {
+	for i := 0; i+1 < len(r.Ctx); i += 2 {
+		if r.Ctx[i] == key {
+			return r.Ctx[i+1]
+		}
+	}
+	return nil
+}
+
+func TestDBProgressWriter(t *testing.T) {
+	defer func(d time.Duration) { dbProgressInterval = d }(dbProgressInterval)
+	records, restore := recordProgress()
+	defer restore()
+
+	// no report before the interval has passed
+	dbProgressInterval = time.Hour
+	var out bytes.Buffer
+	p := newDBProgress("exporting", 0)
+	w := &progressWriter{&out, p}
+	if _, err := w.Write(make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*records) != 0 {
+		t.Fatalf("expected no progress report, got %d", len(*records))
+	}
+
+	dbProgressInterval = 0
+	if _, err := w.Write(make([]byte, 50)); err != nil {
+		t.Fatal(err)
+	}
+	if p.count != 150 || out.Len() != 150 {
+		t.Fatalf("expected 150 bytes written and counted, got %d written and %d counted", out.Len(), p.count)
+	}
+	if len(*records) != 1 {
+		t.Fatalf("expected 1 progress report, got %d", len(*records))
+	}
+	r := (*records)[0]
+	if r.Msg != "exporting" || progressValue(r, "bytes") != int64(150) {
+		t.Fatalf("unexpected progress report %q %v", r.Msg, r.Ctx)
+	}
+	// the percentage is only reported if the size is known
+	if v := progressValue(r, "percent"); v != nil {
+		t.Fatalf("expected no percentage, got %v", v)
+	}
+}
+
+func TestDBProgressReader(t *testing.T) {
+	defer func(d time.Duration) { dbProgressInterval = d }(dbProgressInterval)
+	records, restore := recordProgress()
+	defer restore()
+
+	dbProgressInterval = 0
+	p := newDBProgress("importing", 200)
+	r := &progressReader{bytes.NewReader(make([]byte, 200)), p}
+	n, err := io.Copy(ioutil.Discard, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 200 || p.count != 200 {
+		t.Fatalf("expected 200 bytes read and counted, got %d read and %d counted", n, p.count)
+	}
+	if len(*records) == 0 {
+		t.Fatal("expected progress reports")
+	}
+	last := (*records)[len(*records)-1]
+	if last.Msg != "importing" || progressValue(last, "bytes") != int64(200) || progressValue(last, "percent") != "100.0" {
+		t.Fatalf("unexpected progress report %q %v", last.Msg, last.Ctx)
+	}
+}
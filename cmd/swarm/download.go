@@ -50,8 +50,17 @@ func download(ctx *cli.Context) {
 	var (
 		bzzapi      = strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
 		isRecursive = ctx.Bool(SwarmRecursiveFlag.Name)
+		parallel    = ctx.Int(SwarmDownloadParallelFlag.Name)
 		client      = swarm.NewClient(bzzapi)
+		locator     = args[0]
 	)
+	client.Timeout = ctx.Duration(SwarmTimeoutFlag.Name)
+	client.Retries = ctx.Int(SwarmRetriesFlag.Name)
+
+	// accept <hash|ens>/<path> locators without a scheme
+	if !strings.Contains(locator, ":") {
+		locator = "bzz:/" + locator
+	}
 
 	if fi, err := os.Stat(dest); err == nil {
 		if isRecursive && !fi.Mode().IsDir() {
@@ -63,21 +72,37 @@ func download(ctx *cli.Context) {
 		}
 	}
 
-	uri, err := api.Parse(args[0])
+	uri, err := api.Parse(locator)
 	if err != nil {
 		utils.Fatalf("could not parse uri argument: %v", err)
 	}
 
 	// assume behaviour according to --recursive switch
 	if isRecursive {
-		if err := client.DownloadDirectory(uri.Addr, uri.Path, dest); err != nil {
+		entries, err := client.ListTree(uri.Addr, uri.Path)
+		if err != nil {
+			utils.Fatalf("could not list manifest: %v", err)
+		}
+		var total int64
+		for _, entry := range entries {
+			total += entry.Size
+		}
+		progress := newProgress("downloading", total)
+		client.Progress = progress.Add
+		stats, err := client.DownloadEntries(entries, uri.Path, dest, parallel)
+		progress.Stop()
+		if err != nil {
 			utils.Fatalf("encoutered an error while downloading directory: %v", err)
 		}
+		log.Info("swarm down: download complete", "fetched", stats.Downloaded, "bytes", stats.Bytes, "unchanged", stats.Skipped)
 	} else {
 		// we are downloading a file
 		log.Debug(fmt.Sprintf("downloading file/path from a manifest. hash: %s, path:%s", uri.Addr, uri.Path))
 
+		progress := newProgress("downloading", 0)
+		client.Progress = progress.Add
 		err := client.DownloadFile(uri.Addr, uri.Path, dest)
+		progress.Stop()
 		if err != nil {
 			utils.Fatalf("could not download %s from given address: %s. error: %v", uri.Path, uri.Addr, err)
 		}
@@ -22,6 +22,8 @@ import (
 	"os"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -29,20 +31,62 @@ import (
 func hash(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 1 {
-		utils.Fatalf("Usage: swarm hash <file name>")
+		utils.Fatalf("Usage: swarm hash <file name|directory>")
 	}
-	f, err := os.Open(args[0])
+	stat, err := os.Stat(args[0])
 	if err != nil {
-		utils.Fatalf("Error opening file " + args[1])
+		utils.Fatalf("Error opening file %s: %v", args[0], err)
 	}
-	defer f.Close()
 
-	stat, _ := f.Stat()
-	fileStore := storage.NewFileStore(storage.NewMapChunkStore(), storage.NewFileStoreParams())
-	addr, _, err := fileStore.Store(f, stat.Size(), false)
+	var addr storage.Address
+	if stat.IsDir() {
+		addr, err = hashDirectory(args[0], ctx.GlobalString(SwarmUploadDefaultPath.Name))
+	} else {
+		addr, err = hashFile(args[0])
+	}
 	if err != nil {
 		utils.Fatalf("%v\n", err)
 	} else {
 		fmt.Printf("%v\n", addr)
 	}
 }
+
+// hashFile computes the hash of the content of a file, the chunks of the
+// content are hashed but not kept
+func hashFile(file string) (storage.Address, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileStore := storage.NewFileStore(&storage.FakeChunkStore{}, storage.NewFileStoreParams())
+	addr, wait, err := fileStore.Store(f, stat.Size(), false)
+	if err != nil {
+		return nil, err
+	}
+	wait()
+	return addr, nil
+}
+
+// hashDirectory computes the hash of the manifest created by a recursive
+// upload of dir, the files go through the same tar stream and manifest
+// writer as an upload but their chunks are hashed and not kept
+func hashDirectory(dir, defaultPath string) (storage.Address, error) {
+	ignore, err := swarm.LoadIgnore(dir)
+	if err != nil {
+		return nil, err
+	}
+	tar := swarm.TarStream(&swarm.DirectoryUploader{Dir: dir, DefaultPath: defaultPath, Ignore: ignore})
+	defer tar.Close()
+
+	fileStore := storage.NewFileStore(&storage.FakeChunkStore{}, storage.NewFileStoreParams())
+	mw := api.NewApi(fileStore, nil, nil).NewEmptyManifestWriter(false, nil)
+	if err := mw.AddTar(tar, ""); err != nil {
+		return nil, err
+	}
+	return mw.Store()
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCLISwarmHash tests that 'swarm hash' prints the hash 'swarm up' returns
+// for the same file or directory
+func TestCLISwarmHash(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-hash-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for path, content := range map[string]string{
+		".swarmignore":   "*.log\n",
+		"index.html":     "<h1>index</h1>",
+		"error.log":      "ignored",
+		"docs/a.txt":     "aaa",
+		"docs/sub/b.txt": "bbbb",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("docs/a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	defaultPath := filepath.Join(dir, "index.html")
+
+	run := func(args ...string) string {
+		cmd := runSwarm(t, append([]string{"--bzzapi", cluster.Nodes[0].URL}, args...)...)
+		_, matches := cmd.ExpectRegexp(`[a-f\d]{64}`)
+		cmd.ExpectExit()
+		return matches[0]
+	}
+
+	uploaded := run("--recursive", "--defaultpath", defaultPath, "up", dir)
+	if hash := run("--defaultpath", defaultPath, "hash", dir); hash != uploaded {
+		t.Fatalf("expected directory hash %s, got %s", uploaded, hash)
+	}
+
+	uploaded = run("--manifest=false", "up", defaultPath)
+	if hash := run("hash", defaultPath); hash != uploaded {
+		t.Fatalf("expected file hash %s, got %s", uploaded, hash)
+	}
+}
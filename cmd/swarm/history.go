@@ -0,0 +1,86 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// historySize is the number of hashes kept in the local history
+const historySize = 100
+
+// historyPath returns the path of the file keeping the hashes recently
+// returned by the swarm commands, in the swarm directory of the data directory
+func historyPath(ctx *cli.Context) string {
+	datadir := ctx.GlobalString(utils.DataDirFlag.Name)
+	if datadir == "" {
+		datadir = node.DefaultDataDir()
+	}
+	return filepath.Join(datadir, clientIdentifier, "history")
+}
+
+// readHistory returns the hashes in the local history, most recent first
+func readHistory(ctx *cli.Context) []string {
+	data, err := ioutil.ReadFile(historyPath(ctx))
+	if err != nil {
+		return nil
+	}
+	var hashes []string
+	lines := strings.Split(string(data), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if hash := strings.TrimSpace(lines[i]); hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// addHistory adds the hash to the local history, failures are only logged as
+// the history is a convenience for shell completion. Encrypted references are
+// not added, as they embed the decryption key of the content.
+func addHistory(ctx *cli.Context, hash string) {
+	if len(hash) > 2*storage.KeyLength {
+		return
+	}
+	hashes := []string{hash}
+	for _, h := range readHistory(ctx) {
+		if h != hash && len(hashes) < historySize {
+			hashes = append(hashes, h)
+		}
+	}
+	var data []byte
+	for i := len(hashes) - 1; i >= 0; i-- {
+		data = append(data, hashes[i]+"\n"...)
+	}
+	path := historyPath(ctx)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.Debug("Could not create history directory", "path", path, "err", err)
+		return
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		log.Debug("Could not write history", "path", path, "err", err)
+	}
+}
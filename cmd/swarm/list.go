@@ -17,12 +17,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/swarm/api"
 	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -33,29 +35,59 @@ func list(ctx *cli.Context) {
 	if len(args) < 1 {
 		utils.Fatalf("Please supply a manifest reference as the first argument")
 	} else if len(args) > 2 {
-		utils.Fatalf("Too many arguments - usage 'swarm ls manifest [prefix]'")
+		utils.Fatalf("Too many arguments - usage 'swarm ls <hash|ens>[/<path>]'")
 	}
-	manifest := args[0]
 
-	var prefix string
+	// accept <hash|ens>/<path> locators as well as a separate prefix argument
+	locator := args[0]
+	if !strings.Contains(locator, ":") {
+		locator = "bzz:/" + locator
+	}
+	uri, err := api.Parse(locator)
+	if err != nil {
+		utils.Fatalf("Could not parse manifest reference: %v", err)
+	}
+	manifest, prefix := uri.Addr, uri.Path
 	if len(args) == 2 {
-		prefix = args[1]
+		prefix += args[1]
 	}
 
-	bzzapi := strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
-	client := swarm.NewClient(bzzapi)
-	list, err := client.List(manifest, prefix)
-	if err != nil {
-		utils.Fatalf("Failed to generate file and directory list: %s", err)
+	var (
+		bzzapi      = strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+		isRecursive = ctx.Bool(SwarmRecursiveFlag.Name)
+		asJSON      = ctx.Bool(SwarmJSONFlag.Name)
+		client      = swarm.NewClient(bzzapi)
+		list        *api.ManifestList
+	)
+	if isRecursive {
+		entries, err := client.ListTree(manifest, prefix)
+		if err != nil {
+			utils.Fatalf("Failed to generate file list: %s", err)
+		}
+		list = &api.ManifestList{Entries: entries}
+	} else {
+		list, err = client.List(manifest, prefix)
+		if err != nil {
+			utils.Fatalf("Failed to generate file and directory list: %s", err)
+		}
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			utils.Fatalf("Failed to encode list: %s", err)
+		}
+		fmt.Println(string(out))
+		return
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
 	defer w.Flush()
-	fmt.Fprintln(w, "HASH\tCONTENT TYPE\tPATH")
+	fmt.Fprintln(w, "PATH\tSIZE\tCONTENT TYPE\tHASH")
 	for _, prefix := range list.CommonPrefixes {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", "", "DIR", prefix)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", prefix, "", "DIR", "")
 	}
 	for _, entry := range list.Entries {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Hash, entry.ContentType, entry.Path)
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", entry.Path, entry.Size, entry.ContentType, entry.Hash)
 	}
 }
@@ -0,0 +1,99 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+)
+
+// TestCLISwarmLs tests that 'swarm ls' lists the entries below a path of a
+// manifest, either one level deep or recursively
+func TestCLISwarmLs(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-ls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for path, content := range map[string]string{
+		"index.html":      "index",
+		"docs/a.txt":      "aaa",
+		"docs/sub/b.txt":  "bbbb",
+		"images/logo.png": "png",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := swarm.NewClient(cluster.Nodes[0].URL)
+	hash, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls := func(args ...string) *api.ManifestList {
+		args = append([]string{"--bzzapi", cluster.Nodes[0].URL, "ls", "--json"}, args...)
+		cmd := runSwarm(t, args...)
+		_, matches := cmd.ExpectRegexp(`(?s)\{.*\n\}\n`)
+		cmd.ExpectExit()
+		var list api.ManifestList
+		if err := json.Unmarshal([]byte(matches[0]), &list); err != nil {
+			t.Fatalf("could not decode output of swarm ls: %v", err)
+		}
+		return &list
+	}
+	paths := func(list *api.ManifestList) []string {
+		var paths []string
+		for _, entry := range list.Entries {
+			paths = append(paths, entry.Path)
+		}
+		sort.Strings(paths)
+		return paths
+	}
+
+	list := ls(hash + "/docs/")
+	if expected := []string{"docs/a.txt"}; !reflect.DeepEqual(paths(list), expected) {
+		t.Fatalf("expected entries %v, got %v", expected, paths(list))
+	}
+	if expected := []string{"docs/sub/"}; !reflect.DeepEqual(list.CommonPrefixes, expected) {
+		t.Fatalf("expected common prefixes %v, got %v", expected, list.CommonPrefixes)
+	}
+	if entry := list.Entries[0]; entry.Size != 3 || entry.ContentType == "" || entry.Hash == "" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	list = ls("--recursive", hash)
+	expected := []string{"docs/a.txt", "docs/sub/b.txt", "images/logo.png", "index.html"}
+	if !reflect.DeepEqual(paths(list), expected) {
+		t.Fatalf("expected entries %v, got %v", expected, paths(list))
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
@@ -41,8 +42,12 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/swarm"
+	"github.com/ethereum/go-ethereum/swarm/admin"
 	bzzapi "github.com/ethereum/go-ethereum/swarm/api"
+	bzzclient "github.com/ethereum/go-ethereum/swarm/api/client"
 	swarmmetrics "github.com/ethereum/go-ethereum/swarm/metrics"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
 
 	"gopkg.in/urfave/cli.v1"
 )
@@ -112,6 +117,21 @@ var (
 		Usage:  "URL of the Ethereum API provider to use to settle SWAP payments",
 		EnvVar: SWARM_ENV_SWAP_API,
 	}
+	SwarmSwapPayAtFlag = cli.IntFlag{
+		Name:   "swap.payat",
+		Usage:  "Number of chunks owed to a peer that triggers a SWAP payment (default 100)",
+		EnvVar: SWARM_ENV_SWAP_PAY_AT,
+	}
+	SwarmSwapDropAtFlag = cli.IntFlag{
+		Name:   "swap.dropat",
+		Usage:  "Number of chunks owed by a peer that triggers a disconnect (default 10000)",
+		EnvVar: SWARM_ENV_SWAP_DROP_AT,
+	}
+	SwarmPriceOracleFlag = cli.StringFlag{
+		Name:   "prices",
+		Usage:  "Source of the chunk prices used for accounting: file:<path> or contract:<address> (default static prices)",
+		EnvVar: SWARM_ENV_PRICE_ORACLE,
+	}
 	SwarmSyncDisabledFlag = cli.BoolTFlag{
 		Name:   "nosync",
 		Usage:  "Disable swarm syncing",
@@ -127,6 +147,41 @@ var (
 		Usage:  "Skip chunk delivery check (default false)",
 		EnvVar: SWARM_ENV_DELIVERY_SKIP_CHECK,
 	}
+	SwarmDedupFlag = cli.BoolFlag{
+		Name:   "dedup",
+		Usage:  "Skip writing uploaded chunks which are already in the local store (default false)",
+		EnvVar: SWARM_ENV_DEDUP,
+	}
+	SwarmCompressionFlag = cli.BoolFlag{
+		Name:   "compression",
+		Usage:  "Compress chunk data exchanged with peers that support it (default false)",
+		EnvVar: SWARM_ENV_COMPRESSION,
+	}
+	SwarmBootnodesURLFlag = cli.StringFlag{
+		Name:   "bootnodes.url",
+		Usage:  "URL of a signed bootnode list to fetch periodically (https:// or dns://)",
+		EnvVar: SWARM_ENV_BOOTNODES_URL,
+	}
+	SwarmBootnodesPubKeyFlag = cli.StringFlag{
+		Name:   "bootnodes.pubkey",
+		Usage:  "Hex encoded public key the bootnode list is signed with",
+		EnvVar: SWARM_ENV_BOOTNODES_PUBKEY,
+	}
+	SwarmBootnodesRefreshFlag = cli.DurationFlag{
+		Name:   "bootnodes.refresh",
+		Usage:  "Interval between bootnode list fetches (default 1h)",
+		EnvVar: SWARM_ENV_BOOTNODES_REFRESH,
+	}
+	SwarmFSCacheFlag = cli.IntFlag{
+		Name:   "swarmfs.cache",
+		Usage:  "Size of the swarmfs read cache in megabytes, 0 disables caching (default 64)",
+		EnvVar: SWARM_ENV_SWARMFS_CACHE,
+	}
+	SwarmFSReadaheadFlag = cli.IntFlag{
+		Name:   "swarmfs.readahead",
+		Usage:  "Number of 128KB blocks read ahead of sequential swarmfs reads (default 8)",
+		EnvVar: SWARM_ENV_SWARMFS_READAHEAD,
+	}
 	EnsAPIFlag = cli.StringSliceFlag{
 		Name:   "ens-api",
 		Usage:  "ENS API endpoint for a TLD and with contract address, can be repeated, format [tld:][contract-addr@]url",
@@ -161,6 +216,49 @@ var (
 		Name:  "encrypt",
 		Usage: "use encrypted upload",
 	}
+	SwarmDownloadParallelFlag = cli.IntFlag{
+		Name:  "parallel",
+		Usage: "number of files downloaded concurrently",
+		Value: bzzclient.DefaultDownloadParallelism,
+	}
+	SwarmUploadParallelFlag = cli.IntFlag{
+		Name:  "parallel",
+		Usage: "number of concurrent tar streams a recursive upload is split into",
+		Value: 1,
+	}
+	SwarmTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "time limit of every HTTP request of the transfer, 0 for no limit",
+	}
+	SwarmRetriesFlag = cli.IntFlag{
+		Name:  "retries",
+		Usage: "number of times a failed transfer is retried",
+	}
+	SwarmJSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "print the output as JSON",
+	}
+	SwarmPinnedFlag = cli.BoolFlag{
+		Name:  "pinned",
+		Usage: "only export the chunks of pinned content",
+	}
+	SwarmVerifyLocalFlag = cli.BoolFlag{
+		Name:  "local",
+		Usage: "only check the local store of the node, without network retrieval",
+	}
+	SwarmUploadDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "list the files which would be uploaded and their total size without uploading",
+	}
+	SwarmWatchIntervalFlag = cli.DurationFlag{
+		Name:  "interval",
+		Usage: "interval between checks of the watched directory for changes",
+		Value: time.Second,
+	}
+	SwarmWatchResourceFlag = cli.StringFlag{
+		Name:  "resource",
+		Usage: "mutable resource (manifest hash or name) to publish the new manifests to",
+	}
 	CorsStringFlag = cli.StringFlag{
 		Name:   "corsdomain",
 		Usage:  "Domain on which to send Access-Control-Allow-Origin header (multiple domains can be supplied separated by a ',')",
@@ -171,6 +269,16 @@ var (
 		Usage:  "Path to leveldb chunk DB (default <$GETH_ENV_DIR>/swarm/bzz-<$BZZ_KEY>/chunks)",
 		EnvVar: SWARM_ENV_STORE_PATH,
 	}
+	SwarmStoreBackend = cli.StringFlag{
+		Name:   "store.backend",
+		Usage:  fmt.Sprintf("Key-value store backing the chunk DB (%s), only used when the DB is created (default %s)", strings.Join(storage.Backends(), ", "), storage.DefaultBackend),
+		EnvVar: SWARM_ENV_STORE_BACKEND,
+	}
+	SwarmStoreGCPolicy = cli.StringFlag{
+		Name:   "store.gc-policy",
+		Usage:  fmt.Sprintf("Policy picking the chunks evicted when the chunk DB is full (%s) (default %s)", strings.Join(storage.GCPolicies(), ", "), storage.DefaultGCPolicy),
+		EnvVar: SWARM_ENV_STORE_GC_POLICY,
+	}
 	SwarmStoreCapacity = cli.Uint64Flag{
 		Name:   "store.size",
 		Usage:  "Number of chunks (5M is roughly 20-25GB) (default 5000000)",
@@ -181,6 +289,21 @@ var (
 		Usage:  "Number of recent chunks cached in memory (default 5000)",
 		EnvVar: SWARM_ENV_STORE_CACHE_CAPACITY,
 	}
+	SwarmKadBinSize = cli.IntFlag{
+		Name:   "kad.binsize",
+		Usage:  "Minimum number of peers kept connected in each kademlia bin (default 2)",
+		EnvVar: SWARM_ENV_KAD_BIN_SIZE,
+	}
+	SwarmKadProxBinSize = cli.IntFlag{
+		Name:   "kad.proxbinsize",
+		Usage:  "Minimum number of peers in the nearest neighbour set (default 2)",
+		EnvVar: SWARM_ENV_KAD_PROX_BIN_SIZE,
+	}
+	SwarmKadMaxConns = cli.IntFlag{
+		Name:   "kad.maxconns",
+		Usage:  "Maximum number of kademlia connections the node dials for (default 0=unlimited)",
+		EnvVar: SWARM_ENV_KAD_MAX_CONNS,
+	}
 )
 
 //declare a few constant error messages, useful for later error check comparisons in test
@@ -222,58 +345,205 @@ func init() {
 			Name:               "up",
 			Usage:              "uploads a file or directory to swarm using the HTTP API",
 			ArgsUsage:          "<file>",
-			Flags:              []cli.Flag{SwarmEncryptedFlag},
-			Description:        "uploads a file or directory to swarm using the HTTP API and prints the root hash",
+			Flags:              []cli.Flag{SwarmEncryptedFlag, SwarmUploadDryRunFlag, SwarmUploadParallelFlag, SwarmTimeoutFlag, SwarmRetriesFlag},
+			Description:        "uploads a file or directory to swarm using the HTTP API and prints the root hash, paths matching the patterns of a .swarmignore file in an uploaded directory are skipped. The progress of the upload is reported on stderr, as a progress bar with the transfer rate and ETA on terminals. A recursive upload is split into --parallel tar streams sent concurrently, whose manifests are merged by the node. Every request is limited to --timeout and a failed transfer is retried up to --retries times",
 		},
 		{
 			Action:             list,
 			CustomHelpTemplate: helpTemplate,
 			Name:               "ls",
 			Usage:              "list files and directories contained in a manifest",
-			ArgsUsage:          "<manifest> [<prefix>]",
-			Description:        "Lists files and directories contained in a manifest",
+			ArgsUsage:          "<hash|ens>[/<path>]",
+			Flags:              []cli.Flag{SwarmRecursiveFlag, SwarmJSONFlag},
+			Description:        "Lists the path, size, content type and hash of the entries of a manifest whose paths start with the given path, --recursive lists the files of all directories below the path and --json prints the list as JSON",
 		},
 		{
 			Action:             hash,
 			CustomHelpTemplate: helpTemplate,
 			Name:               "hash",
 			Usage:              "print the swarm hash of a file or directory",
-			ArgsUsage:          "<file>",
-			Description:        "Prints the swarm hash of file or directory",
+			ArgsUsage:          "<file|dir>",
+			Description:        "Prints the swarm hash of a file or directory without uploading it. The hash of a file is the hash of its content as uploaded with --manifest=false, the hash of a directory is the manifest hash of a --recursive upload with the same --defaultpath",
+		},
+		{
+			Action:             verify,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "verify",
+			Usage:              "check that every chunk of some content can be retrieved",
+			ArgsUsage:          "<hash|ens>[/<path>]",
+			Flags:              []cli.Flag{SwarmRecursiveFlag, SwarmVerifyLocalFlag, SwarmJSONFlag},
+			Description: `
+Asks the node to retrieve every chunk of the content at the given hash or path
+of a manifest and reports the chunks which cannot be retrieved, with their
+depth in the chunk tree and the offset and length of the data under them.
+
+--recursive also checks the content of every file below the path of the
+manifest, --local only checks the local store of the node without retrieving
+chunks from the network and --json prints the results as JSON. The command
+exits with a non-zero status if any chunk is missing.
+`,
+		},
+		{
+			Action:             status,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "status",
+			Usage:              "print a health summary of a running node",
+			Flags:              []cli.Flag{utils.IPCPathFlag, SwarmJSONFlag},
+			Description: `
+Connects to the IPC endpoint of a running node given by --ipcpath and prints
+its overlay address, the number of connected and known peers in each
+kademlia bin, the usage of the local store, the state of chunk syncing and
+the request statistics of the HTTP gateway. --json prints the status as JSON.
+`,
+		},
+		{
+			Action:             pin,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "pin",
+			Usage:              "exclude content from garbage collection on a running node",
+			ArgsUsage:          "<hash|ens>",
+			Flags:              []cli.Flag{utils.IPCPathFlag, SwarmRecursiveFlag},
+			Description: `
+Connects to the IPC endpoint of a running node given by --ipcpath and pins the
+chunks of the content at the given hash, which must be stored by the node, so
+that they are never garbage collected. --recursive also pins the content of
+every entry if the content is a manifest. The roots of the pinned content are
+printed.
+`,
+		},
+		{
+			Action:             unpin,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "unpin",
+			Usage:              "make pinned content collectable again on a running node",
+			ArgsUsage:          "<hash|ens>",
+			Flags:              []cli.Flag{utils.IPCPathFlag, SwarmRecursiveFlag},
+			Description: `
+Connects to the IPC endpoint of a running node given by --ipcpath and unpins
+the content at the given hash, and the content of every entry of the manifest
+with --recursive. Content pinned several times stays pinned until it is
+unpinned as many times, and chunks shared with other pinned content stay
+pinned.
+`,
+		},
+		{
+			Action:             pins,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "pins",
+			Usage:              "list the pinned content of a running node",
+			Flags:              []cli.Flag{utils.IPCPathFlag, SwarmJSONFlag},
+			Description: `
+Connects to the IPC endpoint of a running node given by --ipcpath and prints
+the roots of the pinned content with their sizes and the number of times they
+are pinned. --json prints the list as JSON.
+`,
+		},
+		{
+			Action:             completion,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "completion",
+			Usage:              "generate a shell completion script",
+			ArgsUsage:          "bash|zsh|fish",
+			Description: `
+Prints a completion script for the given shell which completes the commands,
+subcommands and options of swarm, and the hashes recently returned by the
+commands uploading content or updating manifests, which are kept in the swarm
+directory of --datadir. Encrypted references are not kept, as they hold the
+decryption key of the content. To load the completions in the current shell run:
+
+    source <(swarm completion bash)
+    source <(swarm completion zsh)
+    swarm completion fish | source
+
+'swarm completion hashes' prints the recent hashes for the scripts.
+`,
+		},
+		{
+			Action:             cp,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "cp",
+			Usage:              "copy content from a swarm manifest into another manifest",
+			ArgsUsage:          "bzz:/<hash|ens>/<path> bzz:/<manifest>/<path>",
+			Description: `
+Copies the file or the directory at the source path into the destination
+manifest at the given path and prints the hash of the new destination manifest.
+
+The copy is made by the node by reference: the new manifest entries point to
+the existing content, which is neither downloaded nor uploaded again. A file is
+copied to its base name if the destination path is empty or ends with a slash,
+entries already present at the destination are replaced.
+`,
 		},
 		{
 			Action:    download,
 			Name:      "down",
-			Flags:     []cli.Flag{SwarmRecursiveFlag},
+			Flags:     []cli.Flag{SwarmRecursiveFlag, SwarmDownloadParallelFlag, SwarmTimeoutFlag, SwarmRetriesFlag},
 			Usage:     "downloads a swarm manifest or a file inside a manifest",
 			ArgsUsage: " <uri> [<dir>]",
 			Description: `
-Downloads a swarm bzz uri to the given dir. When no dir is provided, working directory is assumed. --recursive flag is expected when downloading a manifest with multiple entries.
+Downloads a swarm bzz uri or a <hash|ens>/<path> locator to the given dir. When no dir is provided, working directory is assumed. --recursive flag is expected when downloading a manifest with multiple entries.
+
+Recursive downloads fetch --parallel files concurrently and verify the content of every file against the hash of its manifest entry. Files which are already present with the expected content are not downloaded again, so an interrupted download can be resumed by running the same command.
+
+The progress of the download is reported on stderr, as a progress bar with the transfer rate and ETA on terminals.
+
+Every request is limited to --timeout and the transfer of a file is retried up to --retries times if it fails.
 `,
 		},
 
+		{
+			Action:             watch,
+			CustomHelpTemplate: helpTemplate,
+			Name:               "watch",
+			Usage:              "keep a manifest in sync with a local directory",
+			ArgsUsage:          "<dir> [<manifest>]",
+			Flags:              []cli.Flag{SwarmWatchIntervalFlag, SwarmWatchResourceFlag},
+			Description: `
+Uploads the directory, or updates the given manifest to match it, then checks
+the directory for changes every --interval and prints the hash of the updated
+manifest after every change.
+
+Only the chunks of the changed files which the node does not store yet are
+uploaded, the entries of files whose mode or modification time changed are
+updated and the entries of deleted files are removed. The files are skipped
+according to the ignore file of the directory and --defaultpath the same way
+as in a recursive upload.
+
+--resource publishes every new manifest as a multihash update of an existing
+mutable resource, given by the hash of its resource manifest or its name, so
+that a website served from the resource follows the local directory.
+`,
+		},
 		{
 			Name:               "manifest",
 			CustomHelpTemplate: helpTemplate,
 			Usage:              "perform operations on swarm manifests",
 			ArgsUsage:          "COMMAND",
-			Description:        "Updates a MANIFEST by adding/removing/updating the hash of a path.\nCOMMAND could be: add, update, remove",
+			Description:        "Updates a MANIFEST by adding/removing/updating a path or changing its content type and prints the new manifest hash.\nCOMMAND could be: add, update, set-type, remove",
 			Subcommands: []cli.Command{
 				{
 					Action:             add,
 					CustomHelpTemplate: helpTemplate,
 					Name:               "add",
 					Usage:              "add a new path to the manifest",
-					ArgsUsage:          "<MANIFEST> <path> <hash> [<content-type>]",
-					Description:        "Adds a new path to the manifest",
+					ArgsUsage:          "<MANIFEST> <path> <hash|file> [<content-type>]",
+					Description:        "Adds a new path to the manifest. If a local file is given instead of a hash, it is uploaded to the path of the manifest",
 				},
 				{
 					Action:             update,
 					CustomHelpTemplate: helpTemplate,
 					Name:               "update",
 					Usage:              "update the hash for an already existing path in the manifest",
-					ArgsUsage:          "<MANIFEST> <path> <newhash> [<newcontent-type>]",
-					Description:        "Update the hash for an already existing path in the manifest",
+					ArgsUsage:          "<MANIFEST> <path> <newhash|file> [<newcontent-type>]",
+					Description:        "Update the hash for an already existing path in the manifest. If a local file is given instead of a hash, it is uploaded to the path of the manifest",
+				},
+				{
+					Action:             setType,
+					CustomHelpTemplate: helpTemplate,
+					Name:               "set-type",
+					Usage:              "change the content type of an already existing path in the manifest",
+					ArgsUsage:          "<MANIFEST> <path> <content-type>",
+					Description:        "Changes the content type of an already existing path in the manifest",
 				},
 				{
 					Action:             remove,
@@ -285,12 +555,72 @@ Downloads a swarm bzz uri to the given dir. When no dir is provided, working dir
 				},
 			},
 		},
+		{
+			Name:               "access",
+			CustomHelpTemplate: helpTemplate,
+			Usage:              "share encrypted uploads with other accounts",
+			ArgsUsage:          "COMMAND",
+			Description: `
+Share the reference of an encrypted upload with the holders of other keys by
+publishing an access manifest, which stores the reference encrypted for each
+grantee. The key of the --bzzaccount account (a hex key file or a keystore
+account) is used as the publisher or grantee key.
+
+    hash=$(swarm up --encrypt file.pdf)
+    access=$(swarm --bzzaccount publisher.key access new $hash 0x02...)
+    swarm --bzzaccount grantee.key access resolve $access
+
+Revoking a grantee publishes a new access manifest without it, a grantee
+which already resolved the reference keeps access to the content.
+`,
+			Subcommands: []cli.Command{
+				{
+					Action:             accessNew,
+					CustomHelpTemplate: helpTemplate,
+					Name:               "new",
+					Usage:              "publish an access manifest for an encrypted reference",
+					ArgsUsage:          "<ref> [<grantee-pubkey>...]",
+					Description:        "Publishes an access manifest granting the given public keys access to the reference and prints its hash",
+				},
+				{
+					Action:             accessGrant,
+					CustomHelpTemplate: helpTemplate,
+					Name:               "grant",
+					Usage:              "grant access to the reference of an access manifest",
+					ArgsUsage:          "<access-manifest> <grantee-pubkey>...",
+					Description:        "Publishes a copy of the access manifest which also grants the given public keys access and prints its hash",
+				},
+				{
+					Action:             accessRevoke,
+					CustomHelpTemplate: helpTemplate,
+					Name:               "revoke",
+					Usage:              "revoke access to the reference of an access manifest",
+					ArgsUsage:          "<access-manifest> <grantee-pubkey>...",
+					Description:        "Publishes a copy of the access manifest without the given public keys and prints its hash",
+				},
+				{
+					Action:             accessResolve,
+					CustomHelpTemplate: helpTemplate,
+					Name:               "resolve",
+					Usage:              "print the reference an access manifest grants access to",
+					ArgsUsage:          "<access-manifest>",
+					Description:        "Decrypts and prints the reference the access manifest grants the account access to",
+				},
+				{
+					Action:             accessPubkey,
+					CustomHelpTemplate: helpTemplate,
+					Name:               "pubkey",
+					Usage:              "print the public key of the account",
+					Description:        "Prints the compressed public key of the account to pass to access new and grant",
+				},
+			},
+		},
 		{
 			Name:               "fs",
 			CustomHelpTemplate: helpTemplate,
 			Usage:              "perform FUSE operations",
 			ArgsUsage:          "fs COMMAND",
-			Description:        "Performs FUSE operations by mounting/unmounting/listing mount points. This assumes you already have a Swarm node running locally. For all operation you must reference the correct path to bzzd.ipc in order to communicate with the node",
+			Description:        "Performs FUSE operations by mounting/unmounting/listing mount points. This assumes you already have a Swarm node running locally. For all operation you must reference the correct path to bzzd.ipc in order to communicate with the node. Mounts are supported on Linux, FreeBSD and macOS (with osxfuse), not on Windows",
 			Subcommands: []cli.Command{
 				{
 					Action:             mount,
@@ -334,13 +664,19 @@ Downloads a swarm bzz uri to the given dir. When no dir is provided, working dir
 					Name:               "export",
 					Usage:              "export a local chunk database as a tar archive (use - to send to stdout)",
 					ArgsUsage:          "<chunkdb> <file>",
+					Flags:              []cli.Flag{SwarmPinnedFlag},
 					Description: `
 Export a local chunk database as a tar archive (use - to send to stdout).
 
     swarm db export ~/.ethereum/swarm/bzz-KEY/chunks chunks.tar
 
-The export may be quite large, consider piping the output through the Unix
-pv(1) tool to get a progress bar:
+With --pinned only the chunks of the content pinned with 'swarm pin' are
+exported. The pins are not part of the archive, the content has to be pinned
+again on the node it is imported into.
+
+The number of bytes exported so far is logged every few seconds. The export
+may be quite large, consider piping the output through the Unix pv(1) tool
+to get a progress bar:
 
     swarm db export ~/.ethereum/swarm/bzz-KEY/chunks - | pv > chunks.tar
 `,
@@ -356,8 +692,9 @@ Import chunks from a tar archive into a local chunk database (use - to read from
 
     swarm db import ~/.ethereum/swarm/bzz-KEY/chunks chunks.tar
 
-The import may be quite large, consider piping the input through the Unix
-pv(1) tool to get a progress bar:
+The number of bytes imported so far is logged every few seconds. The import
+may be quite large, consider piping the input through the Unix pv(1) tool
+to get a progress bar:
 
     pv chunks.tar | swarm db import ~/.ethereum/swarm/bzz-KEY/chunks -
 `,
@@ -400,9 +737,19 @@ pv(1) tool to get a progress bar:
 		SwarmTomlConfigPathFlag,
 		SwarmSwapEnabledFlag,
 		SwarmSwapAPIFlag,
+		SwarmSwapPayAtFlag,
+		SwarmSwapDropAtFlag,
+		SwarmPriceOracleFlag,
 		SwarmSyncDisabledFlag,
 		SwarmSyncUpdateDelay,
 		SwarmDeliverySkipCheckFlag,
+		SwarmDedupFlag,
+		SwarmCompressionFlag,
+		SwarmBootnodesURLFlag,
+		SwarmBootnodesPubKeyFlag,
+		SwarmBootnodesRefreshFlag,
+		SwarmFSCacheFlag,
+		SwarmFSReadaheadFlag,
 		SwarmListenAddrFlag,
 		SwarmPortFlag,
 		SwarmAccountFlag,
@@ -417,8 +764,14 @@ pv(1) tool to get a progress bar:
 		SwarmUploadMimeType,
 		// storage flags
 		SwarmStorePath,
+		SwarmStoreBackend,
+		SwarmStoreGCPolicy,
 		SwarmStoreCapacity,
 		SwarmStoreCacheCapacity,
+		// kademlia flags
+		SwarmKadBinSize,
+		SwarmKadProxBinSize,
+		SwarmKadMaxConns,
 	}
 	rpcFlags := []cli.Flag{
 		utils.WSEnabledFlag,
@@ -430,15 +783,28 @@ pv(1) tool to get a progress bar:
 	app.Flags = append(app.Flags, rpcFlags...)
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, swarmmetrics.Flags...)
+	app.Flags = append(app.Flags, tracing.Flags...)
+	app.Flags = append(app.Flags, admin.Flags...)
 	app.Before = func(ctx *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
 		if err := debug.Setup(ctx); err != nil {
 			return err
 		}
-		swarmmetrics.Setup(ctx)
-		return nil
+		metricsConfig, err := buildMetricsConfig(ctx)
+		if err != nil {
+			return err
+		}
+		swarmmetrics.Setup(metricsConfig)
+		tracingConfig := tracing.DefaultConfig
+		tracing.SetConfig(ctx, &tracingConfig)
+		tracing.Setup(&tracingConfig)
+		adminConfig := admin.DefaultConfig
+		admin.SetConfig(ctx, &adminConfig)
+		return admin.Setup(&adminConfig)
 	}
 	app.After = func(ctx *cli.Context) error {
+		admin.Close()
+		tracing.Close()
 		debug.Exit()
 		return nil
 	}
@@ -473,20 +839,12 @@ func bzzd(ctx *cli.Context) error {
 		utils.Fatalf("unable to configure swarm: %v", err)
 	}
 
-	cfg := defaultNodeConfig
-
-	//pss operates on ws
-	cfg.WSModules = append(cfg.WSModules, "pss")
-
-	//geth only supports --datadir via command line
-	//in order to be consistent within swarm, if we pass --datadir via environment variable
-	//or via config file, we get the same directory for geth and swarm
-	if _, err := os.Stat(bzzconfig.Path); err == nil {
-		cfg.DataDir = bzzconfig.Path
+	cfg, err := buildNodeConfig(ctx, bzzconfig)
+	if err != nil {
+		utils.Fatalf("unable to configure node: %v", err)
 	}
 	//setup the ethereum node
-	utils.SetNodeConfig(ctx, &cfg)
-	stack, err := node.New(&cfg)
+	stack, err := node.New(cfg)
 	if err != nil {
 		utils.Fatalf("can't create node: %v", err)
 	}
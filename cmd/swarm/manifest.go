@@ -18,9 +18,9 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"mime"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -35,67 +35,96 @@ const bzzManifestJSON = "application/bzz-manifest+json"
 func add(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 3 {
-		utils.Fatalf("Need at least three arguments <MHASH> <path> <HASH> [<content-type>]")
+		utils.Fatalf("Need at least three arguments <MHASH> <path> <HASH|file> [<content-type>]")
 	}
 
 	var (
-		mhash = args[0]
-		path  = args[1]
-		hash  = args[2]
+		mhash  = args[0]
+		path   = args[1]
+		hash   = args[2]
+		client = manifestClient(ctx)
 
-		ctype        string
-		wantManifest = ctx.GlobalBoolT(SwarmWantManifestFlag.Name)
-		mroot        api.Manifest
+		ctype string
 	)
 
 	if len(args) > 3 {
 		ctype = args[3]
-	} else {
-		ctype = mime.TypeByExtension(filepath.Ext(path))
 	}
 
-	newManifest := addEntryToManifest(ctx, mhash, path, hash, ctype)
-	fmt.Println(newManifest)
-
-	if !wantManifest {
-		// Print the manifest. This is the only output to stdout.
-		mrootJSON, _ := json.MarshalIndent(mroot, "", "  ")
-		fmt.Println(string(mrootJSON))
+	if isLocalFile(hash) {
+		if _, ok := findManifestEntry(client, mhash, path); ok {
+			utils.Fatalf("Path %s already present, not adding anything", path)
+		}
+		newManifest := uploadToManifest(client, mhash, path, hash, ctype)
+		addHistory(ctx, newManifest)
+		fmt.Println(newManifest)
 		return
 	}
+
+	if ctype == "" {
+		ctype = mime.TypeByExtension(filepath.Ext(path))
+	}
+	newManifest := addEntryToManifest(ctx, mhash, path, hash, ctype)
+	addHistory(ctx, newManifest)
+	fmt.Println(newManifest)
 }
 
 func update(ctx *cli.Context) {
 
 	args := ctx.Args()
 	if len(args) < 3 {
-		utils.Fatalf("Need at least three arguments <MHASH> <path> <HASH>")
+		utils.Fatalf("Need at least three arguments <MHASH> <path> <HASH|file> [<content-type>]")
 	}
 
 	var (
-		mhash = args[0]
-		path  = args[1]
-		hash  = args[2]
+		mhash  = args[0]
+		path   = args[1]
+		hash   = args[2]
+		client = manifestClient(ctx)
 
-		ctype        string
-		wantManifest = ctx.GlobalBoolT(SwarmWantManifestFlag.Name)
-		mroot        api.Manifest
+		ctype string
 	)
 	if len(args) > 3 {
 		ctype = args[3]
-	} else {
-		ctype = mime.TypeByExtension(filepath.Ext(path))
 	}
 
+	if isLocalFile(hash) {
+		if _, ok := findManifestEntry(client, mhash, path); !ok {
+			utils.Fatalf("Path %s not present in the Manifest, not setting anything", path)
+		}
+		newManifest := uploadToManifest(client, mhash, path, hash, ctype)
+		addHistory(ctx, newManifest)
+		fmt.Println(newManifest)
+		return
+	}
+
+	if ctype == "" {
+		ctype = mime.TypeByExtension(filepath.Ext(path))
+	}
 	newManifest := updateEntryInManifest(ctx, mhash, path, hash, ctype)
+	addHistory(ctx, newManifest)
 	fmt.Println(newManifest)
+}
 
-	if !wantManifest {
-		// Print the manifest. This is the only output to stdout.
-		mrootJSON, _ := json.MarshalIndent(mroot, "", "  ")
-		fmt.Println(string(mrootJSON))
-		return
+func setType(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 3 {
+		utils.Fatalf("Need exactly three arguments <MHASH> <path> <content-type>")
+	}
+
+	var (
+		mhash = args[0]
+		path  = args[1]
+		ctype = args[2]
+	)
+
+	entry, ok := findManifestEntry(manifestClient(ctx), mhash, path)
+	if !ok {
+		utils.Fatalf("Path %s not present in the Manifest, not setting anything", path)
 	}
+	newManifest := updateEntryInManifest(ctx, mhash, path, entry.Hash, ctype)
+	addHistory(ctx, newManifest)
+	fmt.Println(newManifest)
 }
 
 func remove(ctx *cli.Context) {
@@ -105,22 +134,64 @@ func remove(ctx *cli.Context) {
 	}
 
 	var (
-		mhash = args[0]
-		path  = args[1]
-
-		wantManifest = ctx.GlobalBoolT(SwarmWantManifestFlag.Name)
-		mroot        api.Manifest
+		mhash  = args[0]
+		path   = args[1]
+		client = manifestClient(ctx)
 	)
 
-	newManifest := removeEntryFromManifest(ctx, mhash, path)
+	if _, ok := findManifestEntry(client, mhash, path); !ok {
+		utils.Fatalf("Path %s not present in the Manifest, not removing anything", path)
+	}
+	newManifest, err := client.RemovePath(mhash, path)
+	if err != nil {
+		utils.Fatalf("Manifest update failed: %v", err)
+	}
+	addHistory(ctx, newManifest)
 	fmt.Println(newManifest)
+}
 
-	if !wantManifest {
-		// Print the manifest. This is the only output to stdout.
-		mrootJSON, _ := json.MarshalIndent(mroot, "", "  ")
-		fmt.Println(string(mrootJSON))
-		return
+func manifestClient(ctx *cli.Context) *swarm.Client {
+	return swarm.NewClient(strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/"))
+}
+
+// isLocalFile returns true if arg names a regular file rather than a hash
+func isLocalFile(arg string) bool {
+	fi, err := os.Stat(arg)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// findManifestEntry returns the entry with the given path in the manifest,
+// looking into submanifests
+func findManifestEntry(client *swarm.Client, mhash, path string) (*api.ManifestEntry, bool) {
+	list, err := client.List(mhash, path)
+	if err != nil {
+		utils.Fatalf("Manifest listing failed: %v", err)
+	}
+	for _, entry := range list.Entries {
+		if entry.Path == path {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// uploadToManifest uploads the local file at the given path of the manifest,
+// letting the node patch the manifest, and returns the new manifest hash
+func uploadToManifest(client *swarm.Client, mhash, path, file, ctype string) string {
+	f, err := swarm.Open(file)
+	if err != nil {
+		utils.Fatalf("Error opening file: %s", err)
+	}
+	defer f.Close()
+	f.Path = path
+	if ctype != "" {
+		f.ContentType = ctype
+	}
+	newManifest, err := client.Upload(f, mhash, false)
+	if err != nil {
+		utils.Fatalf("Upload failed: %v", err)
 	}
+	return newManifest
 }
 
 func addEntryToManifest(ctx *cli.Context, mhash, path, hash, ctype string) string {
@@ -244,11 +315,13 @@ func updateEntryInManifest(ctx *cli.Context, mhash, path, hash, ctype string) st
 		newMRoot := &api.Manifest{}
 		for _, entry := range mroot.Entries {
 			if newEntry.Path == entry.Path {
-				myEntry := api.ManifestEntry{
-					Hash:        hash,
-					Path:        entry.Path,
-					ContentType: ctype,
+				// keep the attributes of the entry unless its content changes
+				myEntry := entry
+				if hash != entry.Hash {
+					myEntry = api.ManifestEntry{Path: entry.Path}
 				}
+				myEntry.Hash = hash
+				myEntry.ContentType = ctype
 				newMRoot.Entries = append(newMRoot.Entries, myEntry)
 			} else {
 				newMRoot.Entries = append(newMRoot.Entries, entry)
@@ -263,69 +336,3 @@ func updateEntryInManifest(ctx *cli.Context, mhash, path, hash, ctype string) st
 	}
 	return newManifestHash
 }
-
-func removeEntryFromManifest(ctx *cli.Context, mhash, path string) string {
-
-	var (
-		bzzapi           = strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
-		client           = swarm.NewClient(bzzapi)
-		entryToRemove    = api.ManifestEntry{}
-		longestPathEntry = api.ManifestEntry{}
-	)
-
-	mroot, isEncrypted, err := client.DownloadManifest(mhash)
-	if err != nil {
-		utils.Fatalf("Manifest download failed: %v", err)
-	}
-
-	// See if we path is in this Manifest or do we have to dig deeper
-	for _, entry := range mroot.Entries {
-		if path == entry.Path {
-			entryToRemove = entry
-		} else {
-			if entry.ContentType == bzzManifestJSON {
-				prfxlen := strings.HasPrefix(path, entry.Path)
-				if prfxlen && len(path) > len(longestPathEntry.Path) {
-					longestPathEntry = entry
-				}
-			}
-		}
-	}
-
-	if longestPathEntry.Path == "" && entryToRemove.Path == "" {
-		utils.Fatalf("Path %s not present in the Manifest, not removing anything", path)
-	}
-
-	if longestPathEntry.Path != "" {
-		// Load the child Manifest remove the entry there
-		newPath := path[len(longestPathEntry.Path):]
-		newHash := removeEntryFromManifest(ctx, longestPathEntry.Hash, newPath)
-
-		// Replace the hash for parent Manifests
-		newMRoot := &api.Manifest{}
-		for _, entry := range mroot.Entries {
-			if longestPathEntry.Path == entry.Path {
-				entry.Hash = newHash
-			}
-			newMRoot.Entries = append(newMRoot.Entries, entry)
-		}
-		mroot = newMRoot
-	}
-
-	if entryToRemove.Path != "" {
-		// remove the entry in this Manifest
-		newMRoot := &api.Manifest{}
-		for _, entry := range mroot.Entries {
-			if entryToRemove.Path != entry.Path {
-				newMRoot.Entries = append(newMRoot.Entries, entry)
-			}
-		}
-		mroot = newMRoot
-	}
-
-	newManifestHash, err := client.UploadManifest(mroot, isEncrypted)
-	if err != nil {
-		utils.Fatalf("Manifest upload failed: %v", err)
-	}
-	return newManifestHash
-}
@@ -0,0 +1,114 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+)
+
+// TestCLISwarmManifest tests that the 'swarm manifest' subcommands patch an
+// existing manifest and print the new manifest hash
+func TestCLISwarmManifest(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-manifest-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for path, content := range map[string]string{
+		"index.html": "<h1>index</h1>",
+		"docs/a.txt": "aaa",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := swarm.NewClient(cluster.Nodes[0].URL)
+	hash, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		cmd := runSwarm(t, append([]string{"--bzzapi", cluster.Nodes[0].URL, "manifest"}, args...)...)
+		_, matches := cmd.ExpectRegexp(`[a-f\d]{64}`)
+		cmd.ExpectExit()
+		return matches[0]
+	}
+	checkFile := func(hash, path, content, ctype string) {
+		file, err := client.Download(hash, path)
+		if err != nil {
+			t.Fatalf("error downloading %s: %v", path, err)
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Fatalf("expected %s to contain %q, got %q", path, content, data)
+		}
+		if file.ContentType != ctype {
+			t.Fatalf("expected %s to have content type %q, got %q", path, ctype, file.ContentType)
+		}
+	}
+
+	// add a local file
+	newFile := filepath.Join(dir, "new.txt")
+	if err := ioutil.WriteFile(newFile, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash = run("add", hash, "docs/new.txt", newFile)
+	checkFile(hash, "docs/new.txt", "new", "text/plain; charset=utf-8")
+	checkFile(hash, "index.html", "<h1>index</h1>", "text/html; charset=utf-8")
+
+	// replace it with other content
+	if err := ioutil.WriteFile(newFile, []byte("updated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash = run("update", hash, "docs/new.txt", newFile)
+	checkFile(hash, "docs/new.txt", "updated", "text/plain; charset=utf-8")
+
+	// change its content type
+	hash = run("set-type", hash, "docs/new.txt", "application/json")
+	checkFile(hash, "docs/new.txt", "updated", "application/json")
+
+	// remove it
+	hash = run("remove", hash, "docs/new.txt")
+	if list, err := client.List(hash, "docs/"); err != nil {
+		t.Fatal(err)
+	} else if len(list.Entries) != 1 || list.Entries[0].Path != "docs/a.txt" {
+		t.Fatalf("expected only docs/a.txt to remain, got %+v", list.Entries)
+	}
+	checkFile(hash, "docs/a.txt", "aaa", "text/plain; charset=utf-8")
+
+	// removing a missing path fails
+	cmd := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "manifest", "remove", hash, "docs/new.txt")
+	cmd.ExpectRegexp("Path docs/new.txt not present in the Manifest, not removing anything\n")
+	cmd.ExpectExit()
+}
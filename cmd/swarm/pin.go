@@ -0,0 +1,95 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/swarm"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func pin(ctx *cli.Context) {
+	updatePins(ctx, "pin", "bzz_pin")
+}
+
+func unpin(ctx *cli.Context) {
+	updatePins(ctx, "unpin", "bzz_unpin")
+}
+
+// updatePins calls the RPC method pinning or unpinning the content given as
+// the argument of the command and prints the roots of the updated content
+func updatePins(cliContext *cli.Context, command, method string) {
+	args := cliContext.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm %s <hash|ens>", command)
+	}
+	client, err := dialRPC(cliContext)
+	if err != nil {
+		utils.Fatalf("had an error dailing to RPC endpoint: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var roots []storage.Address
+	if err := client.CallContext(ctx, &roots, method, args[0], cliContext.Bool(SwarmRecursiveFlag.Name)); err != nil {
+		utils.Fatalf("Could not %s %s: %v", command, args[0], err)
+	}
+	for _, root := range roots {
+		fmt.Println(root)
+	}
+}
+
+func pins(cliContext *cli.Context) {
+	client, err := dialRPC(cliContext)
+	if err != nil {
+		utils.Fatalf("had an error dailing to RPC endpoint: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var pins []swarm.PinInfo
+	if err := client.CallContext(ctx, &pins, "bzz_pins"); err != nil {
+		utils.Fatalf("Could not list pins: %v", err)
+	}
+
+	if cliContext.Bool(SwarmJSONFlag.Name) {
+		out, err := json.MarshalIndent(pins, "", "  ")
+		if err != nil {
+			utils.Fatalf("Failed to encode pins: %s", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "ROOT\tSIZE\tCOUNT")
+	for _, pin := range pins {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", pin.Root, pin.Size, pin.Count)
+	}
+	w.Flush()
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm"
+	swarmapi "github.com/ethereum/go-ethereum/swarm/api/client"
+)
+
+// TestCLISwarmPin tests that 'swarm pin', 'swarm unpin' and 'swarm pins'
+// pin, unpin and list content of a running node
+func TestCLISwarmPin(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+	node := cluster.Nodes[0]
+
+	client := swarmapi.NewClient(node.URL)
+	hash, err := client.UploadRaw(strings.NewReader("pinned"), 6, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipcPath := filepath.Join(node.Dir, node.IpcPath)
+
+	pins := func() []swarm.PinInfo {
+		cmd := runSwarm(t, "pins", "--ipcpath", ipcPath, "--json")
+		_, matches := cmd.ExpectRegexp(`(?s)\[.*\]\n`)
+		cmd.ExpectExit()
+		var pins []swarm.PinInfo
+		if err := json.Unmarshal([]byte(matches[0]), &pins); err != nil {
+			t.Fatalf("could not decode output of swarm pins: %v", err)
+		}
+		return pins
+	}
+
+	// pin the content twice, it stays pinned after the first unpin
+	for i := 0; i < 2; i++ {
+		cmd := runSwarm(t, "pin", "--ipcpath", ipcPath, hash)
+		cmd.ExpectRegexp(hash + `\n`)
+		cmd.ExpectExit()
+	}
+	if p := pins(); len(p) != 1 || p[0].Root.Hex() != hash || p[0].Size != 6 || p[0].Count != 2 {
+		t.Fatalf("unexpected pins %+v", p)
+	}
+	cmd := runSwarm(t, "pins", "--ipcpath", ipcPath)
+	cmd.ExpectRegexp(`ROOT\s+SIZE\s+COUNT\n` + hash + `\s+6\s+2\n`)
+	cmd.ExpectExit()
+
+	cmd = runSwarm(t, "unpin", "--ipcpath", ipcPath, hash)
+	cmd.ExpectRegexp(hash + `\n`)
+	cmd.ExpectExit()
+	if p := pins(); len(p) != 1 || p[0].Count != 1 {
+		t.Fatalf("unexpected pins %+v", p)
+	}
+	cmd = runSwarm(t, "unpin", "--ipcpath", ipcPath, hash)
+	cmd.ExpectRegexp(hash + `\n`)
+	cmd.ExpectExit()
+	if p := pins(); len(p) != 0 {
+		t.Fatalf("expected no pins, got %+v", p)
+	}
+
+	// unpinning content which is not pinned fails
+	cmd = runSwarm(t, "unpin", "--ipcpath", ipcPath, hash)
+	cmd.ExpectRegexp(`Fatal: Could not unpin ` + hash + `: .*not pinned`)
+	cmd.ExpectExit()
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	isatty "github.com/mattn/go-isatty"
+)
+
+const (
+	progressBarWidth       = 30
+	progressRenderInterval = 200 * time.Millisecond // between progress bar updates on terminals
+	progressLogInterval    = 5 * time.Second        // between progress log lines otherwise
+)
+
+// progress reports the progress of a transfer on stderr, as a progress bar
+// with the transfer rate and ETA if stderr is a terminal and as log lines
+// otherwise
+type progress struct {
+	msg   string
+	total int64 // number of bytes to transfer, 0 if unknown
+	count int64 // number of bytes transferred, accessed atomically
+	start time.Time
+
+	tty  bool
+	out  io.Writer
+	quit chan struct{}
+	done chan struct{}
+}
+
+// newProgress starts reporting the progress of a transfer of total bytes
+func newProgress(msg string, total int64) *progress {
+	p := &progress{
+		msg:   msg,
+		total: total,
+		start: time.Now(),
+		tty:   isatty.IsTerminal(os.Stderr.Fd()),
+		out:   os.Stderr,
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.loop()
+	return p
+}
+
+// Add records the transfer of n bytes
+func (p *progress) Add(n int) {
+	atomic.AddInt64(&p.count, int64(n))
+}
+
+// Stop stops reporting and prints the final state of the transfer
+func (p *progress) Stop() {
+	close(p.quit)
+	<-p.done
+}
+
+func (p *progress) loop() {
+	defer close(p.done)
+	interval := progressLogInterval
+	if p.tty {
+		interval = progressRenderInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.report(false)
+		case <-p.quit:
+			p.report(true)
+			return
+		}
+	}
+}
+
+func (p *progress) report(final bool) {
+	var (
+		count   = atomic.LoadInt64(&p.count)
+		elapsed = time.Since(p.start)
+		rate    float64
+		eta     time.Duration
+	)
+	if elapsed > 0 {
+		rate = float64(count) / elapsed.Seconds()
+	}
+	if p.total > 0 && rate > 0 && count < p.total {
+		eta = time.Duration(float64(p.total-count)/rate) * time.Second
+	}
+	if !p.tty {
+		ctx := []interface{}{"bytes", count, "rate", common.StorageSize(rate).String() + "/s", "elapsed", common.PrettyDuration(elapsed)}
+		if p.total > 0 {
+			ctx = append(ctx, "total", p.total, "percent", fmt.Sprintf("%.1f", percent(count, p.total)))
+			if !final {
+				ctx = append(ctx, "eta", common.PrettyDuration(eta))
+			}
+		}
+		log.Info(p.msg, ctx...)
+		return
+	}
+	line := fmt.Sprintf("%s %s %s/s", p.msg, common.StorageSize(count).TerminalString(), common.StorageSize(rate).TerminalString())
+	if p.total > 0 {
+		done := int(percent(count, p.total) / 100 * progressBarWidth)
+		if done > progressBarWidth {
+			done = progressBarWidth
+		}
+		bar := strings.Repeat("=", done) + strings.Repeat(" ", progressBarWidth-done)
+		line = fmt.Sprintf("%s [%s] %5.1f%% %s/%s %s/s ETA %v", p.msg, bar, percent(count, p.total),
+			common.StorageSize(count).TerminalString(), common.StorageSize(p.total).TerminalString(),
+			common.StorageSize(rate).TerminalString(), eta)
+	}
+	// pad the line to overwrite longer previous ones
+	fmt.Fprintf(p.out, "\r%-100s", line)
+	if final {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// percent returns count as a percentage of total
+func percent(count, total int64) float64 {
+	return float64(count) * 100 / float64(total)
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	p := &progress{
+		msg:   "uploading",
+		total: 4000,
+		start: time.Now().Add(-2 * time.Second),
+		tty:   true,
+		out:   &out,
+	}
+	p.Add(2000)
+	p.report(false)
+	expected := regexp.MustCompile(`^\ruploading \[={15} {15}\]  50\.0% 2\.00kB/4\.00kB [\d.]+[km]?B/s ETA \d+s +$`)
+	if !expected.Match(out.Bytes()) {
+		t.Fatalf("unexpected progress bar %q", out.String())
+	}
+
+	out.Reset()
+	p.Add(2000)
+	p.report(true)
+	expected = regexp.MustCompile(`^\ruploading \[={30}\] 100\.0% 4\.00kB/4\.00kB [\d.]+[km]?B/s ETA 0s +\n$`)
+	if !expected.Match(out.Bytes()) {
+		t.Fatalf("unexpected final progress bar %q", out.String())
+	}
+}
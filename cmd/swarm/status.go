@@ -0,0 +1,108 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/swarm"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func status(cliContext *cli.Context) {
+	client, err := dialRPC(cliContext)
+	if err != nil {
+		utils.Fatalf("had an error dailing to RPC endpoint: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var status swarm.Status
+	if err := client.CallContext(ctx, &status, "bzz_status"); err != nil {
+		utils.Fatalf("had an error calling the RPC endpoint while getting the status: %v", err)
+	}
+
+	if cliContext.Bool(SwarmJSONFlag.Name) {
+		out, err := json.MarshalIndent(&status, "", "  ")
+		if err != nil {
+			utils.Fatalf("Failed to encode status: %s", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+	printStatus(&status)
+}
+
+func printStatus(status *swarm.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	fmt.Fprintf(w, "Uptime:\t%v\n", status.Uptime.Round(time.Second))
+	if overlay := status.Overlay; overlay != nil {
+		fmt.Fprintf(w, "Overlay address:\t%x\n", []byte(overlay.BaseAddr))
+		fmt.Fprintf(w, "Peers:\t%d connected, %d known\n", overlay.Connected, overlay.Known)
+		fmt.Fprintf(w, "Depth:\t%d (neighbourhood %d)\n", overlay.Depth, overlay.NeighbourhoodDepth)
+	}
+	if store := status.Store; store != nil {
+		usage := 0.0
+		if store.Capacity > 0 {
+			usage = 100 * float64(store.Chunks) / float64(store.Capacity)
+		}
+		fmt.Fprintf(w, "Store:\t%d of %d chunks (%.1f%%)\n", store.Chunks, store.Capacity, usage)
+	}
+	if sync := status.Sync; sync != nil {
+		state := "disabled"
+		if sync.Enabled {
+			state = "enabled"
+		}
+		fmt.Fprintf(w, "Sync:\t%s, %d of %d peers syncing, %d streams served, %d received\n", state, sync.Syncing, sync.Peers, sync.Servers, sync.Clients)
+	}
+	if gateway := status.Gateway; gateway != nil {
+		var total int64
+		methods := make([]string, 0, len(gateway.Requests))
+		for method, n := range gateway.Requests {
+			methods = append(methods, method)
+			total += n
+		}
+		sort.Strings(methods)
+		fmt.Fprintf(w, "Gateway:\t%d requests, %d client errors, %d server errors\n", total, gateway.ClientErrors, gateway.ServerErrors)
+		for _, method := range methods {
+			fmt.Fprintf(w, "\t  %s: %d\n", method, gateway.Requests[method])
+		}
+	} else {
+		fmt.Fprintf(w, "Gateway:\tdisabled\n")
+	}
+	w.Flush()
+
+	if status.Overlay == nil || len(status.Overlay.Bins) == 0 {
+		return
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "BIN\tCONNECTED\tKNOWN")
+	for _, bin := range status.Overlay.Bins {
+		fmt.Fprintf(w, "%d\t%d\t%d\n", bin.Bin, bin.Connected, bin.Known)
+	}
+	w.Flush()
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm"
+	swarmapi "github.com/ethereum/go-ethereum/swarm/api/client"
+)
+
+// TestCLISwarmStatus tests that 'swarm status' reports the status of a
+// running node
+func TestCLISwarmStatus(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+	node := cluster.Nodes[0]
+
+	client := swarmapi.NewClient(node.URL)
+	if _, err := client.UploadRaw(strings.NewReader("status"), 6, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ipcPath := filepath.Join(node.Dir, node.IpcPath)
+	cmd := runSwarm(t, "status", "--ipcpath", ipcPath, "--json")
+	_, matches := cmd.ExpectRegexp(`(?s)\{.*\n\}\n`)
+	cmd.ExpectExit()
+	var status swarm.Status
+	if err := json.Unmarshal([]byte(matches[0]), &status); err != nil {
+		t.Fatalf("could not decode output of swarm status: %v", err)
+	}
+	if status.Overlay == nil || len(status.Overlay.BaseAddr) != 32 {
+		t.Fatalf("unexpected overlay status %+v", status.Overlay)
+	}
+	if status.Store == nil || status.Store.Chunks == 0 || status.Store.Capacity == 0 {
+		t.Fatalf("unexpected store status %+v", status.Store)
+	}
+	if status.Sync == nil || !status.Sync.Enabled {
+		t.Fatalf("unexpected sync status %+v", status.Sync)
+	}
+	if status.Gateway == nil || status.Gateway.Requests["POST"] != 1 {
+		t.Fatalf("unexpected gateway status %+v", status.Gateway)
+	}
+
+	cmd = runSwarm(t, "status", "--ipcpath", ipcPath)
+	cmd.ExpectRegexp(`(?s)Overlay address:\s+[0-9a-f]{64}\n.*Store:\s+\d+ of \d+ chunks.*Gateway:\s+\d+ requests`)
+	cmd.ExpectExit()
+}
@@ -47,8 +47,12 @@ func upload(ctx *cli.Context) {
 		mimeType     = ctx.GlobalString(SwarmUploadMimeType.Name)
 		client       = swarm.NewClient(bzzapi)
 		toEncrypt    = ctx.Bool(SwarmEncryptedFlag.Name)
+		dryRun       = ctx.Bool(SwarmUploadDryRunFlag.Name)
+		parallel     = ctx.Int(SwarmUploadParallelFlag.Name)
 		file         string
 	)
+	client.Timeout = ctx.Duration(SwarmTimeoutFlag.Name)
+	client.Retries = ctx.Int(SwarmRetriesFlag.Name)
 
 	if len(args) != 1 {
 		if fromStdin {
@@ -71,16 +75,27 @@ func upload(ctx *cli.Context) {
 		file = expandPath(args[0])
 	}
 
+	if dryRun {
+		if err := listUpload(file, defaultPath, recursive); err != nil {
+			utils.Fatalf("Dry run failed: %s", err)
+		}
+		return
+	}
+
 	if !wantManifest {
 		f, err := swarm.Open(file)
 		if err != nil {
 			utils.Fatalf("Error opening file: %s", err)
 		}
 		defer f.Close()
-		hash, err := client.UploadRaw(f, f.Size, toEncrypt)
+		progress := newProgress("uploading", f.Size)
+		client.Progress = progress.Add
+		hash, err := client.UploadRaw(f.ReadCloser, f.Size, toEncrypt)
+		progress.Stop()
 		if err != nil {
 			utils.Fatalf("Upload failed: %s", err)
 		}
+		addHistory(ctx, hash)
 		fmt.Println(hash)
 		return
 	}
@@ -98,7 +113,11 @@ func upload(ctx *cli.Context) {
 			if !recursive {
 				return "", errors.New("Argument is a directory and recursive upload is disabled")
 			}
-			return client.UploadDirectory(file, defaultPath, "", toEncrypt)
+			uploader, err := newUploader(file, defaultPath, recursive)
+			if err != nil {
+				return "", err
+			}
+			return client.ParallelTarUpload("", uploader, toEncrypt, parallel)
 		}
 	} else {
 		doUpload = func() (string, error) {
@@ -114,13 +133,87 @@ func upload(ctx *cli.Context) {
 			return client.Upload(f, "", toEncrypt)
 		}
 	}
+	total := stat.Size()
+	if stat.IsDir() && recursive {
+		if uploader, err := newUploader(file, defaultPath, recursive); err == nil {
+			_, total, _ = uploadSize(uploader, nil)
+		}
+	}
+	progress := newProgress("uploading", total)
+	client.Progress = progress.Add
 	hash, err := doUpload()
+	progress.Stop()
 	if err != nil {
 		utils.Fatalf("Upload failed: %s", err)
 	}
+	addHistory(ctx, hash)
 	fmt.Println(hash)
 }
 
+// listUpload prints the files which would be uploaded with their sizes,
+// followed by the number of files and their total size
+func listUpload(file, defaultPath string, recursive bool) error {
+	uploader, err := newUploader(file, defaultPath, recursive)
+	if err != nil {
+		return err
+	}
+	count, total, err := uploadSize(uploader, func(f *swarm.File) {
+		name := f.Path
+		if name == "" {
+			name = "(default path)"
+		}
+		fmt.Printf("%s\t%d\n", name, f.Size)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d file(s), %d bytes\n", count, total)
+	return nil
+}
+
+// newUploader returns the uploader of the files uploaded for file
+func newUploader(file, defaultPath string, recursive bool) (swarm.Uploader, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		if !recursive {
+			return nil, errors.New("Argument is a directory and recursive upload is disabled")
+		}
+		ignore, err := swarm.LoadIgnore(file)
+		if err != nil {
+			return nil, err
+		}
+		return &swarm.DirectoryUploader{Dir: file, DefaultPath: defaultPath, Ignore: ignore}, nil
+	}
+	f, err := swarm.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	f.Path = filepath.Base(file)
+	return &swarm.FileUploader{File: f}, nil
+}
+
+// uploadSize returns the number of files of the uploader and their total
+// size, calling fn with every file if it is not nil
+func uploadSize(uploader swarm.Uploader, fn func(f *swarm.File)) (int, int64, error) {
+	var (
+		count int
+		total int64
+	)
+	err := uploader.Upload(func(f *swarm.File) error {
+		defer f.Close()
+		if fn != nil {
+			fn(f)
+		}
+		count++
+		total += f.Size
+		return nil
+	})
+	return count, total, err
+}
+
 // Expands a file path
 // 1. replace tilde with users home dir
 // 2. expands embedded environment variables
@@ -273,3 +273,54 @@ func testCLISwarmUpRecursive(toEncrypt bool, t *testing.T) {
 		}
 	}
 }
+
+// TestCLISwarmUpParallel tests that a recursive upload split into parallel
+// tar streams gives the same manifest as a single stream and that it is
+// downloaded with the transfer tuning flags
+func TestCLISwarmUpParallel(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []string{"a.txt", "b.txt", "c/d.txt", "c/e.txt", "f/g/h.txt"}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := swarm.NewClient(cluster.Nodes[0].URL).UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "--recursive", "up", "--parallel", "3", "--retries", "2", "--timeout", "10s", dir)
+	_, matches := up.ExpectRegexp(`[a-f\d]{64}`)
+	up.ExpectExit()
+	if matches[0] != expected {
+		t.Fatalf("expected manifest %s, got %s", expected, matches[0])
+	}
+
+	tmp, err := ioutil.TempDir("", "swarm-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	down := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "down", "--recursive", "--parallel", "2", "--retries", "2", "--timeout", "10s", "bzz:/"+expected, tmp)
+	down.ExpectExit()
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(tmp, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != file {
+			t.Fatalf("expected %s to contain %q, got %q", file, file, data)
+		}
+	}
+}
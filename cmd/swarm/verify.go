@@ -0,0 +1,131 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// verifyResult is the reachability report of the content at a path
+type verifyResult struct {
+	Path string `json:"path"`
+	Hash string `json:"hash,omitempty"`
+	*storage.VerifyReport
+}
+
+func verify(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		utils.Fatalf("Usage: swarm verify <hash|ens>[/<path>]")
+	}
+
+	locator := args[0]
+	if !strings.Contains(locator, ":") {
+		locator = "bzz:/" + locator
+	}
+	uri, err := api.Parse(locator)
+	if err != nil {
+		utils.Fatalf("Could not parse reference: %v", err)
+	}
+
+	var (
+		bzzapi      = strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+		isRecursive = ctx.Bool(SwarmRecursiveFlag.Name)
+		local       = ctx.Bool(SwarmVerifyLocalFlag.Name)
+		client      = swarm.NewClient(bzzapi)
+		results     []*verifyResult
+	)
+	report, err := client.Verify(uri.Addr, uri.Path, local)
+	if err != nil {
+		utils.Fatalf("Could not verify %s: %v", args[0], err)
+	}
+	results = append(results, &verifyResult{Path: uri.Path, VerifyReport: report})
+
+	// check the content of every file below the path of the manifest
+	if isRecursive && len(report.Missing) == 0 {
+		results[0].Path = "" // the manifest itself
+		entries, err := client.ListTree(uri.Addr, uri.Path)
+		if err != nil {
+			utils.Fatalf("Could not list manifest: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.IsSymlink() {
+				continue
+			}
+			// verified by path so that the parity chunks of erasure coded
+			// content are checked as well
+			report, err := client.Verify(uri.Addr, entry.Path, local)
+			if err != nil {
+				utils.Fatalf("Could not verify %s: %v", entry.Path, err)
+			}
+			results = append(results, &verifyResult{Path: entry.Path, Hash: entry.Hash, VerifyReport: report})
+		}
+	}
+
+	missing := 0
+	for _, result := range results {
+		missing += len(result.Missing)
+	}
+	if ctx.Bool(SwarmJSONFlag.Name) {
+		out, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			utils.Fatalf("Failed to encode results: %s", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		printVerifyResults(results)
+	}
+	if missing > 0 {
+		utils.Fatalf("%d chunk(s) missing", missing)
+	}
+}
+
+func printVerifyResults(results []*verifyResult) {
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tSIZE\tCHUNKS\tMISSING")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", result.Path, result.Size, result.Chunks, len(result.Missing))
+	}
+	w.Flush()
+
+	var missing []string
+	for _, result := range results {
+		for _, chunk := range result.Missing {
+			missing = append(missing, fmt.Sprintf("%s\t%s\t%d\t%d\t%d\t%s", result.Path, chunk.Address.Hex(), chunk.Depth, chunk.Offset, chunk.Length, chunk.Error))
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tMISSING CHUNK\tDEPTH\tOFFSET\tLENGTH\tERROR")
+	for _, line := range missing {
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+)
+
+// TestCLISwarmVerify tests that 'swarm verify' reports the chunks of some
+// content which cannot be retrieved
+func TestCLISwarmVerify(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-verify-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data := make([]byte, 10000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "data.bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("index"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := swarm.NewClient(cluster.Nodes[0].URL)
+	raw, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func(args ...string) []*verifyResult {
+		args = append([]string{"--bzzapi", cluster.Nodes[0].URL, "verify", "--json"}, args...)
+		cmd := runSwarm(t, args...)
+		_, matches := cmd.ExpectRegexp(`(?s)\[.*\n\]\n`)
+		cmd.ExpectExit()
+		var results []*verifyResult
+		if err := json.Unmarshal([]byte(matches[0]), &results); err != nil {
+			t.Fatalf("could not decode output of swarm verify: %v", err)
+		}
+		return results
+	}
+
+	// 3 leaf chunks and the root chunk
+	results := verify(raw)
+	if len(results) != 1 || results[0].Size != 10000 || results[0].Chunks != 4 || len(results[0].Missing) != 0 {
+		t.Fatalf("unexpected results %+v", results[0])
+	}
+	results = verify(manifest + "/data.bin")
+	if len(results) != 1 || results[0].Path != "data.bin" || results[0].Chunks != 4 {
+		t.Fatalf("unexpected results %+v", results[0])
+	}
+
+	// the manifest and both files
+	results = verify("--recursive", manifest)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for _, result := range results[1:] {
+		if result.Hash == "" || len(result.Missing) != 0 {
+			t.Fatalf("unexpected result %+v", result)
+		}
+	}
+
+	// the root chunk of unknown content is missing
+	missing := strings.Repeat("ab", 32)
+	cmd := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "verify", "--local", missing)
+	cmd.ExpectRegexp(`(?s)MISSING CHUNK.*` + missing + `\s+0\s+0\s+0\s+[^\n]*\nFatal: 1 chunk\(s\) missing\n`)
+	cmd.ExpectExit()
+}
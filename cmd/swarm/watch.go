@@ -0,0 +1,344 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func watch(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 && len(args) != 2 {
+		utils.Fatalf("Usage: swarm watch [--interval <duration>] [--resource <manifest|name>] <dir> [<manifest>]")
+	}
+	var (
+		bzzapi      = strings.TrimRight(ctx.GlobalString(SwarmApiFlag.Name), "/")
+		defaultPath = ctx.GlobalString(SwarmUploadDefaultPath.Name)
+		interval    = ctx.Duration(SwarmWatchIntervalFlag.Name)
+		resource    = ctx.String(SwarmWatchResourceFlag.Name)
+		dir         = expandPath(args[0])
+		manifest    string
+	)
+	if len(args) == 2 {
+		manifest = args[1]
+	}
+	if interval <= 0 {
+		utils.Fatalf("The watch interval must be positive")
+	}
+	stat, err := os.Stat(dir)
+	if err != nil {
+		utils.Fatalf("Error opening directory: %s", err)
+	}
+	if !stat.IsDir() {
+		utils.Fatalf("%s is not a directory", dir)
+	}
+
+	client := swarm.NewClient(bzzapi)
+	w, err := newWatcher(client, dir, defaultPath, manifest)
+	if err != nil {
+		utils.Fatalf("Could not upload %s: %v", dir, err)
+	}
+	if manifest != "" {
+		if _, err := w.sync(); err != nil {
+			utils.Fatalf("Could not update manifest %s: %v", manifest, err)
+		}
+	}
+	publishWatched(ctx, client, resource, w.manifest)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		changed, err := w.sync()
+		if err != nil {
+			log.Error("Could not update manifest", "dir", dir, "manifest", w.manifest, "err", err)
+			continue
+		}
+		if changed {
+			publishWatched(ctx, client, resource, w.manifest)
+		}
+	}
+}
+
+// publishWatched prints the manifest hash of the watched directory and
+// publishes it as an update of the mutable resource if one is given
+func publishWatched(ctx *cli.Context, client *swarm.Client, resource, manifest string) {
+	addHistory(ctx, manifest)
+	fmt.Println(manifest)
+	if resource == "" {
+		return
+	}
+	if err := client.UpdateResource(resource, manifest); err != nil {
+		log.Error("Could not update resource", "resource", resource, "manifest", manifest, "err", err)
+	}
+}
+
+// watchedFile is the state of a local file used to detect its changes
+// without reading it
+type watchedFile struct {
+	size        int64
+	mode        int64
+	modTime     time.Time
+	link        string
+	contentType string
+}
+
+// watchedEntry is the state of a manifest entry compared with the local
+// files, hash is the content hash or the link target of the entry
+type watchedEntry struct {
+	hash        string
+	mode        int64
+	modTime     time.Time
+	contentType string
+}
+
+// manifestEntry returns the state of the manifest entry of a local file
+// with the given content hash, the mode and modification time are those
+// of a tar upload of the file
+func (f watchedFile) manifestEntry(hash string) watchedEntry {
+	mode := int64(os.FileMode(f.mode).Perm())
+	if f.link != "" {
+		mode |= int64(os.ModeSymlink)
+	}
+	return watchedEntry{hash: hash, mode: mode, modTime: f.modTime.Round(time.Second), contentType: f.contentType}
+}
+
+// watcher keeps a manifest in sync with a local directory, uploading only
+// the chunks of the files whose content differs from the entries of the
+// manifest
+type watcher struct {
+	client      *swarm.Client
+	dir         string
+	defaultPath string
+	manifest    string
+
+	// files are the local files as of the last sync, entries are the
+	// manifest entries by path
+	files   map[string]watchedFile
+	entries map[string]watchedEntry
+}
+
+// newWatcher returns a watcher of dir which updates manifest, the directory
+// is uploaded to a new manifest if manifest is empty
+func newWatcher(client *swarm.Client, dir, defaultPath, manifest string) (*watcher, error) {
+	w := &watcher{
+		client:      client,
+		dir:         dir,
+		defaultPath: defaultPath,
+		manifest:    manifest,
+		files:       make(map[string]watchedFile),
+		entries:     make(map[string]watchedEntry),
+	}
+	if manifest == "" {
+		// the files are scanned before the upload, so files changed while
+		// uploading are checked against the manifest by the next sync
+		files, err := w.scan()
+		if err != nil {
+			return nil, err
+		}
+		if w.manifest, err = client.UploadDirectory(dir, defaultPath, "", false); err != nil {
+			return nil, err
+		}
+		w.files = files
+		if f, ok := files[""]; ok {
+			hash, err := w.contentHash("", f)
+			if err != nil {
+				return nil, err
+			}
+			w.entries[""] = f.manifestEntry(hash)
+		}
+	}
+	entries, err := client.ListTree(w.manifest, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		hash := entry.Hash
+		if entry.IsSymlink() {
+			hash = "link:" + entry.LinkTarget
+		}
+		w.entries[entry.Path] = watchedEntry{hash: hash, mode: entry.Mode, modTime: entry.ModTime.Round(time.Second), contentType: entry.ContentType}
+	}
+	return w, nil
+}
+
+// uploader returns the uploader of the files of the watched directory, which
+// skips ignored paths the same way as a recursive upload
+func (w *watcher) uploader() (swarm.Uploader, error) {
+	ignore, err := swarm.LoadIgnore(w.dir)
+	if err != nil {
+		return nil, err
+	}
+	return &swarm.DirectoryUploader{Dir: w.dir, DefaultPath: w.defaultPath, Ignore: ignore}, nil
+}
+
+// scan returns the state of the files of the watched directory by path, the
+// default path file has an empty path
+func (w *watcher) scan() (map[string]watchedFile, error) {
+	uploader, err := w.uploader()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]watchedFile)
+	err = uploader.Upload(func(f *swarm.File) error {
+		f.Close()
+		files[f.Path] = watchedFile{size: f.Size, mode: f.Mode, modTime: f.ModTime, link: f.LinkTarget, contentType: f.ContentType}
+		return nil
+	})
+	return files, err
+}
+
+// localPath returns the path of the local file of a manifest path
+func (w *watcher) localPath(path string) string {
+	if path == "" {
+		return w.defaultPath
+	}
+	return filepath.Join(w.dir, filepath.FromSlash(path))
+}
+
+// contentHash returns the hash of the content of the local file at path as
+// it would be uploaded, or its target if the file is a symbolic link
+func (w *watcher) contentHash(path string, f watchedFile) (string, error) {
+	if f.link != "" {
+		return "link:" + f.link, nil
+	}
+	addr, err := hashFile(w.localPath(path))
+	if err != nil {
+		return "", err
+	}
+	return addr.Hex(), nil
+}
+
+// upload sends the chunks of the content of the local file at path which are
+// not stored by the gateway yet and returns the manifest entry of the file
+func (w *watcher) upload(path string, f watchedFile) (*api.ManifestEntry, *swarm.DeltaStats, error) {
+	var (
+		content io.Reader
+		size    = f.size
+	)
+	if f.link != "" {
+		// the link target is stored as the content of the entry
+		content = strings.NewReader(f.link)
+		size = int64(len(f.link))
+	} else {
+		file, err := os.Open(w.localPath(path))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+		content = file
+	}
+	hash, stats, err := w.client.UploadDelta(content, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	attrs := f.manifestEntry(hash)
+	return &api.ManifestEntry{
+		Hash:        hash,
+		Path:        path,
+		ContentType: attrs.contentType,
+		Mode:        attrs.mode,
+		Size:        size,
+		ModTime:     attrs.modTime,
+		LinkTarget:  f.link,
+	}, stats, nil
+}
+
+// sync uploads the files which changed since the last sync and differ from
+// the manifest entries, updates the entries whose attributes changed,
+// removes the entries of deleted files and reports whether the manifest
+// changed
+func (w *watcher) sync() (bool, error) {
+	files, err := w.scan()
+	if err != nil {
+		return false, err
+	}
+	entries := make(map[string]watchedEntry)
+	var changed []string
+	for path, f := range files {
+		entry, ok := w.entries[path]
+		if old, seen := w.files[path]; !seen || old != f || !ok {
+			hash, err := w.contentHash(path, f)
+			if err != nil {
+				return false, err
+			}
+			entry = f.manifestEntry(hash)
+		}
+		if old, ok := w.entries[path]; !ok || old.hash != entry.hash || old.mode != entry.mode || old.contentType != entry.contentType || !old.modTime.Equal(entry.modTime) {
+			changed = append(changed, path)
+		}
+		entries[path] = entry
+	}
+	var removed []string
+	for path := range w.entries {
+		if _, ok := files[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		w.files = files
+		return false, nil
+	}
+
+	// the entries of the changed files are added to the manifest by copying
+	// them from a manifest holding only these entries
+	manifest := w.manifest
+	var chunks, uploaded int
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		delta := &api.Manifest{}
+		for _, path := range changed {
+			entry, stats, err := w.upload(path, files[path])
+			if err != nil {
+				return false, fmt.Errorf("%s: %v", path, err)
+			}
+			delta.Entries = append(delta.Entries, *entry)
+			chunks += stats.Chunks
+			uploaded += stats.Uploaded
+		}
+		hash, err := w.client.UploadManifest(delta, false)
+		if err != nil {
+			return false, err
+		}
+		if manifest, err = w.client.Copy(hash, "", manifest, ""); err != nil {
+			return false, err
+		}
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		if manifest, err = w.client.RemovePath(manifest, path); err != nil {
+			return false, err
+		}
+	}
+	log.Info("Updated manifest", "dir", w.dir, "manifest", manifest, "changed", len(changed), "removed", len(removed), "chunks", chunks, "uploaded", uploaded)
+
+	w.manifest = manifest
+	w.files = files
+	w.entries = entries
+	return true, nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	swarm "github.com/ethereum/go-ethereum/swarm/api/client"
+	"github.com/ethereum/go-ethereum/swarm/multihash"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// TestCLISwarmWatch tests that 'swarm watch' keeps a manifest in sync with
+// the changes of a local directory and publishes them to a mutable resource
+func TestCLISwarmWatch(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-watch-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile := func(path, content string) {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("index.html", "index")
+	writeFile("css/style.css", "style")
+	writeFile("old.txt", "old")
+
+	client := swarm.NewClient(cluster.Nodes[0].URL)
+	manifest, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create a mutable resource serving the manifest
+	mh, err := multihash.Encode(common.FromHex(manifest), multihash.KECCAK_256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Post(cluster.Nodes[0].URL+"/bzz-resource:/watch.test/13", "application/octet-stream", strings.NewReader(hexutil.Encode(mh)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected HTTP status: %s", res.Status)
+	}
+	var resource storage.Address
+	if err := json.NewDecoder(res.Body).Decode(&resource); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := runSwarm(t, "--bzzapi", cluster.Nodes[0].URL, "watch", "--interval", "100ms", "--resource", resource.Hex(), dir, manifest)
+	defer cmd.Kill()
+
+	// the manifest matches the directory, so it is printed unchanged
+	cmd.Expect(manifest + "\n")
+
+	writeFile("index.html", "new index")
+	writeFile("img/logo.png", "logo")
+	if err := os.Remove(filepath.Join(dir, "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the resource follows the changes of the directory
+	checkFiles := func() error {
+		expected := map[string]string{
+			"index.html":    "new index",
+			"css/style.css": "style",
+			"img/logo.png":  "logo",
+			"old.txt":       "",
+		}
+		for path, content := range expected {
+			file, err := client.Download(resource.Hex(), path)
+			if content == "" {
+				if err == nil {
+					file.Close()
+					return fmt.Errorf("expected %s to be removed", path)
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			data, err := ioutil.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			if string(data) != content {
+				return fmt.Errorf("expected %s to contain %q, got %q", path, content, data)
+			}
+		}
+		return nil
+	}
+	for i := 0; ; i++ {
+		err := checkFiles()
+		if err == nil {
+			break
+		}
+		if i == 50 {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TestWatcherSync tests that a sync uploads only the changed chunks of a
+// modified file and updates the entries of files whose mode or modification
+// time changed
+func TestWatcherSync(t *testing.T) {
+	cluster := newTestCluster(t, 1)
+	defer cluster.Shutdown()
+
+	dir, err := ioutil.TempDir("", "swarm-watch-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	large := make([]byte, 3*storage.DefaultChunkSize)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "large.bin"), large, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "small.txt"), []byte("small"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := swarm.NewClient(cluster.Nodes[0].URL)
+	w, err := newWatcher(client, dir, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := w.sync(); err != nil || changed {
+		t.Fatalf("expected no changes, got %v (err %v)", changed, err)
+	}
+
+	// change a single chunk of the large file and only the attributes of
+	// the small one
+	large[storage.DefaultChunkSize] ^= 0xff
+	if err := ioutil.WriteFile(filepath.Join(dir, "large.bin"), large, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "small.txt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-time.Hour).Round(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "small.txt"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := w.sync(); err != nil || !changed {
+		t.Fatalf("expected changes, got %v (err %v)", changed, err)
+	}
+
+	entries, err := client.ListTree(w.manifest, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		switch entry.Path {
+		case "small.txt":
+			if os.FileMode(entry.Mode) != 0600 {
+				t.Fatalf("expected mode 0600, got %v", os.FileMode(entry.Mode))
+			}
+			if !entry.ModTime.Equal(modTime) {
+				t.Fatalf("expected modification time %v, got %v", modTime, entry.ModTime)
+			}
+		case "large.bin":
+			file, err := client.Download(w.manifest, "large.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(file)
+			file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, large) {
+				t.Fatal("large.bin content mismatch")
+			}
+		default:
+			t.Fatalf("unexpected entry %s", entry.Path)
+		}
+	}
+
+	// the unchanged chunks of the large file are already stored
+	_, stats, err := client.UploadDelta(bytes.NewReader(large), int64(len(large)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 0 {
+		t.Fatalf("expected no chunks to be uploaded again, got %d", stats.Uploaded)
+	}
+}
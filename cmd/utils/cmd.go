@@ -85,14 +85,12 @@ func StartNode(stack *node.Node) {
 	}()
 }
 
-func ImportChain(chain *core.BlockChain, fn string) error {
-	// Watch for Ctrl-C while the import is running.
-	// If a signal is received, the import will stop at the next batch.
+// watchInterrupt watches for Ctrl-C while an import is running, returning a
+// function reporting whether a signal was received and one to stop watching.
+func watchInterrupt() (func() bool, func()) {
 	interrupt := make(chan os.Signal, 1)
 	stop := make(chan struct{})
 	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
-	defer signal.Stop(interrupt)
-	defer close(interrupt)
 	go func() {
 		if _, ok := <-interrupt; ok {
 			log.Info("Interrupted during import, stopping at next batch")
@@ -107,6 +105,18 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			return false
 		}
 	}
+	cancel := func() {
+		signal.Stop(interrupt)
+		close(interrupt)
+	}
+	return checkInterrupt, cancel
+}
+
+func ImportChain(chain *core.BlockChain, fn string) error {
+	// Watch for Ctrl-C while the import is running.
+	// If a signal is received, the import will stop at the next batch.
+	checkInterrupt, cancel := watchInterrupt()
+	defer cancel()
 
 	log.Info("Importing blockchain", "file", fn)
 
@@ -237,6 +247,61 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	return nil
 }
 
+// ImportTransactions reads a batch of signed transactions from the specified
+// RLP stream file, as produced by ExportTransactions or the transaction journal.
+func ImportTransactions(fn string) (types.Transactions, error) {
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	var txs types.Transactions
+	for {
+		tx := new(types.Transaction)
+		if err := stream.Decode(tx); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("transaction %d: failed to parse: %v", len(txs), err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// ExportTransactions writes a batch of signed transactions into the specified
+// file as an RLP stream, truncating any data already present in the file.
+func ExportTransactions(txs types.Transactions, fn string) error {
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	for _, tx := range txs {
+		if err := rlp.Encode(writer, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 func ImportPreimages(db *ethdb.LDBDatabase, fn string) error {
 	log.Info("Importing preimages", "file", fn)
@@ -0,0 +1,73 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that signed transaction batches survive an export/import round trip,
+// both plain and gzipped.
+func TestTransactionBatchRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txbatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	signer := types.HomesteadSigner{}
+
+	var txs types.Transactions
+	for i := uint64(0); i < 5; i++ {
+		tx, err := types.SignTx(types.NewTransaction(i, common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction %d: %v", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	for _, name := range []string{"txs.rlp", "txs.rlp.gz"} {
+		fn := filepath.Join(dir, name)
+		if err := ExportTransactions(txs, fn); err != nil {
+			t.Fatalf("%s: failed to export: %v", name, err)
+		}
+		imported, err := ImportTransactions(fn)
+		if err != nil {
+			t.Fatalf("%s: failed to import: %v", name, err)
+		}
+		if len(imported) != len(txs) {
+			t.Fatalf("%s: transaction count mismatch: have %d, want %d", name, len(imported), len(txs))
+		}
+		for i, tx := range imported {
+			if tx.Hash() != txs[i].Hash() {
+				t.Errorf("%s: transaction %d mismatch: have %x, want %x", name, i, tx.Hash(), txs[i].Hash())
+			}
+			if from, err := types.Sender(signer, tx); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+				t.Errorf("%s: transaction %d sender mismatch: have %x, %v", name, i, from, err)
+			}
+		}
+	}
+}
@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -45,6 +46,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -55,6 +57,8 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/whisper/mailserver"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -118,6 +122,29 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+	}
+	DatabaseEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Key-value store engine to create new databases with (" + strings.Join(ethdb.Engines(), ", ") + ")",
+		Value: ethdb.DefaultEngine,
+	}
+	ExportSegmentFlag = cli.Uint64Flag{
+		Name:  "segment",
+		Usage: "Number of blocks per segment file when exporting into a directory (0 = single file export)",
+		Value: 0,
+	}
+	ExportCompressFlag = cli.BoolFlag{
+		Name:  "compress",
+		Usage: "Gzip compress the exported chain segment files",
+	}
+	ChainWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of parallel workers encoding or decoding chain segment files",
+		Value: runtime.NumCPU(),
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -126,6 +153,10 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	USBPathsFlag = cli.StringFlag{
+		Name:  "usb.paths",
+		Usage: "Comma separated base derivation paths to discover USB hardware wallet accounts on (default = standard and legacy paths)",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -167,14 +198,46 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "snap", "full", or "light")`,
 		Value: &defaultSyncMode,
 	}
+	DownloaderStateRangesFlag = cli.IntFlag{
+		Name:  "downloader.stateranges",
+		Usage: "Number of account ranges to retrieve concurrently during snap sync (power of two)",
+		Value: eth.DefaultConfig.Downloader.StateRanges,
+	}
+	DownloaderTargetRTTFlag = cli.DurationFlag{
+		Name:  "downloader.targetrtt",
+		Usage: "Minimum round trip time to size download requests for, larger values mean bigger requests (0 = measured)",
+		Value: eth.DefaultConfig.Downloader.TargetRTT,
+	}
+	DownloaderThroughputImpactFlag = cli.Float64Flag{
+		Name:  "downloader.throughputimpact",
+		Usage: "Impact a single delivery has on the estimated throughput of a peer",
+		Value: eth.DefaultConfig.Downloader.ThroughputImpact,
+	}
+	DownloaderMinThroughputFlag = cli.Float64Flag{
+		Name:  "downloader.minthroughput",
+		Usage: "Fraction of the best peer's throughput a peer needs to be assigned download requests",
+		Value: eth.DefaultConfig.Downloader.MinThroughput,
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain an address and topic index of the logs for fast log filtering (built in the background)",
+	}
+	TxLookupLimitFlag = cli.Uint64Flag{
+		Name:  "txlookuplimit",
+		Usage: "Number of recent blocks to maintain the transaction index for (0 = entire chain)",
+	}
+	NoTxLookupFlag = cli.BoolFlag{
+		Name:  "notxlookup",
+		Usage: "Disable the transaction index, making transactions unretrievable by hash",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -185,10 +248,32 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: eth.DefaultConfig.LightPeers,
 	}
+	ULCServersFlag = cli.StringFlag{
+		Name:  "ulc.servers",
+		Usage: "Comma separated enode URLs of trusted LES servers, enabling the ultra light client mode",
+		Value: "",
+	}
+	ULCFractionFlag = cli.IntFlag{
+		Name:  "ulc.fraction",
+		Usage: "Minimum percentage of trusted LES servers that need to announce a head (1-100)",
+		Value: eth.DefaultULCMinTrustedFraction,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.n",
+		Usage: "Scrypt CPU/memory cost parameter of new keys (power of two, 0 = default)",
+	}
+	KeyStoreScryptRFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.r",
+		Usage: "Scrypt block size parameter of new keys (0 = default)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.p",
+		Usage: "Scrypt parallelization parameter of new keys (0 = default)",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  "dashboard",
@@ -264,6 +349,11 @@ var (
 		Usage: "Price bump percentage to replace an already existing transaction",
 		Value: eth.DefaultConfig.TxPool.PriceBump,
 	}
+	TxPoolMaxTxSizeFlag = cli.Uint64Flag{
+		Name:  "txpool.maxtxsize",
+		Usage: "Maximum size in bytes of a transaction accepted into the pool",
+		Value: eth.DefaultConfig.TxPool.MaxTxSize,
+	}
 	TxPoolAccountSlotsFlag = cli.Uint64Flag{
 		Name:  "txpool.accountslots",
 		Usage: "Minimum number of executable transaction slots guaranteed per account",
@@ -402,6 +492,37 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded secret authenticating HTTP and WS-RPC clients with JSON Web Tokens",
+	}
+	RPCTokensFlag = cli.StringFlag{
+		Name:  "rpc.tokens",
+		Usage: "Path to a file of API tokens authenticating HTTP and WS-RPC clients, one \"<token> <namespace>,...\" per line",
+	}
+	RPCLimitsFlag = cli.StringFlag{
+		Name:  "rpc.limits",
+		Usage: "Comma separated rate limits and execution timeouts of HTTP and WS-RPC methods or namespaces (e.g. eth_getLogs:10:5s,debug:1:1m)",
+	}
+	RPCReadTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.readtimeout",
+		Usage: "Maximum duration for reading an HTTP-RPC request (0 = unlimited)",
+		Value: rpc.DefaultHTTPTimeouts.ReadTimeout,
+	}
+	RPCWriteTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.writetimeout",
+		Usage: "Maximum duration for processing and writing an HTTP-RPC response (0 = unlimited)",
+		Value: rpc.DefaultHTTPTimeouts.WriteTimeout,
+	}
+	RPCIdleTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.idletimeout",
+		Usage: "Maximum duration an idle HTTP-RPC keep-alive connection is kept open",
+		Value: rpc.DefaultHTTPTimeouts.IdleTimeout,
+	}
+	RPCNoKeepAliveFlag = cli.BoolFlag{
+		Name:  "rpc.nokeepalive",
+		Usage: "Close HTTP-RPC connections after every request",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -434,6 +555,40 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSSubscriptionBufferFlag = cli.IntFlag{
+		Name:  "ws.subbuffer",
+		Usage: "Maximum number of notifications buffered per WS-RPC subscription (0 = unbuffered)",
+		Value: 0,
+	}
+	WSSubscriptionOverflowFlag = cli.StringFlag{
+		Name:  "ws.suboverflow",
+		Usage: `Handling of notifications exceeding the WS-RPC subscription buffer ("drop" oldest or "close" connection)`,
+		Value: "drop",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: graphql.DefaultHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: graphql.DefaultPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -518,6 +673,11 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: eth.DefaultConfig.GPO.Percentile,
 	}
+	GpoMaxGasPriceFlag = BigFlag{
+		Name:  "gpomaxprice",
+		Usage: "Maximum gas price the oracle will suggest",
+		Value: eth.DefaultConfig.GPO.MaxPrice,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -532,6 +692,19 @@ var (
 		Usage: "Minimum POW accepted",
 		Value: whisper.DefaultMinimumPoW,
 	}
+	WhisperMailServerFlag = cli.BoolFlag{
+		Name:  "shh.mailserver",
+		Usage: "Archive the whisper envelopes and serve them to mail requests",
+	}
+	WhisperMailServerPasswordFlag = cli.StringFlag{
+		Name:  "shh.mailserver.password",
+		Usage: "Password file to derive the symmetric key of the mail requests from",
+	}
+	WhisperMailServerRetentionFlag = cli.DurationFlag{
+		Name:  "shh.mailserver.retention",
+		Usage: "Duration to keep the archived envelopes for (0 = forever)",
+		Value: mailserver.DefaultConfig.Retention,
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -693,6 +866,18 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCReadTimeoutFlag.Name) {
+		cfg.HTTPTimeouts.ReadTimeout = ctx.GlobalDuration(RPCReadTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCWriteTimeoutFlag.Name) {
+		cfg.HTTPTimeouts.WriteTimeout = ctx.GlobalDuration(RPCWriteTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCIdleTimeoutFlag.Name) {
+		cfg.HTTPTimeouts.IdleTimeout = ctx.GlobalDuration(RPCIdleTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCNoKeepAliveFlag.Name) {
+		cfg.HTTPTimeouts.DisableKeepAlive = ctx.GlobalBool(RPCNoKeepAliveFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -714,6 +899,63 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSSubscriptionBufferFlag.Name) {
+		cfg.WSSubscriptionBuffer.Size = ctx.GlobalInt(WSSubscriptionBufferFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSSubscriptionOverflowFlag.Name) {
+		if err := cfg.WSSubscriptionBuffer.Policy.UnmarshalText([]byte(ctx.GlobalString(WSSubscriptionOverflowFlag.Name))); err != nil {
+			Fatalf("Option %q: %v", WSSubscriptionOverflowFlag.Name, err)
+		}
+	}
+}
+
+// setRPCAuth sets the authentication of the HTTP and WebSocket RPC clients
+// from the set command line flags.
+func setRPCAuth(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTokensFlag.Name) {
+		tokens, err := readRPCTokens(ctx.GlobalString(RPCTokensFlag.Name))
+		if err != nil {
+			Fatalf("Failed to read RPC tokens: %v", err)
+		}
+		cfg.RPCTokens = tokens
+	}
+}
+
+// setRPCLimits sets the rate limits and execution timeouts of the HTTP and
+// WebSocket RPC calls from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCLimitsFlag.Name) {
+		limits, err := rpc.ParseLimits(ctx.GlobalString(RPCLimitsFlag.Name))
+		if err != nil {
+			Fatalf("Invalid RPC limits: %v", err)
+		}
+		cfg.RPCLimits = limits
+	}
+}
+
+// readRPCTokens reads a file of API tokens, each line holding a token and
+// the comma separated API namespaces it grants.
+func readRPCTokens(path string) (map[string][]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make(map[string][]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a token and its namespaces", path, i+1)
+		}
+		tokens[fields[0]] = splitAndTrim(fields[1])
+	}
+	return tokens, nil
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -783,6 +1025,27 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
 	}
 }
 
+// MakeDerivationPaths returns the base derivation paths to discover the accounts
+// of a USB hardware wallet with the given URL scheme on, either the ones specified
+// by the global --usb.paths flag or the defaults of the wallet vendor.
+func MakeDerivationPaths(ctx *cli.Context, scheme string) []accounts.DerivationPath {
+	if !ctx.GlobalIsSet(USBPathsFlag.Name) {
+		if scheme == "ledger" {
+			return []accounts.DerivationPath{accounts.DefaultLedgerBaseDerivationPath, accounts.DefaultBaseDerivationPath}
+		}
+		return []accounts.DerivationPath{accounts.DefaultBaseDerivationPath}
+	}
+	var paths []accounts.DerivationPath
+	for _, text := range strings.Split(ctx.GlobalString(USBPathsFlag.Name), ",") {
+		path, err := accounts.ParseDerivationPath(text)
+		if err != nil {
+			Fatalf("Option %q: invalid derivation path %q: %v", USBPathsFlag.Name, text, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
@@ -874,6 +1137,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAuth(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -886,6 +1151,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	case ctx.GlobalBool(RinkebyFlag.Name):
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "rinkeby")
 	}
+	if ctx.GlobalIsSet(DatabaseEngineFlag.Name) {
+		cfg.DatabaseEngine = ctx.GlobalString(DatabaseEngineFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
@@ -893,6 +1161,15 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		cfg.KeyStoreScryptN = ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptRFlag.Name) {
+		cfg.KeyStoreScryptR = ctx.GlobalInt(KeyStoreScryptRFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		cfg.KeyStoreScryptP = ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
@@ -905,6 +1182,30 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoMaxGasPriceFlag.Name) {
+		cfg.MaxPrice = GlobalBig(ctx, GpoMaxGasPriceFlag.Name)
+	}
+}
+
+func setDownloader(ctx *cli.Context, cfg *downloader.Config) {
+	if ctx.GlobalIsSet(DownloaderStateRangesFlag.Name) {
+		cfg.StateRanges = ctx.GlobalInt(DownloaderStateRangesFlag.Name)
+		if cfg.StateRanges == 0 {
+			Fatalf("--%s must be positive", DownloaderStateRangesFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(DownloaderTargetRTTFlag.Name) {
+		cfg.TargetRTT = ctx.GlobalDuration(DownloaderTargetRTTFlag.Name)
+	}
+	if ctx.GlobalIsSet(DownloaderThroughputImpactFlag.Name) {
+		cfg.ThroughputImpact = ctx.GlobalFloat64(DownloaderThroughputImpactFlag.Name)
+		if cfg.ThroughputImpact == 0 {
+			Fatalf("--%s must be positive", DownloaderThroughputImpactFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(DownloaderMinThroughputFlag.Name) {
+		cfg.MinThroughput = ctx.GlobalFloat64(DownloaderMinThroughputFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -923,6 +1224,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {
 		cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolMaxTxSizeFlag.Name) {
+		cfg.MaxTxSize = ctx.GlobalUint64(TxPoolMaxTxSizeFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolAccountSlotsFlag.Name) {
 		cfg.AccountSlots = ctx.GlobalUint64(TxPoolAccountSlotsFlag.Name)
 	}
@@ -940,6 +1244,21 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	}
 }
 
+// setULC creates the ultra light client configuration from the set command line
+// flags, if any trusted servers are given.
+func setULC(ctx *cli.Context, cfg *eth.Config) {
+	if !ctx.GlobalIsSet(ULCServersFlag.Name) {
+		return
+	}
+	if cfg.SyncMode != downloader.LightSync {
+		Fatalf("--%s requires the light sync mode", ULCServersFlag.Name)
+	}
+	cfg.ULC = &eth.ULCConfig{
+		TrustedServers:     splitAndTrim(ctx.GlobalString(ULCServersFlag.Name)),
+		MinTrustedFraction: ctx.GlobalInt(ULCFractionFlag.Name),
+	}
+}
+
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	if ctx.GlobalIsSet(EthashCacheDirFlag.Name) {
 		cfg.Ethash.CacheDir = ctx.GlobalString(EthashCacheDirFlag.Name)
@@ -1020,6 +1339,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
+	setDownloader(ctx, &cfg.Downloader)
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
 
@@ -1037,6 +1357,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	setULC(ctx, cfg)
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1044,6 +1365,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
@@ -1051,6 +1375,16 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
+		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(NoTxLookupFlag.Name) {
+		cfg.NoTxLookup = ctx.GlobalBool(NoTxLookupFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
@@ -1158,6 +1492,55 @@ func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+// RegisterShhMailServerService configures a whisper mail server archiving the
+// envelopes of the whisper service into the node database, and adds it to the
+// given node.
+func RegisterShhMailServerService(stack *node.Node, ctx *cli.Context, shh *whisper.Config) {
+	cfg := mailserver.DefaultConfig
+	cfg.MinPoW = shh.MinimumAcceptedPOW
+	if ctx.GlobalIsSet(WhisperMailServerRetentionFlag.Name) {
+		cfg.Retention = ctx.GlobalDuration(WhisperMailServerRetentionFlag.Name)
+	}
+	path := ctx.GlobalString(WhisperMailServerPasswordFlag.Name)
+	if path == "" {
+		Fatalf("Whisper mail server requires --%s", WhisperMailServerPasswordFlag.Name)
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		Fatalf("Failed to read mail server password file: %v", err)
+	}
+	cfg.Password = strings.TrimRight(strings.SplitN(string(text), "\n", 2)[0], "\r")
+
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return mailserver.NewService(ctx, &cfg)
+	}); err != nil {
+		Fatalf("Failed to register the Whisper mail server: %v", err)
+	}
+}
+
+// RegisterGraphQLService adds the GraphQL API of the chain data to the node,
+// served on the endpoint configured by the GraphQL flags.
+func RegisterGraphQLService(stack *node.Node, ctx *cli.Context) {
+	endpoint := fmt.Sprintf("%s:%d", ctx.GlobalString(GraphQLListenAddrFlag.Name), ctx.GlobalInt(GraphQLPortFlag.Name))
+	cors := splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	vhosts := splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Serve the chain of the eth service, or of the les service in light mode
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(ethServ.APIBackend, endpoint, cors, vhosts)
+		}
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts)
+		}
+		return nil, errors.New("no Ethereum service")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // th egiven node.
 func RegisterEthStatsService(stack *node.Node, url string) {
@@ -1187,11 +1570,15 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 		handles = makeDatabaseHandles()
 	)
-	name := "chaindata"
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
 	if ctx.GlobalBool(LightModeFlag.Name) {
-		name = "lightchaindata"
+		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles)
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.GlobalString(AncientFlag.Name), "")
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1240,9 +1627,11 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cache := &core.CacheConfig{
-		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
-		TrieNodeLimit: eth.DefaultConfig.TrieCache,
-		TrieTimeLimit: eth.DefaultConfig.TrieTimeout,
+		Disabled:         ctx.GlobalString(GCModeFlag.Name) == "archive",
+		TrieNodeLimit:    eth.DefaultConfig.TrieCache,
+		TrieTimeLimit:    eth.DefaultConfig.TrieTimeout,
+		TxLookupLimit:    ctx.GlobalUint64(TxLookupLimitFlag.Name),
+		TxLookupDisabled: ctx.GlobalBool(NoTxLookupFlag.Name),
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
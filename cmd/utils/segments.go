@@ -0,0 +1,335 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// chainManifestFile is the name of the file describing the segments of a chain
+// exported into a directory.
+const chainManifestFile = "manifest.json"
+
+// ChainManifest describes a chain exported into a directory of segment files,
+// each containing a consecutive range of RLP encoded blocks.
+type ChainManifest struct {
+	Genesis  common.Hash     `json:"genesis"`
+	First    uint64          `json:"first"`
+	Last     uint64          `json:"last"`
+	Segments []*ChainSegment `json:"segments"`
+}
+
+// ChainSegment describes a single segment file of an exported chain.
+type ChainSegment struct {
+	File     string      `json:"file"`
+	First    uint64      `json:"first"`
+	Last     uint64      `json:"last"`
+	Checksum common.Hash `json:"sha256"` // SHA256 of the segment file as stored on disk
+}
+
+// ReadChainManifest loads the manifest of a chain exported into the given
+// directory.
+func ReadChainManifest(dir string) (*ChainManifest, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, chainManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(ChainManifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("invalid chain manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// ExportChainSegments exports a range of the blockchain into the specified
+// directory, splitting it into segment files of the given number of blocks that
+// are encoded (and optionally gzip compressed) by parallel workers. A manifest
+// with the checksums of the segments is written after all of them succeeded.
+func ExportChainSegments(blockchain *core.BlockChain, dir string, first uint64, last uint64, size uint64, workers int, compress bool) error {
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	if size == 0 {
+		return fmt.Errorf("export failed: zero segment size")
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	log.Info("Exporting blockchain segments", "dir", dir, "first", first, "last", last, "size", size, "workers", workers)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	manifest := &ChainManifest{
+		Genesis: blockchain.Genesis().Hash(),
+		First:   first,
+		Last:    last,
+	}
+	for start := first; start <= last; start += size {
+		end := start + size - 1
+		if end > last || end < start {
+			end = last
+		}
+		file := fmt.Sprintf("blocks-%09d-%09d.rlp", start, end)
+		if compress {
+			file += ".gz"
+		}
+		manifest.Segments = append(manifest.Segments, &ChainSegment{File: file, First: start, Last: end})
+		if end == last {
+			break
+		}
+	}
+	// Encode the segments concurrently, aborting all workers on the first failure
+	var (
+		tasks = make(chan *ChainSegment, len(manifest.Segments))
+		abort = make(chan struct{})
+		once  sync.Once
+		fail  error
+		pend  sync.WaitGroup
+	)
+	for _, segment := range manifest.Segments {
+		tasks <- segment
+	}
+	close(tasks)
+
+	for i := 0; i < workers; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+			for segment := range tasks {
+				select {
+				case <-abort:
+					return
+				default:
+				}
+				if err := exportChainSegment(blockchain, dir, segment); err != nil {
+					once.Do(func() {
+						fail = fmt.Errorf("segment %s: %v", segment.File, err)
+						close(abort)
+					})
+					return
+				}
+				log.Info("Exported chain segment", "file", segment.File, "first", segment.First, "last", segment.Last)
+			}
+		}()
+	}
+	pend.Wait()
+	if fail != nil {
+		return fail
+	}
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, chainManifestFile), blob, 0644); err != nil {
+		return err
+	}
+	log.Info("Exported blockchain segments", "dir", dir, "segments", len(manifest.Segments))
+	return nil
+}
+
+// exportChainSegment writes the blocks of a single segment into its file in the
+// export directory, filling in the checksum of the written data.
+func exportChainSegment(blockchain *core.BlockChain, dir string, segment *ChainSegment) error {
+	fh, err := os.OpenFile(filepath.Join(dir, segment.File), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var (
+		hasher = sha256.New()
+		buffer = bufio.NewWriter(io.MultiWriter(fh, hasher))
+		writer io.Writer
+	)
+	writer = buffer
+	if strings.HasSuffix(segment.File, ".gz") {
+		writer = gzip.NewWriter(buffer)
+	}
+	if err := blockchain.ExportN(writer, segment.First, segment.Last); err != nil {
+		return err
+	}
+	if gz, ok := writer.(*gzip.Writer); ok {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := buffer.Flush(); err != nil {
+		return err
+	}
+	copy(segment.Checksum[:], hasher.Sum(nil))
+	return nil
+}
+
+// chainSegmentResult is the outcome of loading and validating a segment file.
+type chainSegmentResult struct {
+	blocks []*types.Block
+	err    error
+}
+
+// ImportChainSegments imports a chain exported into the specified directory by
+// ExportChainSegments. Segments are loaded, checksummed, decoded and have their
+// transaction senders recovered by parallel workers ahead of the import, while
+// the blocks themselves are inserted into the chain in order.
+func ImportChainSegments(chain *core.BlockChain, dir string, workers int) error {
+	checkInterrupt, cancel := watchInterrupt()
+	defer cancel()
+
+	log.Info("Importing blockchain segments", "dir", dir, "workers", workers)
+
+	manifest, err := ReadChainManifest(dir)
+	if err != nil {
+		return err
+	}
+	if genesis := chain.Genesis().Hash(); manifest.Genesis != genesis {
+		return fmt.Errorf("genesis mismatch: have %x, want %x", genesis, manifest.Genesis)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	// Load the segments concurrently, at most as many ahead of the import as there
+	// are workers to keep the memory use bounded
+	var (
+		results = make([]chan *chainSegmentResult, len(manifest.Segments))
+		slots   = make(chan struct{}, workers)
+		abort   = make(chan struct{})
+	)
+	defer close(abort)
+
+	for i := range results {
+		results[i] = make(chan *chainSegmentResult, 1)
+	}
+	go func() {
+		for i, segment := range manifest.Segments {
+			select {
+			case slots <- struct{}{}:
+			case <-abort:
+				return
+			}
+			go func(segment *ChainSegment, result chan *chainSegmentResult) {
+				blocks, err := loadChainSegment(chain, dir, segment)
+				result <- &chainSegmentResult{blocks: blocks, err: err}
+			}(segment, results[i])
+		}
+	}()
+	var parent *types.Block
+	for i, segment := range manifest.Segments {
+		if checkInterrupt() {
+			return fmt.Errorf("interrupted")
+		}
+		result := <-results[i]
+		<-slots
+
+		if result.err != nil {
+			return fmt.Errorf("segment %s: %v", segment.File, result.err)
+		}
+		blocks := result.blocks
+		if parent != nil && len(blocks) > 0 && blocks[0].ParentHash() != parent.Hash() {
+			return fmt.Errorf("segment %s: not contiguous with previous segment", segment.File)
+		}
+		if len(blocks) > 0 {
+			parent = blocks[len(blocks)-1]
+		}
+		// Don't import the genesis block
+		if len(blocks) > 0 && blocks[0].NumberU64() == 0 {
+			blocks = blocks[1:]
+		}
+		for len(blocks) > 0 {
+			if checkInterrupt() {
+				return fmt.Errorf("interrupted")
+			}
+			batch := blocks
+			if len(batch) > importBatchSize {
+				batch = batch[:importBatchSize]
+			}
+			blocks = blocks[len(batch):]
+
+			missing := missingBlocks(chain, batch)
+			if len(missing) == 0 {
+				log.Info("Skipping batch as all blocks present", "segment", segment.File, "first", batch[0].Hash(), "last", batch[len(batch)-1].Hash())
+				continue
+			}
+			if index, err := chain.InsertChain(missing); err != nil {
+				return fmt.Errorf("invalid block %d: %v", missing[index].NumberU64(), err)
+			}
+		}
+		log.Info("Imported chain segment", "file", segment.File, "first", segment.First, "last", segment.Last)
+	}
+	return nil
+}
+
+// loadChainSegment reads a segment file from the export directory, verifies its
+// checksum and contents, and pre-recovers the senders of its transactions.
+func loadChainSegment(chain *core.BlockChain, dir string, segment *ChainSegment) ([]*types.Block, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, segment.File))
+	if err != nil {
+		return nil, err
+	}
+	if checksum := sha256.Sum256(blob); common.Hash(checksum) != segment.Checksum {
+		return nil, fmt.Errorf("checksum mismatch: have %x, want %x", checksum, segment.Checksum)
+	}
+	var reader io.Reader = bytes.NewReader(blob)
+	if strings.HasSuffix(segment.File, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	blocks := make([]*types.Block, 0, segment.Last-segment.First+1)
+	for number := segment.First; number <= segment.Last; number++ {
+		block := new(types.Block)
+		if err := stream.Decode(block); err != nil {
+			return nil, fmt.Errorf("at block %d: %v", number, err)
+		}
+		if block.NumberU64() != number {
+			return nil, fmt.Errorf("block number mismatch: have %d, want %d", block.NumberU64(), number)
+		}
+		if len(blocks) > 0 && block.ParentHash() != blocks[len(blocks)-1].Hash() {
+			return nil, fmt.Errorf("block %d: not contiguous with its parent", number)
+		}
+		// Recover the senders, caching them in the transactions for the import
+		signer := types.MakeSigner(chain.Config(), block.Number())
+		for _, tx := range block.Transactions() {
+			if _, err := types.Sender(signer, tx); err != nil {
+				return nil, fmt.Errorf("block %d: invalid transaction %x: %v", number, tx.Hash(), err)
+			}
+		}
+		blocks = append(blocks, block)
+	}
+	if _, err := stream.Raw(); err != io.EOF {
+		return nil, fmt.Errorf("trailing data after block %d", segment.Last)
+	}
+	return blocks, nil
+}
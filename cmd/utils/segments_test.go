@@ -0,0 +1,158 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newSegmentTestChain creates a blockchain with the given number of blocks on top
+// of a funded genesis, each containing a value transfer.
+func newSegmentTestChain(t *testing.T, blocks int) (*core.BlockChain, *core.Genesis) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		db      = ethdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, blocks, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(address), common.Address{0x01}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return blockchain, gspec
+}
+
+// Tests that a chain exported into segments can be imported into an empty chain.
+func TestChainSegmentsRoundtrip(t *testing.T) {
+	testChainSegmentsRoundtrip(t, false)
+	testChainSegmentsRoundtrip(t, true)
+}
+
+func testChainSegmentsRoundtrip(t *testing.T, compress bool) {
+	dir, err := ioutil.TempDir("", "chainsegments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source, gspec := newSegmentTestChain(t, 25)
+	defer source.Stop()
+
+	if err := ExportChainSegments(source, dir, 0, 25, 7, 3, compress); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	manifest, err := ReadChainManifest(dir)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if len(manifest.Segments) != 4 {
+		t.Fatalf("segment count mismatch: have %d, want %d", len(manifest.Segments), 4)
+	}
+	for _, segment := range manifest.Segments {
+		if strings.HasSuffix(segment.File, ".gz") != compress {
+			t.Errorf("segment %s: compression mismatch: want %v", segment.File, compress)
+		}
+	}
+	if last := manifest.Segments[3]; last.First != 21 || last.Last != 25 {
+		t.Errorf("last segment range mismatch: have [%d, %d], want [21, 25]", last.First, last.Last)
+	}
+	// Import the segments into a fresh chain and check that it is complete
+	db := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	target, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer target.Stop()
+
+	if err := ImportChainSegments(target, dir, 2); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if have, want := target.CurrentBlock().Hash(), source.CurrentBlock().Hash(); have != want {
+		t.Errorf("head mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that corrupted segments are rejected by the import.
+func TestChainSegmentsCorrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainsegments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source, gspec := newSegmentTestChain(t, 10)
+	defer source.Stop()
+
+	if err := ExportChainSegments(source, dir, 0, 10, 4, 2, false); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	manifest, err := ReadChainManifest(dir)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	path := filepath.Join(dir, manifest.Segments[1].File)
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read segment: %v", err)
+	}
+	blob[len(blob)-1] ^= 0xff
+	if err := ioutil.WriteFile(path, blob, 0644); err != nil {
+		t.Fatalf("failed to corrupt segment: %v", err)
+	}
+	db := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	target, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer target.Stop()
+
+	err = ImportChainSegments(target, dir, 2)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("corrupted import error mismatch: have %v, want checksum mismatch", err)
+	}
+	// Only the blocks of the segments before the corrupted one must be imported
+	if head := target.CurrentBlock().NumberU64(); head != manifest.Segments[0].Last {
+		t.Errorf("head mismatch: have %d, want %d", head, manifest.Segments[0].Last)
+	}
+}
@@ -17,12 +17,30 @@
 package clique
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+const (
+	// statsBlocks is the default number of recent blocks to gather the signer
+	// statistics over.
+	statsBlocks = 64
+
+	// maxRangeBlocks is the maximum number of blocks to inspect in a single
+	// historical query to avoid overloading the node.
+	maxRangeBlocks = 65536
+)
+
+// errInvalidRange is returned if a historical query is requested for a range of
+// blocks starting after it ends or spanning too many blocks.
+var errInvalidRange = errors.New("invalid block range")
+
 // API is a user facing RPC API to allow controlling the signer and voting
 // mechanisms of the proof-of-authority scheme.
 type API struct {
@@ -117,3 +135,186 @@ func (api *API) Discard(address common.Address) {
 
 	delete(api.clique.proposals, address)
 }
+
+// Schedule is the signing schedule of the upcoming blocks.
+type Schedule struct {
+	Number   uint64           `json:"number"`   // Number of the first upcoming block
+	InTurn   []common.Address `json:"inturn"`   // In-turn signers of the upcoming blocks, in order
+	Eligible []common.Address `json:"eligible"` // Signers allowed to seal the first upcoming block
+	Recents  []common.Address `json:"recents"`  // Signers waiting for others to seal before they may again
+}
+
+// GetSchedule retrieves the in-turn signers of the given number of upcoming blocks
+// (or one round of all the signers if none requested), along with the signers
+// currently permitted to seal the next block.
+func (api *API) GetSchedule(count *uint64) (*Schedule, error) {
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	signers := snap.signers()
+
+	n := uint64(len(signers))
+	if count != nil {
+		n = *count
+	}
+	if n > maxRangeBlocks {
+		return nil, errInvalidRange
+	}
+	schedule := &Schedule{
+		Number:   snap.Number + 1,
+		InTurn:   make([]common.Address, 0, n),
+		Eligible: []common.Address{},
+		Recents:  []common.Address{},
+	}
+	for i := uint64(0); i < n && len(signers) > 0; i++ {
+		schedule.InTurn = append(schedule.InTurn, signers[(schedule.Number+i)%uint64(len(signers))])
+	}
+	// Signers among the recent ones may only seal if the next block shifts them out
+	limit := uint64(len(signers)/2 + 1)
+	for _, signer := range signers {
+		eligible := true
+		for seen, recent := range snap.Recents {
+			if recent == signer && (schedule.Number < limit || seen > schedule.Number-limit) {
+				eligible = false
+			}
+		}
+		if eligible {
+			schedule.Eligible = append(schedule.Eligible, signer)
+		} else {
+			schedule.Recents = append(schedule.Recents, signer)
+		}
+	}
+	return schedule, nil
+}
+
+// GetVoteHistory retrieves all the votes cast in the given range of blocks, even
+// the ones already passed or discarded. The range defaults to the blocks since
+// the last epoch checkpoint up to the current head.
+func (api *API) GetVoteHistory(from *rpc.BlockNumber, to *rpc.BlockNumber) ([]*Vote, error) {
+	start, end, err := api.blockRange(from, to, func(end uint64) uint64 {
+		return end - end%api.clique.config.Epoch
+	})
+	if err != nil {
+		return nil, err
+	}
+	votes := []*Vote{}
+	for number := start; number <= end; number++ {
+		header := api.chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("missing block %d", number)
+		}
+		// Checkpoint blocks and blocks without a beneficiary carry no votes
+		if number == 0 || header.Coinbase == (common.Address{}) {
+			continue
+		}
+		signer, err := api.clique.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, &Vote{
+			Signer:    signer,
+			Block:     number,
+			Address:   header.Coinbase,
+			Authorize: bytes.Equal(header.Nonce[:], nonceAuthVote),
+		})
+	}
+	return votes, nil
+}
+
+// SignerStats is the sealing activity of a single signer.
+type SignerStats struct {
+	Blocks    uint64 `json:"blocks"`    // Number of blocks sealed by the signer
+	InTurn    uint64 `json:"inturn"`    // Number of blocks sealed while being in-turn
+	OutOfTurn uint64 `json:"outofturn"` // Number of blocks sealed while being out-of-turn
+	LastBlock uint64 `json:"lastBlock"` // Number of the last block sealed by the signer
+}
+
+// Stats is the sealing activity of the signers over a range of blocks.
+type Stats struct {
+	From          uint64                          `json:"from"`          // First block of the range
+	To            uint64                          `json:"to"`            // Last block of the range
+	InTurnPercent float64                         `json:"inturnPercent"` // Percentage of blocks sealed in-turn
+	Signers       map[common.Address]*SignerStats `json:"signers"`       // Activity of the current and past signers
+}
+
+// GetSignerStats retrieves the number of blocks sealed by each signer in the given
+// range of blocks, in-turn and out-of-turn. The range defaults to the 64 blocks up
+// to the current head. Current signers that sealed no blocks are reported too.
+func (api *API) GetSignerStats(from *rpc.BlockNumber, to *rpc.BlockNumber) (*Stats, error) {
+	start, end, err := api.blockRange(from, to, func(end uint64) uint64 {
+		if end < statsBlocks {
+			return 0
+		}
+		return end - statsBlocks + 1
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The genesis block isn't sealed by anyone
+	if start == 0 {
+		start = 1
+	}
+	header := api.chain.GetHeaderByNumber(end)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{From: start, To: end, Signers: make(map[common.Address]*SignerStats)}
+	for signer := range snap.Signers {
+		stats.Signers[signer] = new(SignerStats)
+	}
+	var sealed, inturn uint64
+	for number := start; number <= end; number++ {
+		header := api.chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("missing block %d", number)
+		}
+		signer, err := api.clique.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		if stats.Signers[signer] == nil {
+			stats.Signers[signer] = new(SignerStats)
+		}
+		signerStats := stats.Signers[signer]
+
+		signerStats.Blocks++
+		signerStats.LastBlock = number
+		if header.Difficulty.Cmp(diffInTurn) == 0 {
+			signerStats.InTurn++
+			inturn++
+		} else {
+			signerStats.OutOfTurn++
+		}
+		sealed++
+	}
+	if sealed > 0 {
+		stats.InTurnPercent = float64(inturn) * 100 / float64(sealed)
+	}
+	return stats, nil
+}
+
+// blockRange resolves an optionally specified range of blocks, defaulting the end
+// to the current head and the start to the one computed from the end.
+func (api *API) blockRange(from *rpc.BlockNumber, to *rpc.BlockNumber, start func(end uint64) uint64) (uint64, uint64, error) {
+	end := api.chain.CurrentHeader().Number.Uint64()
+	if to != nil && *to >= 0 {
+		if uint64(*to) > end {
+			return 0, 0, errUnknownBlock
+		}
+		end = uint64(*to)
+	}
+	begin := start(end)
+	if from != nil && *from >= 0 {
+		begin = uint64(*from)
+	}
+	if begin > end || end-begin >= maxRangeBlocks {
+		return 0, 0, errInvalidRange
+	}
+	return begin, end, nil
+}
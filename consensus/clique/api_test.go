@@ -0,0 +1,200 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTesterAPI creates a chain of the given blocks sealed by the named signers
+// in order, casting the given votes, and returns the clique API on top of it.
+func newTesterAPI(t *testing.T, accounts *testerAccountPool, signers []string, sealers []string, votes map[int]testerVote) *API {
+	// Create the genesis block with the initial set of signers
+	genesis := &core.Genesis{ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal)}
+	for i, signer := range signers {
+		copy(genesis.ExtraData[extraVanity+i*common.AddressLength:], accounts.address(signer).Bytes())
+	}
+	db := ethdb.NewMemDatabase()
+	genesis.Config = params.AllCliqueProtocolChanges
+	genesis.MustCommit(db)
+
+	engine := New(&params.CliqueConfig{Epoch: 30000}, db)
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	blocks, _ := core.GenerateChain(genesis.Config, chain.Genesis(), engine, db, len(sealers), nil)
+
+	// Sign the blocks with the requested sealers, using the proper difficulties
+	parent := chain.Genesis()
+	for i, block := range blocks {
+		header := block.Header()
+		header.ParentHash = parent.Hash()
+		header.Extra = make([]byte, extraVanity+extraSeal)
+
+		if vote, ok := votes[i]; ok {
+			header.Coinbase = accounts.address(vote.voted)
+			if vote.auth {
+				copy(header.Nonce[:], nonceAuthVote)
+			}
+		}
+		snap, err := engine.snapshot(chain, parent.NumberU64(), parent.Hash(), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve snapshot: %v", i+1, err)
+		}
+		header.Difficulty = CalcDifficulty(snap, accounts.address(sealers[i]))
+		accounts.sign(header, sealers[i])
+
+		blocks[i] = block.WithSeal(header)
+		if _, err := chain.InsertChain(blocks[i : i+1]); err != nil {
+			t.Fatalf("block %d: failed to insert: %v", i+1, err)
+		}
+		parent = blocks[i]
+	}
+	return &API{chain: chain, clique: engine}
+}
+
+// Tests that the upcoming signing schedule is reported correctly.
+func TestAPISchedule(t *testing.T) {
+	accounts := newTesterAccountPool()
+	api := newTesterAPI(t, accounts, []string{"A", "B", "C"}, []string{"A", "B"}, nil)
+
+	snap, err := api.GetSnapshot(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	signers := snap.signers()
+
+	schedule, err := api.GetSchedule(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve schedule: %v", err)
+	}
+	if schedule.Number != 3 {
+		t.Errorf("schedule start mismatch: have %d, want %d", schedule.Number, 3)
+	}
+	want := []common.Address{signers[0], signers[1], signers[2]}
+	if !reflect.DeepEqual(schedule.InTurn, want) {
+		t.Errorf("in-turn signers mismatch: have %x, want %x", schedule.InTurn, want)
+	}
+	// With three signers, only the last sealer is banned from sealing the next block
+	if len(schedule.Recents) != 1 || schedule.Recents[0] != accounts.address("B") {
+		t.Errorf("recent signers mismatch: have %x, want [%x]", schedule.Recents, accounts.address("B"))
+	}
+	if len(schedule.Eligible) != 2 {
+		t.Errorf("eligible signer count mismatch: have %d, want %d", len(schedule.Eligible), 2)
+	}
+	count := uint64(5)
+	if schedule, err = api.GetSchedule(&count); err != nil {
+		t.Fatalf("failed to retrieve long schedule: %v", err)
+	}
+	want = append(want, signers[0], signers[1])
+	if !reflect.DeepEqual(schedule.InTurn, want) {
+		t.Errorf("long in-turn signers mismatch: have %x, want %x", schedule.InTurn, want)
+	}
+}
+
+// Tests that votes are reported even after they passed and were removed from the
+// snapshots.
+func TestAPIVoteHistory(t *testing.T) {
+	accounts := newTesterAccountPool()
+	api := newTesterAPI(t, accounts, []string{"A"}, []string{"A", "B", "A"}, map[int]testerVote{
+		0: {voted: "B", auth: true},
+		1: {voted: "C", auth: true},
+	})
+	votes, err := api.GetVoteHistory(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve vote history: %v", err)
+	}
+	want := []*Vote{
+		{Signer: accounts.address("A"), Block: 1, Address: accounts.address("B"), Authorize: true},
+		{Signer: accounts.address("B"), Block: 2, Address: accounts.address("C"), Authorize: true},
+	}
+	if !reflect.DeepEqual(votes, want) {
+		t.Errorf("vote history mismatch: have %v, want %v", votes, want)
+	}
+	// The passed vote must be gone from the snapshot
+	snap, err := api.GetSnapshot(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if len(snap.Votes) != 1 {
+		t.Errorf("snapshot vote count mismatch: have %d, want %d", len(snap.Votes), 1)
+	}
+	// Ranges must be respected and validated
+	from, to := rpc.BlockNumber(3), rpc.BlockNumber(3)
+	if votes, err = api.GetVoteHistory(&from, &to); err != nil || len(votes) != 0 {
+		t.Errorf("ranged vote history mismatch: have %v, %v, want none", votes, err)
+	}
+	from, to = 3, 2
+	if _, err := api.GetVoteHistory(&from, &to); err != errInvalidRange {
+		t.Errorf("inverted range error mismatch: have %v, want %v", err, errInvalidRange)
+	}
+}
+
+// Tests that the per signer sealing statistics are gathered correctly.
+func TestAPISignerStats(t *testing.T) {
+	accounts := newTesterAccountPool()
+	api := newTesterAPI(t, accounts, []string{"A", "B", "C"}, []string{"A", "B", "C", "A", "B"}, nil)
+
+	snap, err := api.GetSnapshot(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	stats, err := api.GetSignerStats(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve stats: %v", err)
+	}
+	if stats.From != 1 || stats.To != 5 {
+		t.Errorf("range mismatch: have [%d, %d], want [1, 5]", stats.From, stats.To)
+	}
+	var inturn uint64
+	for i, name := range []string{"A", "B", "C"} {
+		signer := accounts.address(name)
+
+		want := &SignerStats{Blocks: 2, LastBlock: uint64(i + 4)}
+		if name == "C" {
+			want = &SignerStats{Blocks: 1, LastBlock: 3}
+		}
+		// Recompute the expected in-turn count from the sealing order
+		for number, sealer := range []string{"A", "B", "C", "A", "B"} {
+			if sealer != name {
+				continue
+			}
+			if snap.inturn(uint64(number+1), signer) {
+				want.InTurn++
+			} else {
+				want.OutOfTurn++
+			}
+		}
+		inturn += want.InTurn
+
+		if have := stats.Signers[signer]; !reflect.DeepEqual(have, want) {
+			t.Errorf("signer %s: stats mismatch: have %+v, want %+v", name, have, want)
+		}
+	}
+	if want := float64(inturn) * 100 / 5; stats.InTurnPercent != want {
+		t.Errorf("in-turn percentage mismatch: have %v, want %v", stats.InTurnPercent, want)
+	}
+}
@@ -130,6 +130,10 @@ func (c *Console) init(preload []string) error {
 	if _, err := c.jsre.Run("var web3 = new Web3(jeth);"); err != nil {
 		return fmt.Errorf("web3 provider: %v", err)
 	}
+	// Route required file paths to the script module loader, keeping the bundled libraries
+	if _, err := c.jsre.Run(`require = (function(bundled) { return function(name) { return /^\.{0,2}\//.test(name) ? loadModule(name) : bundled(name); }; })(require);`); err != nil {
+		return fmt.Errorf("module loader: %v", err)
+	}
 	// Load the supported APIs into the JavaScript runtime environment
 	apis, err := c.client.SupportedModules()
 	if err != nil {
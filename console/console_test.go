@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/internal/jsre"
+	"github.com/ethereum/go-ethereum/internal/web3ext"
 	"github.com/ethereum/go-ethereum/node"
 )
 
@@ -248,6 +249,66 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+// Tests that script modules can be required from the configured asset path, along
+// with the libraries bundled into the console.
+func TestRequireModule(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	tester.console.Evaluate("require('./module.js').message")
+	if output := tester.output.String(); !strings.Contains(output, "hello module with bundled function") {
+		t.Fatalf("module exports missing: have %s, want %s", output, "hello module with bundled function")
+	}
+}
+
+// Tests that all the web3 extension modules can be loaded and their namespaces are
+// offered for autocompletion, including the swarm and whisper ones.
+func TestAutoCompleteExtensions(t *testing.T) {
+	re := jsre.New("", ioutil.Discard)
+	defer re.Stop(false)
+
+	if err := re.Compile("bignumber.js", jsre.BigNumber_JS); err != nil {
+		t.Fatalf("failed to load bignumber.js: %v", err)
+	}
+	if err := re.Compile("web3.js", jsre.Web3_JS); err != nil {
+		t.Fatalf("failed to load web3.js: %v", err)
+	}
+	if _, err := re.Run("var Web3 = require('web3'); var web3 = new Web3();"); err != nil {
+		t.Fatalf("failed to create web3: %v", err)
+	}
+	for api, file := range web3ext.Modules {
+		if err := re.Compile(api+".js", file); err != nil {
+			t.Fatalf("failed to load %s.js: %v", api, err)
+		}
+		if _, err := re.Run(fmt.Sprintf("var %s = web3.%s;", api, api)); err != nil {
+			t.Fatalf("failed to flatten %s: %v", api, err)
+		}
+	}
+	tests := map[string]string{
+		"ps":        "pss",
+		"pss.sendS": "pss.sendSym",
+		"bzz.sta":   "bzz.status",
+		"bzz.upl":   "bzz.upload",
+		"shh.setBl": "shh.setBloomFilter",
+		"shh.newKe": "shh.newKeyPair",
+	}
+	for line, want := range tests {
+		if completions := re.CompleteKeywords(line); !containsString(completions, want) && !containsString(completions, want+".") && !containsString(completions, want+"(") {
+			t.Errorf("%s: completion missing: have %v, want %s", line, completions, want)
+		}
+	}
+}
+
+// containsString reports whether the given string is part of the list.
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
 // Tests that the JavaScript objects returned by statement executions are properly
 // pretty printed instead of just displaing "[object]".
 func TestPrettyPrint(t *testing.T) {
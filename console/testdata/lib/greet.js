@@ -0,0 +1,3 @@
+exports.hello = function(name) {
+	return 'hello ' + name;
+};
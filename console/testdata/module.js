@@ -0,0 +1,3 @@
+var greet = require('./lib/greet');
+
+exports.message = greet.hello('module') + ' with bundled ' + typeof require('web3');
@@ -896,6 +896,49 @@ func TestLogReorgs(t *testing.T) {
 	}
 }
 
+// Tests that a chain reorganisation posts a reorg event detailing the dropped
+// and added blocks.
+func TestChainReorgEvent(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	reorgCh := make(chan ChainReorgEvent)
+	blockchain.SubscribeChainReorgEvent(reorgCh)
+
+	chain, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	forked, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := blockchain.InsertChain(forked); err != nil {
+		t.Fatalf("failed to insert forked chain: %v", err)
+	}
+	select {
+	case ev := <-reorgCh:
+		if ev.OldHead.Hash() != chain[1].Hash() {
+			t.Errorf("old head mismatch: have %x, want %x", ev.OldHead.Hash(), chain[1].Hash())
+		}
+		if ev.Common.Hash() != genesis.Hash() {
+			t.Errorf("common ancestor mismatch: have %x, want %x", ev.Common.Hash(), genesis.Hash())
+		}
+		if len(ev.Dropped) != 2 || ev.Dropped[0] != chain[0].Hash() || ev.Dropped[1] != chain[1].Hash() {
+			t.Errorf("dropped blocks mismatch: have %x", ev.Dropped)
+		}
+		if len(ev.Added) == 0 || ev.Added[0] != forked[0].Hash() || ev.Added[len(ev.Added)-1] != ev.NewHead.Hash() {
+			t.Errorf("added blocks mismatch: have %x", ev.Added)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout. There is no ChainReorgEvent has been sent.")
+	}
+}
+
 func TestReorgSideEvent(t *testing.T) {
 	var (
 		db      = ethdb.NewMemDatabase()
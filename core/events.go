@@ -49,3 +49,14 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ChainReorgEvent is posted when the canonical chain is reorganised, replacing
+// some previously canonical blocks with the ones of a heavier side chain.
+type ChainReorgEvent struct {
+	OldHead *types.Header // Head of the chain before the reorganisation
+	NewHead *types.Header // Head of the chain after the reorganisation
+	Common  *types.Header // Last block shared by the old and new chains
+
+	Dropped []common.Hash // Hashes of the blocks removed from the canonical chain, ascending
+	Added   []common.Hash // Hashes of the blocks added to the canonical chain, ascending
+}
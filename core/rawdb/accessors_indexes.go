@@ -64,6 +64,25 @@ func DeleteTxLookupEntry(db DatabaseDeleter, hash common.Hash) {
 	db.Delete(append(txLookupPrefix, hash.Bytes()...))
 }
 
+// ReadTxIndexTail retrieves the number of the oldest block whose transactions are
+// indexed. If the entry is missing, the transactions of the entire chain are.
+func ReadTxIndexTail(db DatabaseReader) *uint64 {
+	data, _ := db.Get(txIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTxIndexTail stores the number of the oldest block whose transactions are
+// indexed.
+func WriteTxIndexTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(txIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store transaction index tail", "err", err)
+	}
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db DatabaseReader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -117,3 +136,42 @@ func WriteBloomBits(db DatabaseWriter, bit uint, section uint64, head common.Has
 		log.Crit("Failed to store bloom bits", "err", err)
 	}
 }
+
+// LogIndexAddressEntry returns the log index entry tracking the logs emitted by
+// the given contract address.
+func LogIndexAddressEntry(address common.Address) []byte {
+	return append([]byte{0}, address.Bytes()...)
+}
+
+// LogIndexTopicEntry returns the log index entry tracking the logs carrying the
+// given topic at the given position.
+func LogIndexTopicEntry(position int, topic common.Hash) []byte {
+	return append([]byte{byte(position + 1)}, topic.Bytes()...)
+}
+
+// ReadLogIndex retrieves the ascending numbers of the blocks within the given
+// section containing logs matching the log index entry.
+func ReadLogIndex(db DatabaseReader, entry []byte, section uint64, head common.Hash) []uint64 {
+	data, _ := db.Get(logIndexKey(entry, section, head))
+	if len(data) == 0 {
+		return nil
+	}
+	var numbers []uint64
+	if err := rlp.DecodeBytes(data, &numbers); err != nil {
+		log.Error("Invalid log index RLP", "section", section, "head", head, "err", err)
+		return nil
+	}
+	return numbers
+}
+
+// WriteLogIndex stores the ascending numbers of the blocks within the given
+// section containing logs matching the log index entry.
+func WriteLogIndex(db DatabaseWriter, entry []byte, section uint64, head common.Hash, numbers []uint64) {
+	data, err := rlp.EncodeToBytes(numbers)
+	if err != nil {
+		log.Crit("Failed to RLP encode log index", "err", err)
+	}
+	if err := db.Put(logIndexKey(entry, section, head), data); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// freezerdb is a database wrapper which serves the chain data moved into the
+// freezer as if it was still stored in the key-value database, so that the
+// accessors need not know where the blocks are.
+type freezerdb struct {
+	ethdb.Database
+	freezer *freezer
+}
+
+// NewDatabaseWithFreezer creates a database which moves the immutable part of
+// the chain out of the key-value database db, into a freezer stored in the
+// given directory. The namespace prefixes the metrics of the freezer.
+func NewDatabaseWithFreezer(db ethdb.Database, ancient string, namespace string) (ethdb.Database, error) {
+	frdb, err := newFreezer(ancient, namespace)
+	if err != nil {
+		return nil, err
+	}
+	frdb.wg.Add(1)
+	go frdb.freeze(db)
+
+	return &freezerdb{Database: db, freezer: frdb}, nil
+}
+
+// KeyValueStore returns the key-value database backing a database, which is
+// the database itself unless it has a freezer.
+func KeyValueStore(db ethdb.Database) ethdb.Database {
+	if frdb, ok := db.(*freezerdb); ok {
+		return frdb.Database
+	}
+	return db
+}
+
+// ancientKey resolves the key of a chain data entry into its freezer table,
+// the number of its block and the hash of the block if the key includes it.
+// It returns false for the keys of data which are never frozen.
+func ancientKey(key []byte) (kind string, number uint64, hash []byte, ok bool) {
+	switch {
+	case len(key) == len(headerPrefix)+8+common.HashLength && bytes.HasPrefix(key, headerPrefix):
+		kind = freezerHeaderTable
+	case len(key) == len(headerPrefix)+8+common.HashLength+len(headerTDSuffix) && bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
+		kind = freezerDifficultyTable
+	case len(key) == len(headerPrefix)+8+len(headerHashSuffix) && bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
+		return freezerHashTable, binary.BigEndian.Uint64(key[len(headerPrefix):]), nil, true
+	case len(key) == len(blockBodyPrefix)+8+common.HashLength && bytes.HasPrefix(key, blockBodyPrefix):
+		kind = freezerBodiesTable
+	case len(key) == len(blockReceiptsPrefix)+8+common.HashLength && bytes.HasPrefix(key, blockReceiptsPrefix):
+		kind = freezerReceiptTable
+	default:
+		return "", 0, nil, false
+	}
+	number = binary.BigEndian.Uint64(key[1:9])
+	return kind, number, key[9 : 9+common.HashLength], true
+}
+
+// ancient retrieves a chain data entry from the freezer, nil if the freezer
+// does not hold it.
+func (db *freezerdb) ancient(key []byte) []byte {
+	kind, number, hash, ok := ancientKey(key)
+	if !ok || number >= db.freezer.Ancients() {
+		return nil
+	}
+	// Only the canonical blocks are frozen, check that the block is the one
+	// frozen if the key identifies it
+	if hash != nil {
+		frozen, err := db.freezer.Ancient(freezerHashTable, number)
+		if err != nil || !bytes.Equal(frozen, hash) {
+			return nil
+		}
+	}
+	data, err := db.freezer.Ancient(kind, number)
+	if err != nil {
+		log.Error("Failed to retrieve ancient data", "table", kind, "number", number, "err", err)
+		return nil
+	}
+	return data
+}
+
+// Has retrieves if a key is present in the freezer or the key-value database.
+func (db *freezerdb) Has(key []byte) (bool, error) {
+	if kind, number, hash, ok := ancientKey(key); ok && number < db.freezer.Ancients() {
+		if hash == nil {
+			return db.freezer.HasAncient(kind, number), nil
+		}
+		if db.ancient(key) != nil {
+			return true, nil
+		}
+	}
+	return db.Database.Has(key)
+}
+
+// Get retrieves the given key from the key-value database, or the freezer if
+// the key-value database no longer has it.
+func (db *freezerdb) Get(key []byte) ([]byte, error) {
+	data, err := db.Database.Get(key)
+	if err == nil {
+		return data, nil
+	}
+	if data := db.ancient(key); data != nil {
+		return data, nil
+	}
+	return nil, err
+}
+
+// Delete removes the key from the key-value database. Deleting the canonical
+// hash of a frozen block, which only happens when rewinding the chain, also
+// truncates the freezer to the blocks preceding it.
+func (db *freezerdb) Delete(key []byte) error {
+	if kind, number, _, ok := ancientKey(key); ok && kind == freezerHashTable && number < db.freezer.Ancients() {
+		log.Debug("Rewinding frozen chain segment", "number", number, "frozen", db.freezer.Ancients())
+		if err := db.freezer.TruncateAncients(number); err != nil {
+			return err
+		}
+	}
+	return db.Database.Delete(key)
+}
+
+// Close stops the freezer and closes both it and the key-value database.
+func (db *freezerdb) Close() {
+	if err := db.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	db.Database.Close()
+}
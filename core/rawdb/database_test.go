@@ -0,0 +1,123 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that the freezer moves the blocks past the immutability threshold out
+// of the key-value database, that the chain data stays readable through the
+// database wrapper, and that rewinding the chain truncates the freezer.
+func TestFreezerDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Create a chain of blocks in the key-value database
+	kvdb := ethdb.NewMemDatabase()
+
+	var hashes []common.Hash
+	for i := 0; i < 10; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("test header")}
+		if i > 0 {
+			header.ParentHash = hashes[i-1]
+		}
+		hash, number := header.Hash(), uint64(i)
+
+		WriteHeader(kvdb, header)
+		WriteBody(kvdb, hash, number, &types.Body{})
+		WriteReceipts(kvdb, hash, number, types.Receipts{})
+		WriteTd(kvdb, hash, number, big.NewInt(int64(i+1)))
+		WriteCanonicalHash(kvdb, hash, number)
+
+		hashes = append(hashes, hash)
+	}
+	WriteHeadBlockHash(kvdb, hashes[9])
+
+	// Freeze all but the two most recent blocks
+	frdb, err := newFreezer(dir, "")
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	frdb.threshold = 2
+	frdb.wg.Add(1)
+	go frdb.freeze(kvdb)
+
+	db := &freezerdb{Database: kvdb, freezer: frdb}
+	defer db.Close()
+
+	for start := time.Now(); HasHeader(kvdb, hashes[7], 7); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("blocks not frozen: have %d, want %d", frdb.Ancients(), 8)
+		}
+	}
+	if frozen := frdb.Ancients(); frozen != 8 {
+		t.Fatalf("frozen blocks mismatch: have %d, want %d", frozen, 8)
+	}
+
+	for i, hash := range hashes {
+		number := uint64(i)
+		if frozen := i < 8; frozen != (ReadHeader(kvdb, hash, number) == nil) {
+			t.Errorf("block %d: key-value database presence mismatch: frozen %v", i, frozen)
+		}
+		if have := ReadCanonicalHash(db, number); have != hash {
+			t.Errorf("block %d: canonical hash mismatch: have %x, want %x", i, have, hash)
+		}
+		if header := ReadHeader(db, hash, number); header == nil || header.Hash() != hash {
+			t.Errorf("block %d: header mismatch: have %v", i, header)
+		}
+		if ReadBody(db, hash, number) == nil {
+			t.Errorf("block %d: body missing", i)
+		}
+		if ReadReceipts(db, hash, number) == nil {
+			t.Errorf("block %d: receipts missing", i)
+		}
+		if td := ReadTd(db, hash, number); td == nil || td.Int64() != int64(i+1) {
+			t.Errorf("block %d: total difficulty mismatch: have %v, want %d", i, td, i+1)
+		}
+		if !HasHeader(db, hash, number) {
+			t.Errorf("block %d: header not reported present", i)
+		}
+		if HasHeader(db, common.Hash{0x01}, number) {
+			t.Errorf("block %d: non-canonical header reported present", i)
+		}
+	}
+	// Rewind the chain into the frozen segment
+	for i := 9; i >= 5; i-- {
+		DeleteCanonicalHash(db, uint64(i))
+	}
+	if frozen := frdb.Ancients(); frozen != 5 {
+		t.Fatalf("frozen blocks mismatch after rewind: have %d, want %d", frozen, 5)
+	}
+	if ReadHeader(db, hashes[5], 5) != nil {
+		t.Errorf("rewound block still available")
+	}
+	if ReadHeader(db, hashes[4], 4) == nil {
+		t.Errorf("frozen block missing after rewind")
+	}
+}
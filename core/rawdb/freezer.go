@@ -0,0 +1,353 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errUnknownTable is returned if the user attempts to read from a table that is
+// not tracked by the freezer.
+var errUnknownTable = errors.New("unknown table")
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen into immutable
+	// storage.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting it from the key-value store.
+	freezerBatchLimit = 30000
+)
+
+// freezer is an append-only store of the immutable part of the chain, which
+// keeps the canonical headers, hashes, bodies, receipts and total difficulties
+// of the old blocks in flat files, out of the key-value database.
+//
+// The blocks are moved into the freezer by a background loop once they are
+// older than the immutability threshold, so that they never change anymore,
+// sparing the key-value database the compaction of the bulk of the chain and
+// allowing the flat files to be placed on cheaper storage.
+type freezer struct {
+	frozen    uint64 // Number of blocks already frozen, accessed atomically
+	threshold uint64 // Number of recent blocks kept out of the freezer
+
+	tables map[string]*freezerTable // Data tables for storing everything
+	lock   sync.Mutex               // Lock serializing truncations and key-value deletions
+
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	quitOnce sync.Once
+}
+
+// newFreezer opens the chain freezer stored in a directory, repairing its
+// tables if a crash left them inconsistent.
+func newFreezer(datadir string, namespace string) (*freezer, error) {
+	var (
+		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
+		writeMeter = metrics.NewRegisteredMeter(namespace+"ancient/write", nil)
+	)
+	f := &freezer{
+		threshold: params.ImmutabilityThreshold,
+		tables:    make(map[string]*freezerTable),
+		quit:      make(chan struct{}),
+	}
+	for name, noSnappy := range freezerNoSnappy {
+		table, err := newTable(datadir, name, readMeter, writeMeter, noSnappy)
+		if err != nil {
+			for _, table := range f.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		f.tables[name] = table
+	}
+	if err := f.repair(); err != nil {
+		f.closeTables()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "database", datadir, "frozen", f.frozen)
+	return f, nil
+}
+
+// repair truncates all the tables to the number of items of the shortest one,
+// dropping the blocks which were only partially frozen.
+func (f *freezer) repair() error {
+	min := uint64(1<<64 - 1)
+	for _, table := range f.tables {
+		if items := table.Items(); items < min {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// Close stops the freezing loop and closes all the tables.
+func (f *freezer) Close() error {
+	f.quitOnce.Do(func() { close(f.quit) })
+	f.wg.Wait()
+	return f.closeTables()
+}
+
+// closeTables closes all the tables of the freezer.
+func (f *freezer) closeTables() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists
+// in the freezer.
+func (f *freezer) HasAncient(kind string, number uint64) bool {
+	if table := f.tables[kind]; table != nil {
+		return number < table.Items()
+	}
+	return false
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the number of blocks frozen.
+func (f *freezer) Ancients() uint64 {
+	return atomic.LoadUint64(&f.frozen)
+}
+
+// AppendAncient injects all the data of a block at the end of the freezer. The
+// block number must be the number of blocks already frozen. If any table fails
+// to append the data, the others are reverted.
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	// Rollback all inserted data if any insertion below failed to ensure
+	// the tables won't be out of sync
+	defer func() {
+		if err != nil {
+			for _, table := range f.tables {
+				if err := table.truncate(number); err != nil {
+					log.Error("Failed to truncate ancient table", "table", table.name, "err", err)
+				}
+			}
+		}
+	}()
+	items := []struct {
+		kind string
+		blob []byte
+	}{
+		{freezerHashTable, hash},
+		{freezerHeaderTable, header},
+		{freezerBodiesTable, body},
+		{freezerReceiptTable, receipts},
+		{freezerDifficultyTable, td},
+	}
+	for _, item := range items {
+		if err := f.tables[item.kind].Append(number, item.blob); err != nil {
+			log.Error("Failed to append ancient data", "table", item.kind, "number", number, "err", err)
+			return err
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1)
+	return nil
+}
+
+// TruncateAncients discards any recent data above the provided threshold number.
+func (f *freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+// Sync flushes all the tables to disk.
+func (f *freezer) Sync() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze is a background thread that periodically checks the blockchain for any
+// import progress and moves ancient data from the key-value database into the
+// freezer.
+func (f *freezer) freeze(db ethdb.Database) {
+	defer f.wg.Done()
+
+	backoff := false
+	for {
+		select {
+		case <-f.quit:
+			return
+		default:
+		}
+		if backoff {
+			select {
+			case <-time.NewTimer(freezerRecheckInterval).C:
+				backoff = false
+			case <-f.quit:
+				return
+			}
+		}
+		// Retrieve the freezing threshold
+		hash := ReadHeadBlockHash(db)
+		if hash == (common.Hash{}) {
+			log.Debug("Current full block hash unavailable") // new chain, empty database
+			backoff = true
+			continue
+		}
+		number := ReadHeaderNumber(db, hash)
+		switch {
+		case number == nil:
+			log.Error("Current full block number unavailable", "hash", hash)
+			backoff = true
+			continue
+
+		case *number < f.threshold:
+			log.Debug("Current full block not old enough", "number", *number, "hash", hash, "delay", f.threshold)
+			backoff = true
+			continue
+
+		case *number-f.threshold < f.Ancients():
+			log.Debug("Ancient blocks frozen already", "number", *number, "hash", hash, "frozen", f.Ancients())
+			backoff = true
+			continue
+		}
+		limit := *number - f.threshold
+		if limit-f.Ancients() >= freezerBatchLimit {
+			limit = f.Ancients() + freezerBatchLimit - 1
+		}
+		// Move the blocks up to the limit into the freezer
+		var (
+			start    = time.Now()
+			first    = f.Ancients()
+			ancients = make([]common.Hash, 0, limit-first+1)
+		)
+		for n := first; n <= limit; n++ {
+			hash := ReadCanonicalHash(db, n)
+			if hash == (common.Hash{}) {
+				log.Error("Canonical hash missing, can't freeze", "number", n)
+				break
+			}
+			header := ReadHeaderRLP(db, hash, n)
+			if len(header) == 0 {
+				log.Error("Block header missing, can't freeze", "number", n, "hash", hash)
+				break
+			}
+			body := ReadBodyRLP(db, hash, n)
+			if len(body) == 0 {
+				log.Error("Block body missing, can't freeze", "number", n, "hash", hash)
+				break
+			}
+			receipts := ReadReceiptsRLP(db, hash, n)
+			if len(receipts) == 0 {
+				log.Error("Block receipts missing, can't freeze", "number", n, "hash", hash)
+				break
+			}
+			td := ReadTdRLP(db, hash, n)
+			if len(td) == 0 {
+				log.Error("Total difficulty missing, can't freeze", "number", n, "hash", hash)
+				break
+			}
+			if err := f.AppendAncient(n, hash[:], header, body, receipts, td); err != nil {
+				break
+			}
+			ancients = append(ancients, hash)
+		}
+		// Batch of blocks have been frozen, flush them before wiping from the
+		// key-value database
+		if err := f.Sync(); err != nil {
+			log.Crit("Failed to flush frozen tables", "err", err)
+		}
+		f.wipe(db, first, ancients)
+
+		if len(ancients) > 0 {
+			log.Info("Deep froze chain segment", "blocks", len(ancients), "elapsed", common.PrettyDuration(time.Since(start)), "number", first+uint64(len(ancients))-1)
+		}
+
+		// Avoid database thrashing with tiny writes
+		if len(ancients) < freezerBatchLimit {
+			backoff = true
+		}
+	}
+}
+
+// wipe deletes the frozen blocks from the key-value database, except for their
+// hash to number mappings, skipping any truncated in the meantime.
+func (f *freezer) wipe(db ethdb.Database, first uint64, ancients []common.Hash) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for i, hash := range ancients {
+		number := first + uint64(i)
+		if frozen, err := f.Ancient(freezerHashTable, number); err != nil || !bytes.Equal(frozen, hash[:]) {
+			return
+		}
+		for _, key := range [][]byte{
+			headerHashKey(number),
+			headerKey(number, hash),
+			headerTDKey(number, hash),
+			blockBodyKey(number, hash),
+			blockReceiptsKey(number, hash),
+		} {
+			if err := db.Delete(key); err != nil {
+				log.Crit("Failed to delete frozen block", "number", number, "err", err)
+			}
+		}
+	}
+}
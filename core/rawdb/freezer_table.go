@@ -0,0 +1,429 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/golang/snappy"
+)
+
+var (
+	// errClosed is returned if an operation attempts to read from or write to
+	// the freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+
+	// errOutOfBounds is returned if the item requested is not contained within
+	// the freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// items into the freezer table.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+)
+
+// freezerTableSize is the maximum size of the data files of a freezer table,
+// beyond which a new file is started.
+const freezerTableSize = 2 * 1000 * 1000 * 1000
+
+// indexEntrySize is the size of the entries of the index file of a table.
+const indexEntrySize = 8
+
+// indexEntry is the position of the end of an item in the data files of a
+// freezer table, which is also the start of the next item unless the latter
+// was written into a new file.
+type indexEntry struct {
+	filenum uint32 // Number of the data file holding the item
+	offset  uint32 // Offset of the end of the item within its data file
+}
+
+// unmarshalBinary decodes an index entry from its 8 byte encoding.
+func (e *indexEntry) unmarshalBinary(b []byte) {
+	e.filenum = binary.BigEndian.Uint32(b[:4])
+	e.offset = binary.BigEndian.Uint32(b[4:8])
+}
+
+// marshalBinary encodes an index entry into its 8 byte encoding.
+func (e *indexEntry) marshalBinary() []byte {
+	b := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint32(b[:4], e.filenum)
+	binary.BigEndian.PutUint32(b[4:8], e.offset)
+	return b
+}
+
+// freezerTable is an append-only store of the items of one kind of chain data,
+// such as headers, indexed by their sequential numbers. The items are written
+// one after the other into data files of bounded size, and the index file holds
+// the position of the end of each item. Its first entry is the start of the
+// first item, so the nth item spans from the nth entry to the n+1th one.
+type freezerTable struct {
+	items    uint64 // Number of items stored in the table, accessed atomically
+	noSnappy bool   // Whether the items are stored without compression
+	maxSize  uint32 // Maximum size of a data file
+
+	path  string
+	name  string
+	index *os.File            // File of the positions of the items
+	files map[uint32]*os.File // Open data files, by number
+	head  *os.File            // Data file being appended to
+	headn uint32              // Number of the data file being appended to
+	headz uint32              // Size of the data file being appended to
+
+	readMeter  metrics.Meter // Meter for measuring the effective amount of data read
+	writeMeter metrics.Meter // Meter for measuring the effective amount of data written
+
+	logger log.Logger
+	lock   sync.RWMutex // Mutex protecting the data file descriptors
+}
+
+// newTable opens a freezer table with the default data file size limit.
+func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, noSnappy bool) (*freezerTable, error) {
+	return newCustomTable(path, name, readMeter, writeMeter, freezerTableSize, noSnappy)
+}
+
+// newCustomTable opens a freezer table, creating its files if they do not exist
+// and repairing them if a crash left them inconsistent.
+func newCustomTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, maxSize uint32, noSnappy bool) (*freezerTable, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	idxName := fmt.Sprintf("%s.ridx", name)
+	if !noSnappy {
+		idxName = fmt.Sprintf("%s.cidx", name)
+	}
+	index, err := os.OpenFile(filepath.Join(path, idxName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	tab := &freezerTable{
+		noSnappy:   noSnappy,
+		maxSize:    maxSize,
+		path:       path,
+		name:       name,
+		index:      index,
+		files:      make(map[uint32]*os.File),
+		readMeter:  readMeter,
+		writeMeter: writeMeter,
+		logger:     log.New("table", name),
+	}
+	if err := tab.repair(); err != nil {
+		tab.Close()
+		return nil, err
+	}
+	return tab, nil
+}
+
+// repair cross checks the index and the head data file, truncating them to the
+// last item fully written into both.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	// Create the first entry of a new index, and drop any partial entry
+	if stat.Size() == 0 {
+		if _, err := t.index.Write((&indexEntry{}).marshalBinary()); err != nil {
+			return err
+		}
+		stat, err = t.index.Stat()
+		if err != nil {
+			return err
+		}
+	}
+	size := stat.Size() - stat.Size()%indexEntrySize
+	if err := t.index.Truncate(size); err != nil {
+		return err
+	}
+	// Drop the entries of the items missing from the head data file
+	var last indexEntry
+	for {
+		if last, err = t.readEntry(uint64(size/indexEntrySize - 1)); err != nil {
+			return err
+		}
+		if t.head, err = t.openFile(last.filenum, os.O_RDWR|os.O_CREATE); err != nil {
+			return err
+		}
+		stat, err := t.head.Stat()
+		if err != nil {
+			return err
+		}
+		if stat.Size() >= int64(last.offset) {
+			break
+		}
+		t.logger.Warn("Truncating dangling index entry", "indexed", last.offset, "stored", stat.Size())
+		size -= indexEntrySize
+		if size < indexEntrySize {
+			return errors.New("corrupted freezer index")
+		}
+		if err := t.index.Truncate(size); err != nil {
+			return err
+		}
+	}
+	// Open the data files preceding the head one, and drop the ones after it
+	for num := uint32(0); num < last.filenum; num++ {
+		if _, err := t.openFile(num, os.O_RDWR); err != nil {
+			return err
+		}
+	}
+	if err := t.releaseFilesAfter(last.filenum); err != nil {
+		return err
+	}
+	// Drop any data written beyond the last item
+	if err := t.head.Truncate(int64(last.offset)); err != nil {
+		return err
+	}
+	if _, err := t.index.Seek(size, 0); err != nil {
+		return err
+	}
+	if _, err := t.head.Seek(int64(last.offset), 0); err != nil {
+		return err
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	if err := t.head.Sync(); err != nil {
+		return err
+	}
+	t.headn, t.headz = last.filenum, last.offset
+	atomic.StoreUint64(&t.items, uint64(size/indexEntrySize-1))
+
+	t.logger.Debug("Chain freezer table opened", "items", t.items, "size", t.headz)
+	return nil
+}
+
+// readEntry reads the nth entry of the index.
+func (t *freezerTable) readEntry(n uint64) (indexEntry, error) {
+	var (
+		buf   = make([]byte, indexEntrySize)
+		entry indexEntry
+	)
+	if _, err := t.index.ReadAt(buf, int64(n*indexEntrySize)); err != nil {
+		return entry, err
+	}
+	entry.unmarshalBinary(buf)
+	return entry, nil
+}
+
+// fileName returns the name of the nth data file of the table.
+func (t *freezerTable) fileName(num uint32) string {
+	if t.noSnappy {
+		return filepath.Join(t.path, fmt.Sprintf("%s.%04d.rdat", t.name, num))
+	}
+	return filepath.Join(t.path, fmt.Sprintf("%s.%04d.cdat", t.name, num))
+}
+
+// openFile returns the nth data file of the table, opening it if needed.
+func (t *freezerTable) openFile(num uint32, flag int) (*os.File, error) {
+	if f, ok := t.files[num]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(t.fileName(num), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t.files[num] = f
+	return f, nil
+}
+
+// releaseFilesAfter closes and removes the data files numbered above num.
+func (t *freezerTable) releaseFilesAfter(num uint32) error {
+	for fnum, f := range t.files {
+		if fnum > num {
+			delete(t.files, fnum)
+			f.Close()
+			if err := os.Remove(f.Name()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Items returns the number of items stored in the table.
+func (t *freezerTable) Items() uint64 {
+	return atomic.LoadUint64(&t.items)
+}
+
+// Append injects a binary blob at the end of the table. The item number must
+// be the number of items already stored, ensuring items are never skipped.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if atomic.LoadUint64(&t.items) != item {
+		return errOutOrderInsertion
+	}
+	if !t.noSnappy {
+		blob = snappy.Encode(nil, blob)
+	}
+	// Start a new data file if the item would overflow the current one
+	if t.headz > 0 && uint64(t.headz)+uint64(len(blob)) > uint64(t.maxSize) {
+		head, err := t.openFile(t.headn+1, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return err
+		}
+		if err := t.head.Sync(); err != nil {
+			return err
+		}
+		t.head, t.headn, t.headz = head, t.headn+1, 0
+	}
+	if _, err := t.head.Write(blob); err != nil {
+		return err
+	}
+	t.headz += uint32(len(blob))
+
+	entry := indexEntry{filenum: t.headn, offset: t.headz}
+	if _, err := t.index.Write(entry.marshalBinary()); err != nil {
+		return err
+	}
+	t.writeMeter.Mark(int64(len(blob) + indexEntrySize))
+	atomic.AddUint64(&t.items, 1)
+	return nil
+}
+
+// Retrieve looks up the data blob of an item.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil {
+		return nil, errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= item {
+		return nil, errOutOfBounds
+	}
+	start, err := t.readEntry(item)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.readEntry(item + 1)
+	if err != nil {
+		return nil, err
+	}
+	// Items written into a new data file start at its beginning
+	if start.filenum != end.filenum {
+		start.offset = 0
+	}
+	f, ok := t.files[end.filenum]
+	if !ok {
+		return nil, fmt.Errorf("missing data file %d", end.filenum)
+	}
+	blob := make([]byte, end.offset-start.offset)
+	if _, err := f.ReadAt(blob, int64(start.offset)); err != nil {
+		return nil, err
+	}
+	t.readMeter.Mark(int64(len(blob) + 2*indexEntrySize))
+
+	if t.noSnappy {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob)
+}
+
+// truncate discards any items beyond the given number of items.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= items {
+		return nil
+	}
+	t.logger.Debug("Truncating freezer table", "items", t.items, "limit", items)
+
+	last, err := t.readEntry(items)
+	if err != nil {
+		return err
+	}
+	if err := t.index.Truncate(int64(items+1) * indexEntrySize); err != nil {
+		return err
+	}
+	if _, err := t.index.Seek(int64(items+1)*indexEntrySize, 0); err != nil {
+		return err
+	}
+	// Switch back to the data file holding the new last item
+	if last.filenum != t.headn {
+		head, err := t.openFile(last.filenum, os.O_RDWR)
+		if err != nil {
+			return err
+		}
+		if err := t.releaseFilesAfter(last.filenum); err != nil {
+			return err
+		}
+		t.head, t.headn = head, last.filenum
+	}
+	if err := t.head.Truncate(int64(last.offset)); err != nil {
+		return err
+	}
+	if _, err := t.head.Seek(int64(last.offset), 0); err != nil {
+		return err
+	}
+	t.headz = last.offset
+	atomic.StoreUint64(&t.items, items)
+	return nil
+}
+
+// Sync pushes any pending data from memory out to disk.
+func (t *freezerTable) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	return t.head.Sync()
+}
+
+// Close closes all the files of the table.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range t.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.index, t.head, t.files = nil, nil, nil
+
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// getChunk returns a test item of the given size, filled with a byte.
+func getChunk(size int, b byte) []byte {
+	return bytes.Repeat([]byte{b}, size)
+}
+
+// openTestTable opens a freezer table in a directory with small data files.
+func openTestTable(t *testing.T, dir string, noSnappy bool) *freezerTable {
+	table, err := newCustomTable(dir, "test", metrics.NewMeter(), metrics.NewMeter(), 50, noSnappy)
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	return table
+}
+
+// checkItems verifies that a table holds the given number of test items.
+func checkItems(t *testing.T, table *freezerTable, items int) {
+	if have := table.Items(); have != uint64(items) {
+		t.Fatalf("item count mismatch: have %d, want %d", have, items)
+	}
+	for i := 0; i < items; i++ {
+		blob, err := table.Retrieve(uint64(i))
+		if err != nil {
+			t.Fatalf("item %d: failed to retrieve: %v", i, err)
+		}
+		if want := getChunk(15, byte(i)); !bytes.Equal(blob, want) {
+			t.Fatalf("item %d: content mismatch: have %x, want %x", i, blob, want)
+		}
+	}
+	if _, err := table.Retrieve(uint64(items)); err != errOutOfBounds {
+		t.Fatalf("item %d: error mismatch: have %v, want %v", items, err, errOutOfBounds)
+	}
+}
+
+// Tests that items can be appended to and retrieved from a table, across data
+// files and reopenings.
+func TestFreezerTableBasics(t *testing.T) {
+	for _, noSnappy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("nosnappy=%v", noSnappy), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "freezer")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			table := openTestTable(t, dir, noSnappy)
+			for i := 0; i < 10; i++ {
+				if err := table.Append(uint64(i), getChunk(15, byte(i))); err != nil {
+					t.Fatalf("item %d: failed to append: %v", i, err)
+				}
+			}
+			if err := table.Append(20, getChunk(15, 20)); err != errOutOrderInsertion {
+				t.Fatalf("out of order append error mismatch: have %v, want %v", err, errOutOrderInsertion)
+			}
+			checkItems(t, table, 10)
+			table.Close()
+
+			if _, err := table.Retrieve(0); err != errClosed {
+				t.Fatalf("closed retrieval error mismatch: have %v, want %v", err, errClosed)
+			}
+			table = openTestTable(t, dir, noSnappy)
+			defer table.Close()
+
+			checkItems(t, table, 10)
+			if noSnappy && table.headn == 0 {
+				t.Fatalf("uncompressed items not split over data files")
+			}
+		})
+	}
+}
+
+// Tests that a table repairs itself when a crash left a partial index entry or
+// index entries of items missing from the data files.
+func TestFreezerTableRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := openTestTable(t, dir, true)
+	for i := 0; i < 10; i++ {
+		if err := table.Append(uint64(i), getChunk(15, byte(i))); err != nil {
+			t.Fatalf("item %d: failed to append: %v", i, err)
+		}
+	}
+	table.Close()
+
+	// Cut off half an index entry
+	index := filepath.Join(dir, "test.ridx")
+	stat, err := os.Stat(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(index, stat.Size()-indexEntrySize/2); err != nil {
+		t.Fatal(err)
+	}
+	table = openTestTable(t, dir, true)
+	checkItems(t, table, 9)
+	headn := table.headn
+	table.Close()
+
+	// Cut off the last item of the head data file
+	head := filepath.Join(dir, fmt.Sprintf("test.%04d.rdat", headn))
+	if stat, err = os.Stat(head); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(head, stat.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+	table = openTestTable(t, dir, true)
+	defer table.Close()
+
+	checkItems(t, table, 8)
+	for i := 8; i < 10; i++ {
+		if err := table.Append(uint64(i), getChunk(15, byte(i))); err != nil {
+			t.Fatalf("item %d: failed to append: %v", i, err)
+		}
+	}
+	checkItems(t, table, 10)
+}
+
+// Tests that truncating a table discards the items beyond the limit, along
+// with the data files holding only them.
+func TestFreezerTableTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := openTestTable(t, dir, true)
+	for i := 0; i < 10; i++ {
+		if err := table.Append(uint64(i), getChunk(15, byte(i))); err != nil {
+			t.Fatalf("item %d: failed to append: %v", i, err)
+		}
+	}
+	last := table.headn
+	if err := table.truncate(2); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	checkItems(t, table, 2)
+	if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("test.%04d.rdat", last))); !os.IsNotExist(err) {
+		t.Fatalf("truncated data file not removed: %v", err)
+	}
+	for i := 2; i < 5; i++ {
+		if err := table.Append(uint64(i), getChunk(15, byte(i))); err != nil {
+			t.Fatalf("item %d: failed to append: %v", i, err)
+		}
+	}
+	table.Close()
+
+	table = openTestTable(t, dir, true)
+	defer table.Close()
+
+	checkItems(t, table, 5)
+}
@@ -0,0 +1,411 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements the offline pruning of the historical state of a
+// full node.
+//
+// The state of every block is stored as a Merkle Patricia trie whose nodes are
+// keyed by their hashes, shared between the blocks where the state did not
+// change. A full node only needs the state of its recent blocks, but the nodes
+// of the older states accumulate in the database, as there is no reference
+// counting of the nodes persisted to disk.
+//
+// The pruner reclaims this space in three steps, while the node is stopped:
+//
+//   - It marks the trie nodes and contract code of the retained states into a
+//     marker database, verifying on the way that these states are complete.
+//   - It sweeps the chain database, deleting the nodes and code not marked.
+//   - It verifies the head state after the sweep, and compacts the database.
+//
+// The marker database records the progress of the pruning, so that pruning can
+// be resumed if it is interrupted.
+package pruner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// recentStates is the number of recent blocks whose state is retained if it is
+// on disk, matching the number of state tries a running node keeps in memory
+// and flushes in part when stopping.
+const recentStates = 128
+
+var (
+	// errNoHead is returned if the chain database has no head block.
+	errNoHead = errors.New("head block unavailable")
+
+	// errNotIterable is returned if the key-value store of the chain database
+	// cannot be iterated over to sweep it.
+	errNotIterable = errors.New("state pruning requires a leveldb database")
+
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+var (
+	// markerHeadKey tracks the head block whose states are being retained.
+	markerHeadKey = []byte("head")
+
+	// markerProgressKey tracks the number of retained states fully marked.
+	markerProgressKey = []byte("progress")
+
+	// markerNodePrefix + hash -> index of the state marking the node (uint64 big endian)
+	markerNodePrefix = []byte("n")
+)
+
+// Pruner removes the trie nodes and contract code of the historical states from
+// the chain database of a stopped full node.
+type Pruner struct {
+	db         ethdb.Database     // Chain database to prune
+	kvdb       *ethdb.LDBDatabase // Key-value store of the chain database
+	markerPath string             // Directory of the marker database
+}
+
+// New creates a pruner of a chain database, recording its progress into a
+// marker database stored in the given directory.
+func New(db ethdb.Database, markerPath string) (*Pruner, error) {
+	kvdb, ok := rawdb.KeyValueStore(db).(*ethdb.LDBDatabase)
+	if !ok {
+		return nil, errNotIterable
+	}
+	return &Pruner{db: db, kvdb: kvdb, markerPath: markerPath}, nil
+}
+
+// Prune deletes all the trie nodes and contract code not referenced by the
+// state of the head block, the states of the recent blocks present on disk and
+// the genesis state, resuming any interrupted pruning of the same head.
+func (p *Pruner) Prune() error {
+	head := rawdb.ReadHeadBlockHash(p.db)
+	if head == (common.Hash{}) {
+		return errNoHead
+	}
+	roots, err := p.retainedRoots(head)
+	if err != nil {
+		return err
+	}
+	marker, err := p.openMarker(head)
+	if err != nil {
+		return err
+	}
+	if err := p.mark(marker, roots); err != nil {
+		marker.Close()
+		return err
+	}
+	if err := p.sweep(marker); err != nil {
+		marker.Close()
+		return err
+	}
+	marker.Close()
+
+	if err := p.verify(roots[0]); err != nil {
+		return err
+	}
+	start := time.Now()
+	log.Info("Compacting chain database")
+	if err := p.kvdb.LDB().CompactRange(util.Range{}); err != nil {
+		return err
+	}
+	log.Info("Compacted chain database", "elapsed", common.PrettyDuration(time.Since(start)))
+
+	return os.RemoveAll(p.markerPath)
+}
+
+// retainedRoots returns the roots of the states to retain, starting with the
+// state of the head block, which must be present.
+func (p *Pruner) retainedRoots(head common.Hash) ([]common.Hash, error) {
+	number := rawdb.ReadHeaderNumber(p.db, head)
+	if number == nil {
+		return nil, fmt.Errorf("head block %x number unavailable", head)
+	}
+	header := rawdb.ReadHeader(p.db, head, *number)
+	if header == nil {
+		return nil, fmt.Errorf("head block %x header unavailable", head)
+	}
+	if !p.hasState(header.Root) {
+		return nil, fmt.Errorf("head block %x state %x missing", head, header.Root)
+	}
+	var (
+		roots = []common.Hash{header.Root}
+		seen  = map[common.Hash]bool{header.Root: true}
+	)
+	retain := func(n uint64) {
+		header := rawdb.ReadHeader(p.db, rawdb.ReadCanonicalHash(p.db, n), n)
+		if header != nil && !seen[header.Root] && p.hasState(header.Root) {
+			roots = append(roots, header.Root)
+			seen[header.Root] = true
+		}
+	}
+	for i := uint64(1); i < recentStates && i <= *number; i++ {
+		retain(*number - i)
+	}
+	retain(0)
+
+	return roots, nil
+}
+
+// hasState reports whether the root node of a state is on disk.
+func (p *Pruner) hasState(root common.Hash) bool {
+	if root == types.EmptyRootHash {
+		return true
+	}
+	ok, _ := p.db.Has(root[:])
+	return ok
+}
+
+// openMarker opens the marker database, discarding its content if it was used
+// to prune the chain at another head block.
+func (p *Pruner) openMarker(head common.Hash) (*ethdb.LDBDatabase, error) {
+	marker, err := ethdb.NewLDBDatabase(p.markerPath, 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	stored, _ := marker.Get(markerHeadKey)
+	if bytes.Equal(stored, head[:]) {
+		log.Info("Resuming state pruning", "head", head)
+		return marker, nil
+	}
+	if stored != nil {
+		log.Warn("Chain progressed since state pruning was interrupted, restarting", "head", head)
+	}
+	marker.Close()
+	if err := os.RemoveAll(p.markerPath); err != nil {
+		return nil, err
+	}
+	if marker, err = ethdb.NewLDBDatabase(p.markerPath, 16, 16); err != nil {
+		return nil, err
+	}
+	if err := marker.Put(markerHeadKey, head[:]); err != nil {
+		marker.Close()
+		return nil, err
+	}
+	return marker, nil
+}
+
+// markerKey = markerNodePrefix + hash
+func markerKey(hash []byte) []byte {
+	return append(append([]byte{}, markerNodePrefix...), hash...)
+}
+
+// encodeIndex encodes the index of a retained state as a big endian uint64.
+func encodeIndex(index uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, index)
+	return enc
+}
+
+// mark records the trie nodes and contract code of the retained states into the
+// marker database, failing if any of them is missing.
+//
+// Every node is marked with the index of the state being marked. The subtries
+// of the nodes marked by a fully marked state are skipped, as they were fully
+// marked too, whereas the nodes marked by an interrupted marking are descended
+// into again.
+func (p *Pruner) mark(marker *ethdb.LDBDatabase, roots []common.Hash) error {
+	var progress uint64
+	if enc, err := marker.Get(markerProgressKey); err == nil && len(enc) == 8 {
+		progress = binary.BigEndian.Uint64(enc)
+	}
+	var (
+		sdb    = state.NewDatabase(p.db)
+		batch  = marker.NewBatch()
+		nodes  int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	// visit marks a node, reporting whether its subtrie needs to be descended into
+	visit := func(hash common.Hash, index uint64) (bool, error) {
+		if enc, err := marker.Get(markerKey(hash[:])); err == nil && len(enc) == 8 && binary.BigEndian.Uint64(enc) < index {
+			return false, nil
+		}
+		if err := batch.Put(markerKey(hash[:]), encodeIndex(index)); err != nil {
+			return false, err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return false, err
+			}
+			batch.Reset()
+		}
+		nodes++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Marking retained state", "state", index+1, "states", len(roots), "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		return true, nil
+	}
+	// markTrie marks the nodes of a trie, calling onLeaf for its leaves
+	markTrie := func(tr state.Trie, index uint64, onLeaf func(key, blob []byte) error) error {
+		it := tr.NodeIterator(nil)
+		for descend := true; it.Next(descend); {
+			descend = true
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				ok, err := visit(hash, index)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					descend = false
+					continue
+				}
+			}
+			if it.Leaf() && onLeaf != nil {
+				if err := onLeaf(it.LeafKey(), it.LeafBlob()); err != nil {
+					return err
+				}
+			}
+		}
+		return it.Error()
+	}
+	for index := progress; index < uint64(len(roots)); index++ {
+		root := roots[index]
+		if root == types.EmptyRootHash {
+			continue
+		}
+		tr, err := sdb.OpenTrie(root)
+		if err != nil {
+			return fmt.Errorf("state %x: %v", root, err)
+		}
+		err = markTrie(tr, index, func(key, blob []byte) error {
+			var account state.Account
+			if err := rlp.DecodeBytes(blob, &account); err != nil {
+				return err
+			}
+			addrHash := common.BytesToHash(key)
+			if account.Root != types.EmptyRootHash {
+				storage, err := sdb.OpenStorageTrie(addrHash, account.Root)
+				if err != nil {
+					return err
+				}
+				if err := markTrie(storage, index, nil); err != nil {
+					return err
+				}
+			}
+			if codeHash := common.BytesToHash(account.CodeHash); codeHash != emptyCode {
+				if _, err := sdb.ContractCode(addrHash, codeHash); err != nil {
+					return fmt.Errorf("code %x: %v", codeHash, err)
+				}
+				if _, err := visit(codeHash, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("state %x incomplete: %v", root, err)
+		}
+		// Flush the marks before recording the progress
+		if err := batch.Put(markerProgressKey, encodeIndex(index+1)); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+	}
+	if nodes > 0 {
+		log.Info("Marked retained states", "states", len(roots), "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
+
+// sweep deletes the trie nodes and contract code not marked from the chain
+// database. Trie nodes and code are the only entries keyed by bare hashes.
+func (p *Pruner) sweep(marker *ethdb.LDBDatabase) error {
+	var (
+		batch   = new(leveldb.Batch)
+		it      = p.kvdb.NewIterator()
+		deleted int
+		size    common.StorageSize
+		start   = time.Now()
+		logged  = time.Now()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if ok, err := marker.Has(markerKey(key)); err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+		batch.Delete(key)
+		deleted++
+		size += common.StorageSize(len(key) + len(it.Value()))
+
+		if batch.Len() >= ethdb.IdealBatchSize/common.HashLength {
+			if err := p.kvdb.LDB().Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Sweeping historical state", "deleted", deleted, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := p.kvdb.LDB().Write(batch, nil); err != nil {
+		return err
+	}
+	log.Info("Swept historical state", "deleted", deleted, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// verify checks that the state of the head block is still complete.
+func (p *Pruner) verify(root common.Hash) error {
+	statedb, err := state.New(root, state.NewDatabase(p.db))
+	if err != nil {
+		return fmt.Errorf("head state %x unavailable after pruning: %v", root, err)
+	}
+	var (
+		it     = state.NewNodeIterator(statedb)
+		nodes  int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for it.Next() {
+		nodes++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying head state", "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Error != nil {
+		return fmt.Errorf("head state %x corrupted by pruning: %v", root, it.Error)
+	}
+	log.Info("Verified head state", "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,246 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	testAccount  = common.HexToAddress("0x01")
+	testContract = common.HexToAddress("0x02")
+)
+
+// testChain is a chain database holding the genesis state, a historical state
+// and the head state, which share part of their nodes.
+type testChain struct {
+	dir string
+	db  *ethdb.LDBDatabase
+
+	genesis, old, head common.Hash // Roots of the states
+	oldCode            []byte      // Code only referenced by the historical state
+}
+
+// newTestChain creates a chain of 200 blocks, with the historical state at the
+// first block and the head state at the others.
+func newTestChain(t *testing.T) *testChain {
+	dir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := ethdb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &testChain{dir: dir, db: db, oldCode: []byte("old code")}
+
+	sdb := state.NewDatabase(db)
+	commit := func(statedb *state.StateDB) common.Hash {
+		root, err := statedb.Commit(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sdb.TrieDB().Commit(root, false); err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+	statedb, _ := state.New(common.Hash{}, sdb)
+	statedb.SetBalance(testAccount, big.NewInt(1))
+	chain.genesis = commit(statedb)
+
+	statedb.SetBalance(testAccount, big.NewInt(2))
+	statedb.SetCode(testContract, chain.oldCode)
+	for i := 0; i < 16; i++ {
+		statedb.SetState(testContract, common.BigToHash(big.NewInt(int64(i))), common.Hash{0x01})
+	}
+	chain.old = commit(statedb)
+
+	statedb.SetBalance(testAccount, big.NewInt(3))
+	statedb.SetCode(testContract, []byte("head code"))
+	statedb.SetState(testContract, common.Hash{}, common.Hash{0x02})
+	chain.head = commit(statedb)
+
+	var parent common.Hash
+	for i := 0; i <= 200; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Root: chain.head}
+		switch i {
+		case 0:
+			header.Root = chain.genesis
+		case 1:
+			header.Root = chain.old
+		}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		parent = header.Hash()
+	}
+	rawdb.WriteHeadBlockHash(db, parent)
+
+	return chain
+}
+
+// close closes the database of the chain and removes its files.
+func (c *testChain) close() {
+	c.db.Close()
+	os.RemoveAll(c.dir)
+}
+
+// checkState verifies that a state is complete.
+func checkState(t *testing.T, db ethdb.Database, root common.Hash) {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("state %x unavailable: %v", root, err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("state %x incomplete: %v", root, it.Error)
+	}
+}
+
+// checkPruned verifies that the chain was pruned down to the retained states.
+func checkPruned(t *testing.T, chain *testChain, markerPath string) {
+	checkState(t, chain.db, chain.head)
+	checkState(t, chain.db, chain.genesis)
+
+	if ok, _ := chain.db.Has(chain.old[:]); ok {
+		t.Errorf("historical state root not pruned")
+	}
+	if ok, _ := chain.db.Has(crypto.Keccak256(chain.oldCode)); ok {
+		t.Errorf("historical code not pruned")
+	}
+	if _, err := os.Stat(markerPath); !os.IsNotExist(err) {
+		t.Errorf("marker database not removed: %v", err)
+	}
+}
+
+// Tests that pruning deletes the historical state, retaining the head and
+// genesis states.
+func TestPrune(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.close()
+
+	checkState(t, chain.db, chain.old)
+
+	markerPath := filepath.Join(chain.dir, "prunestate")
+	pruner, err := New(chain.db, markerPath)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	checkPruned(t, chain, markerPath)
+}
+
+// Tests that pruning resumes from the marks of an interrupted run, and restarts
+// if the chain progressed since.
+func TestPruneResume(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.close()
+
+	markerPath := filepath.Join(chain.dir, "prunestate")
+	pruner, err := New(chain.db, markerPath)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	// Interrupt a pruning after marking the states
+	head := rawdb.ReadHeadBlockHash(chain.db)
+	roots, err := pruner.retainedRoots(head)
+	if err != nil {
+		t.Fatalf("failed to retrieve retained states: %v", err)
+	}
+	if len(roots) != 2 || roots[0] != chain.head || roots[1] != chain.genesis {
+		t.Fatalf("retained states mismatch: have %x, want [%x %x]", roots, chain.head, chain.genesis)
+	}
+	marker, err := pruner.openMarker(head)
+	if err != nil {
+		t.Fatalf("failed to open marker: %v", err)
+	}
+	if err := pruner.mark(marker, roots); err != nil {
+		t.Fatalf("failed to mark: %v", err)
+	}
+	// Mark the historical state root as if the chain was at another head
+	marker.Put(markerHeadKey, common.Hash{0x01}.Bytes())
+	marker.Put(markerKey(chain.old[:]), encodeIndex(0))
+	marker.Close()
+
+	// The stale marks must be discarded and the state pruned
+	if err := pruner.Prune(); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	checkPruned(t, chain, markerPath)
+
+	// Resume from fully marked states, which must be swept
+	if marker, err = pruner.openMarker(head); err != nil {
+		t.Fatalf("failed to open marker: %v", err)
+	}
+	if err := pruner.mark(marker, roots); err != nil {
+		t.Fatalf("failed to mark: %v", err)
+	}
+	marker.Close()
+
+	orphan := crypto.Keccak256Hash([]byte("orphan"))
+	chain.db.Put(orphan[:], []byte("orphan"))
+
+	if err := pruner.Prune(); err != nil {
+		t.Fatalf("failed to resume pruning: %v", err)
+	}
+	if ok, _ := chain.db.Has(orphan[:]); ok {
+		t.Errorf("orphan node not pruned on resumption")
+	}
+	checkPruned(t, chain, markerPath)
+}
+
+// Tests that pruning aborts without deleting anything if the head state is
+// incomplete.
+func TestPruneIncompleteState(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.close()
+
+	// Delete a node of the head state below its root
+	statedb, _ := state.New(chain.head, state.NewDatabase(chain.db))
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash != (common.Hash{}) && it.Parent != (common.Hash{}) && it.Parent != chain.head {
+			break
+		}
+	}
+	if err := chain.db.Delete(it.Hash[:]); err != nil {
+		t.Fatal(err)
+	}
+	pruner, err := New(chain.db, filepath.Join(chain.dir, "prunestate"))
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(); err == nil {
+		t.Fatalf("pruned incomplete state")
+	}
+	checkState(t, chain.db, chain.old)
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat copy of the leaves of a state, which allows
+// reading and iterating over the accounts and storage slots of the state without
+// traversing its tries.
+//
+// The snapshot covers the single state it was created for, and is not updated
+// as new blocks are imported.
+package snapshot
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// errNotIterable is returned if the content of a database cannot be iterated.
+var errNotIterable = errors.New("database not iterable")
+
+// Lengths of the database keys of the snapshot entries.
+var (
+	accountKeyLength = len(rawdb.SnapshotAccountPrefix) + common.HashLength
+	storageKeyLength = len(rawdb.SnapshotStoragePrefix) + 2*common.HashLength
+)
+
+// iteratee is implemented by the databases whose content can be iterated over.
+type iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Snapshot is a complete flat copy of the state with a given root. The account
+// entries hold the RLP encoded accounts and the storage entries the RLP encoded
+// slot values, as stored in the leaves of the tries.
+type Snapshot struct {
+	db   ethdb.Database
+	root common.Hash
+}
+
+// Load opens the snapshot stored in the database. It returns nil if there is no
+// complete snapshot, or if the database cannot be iterated over.
+func Load(db ethdb.Database) *Snapshot {
+	if _, ok := rawdb.KeyValueStore(db).(iteratee); !ok {
+		return nil
+	}
+	root := rawdb.ReadSnapshotRoot(db)
+	if root == (common.Hash{}) {
+		return nil
+	}
+	return &Snapshot{db: db, root: root}
+}
+
+// Root returns the root of the state covered by the snapshot.
+func (s *Snapshot) Root() common.Hash {
+	return s.root
+}
+
+// Account retrieves the RLP encoded account with the given hash, or nil if the
+// account does not exist.
+func (s *Snapshot) Account(hash common.Hash) []byte {
+	return rawdb.ReadAccountSnapshot(s.db, hash)
+}
+
+// Storage retrieves the RLP encoded value of a storage slot of an account, or
+// nil if the slot is empty.
+func (s *Snapshot) Storage(accountHash, storageHash common.Hash) []byte {
+	return rawdb.ReadStorageSnapshot(s.db, accountHash, storageHash)
+}
+
+// AccountIterator returns an iterator over the accounts, starting at the given
+// account hash.
+func (s *Snapshot) AccountIterator(seek common.Hash) *Iterator {
+	return newIterator(s.db, rawdb.SnapshotAccountPrefix, accountKeyLength, seek)
+}
+
+// StorageIterator returns an iterator over the storage slots of an account,
+// starting at the given storage hash.
+func (s *Snapshot) StorageIterator(accountHash, seek common.Hash) *Iterator {
+	prefix := append(append([]byte{}, rawdb.SnapshotStoragePrefix...), accountHash[:]...)
+	return newIterator(s.db, prefix, storageKeyLength, seek)
+}
+
+// Iterator iterates over the entries of a snapshot in ascending hash order.
+type Iterator struct {
+	it     iterator.Iterator // Database iterator over the entry keys
+	seek   []byte            // Database key to position the iterator at first
+	length int               // Length of the entry keys, others are skipped
+}
+
+// newIterator creates an iterator over the entries with the given key prefix
+// and length, starting at the entry with the given hash.
+func newIterator(db ethdb.Database, prefix []byte, length int, seek common.Hash) *Iterator {
+	return &Iterator{
+		it:     rawdb.KeyValueStore(db).(iteratee).NewIteratorWithPrefix(prefix),
+		seek:   append(append([]byte{}, prefix...), seek[:]...),
+		length: length,
+	}
+}
+
+// Next moves the iterator to the next entry, returning whether there is one.
+func (it *Iterator) Next() bool {
+	// Trie nodes may share the prefix of the entries, skip them by length
+	for {
+		var ok bool
+		if it.seek != nil {
+			ok, it.seek = it.it.Seek(it.seek), nil
+		} else {
+			ok = it.it.Next()
+		}
+		if !ok || len(it.it.Key()) == it.length {
+			return ok
+		}
+	}
+}
+
+// Hash returns the account or storage hash of the current entry.
+func (it *Iterator) Hash() common.Hash {
+	key := it.it.Key()
+	return common.BytesToHash(key[len(key)-common.HashLength:])
+}
+
+// Entry returns the RLP encoded account or slot value of the current entry.
+func (it *Iterator) Entry() []byte {
+	return it.it.Value()
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *Iterator) Error() error {
+	return it.it.Error()
+}
+
+// Release releases the resources of the iterator.
+func (it *Iterator) Release() {
+	it.it.Release()
+}
+
+// Wipe invalidates the snapshot stored in the database and deletes its entries.
+func Wipe(db ethdb.Database) error {
+	kvdb, ok := rawdb.KeyValueStore(db).(iteratee)
+	if !ok {
+		return errNotIterable
+	}
+	rawdb.DeleteSnapshotRoot(db)
+
+	for prefix, length := range map[string]int{
+		string(rawdb.SnapshotAccountPrefix): accountKeyLength,
+		string(rawdb.SnapshotStoragePrefix): storageKeyLength,
+	} {
+		it := kvdb.NewIteratorWithPrefix([]byte(prefix))
+		for it.Next() {
+			if len(it.Key()) != length {
+				continue
+			}
+			if err := db.Delete(common.CopyBytes(it.Key())); err != nil {
+				it.Release()
+				return err
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generate creates a snapshot of the state with the given root from its tries,
+// replacing any snapshot stored in the database.
+func Generate(db ethdb.Database, root common.Hash) (*Snapshot, error) {
+	if err := Wipe(db); err != nil {
+		return nil, err
+	}
+	sdb := state.NewDatabase(db)
+	tr, err := sdb.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		start    = time.Now()
+		batch    = db.NewBatch()
+		accounts int
+		slots    int
+	)
+	flush := func(force bool) error {
+		if !force && batch.ValueSize() < ethdb.IdealBatchSize {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		accountHash := common.BytesToHash(it.Key)
+		rawdb.WriteAccountSnapshot(batch, accountHash, it.Value)
+		accounts++
+
+		var account state.Account
+		if err := rlp.DecodeBytes(it.Value, &account); err != nil {
+			return nil, err
+		}
+		if account.Root != types.EmptyRootHash {
+			storage, err := sdb.OpenStorageTrie(accountHash, account.Root)
+			if err != nil {
+				return nil, err
+			}
+			sit := trie.NewIterator(storage.NodeIterator(nil))
+			for sit.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(sit.Key), sit.Value)
+				slots++
+
+				if err := flush(false); err != nil {
+					return nil, err
+				}
+			}
+			if sit.Err != nil {
+				return nil, sit.Err
+			}
+		}
+		if err := flush(false); err != nil {
+			return nil, err
+		}
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	rawdb.WriteSnapshotRoot(batch, root)
+	if err := flush(true); err != nil {
+		return nil, err
+	}
+	log.Info("Generated state snapshot", "root", root, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	return &Snapshot{db: db, root: root}, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestState creates a state with a number of accounts, some of which have
+// storage slots.
+func makeTestState(t *testing.T) (*ethdb.MemDatabase, common.Hash) {
+	db := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb)
+
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetBalance(addr, big.NewInt(int64(i)+1))
+		if i%4 == 0 {
+			for j := byte(1); j <= i; j++ {
+				statedb.SetState(addr, common.BytesToHash([]byte{j}), common.BytesToHash([]byte{i, j}))
+			}
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	return db, root
+}
+
+// Tests that a generated snapshot holds the leaves of the state tries, and that
+// its iterators return them in order.
+func TestGenerate(t *testing.T) {
+	db, root := makeTestState(t)
+
+	// Add a trie node sharing the prefix of the account entries
+	node := append([]byte("a"), bytes.Repeat([]byte{0x01}, 31)...)
+	db.Put(node, []byte("node"))
+
+	if Load(db) != nil {
+		t.Fatalf("snapshot loaded before generation")
+	}
+	if _, err := Generate(db, root); err != nil {
+		t.Fatalf("failed to generate snapshot: %v", err)
+	}
+	snap := Load(db)
+	if snap == nil || snap.Root() != root {
+		t.Fatalf("snapshot root mismatch: have %v, want %x", snap, root)
+	}
+	sdb := state.NewDatabase(db)
+	tr, _ := sdb.OpenTrie(root)
+
+	// Iterate over the accounts and storage slots along the trie leaves
+	accounts, it := 0, snap.AccountIterator(common.Hash{})
+	leaves := trie.NewIterator(tr.NodeIterator(nil))
+	for leaves.Next() {
+		if !it.Next() {
+			t.Fatalf("account %x: missing from iteration", leaves.Key)
+		}
+		hash := common.BytesToHash(leaves.Key)
+		if it.Hash() != hash || !bytes.Equal(it.Entry(), leaves.Value) {
+			t.Fatalf("account %x: entry mismatch: have %x %x", hash, it.Hash(), it.Entry())
+		}
+		if !bytes.Equal(snap.Account(hash), leaves.Value) {
+			t.Fatalf("account %x: retrieved entry mismatch", hash)
+		}
+		accounts++
+	}
+	if it.Next() {
+		t.Fatalf("unexpected account %x iterated", it.Hash())
+	}
+	it.Release()
+
+	if accounts != 64 {
+		t.Fatalf("account count mismatch: have %d, want %d", accounts, 64)
+	}
+	addrHash := crypto.Keccak256Hash(common.BytesToAddress([]byte{8}).Bytes())
+	slots, sit := 0, snap.StorageIterator(addrHash, common.Hash{})
+	for sit.Next() {
+		slots++
+	}
+	sit.Release()
+	if slots != 8 {
+		t.Fatalf("storage slot count mismatch: have %d, want %d", slots, 8)
+	}
+	slot := crypto.Keccak256Hash(common.BytesToHash([]byte{3}).Bytes())
+	if snap.Storage(addrHash, slot) == nil {
+		t.Fatalf("storage slot %x missing", slot)
+	}
+	// Iterate from the middle of the accounts
+	var seek common.Hash
+	seek[0] = 0x80
+	if it = snap.AccountIterator(seek); !it.Next() || bytes.Compare(it.Hash().Bytes(), seek.Bytes()) < 0 {
+		t.Fatalf("seeked iteration started at %x, before %x", it.Hash(), seek)
+	}
+	it.Release()
+
+	// Wipe the snapshot and ensure only its entries are deleted
+	if err := Wipe(db); err != nil {
+		t.Fatalf("failed to wipe snapshot: %v", err)
+	}
+	if Load(db) != nil {
+		t.Fatalf("snapshot loaded after wipe")
+	}
+	if snap.Account(addrHash) != nil || snap.Storage(addrHash, slot) != nil {
+		t.Fatalf("snapshot entries not wiped")
+	}
+	if ok, _ := db.Has(node); !ok {
+		t.Fatalf("trie node wiped along with the snapshot")
+	}
+	if _, err := state.New(root, sdb); err != nil {
+		t.Fatalf("state unavailable after wipe: %v", err)
+	}
+}
@@ -249,6 +249,35 @@ func (self *StateDB) Database() Database {
 	return self.db
 }
 
+// proofList collects the nodes of a Merkle proof in the order they are
+// written, from the root to the leaf.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// GetProof returns the Merkle proof of an account in the state trie, the
+// RLP encoded trie nodes on the path from the root to the account.
+func (self *StateDB) GetProof(addr common.Address) ([][]byte, error) {
+	var proof proofList
+	err := self.trie.Prove(crypto.Keccak256(addr.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// GetStorageProof returns the Merkle proof of a storage slot of an account in
+// its storage trie.
+func (self *StateDB) GetStorageProof(addr common.Address, key common.Hash) ([][]byte, error) {
+	tr := self.StorageTrie(addr)
+	if tr == nil {
+		return nil, fmt.Errorf("storage trie of account %x does not exist", addr)
+	}
+	var proof proofList
+	err := tr.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
 // StorageTrie returns the storage trie of an account.
 // The return value is a copy and is nil for non-existent accounts.
 func (self *StateDB) StorageTrie(addr common.Address) Trie {
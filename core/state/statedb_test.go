@@ -32,7 +32,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -435,3 +438,71 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+func TestStateProofs(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(ethdb.NewMemDatabase()))
+	addr := common.BytesToAddress([]byte{0x01})
+	key, value := common.Hash{0x02}, common.Hash{0x03}
+	for i := byte(0); i < 100; i++ {
+		state.AddBalance(common.BytesToAddress([]byte{i, 0xff}), big.NewInt(int64(i)+1))
+	}
+	state.SetNonce(addr, 7)
+	state.SetState(addr, key, value)
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the proofs of the account and of its storage slot
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _, err := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), proofDb(proof))
+	if err != nil {
+		t.Fatalf("invalid account proof: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Nonce != 7 || account.Root != state.StorageTrie(addr).Hash() {
+		t.Fatalf("unexpected account %+v", account)
+	}
+	proof, err = state.GetStorageProof(addr, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _, err = trie.VerifyProof(account.Root, crypto.Keccak256(key.Bytes()), proofDb(proof))
+	if err != nil {
+		t.Fatalf("invalid storage proof: %v", err)
+	}
+	var stored []byte
+	if err := rlp.DecodeBytes(enc, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if common.BytesToHash(stored) != value {
+		t.Fatalf("unexpected storage value %x", stored)
+	}
+
+	// the proof of a missing account proves its absence
+	missing := common.BytesToAddress([]byte{0x04})
+	if proof, err = state.GetProof(missing); err != nil {
+		t.Fatal(err)
+	}
+	if enc, _, err := trie.VerifyProof(root, crypto.Keccak256(missing.Bytes()), proofDb(proof)); err != nil || enc != nil {
+		t.Fatalf("expected a proof of absence, got %x %v", enc, err)
+	}
+	if _, err := state.GetStorageProof(missing, key); err == nil {
+		t.Fatal("expected no storage proof of a missing account")
+	}
+}
+
+// proofDb stores the nodes of a proof by hash for verification
+func proofDb(proof [][]byte) *ethdb.MemDatabase {
+	db := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
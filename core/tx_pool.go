@@ -129,6 +129,7 @@ type TxPoolConfig struct {
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
+	MaxTxSize  uint64 // Maximum size in bytes of a transaction accepted into the pool
 
 	AccountSlots uint64 // Minimum number of executable transaction slots guaranteed per account
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
@@ -146,6 +147,7 @@ var DefaultTxPoolConfig = TxPoolConfig{
 
 	PriceLimit: 1,
 	PriceBump:  10,
+	MaxTxSize:  32 * 1024,
 
 	AccountSlots: 16,
 	GlobalSlots:  4096,
@@ -171,9 +173,23 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.MaxTxSize < 1 {
+		log.Warn("Sanitizing invalid txpool max transaction size", "provided", conf.MaxTxSize, "updated", DefaultTxPoolConfig.MaxTxSize)
+		conf.MaxTxSize = DefaultTxPoolConfig.MaxTxSize
+	}
 	return conf
 }
 
+// TxPoolPolicy is the part of the transaction pool configuration governing
+// the replacement and inclusion of transactions, which may be adjusted while
+// the pool is running.
+type TxPoolPolicy struct {
+	PriceBump    uint64 `json:"priceBump"`    // Minimum price bump percentage to replace an already existing transaction (nonce)
+	AccountSlots uint64 `json:"accountSlots"` // Minimum number of executable transaction slots guaranteed per account
+	NoLocals     bool   `json:"noLocals"`     // Whether local transaction handling should be disabled
+	MaxTxSize    uint64 `json:"maxTxSize"`    // Maximum size in bytes of a transaction accepted into the pool
+}
+
 // TxPool contains all currently known transactions. Transactions
 // enter the pool when they are received from the network or submitted
 // locally. They exit the pool when they are included in the blockchain.
@@ -471,6 +487,44 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// Policy retrieves the current replacement and inclusion policy of the pool.
+func (pool *TxPool) Policy() TxPoolPolicy {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return TxPoolPolicy{
+		PriceBump:    pool.config.PriceBump,
+		AccountSlots: pool.config.AccountSlots,
+		NoLocals:     pool.config.NoLocals,
+		MaxTxSize:    pool.config.MaxTxSize,
+	}
+}
+
+// SetPolicy updates the replacement and inclusion policy of the pool. The pool
+// limits are enforced again right away, the other rules apply to the
+// transactions added afterwards.
+func (pool *TxPool) SetPolicy(policy TxPoolPolicy) error {
+	if policy.PriceBump < 1 {
+		return errors.New("price bump must be positive")
+	}
+	if policy.MaxTxSize < 1 {
+		return errors.New("max transaction size must be positive")
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.config.PriceBump = policy.PriceBump
+	pool.config.AccountSlots = policy.AccountSlots
+	pool.config.NoLocals = policy.NoLocals
+	pool.config.MaxTxSize = policy.MaxTxSize
+
+	pool.promoteExecutables(nil)
+
+	log.Info("Transaction pool policy updated", "pricebump", policy.PriceBump, "accountslots", policy.AccountSlots,
+		"nolocals", policy.NoLocals, "maxtxsize", policy.MaxTxSize)
+	return nil
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -533,6 +587,16 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+// Locals retrieves all currently known local transactions, groupped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxPool) Locals() map[common.Address]types.Transactions {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.local()
+}
+
 // local retrieves all currently known local transactions, groupped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -552,8 +616,8 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
-	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
-	if tx.Size() > 32*1024 {
+	// Heuristic limit, reject transactions over 32KB by default to prevent DOS attacks
+	if tx.Size() > common.StorageSize(pool.config.MaxTxSize) {
 		return ErrOversizedData
 	}
 	// Transactions can't be negative. This may never happen using RLP decoded
@@ -755,7 +819,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 // the sender as a local one in the mean time, ensuring it goes around the local
 // pricing constraints.
 func (pool *TxPool) AddLocal(tx *types.Transaction) error {
-	return pool.addTx(tx, !pool.config.NoLocals)
+	return pool.addTx(tx, true)
 }
 
 // AddRemote enqueues a single transaction into the pool if it is valid. If the
@@ -769,7 +833,7 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 // marking the senders as a local ones in the mean time, ensuring they go around
 // the local pricing constraints.
 func (pool *TxPool) AddLocals(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals)
+	return pool.addTxs(txs, true)
 }
 
 // AddRemotes enqueues a batch of transactions into the pool if they are valid.
@@ -784,6 +848,8 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	local = local && !pool.config.NoLocals
+
 	// Try to inject the transaction and update any state
 	replace, err := pool.add(tx, local)
 	if err != nil {
@@ -802,6 +868,8 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	local = local && !pool.config.NoLocals
+
 	return pool.addTxsLocked(txs, local)
 }
 
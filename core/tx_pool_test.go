@@ -1567,6 +1567,59 @@ func TestTransactionReplacement(t *testing.T) {
 	}
 }
 
+// Tests that the replacement and inclusion policy of the pool can be adjusted
+// while it is running.
+func TestTransactionPoolPolicy(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	policy := pool.Policy()
+	if want := (TxPoolPolicy{testTxPoolConfig.PriceBump, testTxPoolConfig.AccountSlots, testTxPoolConfig.NoLocals, testTxPoolConfig.MaxTxSize}); policy != want {
+		t.Fatalf("policy mismatch: have %+v, want %+v", policy, want)
+	}
+	if err := pool.SetPolicy(TxPoolPolicy{MaxTxSize: 1024}); err == nil {
+		t.Fatalf("zero price bump accepted")
+	}
+	policy.PriceBump, policy.MaxTxSize, policy.NoLocals = 50, 1024, true
+	if err := pool.SetPolicy(policy); err != nil {
+		t.Fatalf("failed to set policy: %v", err)
+	}
+	// Ensure the new price bump is enforced for replacements
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(100), key)); err != nil {
+		t.Fatalf("failed to add original pending transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(149), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("pending transaction replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(150), key)); err != nil {
+		t.Fatalf("failed to replace pending transaction: %v", err)
+	}
+	// Ensure the new size limit is enforced
+	tx, _ := types.SignTx(types.NewTransaction(1, common.Address{}, big.NewInt(100), 1000000, big.NewInt(1), make([]byte, 1024)), types.HomesteadSigner{}, key)
+	if err := pool.AddRemote(tx); err != ErrOversizedData {
+		t.Fatalf("oversized transaction error mismatch: have %v, want %v", err, ErrOversizedData)
+	}
+	// Ensure local transactions lose their price exemption without local handling
+	pool.SetGasPrice(big.NewInt(100))
+	if err := pool.AddLocal(pricedTransaction(1, 100000, big.NewInt(1), key)); err != ErrUnderpriced {
+		t.Fatalf("local transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	policy.NoLocals = false
+	if err := pool.SetPolicy(policy); err != nil {
+		t.Fatalf("failed to set policy: %v", err)
+	}
+	if err := pool.AddLocal(pricedTransaction(1, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
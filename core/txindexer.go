@@ -0,0 +1,181 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// txIndexTailInterval is the number of blocks after which the progress of the
+// transaction unindexing is persisted.
+const txIndexTailInterval = 1024
+
+// txIndexTail returns the oldest block whose transactions should be indexed with
+// the given chain head, according to the configured lookup limit.
+func (bc *BlockChain) txIndexTail(head uint64) uint64 {
+	switch {
+	case bc.cacheConfig.TxLookupDisabled:
+		return head + 1
+	case bc.cacheConfig.TxLookupLimit > 0 && head >= bc.cacheConfig.TxLookupLimit:
+		return head - bc.cacheConfig.TxLookupLimit + 1
+	default:
+		return 0
+	}
+}
+
+// maintainTxIndex keeps the transaction lookup index restricted to the window of
+// recent blocks configured by the lookup limit. Whenever the chain head or the
+// window itself changes, the blocks falling out of the window are unindexed and
+// the ones entering it are (re)indexed in the background.
+func (bc *BlockChain) maintainTxIndex() {
+	defer bc.wg.Done()
+
+	// Subscribe outside of the scope, which may already be closed by a quick Stop
+	heads := make(chan ChainHeadEvent, 10)
+	sub := bc.chainHeadFeed.Subscribe(heads)
+	defer sub.Unsubscribe()
+
+	// Run at most one index update at a time, skipping heads arriving meanwhile
+	var done, abort chan struct{}
+	update := func(head uint64) {
+		done, abort = make(chan struct{}), make(chan struct{})
+		go bc.updateTxIndex(bc.txIndexTail(head), done, abort)
+	}
+	update(bc.CurrentBlock().NumberU64())
+	for {
+		select {
+		case head := <-heads:
+			if done == nil {
+				update(head.Block.NumberU64())
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				close(abort)
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// updateTxIndex moves the tail of the transaction lookup index to the given
+// block, unindexing or indexing the blocks in between.
+func (bc *BlockChain) updateTxIndex(want uint64, done chan struct{}, abort chan struct{}) {
+	defer close(done)
+
+	var tail uint64
+	if stored := rawdb.ReadTxIndexTail(bc.db); stored != nil {
+		tail = *stored
+	}
+	switch {
+	case tail < want:
+		bc.unindexTransactions(tail, want, abort)
+	case tail > want:
+		bc.indexTransactions(want, tail, abort)
+	}
+}
+
+// indexTransactions writes the lookup entries of the transactions of the canonical
+// blocks in [from, to), going backwards from the current index tail.
+func (bc *BlockChain) indexTransactions(from uint64, to uint64, abort chan struct{}) {
+	var (
+		batch  = bc.db.NewBatch()
+		start  = time.Now()
+		logged = time.Now()
+		tail   = to
+	)
+	for tail > from {
+		select {
+		case <-abort:
+			bc.commitTxIndexTail(batch, tail)
+			return
+		default:
+		}
+		number := tail - 1
+		if block := rawdb.ReadBlock(bc.db, rawdb.ReadCanonicalHash(bc.db, number), number); block != nil {
+			rawdb.WriteTxLookupEntries(batch, block)
+		}
+		tail = number
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			bc.commitTxIndexTail(batch, tail)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing transactions", "tail", tail, "remaining", tail-from, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	bc.commitTxIndexTail(batch, tail)
+	if to-from > 1 {
+		log.Info("Indexed transactions", "blocks", to-from, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// unindexTransactions deletes the lookup entries of the transactions of the
+// canonical blocks in [from, to), going forward from the current index tail.
+// As batches cannot delete, the entries are removed from the database directly
+// and the tail is only advanced periodically, after the deletions.
+func (bc *BlockChain) unindexTransactions(from uint64, to uint64, abort chan struct{}) {
+	var (
+		batch  = bc.db.NewBatch()
+		start  = time.Now()
+		logged = time.Now()
+		tail   = from
+	)
+	for tail < to {
+		select {
+		case <-abort:
+			bc.commitTxIndexTail(batch, tail)
+			return
+		default:
+		}
+		if body := rawdb.ReadBody(bc.db, rawdb.ReadCanonicalHash(bc.db, tail), tail); body != nil {
+			for _, tx := range body.Transactions {
+				rawdb.DeleteTxLookupEntry(bc.db, tx.Hash())
+			}
+		}
+		tail++
+
+		if tail%txIndexTailInterval == 0 {
+			bc.commitTxIndexTail(batch, tail)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Unindexing transactions", "tail", tail, "remaining", to-tail, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	bc.commitTxIndexTail(batch, tail)
+	if to-from > 1 {
+		log.Info("Unindexed transactions", "blocks", to-from, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// commitTxIndexTail flushes the pending index changes along with the new tail.
+func (bc *BlockChain) commitTxIndexTail(batch ethdb.Batch, tail uint64) {
+	rawdb.WriteTxIndexTail(batch, tail)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write transaction index", "err", err)
+	}
+	batch.Reset()
+}
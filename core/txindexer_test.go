@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the transaction lookup index is restricted to the configured window
+// of recent blocks, and that it is lazily rebuilt when the window changes.
+func TestTxIndexLimit(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		db      = ethdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(address), common.Address{0x01}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	// check waits for the index to reach the given tail, and verifies that exactly
+	// the transactions of the blocks from the tail onwards are indexed
+	check := func(tail uint64) {
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			if stored := rawdb.ReadTxIndexTail(db); stored != nil && *stored == tail {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("index tail mismatch: have %v, want %d", rawdb.ReadTxIndexTail(db), tail)
+			}
+		}
+		for _, block := range blocks {
+			for _, tx := range block.Transactions() {
+				hash, _, _ := rawdb.ReadTxLookupEntry(db, tx.Hash())
+				if indexed, want := hash != (common.Hash{}), block.NumberU64() >= tail; indexed != want {
+					t.Errorf("block %d: index presence mismatch: have %v, want %v", block.NumberU64(), indexed, want)
+				}
+			}
+		}
+	}
+	// open recreates the blockchain with the given transaction index settings
+	open := func(limit uint64, disabled bool) *BlockChain {
+		chain, err := NewBlockChain(db, &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, TxLookupLimit: limit, TxLookupDisabled: disabled}, gspec.Config, ethash.NewFaker(), vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		return chain
+	}
+	chain := open(4, false)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	check(7)
+	chain.Stop()
+
+	// Lift the limit and check that the old blocks are reindexed
+	chain = open(0, false)
+	check(0)
+	chain.Stop()
+
+	// Disable the index and check that all entries are dropped
+	chain = open(0, true)
+	check(11)
+	chain.Stop()
+}
@@ -19,7 +19,9 @@ package vm
 import (
 	"crypto/sha256"
 	"errors"
+	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -68,6 +70,96 @@ func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contr
 	return nil, ErrOutOfGas
 }
 
+// customPrecompile is an additional precompiled contract registered by an
+// embedder at a fixed address, active on every chain from a given block.
+type customPrecompile struct {
+	contract PrecompiledContract
+	block    *big.Int
+}
+
+var (
+	customPrecompiles     = make(map[common.Address]*customPrecompile) // Contracts registered at fixed addresses
+	namedPrecompiles      = make(map[string]PrecompiledContract)       // Contracts registered for activation by chain configs
+	customPrecompilesLock sync.RWMutex
+)
+
+// RegisterPrecompiledContract registers an additional precompiled contract at
+// the given address, active on all chains from the given block onward (nil
+// meaning from genesis). It is meant for embedders running private networks and
+// must be called before any chain is processed, as all nodes of a network need
+// to agree on the set of precompiled contracts.
+func RegisterPrecompiledContract(addr common.Address, block *big.Int, contract PrecompiledContract) error {
+	if _, ok := PrecompiledContractsByzantium[addr]; ok {
+		return fmt.Errorf("address %x reserved for a standard precompiled contract", addr)
+	}
+	if block == nil {
+		block = new(big.Int)
+	}
+	customPrecompilesLock.Lock()
+	defer customPrecompilesLock.Unlock()
+
+	if _, ok := customPrecompiles[addr]; ok {
+		return fmt.Errorf("precompiled contract already registered at %x", addr)
+	}
+	customPrecompiles[addr] = &customPrecompile{contract: contract, block: new(big.Int).Set(block)}
+	return nil
+}
+
+// RegisterNamedPrecompiledContract registers a precompiled contract under the
+// given name, allowing chain configs to activate it at the addresses and blocks
+// listed in their precompiles section.
+func RegisterNamedPrecompiledContract(name string, contract PrecompiledContract) error {
+	customPrecompilesLock.Lock()
+	defer customPrecompilesLock.Unlock()
+
+	if _, ok := namedPrecompiles[name]; ok {
+		return fmt.Errorf("precompiled contract %q already registered", name)
+	}
+	namedPrecompiles[name] = contract
+	return nil
+}
+
+// ValidatePrecompiles checks that all the additional precompiled contracts of a
+// chain config are registered and don't collide with any other precompiled one.
+func ValidatePrecompiles(config *params.ChainConfig) error {
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+
+	for addr, precompile := range config.Precompiles {
+		if precompile == nil {
+			return fmt.Errorf("precompile %x: missing config", addr)
+		}
+		if _, ok := namedPrecompiles[precompile.Name]; !ok {
+			return fmt.Errorf("precompile %x: unknown contract %q", addr, precompile.Name)
+		}
+		if _, ok := PrecompiledContractsByzantium[addr]; ok {
+			return fmt.Errorf("precompile %x: address reserved for a standard precompiled contract", addr)
+		}
+		if _, ok := customPrecompiles[addr]; ok {
+			return fmt.Errorf("precompile %x: address already registered by the embedder", addr)
+		}
+	}
+	return nil
+}
+
+// activeCustomPrecompile returns the additional precompiled contract active at
+// the given address and block, if any.
+func activeCustomPrecompile(config *params.ChainConfig, number *big.Int, addr common.Address) PrecompiledContract {
+	if precompile := config.Precompiles[addr]; precompile != nil && precompile.Block != nil && precompile.Block.Cmp(number) <= 0 {
+		customPrecompilesLock.RLock()
+		defer customPrecompilesLock.RUnlock()
+
+		return namedPrecompiles[precompile.Name]
+	}
+	customPrecompilesLock.RLock()
+	defer customPrecompilesLock.RUnlock()
+
+	if precompile := customPrecompiles[addr]; precompile != nil && precompile.block.Cmp(number) <= 0 {
+		return precompile.contract
+	}
+	return nil
+}
+
 // ECRECOVER implemented as a native contract.
 type ecrecover struct{}
 
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -481,3 +482,69 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// testPrecompile is an additional precompiled contract echoing its input.
+type testPrecompile struct{}
+
+func (testPrecompile) RequiredGas(input []byte) uint64  { return 10 }
+func (testPrecompile) Run(input []byte) ([]byte, error) { return input, nil }
+
+// Tests that additional precompiled contracts are activated at their configured
+// blocks and that conflicting registrations are rejected.
+func TestCustomPrecompiles(t *testing.T) {
+	var (
+		named = common.BytesToAddress([]byte{0x01, 0x00})
+		fixed = common.BytesToAddress([]byte{0x02, 0x00})
+	)
+	if err := RegisterNamedPrecompiledContract("test-echo", testPrecompile{}); err != nil {
+		t.Fatalf("failed to register named contract: %v", err)
+	}
+	if err := RegisterNamedPrecompiledContract("test-echo", testPrecompile{}); err == nil {
+		t.Errorf("duplicate named contract registered")
+	}
+	if err := RegisterPrecompiledContract(common.BytesToAddress([]byte{1}), nil, testPrecompile{}); err == nil {
+		t.Errorf("contract registered at a standard precompile address")
+	}
+	if err := RegisterPrecompiledContract(fixed, big.NewInt(3), testPrecompile{}); err != nil {
+		t.Fatalf("failed to register contract: %v", err)
+	}
+	if err := RegisterPrecompiledContract(fixed, nil, testPrecompile{}); err == nil {
+		t.Errorf("duplicate contract registered")
+	}
+	config := *params.TestChainConfig
+	config.Precompiles = map[common.Address]*params.PrecompileConfig{
+		named: {Name: "test-echo", Block: big.NewInt(5)},
+	}
+	if err := ValidatePrecompiles(&config); err != nil {
+		t.Fatalf("failed to validate precompiles: %v", err)
+	}
+	// Check that the contracts are only active from their activation blocks
+	tests := []struct {
+		number       int64
+		named, fixed bool
+	}{
+		{0, false, false},
+		{3, false, true},
+		{5, true, true},
+	}
+	for i, tt := range tests {
+		evm := NewEVM(Context{BlockNumber: big.NewInt(tt.number)}, nil, &config, Config{})
+		if active := evm.IsPrecompile(named); active != tt.named {
+			t.Errorf("test %d: named contract activity mismatch: have %v, want %v", i, active, tt.named)
+		}
+		if active := evm.IsPrecompile(fixed); active != tt.fixed {
+			t.Errorf("test %d: fixed contract activity mismatch: have %v, want %v", i, active, tt.fixed)
+		}
+	}
+	// Check that invalid chain configs are rejected
+	for i, precompiles := range []map[common.Address]*params.PrecompileConfig{
+		{named: {Name: "test-unknown", Block: big.NewInt(0)}},
+		{common.BytesToAddress([]byte{1}): {Name: "test-echo", Block: big.NewInt(0)}},
+		{fixed: {Name: "test-echo", Block: big.NewInt(0)}},
+	} {
+		config.Precompiles = precompiles
+		if err := ValidatePrecompiles(&config); err == nil {
+			t.Errorf("test %d: invalid precompiles accepted", i)
+		}
+	}
+}
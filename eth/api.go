@@ -25,6 +25,7 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
@@ -293,6 +295,167 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// ExportTransactions exports the local transactions of the transaction pool (or
+// all pending and queued ones if all is set) into a local file as an RLP stream
+// of signed transactions, returning the number of transactions exported.
+func (api *PrivateAdminAPI) ExportTransactions(file string, all *bool) (int, error) {
+	// Gather the transactions to export, ordered by nonce per account
+	var accounts []map[common.Address]types.Transactions
+	if all != nil && *all {
+		pending, queued := api.eth.TxPool().Content()
+		accounts = append(accounts, pending, queued)
+	} else {
+		accounts = append(accounts, api.eth.TxPool().Locals())
+	}
+	// Make sure we can create the file to export into
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if strings.HasSuffix(file, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	exported := 0
+	for _, txs := range accounts {
+		for _, list := range txs {
+			for _, tx := range list {
+				if err := rlp.Encode(writer, tx); err != nil {
+					return exported, err
+				}
+				exported++
+			}
+		}
+	}
+	return exported, nil
+}
+
+// ImportTransactions injects the signed transactions of an RLP stream file into
+// the transaction pool, as local ones unless remote is set, returning the number
+// of transactions accepted.
+func (api *PrivateAdminAPI) ImportTransactions(file string, remote *bool) (int, error) {
+	// Make sure the can access the file to import
+	in, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return 0, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	var txs []*types.Transaction
+	for {
+		tx := new(types.Transaction)
+		if err := stream.Decode(tx); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("transaction %d: failed to parse: %v", len(txs), err)
+		}
+		txs = append(txs, tx)
+	}
+	add := api.eth.TxPool().AddLocals
+	if remote != nil && *remote {
+		add = api.eth.TxPool().AddRemotes
+	}
+	accepted := 0
+	for i, err := range add(txs) {
+		if err != nil {
+			log.Debug("Failed to import transaction", "hash", txs[i].Hash(), "err", err)
+			continue
+		}
+		accepted++
+	}
+	return accepted, nil
+}
+
+// TxPoolPolicyArgs are the changes to the replacement and inclusion policy of
+// the transaction pool, the rules which are not set being left unchanged.
+type TxPoolPolicyArgs struct {
+	PriceBump    *uint64 `json:"priceBump"`
+	AccountSlots *uint64 `json:"accountSlots"`
+	NoLocals     *bool   `json:"noLocals"`
+	MaxTxSize    *uint64 `json:"maxTxSize"`
+}
+
+// TxPoolPolicy retrieves the replacement and inclusion policy of the
+// transaction pool.
+func (api *PrivateAdminAPI) TxPoolPolicy() core.TxPoolPolicy {
+	return api.eth.TxPool().Policy()
+}
+
+// SetTxPoolPolicy adjusts the replacement and inclusion policy of the
+// transaction pool, returning the resulting policy.
+func (api *PrivateAdminAPI) SetTxPoolPolicy(args TxPoolPolicyArgs) (core.TxPoolPolicy, error) {
+	policy := api.eth.TxPool().Policy()
+	if args.PriceBump != nil {
+		policy.PriceBump = *args.PriceBump
+	}
+	if args.AccountSlots != nil {
+		policy.AccountSlots = *args.AccountSlots
+	}
+	if args.NoLocals != nil {
+		policy.NoLocals = *args.NoLocals
+	}
+	if args.MaxTxSize != nil {
+		policy.MaxTxSize = *args.MaxTxSize
+	}
+	if err := api.eth.TxPool().SetPolicy(policy); err != nil {
+		return core.TxPoolPolicy{}, err
+	}
+	return policy, nil
+}
+
+// DownloaderConfigArgs are the changes to the tunable parameters of the chain
+// downloader, the parameters which are not set being left unchanged.
+type DownloaderConfigArgs struct {
+	StateRanges      *int           `json:"stateRanges"`
+	TargetRTT        *time.Duration `json:"targetRTT"`
+	ThroughputImpact *float64       `json:"throughputImpact"`
+	MinThroughput    *float64       `json:"minThroughput"`
+}
+
+// DownloaderConfig retrieves the tunable parameters of the chain downloader.
+func (api *PrivateAdminAPI) DownloaderConfig() downloader.Config {
+	return api.eth.Downloader().Config()
+}
+
+// SetDownloaderConfig adjusts the tunable parameters of the chain downloader,
+// returning the resulting configuration.
+func (api *PrivateAdminAPI) SetDownloaderConfig(args DownloaderConfigArgs) (downloader.Config, error) {
+	if args.StateRanges != nil && *args.StateRanges == 0 {
+		return downloader.Config{}, errors.New("state range count must be positive")
+	}
+	if args.ThroughputImpact != nil && *args.ThroughputImpact == 0 {
+		return downloader.Config{}, errors.New("throughput impact must be positive")
+	}
+	config := api.eth.Downloader().Config()
+	if args.StateRanges != nil {
+		config.StateRanges = *args.StateRanges
+	}
+	if args.TargetRTT != nil {
+		config.TargetRTT = *args.TargetRTT
+	}
+	if args.ThroughputImpact != nil {
+		config.ThroughputImpact = *args.ThroughputImpact
+	}
+	if args.MinThroughput != nil {
+		config.MinThroughput = *args.MinThroughput
+	}
+	if err := api.eth.Downloader().SetConfig(config); err != nil {
+		return downloader.Config{}, err
+	}
+	return config, nil
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
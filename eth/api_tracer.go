@@ -568,15 +568,22 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
-			return nil, err
+		// Constuct the native or JavaScript tracer to execute with
+		var stop func(error)
+		if native, ok := tracers.NewNative(*config.Tracer); ok {
+			tracer, stop = native, native.Stop
+		} else {
+			jst, err := tracers.New(*config.Tracer)
+			if err != nil {
+				return nil, err
+			}
+			tracer, stop = jst, jst.Stop
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -603,7 +610,8 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.NativeTracer:
+		// JavaScript tracers expose the same interface as the native ones
 		return tracer.GetResult()
 
 	default:
@@ -35,7 +35,8 @@ import (
 
 // DefaultConfig contains default settings for use on the Ethereum main net.
 var DefaultConfig = Config{
-	SyncMode: downloader.FastSync,
+	SyncMode:   downloader.FastSync,
+	Downloader: downloader.DefaultConfig,
 	Ethash: ethash.Config{
 		CacheDir:       "ethash",
 		CachesInMem:    2,
@@ -54,6 +55,7 @@ var DefaultConfig = Config{
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
+		MaxPrice:   gasprice.DefaultMaxPrice,
 	},
 }
 
@@ -79,20 +81,28 @@ type Config struct {
 	Genesis *core.Genesis `toml:",omitempty"`
 
 	// Protocol options
-	NetworkId uint64 // Network ID to use for selecting peers to connect to
-	SyncMode  downloader.SyncMode
-	NoPruning bool
+	NetworkId  uint64 // Network ID to use for selecting peers to connect to
+	SyncMode   downloader.SyncMode
+	Downloader downloader.Config // Tunable parameters of the chain downloader
+	NoPruning  bool
 
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Ultra light client options
+	ULC *ULCConfig `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string
 	TrieCache          int
 	TrieTimeout        time.Duration
+	LogIndex           bool   // Whether to maintain an address and topic index of the logs
+	TxLookupLimit      uint64 // Number of recent blocks to index the transactions of (0 = entire chain)
+	NoTxLookup         bool   // Whether to disable the transaction lookup index altogether
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Config contains the tunable parameters of the downloader, allowing nodes with
+// ample bandwidth and processing power to sync more aggressively than the
+// conservative defaults permit.
+type Config struct {
+	StateRanges      int           `json:"stateRanges"`      // Number of account ranges to retrieve concurrently during snap sync (power of two)
+	TargetRTT        time.Duration `json:"targetRTT"`        // Minimum round trip time to size requests for (0 = measured RTT only)
+	ThroughputImpact float64       `json:"throughputImpact"` // Impact a single delivery has on a peer's estimated throughput
+	MinThroughput    float64       `json:"minThroughput"`    // Fraction of the best idle peer's throughput a peer needs to be assigned requests
+}
+
+// DefaultConfig contains the default downloader parameters.
+var DefaultConfig = Config{
+	StateRanges:      16,
+	ThroughputImpact: 0.1,
+}
+
+// withDefaults returns a copy of the configuration with the unset (zero) fields
+// which have no meaningful zero value filled in from DefaultConfig, so that
+// hand built configurations don't have to specify every parameter.
+func (c Config) withDefaults() Config {
+	if c.StateRanges == 0 {
+		c.StateRanges = DefaultConfig.StateRanges
+	}
+	if c.ThroughputImpact == 0 {
+		c.ThroughputImpact = DefaultConfig.ThroughputImpact
+	}
+	return c
+}
+
+// sanitize checks the parameters of the configuration, returning an error if
+// any of them are out of bounds.
+func (c Config) sanitize() error {
+	if c.StateRanges < 1 || c.StateRanges > 256 || c.StateRanges&(c.StateRanges-1) != 0 {
+		return fmt.Errorf("invalid state range count %d, must be a power of two within [1, 256]", c.StateRanges)
+	}
+	if c.TargetRTT < 0 || c.TargetRTT > ttlLimit {
+		return fmt.Errorf("invalid target RTT %v, must be within [0, %v]", c.TargetRTT, ttlLimit)
+	}
+	if c.ThroughputImpact <= 0 || c.ThroughputImpact > 1 {
+		return fmt.Errorf("invalid throughput impact %v, must be within (0, 1]", c.ThroughputImpact)
+	}
+	if c.MinThroughput < 0 || c.MinThroughput > 1 {
+		return fmt.Errorf("invalid minimum throughput %v, must be within [0, 1]", c.MinThroughput)
+	}
+	return nil
+}
+
+// tuning is a thread safe holder of the downloader configuration, shared by the
+// downloader and its peer set so the parameters can be changed while syncing.
+type tuning struct {
+	config atomic.Value
+}
+
+// newTuning creates a configuration holder with the given initial parameters.
+func newTuning(config Config) *tuning {
+	t := new(tuning)
+	t.config.Store(config)
+	return t
+}
+
+// get retrieves the current downloader configuration.
+func (t *tuning) get() Config {
+	return t.config.Load().(Config)
+}
+
+// set replaces the downloader configuration.
+func (t *tuning) set(config Config) {
+	t.config.Store(config)
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that invalid downloader configurations are rejected.
+func TestConfigSanitize(t *testing.T) {
+	tests := []struct {
+		config Config
+		valid  bool
+	}{
+		{DefaultConfig, true},
+		{Config{StateRanges: 256, TargetRTT: 10 * time.Second, ThroughputImpact: 1, MinThroughput: 1}, true},
+		{Config{StateRanges: 0, ThroughputImpact: 0.1}, false},
+		{Config{StateRanges: 12, ThroughputImpact: 0.1}, false},
+		{Config{StateRanges: 512, ThroughputImpact: 0.1}, false},
+		{Config{StateRanges: 16, TargetRTT: -time.Second, ThroughputImpact: 0.1}, false},
+		{Config{StateRanges: 16, TargetRTT: 2 * ttlLimit, ThroughputImpact: 0.1}, false},
+		{Config{StateRanges: 16, ThroughputImpact: 0}, false},
+		{Config{StateRanges: 16, ThroughputImpact: 0.1, MinThroughput: 1.5}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.config.sanitize(); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+// Tests that peers falling below the configured fraction of the best throughput
+// are not assigned requests, and that the target RTT bounds the request sizing.
+func TestConfigPeerSelection(t *testing.T) {
+	tuning := newTuning(DefaultConfig)
+	peers := newPeerSet(tuning)
+	for i, throughput := range []float64{100, 60, 20} {
+		p := newPeerConnection(fmt.Sprintf("peer-%d", i), 63, nil, tuning, log.New())
+		if err := peers.Register(p); err != nil {
+			t.Fatalf("failed to register peer %d: %v", i, err)
+		}
+		p.headerThroughput = throughput
+	}
+	if idle, total := peers.HeaderIdlePeers(); len(idle) != 3 || total != 3 {
+		t.Fatalf("unfiltered idle peers mismatch: have %d/%d, want 3/3", len(idle), total)
+	}
+	config := DefaultConfig
+	config.MinThroughput = 0.5
+	tuning.set(config)
+
+	idle, total := peers.HeaderIdlePeers()
+	if len(idle) != 2 || total != 2 {
+		t.Fatalf("filtered idle peers mismatch: have %d/%d, want 2/2", len(idle), total)
+	}
+	if idle[0].id != "peer-0" || idle[1].id != "peer-1" {
+		t.Errorf("filtered idle peers mismatch: have %s, %s", idle[0].id, idle[1].id)
+	}
+	// Check that the downloader sizes its requests for at least the target RTT
+	tester := newTester()
+	defer tester.terminate()
+
+	config = DefaultConfig
+	config.TargetRTT = 2 * rttMaxEstimate
+	if err := tester.downloader.SetConfig(config); err != nil {
+		t.Fatalf("failed to set config: %v", err)
+	}
+	if rtt := tester.downloader.requestRTT(); rtt != config.TargetRTT {
+		t.Errorf("request RTT mismatch: have %v, want %v", rtt, config.TargetRTT)
+	}
+	config.StateRanges = 3
+	if err := tester.downloader.SetConfig(config); err == nil {
+		t.Errorf("invalid config accepted")
+	}
+}
+
+// Tests that unset fields of a configuration take their defaults instead of
+// being rejected.
+func TestConfigDefaults(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	if err := tester.downloader.SetConfig(Config{}); err != nil {
+		t.Fatalf("failed to set empty config: %v", err)
+	}
+	if config := tester.downloader.Config(); config != DefaultConfig {
+		t.Errorf("config mismatch: have %+v, want %+v", config, DefaultConfig)
+	}
+	if err := tester.downloader.SetConfig(Config{StateRanges: 64, MinThroughput: 0.5}); err != nil {
+		t.Fatalf("failed to set partial config: %v", err)
+	}
+	want := Config{StateRanges: 64, ThroughputImpact: DefaultConfig.ThroughputImpact, MinThroughput: 0.5}
+	if config := tester.downloader.Config(); config != want {
+		t.Errorf("config mismatch: have %+v, want %+v", config, want)
+	}
+}
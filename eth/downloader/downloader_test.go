@@ -28,11 +28,13 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -69,6 +71,7 @@ type downloadTester struct {
 	peerChainTds map[string]map[common.Hash]*big.Int       // Total difficulties of the blocks in the peer chains
 
 	peerMissingStates map[string]map[common.Hash]bool // State entries that fast sync should not return
+	peerRangeLimit    uint64                          // Size limit of the state ranges served, if non-zero
 
 	lock sync.RWMutex
 }
@@ -398,7 +401,8 @@ func (dl *downloadTester) newSlowPeer(id string, version int, hashes []common.Ha
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
-	var err = dl.downloader.RegisterPeer(id, version, &downloadTesterPeer{dl: dl, id: id, delay: delay})
+	// Tester peers of the newest protocol version also run the snap protocol
+	var err = dl.downloader.RegisterPeer(id, version, &downloadTesterPeer{dl: dl, id: id, delay: delay, snap: version >= 64})
 	if err == nil {
 		// Assign the owned hashes, headers and blocks to the peer (deep copy)
 		dl.peerHashes[id] = make([]common.Hash, len(hashes))
@@ -460,6 +464,7 @@ type downloadTesterPeer struct {
 	dl    *downloadTester
 	id    string
 	delay time.Duration
+	snap  bool // Whether the peer runs the snap protocol, serving state ranges
 	lock  sync.RWMutex
 }
 
@@ -603,6 +608,143 @@ func (dlp *downloadTesterPeer) RequestNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// ServesRanges reports whether the peer runs the snap protocol.
+func (dlp *downloadTesterPeer) ServesRanges() bool {
+	return dlp.snap
+}
+
+// rangeLimit caps the requested size of a state range response by the limit
+// configured in the download tester.
+func (dlp *downloadTesterPeer) rangeLimit(bytes uint64) uint64 {
+	if limit := dlp.dl.peerRangeLimit; limit != 0 && limit < bytes {
+		return limit
+	}
+	return bytes
+}
+
+// serveRange collects the leaves of a trie starting at origin until the size
+// limit is reached, along with the proof of the range edges. It returns whether
+// leaves were left out due to the limit.
+func (dlp *downloadTesterPeer) serveRange(tr *trie.Trie, origin common.Hash, limit uint64) ([]common.Hash, [][]byte, [][]byte, bool) {
+	var (
+		hashes  []common.Hash
+		leaves  [][]byte
+		size    uint64
+		partial bool
+	)
+	it := trie.NewIterator(tr.NodeIterator(origin[:]))
+	for it.Next() {
+		if size >= limit {
+			partial = true
+			break
+		}
+		hashes = append(hashes, common.BytesToHash(it.Key))
+		leaves = append(leaves, common.CopyBytes(it.Value))
+		size += uint64(common.HashLength + len(it.Value))
+	}
+
+	db := ethdb.NewMemDatabase()
+	tr.Prove(origin[:], 0, db)
+	if len(hashes) > 0 {
+		tr.Prove(hashes[len(hashes)-1][:], 0, db)
+	}
+	var proof [][]byte
+	for _, key := range db.Keys() {
+		node, _ := db.Get(key)
+		proof = append(proof, node)
+	}
+	return hashes, leaves, proof, partial
+}
+
+// RequestAccountRange constructs a getAccountRange method associated with a
+// particular peer in the download tester, serving the accounts of the peer state.
+func (dlp *downloadTesterPeer) RequestAccountRange(root common.Hash, origin common.Hash, bytes uint64) error {
+	dlp.waitDelay()
+
+	dlp.dl.lock.RLock()
+	defer dlp.dl.lock.RUnlock()
+
+	tr, err := trie.New(root, trie.NewDatabase(dlp.dl.peerDb))
+	if err != nil {
+		go dlp.dl.downloader.DeliverAccountRange(dlp.id, nil, nil, nil)
+		return nil
+	}
+	hashes, accounts, proof, _ := dlp.serveRange(tr, origin, dlp.rangeLimit(bytes))
+	go dlp.dl.downloader.DeliverAccountRange(dlp.id, hashes, accounts, proof)
+
+	return nil
+}
+
+// RequestStorageRanges constructs a getStorageRanges method associated with a
+// particular peer in the download tester, serving the storage of the peer state.
+func (dlp *downloadTesterPeer) RequestStorageRanges(root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error {
+	dlp.waitDelay()
+
+	dlp.dl.lock.RLock()
+	defer dlp.dl.lock.RUnlock()
+
+	var (
+		triedb = trie.NewDatabase(dlp.dl.peerDb)
+		hashes [][]common.Hash
+		slots  [][][]byte
+		limit  = dlp.rangeLimit(bytes)
+		size   uint64
+	)
+	tr, err := trie.New(root, triedb)
+	if err != nil {
+		go dlp.dl.downloader.DeliverStorageRanges(dlp.id, nil, nil, nil)
+		return nil
+	}
+	for i, account := range accounts {
+		if size >= limit {
+			break
+		}
+		var start common.Hash
+		if i == 0 {
+			start = origin
+		}
+		var data state.Account
+		if err := rlp.DecodeBytes(tr.Get(account[:]), &data); err != nil {
+			break
+		}
+		storage, err := trie.New(data.Root, triedb)
+		if err != nil {
+			break
+		}
+		keys, values, proof, partial := dlp.serveRange(storage, start, limit-size)
+		hashes, slots = append(hashes, keys), append(slots, values)
+		if partial || start != (common.Hash{}) {
+			go dlp.dl.downloader.DeliverStorageRanges(dlp.id, hashes, slots, proof)
+			return nil
+		}
+		for _, value := range values {
+			size += uint64(common.HashLength + len(value))
+		}
+	}
+	go dlp.dl.downloader.DeliverStorageRanges(dlp.id, hashes, slots, nil)
+
+	return nil
+}
+
+// RequestByteCodes constructs a getByteCodes method associated with a particular
+// peer in the download tester, serving the contract codes of the peer state.
+func (dlp *downloadTesterPeer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	dlp.waitDelay()
+
+	dlp.dl.lock.RLock()
+	defer dlp.dl.lock.RUnlock()
+
+	var codes [][]byte
+	for _, hash := range hashes {
+		if code, err := dlp.dl.peerDb.Get(hash[:]); err == nil {
+			codes = append(codes, code)
+		}
+	}
+	go dlp.dl.downloader.DeliverByteCodes(dlp.id, codes)
+
+	return nil
+}
+
 // assertOwnChain checks if the local chain contains the correct number of items
 // of the various chain components.
 func assertOwnChain(t *testing.T, tester *downloadTester, length int) {
@@ -666,6 +808,9 @@ func TestCanonicalSynchronisation64Full(t *testing.T)  { testCanonicalSynchronis
 func TestCanonicalSynchronisation64Fast(t *testing.T)  { testCanonicalSynchronisation(t, 64, FastSync) }
 func TestCanonicalSynchronisation64Light(t *testing.T) { testCanonicalSynchronisation(t, 64, LightSync) }
 
+func TestCanonicalSynchronisation63Snap(t *testing.T) { testCanonicalSynchronisation(t, 63, SnapSync) }
+func TestCanonicalSynchronisation64Snap(t *testing.T) { testCanonicalSynchronisation(t, 64, SnapSync) }
+
 func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
 
@@ -779,6 +924,7 @@ func TestForkedSync63Fast(t *testing.T)  { testForkedSync(t, 63, FastSync) }
 func TestForkedSync64Full(t *testing.T)  { testForkedSync(t, 64, FullSync) }
 func TestForkedSync64Fast(t *testing.T)  { testForkedSync(t, 64, FastSync) }
 func TestForkedSync64Light(t *testing.T) { testForkedSync(t, 64, LightSync) }
+func TestForkedSync64Snap(t *testing.T)  { testForkedSync(t, 64, SnapSync) }
 
 func testForkedSync(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
@@ -955,6 +1101,7 @@ func TestCancel63Fast(t *testing.T)  { testCancel(t, 63, FastSync) }
 func TestCancel64Full(t *testing.T)  { testCancel(t, 64, FullSync) }
 func TestCancel64Fast(t *testing.T)  { testCancel(t, 64, FastSync) }
 func TestCancel64Light(t *testing.T) { testCancel(t, 64, LightSync) }
+func TestCancel64Snap(t *testing.T)  { testCancel(t, 64, SnapSync) }
 
 func testCancel(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
@@ -1026,6 +1173,7 @@ func TestMultiProtoSynchronisation63Fast(t *testing.T)  { testMultiProtoSync(t,
 func TestMultiProtoSynchronisation64Full(t *testing.T)  { testMultiProtoSync(t, 64, FullSync) }
 func TestMultiProtoSynchronisation64Fast(t *testing.T)  { testMultiProtoSync(t, 64, FastSync) }
 func TestMultiProtoSynchronisation64Light(t *testing.T) { testMultiProtoSync(t, 64, LightSync) }
+func TestMultiProtoSynchronisation64Snap(t *testing.T)  { testMultiProtoSync(t, 64, SnapSync) }
 
 func testMultiProtoSync(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
@@ -40,4 +40,7 @@ var (
 
 	stateInMeter   = metrics.NewRegisteredMeter("eth/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("eth/downloader/states/drop", nil)
+
+	rangeInMeter   = metrics.NewRegisteredMeter("eth/downloader/ranges/in", nil)
+	rangeDropMeter = metrics.NewRegisteredMeter("eth/downloader/ranges/drop", nil)
 )
@@ -34,15 +34,13 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 )
 
-const (
-	maxLackingHashes  = 4096 // Maximum number of entries allowed on the list or lacking items
-	measurementImpact = 0.1  // The impact a single measurement has on a peer's final throughput value.
-)
+const maxLackingHashes = 4096 // Maximum number of entries allowed on the list or lacking items
 
 var (
 	errAlreadyFetching   = errors.New("already fetching blocks from peer")
 	errAlreadyRegistered = errors.New("peer is already registered")
 	errNotRegistered     = errors.New("peer is not registered")
+	errNoRanges          = errors.New("peer does not serve state ranges")
 )
 
 // peerConnection represents an active peer from which hashes and blocks are retrieved.
@@ -53,11 +51,13 @@ type peerConnection struct {
 	blockIdle   int32 // Current block activity state of the peer (idle = 0, active = 1)
 	receiptIdle int32 // Current receipt activity state of the peer (idle = 0, active = 1)
 	stateIdle   int32 // Current node data activity state of the peer (idle = 0, active = 1)
+	rangeIdle   int32 // Current state range activity state of the peer (idle = 0, active = 1)
 
 	headerThroughput  float64 // Number of headers measured to be retrievable per second
 	blockThroughput   float64 // Number of blocks (bodies) measured to be retrievable per second
 	receiptThroughput float64 // Number of receipts measured to be retrievable per second
 	stateThroughput   float64 // Number of node data pieces measured to be retrievable per second
+	rangeThroughput   float64 // Number of state range items measured to be retrievable per second
 
 	rtt time.Duration // Request round trip time to track responsiveness (QoS)
 
@@ -65,10 +65,12 @@ type peerConnection struct {
 	blockStarted   time.Time // Time instance when the last block (body) fetch was started
 	receiptStarted time.Time // Time instance when the last receipt fetch was started
 	stateStarted   time.Time // Time instance when the last node data fetch was started
+	rangeStarted   time.Time // Time instance when the last state range fetch was started
 
 	lacking map[common.Hash]struct{} // Set of hashes not to request (didn't have previously)
 
-	peer Peer
+	peer   Peer
+	tuning *tuning // Downloader configuration to weigh the throughput measurements with
 
 	version int        // Eth protocol version number to switch strategies
 	log     log.Logger // Contextual logger to add extra infos to peer logs
@@ -90,6 +92,16 @@ type Peer interface {
 	RequestNodeData([]common.Hash) error
 }
 
+// RangePeer encapsulates the methods required to retrieve the state of a remote
+// full peer as ranges of accounts and storage slots, through the snap protocol
+// run alongside the eth one.
+type RangePeer interface {
+	ServesRanges() bool
+	RequestAccountRange(root common.Hash, origin common.Hash, bytes uint64) error
+	RequestStorageRanges(root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error
+	RequestByteCodes(hashes []common.Hash, bytes uint64) error
+}
+
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
 type lightPeerWrapper struct {
 	peer LightPeer
@@ -113,12 +125,13 @@ func (w *lightPeerWrapper) RequestNodeData([]common.Hash) error {
 }
 
 // newPeerConnection creates a new downloader peer.
-func newPeerConnection(id string, version int, peer Peer, logger log.Logger) *peerConnection {
+func newPeerConnection(id string, version int, peer Peer, tuning *tuning, logger log.Logger) *peerConnection {
 	return &peerConnection{
 		id:      id,
 		lacking: make(map[common.Hash]struct{}),
 
-		peer: peer,
+		peer:   peer,
+		tuning: tuning,
 
 		version: version,
 		log:     logger,
@@ -134,11 +147,13 @@ func (p *peerConnection) Reset() {
 	atomic.StoreInt32(&p.blockIdle, 0)
 	atomic.StoreInt32(&p.receiptIdle, 0)
 	atomic.StoreInt32(&p.stateIdle, 0)
+	atomic.StoreInt32(&p.rangeIdle, 0)
 
 	p.headerThroughput = 0
 	p.blockThroughput = 0
 	p.receiptThroughput = 0
 	p.stateThroughput = 0
+	p.rangeThroughput = 0
 
 	p.lacking = make(map[common.Hash]struct{})
 }
@@ -222,6 +237,55 @@ func (p *peerConnection) FetchNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// FetchAccountRange sends an account range retrieval request to the remote peer.
+func (p *peerConnection) FetchAccountRange(root common.Hash, origin common.Hash, bytes uint64) error {
+	peer, err := p.startRangeFetch()
+	if err != nil {
+		return err
+	}
+	go peer.RequestAccountRange(root, origin, bytes)
+
+	return nil
+}
+
+// FetchStorageRanges sends a storage range retrieval request to the remote peer.
+func (p *peerConnection) FetchStorageRanges(root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error {
+	peer, err := p.startRangeFetch()
+	if err != nil {
+		return err
+	}
+	go peer.RequestStorageRanges(root, accounts, origin, bytes)
+
+	return nil
+}
+
+// FetchByteCodes sends a contract code retrieval request to the remote peer.
+func (p *peerConnection) FetchByteCodes(hashes []common.Hash, bytes uint64) error {
+	peer, err := p.startRangeFetch()
+	if err != nil {
+		return err
+	}
+	go peer.RequestByteCodes(hashes, bytes)
+
+	return nil
+}
+
+// startRangeFetch marks the peer as busy retrieving state ranges.
+func (p *peerConnection) startRangeFetch() (RangePeer, error) {
+	// Make sure the peer (still) runs the snap protocol
+	peer, ok := p.peer.(RangePeer)
+	if !ok || !peer.ServesRanges() {
+		return nil, errNoRanges
+	}
+	// Short circuit if the peer is already fetching
+	if !atomic.CompareAndSwapInt32(&p.rangeIdle, 0, 1) {
+		return nil, errAlreadyFetching
+	}
+	p.rangeStarted = time.Now()
+
+	return peer, nil
+}
+
 // SetHeadersIdle sets the peer to idle, allowing it to execute new header retrieval
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
@@ -257,6 +321,13 @@ func (p *peerConnection) SetNodeDataIdle(delivered int) {
 	p.setIdle(p.stateStarted, delivered, &p.stateThroughput, &p.stateIdle)
 }
 
+// SetRangesIdle sets the peer to idle, allowing it to execute new state range
+// retrieval requests. Its estimated state range retrieval throughput is updated
+// with that measured just now.
+func (p *peerConnection) SetRangesIdle(delivered int) {
+	p.setIdle(p.rangeStarted, delivered, &p.rangeThroughput, &p.rangeIdle)
+}
+
 // setIdle sets the peer to idle, allowing it to execute new retrieval requests.
 // Its estimated retrieval throughput is updated with that measured just now.
 func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *float64, idle *int32) {
@@ -274,9 +345,10 @@ func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *f
 	// Otherwise update the throughput with a new measurement
 	elapsed := time.Since(started) + 1 // +1 (ns) to ensure non-zero divisor
 	measured := float64(delivered) / (float64(elapsed) / float64(time.Second))
+	impact := p.tuning.get().ThroughputImpact
 
-	*throughput = (1-measurementImpact)*(*throughput) + measurementImpact*measured
-	p.rtt = time.Duration((1-measurementImpact)*float64(p.rtt) + measurementImpact*float64(elapsed))
+	*throughput = (1-impact)*(*throughput) + impact*measured
+	p.rtt = time.Duration((1-impact)*float64(p.rtt) + impact*float64(elapsed))
 
 	p.log.Trace("Peer throughput measurements updated",
 		"hps", p.headerThroughput, "bps", p.blockThroughput,
@@ -350,15 +422,17 @@ func (p *peerConnection) Lacks(hash common.Hash) bool {
 // download procedure.
 type peerSet struct {
 	peers        map[string]*peerConnection
+	tuning       *tuning // Downloader configuration to select the peers with
 	newPeerFeed  event.Feed
 	peerDropFeed event.Feed
 	lock         sync.RWMutex
 }
 
 // newPeerSet creates a new peer set top track the active download sources.
-func newPeerSet() *peerSet {
+func newPeerSet(tuning *tuning) *peerSet {
 	return &peerSet{
-		peers: make(map[string]*peerConnection),
+		peers:  make(map[string]*peerConnection),
+		tuning: tuning,
 	}
 }
 
@@ -522,9 +596,26 @@ func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
 	return ps.idlePeers(63, 64, idle, throughput)
 }
 
+// RangeIdlePeers retrieves a flat list of all the currently state-range-idle
+// peers within the active peer set capable of serving state ranges, ordered by
+// their reputation.
+func (ps *peerSet) RangeIdlePeers() ([]*peerConnection, int) {
+	idle := func(p *peerConnection) bool {
+		peer, ok := p.peer.(RangePeer)
+		return ok && peer.ServesRanges() && atomic.LoadInt32(&p.rangeIdle) == 0
+	}
+	throughput := func(p *peerConnection) float64 {
+		p.lock.RLock()
+		defer p.lock.RUnlock()
+		return p.rangeThroughput
+	}
+	return ps.idlePeers(63, 64, idle, throughput)
+}
+
 // idlePeers retrieves a flat list of all currently idle peers satisfying the
 // protocol version constraints, using the provided function to check idleness.
-// The resulting set of peers are sorted by their measure throughput.
+// The resulting set of peers are sorted by their measure throughput, omitting the
+// ones falling below the configured fraction of the best peer's throughput.
 func (ps *peerSet) idlePeers(minProtocol, maxProtocol int, idleCheck func(*peerConnection) bool, throughput func(*peerConnection) float64) ([]*peerConnection, int) {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
@@ -545,6 +636,12 @@ func (ps *peerSet) idlePeers(minProtocol, maxProtocol int, idleCheck func(*peerC
 			}
 		}
 	}
+	if min := ps.tuning.get().MinThroughput; min > 0 && len(idle) > 0 {
+		cutoff := min * throughput(idle[0])
+		for len(idle) > 1 && throughput(idle[len(idle)-1]) < cutoff {
+			idle, total = idle[:len(idle)-1], total-1
+		}
+	}
 	return idle, total
 }
 
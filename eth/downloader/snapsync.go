@@ -0,0 +1,606 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// emptyCode is the known hash of the empty EVM bytecode.
+var emptyCode = crypto.Keccak256Hash(nil)
+
+// trieCommitThreshold is the number of leaves inserted into a trie being rebuilt
+// from ranges, after which its nodes are flushed to disk to bound memory use.
+const trieCommitThreshold = 65536
+
+// rangeReq represents a state range retrieval request sent to a single peer. It
+// carries either an account range, a batch of storage ranges or a batch of codes.
+type rangeReq struct {
+	account *accountTask   // Account range task to fetch the next range of
+	storage []*storageTask // Storage tasks to fetch the next ranges of
+	codes   []common.Hash  // Hashes of the contract codes to fetch
+	timeout time.Duration  // Maximum round trip time for this to complete
+	timer   *time.Timer    // Timer to fire when the RTT timeout expires
+	peer    *peerConnection
+	// Response data of the peer (nil for timeouts)
+	response dataPack
+	dropped  bool // Flag whether the peer dropped off early
+}
+
+// timedOut returns if this request timed out.
+func (req *rangeReq) timedOut() bool {
+	return req.response == nil
+}
+
+// accountTask is a section of the account hash space to retrieve the accounts
+// of, range by range.
+type accountTask struct {
+	next    common.Hash // Hash of the first account not yet retrieved
+	last    common.Hash // Hash of the last account covered by the task
+	pending bool        // Whether a range request is in flight
+	done    bool        // Whether all accounts of the section were retrieved
+}
+
+// storageTask is the storage trie of an account to retrieve the slots of.
+type storageTask struct {
+	account common.Hash  // Hash of the account owning the storage
+	root    common.Hash  // Root of the storage trie to rebuild
+	next    common.Hash  // Hash of the first slot not yet retrieved
+	builder *trieBuilder // Storage trie being rebuilt from the retrieved ranges
+	pending bool         // Whether a range request is in flight
+	done    bool         // Whether all slots were retrieved
+}
+
+// trieBuilder rebuilds a trie from its leaves, periodically flushing its nodes
+// into the database. The nodes of the intermediate tries left behind are not
+// referenced by the final trie and are dropped by pruning.
+type trieBuilder struct {
+	triedb *trie.Database
+	trie   *trie.Trie
+	leaves int // Number of leaves inserted since the last flush
+}
+
+// newTrieBuilder creates a builder of a trie, starting out empty.
+func newTrieBuilder(triedb *trie.Database) *trieBuilder {
+	tr, _ := trie.New(common.Hash{}, triedb)
+	return &trieBuilder{triedb: triedb, trie: tr}
+}
+
+// update inserts a leaf into the trie, flushing it if enough leaves accumulated.
+func (b *trieBuilder) update(key, value []byte) error {
+	if err := b.trie.TryUpdate(key, value); err != nil {
+		return err
+	}
+	if b.leaves++; b.leaves >= trieCommitThreshold {
+		if _, err := b.commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit flushes the trie into the database and returns its root hash.
+func (b *trieBuilder) commit() (common.Hash, error) {
+	root, err := b.trie.Commit(nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.triedb.Commit(root, false); err != nil {
+		return common.Hash{}, err
+	}
+	if b.trie, err = trie.New(root, b.triedb); err != nil {
+		return common.Hash{}, err
+	}
+	b.leaves = 0
+	return root, nil
+}
+
+// rangeSync fills the state of a state sync from the ranges of its accounts and
+// storage slots, rebuilding the tries locally and writing the flat snapshot of
+// the state alongside. Any part of the state the ranges fail to cover is left
+// to the trie node sync following it.
+type rangeSync struct {
+	s      *stateSync
+	db     ethdb.Database
+	triedb *trie.Database
+
+	accountTasks []*accountTask       // Sections of the account hash space to retrieve
+	accountTrie  *trieBuilder         // Account trie being rebuilt from the ranges
+	storageTasks []*storageTask       // Storage tries queued for retrieval
+	codes        map[common.Hash]bool // Contract codes to retrieve, true if in flight
+	stale        map[string]struct{}  // Peers not serving the state being synced
+	inflight     int                  // Number of requests in flight
+	batch        ethdb.Batch          // Batch of the snapshot entries and codes
+	snapshot     bool                 // Whether the flat snapshot is written
+
+	accounts, slots, bytecodes int // Number of entries retrieved since the last log
+}
+
+// newRangeSync creates a range sync filling the state of the given state sync.
+func newRangeSync(s *stateSync) *rangeSync {
+	r := &rangeSync{
+		s:      s,
+		db:     s.d.stateDB,
+		triedb: trie.NewDatabase(s.d.stateDB),
+		codes:  make(map[common.Hash]bool),
+		stale:  make(map[string]struct{}),
+		batch:  s.d.stateDB.NewBatch(),
+	}
+	r.accountTrie = newTrieBuilder(r.triedb)
+
+	// Split the account hash space into equal sections retrieved concurrently
+	ranges := s.d.tuning.get().StateRanges
+	step := 256 / ranges
+	for i := 0; i < ranges; i++ {
+		task := new(accountTask)
+		task.next[0] = byte(i * step)
+		task.last[0] = byte((i+1)*step - 1)
+		for j := 1; j < common.HashLength; j++ {
+			task.last[j] = 0xff
+		}
+		r.accountTasks = append(r.accountTasks, task)
+	}
+	// The snapshot of the previously synced state is superseded, drop it
+	if err := snapshot.Wipe(r.db); err != nil {
+		log.Warn("State snapshot unavailable", "err", err)
+	} else {
+		r.snapshot = true
+	}
+	return r
+}
+
+// run retrieves the state ranges until the whole state is covered. It returns
+// errRangesUnavailable if none of the peers serve the ranges of the state.
+func (r *rangeSync) run() error {
+	newPeer := make(chan *peerConnection, 1024)
+	peerSub := r.s.d.peers.SubscribeNewPeers(newPeer)
+	defer peerSub.Unsubscribe()
+
+	for !r.done() {
+		if err := r.commit(false); err != nil {
+			return err
+		}
+		r.assignTasks()
+		if r.inflight == 0 {
+			// Either all peers are stale or they do not serve ranges at all
+			r.commit(true)
+			return errRangesUnavailable
+		}
+		select {
+		case <-newPeer:
+			// New peer arrived, try to assign it download tasks
+
+		case <-r.s.cancel:
+			return errCancelStateFetch
+
+		case <-r.s.d.cancelCh:
+			return errCancelStateFetch
+
+		case req := <-r.s.deliverRange:
+			r.inflight--
+
+			log.Trace("Received state range response", "peer", req.peer.id, "dropped", req.dropped, "timeout", !req.dropped && req.timedOut())
+			delivered, err := r.process(req)
+			if err != nil {
+				return err
+			}
+			req.peer.SetRangesIdle(delivered)
+		}
+	}
+	// All ranges retrieved, make sure they rebuilt the requested state
+	root, err := r.accountTrie.commit()
+	if err != nil {
+		return err
+	}
+	if root != r.s.root {
+		return fmt.Errorf("state ranges rebuilt root %x, want %x", root, r.s.root)
+	}
+	if r.snapshot {
+		rawdb.WriteSnapshotRoot(r.batch, root)
+	}
+	return r.commit(true)
+}
+
+// done returns whether all the ranges and codes were retrieved.
+func (r *rangeSync) done() bool {
+	for _, task := range r.accountTasks {
+		if !task.done {
+			return false
+		}
+	}
+	return len(r.storageTasks) == 0 && len(r.codes) == 0
+}
+
+// commit flushes the snapshot entries and codes if enough accumulated, or if
+// forced to, and reports the progress.
+func (r *rangeSync) commit(force bool) error {
+	if !force && r.batch.ValueSize() < ethdb.IdealBatchSize {
+		return nil
+	}
+	start := time.Now()
+	if err := r.batch.Write(); err != nil {
+		return fmt.Errorf("DB write error: %v", err)
+	}
+	r.batch.Reset()
+
+	written := r.accounts + r.slots + r.bytecodes
+	if written == 0 {
+		return nil
+	}
+	r.s.d.syncStatsLock.Lock()
+	r.s.d.syncStatsState.processed += uint64(written)
+	processed := r.s.d.syncStatsState.processed
+	r.s.d.syncStatsLock.Unlock()
+
+	log.Info("Imported new state ranges", "accounts", r.accounts, "slots", r.slots, "codes", r.bytecodes, "elapsed", common.PrettyDuration(time.Since(start)), "processed", processed, "storage", len(r.storageTasks), "pending", len(r.codes))
+	r.accounts, r.slots, r.bytecodes = 0, 0, 0
+	return nil
+}
+
+// assignTasks attempts to assign a new request to all idle peers serving the
+// state, preferring codes and storage over new accounts to bound the queues.
+func (r *rangeSync) assignTasks() {
+	peers, _ := r.s.d.peers.RangeIdlePeers()
+	for _, p := range peers {
+		if _, ok := r.stale[p.id]; ok {
+			continue
+		}
+		req := &rangeReq{peer: p, timeout: r.s.d.requestTTL()}
+		if !r.fillTask(req) {
+			return
+		}
+		var err error
+		select {
+		case r.s.d.trackRangeReq <- req:
+			switch {
+			case len(req.codes) > 0:
+				req.peer.log.Trace("Requesting new batch of data", "type", "codes", "count", len(req.codes))
+				err = req.peer.FetchByteCodes(req.codes, rangeResponseSize)
+			case len(req.storage) > 0:
+				req.peer.log.Trace("Requesting new batch of data", "type", "storage", "count", len(req.storage), "origin", req.storage[0].next)
+				accounts := make([]common.Hash, len(req.storage))
+				for i, task := range req.storage {
+					accounts[i] = task.account
+				}
+				err = req.peer.FetchStorageRanges(r.s.root, accounts, req.storage[0].next, rangeResponseSize)
+			default:
+				req.peer.log.Trace("Requesting new batch of data", "type", "accounts", "origin", req.account.next)
+				err = req.peer.FetchAccountRange(r.s.root, req.account.next, rangeResponseSize)
+			}
+		case <-r.s.cancel:
+			return
+		case <-r.s.d.cancelCh:
+			return
+		}
+		// The request is tracked, a failed send is handled as a timeout
+		r.inflight++
+		if err != nil {
+			req.peer.log.Debug("Failed to request state ranges", "err", err)
+		}
+	}
+}
+
+// fillTask fills the given request with the next batch of codes, the next
+// storage ranges or the next account range, in this order of priority. It
+// returns false if there is nothing left to request.
+func (r *rangeSync) fillTask(req *rangeReq) bool {
+	for hash, inflight := range r.codes {
+		if len(req.codes) == MaxStateFetch {
+			break
+		}
+		if !inflight {
+			r.codes[hash] = true
+			req.codes = append(req.codes, hash)
+		}
+	}
+	if len(req.codes) > 0 {
+		return true
+	}
+	// A partially retrieved storage trie is continued on its own
+	for _, task := range r.storageTasks {
+		if task.pending {
+			continue
+		}
+		if task.next != (common.Hash{}) {
+			if len(req.storage) > 0 {
+				continue
+			}
+			task.pending = true
+			req.storage = append(req.storage, task)
+			return true
+		}
+		if len(req.storage) < MaxStorageFetch {
+			task.pending = true
+			req.storage = append(req.storage, task)
+		}
+	}
+	if len(req.storage) > 0 {
+		return true
+	}
+	for _, task := range r.accountTasks {
+		if !task.pending && !task.done {
+			task.pending = true
+			req.account = task
+			return true
+		}
+	}
+	return false
+}
+
+// process handles a state range response, timeout or disconnect, requeueing
+// anything not retrieved. It returns the number of delivered items.
+func (r *rangeSync) process(req *rangeReq) (int, error) {
+	if req.timedOut() {
+		r.revert(req)
+		return 0, nil
+	}
+	var (
+		delivered = req.response.Items()
+		err       error
+	)
+	switch pack := req.response.(type) {
+	case *accountRangePack:
+		err = r.processAccounts(req, pack)
+	case *storageRangesPack:
+		err = r.processStorage(req, pack)
+	case *byteCodesPack:
+		err = r.processCodes(req, pack)
+	}
+	return delivered, err
+}
+
+// revert requeues all the tasks of a request.
+func (r *rangeSync) revert(req *rangeReq) {
+	for _, hash := range req.codes {
+		if _, ok := r.codes[hash]; ok {
+			r.codes[hash] = false
+		}
+	}
+	for _, task := range req.storage {
+		task.pending = false
+	}
+	if req.account != nil {
+		req.account.pending = false
+	}
+}
+
+// markStale flags the peer of a request as not serving the synced state, and
+// requeues the tasks of the request.
+func (r *rangeSync) markStale(req *rangeReq) {
+	log.Debug("Peer not serving state ranges", "peer", req.peer.id, "root", r.s.root)
+	r.stale[req.peer.id] = struct{}{}
+	r.revert(req)
+}
+
+// dropInvalid drops the peer of a request delivering invalid ranges, and
+// requeues the tasks of the request.
+func (r *rangeSync) dropInvalid(req *rangeReq, err error) {
+	log.Warn("Invalid state range, dropping peer", "peer", req.peer.id, "err", err)
+	r.s.d.dropPeer(req.peer.id)
+	r.revert(req)
+}
+
+// proofDatabase collects the nodes of a range proof keyed by their hashes, or
+// returns nil if there is no proof.
+func proofDatabase(proof [][]byte) trie.DatabaseReader {
+	if len(proof) == 0 {
+		return nil
+	}
+	db := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// processAccounts verifies a range of accounts and inserts it into the state,
+// scheduling the retrieval of the storage and codes of the accounts.
+func (r *rangeSync) processAccounts(req *rangeReq, pack *accountRangePack) error {
+	task := req.account
+	if len(pack.hashes) == 0 && len(pack.proof) == 0 {
+		r.markStale(req)
+		return nil
+	}
+	keys := make([][]byte, len(pack.hashes))
+	for i := range pack.hashes {
+		keys[i] = pack.hashes[i][:]
+	}
+	more, err := trie.VerifyRangeProof(r.s.root, task.next[:], keys, pack.accounts, proofDatabase(pack.proof))
+	if err != nil {
+		r.dropInvalid(req, err)
+		return nil
+	}
+	task.pending = false
+
+	for i, hash := range pack.hashes {
+		// Accounts past the section are retrieved by the following task
+		if bytes.Compare(hash[:], task.last[:]) > 0 {
+			task.done = true
+			break
+		}
+		if err := r.accountTrie.update(hash[:], pack.accounts[i]); err != nil {
+			return err
+		}
+		if r.snapshot {
+			rawdb.WriteAccountSnapshot(r.batch, hash, pack.accounts[i])
+		}
+		r.accounts++
+
+		var account state.Account
+		if err := rlp.DecodeBytes(pack.accounts[i], &account); err != nil {
+			return fmt.Errorf("invalid account %x: %v", hash, err)
+		}
+		if account.Root != types.EmptyRootHash {
+			if ok, _ := r.db.Has(account.Root[:]); ok {
+				if err := r.copyStorage(hash, account.Root); err != nil {
+					return err
+				}
+			} else {
+				r.storageTasks = append(r.storageTasks, &storageTask{account: hash, root: account.Root})
+			}
+		}
+		if code := common.BytesToHash(account.CodeHash); code != emptyCode {
+			if _, ok := r.codes[code]; !ok {
+				if ok, _ := r.db.Has(code[:]); !ok {
+					r.codes[code] = false
+				}
+			}
+		}
+	}
+	if !task.done {
+		if !more || len(pack.hashes) == 0 {
+			task.done = true
+		} else if task.next = incHash(pack.hashes[len(pack.hashes)-1]); task.next == (common.Hash{}) {
+			task.done = true
+		}
+	}
+	return nil
+}
+
+// copyStorage writes the snapshot entries of a storage trie already present in
+// the database.
+func (r *rangeSync) copyStorage(account common.Hash, root common.Hash) error {
+	if !r.snapshot {
+		return nil
+	}
+	tr, err := trie.New(root, r.triedb)
+	if err != nil {
+		return err
+	}
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		rawdb.WriteStorageSnapshot(r.batch, account, common.BytesToHash(it.Key), it.Value)
+		r.slots++
+	}
+	return it.Err
+}
+
+// processStorage verifies a batch of storage ranges and inserts them into the
+// storage tries of their accounts. Only the last range may be partial.
+func (r *rangeSync) processStorage(req *rangeReq, pack *storageRangesPack) error {
+	if len(pack.slots) == 0 && len(pack.proof) == 0 {
+		r.markStale(req)
+		return nil
+	}
+	if len(pack.slots) > len(req.storage) || len(pack.hashes) != len(pack.slots) {
+		r.dropInvalid(req, fmt.Errorf("unexpected storage ranges: have %d, want %d", len(pack.slots), len(req.storage)))
+		return nil
+	}
+	for i, slots := range pack.slots {
+		task := req.storage[i]
+
+		var proof trie.DatabaseReader
+		if i == len(pack.slots)-1 {
+			proof = proofDatabase(pack.proof)
+		}
+		keys := make([][]byte, len(pack.hashes[i]))
+		for j := range pack.hashes[i] {
+			keys[j] = pack.hashes[i][j][:]
+		}
+		more, err := trie.VerifyRangeProof(task.root, task.next[:], keys, slots, proof)
+		if err != nil {
+			r.dropInvalid(&rangeReq{peer: req.peer, storage: req.storage[i:]}, err)
+			break
+		}
+		task.pending = false
+
+		if task.builder == nil {
+			task.builder = newTrieBuilder(r.triedb)
+		}
+		for j, key := range keys {
+			if err := task.builder.update(key, slots[j]); err != nil {
+				return err
+			}
+			if r.snapshot {
+				rawdb.WriteStorageSnapshot(r.batch, task.account, pack.hashes[i][j], slots[j])
+			}
+			r.slots++
+		}
+		if more && len(keys) > 0 {
+			task.next = incHash(pack.hashes[i][len(keys)-1])
+			continue
+		}
+		root, err := task.builder.commit()
+		if err != nil {
+			return err
+		}
+		if root != task.root {
+			return fmt.Errorf("storage ranges of %x rebuilt root %x, want %x", task.account, root, task.root)
+		}
+		task.builder, task.done = nil, true
+	}
+	// Requeue the storage not delivered and drop the completed tasks
+	for _, task := range req.storage[len(pack.slots):] {
+		task.pending = false
+	}
+	tasks := r.storageTasks[:0]
+	for _, task := range r.storageTasks {
+		if !task.done {
+			tasks = append(tasks, task)
+		}
+	}
+	for i := len(tasks); i < len(r.storageTasks); i++ {
+		r.storageTasks[i] = nil
+	}
+	r.storageTasks = tasks
+	return nil
+}
+
+// processCodes writes the delivered contract codes, requeueing the missing ones.
+func (r *rangeSync) processCodes(req *rangeReq, pack *byteCodesPack) error {
+	if len(pack.codes) == 0 {
+		r.markStale(req)
+		return nil
+	}
+	for _, code := range pack.codes {
+		hash := crypto.Keccak256Hash(code)
+		if inflight, ok := r.codes[hash]; !ok || !inflight {
+			continue
+		}
+		if err := r.batch.Put(hash[:], code); err != nil {
+			return err
+		}
+		delete(r.codes, hash)
+		r.bytecodes++
+	}
+	r.revert(req)
+	return nil
+}
+
+// incHash returns the hash following the given one, wrapping around to zero.
+func incHash(h common.Hash) common.Hash {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// makeSnapState creates a state with accounts holding storage and code, some of
+// the codes shared between accounts and one account holding a large storage.
+func makeSnapState(db ethdb.Database, accounts int) common.Hash {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	for i := 0; i < accounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		statedb.SetBalance(addr, big.NewInt(int64(i)))
+		statedb.SetNonce(addr, uint64(i))
+		if i%3 == 0 {
+			statedb.SetCode(addr, []byte{0x60, byte(i % 7)})
+		}
+		if i%4 == 0 {
+			slots := i%10 + 1
+			if i == 0 {
+				slots = 2000
+			}
+			for j := 0; j < slots; j++ {
+				statedb.SetState(addr, common.BigToHash(big.NewInt(int64(j+1))), common.BigToHash(big.NewInt(int64(i*j+1))))
+			}
+		}
+	}
+	root, _ := statedb.Commit(false)
+	statedb.Database().TrieDB().Commit(root, false)
+	return root
+}
+
+// Tests that the state is retrieved in ranges from peers running the snap
+// protocol, rebuilding all the tries and the snapshot of the state, whether the
+// ranges are served whole or split into many partial responses. Peers without
+// snap are retrieved the trie nodes from instead.
+func TestSnapSyncState63(t *testing.T)        { testSnapSyncState(t, 63, 0) }
+func TestSnapSyncState64(t *testing.T)        { testSnapSyncState(t, 64, 0) }
+func TestSnapSyncState64Partial(t *testing.T) { testSnapSyncState(t, 64, 1024) }
+
+func testSnapSyncState(t *testing.T, protocol int, limit uint64) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	root := makeSnapState(tester.peerDb, 500)
+	tester.peerRangeLimit = limit
+
+	genesis := tester.genesis
+	tester.newPeer("peer", protocol, []common.Hash{genesis.Hash()}, map[common.Hash]*types.Header{genesis.Hash(): genesis.Header()}, map[common.Hash]*types.Block{genesis.Hash(): genesis}, nil)
+
+	// Mark a sync active for the downloader to accept the state deliveries
+	tester.downloader.mode = SnapSync
+	tester.downloader.cancelCh = make(chan struct{})
+
+	if err := tester.downloader.syncState(root).Wait(); err != nil {
+		t.Fatalf("failed to sync state: %v", err)
+	}
+	// Make sure the tries were rebuilt in their entirety
+	statedb, err := state.New(root, state.NewDatabase(tester.stateDb))
+	if err != nil {
+		t.Fatalf("failed to open synced state: %v", err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("synced state incomplete: %v", it.Error)
+	}
+	// Make sure the snapshot was written along when retrieved in ranges
+	snap := snapshot.Load(tester.stateDb)
+	if !tester.downloader.peers.Peer("peer").peer.(RangePeer).ServesRanges() {
+		if snap != nil {
+			t.Fatalf("snapshot created from trie nodes")
+		}
+		return
+	}
+	if snap == nil || snap.Root() != root {
+		t.Fatalf("snapshot missing")
+	}
+	accounts := snap.AccountIterator(common.Hash{})
+	defer accounts.Release()
+
+	count := 0
+	for ; accounts.Next(); count++ {
+		hash := accounts.Hash()
+
+		var slots int
+		storage := snap.StorageIterator(hash, common.Hash{})
+		for storage.Next() {
+			slots++
+		}
+		storage.Release()
+
+		var account state.Account
+		if err := rlp.DecodeBytes(accounts.Entry(), &account); err != nil {
+			t.Fatalf("invalid account %x: %v", hash, err)
+		}
+		if (slots == 0) != (account.Root == types.EmptyRootHash) {
+			t.Errorf("account %x: snapshot storage mismatch, slots %d, root %x", hash, slots, account.Root)
+		}
+	}
+	if count != 500 {
+		t.Errorf("snapshot account count mismatch: have %d, want %d", count, 500)
+	}
+	var (
+		account = crypto.Keccak256Hash(common.BigToAddress(big.NewInt(1)).Bytes())
+		key     = crypto.Keccak256Hash(common.BigToHash(big.NewInt(2)).Bytes())
+	)
+	if slot := snap.Storage(account, key); !bytes.Equal(slot, []byte{0x01}) {
+		t.Errorf("snapshot storage slot mismatch: have %x, want %x", slot, []byte{0x01})
+	}
+}
@@ -80,6 +80,8 @@ func (d *Downloader) stateFetcher() {
 			}
 		case <-d.stateCh:
 			// Ignore state responses while no sync is running.
+		case <-d.rangeCh:
+			// Ignore state range responses while no sync is running.
 		case <-d.quitCh:
 			return
 		}
@@ -93,6 +95,10 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 		active   = make(map[string]*stateReq) // Currently in-flight requests
 		finished []*stateReq                  // Completed or failed requests
 		timeout  = make(chan *stateReq)       // Timed out active requests
+
+		activeRanges   = make(map[string]*rangeReq) // Currently in-flight state range requests
+		finishedRanges []*rangeReq                  // Completed or failed state range requests
+		rangeTimeout   = make(chan *rangeReq)       // Timed out active state range requests
 	)
 	defer func() {
 		// Cancel active request timers on exit. Also set peers to idle so they're
@@ -101,6 +107,10 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			req.timer.Stop()
 			req.peer.SetNodeDataIdle(len(req.items))
 		}
+		for _, req := range activeRanges {
+			req.timer.Stop()
+			req.peer.SetRangesIdle(0)
+		}
 	}()
 	// Run the state sync.
 	go s.run()
@@ -121,6 +131,14 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			deliverReq = finished[0]
 			deliverReqCh = s.deliver
 		}
+		var (
+			deliverRange   *rangeReq
+			deliverRangeCh chan *rangeReq
+		)
+		if len(finishedRanges) > 0 {
+			deliverRange = finishedRanges[0]
+			deliverRangeCh = s.deliverRange
+		}
 
 		select {
 		// The stateSync lifecycle:
@@ -137,6 +155,11 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			finished[len(finished)-1] = nil
 			finished = finished[:len(finished)-1]
 
+		case deliverRangeCh <- deliverRange:
+			copy(finishedRanges, finishedRanges[1:])
+			finishedRanges[len(finishedRanges)-1] = nil
+			finishedRanges = finishedRanges[:len(finishedRanges)-1]
+
 		// Handle incoming state packs:
 		case pack := <-d.stateCh:
 			// Discard any data not requested (or previously timed out)
@@ -152,19 +175,38 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			finished = append(finished, req)
 			delete(active, pack.PeerId())
 
-			// Handle dropped peer connections:
-		case p := <-peerDrop:
-			// Skip if no request is currently pending
-			req := active[p.id]
+		// Handle incoming state range packs:
+		case pack := <-d.rangeCh:
+			// Discard any data not requested (or previously timed out)
+			req := activeRanges[pack.PeerId()]
 			if req == nil {
+				log.Debug("Unrequested state range", "peer", pack.PeerId(), "len", pack.Items())
 				continue
 			}
 			// Finalize the request and queue up for processing
 			req.timer.Stop()
-			req.dropped = true
+			req.response = pack
 
-			finished = append(finished, req)
-			delete(active, p.id)
+			finishedRanges = append(finishedRanges, req)
+			delete(activeRanges, pack.PeerId())
+
+			// Handle dropped peer connections:
+		case p := <-peerDrop:
+			// Finalize the pending requests of the peer and queue up for processing
+			if req := active[p.id]; req != nil {
+				req.timer.Stop()
+				req.dropped = true
+
+				finished = append(finished, req)
+				delete(active, p.id)
+			}
+			if req := activeRanges[p.id]; req != nil {
+				req.timer.Stop()
+				req.dropped = true
+
+				finishedRanges = append(finishedRanges, req)
+				delete(activeRanges, p.id)
+			}
 
 		// Handle timed-out requests:
 		case req := <-timeout:
@@ -178,6 +220,14 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			finished = append(finished, req)
 			delete(active, req.peer.id)
 
+		case req := <-rangeTimeout:
+			// Ignore stale timeouts, as with node data requests
+			if activeRanges[req.peer.id] != req {
+				continue
+			}
+			finishedRanges = append(finishedRanges, req)
+			delete(activeRanges, req.peer.id)
+
 		// Track outgoing state requests:
 		case req := <-d.trackStateReq:
 			// If an active request already exists for this peer, we have a problem. In
@@ -205,6 +255,26 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 				}
 			})
 			active[req.peer.id] = req
+
+		// Track outgoing state range requests:
+		case req := <-d.trackRangeReq:
+			// Range requests are only assigned to idle peers, but the same reconnect
+			// race as with node data requests may occur
+			if old := activeRanges[req.peer.id]; old != nil {
+				log.Warn("Busy peer assigned new state range fetch", "peer", old.peer.id)
+
+				old.timer.Stop()
+				old.dropped = true
+
+				finishedRanges = append(finishedRanges, old)
+			}
+			req.timer = time.AfterFunc(req.timeout, func() {
+				select {
+				case rangeTimeout <- req:
+				case <-s.done:
+				}
+			})
+			activeRanges[req.peer.id] = req
 		}
 	}
 }
@@ -214,6 +284,8 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 type stateSync struct {
 	d *Downloader // Downloader instance to access and manage current peerset
 
+	root   common.Hash                // Root of the state to synchronise
+	ranges bool                       // Whether to fill the state from ranges first
 	sched  *trie.Sync                 // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
 	tasks  map[common.Hash]*stateTask // Set of tasks currently queued for retrieval
@@ -221,11 +293,12 @@ type stateSync struct {
 	numUncommitted   int
 	bytesUncommitted int
 
-	deliver    chan *stateReq // Delivery channel multiplexing peer responses
-	cancel     chan struct{}  // Channel to signal a termination request
-	cancelOnce sync.Once      // Ensures cancel only ever gets called once
-	done       chan struct{}  // Channel to signal termination completion
-	err        error          // Any error hit during sync (set before completion)
+	deliver      chan *stateReq // Delivery channel multiplexing peer responses
+	deliverRange chan *rangeReq // Delivery channel multiplexing peer state range responses
+	cancel       chan struct{}  // Channel to signal a termination request
+	cancelOnce   sync.Once      // Ensures cancel only ever gets called once
+	done         chan struct{}  // Channel to signal termination completion
+	err          error          // Any error hit during sync (set before completion)
 }
 
 // stateTask represents a single trie node download task, containing a set of
@@ -238,13 +311,16 @@ type stateTask struct {
 // yet start the sync. The user needs to call run to initiate.
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	return &stateSync{
-		d:       d,
-		sched:   state.NewStateSync(root, d.stateDB),
-		keccak:  sha3.NewKeccak256(),
-		tasks:   make(map[common.Hash]*stateTask),
-		deliver: make(chan *stateReq),
-		cancel:  make(chan struct{}),
-		done:    make(chan struct{}),
+		d:            d,
+		root:         root,
+		ranges:       d.mode == SnapSync,
+		sched:        state.NewStateSync(root, d.stateDB),
+		keccak:       sha3.NewKeccak256(),
+		tasks:        make(map[common.Hash]*stateTask),
+		deliver:      make(chan *stateReq),
+		deliverRange: make(chan *rangeReq),
+		cancel:       make(chan struct{}),
+		done:         make(chan struct{}),
 	}
 }
 
@@ -286,6 +362,18 @@ func (s *stateSync) loop() (err error) {
 		}
 	}()
 
+	// Fill the state from ranges if requested, leaving the trie sync to heal any
+	// missing parts
+	if s.ranges {
+		switch err := newRangeSync(s).run(); err {
+		case nil:
+			s.sched = state.NewStateSync(s.root, s.d.stateDB)
+		case errRangesUnavailable:
+			log.Warn("State ranges unavailable, retrieving trie nodes", "root", s.root)
+		default:
+			return err
+		}
+	}
 	// Keep assigning new tasks until the sync completes or aborts
 	for s.sched.Pending() > 0 {
 		if err = s.commit(false); err != nil {
@@ -28,6 +28,7 @@ import (
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -233,6 +234,64 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// ReorgEvent is the notification sent to reorg subscribers when the canonical
+// chain is reorganised.
+type ReorgEvent struct {
+	OldHead   common.Hash    `json:"oldHead"`
+	OldNumber hexutil.Uint64 `json:"oldNumber"`
+	NewHead   common.Hash    `json:"newHead"`
+	NewNumber hexutil.Uint64 `json:"newNumber"`
+	Common    common.Hash    `json:"commonAncestor"`
+	Depth     hexutil.Uint64 `json:"depth"`
+	Dropped   []common.Hash  `json:"dropped"`
+	Added     []common.Hash  `json:"added"`
+}
+
+// newReorgEvent converts a chain reorg event into its RPC representation.
+func newReorgEvent(ev core.ChainReorgEvent) *ReorgEvent {
+	return &ReorgEvent{
+		OldHead:   ev.OldHead.Hash(),
+		OldNumber: hexutil.Uint64(ev.OldHead.Number.Uint64()),
+		NewHead:   ev.NewHead.Hash(),
+		NewNumber: hexutil.Uint64(ev.NewHead.Number.Uint64()),
+		Common:    ev.Common.Hash(),
+		Depth:     hexutil.Uint64(len(ev.Dropped)),
+		Dropped:   ev.Dropped,
+		Added:     ev.Added,
+	}
+}
+
+// Reorgs send a notification each time the canonical chain is reorganised,
+// detailing the blocks dropped from and added to it.
+func (api *PublicFilterAPI) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ChainReorgEvent)
+		reorgsSub := api.events.SubscribeReorgs(reorgs)
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, newReorgEvent(ev))
+			case <-rpcSub.Err():
+				reorgsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				reorgsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -40,9 +41,14 @@ type Backend interface {
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
+
+	// LogIndexStatus returns the section size and the number of sections of the
+	// address and topic log index available locally, or zero if not maintained.
+	LogIndexStatus() (uint64, uint64)
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -114,7 +120,18 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		logs []*types.Log
 		err  error
 	)
-	size, sections := f.backend.BloomStatus()
+	size, sections := f.backend.LogIndexStatus()
+	if indexed := sections * size; indexed > uint64(f.begin) && f.logIndexable() {
+		if indexed > end {
+			logs, err = f.logIndexLogs(ctx, size, end)
+		} else {
+			logs, err = f.logIndexLogs(ctx, size, indexed-1)
+		}
+		if err != nil {
+			return logs, err
+		}
+	}
+	size, sections = f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
 		if indexed > end {
 			logs, err = f.indexedLogs(ctx, end)
@@ -130,6 +147,123 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	return logs, err
 }
 
+// logIndexable returns whether the filter criteria are selective enough for the
+// address and topic log index to be of any use.
+func (f *Filter) logIndexable() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, topics := range f.topics {
+		if len(topics) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// logIndexLogs returns the logs matching the filter criteria based on the address
+// and topic log index available locally.
+func (f *Filter) logIndexLogs(ctx context.Context, size uint64, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section <= end/size; section++ {
+		head := rawdb.ReadCanonicalHash(f.db, (section+1)*size-1)
+		for _, number := range f.logIndexMatches(section, head) {
+			if number < uint64(f.begin) || number > end {
+				continue
+			}
+			// Retrieve the suggested block and pull any truly matching logs
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+		}
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+	}
+	f.begin = int64(end) + 1
+	return logs, nil
+}
+
+// logIndexMatches returns the ascending numbers of the blocks within the given log
+// index section that contain logs matching all the filter clauses.
+func (f *Filter) logIndexMatches(section uint64, head common.Hash) []uint64 {
+	var entries [][][]byte
+	if len(f.addresses) > 0 {
+		clause := make([][]byte, len(f.addresses))
+		for i, address := range f.addresses {
+			clause[i] = rawdb.LogIndexAddressEntry(address)
+		}
+		entries = append(entries, clause)
+	}
+	for i, topics := range f.topics {
+		if len(topics) == 0 {
+			continue // wildcard position
+		}
+		clause := make([][]byte, len(topics))
+		for j, topic := range topics {
+			clause[j] = rawdb.LogIndexTopicEntry(i, topic)
+		}
+		entries = append(entries, clause)
+	}
+	// Blocks must match any entry within a clause and all the clauses
+	var matches []uint64
+	for i, clause := range entries {
+		var union []uint64
+		for _, entry := range clause {
+			union = mergeNumbers(union, rawdb.ReadLogIndex(f.db, entry, section, head))
+		}
+		if i == 0 {
+			matches = union
+		} else {
+			matches = intersectNumbers(matches, union)
+		}
+		if len(matches) == 0 {
+			return nil
+		}
+	}
+	return matches
+}
+
+// mergeNumbers returns the ascending union of two ascending number lists.
+func mergeNumbers(a, b []uint64) []uint64 {
+	merged := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			merged, a = append(merged, a[0]), a[1:]
+		case a[0] > b[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	return append(append(merged, a...), b...)
+}
+
+// intersectNumbers returns the ascending intersection of two ascending number
+// lists.
+func intersectNumbers(a, b []uint64) []uint64 {
+	var both []uint64
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case a[0] > b[0]:
+			b = b[1:]
+		default:
+			both, a, b = append(both, a[0]), a[1:], b[1:]
+		}
+	}
+	return both
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -39,13 +39,15 @@ import (
 )
 
 type testBackend struct {
-	mux        *event.TypeMux
-	db         ethdb.Database
-	sections   uint64
-	txFeed     *event.Feed
-	rmLogsFeed *event.Feed
-	logsFeed   *event.Feed
-	chainFeed  *event.Feed
+	mux         *event.TypeMux
+	db          ethdb.Database
+	sections    uint64
+	logSections uint64
+	txFeed      *event.Feed
+	rmLogsFeed  *event.Feed
+	logsFeed    *event.Feed
+	chainFeed   *event.Feed
+	reorgFeed   *event.Feed
 }
 
 func (b *testBackend) ChainDb() ethdb.Database {
@@ -112,10 +114,18 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.reorgFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
 
+func (b *testBackend) LogIndexStatus() (uint64, uint64) {
+	return testLogIndexSize, b.logSections
+}
+
 func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	requests := make(chan chan *bloombits.Retrieval)
 
@@ -158,7 +168,8 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed   = new(event.Feed)
+		backend     = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
@@ -204,6 +215,55 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestReorgSubscription tests if a reorg subscription receives the posted chain
+// reorg events and that its RPC representation is derived correctly.
+func TestReorgSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux         = new(event.TypeMux)
+		db          = ethdb.NewMemDatabase()
+		txFeed      = new(event.Feed)
+		rmLogsFeed  = new(event.Feed)
+		logsFeed    = new(event.Feed)
+		chainFeed   = new(event.Feed)
+		reorgFeed   = new(event.Feed)
+		backend     = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
+		api         = NewPublicFilterAPI(backend, false)
+		genesis     = new(core.Genesis).MustCommit(db)
+		oldChain, _ = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {})
+		newChain, _ = core.GenerateChain(params.TestChainConfig, oldChain[0], ethash.NewFaker(), db, 3, func(i int, gen *core.BlockGen) {
+			gen.SetCoinbase(common.Address{0x01})
+		})
+	)
+	ev := core.ChainReorgEvent{
+		OldHead: oldChain[2].Header(),
+		NewHead: newChain[2].Header(),
+		Common:  oldChain[0].Header(),
+		Dropped: []common.Hash{oldChain[1].Hash(), oldChain[2].Hash()},
+		Added:   []common.Hash{newChain[0].Hash(), newChain[1].Hash(), newChain[2].Hash()},
+	}
+	reorgs := make(chan core.ChainReorgEvent)
+	sub := api.events.SubscribeReorgs(reorgs)
+	defer sub.Unsubscribe()
+
+	if n := reorgFeed.Send(ev); n != 1 {
+		t.Fatalf("reorg event subscriber count mismatch: have %d, want 1", n)
+	}
+	select {
+	case have := <-reorgs:
+		if have.NewHead.Hash() != ev.NewHead.Hash() {
+			t.Errorf("new head mismatch: have %x, want %x", have.NewHead.Hash(), ev.NewHead.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reorg event not delivered")
+	}
+	rpcEv := newReorgEvent(ev)
+	if rpcEv.Depth != 2 || rpcEv.OldNumber != 3 || rpcEv.NewNumber != 4 || rpcEv.Common != oldChain[0].Hash() {
+		t.Errorf("reorg notification mismatch: %+v", rpcEv)
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -215,7 +275,8 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -275,7 +336,8 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -324,7 +386,8 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
@@ -354,7 +417,8 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -473,7 +537,8 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
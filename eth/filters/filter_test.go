@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -57,7 +58,8 @@ func BenchmarkFilters(b *testing.B) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr1      = crypto.PubkeyToAddress(key1.PublicKey)
 		addr2      = common.BytesToAddress([]byte("jeff"))
@@ -116,7 +118,8 @@ func TestFilters(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr       = crypto.PubkeyToAddress(key1.PublicKey)
 
@@ -230,3 +233,109 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// testLogIndexSize is the section size of the log index used by the tests.
+const testLogIndexSize = 100
+
+// Tests that log filters are served from the address and topic log index for
+// the indexed sections, and continue seamlessly in the unindexed blocks.
+func TestLogIndexFilters(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		backend = &testBackend{new(event.TypeMux), db, 0, 9, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+		addr1   = common.BytesToAddress([]byte("address1"))
+		addr2   = common.BytesToAddress([]byte("address2"))
+
+		hash1 = common.BytesToHash([]byte("topic1"))
+		hash2 = common.BytesToHash([]byte("topic2"))
+		hash3 = common.BytesToHash([]byte("topic3"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr1, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 1000, func(i int, gen *core.BlockGen) {
+		var logs []*types.Log
+		switch i {
+		case 1:
+			logs = []*types.Log{{Address: addr1, Topics: []common.Hash{hash1}}}
+		case 2:
+			logs = []*types.Log{{Address: addr1, Topics: []common.Hash{hash2}}}
+		case 500:
+			logs = []*types.Log{{Address: addr2, Topics: []common.Hash{hash1, hash3}}}
+		case 501:
+			logs = []*types.Log{{Address: addr2, Topics: []common.Hash{hash3}}}
+		case 998:
+			logs = []*types.Log{{Address: addr1, Topics: []common.Hash{hash1, hash3}}}
+		default:
+			return
+		}
+		for _, log := range logs {
+			log.BlockNumber = uint64(i + 1)
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = logs
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+	})
+	// Import the chain and index the first few sections, omitting the address of
+	// the log in block 3 to detect whether the index is used
+	entries := make(map[uint64]map[string][]uint64)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+
+		section := block.NumberU64() / testLogIndexSize
+		if entries[section] == nil {
+			entries[section] = make(map[string][]uint64)
+		}
+		for _, receipt := range receipts[i] {
+			for _, log := range receipt.Logs {
+				if block.NumberU64() != 3 {
+					entry := string(rawdb.LogIndexAddressEntry(log.Address))
+					entries[section][entry] = append(entries[section][entry], block.NumberU64())
+				}
+				for j, topic := range log.Topics {
+					entry := string(rawdb.LogIndexTopicEntry(j, topic))
+					entries[section][entry] = append(entries[section][entry], block.NumberU64())
+				}
+			}
+		}
+	}
+	for section := uint64(0); section < backend.logSections; section++ {
+		head := rawdb.ReadCanonicalHash(db, (section+1)*testLogIndexSize-1)
+		for entry, numbers := range entries[section] {
+			rawdb.WriteLogIndex(db, []byte(entry), section, head, numbers)
+		}
+	}
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+		want       []uint64
+	}{
+		{0, -1, []common.Address{addr1}, nil, []uint64{2, 999}},
+		{0, -1, nil, [][]common.Hash{{hash1}}, []uint64{2, 501, 999}},
+		{0, -1, nil, [][]common.Hash{{hash1, hash2}}, []uint64{2, 3, 501, 999}},
+		{0, -1, []common.Address{addr1, addr2}, [][]common.Hash{{hash1}}, []uint64{2, 501, 999}},
+		{0, -1, nil, [][]common.Hash{nil, {hash3}}, []uint64{501, 999}},
+		{0, -1, []common.Address{addr2}, [][]common.Hash{{hash3}}, []uint64{502}},
+		{450, 950, nil, [][]common.Hash{{hash1, hash3}}, []uint64{501, 502}},
+		{502, 502, nil, [][]common.Hash{{hash3}}, []uint64{502}},
+		{0, -1, []common.Address{addr2}, [][]common.Hash{{hash2}}, nil},
+		{0, -1, nil, nil, []uint64{2, 3, 501, 502, 999}},
+	}
+	for i, tt := range tests {
+		logs, err := New(backend, tt.begin, tt.end, tt.addresses, tt.topics).Logs(context.Background())
+		if err != nil {
+			t.Errorf("test %d: failed to filter logs: %v", i, err)
+			continue
+		}
+		var have []uint64
+		for _, log := range logs {
+			have = append(have, log.BlockNumber)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: log blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
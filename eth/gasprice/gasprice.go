@@ -18,6 +18,7 @@ package gasprice
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"sort"
 	"sync"
@@ -29,23 +30,27 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-var maxPrice = big.NewInt(500 * params.Shannon)
+// DefaultMaxPrice is the highest gas price the oracle suggests if no other cap
+// is configured.
+var DefaultMaxPrice = big.NewInt(500 * params.Shannon)
 
 type Config struct {
 	Blocks     int
 	Percentile int
 	Default    *big.Int `toml:",omitempty"`
+	MaxPrice   *big.Int `toml:",omitempty"`
 }
 
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
-	backend   ethapi.Backend
-	lastHead  common.Hash
-	lastPrice *big.Int
-	cacheLock sync.RWMutex
-	fetchLock sync.Mutex
+	backend     ethapi.Backend
+	lastHead    common.Hash
+	lastSamples []*big.Int
+	cacheLock   sync.RWMutex
+	fetchLock   sync.Mutex
 
+	defaultPrice, maxPrice           *big.Int
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
 }
@@ -63,27 +68,71 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
+	maxPrice := params.MaxPrice
+	if maxPrice == nil || maxPrice.Sign() <= 0 {
+		maxPrice = DefaultMaxPrice
+	}
 	return &Oracle{
-		backend:     backend,
-		lastPrice:   params.Default,
-		checkBlocks: blocks,
-		maxEmpty:    blocks / 2,
-		maxBlocks:   blocks * 5,
-		percentile:  percent,
+		backend:      backend,
+		defaultPrice: params.Default,
+		maxPrice:     maxPrice,
+		checkBlocks:  blocks,
+		maxEmpty:     blocks / 2,
+		maxBlocks:    blocks * 5,
+		percentile:   percent,
 	}
 }
 
-// SuggestPrice returns the recommended gas price.
+// SuggestPrice returns the recommended gas price, which is the configured
+// percentile of the lowest gas prices paid in recent blocks.
 func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	prices, err := gpo.SuggestPrices(ctx, []int{gpo.percentile})
+	return prices[0], err
+}
+
+// SuggestPrices returns the gas prices at the given percentiles of the lowest
+// gas prices paid in recent blocks, allowing callers to offer multiple pricing
+// tiers. If the recent blocks cannot be retrieved, the prices derived from the
+// previously sampled blocks are returned along with the error.
+func (gpo *Oracle) SuggestPrices(ctx context.Context, percentiles []int) ([]*big.Int, error) {
+	for _, percentile := range percentiles {
+		if percentile < 0 || percentile > 100 {
+			return nil, fmt.Errorf("invalid percentile %d, must be within [0, 100]", percentile)
+		}
+	}
+	samples, err := gpo.samples(ctx)
+
+	prices := make([]*big.Int, len(percentiles))
+	for i, percentile := range percentiles {
+		price := gpo.defaultPrice
+		if len(samples) > 0 {
+			price = samples[(len(samples)-1)*percentile/100]
+		}
+		if price != nil && price.Cmp(gpo.maxPrice) > 0 {
+			price = gpo.maxPrice
+		}
+		if price != nil {
+			price = new(big.Int).Set(price)
+		}
+		prices[i] = price
+	}
+	return prices, err
+}
+
+// samples returns the sorted lowest gas prices paid in the recent blocks. They
+// are only gathered anew if the chain head changed since the last call, and if
+// none of the recent blocks contain priced transactions, the previous samples
+// are retained.
+func (gpo *Oracle) samples(ctx context.Context) ([]*big.Int, error) {
 	gpo.cacheLock.RLock()
 	lastHead := gpo.lastHead
-	lastPrice := gpo.lastPrice
+	lastSamples := gpo.lastSamples
 	gpo.cacheLock.RUnlock()
 
 	head, _ := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
 	if headHash == lastHead {
-		return lastPrice, nil
+		return lastSamples, nil
 	}
 
 	gpo.fetchLock.Lock()
@@ -92,10 +141,10 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	// try checking the cache again, maybe the last fetch fetched what we need
 	gpo.cacheLock.RLock()
 	lastHead = gpo.lastHead
-	lastPrice = gpo.lastPrice
+	lastSamples = gpo.lastSamples
 	gpo.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return lastPrice, nil
+		return lastSamples, nil
 	}
 
 	blockNum := head.Number.Uint64()
@@ -113,7 +162,7 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	for exp > 0 {
 		res := <-ch
 		if res.err != nil {
-			return lastPrice, res.err
+			return lastSamples, res.err
 		}
 		exp--
 		if res.price != nil {
@@ -131,20 +180,17 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 			blockNum--
 		}
 	}
-	price := lastPrice
+	samples := lastSamples
 	if len(blockPrices) > 0 {
 		sort.Sort(bigIntArray(blockPrices))
-		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
-	}
-	if price.Cmp(maxPrice) > 0 {
-		price = new(big.Int).Set(maxPrice)
+		samples = blockPrices
 	}
 
 	gpo.cacheLock.Lock()
 	gpo.lastHead = headHash
-	gpo.lastPrice = price
+	gpo.lastSamples = samples
 	gpo.cacheLock.Unlock()
-	return price, nil
+	return samples, nil
 }
 
 type getBlockPricesResult struct {
@@ -34,7 +34,9 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that protocol versions and modes of operations are matched up properly.
@@ -448,6 +450,70 @@ func testGetReceipt(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that account ranges can be retrieved from a state, proven against its
+// root, and that unknown states are answered with empty ranges.
+func TestGetAccountRange(t *testing.T) {
+	// Create a chain funding a few accounts to serve the ranges from
+	generator := func(i int, block *core.BlockGen) {
+		signer := types.HomesteadSigner{}
+		for j := 0; j < 4; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), common.Address{byte(i), byte(j)}, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+			block.AddTx(tx)
+		}
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, generator, nil)
+	peer, _ := newTestSnapPeer("peer", pm)
+	defer peer.close()
+
+	root := pm.blockchain.CurrentBlock().Root()
+	tests := []struct {
+		bytes    uint64 // Size limit of the requested range
+		accounts int    // Number of accounts expected in the range
+		more     bool   // Whether accounts are expected to remain after the range
+	}{
+		{softResponseLimit, 17, false},
+		{1, 1, true},
+		{200, 2, true},
+	}
+	for i, tt := range tests {
+		p2p.Send(peer.app, GetAccountRangeMsg, &getAccountRangeData{Root: root, Bytes: tt.bytes})
+		msg, err := peer.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("test %d: failed to read account range: %v", i, err)
+		}
+		if msg.Code != AccountRangeMsg {
+			t.Fatalf("test %d: response packet code mismatch: have %x, want %x", i, msg.Code, AccountRangeMsg)
+		}
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			t.Fatalf("test %d: failed to decode account range: %v", i, err)
+		}
+		if len(res.Accounts) != tt.accounts {
+			t.Errorf("test %d: account count mismatch: have %d, want %d", i, len(res.Accounts), tt.accounts)
+		}
+		// Verify the range against the state root
+		proof := ethdb.NewMemDatabase()
+		for _, node := range res.Proof {
+			proof.Put(crypto.Keccak256(node), node)
+		}
+		keys, values := make([][]byte, len(res.Accounts)), make([][]byte, len(res.Accounts))
+		for j, account := range res.Accounts {
+			keys[j], values[j] = account.Hash[:], account.Body
+		}
+		more, err := trie.VerifyRangeProof(root, common.Hash{}.Bytes(), keys, values, proof)
+		if err != nil {
+			t.Errorf("test %d: invalid account range: %v", i, err)
+		} else if more != tt.more {
+			t.Errorf("test %d: remaining accounts mismatch: have %v, want %v", i, more, tt.more)
+		}
+	}
+	// Request the range of an unknown state
+	p2p.Send(peer.app, GetAccountRangeMsg, &getAccountRangeData{Root: common.Hash{0x01}, Bytes: softResponseLimit})
+	if err := p2p.ExpectMsg(peer.app, AccountRangeMsg, &accountRangeData{}); err != nil {
+		t.Errorf("unknown state range mismatch: %v", err)
+	}
+}
+
 // Tests that post eth protocol handshake, DAO fork-enabled clients also execute
 // a DAO "challenge" verifying each others' DAO fork headers to ensure they're on
 // compatible chains.
@@ -520,3 +586,47 @@ func testDAOChallenge(t *testing.T, localForked, remoteForked bool, timeout bool
 		}
 	}
 }
+
+// Tests that the snap protocol connection of a peer is attached to its eth
+// connection, whichever is established first, and detached once closed.
+func TestSnapPeerAttach(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	for _, snapFirst := range []bool{true, false} {
+		var id discover.NodeID
+		rand.Read(id[:])
+
+		app, net := p2p.MsgPipe()
+		sp := newSnapPeer(snap1, p2p.NewPeer(id, "peer", nil), net)
+		p := newPeer(eth63, p2p.NewPeer(id, "peer", nil), net)
+
+		if snapFirst {
+			if err := pm.peers.RegisterSnap(sp); err != nil {
+				t.Fatalf("failed to register snap peer: %v", err)
+			}
+		}
+		if err := pm.peers.Register(p); err != nil {
+			t.Fatalf("failed to register peer: %v", err)
+		}
+		if !snapFirst {
+			if err := pm.peers.RegisterSnap(sp); err != nil {
+				t.Fatalf("failed to register snap peer: %v", err)
+			}
+		}
+		if !p.ServesRanges() {
+			t.Errorf("snap first %v: snap connection not attached", snapFirst)
+		}
+		if err := pm.peers.UnregisterSnap(sp.id); err != nil {
+			t.Fatalf("failed to unregister snap peer: %v", err)
+		}
+		if p.ServesRanges() {
+			t.Errorf("snap first %v: snap connection not detached", snapFirst)
+		}
+		if err := p.RequestAccountRange(common.Hash{}, common.Hash{}, softResponseLimit); err != errNoSnap {
+			t.Errorf("snap first %v: range request error mismatch: have %v, want %v", snapFirst, err, errNoSnap)
+		}
+		pm.peers.Unregister(p.id)
+		app.Close()
+	}
+}
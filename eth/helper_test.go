@@ -199,3 +199,37 @@ func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesi
 func (p *testPeer) close() {
 	p.app.Close()
 }
+
+// testSnapPeer is a simulated snap protocol connection to allow testing the
+// serving of state ranges.
+type testSnapPeer struct {
+	net p2p.MsgReadWriter // Network layer reader/writer to simulate remote messaging
+	app *p2p.MsgPipeRW    // Application layer reader/writer to simulate the local side
+	*snapPeer
+}
+
+// newTestSnapPeer creates a new snap protocol connection registered at the given
+// protocol manager.
+func newTestSnapPeer(name string, pm *ProtocolManager) (*testSnapPeer, <-chan error) {
+	// Create a message pipe to communicate through
+	app, net := p2p.MsgPipe()
+
+	// Generate a random id and create the peer
+	var id discover.NodeID
+	rand.Read(id[:])
+
+	peer := newSnapPeer(snap1, p2p.NewPeer(id, name, nil), net)
+
+	// Start the peer on a new thread
+	errc := make(chan error, 1)
+	go func() {
+		errc <- pm.handleSnap(peer)
+	}()
+	return &testSnapPeer{app: app, net: net, snapPeer: peer}, errc
+}
+
+// close terminates the local side of the snap connection, notifying the remote
+// protocol manager of termination.
+func (p *testSnapPeer) close() {
+	p.app.Close()
+}
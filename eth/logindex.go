@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// LogIndexer implements a core.ChainIndexer, building up an index of the blocks
+// containing logs emitted by each contract address or carrying each topic, which
+// permits answering log filters over wide block ranges without scanning blooms.
+type LogIndexer struct {
+	db ethdb.Database // database instance to read receipts from and write index data into
+
+	section uint64              // Section is the section number being processed currently
+	head    common.Hash         // Head is the hash of the last header processed
+	entries map[string][]uint64 // Block numbers containing logs matching each index entry
+}
+
+// NewLogIndexer returns a chain indexer that generates the address and topic log
+// index for the canonical chain, catching up with the existing chain in the
+// background.
+func NewLogIndexer(db ethdb.Database, size uint64) *core.ChainIndexer {
+	backend := &LogIndexer{
+		db: db,
+	}
+	table := ethdb.NewTable(db, string(rawdb.LogIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, bloomConfirms, bloomThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	l.section, l.head, l.entries = section, common.Hash{}, make(map[string][]uint64)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header's
+// receipts into the index.
+func (l *LogIndexer) Process(header *types.Header) {
+	number, hash := header.Number.Uint64(), header.Hash()
+	l.head = hash
+
+	// Blooms are cheap to check, skip retrieving the receipts of log-less blocks
+	if header.Bloom == (types.Bloom{}) {
+		return
+	}
+	for _, receipt := range rawdb.ReadReceipts(l.db, hash, number) {
+		for _, log := range receipt.Logs {
+			l.add(rawdb.LogIndexAddressEntry(log.Address), number)
+			for i, topic := range log.Topics {
+				l.add(rawdb.LogIndexTopicEntry(i, topic), number)
+			}
+		}
+	}
+}
+
+// add records that the given block contains a log matching the index entry.
+func (l *LogIndexer) add(entry []byte, number uint64) {
+	numbers := l.entries[string(entry)]
+	if len(numbers) > 0 && numbers[len(numbers)-1] == number {
+		return
+	}
+	l.entries[string(entry)] = append(numbers, number)
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database.
+func (l *LogIndexer) Commit() error {
+	batch := l.db.NewBatch()
+
+	for entry, numbers := range l.entries {
+		rawdb.WriteLogIndex(batch, []byte(entry), l.section, l.head, numbers)
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the log indexer records the blocks containing logs for each address
+// and positional topic of a section.
+func TestLogIndexer(t *testing.T) {
+	var (
+		db    = ethdb.NewMemDatabase()
+		addr1 = common.BytesToAddress([]byte("address1"))
+		addr2 = common.BytesToAddress([]byte("address2"))
+		hash1 = common.BytesToHash([]byte("topic1"))
+		hash2 = common.BytesToHash([]byte("topic2"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr1, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 7, func(i int, gen *core.BlockGen) {
+		var logs []*types.Log
+		switch i {
+		case 1:
+			logs = []*types.Log{{Address: addr1, Topics: []common.Hash{hash1}}, {Address: addr1, Topics: []common.Hash{hash1, hash2}}}
+		case 3:
+			logs = []*types.Log{{Address: addr2, Topics: []common.Hash{hash2, hash1}}}
+		case 6:
+			logs = []*types.Log{{Address: addr1}}
+		default:
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = logs
+		gen.AddUncheckedReceipt(receipt)
+	})
+	indexer := &LogIndexer{db: db}
+	if err := indexer.Reset(1, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	for i, block := range chain {
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		indexer.Process(block.Header())
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit index: %v", err)
+	}
+	head := chain[len(chain)-1].Hash()
+
+	tests := []struct {
+		entry []byte
+		want  []uint64
+	}{
+		{rawdb.LogIndexAddressEntry(addr1), []uint64{2, 7}},
+		{rawdb.LogIndexAddressEntry(addr2), []uint64{4}},
+		{rawdb.LogIndexTopicEntry(0, hash1), []uint64{2}},
+		{rawdb.LogIndexTopicEntry(1, hash1), []uint64{4}},
+		{rawdb.LogIndexTopicEntry(0, hash2), []uint64{4}},
+		{rawdb.LogIndexTopicEntry(1, hash2), []uint64{2}},
+		{rawdb.LogIndexTopicEntry(2, hash1), nil},
+	}
+	for i, tt := range tests {
+		if have := rawdb.ReadLogIndex(db, tt.entry, 1, head); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: indexed blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if have := rawdb.ReadLogIndex(db, rawdb.LogIndexAddressEntry(addr1), 0, head); have != nil {
+		t.Errorf("unindexed section returned blocks: %v", have)
+	}
+}
@@ -46,6 +46,10 @@ var (
 	reqStateInTrafficMeter    = metrics.NewRegisteredMeter("eth/req/states/in/traffic", nil)
 	reqStateOutPacketsMeter   = metrics.NewRegisteredMeter("eth/req/states/out/packets", nil)
 	reqStateOutTrafficMeter   = metrics.NewRegisteredMeter("eth/req/states/out/traffic", nil)
+	reqRangeInPacketsMeter    = metrics.NewRegisteredMeter("eth/req/ranges/in/packets", nil)
+	reqRangeInTrafficMeter    = metrics.NewRegisteredMeter("eth/req/ranges/in/traffic", nil)
+	reqRangeOutPacketsMeter   = metrics.NewRegisteredMeter("eth/req/ranges/out/packets", nil)
+	reqRangeOutTrafficMeter   = metrics.NewRegisteredMeter("eth/req/ranges/out/traffic", nil)
 	reqReceiptInPacketsMeter  = metrics.NewRegisteredMeter("eth/req/receipts/in/packets", nil)
 	reqReceiptInTrafficMeter  = metrics.NewRegisteredMeter("eth/req/receipts/in/traffic", nil)
 	reqReceiptOutPacketsMeter = metrics.NewRegisteredMeter("eth/req/receipts/out/packets", nil)
@@ -59,8 +63,9 @@ var (
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
 // accumulating the above defined metrics based on the data stream contents.
 type meteredMsgReadWriter struct {
-	p2p.MsgReadWriter     // Wrapped message stream to meter
-	version           int // Protocol version to select correct meters
+	p2p.MsgReadWriter      // Wrapped message stream to meter
+	version           int  // Protocol version to select correct meters
+	snap              bool // Whether the stream carries the snap protocol
 }
 
 // newMeteredMsgWriter wraps a p2p MsgReadWriter with metering support. If the
@@ -72,6 +77,16 @@ func newMeteredMsgWriter(rw p2p.MsgReadWriter) p2p.MsgReadWriter {
 	return &meteredMsgReadWriter{MsgReadWriter: rw}
 }
 
+// newMeteredSnapMsgWriter wraps the p2p MsgReadWriter of a snap protocol
+// connection with metering support. If the metrics system is disabled, this
+// function returns the original object.
+func newMeteredSnapMsgWriter(rw p2p.MsgReadWriter) p2p.MsgReadWriter {
+	if !metrics.Enabled {
+		return rw
+	}
+	return &meteredMsgReadWriter{MsgReadWriter: rw, snap: true}
+}
+
 // Init sets the protocol version used by the stream to know which meters to
 // increment in case of overlapping message ids between protocol versions.
 func (rw *meteredMsgReadWriter) Init(version int) {
@@ -87,6 +102,12 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 	// Account for the data traffic
 	packets, traffic := miscInPacketsMeter, miscInTrafficMeter
 	switch {
+	case rw.snap:
+		// Snap message codes overlap the eth ones, only responses are metered apart
+		if msg.Code == AccountRangeMsg || msg.Code == StorageRangesMsg || msg.Code == ByteCodesMsg {
+			packets, traffic = reqRangeInPacketsMeter, reqRangeInTrafficMeter
+		}
+
 	case msg.Code == BlockHeadersMsg:
 		packets, traffic = reqHeaderInPacketsMeter, reqHeaderInTrafficMeter
 	case msg.Code == BlockBodiesMsg:
@@ -114,6 +135,12 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 	// Account for the data traffic
 	packets, traffic := miscOutPacketsMeter, miscOutTrafficMeter
 	switch {
+	case rw.snap:
+		// Snap message codes overlap the eth ones, only responses are metered apart
+		if msg.Code == AccountRangeMsg || msg.Code == StorageRangesMsg || msg.Code == ByteCodesMsg {
+			packets, traffic = reqRangeOutPacketsMeter, reqRangeOutTrafficMeter
+		}
+
 	case msg.Code == BlockHeadersMsg:
 		packets, traffic = reqHeaderOutPacketsMeter, reqHeaderOutTrafficMeter
 	case msg.Code == BlockBodiesMsg:
@@ -34,6 +34,7 @@ var (
 	errClosed            = errors.New("peer set is closed")
 	errAlreadyRegistered = errors.New("peer is already registered")
 	errNotRegistered     = errors.New("peer is not registered")
+	errNoSnap            = errors.New("peer does not run the snap protocol")
 )
 
 const (
@@ -83,6 +84,7 @@ type peer struct {
 
 	head common.Hash
 	td   *big.Int
+	snap *snapPeer // Snap protocol connection of the peer, nil if it doesn't run snap
 	lock sync.RWMutex
 
 	knownTxs    *set.Set                  // Set of transaction hashes known to be known by this peer
@@ -327,6 +329,60 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetReceiptsMsg, hashes)
 }
 
+// ServesRanges reports whether the peer runs the snap protocol, retrieving the
+// state in ranges.
+func (p *peer) ServesRanges() bool {
+	return p.snapPeer() != nil
+}
+
+// RequestAccountRange fetches a range of accounts of a state through the snap
+// protocol connection of the peer.
+func (p *peer) RequestAccountRange(root common.Hash, origin common.Hash, bytes uint64) error {
+	sp := p.snapPeer()
+	if sp == nil {
+		return errNoSnap
+	}
+	return sp.RequestAccountRange(root, origin, bytes)
+}
+
+// RequestStorageRanges fetches the storage slots of a batch of accounts of a
+// state through the snap protocol connection of the peer.
+func (p *peer) RequestStorageRanges(root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error {
+	sp := p.snapPeer()
+	if sp == nil {
+		return errNoSnap
+	}
+	return sp.RequestStorageRanges(root, accounts, origin, bytes)
+}
+
+// RequestByteCodes fetches a batch of contract codes through the snap protocol
+// connection of the peer.
+func (p *peer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	sp := p.snapPeer()
+	if sp == nil {
+		return errNoSnap
+	}
+	return sp.RequestByteCodes(hashes, bytes)
+}
+
+// snapPeer retrieves the snap protocol connection of the peer, nil if the peer
+// doesn't run the snap protocol.
+func (p *peer) snapPeer() *snapPeer {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.snap
+}
+
+// setSnapPeer attaches or, if nil, detaches the snap protocol connection of the
+// peer.
+func (p *peer) setSnapPeer(sp *snapPeer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.snap = sp
+}
+
 // Handshake executes the eth protocol handshake, negotiating version number,
 // network IDs, difficulties, head and genesis blocks.
 func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
@@ -396,10 +452,69 @@ func (p *peer) String() string {
 	)
 }
 
+// snapPeer is the snap protocol connection of a remote peer, retrieving and
+// serving the state in ranges alongside its eth protocol connection.
+type snapPeer struct {
+	id string
+
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	version int // Snap protocol version negotiated
+}
+
+func newSnapPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *snapPeer {
+	return &snapPeer{
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		id:      fmt.Sprintf("%x", p.ID().Bytes()[:8]),
+	}
+}
+
+// SendAccountRange sends a range of accounts of a state, along with the proof of
+// its edges.
+func (p *snapPeer) SendAccountRange(accounts []*accountData, proof [][]byte) error {
+	return p2p.Send(p.rw, AccountRangeMsg, &accountRangeData{Accounts: accounts, Proof: proof})
+}
+
+// SendStorageRanges sends the storage ranges of a batch of accounts, along with
+// the proof of the edges of the last range if it is partial.
+func (p *snapPeer) SendStorageRanges(slots [][]*storageData, proof [][]byte) error {
+	return p2p.Send(p.rw, StorageRangesMsg, &storageRangesData{Slots: slots, Proof: proof})
+}
+
+// SendByteCodes sends a batch of contract codes, corresponding to the ones
+// requested.
+func (p *snapPeer) SendByteCodes(codes [][]byte) error {
+	return p2p.Send(p.rw, ByteCodesMsg, codes)
+}
+
+// RequestAccountRange fetches a range of accounts of a state, starting at the
+// given account hash, from the remote node.
+func (p *snapPeer) RequestAccountRange(root common.Hash, origin common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching range of accounts", "root", root, "origin", origin, "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetAccountRangeMsg, &getAccountRangeData{Root: root, Origin: origin, Bytes: bytes})
+}
+
+// RequestStorageRanges fetches the storage slots of a batch of accounts of a
+// state from the remote node, starting at the given slot hash for the first one.
+func (p *snapPeer) RequestStorageRanges(root common.Hash, accounts []common.Hash, origin common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching ranges of storage slots", "root", root, "accounts", len(accounts), "origin", origin, "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetStorageRangesMsg, &getStorageRangesData{Root: root, Accounts: accounts, Origin: origin, Bytes: bytes})
+}
+
+// RequestByteCodes fetches a batch of contract codes from the remote node.
+func (p *snapPeer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching batch of byte codes", "count", len(hashes), "bytes", common.StorageSize(bytes))
+	return p2p.Send(p.rw, GetByteCodesMsg, &getByteCodesData{Hashes: hashes, Bytes: bytes})
+}
+
 // peerSet represents the collection of active peers currently participating in
 // the Ethereum sub-protocol.
 type peerSet struct {
 	peers  map[string]*peer
+	snaps  map[string]*snapPeer
 	lock   sync.RWMutex
 	closed bool
 }
@@ -408,6 +523,7 @@ type peerSet struct {
 func newPeerSet() *peerSet {
 	return &peerSet{
 		peers: make(map[string]*peer),
+		snaps: make(map[string]*snapPeer),
 	}
 }
 
@@ -425,6 +541,9 @@ func (ps *peerSet) Register(p *peer) error {
 		return errAlreadyRegistered
 	}
 	ps.peers[p.id] = p
+	if sp := ps.snaps[p.id]; sp != nil {
+		p.setSnapPeer(sp)
+	}
 	go p.broadcast()
 
 	return nil
@@ -446,6 +565,41 @@ func (ps *peerSet) Unregister(id string) error {
 	return nil
 }
 
+// RegisterSnap injects the snap protocol connection of a remote peer into the
+// working set, attaching it to the eth peer with the same id once registered.
+func (ps *peerSet) RegisterSnap(sp *snapPeer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed {
+		return errClosed
+	}
+	if _, ok := ps.snaps[sp.id]; ok {
+		return errAlreadyRegistered
+	}
+	ps.snaps[sp.id] = sp
+	if p := ps.peers[sp.id]; p != nil {
+		p.setSnapPeer(sp)
+	}
+	return nil
+}
+
+// UnregisterSnap removes the snap protocol connection of a remote peer from the
+// active set, detaching it from the eth peer with the same id.
+func (ps *peerSet) UnregisterSnap(id string) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.snaps[id]; !ok {
+		return errNotRegistered
+	}
+	delete(ps.snaps, id)
+	if p := ps.peers[id]; p != nil {
+		p.setSnapPeer(nil)
+	}
+	return nil
+}
+
 // Peer retrieves the registered peer with the given id.
 func (ps *peerSet) Peer(id string) *peer {
 	ps.lock.RLock()
@@ -64,6 +64,32 @@ const (
 	ReceiptsMsg    = 0x10
 )
 
+// Constants to match up snap protocol versions and messages
+const (
+	snap1 = 1
+)
+
+// SnapProtocolName is the short name of the sub-protocol retrieving the state
+// in ranges during snap sync, run alongside the eth protocol.
+var SnapProtocolName = "snap"
+
+// SnapProtocolVersions are the supported versions of the snap protocol (first is primary).
+var SnapProtocolVersions = []uint{snap1}
+
+// SnapProtocolLengths are the number of implemented message corresponding to different snap protocol versions.
+var SnapProtocolLengths = []uint64{6}
+
+// snap protocol message codes
+const (
+	// Protocol messages belonging to snap/1
+	GetAccountRangeMsg  = 0x00
+	AccountRangeMsg     = 0x01
+	GetStorageRangesMsg = 0x02
+	StorageRangesMsg    = 0x03
+	GetByteCodesMsg     = 0x04
+	ByteCodesMsg        = 0x05
+)
+
 type errCode int
 
 const (
@@ -181,3 +207,52 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// getAccountRangeData represents a query for a range of accounts of a state.
+type getAccountRangeData struct {
+	Root   common.Hash // Root of the state to retrieve the accounts from
+	Origin common.Hash // Hash of the first account to retrieve
+	Bytes  uint64      // Soft limit on the size of the response
+}
+
+// accountData is a single account of an account range.
+type accountData struct {
+	Hash common.Hash  // Hash of the account address
+	Body rlp.RawValue // RLP encoded account, as stored in the account trie
+}
+
+// accountRangeData is the network packet for account range distribution. The
+// proof contains the trie nodes proving the origin and the last account.
+type accountRangeData struct {
+	Accounts []*accountData
+	Proof    [][]byte
+}
+
+// getStorageRangesData represents a query for the storage slots of a batch of
+// accounts of a state.
+type getStorageRangesData struct {
+	Root     common.Hash   // Root of the state to retrieve the storage from
+	Accounts []common.Hash // Hashes of the accounts to retrieve the storage of
+	Origin   common.Hash   // Hash of the first storage slot of the first account
+	Bytes    uint64        // Soft limit on the size of the response
+}
+
+// storageData is a single slot of a storage range.
+type storageData struct {
+	Hash common.Hash // Hash of the storage slot
+	Body []byte      // RLP encoded slot value, as stored in the storage trie
+}
+
+// storageRangesData is the network packet for storage range distribution. Only
+// the last range may be partial, in which case the proof contains the storage
+// trie nodes proving its edges.
+type storageRangesData struct {
+	Slots [][]*storageData
+	Proof [][]byte
+}
+
+// getByteCodesData represents a query for a batch of contract codes.
+type getByteCodesData struct {
+	Hashes []common.Hash // Hashes of the codes to retrieve
+	Bytes  uint64        // Soft limit on the size of the response
+}
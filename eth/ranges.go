@@ -0,0 +1,191 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// rangeIterator iterates over the leaves of a trie in ascending key order.
+type rangeIterator interface {
+	Next() bool
+	Hash() common.Hash
+	Entry() []byte
+	Error() error
+	Release()
+}
+
+// trieRangeIterator is a rangeIterator walking the leaves of a trie.
+type trieRangeIterator struct {
+	*trie.Iterator
+}
+
+func (it trieRangeIterator) Hash() common.Hash { return common.BytesToHash(it.Key) }
+func (it trieRangeIterator) Entry() []byte     { return it.Value }
+func (it trieRangeIterator) Error() error      { return it.Err }
+func (it trieRangeIterator) Release()          {}
+
+// responseLimit caps the requested size of a range response.
+func responseLimit(bytes uint64) uint64 {
+	if bytes > softResponseLimit {
+		return softResponseLimit
+	}
+	return bytes
+}
+
+// proveRange collects the trie nodes proving the edges of a range, starting at
+// origin and ending at the last of the hashes, if any.
+func proveRange(tr state.Trie, origin common.Hash, hashes []common.Hash) [][]byte {
+	db := ethdb.NewMemDatabase()
+	tr.Prove(origin[:], 0, db)
+	if len(hashes) > 0 {
+		tr.Prove(hashes[len(hashes)-1][:], 0, db)
+	}
+	proof := make([][]byte, 0, db.Len())
+	for _, key := range db.Keys() {
+		node, _ := db.Get(key)
+		proof = append(proof, node)
+	}
+	return proof
+}
+
+// serveAccountRange collects a range of accounts of the requested state until
+// the size limit is reached, along with the proof of its edges. The accounts are
+// read from the snapshot if it covers the state, or from the account trie. If
+// the state is unavailable, both results are empty.
+func (pm *ProtocolManager) serveAccountRange(query *getAccountRangeData) ([]*accountData, [][]byte) {
+	tr, err := pm.blockchain.StateCache().OpenTrie(query.Root)
+	if err != nil {
+		return nil, nil
+	}
+	var it rangeIterator = trieRangeIterator{trie.NewIterator(tr.NodeIterator(query.Origin[:]))}
+	if snap := snapshot.Load(pm.chaindb); snap != nil && snap.Root() == query.Root {
+		it = snap.AccountIterator(query.Origin)
+	}
+	defer it.Release()
+
+	var (
+		accounts []*accountData
+		hashes   []common.Hash
+		limit    = responseLimit(query.Bytes)
+		bytes    uint64
+	)
+	for bytes < limit && it.Next() {
+		accounts = append(accounts, &accountData{Hash: it.Hash(), Body: common.CopyBytes(it.Entry())})
+		hashes = append(hashes, it.Hash())
+		bytes += uint64(common.HashLength + len(it.Entry()))
+	}
+	if it.Error() != nil {
+		return nil, nil
+	}
+	return accounts, proveRange(tr, query.Origin, hashes)
+}
+
+// serveStorageRanges collects the storage slots of the requested accounts until
+// the size limit is reached. Only the last range may be partial, in which case
+// it is returned along with the proof of its edges. The range of the first
+// account starts at the requested origin, which also requires a proof.
+func (pm *ProtocolManager) serveStorageRanges(query *getStorageRangesData) ([][]*storageData, [][]byte) {
+	statedb := pm.blockchain.StateCache()
+	tr, err := statedb.OpenTrie(query.Root)
+	if err != nil {
+		return nil, nil
+	}
+	snap := snapshot.Load(pm.chaindb)
+	if snap != nil && snap.Root() != query.Root {
+		snap = nil
+	}
+	var (
+		slots [][]*storageData
+		limit = responseLimit(query.Bytes)
+		bytes uint64
+	)
+	for i, accountHash := range query.Accounts {
+		if bytes >= limit {
+			break
+		}
+		var origin common.Hash
+		if i == 0 {
+			origin = query.Origin
+		}
+		// Open the storage trie of the account
+		var account state.Account
+		if blob, err := tr.TryGet(accountHash[:]); err != nil || blob == nil {
+			break
+		} else if err := rlp.DecodeBytes(blob, &account); err != nil {
+			break
+		}
+		storage, err := statedb.OpenStorageTrie(accountHash, account.Root)
+		if err != nil {
+			break
+		}
+		var it rangeIterator = trieRangeIterator{trie.NewIterator(storage.NodeIterator(origin[:]))}
+		if snap != nil {
+			it = snap.StorageIterator(accountHash, origin)
+		}
+		// Gather the slots of the account until the limit is reached
+		var (
+			list    []*storageData
+			hashes  []common.Hash
+			partial bool
+		)
+		for it.Next() {
+			if bytes >= limit {
+				partial = true
+				break
+			}
+			list = append(list, &storageData{Hash: it.Hash(), Body: common.CopyBytes(it.Entry())})
+			hashes = append(hashes, it.Hash())
+			bytes += uint64(common.HashLength + len(it.Entry()))
+		}
+		it.Release()
+		if it.Error() != nil {
+			break
+		}
+		slots = append(slots, list)
+		if partial || origin != (common.Hash{}) {
+			return slots, proveRange(storage, origin, hashes)
+		}
+	}
+	return slots, nil
+}
+
+// serveByteCodes collects the requested contract codes until the size limit is
+// reached, skipping the unknown ones.
+func (pm *ProtocolManager) serveByteCodes(query *getByteCodesData) [][]byte {
+	var (
+		codes [][]byte
+		limit = responseLimit(query.Bytes)
+		bytes uint64
+	)
+	for _, hash := range query.Hashes {
+		if bytes >= limit || len(codes) >= downloader.MaxStateFetch {
+			break
+		}
+		if code, err := pm.blockchain.StateCache().ContractCode(common.Hash{}, hash); err == nil {
+			codes = append(codes, code)
+			bytes += uint64(len(code))
+		}
+	}
+	return codes
+}
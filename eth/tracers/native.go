@@ -0,0 +1,125 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// NativeTracer is a transaction tracer compiled into go-ethereum. Unlike the
+// JavaScript tracers, which are interpreted at every executed opcode, native
+// tracers run at the speed of the EVM, making it feasible to trace whole
+// blocks and chains.
+type NativeTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded result of the trace, or the error
+	// which aborted it.
+	GetResult() (json.RawMessage, error)
+
+	// Stop aborts the trace at the first opportune moment, failing it with
+	// the given error. It may be called concurrently with the tracing.
+	Stop(err error)
+}
+
+var (
+	nativesLock sync.RWMutex
+	natives     = make(map[string]func() NativeTracer)
+)
+
+// RegisterNative makes a native tracer selectable by name in the tracing RPC
+// methods, the constructor being called for every traced transaction. It
+// panics if the name is already taken by a native or JavaScript tracer.
+func RegisterNative(name string, ctor func() NativeTracer) {
+	nativesLock.Lock()
+	defer nativesLock.Unlock()
+
+	if _, ok := natives[name]; ok {
+		panic(fmt.Sprintf("native tracer %q already registered", name))
+	}
+	if _, ok := all[name]; ok {
+		panic(fmt.Sprintf("native tracer %q shadows a JavaScript tracer", name))
+	}
+	natives[name] = ctor
+}
+
+// NewNative creates the native tracer registered under a name, reporting
+// whether there is one.
+func NewNative(name string) (NativeTracer, bool) {
+	nativesLock.RLock()
+	ctor, ok := natives[name]
+	nativesLock.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+	return ctor(), true
+}
+
+// Natives returns the sorted names of the registered native tracers.
+func Natives() []string {
+	nativesLock.RLock()
+	defer nativesLock.RUnlock()
+
+	names := make([]string, 0, len(natives))
+	for name := range natives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// interruptible implements the interruption of the built-in native tracers.
+type interruptible struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *interruptible) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// interrupted returns the reason the tracer was stopped for, nil if it was not.
+func (t *interruptible) interrupted() error {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return t.reason
+	}
+	return nil
+}
+
+// memorySlice copies a region of the EVM memory, returning an empty slice if
+// the region is out of bounds.
+func memorySlice(memory *vm.Memory, offset, size *big.Int) []byte {
+	if !offset.IsUint64() || !size.IsUint64() {
+		return []byte{}
+	}
+	start, end := offset.Uint64(), offset.Uint64()+size.Uint64()
+	if end < start || end > uint64(memory.Len()) {
+		return []byte{}
+	}
+	return common.CopyBytes(memory.Data()[start:end])
+}
@@ -18,6 +18,7 @@ package tracers
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"math/big"
 	"path/filepath"
@@ -116,78 +117,163 @@ type callTracerTest struct {
 	Result  *callTrace    `json:"result"`
 }
 
-// Iterates over all the input-output datasets in the tracer test harness and
-// runs the JavaScript tracers against them.
-func TestCallTracer(t *testing.T) {
+// newTestTracer creates the native or JavaScript tracer of a name.
+func newTestTracer(name string) (NativeTracer, error) {
+	if tracer, ok := NewNative(name); ok {
+		return tracer, nil
+	}
+	return New(name)
+}
+
+// runCallTracerTest executes the transaction of a call tracer test with the
+// named tracer, returning the test and the trace result.
+func runCallTracerTest(t *testing.T, file string, name string) (*callTracerTest, json.RawMessage) {
+	// Call tracer test found, read if from disk
+	blob, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("failed to read testcase: %v", err)
+	}
+	test := new(callTracerTest)
+	if err := json.Unmarshal(blob, test); err != nil {
+		t.Fatalf("failed to parse testcase: %v", err)
+	}
+	// Configure a blockchain with the given prestate
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(ethdb.NewMemDatabase(), test.Genesis.Alloc)
+
+	// Create the tracer, the EVM environment and run it
+	tracer, err := newTestTracer(name)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", name, err)
+	}
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	// Retrieve the trace result
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return test, res
+}
+
+// callTracerTests returns the files of the tracer test harness.
+func callTracerTests(t *testing.T) []string {
 	files, err := ioutil.ReadDir("testdata")
 	if err != nil {
 		t.Fatalf("failed to retrieve tracer test suite: %v", err)
 	}
+	var names []string
 	for _, file := range files {
-		if !strings.HasPrefix(file.Name(), "call_tracer_") {
-			continue
+		if strings.HasPrefix(file.Name(), "call_tracer_") {
+			names = append(names, file.Name())
 		}
+	}
+	return names
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// runs the JavaScript and native call tracers against them.
+func TestCallTracer(t *testing.T) {
+	for _, file := range callTracerTests(t) {
 		file := file // capture range variable
-		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file, "call_tracer_"), ".json")), func(t *testing.T) {
 			t.Parallel()
 
-			// Call tracer test found, read if from disk
-			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
-			if err != nil {
-				t.Fatalf("failed to read testcase: %v", err)
-			}
-			test := new(callTracerTest)
-			if err := json.Unmarshal(blob, test); err != nil {
-				t.Fatalf("failed to parse testcase: %v", err)
-			}
-			// Configure a blockchain with the given prestate
-			tx := new(types.Transaction)
-			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
-				t.Fatalf("failed to parse testcase input: %v", err)
-			}
-			signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
-			origin, _ := signer.Sender(tx)
-
-			context := vm.Context{
-				CanTransfer: core.CanTransfer,
-				Transfer:    core.Transfer,
-				Origin:      origin,
-				Coinbase:    test.Context.Miner,
-				BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
-				Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
-				Difficulty:  (*big.Int)(test.Context.Difficulty),
-				GasLimit:    uint64(test.Context.GasLimit),
-				GasPrice:    tx.GasPrice(),
-			}
-			statedb := tests.MakePreState(ethdb.NewMemDatabase(), test.Genesis.Alloc)
+			for _, name := range []string{"callTracer", "nativeCallTracer"} {
+				test, res := runCallTracerTest(t, file, name)
 
-			// Create the tracer, the EVM environment and run it
-			tracer, err := New("callTracer")
-			if err != nil {
-				t.Fatalf("failed to create call tracer: %v", err)
+				// Compare the trace result against the etalon
+				ret := new(callTrace)
+				if err := json.Unmarshal(res, ret); err != nil {
+					t.Fatalf("%s: failed to unmarshal trace result: %v", name, err)
+				}
+				if !reflect.DeepEqual(ret, test.Result) {
+					t.Fatalf("%s: trace mismatch: have %+v, want %+v", name, ret, test.Result)
+				}
 			}
-			evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+		})
+	}
+}
 
-			msg, err := tx.AsMessage(signer)
-			if err != nil {
-				t.Fatalf("failed to prepare transaction for tracing: %v", err)
-			}
-			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-			if _, _, _, err = st.TransitionDb(); err != nil {
-				t.Fatalf("failed to execute transaction: %v", err)
-			}
-			// Retrieve the trace result and compare against the etalon
-			res, err := tracer.GetResult()
-			if err != nil {
-				t.Fatalf("failed to retrieve trace result: %v", err)
-			}
-			ret := new(callTrace)
-			if err := json.Unmarshal(res, ret); err != nil {
-				t.Fatalf("failed to unmarshal trace result: %v", err)
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks that the native prestate tracer agrees with the JavaScript one.
+func TestPrestateTracer(t *testing.T) {
+	for _, file := range callTracerTests(t) {
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file, "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				raws    []json.RawMessage
+				results []map[common.Address]*prestateAccount
+			)
+			for _, name := range []string{"prestateTracer", "nativePrestateTracer"} {
+				_, res := runCallTracerTest(t, file, name)
+
+				var prestate map[common.Address]*prestateAccount
+				if err := json.Unmarshal(res, &prestate); err != nil {
+					t.Fatalf("%s: failed to unmarshal trace result: %v", name, err)
+				}
+				raws, results = append(raws, res), append(results, prestate)
 			}
-			if !reflect.DeepEqual(ret, test.Result) {
-				t.Fatalf("trace mismatch: have %+v, want %+v", ret, test.Result)
+			if !reflect.DeepEqual(results[0], results[1]) {
+				t.Fatalf("prestate mismatch: have %s, want %s", raws[1], raws[0])
 			}
 		})
 	}
 }
+
+func TestRegisterNative(t *testing.T) {
+	if _, ok := NewNative("callTracer"); ok {
+		t.Fatal("expected JavaScript tracer not to be native")
+	}
+	for _, name := range []string{"nativeCallTracer", "callTracer"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected registration to panic", name)
+				}
+			}()
+			RegisterNative(name, func() NativeTracer { return newCallTracer() })
+		}()
+	}
+	if names := Natives(); !reflect.DeepEqual(names, []string{"nativeCallTracer", "nativePrestateTracer"}) {
+		t.Fatalf("unexpected native tracers %v", names)
+	}
+}
+
+func TestNativeTracerStop(t *testing.T) {
+	tracer, ok := NewNative("nativeCallTracer")
+	if !ok {
+		t.Fatal("native call tracer not registered")
+	}
+	tracer.Stop(errors.New("stopped"))
+	if _, err := runTrace(tracer); err == nil || err.Error() != "stopped" {
+		t.Fatalf("expected the stop error, got %v", err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
 func newTestLDB() (*ethdb.LDBDatabase, func()) {
@@ -188,3 +189,33 @@ func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_IteratorWithPrefix(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIteratorWithPrefix(db, db.NewIteratorWithPrefix, t)
+}
+
+func TestMemoryDB_IteratorWithPrefix(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	testIteratorWithPrefix(db, db.NewIteratorWithPrefix, t)
+}
+
+func testIteratorWithPrefix(db ethdb.Database, newIterator func([]byte) iterator.Iterator, t *testing.T) {
+	for _, k := range []string{"b2", "a1", "b1", "c1", "b3"} {
+		db.Put([]byte(k), []byte("v"+k))
+	}
+	it := newIterator([]byte("b"))
+	defer it.Release()
+
+	var keys []string
+	for it.Next() {
+		if want := "v" + string(it.Key()); string(it.Value()) != want {
+			t.Errorf("key %q: value mismatch: have %q, want %q", it.Key(), it.Value(), want)
+		}
+		keys = append(keys, string(it.Key()))
+	}
+	if fmt.Sprint(keys) != "[b1 b2 b3]" {
+		t.Errorf("iterated keys mismatch: have %v, want [b1 b2 b3]", keys)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultEngine is the name of the key-value store engine backing the databases
+// unless another one is selected.
+const DefaultEngine = "leveldb"
+
+// Engine opens the database stored in the given directory, creating it if it
+// does not exist yet. The cache is the memory allowance of the database in
+// megabytes and handles the number of files it may keep open.
+type Engine func(dir string, cache int, handles int) (Database, error)
+
+// engines are the key-value store engines of the databases, the one a database
+// was created with is recorded in the file ENGINE of its directory.
+var engines = NewEngineRegistry("database engine", "ENGINE", DefaultEngine, Engine(func(dir string, cache int, handles int) (Database, error) {
+	return NewLDBDatabase(dir, cache, handles)
+}))
+
+// RegisterEngine makes a key-value store engine available under the given name.
+// It panics if an engine is already registered with the same name.
+func RegisterEngine(name string, engine Engine) {
+	engines.Register(name, engine)
+}
+
+// Engines returns the sorted names of the registered key-value store engines.
+func Engines() []string {
+	return engines.Names()
+}
+
+// Open opens the database stored in the given directory with the named engine,
+// creating it if it does not exist yet. An existing database can only be opened
+// with the engine it was created with. If no engine is named, the one the
+// database was created with is used, or the default one for new databases.
+func Open(engine string, dir string, cache int, handles int) (Database, error) {
+	open, name, recorded, err := engines.Select(engine, dir)
+	if err != nil {
+		return nil, err
+	}
+	db, err := open.(Engine)(dir, cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		if err := engines.Record(name, dir); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// EngineRegistry keeps the named engines key-value stores can be opened with,
+// and selects the engine a store in a directory was created with from a file
+// of the directory recording it. Stores created before the engine was recorded
+// are LevelDB ones, which are recognised by their CURRENT file. The engines
+// are opaque to the registry, their users open the stores with them.
+type EngineRegistry struct {
+	kind    string // what the engines are called in errors
+	file    string // name of the file recording the engine of a store
+	def     string // name of the engine of new and unrecorded LevelDB stores
+	lock    sync.RWMutex
+	engines map[string]interface{}
+}
+
+// NewEngineRegistry creates a registry of the kind of engines recorded in the
+// given file, with the default engine registered under the given name.
+func NewEngineRegistry(kind, file, name string, engine interface{}) *EngineRegistry {
+	return &EngineRegistry{
+		kind:    kind,
+		file:    file,
+		def:     name,
+		engines: map[string]interface{}{name: engine},
+	}
+}
+
+// Register makes an engine available under the given name. It panics if an
+// engine is already registered with the same name.
+func (r *EngineRegistry) Register(name string, engine interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.engines[name]; ok {
+		panic(fmt.Sprintf("%s %q registered twice", r.kind, name))
+	}
+	r.engines[name] = engine
+}
+
+// Names returns the sorted names of the registered engines.
+func (r *EngineRegistry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.engines))
+	for name := range r.engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select returns the engine to open the store in the given directory with and
+// its name: the named one, which must be the one the store was created with,
+// or if no engine is named, the one the store was created with or the default
+// one for new stores. It also reports whether the engine is recorded in the
+// directory, if not, it should be with Record once the store is opened.
+func (r *EngineRegistry) Select(name string, dir string) (interface{}, string, bool, error) {
+	stored, recorded, err := r.read(dir)
+	if err != nil {
+		return nil, "", false, err
+	}
+	switch {
+	case name == "" && stored != "":
+		name = stored
+	case name == "":
+		name = r.def
+	case stored != "" && stored != name:
+		return nil, "", false, fmt.Errorf("%s was created with %s %q, not %q", dir, r.kind, stored, name)
+	}
+	r.lock.RLock()
+	engine, ok := r.engines[name]
+	r.lock.RUnlock()
+	if !ok {
+		return nil, "", false, fmt.Errorf("unknown %s %q (available: %s)", r.kind, name, strings.Join(r.Names(), ", "))
+	}
+	return engine, name, recorded, nil
+}
+
+// Record records the engine the store in the given directory was created with.
+func (r *EngineRegistry) Record(name string, dir string) error {
+	return ioutil.WriteFile(filepath.Join(dir, r.file), []byte(name+"\n"), 0644)
+}
+
+// read retrieves the engine a store was created with, or an empty name if there
+// is no store in the given directory yet. It also reports whether the engine is
+// recorded in the directory.
+func (r *EngineRegistry) read(dir string) (string, bool, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, r.file))
+	switch {
+	case err == nil:
+		return strings.TrimSpace(string(blob)), true, nil
+	case !os.IsNotExist(err):
+		return "", false, err
+	}
+	// Stores created before the engine was recorded are all LevelDB ones
+	if _, err := os.Stat(filepath.Join(dir, "CURRENT")); err == nil {
+		return r.def, false, nil
+	}
+	return "", false, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func init() {
+	ethdb.RegisterEngine("memory", func(dir string, cache int, handles int) (ethdb.Database, error) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		return ethdb.NewMemDatabase(), nil
+	})
+}
+
+// Tests that databases remember the engine they were created with and refuse
+// to be opened with any other one.
+func TestEngineSelection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethdb-engine-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := ethdb.Open("unknown", filepath.Join(dir, "unknown"), 0, 0); err == nil {
+		t.Fatalf("opened database with unknown engine")
+	}
+	// Create a database with a custom engine and check that it's recorded
+	custom := filepath.Join(dir, "custom")
+	db, err := ethdb.Open("memory", custom, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create custom database: %v", err)
+	}
+	db.Close()
+
+	if _, err := ethdb.Open(ethdb.DefaultEngine, custom, 0, 0); err == nil {
+		t.Fatalf("opened custom database with default engine")
+	}
+	db, err = ethdb.Open("", custom, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen custom database: %v", err)
+	}
+	if _, ok := db.(*ethdb.MemDatabase); !ok {
+		t.Fatalf("custom database reopened with wrong engine: %T", db)
+	}
+	db.Close()
+
+	// Create a plain LevelDB database and check that it's detected as such
+	legacy := filepath.Join(dir, "legacy")
+	ldb, err := ethdb.NewLDBDatabase(legacy, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	ldb.Close()
+
+	if _, err := ethdb.Open("memory", legacy, 0, 0); err == nil {
+		t.Fatalf("opened legacy database with custom engine")
+	}
+	db, err = ethdb.Open("", legacy, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen legacy database: %v", err)
+	}
+	if _, ok := db.(*ethdb.LDBDatabase); !ok {
+		t.Fatalf("legacy database reopened with wrong engine: %T", db)
+	}
+	db.Close()
+}
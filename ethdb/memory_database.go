@@ -17,10 +17,14 @@
 package ethdb
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
 /*
@@ -80,6 +84,22 @@ func (db *MemDatabase) Keys() [][]byte {
 	return keys
 }
 
+// NewIteratorWithPrefix returns an iterator over a copy of the database content
+// with a particular prefix, in ascending key order.
+func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var items memItems
+	for key, value := range db.db {
+		if strings.HasPrefix(key, string(prefix)) {
+			items = append(items, kv{[]byte(key), common.CopyBytes(value)})
+		}
+	}
+	sort.Sort(items)
+	return iterator.NewArrayIterator(items)
+}
+
 func (db *MemDatabase) Delete(key []byte) error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
@@ -98,6 +118,19 @@ func (db *MemDatabase) Len() int { return len(db.db) }
 
 type kv struct{ k, v []byte }
 
+// memItems is a list of key-value pairs sorted by key, iterable as an array.
+type memItems []kv
+
+func (m memItems) Len() int           { return len(m) }
+func (m memItems) Less(i, j int) bool { return bytes.Compare(m[i].k, m[j].k) < 0 }
+func (m memItems) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+
+func (m memItems) Search(key []byte) int {
+	return sort.Search(len(m), func(i int) bool { return bytes.Compare(m[i].k, key) >= 0 })
+}
+
+func (m memItems) Index(i int) ([]byte, []byte) { return m[i].k, m[i].v }
+
 type memBatch struct {
 	db     *MemDatabase
 	writes []kv
@@ -0,0 +1,477 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Response is the response to a GraphQL request
+type Response struct {
+	Data   interface{}   `json:"data"`
+	Errors []*QueryError `json:"errors,omitempty"`
+}
+
+// QueryError is an error of a GraphQL request, Path is the path of the
+// field which failed in the response
+type QueryError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *QueryError) Error() string {
+	return e.Message
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// schema resolves queries with the methods of Go types. Each object type of
+// the schema is a Go type whose fields are its methods taking a context and
+// optionally a struct of arguments, and returning the value of the field and
+// optionally an error. The name of a field is the name of its method with a
+// lower case first letter, and the names of its arguments are those of the
+// fields of the argument struct. Values of types which are not object types
+// are serialized as JSON scalars, except slices, which are lists.
+type schema struct {
+	query interface{}                  // value of the Query type
+	types map[reflect.Type]*objectType // object types by Go type
+}
+
+type objectType struct {
+	name   string
+	fields map[string]*resolver
+}
+
+// resolver is the method resolving a field
+type resolver struct {
+	method reflect.Method
+	args   reflect.Type // argument struct, nil if the field has no arguments
+	hasErr bool         // the method returns an error
+}
+
+// newSchema creates a schema from the Go types of its object types, the
+// query value being the value of the Query type
+func newSchema(query interface{}, types map[string]interface{}) *schema {
+	s := &schema{query: query, types: make(map[reflect.Type]*objectType)}
+	for name, v := range types {
+		typ := reflect.TypeOf(v)
+		obj := &objectType{name: name, fields: make(map[string]*resolver)}
+		for i := 0; i < typ.NumMethod(); i++ {
+			m := typ.Method(i)
+			mt := m.Type
+			if mt.NumIn() < 2 || mt.NumIn() > 3 || mt.In(1) != contextType {
+				continue
+			}
+			if mt.NumOut() < 1 || mt.NumOut() > 2 || (mt.NumOut() == 2 && mt.Out(1) != errorType) {
+				panic(fmt.Sprintf("invalid results of resolver %s.%s", name, m.Name))
+			}
+			res := &resolver{method: m, hasErr: mt.NumOut() == 2}
+			if mt.NumIn() == 3 {
+				if mt.In(2).Kind() != reflect.Struct {
+					panic(fmt.Sprintf("invalid arguments of resolver %s.%s", name, m.Name))
+				}
+				res.args = mt.In(2)
+			}
+			obj.fields[fieldName(m.Name)] = res
+		}
+		s.types[typ] = obj
+	}
+	return s
+}
+
+// fieldName returns the GraphQL name of a Go method or struct field
+func fieldName(name string) string {
+	r, n := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[n:]
+}
+
+// orderedObject is an object of the response, whose fields are serialized
+// in the order of the query
+type orderedObject []objectEntry
+
+type objectEntry struct {
+	key   string
+	value interface{}
+}
+
+// MarshalJSON implements json.Marshaler
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(entry.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// execution is the state of the execution of a query
+type execution struct {
+	ctx    context.Context
+	schema *schema
+	doc    *document
+	vars   map[string]interface{}
+	errors []*QueryError
+}
+
+// exec executes the operation opName of a query document, which may be empty
+// if the document has a single operation, with the given variables
+func (s *schema) exec(ctx context.Context, query, opName string, vars map[string]interface{}) *Response {
+	doc, err := parseQuery(query)
+	if err != nil {
+		return &Response{Errors: []*QueryError{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(opName)
+	if err != nil {
+		return &Response{Errors: []*QueryError{{Message: err.Error()}}}
+	}
+	e := &execution{ctx: ctx, schema: s, doc: doc, vars: make(map[string]interface{})}
+	for _, v := range op.vars {
+		value, ok := vars[v.name]
+		if !ok {
+			value = v.def
+		}
+		if value == nil && v.nonNull {
+			return &Response{Errors: []*QueryError{{Message: fmt.Sprintf("variable $%s is required", v.name)}}}
+		}
+		e.vars[v.name] = value
+	}
+	root := reflect.ValueOf(s.query)
+	data := e.executeSelections(root, s.types[root.Type()], op.selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// operation returns the operation of the document named name
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, fmt.Errorf("operation name required for documents with multiple operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+func (e *execution) addError(path []interface{}, err error) {
+	e.errors = append(e.errors, &QueryError{Message: err.Error(), Path: path})
+}
+
+// fieldGroup is the fields of a selection set with the same response key
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collectFields groups the fields selected on an object of type typeName by
+// response key, expanding the fragments which apply to the type
+func (e *execution) collectFields(typeName string, sels []selection, groups []*fieldGroup, visited map[string]bool) ([]*fieldGroup, error) {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *field:
+			if ok, err := e.included(sel.directives); !ok || err != nil {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var group *fieldGroup
+			for _, g := range groups {
+				if g.key == sel.key() {
+					group = g
+					break
+				}
+			}
+			if group == nil {
+				group = &fieldGroup{key: sel.key()}
+				groups = append(groups, group)
+			}
+			group.fields = append(group.fields, sel)
+
+		case *fragmentSpread:
+			if ok, err := e.included(sel.directives); !ok || err != nil {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if visited[sel.name] {
+				continue
+			}
+			visited[sel.name] = true
+			frag, ok := e.doc.fragments[sel.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %s", sel.name)
+			}
+			if frag.typeCond != typeName {
+				continue
+			}
+			var err error
+			if groups, err = e.collectFields(typeName, frag.selections, groups, visited); err != nil {
+				return nil, err
+			}
+
+		case *inlineFragment:
+			if ok, err := e.included(sel.directives); !ok || err != nil {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if sel.typeCond != "" && sel.typeCond != typeName {
+				continue
+			}
+			var err error
+			if groups, err = e.collectFields(typeName, sel.selections, groups, visited); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return groups, nil
+}
+
+// included evaluates the @skip and @include directives of a selection
+func (e *execution) included(dirs []*directive) (bool, error) {
+	for _, dir := range dirs {
+		if dir.name != "skip" && dir.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", dir.name)
+		}
+		if len(dir.args) != 1 || dir.args[0].name != "if" {
+			return false, fmt.Errorf("directive @%s requires an if argument", dir.name)
+		}
+		value, err := e.resolveValue(dir.args[0].value)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := value.(bool)
+		if !ok {
+			return false, fmt.Errorf("argument if of directive @%s must be a boolean", dir.name)
+		}
+		if cond == (dir.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// executeSelections resolves the fields selected on obj
+func (e *execution) executeSelections(obj reflect.Value, typ *objectType, sels []selection, path []interface{}) interface{} {
+	groups, err := e.collectFields(typ.name, sels, nil, make(map[string]bool))
+	if err != nil {
+		e.addError(path, err)
+		return nil
+	}
+	result := make(orderedObject, 0, len(groups))
+	for _, group := range groups {
+		fieldPath := append(append([]interface{}{}, path...), group.key)
+		value, err := e.executeField(obj, typ, group, fieldPath)
+		if err != nil {
+			e.addError(fieldPath, err)
+			value = nil
+		}
+		result = append(result, objectEntry{key: group.key, value: value})
+	}
+	return result
+}
+
+// executeField resolves a field of obj and completes its value with the
+// selections of the fields of the group
+func (e *execution) executeField(obj reflect.Value, typ *objectType, group *fieldGroup, path []interface{}) (value interface{}, err error) {
+	f := group.fields[0]
+	if f.name == "__typename" {
+		return typ.name, nil
+	}
+	res, ok := typ.fields[f.name]
+	if !ok {
+		return nil, fmt.Errorf("cannot query field %q on type %q", f.name, typ.name)
+	}
+	in := []reflect.Value{obj, reflect.ValueOf(e.ctx)}
+	if res.args != nil {
+		args, err := e.buildArgs(res.args, f.args)
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, args)
+	} else if len(f.args) > 0 {
+		return nil, fmt.Errorf("unknown argument %q of field %q", f.args[0].name, f.name)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("GraphQL resolver panicked", "type", typ.name, "field", f.name, "err", r)
+			value, err = nil, fmt.Errorf("internal error")
+		}
+	}()
+	out := res.method.Func.Call(in)
+	if res.hasErr && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	var sels []selection
+	for _, f := range group.fields {
+		sels = append(sels, f.selections...)
+	}
+	return e.complete(out[0], f, sels, path)
+}
+
+// complete converts a resolved value into the value of the response
+func (e *execution) complete(v reflect.Value, f *field, sels []selection, path []interface{}) (interface{}, error) {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface || v.Kind() == reflect.Slice) && v.IsNil() {
+		return nil, nil
+	}
+	if typ, ok := e.schema.types[v.Type()]; ok {
+		if len(sels) == 0 {
+			return nil, fmt.Errorf("field %q of type %q must have a selection of subfields", f.name, typ.name)
+		}
+		return e.executeSelections(v, typ, sels, path), nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			item, err := e.complete(v.Index(i), f, sels, append(append([]interface{}{}, path...), i))
+			if err != nil {
+				e.addError(append(append([]interface{}{}, path...), i), err)
+			}
+			list[i] = item
+		}
+		return list, nil
+	}
+	if len(sels) > 0 {
+		return nil, fmt.Errorf("field %q must not have a selection since it is a scalar", f.name)
+	}
+	return v.Interface(), nil
+}
+
+// buildArgs converts the arguments of a field into its argument struct,
+// converting values through JSON so that the types of the struct parse
+// their values as they do in the JSON-RPC API
+func (e *execution) buildArgs(typ reflect.Type, args []*argument) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	given := make(map[string]bool)
+	for _, arg := range args {
+		i := argIndex(typ, arg.name)
+		if i < 0 {
+			return v, fmt.Errorf("unknown argument %q", arg.name)
+		}
+		value, err := e.resolveValue(arg.value)
+		if err != nil {
+			return v, err
+		}
+		if value == nil {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return v, err
+		}
+		if err := json.Unmarshal(data, v.Field(i).Addr().Interface()); err != nil {
+			return v, fmt.Errorf("invalid argument %q: %v", arg.name, err)
+		}
+		given[arg.name] = true
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		switch typ.Field(i).Type.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			continue // optional
+		}
+		if name := argName(typ.Field(i)); !given[name] {
+			return v, fmt.Errorf("argument %q is required", name)
+		}
+	}
+	return v, nil
+}
+
+// argName returns the GraphQL name of a field of an argument struct
+func argName(f reflect.StructField) string {
+	if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" {
+		return tag
+	}
+	return fieldName(f.Name)
+}
+
+func argIndex(typ reflect.Type, name string) int {
+	for i := 0; i < typ.NumField(); i++ {
+		if argName(typ.Field(i)) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveValue replaces the variables of a value of the query with their
+// values and enum values with strings
+func (e *execution) resolveValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case variable:
+		v, ok := e.vars[string(value)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", value)
+		}
+		return v, nil
+	case enumValue:
+		return string(value), nil
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			v, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			v, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = v
+		}
+		return obj, nil
+	}
+	return value, nil
+}
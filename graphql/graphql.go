@@ -0,0 +1,680 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to the chain data of a node.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxBlocks is the maximum number of blocks a blocks query may return
+	maxBlocks = 1000
+
+	// maxLogBlocks is the maximum number of blocks a logs query may scan
+	maxLogBlocks = 10000
+)
+
+var errBlockNotFound = errors.New("block not found")
+
+// Account is an account at a given block
+type Account struct {
+	backend     ethapi.Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+func (a *Account) state(ctx context.Context) (*state.StateDB, error) {
+	st, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, errBlockNotFound
+	}
+	return st, nil
+}
+
+func (a *Account) Address(ctx context.Context) common.Address {
+	return a.address
+}
+
+func (a *Account) Balance(ctx context.Context) (*hexutil.Big, error) {
+	st, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(st.GetBalance(a.address)), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (uint64, error) {
+	st, err := a.state(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return st.GetNonce(a.address), nil
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	st, err := a.state(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetCode(a.address), nil
+}
+
+type storageArgs struct {
+	Slot common.Hash
+}
+
+func (a *Account) Storage(ctx context.Context, args storageArgs) (common.Hash, error) {
+	st, err := a.state(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return st.GetState(a.address, args.Slot), nil
+}
+
+// blockNumberArgs selects the block of the state of an account, the latest
+// block if Block is not set
+type blockNumberArgs struct {
+	Block *uint64
+}
+
+func (args blockNumberArgs) number() rpc.BlockNumber {
+	if args.Block == nil {
+		return rpc.LatestBlockNumber
+	}
+	return rpc.BlockNumber(*args.Block)
+}
+
+// Log is a log emitted by a transaction
+type Log struct {
+	backend ethapi.Backend
+	tx      *Transaction
+	log     *types.Log
+}
+
+func (l *Log) Index(ctx context.Context) uint64 {
+	return uint64(l.log.Index)
+}
+
+func (l *Log) Account(ctx context.Context, args blockNumberArgs) *Account {
+	return &Account{backend: l.backend, address: l.log.Address, blockNumber: args.number()}
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return l.log.Data
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.tx
+}
+
+// Transaction is a transaction of a block, or a pending transaction if its
+// block is nil
+type Transaction struct {
+	backend ethapi.Backend
+	tx      *types.Transaction
+	block   *Block
+	index   uint64
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.tx.Hash()
+}
+
+func (t *Transaction) Nonce(ctx context.Context) uint64 {
+	return t.tx.Nonce()
+}
+
+func (t *Transaction) Index(ctx context.Context) *uint64 {
+	if t.block == nil {
+		return nil
+	}
+	return &t.index
+}
+
+func (t *Transaction) From(ctx context.Context, args blockNumberArgs) (*Account, error) {
+	var signer types.Signer = types.FrontierSigner{}
+	if t.block != nil {
+		signer = types.MakeSigner(t.backend.ChainConfig(), t.block.block.Number())
+	} else if t.tx.Protected() {
+		signer = types.NewEIP155Signer(t.tx.ChainId())
+	}
+	from, err := types.Sender(signer, t.tx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{backend: t.backend, address: from, blockNumber: args.number()}, nil
+}
+
+func (t *Transaction) To(ctx context.Context, args blockNumberArgs) *Account {
+	to := t.tx.To()
+	if to == nil {
+		return nil
+	}
+	return &Account{backend: t.backend, address: *to, blockNumber: args.number()}
+}
+
+func (t *Transaction) Value(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(t.tx.Value())
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(t.tx.GasPrice())
+}
+
+func (t *Transaction) Gas(ctx context.Context) uint64 {
+	return t.tx.Gas()
+}
+
+func (t *Transaction) InputData(ctx context.Context) hexutil.Bytes {
+	return t.tx.Data()
+}
+
+func (t *Transaction) Block(ctx context.Context) *Block {
+	return t.block
+}
+
+// receipt returns the receipt of the transaction, nil if it is pending
+func (t *Transaction) receipt(ctx context.Context) (*types.Receipt, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.getReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(receipts)) {
+		return nil, errors.New("receipt not found")
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*uint64, error) {
+	receipt, err := t.receipt(ctx)
+	if receipt == nil || len(receipt.PostState) != 0 {
+		// pending or pre-Byzantium transaction
+		return nil, err
+	}
+	return &receipt.Status, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*uint64, error) {
+	receipt, err := t.receipt(ctx)
+	if receipt == nil {
+		return nil, err
+	}
+	return &receipt.GasUsed, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*uint64, error) {
+	receipt, err := t.receipt(ctx)
+	if receipt == nil {
+		return nil, err
+	}
+	return &receipt.CumulativeGasUsed, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args blockNumberArgs) (*Account, error) {
+	receipt, err := t.receipt(ctx)
+	if receipt == nil || t.tx.To() != nil {
+		return nil, err
+	}
+	return &Account{backend: t.backend, address: receipt.ContractAddress, blockNumber: args.number()}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) ([]*Log, error) {
+	receipt, err := t.receipt(ctx)
+	if receipt == nil {
+		return nil, err
+	}
+	logs := make([]*Log, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		logs[i] = &Log{backend: t.backend, tx: t, log: log}
+	}
+	return logs, nil
+}
+
+// Block is a block of the chain, or an ommer if it has no transactions
+type Block struct {
+	backend  ethapi.Backend
+	block    *types.Block
+	receipts types.Receipts
+}
+
+func (b *Block) getReceipts(ctx context.Context) (types.Receipts, error) {
+	if b.receipts == nil {
+		receipts, err := b.backend.GetReceipts(ctx, b.block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		b.receipts = receipts
+	}
+	return b.receipts, nil
+}
+
+func (b *Block) Number(ctx context.Context) uint64 {
+	return b.block.NumberU64()
+}
+
+func (b *Block) Hash(ctx context.Context) common.Hash {
+	return b.block.Hash()
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	if b.block.NumberU64() == 0 {
+		return nil, nil
+	}
+	return blockByHash(ctx, b.backend, b.block.ParentHash())
+}
+
+func (b *Block) Nonce(ctx context.Context) types.BlockNonce {
+	return b.block.Header().Nonce
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) common.Hash {
+	return b.block.TxHash()
+}
+
+func (b *Block) StateRoot(ctx context.Context) common.Hash {
+	return b.block.Root()
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) common.Hash {
+	return b.block.ReceiptHash()
+}
+
+func (b *Block) Miner(ctx context.Context, args blockNumberArgs) *Account {
+	return &Account{backend: b.backend, address: b.block.Coinbase(), blockNumber: args.number()}
+}
+
+func (b *Block) ExtraData(ctx context.Context) hexutil.Bytes {
+	return b.block.Extra()
+}
+
+func (b *Block) GasLimit(ctx context.Context) uint64 {
+	return b.block.GasLimit()
+}
+
+func (b *Block) GasUsed(ctx context.Context) uint64 {
+	return b.block.GasUsed()
+}
+
+func (b *Block) Timestamp(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(b.block.Time())
+}
+
+func (b *Block) LogsBloom(ctx context.Context) types.Bloom {
+	return b.block.Bloom()
+}
+
+func (b *Block) MixHash(ctx context.Context) common.Hash {
+	return b.block.MixDigest()
+}
+
+func (b *Block) Difficulty(ctx context.Context) *hexutil.Big {
+	return (*hexutil.Big)(b.block.Difficulty())
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (*hexutil.Big, error) {
+	td := b.backend.GetTd(b.block.Hash())
+	if td == nil {
+		return nil, fmt.Errorf("total difficulty of block %x not found", b.block.Hash())
+	}
+	return (*hexutil.Big)(td), nil
+}
+
+func (b *Block) OmmerHash(ctx context.Context) common.Hash {
+	return b.block.UncleHash()
+}
+
+func (b *Block) OmmerCount(ctx context.Context) uint64 {
+	return uint64(len(b.block.Uncles()))
+}
+
+func (b *Block) Ommers(ctx context.Context) []*Block {
+	ommers := make([]*Block, len(b.block.Uncles()))
+	for i, header := range b.block.Uncles() {
+		ommers[i] = &Block{backend: b.backend, block: types.NewBlockWithHeader(header)}
+	}
+	return ommers
+}
+
+func (b *Block) TransactionCount(ctx context.Context) uint64 {
+	return uint64(len(b.block.Transactions()))
+}
+
+func (b *Block) transaction(index uint64) *Transaction {
+	return &Transaction{backend: b.backend, tx: b.block.Transactions()[index], block: b, index: index}
+}
+
+// pageArgs selects First items of a list after skipping the first Skip,
+// all the remaining items if First is not set
+type pageArgs struct {
+	Skip  *uint64
+	First *uint64
+}
+
+func (b *Block) Transactions(ctx context.Context, args pageArgs) []*Transaction {
+	start, end := uint64(0), uint64(len(b.block.Transactions()))
+	if args.Skip != nil && *args.Skip < end {
+		start = *args.Skip
+	} else if args.Skip != nil {
+		start = end
+	}
+	if args.First != nil && *args.First < end-start {
+		end = start + *args.First
+	}
+	txs := make([]*Transaction, 0, end-start)
+	for i := start; i < end; i++ {
+		txs = append(txs, b.transaction(i))
+	}
+	return txs
+}
+
+type indexArgs struct {
+	Index uint64
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args indexArgs) *Transaction {
+	if args.Index >= uint64(len(b.block.Transactions())) {
+		return nil
+	}
+	return b.transaction(args.Index)
+}
+
+// logFilterArgs selects logs emitted by any of Addresses, all addresses if
+// it is empty, whose topics match Topics, where an empty list of topics at a
+// position matches any topic
+type logFilterArgs struct {
+	Addresses []common.Address
+	Topics    [][]common.Hash
+}
+
+type blockLogsArgs struct {
+	Filter logFilterArgs
+}
+
+func (b *Block) Logs(ctx context.Context, args blockLogsArgs) ([]*Log, error) {
+	return b.filterLogs(ctx, args.Filter)
+}
+
+// filterLogs returns the logs of the block selected by a filter
+func (b *Block) filterLogs(ctx context.Context, filter logFilterArgs) ([]*Log, error) {
+	if !bloomMatches(b.block.Bloom(), filter) {
+		return nil, nil
+	}
+	receipts, err := b.getReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var logs []*Log
+	for i, receipt := range receipts {
+		var tx *Transaction
+		for _, log := range receipt.Logs {
+			if !logMatches(log, filter) {
+				continue
+			}
+			if tx == nil {
+				tx = b.transaction(uint64(i))
+			}
+			logs = append(logs, &Log{backend: b.backend, tx: tx, log: log})
+		}
+	}
+	return logs, nil
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) *Account {
+	return &Account{backend: b.backend, address: args.Address, blockNumber: rpc.BlockNumber(b.block.NumberU64())}
+}
+
+// bloomMatches reports whether a block whose logs bloom is bloom may contain
+// logs selected by a filter
+func bloomMatches(bloom types.Bloom, filter logFilterArgs) bool {
+	if len(filter.Addresses) > 0 {
+		included := false
+		for _, addr := range filter.Addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, topics := range filter.Topics {
+		included := len(topics) == 0
+		for _, topic := range topics {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// logMatches reports whether a filter selects a log
+func logMatches(log *types.Log, filter logFilterArgs) bool {
+	if len(filter.Addresses) > 0 {
+		included := false
+		for _, addr := range filter.Addresses {
+			if log.Address == addr {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(filter.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, topics := range filter.Topics {
+		included := len(topics) == 0
+		for _, topic := range topics {
+			if log.Topics[i] == topic {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+func blockByHash(ctx context.Context, backend ethapi.Backend, hash common.Hash) (*Block, error) {
+	block, err := backend.GetBlock(ctx, hash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return &Block{backend: backend, block: block}, nil
+}
+
+func blockByNumber(ctx context.Context, backend ethapi.Backend, number rpc.BlockNumber) (*Block, error) {
+	block, err := backend.BlockByNumber(ctx, number)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return &Block{backend: backend, block: block}, nil
+}
+
+// Resolver is the Query type of the schema
+type Resolver struct {
+	backend ethapi.Backend
+}
+
+type blockArgs struct {
+	Number *uint64
+	Hash   *common.Hash
+}
+
+// Block returns a block by number or hash, the latest block if neither is
+// given
+func (r *Resolver) Block(ctx context.Context, args blockArgs) (*Block, error) {
+	switch {
+	case args.Number != nil && args.Hash != nil:
+		return nil, errors.New("only one of number or hash may be given")
+	case args.Hash != nil:
+		return blockByHash(ctx, r.backend, *args.Hash)
+	case args.Number != nil:
+		return blockByNumber(ctx, r.backend, rpc.BlockNumber(*args.Number))
+	}
+	return blockByNumber(ctx, r.backend, rpc.LatestBlockNumber)
+}
+
+type blocksArgs struct {
+	From uint64
+	To   *uint64
+}
+
+// Blocks returns the blocks from From to To, up to the latest block
+func (r *Resolver) Blocks(ctx context.Context, args blocksArgs) ([]*Block, error) {
+	head, err := r.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	to := head.Number.Uint64()
+	if args.To != nil && *args.To < to {
+		to = *args.To
+	}
+	if args.From > to {
+		return []*Block{}, nil
+	}
+	if to-args.From >= maxBlocks {
+		return nil, fmt.Errorf("query returns more than %d blocks", maxBlocks)
+	}
+	blocks := make([]*Block, 0, to-args.From+1)
+	for n := args.From; n <= to; n++ {
+		block, err := blockByNumber(ctx, r.backend, rpc.BlockNumber(n))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+type transactionArgs struct {
+	Hash common.Hash
+}
+
+// Transaction returns a transaction of the chain or the transaction pool
+func (r *Resolver) Transaction(ctx context.Context, args transactionArgs) (*Transaction, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(r.backend.ChainDb(), args.Hash)
+	if tx == nil {
+		if tx = r.backend.GetPoolTransaction(args.Hash); tx == nil {
+			return nil, nil
+		}
+		return &Transaction{backend: r.backend, tx: tx}, nil
+	}
+	block, err := blockByHash(ctx, r.backend, blockHash)
+	if block == nil {
+		if err == nil {
+			err = errBlockNotFound
+		}
+		return nil, err
+	}
+	return &Transaction{backend: r.backend, tx: tx, block: block, index: index}, nil
+}
+
+type logsArgs struct {
+	Filter struct {
+		FromBlock *uint64
+		ToBlock   *uint64
+		logFilterArgs
+	}
+}
+
+// Logs returns the logs selected by a filter in the blocks from FromBlock
+// to ToBlock, the latest block if either is not set
+func (r *Resolver) Logs(ctx context.Context, args logsArgs) ([]*Log, error) {
+	head, err := r.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	from, to := head.Number.Uint64(), head.Number.Uint64()
+	if args.Filter.FromBlock != nil {
+		from = *args.Filter.FromBlock
+	}
+	if args.Filter.ToBlock != nil && *args.Filter.ToBlock < to {
+		to = *args.Filter.ToBlock
+	}
+	if from <= to && to-from >= maxLogBlocks {
+		return nil, fmt.Errorf("query scans more than %d blocks", maxLogBlocks)
+	}
+	logs := []*Log{}
+	for n := from; n <= to; n++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := r.backend.HeaderByNumber(ctx, rpc.BlockNumber(n))
+		if header == nil || err != nil {
+			return nil, err
+		}
+		if !bloomMatches(header.Bloom, args.Filter.logFilterArgs) {
+			continue
+		}
+		block, err := blockByHash(ctx, r.backend, header.Hash())
+		if block == nil || err != nil {
+			return nil, err
+		}
+		blockLogs, err := block.filterLogs(ctx, args.Filter.logFilterArgs)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, blockLogs...)
+	}
+	return logs, nil
+}
+
+type accountArgs struct {
+	Address     common.Address
+	BlockNumber *uint64
+}
+
+// Account returns an account at a block, the latest block if BlockNumber is
+// not set
+func (r *Resolver) Account(ctx context.Context, args accountArgs) *Account {
+	return &Account{backend: r.backend, address: args.Address, blockNumber: blockNumberArgs{Block: args.BlockNumber}.number()}
+}
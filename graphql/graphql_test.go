@@ -0,0 +1,447 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(1000000000000000000)
+	testTo      = common.HexToAddress("0x0000000000000000000000000000000000000042")
+
+	// testCode creates a contract emitting a log with topic 1 and no code
+	testCode = common.FromHex("0x600160006000a100")
+)
+
+// testBackend serves the chain data of a blockchain
+type testBackend struct {
+	ethapi.Backend
+	db    ethdb.Database
+	chain *core.BlockChain
+	pool  map[common.Hash]*types.Transaction
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, _ := b.HeaderByNumber(ctx, number)
+	if header == nil {
+		return nil, nil, nil
+	}
+	st, err := b.chain.StateAt(header.Root)
+	return st, header, err
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if number := rawdb.ReadHeaderNumber(b.db, hash); number != nil {
+		return rawdb.ReadReceipts(b.db, hash, *number), nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) GetTd(hash common.Hash) *big.Int {
+	return b.chain.GetTdByHash(hash)
+}
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	return b.pool[hash]
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func (b *testBackend) ChainDb() ethdb.Database {
+	return b.db
+}
+
+// newTestBackend creates a chain of 3 blocks, the first containing 3
+// transfers, the second the creation of a contract emitting a log, and a
+// pending transaction in the pool
+func newTestBackend(t *testing.T) (*testBackend, []*types.Transaction) {
+	db := ethdb.NewMemDatabase()
+	genesis := (&core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	}).MustCommit(db)
+
+	signer := types.HomesteadSigner{}
+	var txs []*types.Transaction
+	sign := func(tx *types.Transaction) *types.Transaction {
+		tx, err := types.SignTx(tx, signer, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+		return tx
+	}
+	engine := ethash.NewFaker()
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 3, func(i int, gen *core.BlockGen) {
+		switch i {
+		case 0:
+			for j := 0; j < 3; j++ {
+				gen.AddTx(sign(types.NewTransaction(gen.TxNonce(testAddr), testTo, big.NewInt(int64(j+1)), 21000, big.NewInt(1), nil)))
+			}
+		case 1:
+			gen.AddTx(sign(types.NewContractCreation(gen.TxNonce(testAddr), new(big.Int), 100000, big.NewInt(1), testCode)))
+		}
+	})
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	pending := sign(types.NewTransaction(4, testTo, big.NewInt(5), 21000, big.NewInt(1), nil))
+	return &testBackend{
+		db:    db,
+		chain: chain,
+		pool:  map[common.Hash]*types.Transaction{pending.Hash(): pending},
+	}, txs
+}
+
+// query posts a query to a handler and decodes its response
+func query(t *testing.T, h http.Handler, query string, vars map[string]interface{}) (map[string]interface{}, []*QueryError) {
+	body, err := json.Marshal(&request{Query: query, Variables: vars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body)
+	}
+	var res struct {
+		Data   map[string]interface{}
+		Errors []*QueryError
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res.Data, res.Errors
+}
+
+// get returns the value at a path of the response
+func get(v interface{}, path ...interface{}) interface{} {
+	for _, key := range path {
+		switch key := key.(type) {
+		case string:
+			obj, _ := v.(map[string]interface{})
+			v = obj[key]
+		case int:
+			list, _ := v.([]interface{})
+			if key >= len(list) {
+				return nil
+			}
+			v = list[key]
+		}
+	}
+	return v
+}
+
+func TestBlockQuery(t *testing.T) {
+	backend, txs := newTestBackend(t)
+	h := NewHandler(backend)
+
+	data, errs := query(t, h, `{
+		block(number: 1) {
+			number
+			parent { number hash }
+			transactionCount
+			transactions(skip: 1, first: 1) { hash index value from { address } }
+			last: transactionAt(index: 2) { hash }
+			totalDifficulty
+			__typename
+		}
+		latest: block { number }
+	}`, nil)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	genesis := backend.chain.Genesis().Hash()
+	for _, c := range []struct {
+		path []interface{}
+		want interface{}
+	}{
+		{[]interface{}{"block", "number"}, float64(1)},
+		{[]interface{}{"block", "parent", "number"}, float64(0)},
+		{[]interface{}{"block", "parent", "hash"}, genesis.Hex()},
+		{[]interface{}{"block", "transactionCount"}, float64(3)},
+		{[]interface{}{"block", "transactions", 0, "hash"}, txs[1].Hash().Hex()},
+		{[]interface{}{"block", "transactions", 0, "index"}, float64(1)},
+		{[]interface{}{"block", "transactions", 0, "value"}, "0x2"},
+		{[]interface{}{"block", "transactions", 0, "from", "address"}, strings.ToLower(testAddr.Hex())},
+		{[]interface{}{"block", "last", "hash"}, txs[2].Hash().Hex()},
+		{[]interface{}{"block", "__typename"}, "Block"},
+		{[]interface{}{"latest", "number"}, float64(3)},
+	} {
+		if got := get(data, c.path...); got != c.want {
+			t.Errorf("%v: got %v, want %v", c.path, got, c.want)
+		}
+	}
+	if n := len(get(data, "block", "transactions").([]interface{})); n != 1 {
+		t.Errorf("expected a page of 1 transaction, got %d", n)
+	}
+	if td, _ := get(data, "block", "totalDifficulty").(string); td == "" {
+		t.Error("expected the total difficulty of the block")
+	}
+
+	data, errs = query(t, h, `{ blocks(from: 1, to: 10) { number } }`, nil)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	if blocks := get(data, "blocks").([]interface{}); len(blocks) != 3 || get(blocks, 2, "number") != float64(3) {
+		t.Fatalf("unexpected blocks %v", blocks)
+	}
+}
+
+func TestTransactionQuery(t *testing.T) {
+	backend, txs := newTestBackend(t)
+	h := NewHandler(backend)
+
+	const q = `query($hash: Bytes32!) {
+		transaction(hash: $hash) {
+			index status gasUsed
+			block { number }
+			createdContract { address }
+			logs { index topics account { address } transaction { hash } }
+		}
+	}`
+	creation := txs[3]
+	data, errs := query(t, h, q, map[string]interface{}{"hash": creation.Hash()})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	contract := crypto.CreateAddress(testAddr, creation.Nonce())
+	for _, c := range []struct {
+		path []interface{}
+		want interface{}
+	}{
+		{[]interface{}{"transaction", "index"}, float64(0)},
+		{[]interface{}{"transaction", "status"}, float64(1)},
+		{[]interface{}{"transaction", "block", "number"}, float64(2)},
+		{[]interface{}{"transaction", "createdContract", "address"}, strings.ToLower(contract.Hex())},
+		{[]interface{}{"transaction", "logs", 0, "topics", 0}, common.BigToHash(big.NewInt(1)).Hex()},
+		{[]interface{}{"transaction", "logs", 0, "account", "address"}, strings.ToLower(contract.Hex())},
+		{[]interface{}{"transaction", "logs", 0, "transaction", "hash"}, creation.Hash().Hex()},
+	} {
+		if got := get(data, c.path...); got != c.want {
+			t.Errorf("%v: got %v, want %v", c.path, got, c.want)
+		}
+	}
+
+	// pending transactions have no block and no receipt
+	data, errs = query(t, h, q, map[string]interface{}{"hash": txs[4].Hash()})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	tx := get(data, "transaction").(map[string]interface{})
+	if tx["index"] != nil || tx["block"] != nil || tx["status"] != nil || tx["logs"] != nil {
+		t.Fatalf("unexpected pending transaction %v", tx)
+	}
+
+	data, errs = query(t, h, q, map[string]interface{}{"hash": common.Hash{}})
+	if len(errs) != 0 || data["transaction"] != nil {
+		t.Fatalf("expected no transaction, got %v %+v", data, errs)
+	}
+}
+
+func TestLogsQuery(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	h := NewHandler(backend)
+
+	const q = `query($filter: FilterCriteria!) { logs(filter: $filter) { transaction { block { number } } } }`
+	topic := common.BigToHash(big.NewInt(1))
+	tests := []struct {
+		filter map[string]interface{}
+		count  int
+	}{
+		{map[string]interface{}{"fromBlock": 0}, 1},
+		{map[string]interface{}{"fromBlock": 0, "topics": [][]common.Hash{{topic}}}, 1},
+		{map[string]interface{}{"fromBlock": 0, "topics": [][]common.Hash{{common.Hash{}}}}, 0},
+		{map[string]interface{}{"fromBlock": 0, "addresses": []common.Address{testTo}}, 0},
+		{map[string]interface{}{"fromBlock": 3}, 0},
+		{map[string]interface{}{"fromBlock": 0, "toBlock": 1}, 0},
+	}
+	for i, test := range tests {
+		data, errs := query(t, h, q, map[string]interface{}{"filter": test.filter})
+		if len(errs) != 0 {
+			t.Fatalf("test %d: unexpected errors %+v", i, errs)
+		}
+		logs := get(data, "logs").([]interface{})
+		if len(logs) != test.count {
+			t.Errorf("test %d: expected %d logs, got %d", i, test.count, len(logs))
+		}
+		if len(logs) > 0 && get(logs, 0, "transaction", "block", "number") != float64(2) {
+			t.Errorf("test %d: unexpected log %v", i, logs[0])
+		}
+	}
+
+	_, errs := query(t, h, `{ logs(filter: {fromBlock: 0, toBlock: 100000}) { index } }`, nil)
+	if len(errs) != 0 {
+		t.Fatalf("expected the range to end at the latest block, got %+v", errs)
+	}
+}
+
+func TestAccountQuery(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	h := NewHandler(backend)
+
+	data, errs := query(t, h, `query($addr: Address!) {
+		genesis: account(address: $addr, blockNumber: 0) { balance transactionCount }
+		latest: account(address: $addr) { transactionCount }
+		to: block(number: 1) { account(address: "0x0000000000000000000000000000000000000042") { balance } }
+	}`, map[string]interface{}{"addr": testAddr})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	for _, c := range []struct {
+		path []interface{}
+		want interface{}
+	}{
+		{[]interface{}{"genesis", "balance"}, "0xde0b6b3a7640000"},
+		{[]interface{}{"genesis", "transactionCount"}, float64(0)},
+		{[]interface{}{"latest", "transactionCount"}, float64(4)},
+		{[]interface{}{"to", "account", "balance"}, "0x6"},
+	} {
+		if got := get(data, c.path...); got != c.want {
+			t.Errorf("%v: got %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	h := NewHandler(backend)
+
+	// field errors set the field to null and report its path
+	data, errs := query(t, h, `{ block(number: 1) { number foo } other: block(hash: "0x1234") { number } }`, nil)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %+v", errs)
+	}
+	if len(errs[0].Path) != 2 || errs[0].Path[0] != "block" || errs[0].Path[1] != "foo" {
+		t.Errorf("unexpected error %+v", errs[0])
+	}
+	if get(data, "block", "number") != float64(1) || data["other"] != nil {
+		t.Errorf("unexpected data %v", data)
+	}
+
+	for _, q := range []string{
+		`{ block(number: 1) { number `,
+		`{ block }`,
+		`{ block(number: 1) { number { hash } } }`,
+		`{ block(foo: 1) { number } }`,
+		`{ transaction { hash } }`,
+		`query($n: Long!) { block(number: $n) { number } }`,
+		`{ block(number: $n) { number } }`,
+		`{ block @skip { number } }`,
+		`{ ...missing }`,
+		`query A { block { number } } query B { block { number } }`,
+	} {
+		if _, errs := query(t, h, q, nil); len(errs) == 0 {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
+
+func TestFragments(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	h := NewHandler(backend)
+
+	data, errs := query(t, h, `query($skip: Boolean = true) {
+		block(number: 1) {
+			...header
+			... on Block { transactionCount }
+			... on Transaction { nonce }
+			hash @skip(if: $skip)
+			gasUsed @include(if: $skip)
+		}
+	}
+	fragment header on Block { number number }`, nil)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	block := get(data, "block").(map[string]interface{})
+	if len(block) != 3 || block["number"] != float64(1) || block["transactionCount"] != float64(3) || block["gasUsed"] == nil {
+		t.Fatalf("unexpected block %v", block)
+	}
+}
+
+func TestHTTPRequests(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	h := NewHandler(backend)
+
+	params := url.Values{"query": {`query($n: Long) { block(number: $n) { number } }`}, "variables": {`{"n": 2}`}}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/?"+params.Encode(), nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"data":{"block":{"number":2}}}`+"\n" {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/", nil),
+		httptest.NewRequest("POST", "/", bytes.NewReader([]byte("{"))),
+		httptest.NewRequest("PUT", "/", nil),
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			t.Errorf("%s %s: expected an error", req.Method, req.URL)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL query document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query of a document, mutations and subscriptions are not
+// supported
+type operation struct {
+	name       string
+	vars       []*varDef
+	selections []selection
+}
+
+// varDef is the definition of a variable of an operation
+type varDef struct {
+	name    string
+	nonNull bool        // the type of the variable is non-null
+	def     interface{} // default value, nil if none
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []selection
+}
+
+// key returns the key of the field in the response
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	typeCond   string // empty if the fragment applies to all types
+	directives []*directive
+	selections []selection
+}
+
+type fragment struct {
+	name       string
+	typeCond   string
+	selections []selection
+}
+
+type directive struct {
+	name string
+	args []*argument
+}
+
+type argument struct {
+	name  string
+	value interface{}
+}
+
+// Values of the query are nil, bool, int64, float64, string, enumValue,
+// variable, []interface{} and map[string]interface{}.
+type (
+	enumValue string
+	variable  string
+)
+
+// token kinds of the lexer
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind int
+	text string // punctuator, name or number, unquoted string value
+	pos  int
+}
+
+// lexer splits a query into tokens, skipping whitespace, commas and
+// comments, which are insignificant in GraphQL
+type lexer struct {
+	src string
+	pos int
+	tok token
+}
+
+// SyntaxError is an error in the syntax of a query
+type SyntaxError struct {
+	Pos int // byte offset in the query
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at offset %d: %s", e.Pos, e.Msg)
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) {
+	panic(&SyntaxError{Pos: l.tok.pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// next reads the next token
+func (l *lexer) next() {
+	l.skipIgnored()
+	start := l.pos
+	l.tok = token{pos: start}
+	if l.pos >= len(l.src) {
+		l.tok.kind = tokEOF
+		return
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		l.tok.kind, l.tok.text = tokPunct, "..."
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		l.pos++
+		l.tok.kind, l.tok.text = tokPunct, string(c)
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		l.tok.kind, l.tok.text = tokName, l.src[start:l.pos]
+	case c == '-' || isDigit(c):
+		l.readNumber()
+	case c == '"':
+		l.readString()
+	default:
+		l.errorf("unexpected character %q", c)
+	}
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\ufeff"):
+			l.pos += len("\ufeff")
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) readNumber() {
+	start := l.pos
+	l.tok.kind = tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	l.readDigits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.tok.kind = tokFloat
+		l.pos++
+		l.readDigits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.tok.kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		l.readDigits()
+	}
+	l.tok.text = l.src[start:l.pos]
+}
+
+func (l *lexer) readDigits() {
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.errorf("invalid number")
+	}
+}
+
+func (l *lexer) readString() {
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		l.readBlockString()
+		return
+	}
+	l.pos++
+	var b bytes.Buffer
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' || l.src[l.pos] == '\r' {
+			l.errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		if c == '"' {
+			l.pos++
+			break
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			l.pos++
+			continue
+		}
+		if l.pos+1 >= len(l.src) {
+			l.errorf("unterminated string")
+		}
+		switch esc := l.src[l.pos+1]; esc {
+		case '"', '\\', '/':
+			b.WriteByte(esc)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if l.pos+6 > len(l.src) {
+				l.errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+			if err != nil {
+				l.errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			l.pos += 4
+		default:
+			l.errorf("invalid escape sequence \\%c", esc)
+		}
+		l.pos += 2
+	}
+	l.tok.kind, l.tok.text = tokString, b.String()
+}
+
+// readBlockString reads a """ string, whose common indentation is removed
+func (l *lexer) readBlockString() {
+	l.pos += 3
+	end := strings.Index(l.src[l.pos:], `"""`)
+	for end > 0 && l.src[l.pos+end-1] == '\\' {
+		next := strings.Index(l.src[l.pos+end+3:], `"""`)
+		if next < 0 {
+			end = -1
+			break
+		}
+		end += 3 + next
+	}
+	if end < 0 {
+		l.errorf("unterminated string")
+	}
+	raw := strings.Replace(l.src[l.pos:l.pos+end], `\"""`, `"""`, -1)
+	l.pos += end + 3
+
+	lines := strings.Split(strings.Replace(raw, "\r\n", "\n", -1), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = ""
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	l.tok.kind, l.tok.text = tokString, strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser is a recursive descent parser of query documents
+type parser struct {
+	lexer
+}
+
+// parseQuery parses a query document
+func parseQuery(query string) (doc *document, err error) {
+	if !utf8.ValidString(query) {
+		return nil, &SyntaxError{Msg: "invalid UTF-8"}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			synErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = synErr
+		}
+	}()
+	p := &parser{lexer{src: query}}
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"):
+			doc.operations = append(doc.operations, &operation{selections: p.parseSelectionSet()})
+		case p.peekName("query"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case p.peekName("mutation") || p.peekName("subscription"):
+			p.errorf("%ss are not supported", p.tok.text)
+		case p.peekName("fragment"):
+			f := p.parseFragment()
+			if _, ok := doc.fragments[f.name]; ok {
+				p.errorf("duplicate fragment %s", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			p.errorf("unexpected %q", p.tok.text)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &SyntaxError{Msg: "no operation"}
+	}
+	return doc, nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == punct
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokName && p.tok.text == name
+}
+
+// skip reads the next token if the current one is punct
+func (p *parser) skip(punct string) bool {
+	if p.peek(punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) {
+	if !p.skip(punct) {
+		p.errorf("expected %q, found %q", punct, p.tok.text)
+	}
+}
+
+func (p *parser) parseName() string {
+	if p.tok.kind != tokName {
+		p.errorf("expected name, found %q", p.tok.text)
+	}
+	name := p.tok.text
+	p.next()
+	return name
+}
+
+func (p *parser) parseOperation() *operation {
+	p.next() // query
+	op := new(operation)
+	if p.tok.kind == tokName {
+		op.name = p.parseName()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			p.expect("$")
+			v := &varDef{name: p.parseName()}
+			p.expect(":")
+			v.nonNull = p.parseType()
+			if p.skip("=") {
+				v.def = p.parseValue(true)
+			}
+			op.vars = append(op.vars, v)
+		}
+	}
+	p.parseDirectives()
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+// parseType parses the type of a variable and returns whether it is
+// non-null. Types are checked when the values are converted to the
+// arguments of the resolvers.
+func (p *parser) parseType() bool {
+	if p.skip("[") {
+		p.parseType()
+		p.expect("]")
+	} else {
+		p.parseName()
+	}
+	return p.skip("!")
+}
+
+func (p *parser) parseFragment() *fragment {
+	p.next() // fragment
+	f := &fragment{name: p.parseName()}
+	if f.name == "on" {
+		p.errorf("invalid fragment name on")
+	}
+	if !p.peekName("on") {
+		p.errorf("expected type condition of fragment %s", f.name)
+	}
+	p.next()
+	f.typeCond = p.parseName()
+	p.parseDirectives()
+	f.selections = p.parseSelectionSet()
+	return f
+}
+
+func (p *parser) parseSelectionSet() []selection {
+	p.expect("{")
+	var sels []selection
+	for !p.skip("}") {
+		sels = append(sels, p.parseSelection())
+	}
+	if len(sels) == 0 {
+		p.errorf("empty selection set")
+	}
+	return sels
+}
+
+func (p *parser) parseSelection() selection {
+	if !p.skip("...") {
+		return p.parseField()
+	}
+	if p.tok.kind == tokName && !p.peekName("on") {
+		return &fragmentSpread{name: p.parseName(), directives: p.parseDirectives()}
+	}
+	frag := new(inlineFragment)
+	if p.peekName("on") {
+		p.next()
+		frag.typeCond = p.parseName()
+	}
+	frag.directives = p.parseDirectives()
+	frag.selections = p.parseSelectionSet()
+	return frag
+}
+
+func (p *parser) parseField() *field {
+	f := &field{name: p.parseName()}
+	if p.skip(":") {
+		f.alias, f.name = f.name, p.parseName()
+	}
+	f.args = p.parseArguments(false)
+	f.directives = p.parseDirectives()
+	if p.peek("{") {
+		f.selections = p.parseSelectionSet()
+	}
+	return f
+}
+
+func (p *parser) parseArguments(constant bool) []*argument {
+	var args []*argument
+	if p.skip("(") {
+		for !p.skip(")") {
+			arg := &argument{name: p.parseName()}
+			p.expect(":")
+			arg.value = p.parseValue(constant)
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+func (p *parser) parseDirectives() []*directive {
+	var dirs []*directive
+	for p.skip("@") {
+		dirs = append(dirs, &directive{name: p.parseName(), args: p.parseArguments(false)})
+	}
+	return dirs
+}
+
+// parseValue parses a value, which may not contain variables if constant
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		p.next()
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			p.tok = tok
+			p.errorf("invalid integer %s", tok.text)
+		}
+		return n
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			p.tok = tok
+			p.errorf("invalid float %s", tok.text)
+		}
+		return f
+	case tokString:
+		p.next()
+		return tok.text
+	case tokName:
+		p.next()
+		switch tok.text {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.text)
+	}
+	switch {
+	case p.skip("$"):
+		if constant {
+			p.tok = tok
+			p.errorf("unexpected variable")
+		}
+		return variable(p.parseName())
+	case p.skip("["):
+		list := []interface{}{}
+		for !p.skip("]") {
+			list = append(list, p.parseValue(constant))
+		}
+		return list
+	case p.skip("{"):
+		obj := make(map[string]interface{})
+		for !p.skip("}") {
+			name := p.parseName()
+			p.expect(":")
+			obj[name] = p.parseValue(constant)
+		}
+		return obj
+	}
+	p.errorf("unexpected %q", tok.text)
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	doc, err := parseQuery(`
+		# the transactions of a block
+		query Txs($number: Long = 10, $hash: Bytes32!) {
+			latest: block { number }
+			block(number: $number, hash: $hash) @include(if: true) {
+				...txs
+				... on Block { hash }
+			}
+		}
+
+		fragment txs on Block {
+			transactions(first: 2) { hash value }
+		}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.operations) != 1 || len(doc.fragments) != 1 {
+		t.Fatalf("expected an operation and a fragment, got %d and %d", len(doc.operations), len(doc.fragments))
+	}
+	op := doc.operations[0]
+	if op.name != "Txs" || len(op.vars) != 2 {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+	if v := op.vars[0]; v.name != "number" || v.nonNull || v.def != int64(10) {
+		t.Fatalf("unexpected variable %+v", v)
+	}
+	if v := op.vars[1]; v.name != "hash" || !v.nonNull || v.def != nil {
+		t.Fatalf("unexpected variable %+v", v)
+	}
+	if len(op.selections) != 2 {
+		t.Fatalf("expected 2 selections, got %d", len(op.selections))
+	}
+	if f := op.selections[0].(*field); f.key() != "latest" || f.name != "block" || len(f.selections) != 1 {
+		t.Fatalf("unexpected field %+v", f)
+	}
+	block := op.selections[1].(*field)
+	if len(block.args) != 2 || block.args[0].value != variable("number") || len(block.directives) != 1 {
+		t.Fatalf("unexpected field %+v", block)
+	}
+	if spread := block.selections[0].(*fragmentSpread); spread.name != "txs" {
+		t.Fatalf("unexpected fragment spread %+v", spread)
+	}
+	if frag := block.selections[1].(*inlineFragment); frag.typeCond != "Block" {
+		t.Fatalf("unexpected inline fragment %+v", frag)
+	}
+	if frag := doc.fragments["txs"]; frag.typeCond != "Block" || len(frag.selections) != 1 {
+		t.Fatalf("unexpected fragment %+v", frag)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		value string
+		want  interface{}
+	}{
+		{`12`, int64(12)},
+		{`-1.5e3`, float64(-1500)},
+		{`"a\"bé\n"`, "a\"bé\n"},
+		{`"""  block
+		    string """`, "  block\nstring "},
+		{`true`, true},
+		{`null`, nil},
+		{`LATEST`, enumValue("LATEST")},
+		{`$v`, variable("v")},
+		{`[1, "a", [] ]`, []interface{}{int64(1), "a", []interface{}{}}},
+		{`{a: 1, b: {c: $v}}`, map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": variable("v")}}},
+	}
+	for _, test := range tests {
+		doc, err := parseQuery(`{ f(arg: ` + test.value + `) }`)
+		if err != nil {
+			t.Errorf("%s: %v", test.value, err)
+			continue
+		}
+		value := doc.operations[0].selections[0].(*field).args[0].value
+		if !reflect.DeepEqual(value, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.value, value, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`{}`,
+		`{ block `,
+		`{ block(number: ) }`,
+		`{ f(a: "unterminated) }`,
+		`{ f(a: 1.) }`,
+		`{ f(a: 99999999999999999999) }`,
+		`query($v: Int = $w) { f }`,
+		`mutation { f }`,
+		`{ ...on }`,
+		`fragment f on Block { a } fragment f on Block { b } { f }`,
+		`{ f } %`,
+		"{ f(a: \"\xff\") }",
+	}
+	for _, query := range tests {
+		if _, err := parseQuery(query); err == nil {
+			t.Errorf("%q: expected a syntax error", query)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("%q: expected a syntax error, got %T", query, err)
+		}
+	}
+}
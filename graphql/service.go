@@ -0,0 +1,158 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	DefaultHost = "localhost" // Default host interface for the GraphQL server
+	DefaultPort = 8547        // Default TCP port for the GraphQL server
+
+	// maxRequestContentLength is the maximum size of the body of a request
+	maxRequestContentLength = 1024 * 512
+)
+
+// request is a GraphQL request, sent as the JSON body of a POST request or
+// as the parameters of a GET request
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler serves GraphQL queries over HTTP
+type Handler struct {
+	schema *schema
+}
+
+// NewHandler creates a handler of GraphQL queries of the chain data of a
+// backend
+func NewHandler(backend ethapi.Backend) *Handler {
+	return &Handler{
+		schema: newSchema(&Resolver{backend: backend}, map[string]interface{}{
+			"Query":       &Resolver{},
+			"Block":       &Block{},
+			"Transaction": &Transaction{},
+			"Log":         &Log{},
+			"Account":     &Account{},
+		}),
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if vars := params.Get("variables"); vars != "" {
+			if err := decodeJSON(vars, &req.Variables); err != nil {
+				http.Error(w, fmt.Sprintf("invalid variables: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxRequestContentLength))
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "missing query", http.StatusBadRequest)
+		return
+	}
+	response := h.schema.exec(r.Context(), req.Query, req.OperationName, req.Variables)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Warn("Failed to write GraphQL response", "err", err)
+	}
+}
+
+// decodeJSON decodes a JSON value keeping numbers as json.Number, so that
+// integers too large for a float64 keep their value
+func decodeJSON(data string, v interface{}) error {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Service is a node service serving the GraphQL API on an HTTP endpoint
+type Service struct {
+	endpoint string
+	cors     []string
+	vhosts   []string
+	handler  *Handler
+	listener net.Listener
+}
+
+// New creates a GraphQL service serving the chain data of a backend on
+// endpoint, with the CORS and virtual host policies of the HTTP-RPC server
+func New(backend ethapi.Backend, endpoint string, cors, vhosts []string) (*Service, error) {
+	return &Service{
+		endpoint: endpoint,
+		cors:     cors,
+		vhosts:   vhosts,
+		handler:  NewHandler(backend),
+	}, nil
+}
+
+// Protocols implements node.Service, returning no p2p protocols
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning no RPC APIs
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, starting the HTTP server of the endpoint
+func (s *Service) Start(server *p2p.Server) error {
+	listener, err := net.Listen("tcp", s.endpoint)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	go (&http.Server{Handler: rpc.NewHTTPHandlerStack(s.handler, s.cors, s.vhosts)}).Serve(listener)
+	log.Info("GraphQL endpoint opened", "url", fmt.Sprintf("http://%s", listener.Addr()))
+	return nil
+}
+
+// Stop implements node.Service, closing the HTTP server of the endpoint
+func (s *Service) Stop() error {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+		log.Info("GraphQL endpoint closed", "url", fmt.Sprintf("http://%s", s.endpoint))
+	}
+	return nil
+}
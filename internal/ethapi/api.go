@@ -66,6 +66,21 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// GasPricePercentiles returns the gas prices at the given percentiles of the
+// lowest prices paid in recent blocks, e.g. to offer slow, normal and fast
+// pricing tiers.
+func (s *PublicEthereumAPI) GasPricePercentiles(ctx context.Context, percentiles []int) ([]*hexutil.Big, error) {
+	prices, err := s.b.SuggestPrices(ctx, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*hexutil.Big, len(prices))
+	for i, price := range prices {
+		result[i] = (*hexutil.Big)(price)
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -441,6 +456,44 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 	return signature, nil
 }
 
+// SignTypedData calculates an EIP-712 signature for the given structured data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+//
+// Hardware wallets are sent the domain separator and message hash so the user
+// can verify them on the device, other wallets are unlocked with the passphrase
+// to sign the final hash.
+//
+// The signature's V value is 27 or 28, matching personal_sign.
+func (s *PrivateAccountAPI) SignTypedData(ctx context.Context, data accounts.TypedData, addr common.Address, passwd string) (hexutil.Bytes, error) {
+	domainSeparator, err := data.DomainSeparator()
+	if err != nil {
+		return nil, err
+	}
+	messageHash, err := data.MessageHash()
+	if err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the data with the wallet, passing the components if it needs them
+	var signature []byte
+	if signer, ok := wallet.(accounts.TypedDataSigner); ok {
+		signature, err = signer.SignTypedData(account, domainSeparator, messageHash)
+	} else {
+		signature, err = wallet.SignHashWithPassphrase(account, passwd, crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, messageHash))
+	}
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // EcRecover returns the address for the account that was used to create the signature.
 // Note, this function is compatible with eth_sign and personal_sign. As such it recovers
 // the address of:
@@ -603,6 +656,79 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the Merkle proof of an account and of some of its storage
+// slots returned by GetProof, as specified by EIP-1186.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the Merkle proof of a storage slot.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the Merkle proof of an account and of the given storage
+// slots of the account at the given block number, so that clients can verify
+// them against the state root of the block.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Accounts which do not exist have an empty storage trie and no code,
+	// their proofs prove their absence from the state trie
+	storageTrie := state.StorageTrie(address)
+	storageHash := types.EmptyRootHash
+	codeHash := crypto.Keccak256Hash(nil)
+	if storageTrie != nil {
+		storageHash = storageTrie.Hash()
+		codeHash = state.GetCodeHash(address)
+	}
+	storageProof := make([]StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		if storageTrie == nil {
+			storageProof[i] = StorageResult{Key: key, Value: new(hexutil.Big), Proof: []string{}}
+			continue
+		}
+		proof, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		value := state.GetState(address, common.HexToHash(key))
+		storageProof[i] = StorageResult{Key: key, Value: (*hexutil.Big)(value.Big()), Proof: toHexArray(proof)}
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexArray(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexArray encodes the nodes of a Merkle proof as hex strings.
+func toHexArray(proof [][]byte) []string {
+	res := make([]string, len(proof))
+	for i, node := range proof {
+		res[i] = hexutil.Encode(node)
+	}
+	return res
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -1046,8 +1172,52 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
-	receipt := receipts[index]
+	return marshalReceipt(receipts[index], blockHash, blockNumber, tx, index), nil
+}
+
+// GetBlockReceipts returns the receipts of all the transactions in the block
+// with the given number, in the order of the transactions.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNr rpc.BlockNumber) ([]map[string]interface{}, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		return nil, errors.New("receipts of the pending block are not available")
+	}
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, err
+	}
+	return s.blockReceipts(ctx, block)
+}
+
+// GetBlockReceiptsByHash returns the receipts of all the transactions in the
+// block with the given hash, in the order of the transactions.
+func (s *PublicTransactionPoolAPI) GetBlockReceiptsByHash(ctx context.Context, blockHash common.Hash) ([]map[string]interface{}, error) {
+	block, err := s.b.GetBlock(ctx, blockHash)
+	if block == nil {
+		return nil, err
+	}
+	return s.blockReceipts(ctx, block)
+}
+
+// blockReceipts retrieves and marshals the receipts of all the transactions in
+// a block.
+func (s *PublicTransactionPoolAPI) blockReceipts(ctx context.Context, block *types.Block) ([]map[string]interface{}, error) {
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts unavailable for block %d: have %d, want %d", block.NumberU64(), len(receipts), len(txs))
+	}
+	fields := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		fields[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), txs[i], uint64(i))
+	}
+	return fields, nil
+}
 
+// marshalReceipt converts a transaction receipt into the RPC representation.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, tx *types.Transaction, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
 		signer = types.NewEIP155Signer(tx.ChainId())
@@ -1057,7 +1227,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1081,7 +1251,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -0,0 +1,286 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBundleCalls is the maximum number of calls a single bundle may simulate.
+const maxBundleCalls = 256
+
+// bundleTimeout is the time allowance for simulating all the calls of a bundle.
+const bundleTimeout = 5 * time.Second
+
+// OverrideAccount specifies the fields of an account to replace in the state
+// before simulating a bundle of calls. Unset fields are left unchanged.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64             `json:"nonce"`
+	Code      *hexutil.Bytes              `json:"code"`
+	Balance   *hexutil.Big                `json:"balance"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the set of accounts to override before simulating a bundle.
+type StateOverride map[common.Address]OverrideAccount
+
+// apply overrides the fields of the specified accounts in the given state.
+func (diff StateOverride) apply(state *state.StateDB) {
+	for addr, account := range diff {
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.SetBalance(addr, account.Balance.ToInt())
+		}
+		for key, value := range account.StateDiff {
+			state.SetState(addr, key, value)
+		}
+	}
+}
+
+// AccountDiff is the change of an account caused by a simulated call. Only the
+// modified fields are set, each as a pair of its values before and after.
+type AccountDiff struct {
+	Balance *[2]*hexutil.Big               `json:"balance,omitempty"`
+	Nonce   *[2]hexutil.Uint64             `json:"nonce,omitempty"`
+	Code    *[2]hexutil.Bytes              `json:"code,omitempty"`
+	Storage map[common.Hash][2]common.Hash `json:"storage,omitempty"`
+}
+
+// BundleCallResult is the outcome of a single call simulated within a bundle.
+type BundleCallResult struct {
+	ReturnValue hexutil.Bytes                   `json:"returnValue"`
+	GasUsed     hexutil.Uint64                  `json:"gasUsed"`
+	Failed      bool                            `json:"failed"`
+	Error       string                          `json:"error,omitempty"`
+	Logs        []*types.Log                    `json:"logs"`
+	StateDiff   map[common.Address]*AccountDiff `json:"stateDiff"`
+}
+
+// CallBundle simulates a sequence of calls on top of the state of the given
+// block, each one seeing the effects of the ones before it, and returns the
+// result, gas usage, logs and state changes of every call. The state can be
+// modified with the optional overrides before the first call. Contrary to
+// eth_call, the senders are not credited with any funds, so the simulation
+// reflects what would happen if the calls were included in the next block as
+// transactions. None of the changes are persisted.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) ([]*BundleCallResult, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(calls) > maxBundleCalls {
+		return nil, errors.New("too many calls in bundle")
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if overrides != nil {
+		overrides.apply(state)
+	}
+	// Make sure the simulation of the entire bundle is bounded in time
+	ctx, cancel := context.WithTimeout(ctx, bundleTimeout)
+	defer cancel()
+
+	results := make([]*BundleCallResult, 0, len(calls))
+	for i, args := range calls {
+		result, err := s.simulateBundleCall(ctx, state, header, i, args)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// simulateBundleCall executes a single call of a bundle on the given state,
+// leaving its modifications in place for the subsequent calls.
+func (s *PublicBlockChainAPI) simulateBundleCall(ctx context.Context, statedb *state.StateDB, header *types.Header, index int, args CallArgs) (*BundleCallResult, error) {
+	// Set sender address or use a default if none specified
+	addr := args.From
+	if addr == (common.Address{}) {
+		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+				addr = accounts[0].Address
+			}
+		}
+	}
+	// Gas is unlimited unless set, but it is only paid for at an explicit price
+	gas := uint64(args.Gas)
+	if gas == 0 {
+		gas = math.MaxUint64 / 2
+	}
+	msg := types.NewMessage(addr, args.To, statedb.GetNonce(addr), args.Value.ToInt(), gas, args.GasPrice.ToInt(), args.Data, false)
+
+	// Create the EVM, undoing the balance it grants the sender of plain calls
+	var (
+		prestate = statedb.Copy()
+		tracer   = newTouchTracer()
+		balance  = new(big.Int).Set(statedb.GetBalance(addr))
+	)
+	evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, vm.Config{Debug: true, Tracer: tracer})
+	if err != nil {
+		return nil, err
+	}
+	statedb.SetBalance(addr, balance)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	// Apply the message, recording transaction level errors in the result
+	statedb.Prepare(common.Hash{}, header.Hash(), index)
+	logs := len(statedb.GetLogs(common.Hash{}))
+
+	res, gasUsed, failed, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, errors.New("bundle simulation timed out")
+	}
+	statedb.Finalise(true)
+
+	result := &BundleCallResult{
+		ReturnValue: res,
+		GasUsed:     hexutil.Uint64(gasUsed),
+		Failed:      failed,
+		Logs:        statedb.GetLogs(common.Hash{})[logs:],
+	}
+	if err != nil {
+		result.Failed, result.Error = true, err.Error()
+	}
+	if result.Logs == nil {
+		result.Logs = []*types.Log{}
+	}
+	tracer.touch(addr)
+	tracer.touch(evm.Coinbase)
+	if args.To != nil {
+		tracer.touch(*args.To)
+	}
+	result.StateDiff = tracer.diff(prestate, statedb)
+	return result, nil
+}
+
+// touchTracer is a vm.Tracer collecting the accounts and storage slots touched
+// by a call, to limit the state diffing to the parts which may have changed.
+type touchTracer struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+func newTouchTracer() *touchTracer {
+	return &touchTracer{accounts: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+// touch marks an account as possibly modified.
+func (t *touchTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.accounts[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.accounts[addr] = slots
+	}
+	return slots
+}
+
+// CaptureStart implements vm.Tracer, marking the recipient of the call.
+func (t *touchTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.touch(from)
+	t.touch(to)
+	return nil
+}
+
+// CaptureState implements vm.Tracer, marking the accounts and storage slots
+// which the executed opcode may modify.
+func (t *touchTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	t.touch(contract.Address())
+
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		t.touch(common.BigToAddress(stack.Back(1)))
+	case vm.CREATE:
+		from := contract.Address()
+		t.touch(crypto.CreateAddress(from, env.StateDB.GetNonce(from)))
+	case vm.SELFDESTRUCT:
+		t.touch(common.BigToAddress(stack.Back(0)))
+	case vm.SSTORE:
+		t.touch(contract.Address())[common.BigToHash(stack.Back(0))] = struct{}{}
+	}
+	return nil
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *touchTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *touchTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// diff compares the touched accounts and storage slots between the states before
+// and after a call, returning the ones which were actually modified.
+func (t *touchTracer) diff(prestate, poststate *state.StateDB) map[common.Address]*AccountDiff {
+	diffs := make(map[common.Address]*AccountDiff)
+	for addr, slots := range t.accounts {
+		diff := new(AccountDiff)
+		if pre, post := prestate.GetBalance(addr), poststate.GetBalance(addr); pre.Cmp(post) != 0 {
+			diff.Balance = &[2]*hexutil.Big{(*hexutil.Big)(new(big.Int).Set(pre)), (*hexutil.Big)(new(big.Int).Set(post))}
+		}
+		if pre, post := prestate.GetNonce(addr), poststate.GetNonce(addr); pre != post {
+			diff.Nonce = &[2]hexutil.Uint64{hexutil.Uint64(pre), hexutil.Uint64(post)}
+		}
+		if pre, post := prestate.GetCode(addr), poststate.GetCode(addr); !bytes.Equal(pre, post) {
+			diff.Code = &[2]hexutil.Bytes{common.CopyBytes(pre), common.CopyBytes(post)}
+		}
+		for key := range slots {
+			if pre, post := prestate.GetState(addr, key), poststate.GetState(addr, key); pre != post {
+				if diff.Storage == nil {
+					diff.Storage = make(map[common.Hash][2]common.Hash)
+				}
+				diff.Storage[key] = [2]common.Hash{pre, post}
+			}
+		}
+		if diff.Balance != nil || diff.Nonce != nil || diff.Code != nil || diff.Storage != nil {
+			diffs[addr] = diff
+		}
+	}
+	return diffs
+}
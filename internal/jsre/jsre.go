@@ -24,6 +24,8 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -50,6 +52,7 @@ type JSRE struct {
 	evalQueue     chan *evalReq
 	stopEventLoop chan bool
 	closed        chan struct{}
+	modules       map[string]otto.Value // Exports of the loaded script modules by absolute path (event loop only)
 }
 
 // jsTimer is a single timer instance with a callback function
@@ -74,9 +77,11 @@ func New(assetPath string, output io.Writer) *JSRE {
 		closed:        make(chan struct{}),
 		evalQueue:     make(chan *evalReq),
 		stopEventLoop: make(chan bool),
+		modules:       make(map[string]otto.Value),
 	}
 	go re.runEventLoop()
 	re.Set("loadScript", re.loadScript)
+	re.Set("loadModule", re.loadModule)
 	re.Set("inspect", re.prettyPrintJS)
 	return re
 }
@@ -303,6 +308,84 @@ func (re *JSRE) loadScript(call otto.FunctionCall) otto.Value {
 	return otto.TrueValue()
 }
 
+// loadModule loads a CommonJS style script module from inside the currently
+// executing JS code, returning its exports. If a relative path is given, the
+// jsre's assetPath is used.
+func (re *JSRE) loadModule(call otto.FunctionCall) otto.Value {
+	file, err := call.Argument(0).ToString()
+	if err != nil {
+		panic(call.Otto.MakeCustomError("Error", err.Error()))
+	}
+	exports, err := re.requireModule(call.Otto, re.assetPath, file)
+	if err != nil {
+		panic(call.Otto.MakeCustomError("Error", err.Error()))
+	}
+	return exports
+}
+
+// requireModule evaluates the script module at the given path relative to dir,
+// providing it with its own exports, module and require bindings. Relative paths
+// required by the module are resolved against its own directory, any other names
+// are passed on to the global require function. Modules are evaluated only once,
+// later loads return the cached exports.
+func (re *JSRE) requireModule(vm *otto.Otto, dir string, file string) (otto.Value, error) {
+	file = common.AbsolutePath(dir, file)
+	if filepath.Ext(file) == "" {
+		file += ".js"
+	}
+	if exports, ok := re.modules[file]; ok {
+		return exports, nil
+	}
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		return otto.Value{}, err
+	}
+	// Wrap the module into a function without shifting its line numbers
+	wrapper, err := compileAndRun(vm, file, "(function(exports, require, module, __filename, __dirname) {"+string(source)+"\n})")
+	if err != nil {
+		return otto.Value{}, err
+	}
+	module, _ := vm.Object(`({exports: {}})`)
+	exports, _ := module.Get("exports")
+
+	// Cache the exports early to allow for cyclic dependencies
+	re.modules[file] = exports
+
+	require := func(call otto.FunctionCall) otto.Value {
+		name, err := call.Argument(0).ToString()
+		if err != nil {
+			panic(call.Otto.MakeCustomError("Error", err.Error()))
+		}
+		if !isModulePath(name) {
+			value, err := call.Otto.Call("require", nil, name)
+			if err != nil {
+				panic(call.Otto.MakeCustomError("Error", err.Error()))
+			}
+			return value
+		}
+		exports, err := re.requireModule(call.Otto, filepath.Dir(file), name)
+		if err != nil {
+			panic(call.Otto.MakeCustomError("Error", err.Error()))
+		}
+		return exports
+	}
+	if _, err := wrapper.Call(otto.NullValue(), exports, require, module, file, filepath.Dir(file)); err != nil {
+		delete(re.modules, file)
+		return otto.Value{}, err
+	}
+	// The module might have replaced its exports object altogether
+	exports, _ = module.Get("exports")
+	re.modules[file] = exports
+
+	return exports, nil
+}
+
+// isModulePath reports whether a required name refers to a script module on the
+// filesystem instead of a bundled library.
+func isModulePath(name string) bool {
+	return strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") || filepath.IsAbs(name)
+}
+
 // Evaluate executes code and pretty prints the result to the specified output
 // stream.
 func (re *JSRE) Evaluate(code string, w io.Writer) error {
@@ -135,3 +135,29 @@ func TestLoadScript(t *testing.T) {
 	}
 	jsre.Stop(false)
 }
+
+func TestLoadModule(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `counter++; var add = require("./lib/add"); exports.sum = add(2, 3);`)
+	defer os.RemoveAll(dir)
+	defer jsre.Stop(false)
+
+	if err := os.Mkdir(path.Join(dir, "lib"), 0700); err != nil {
+		t.Fatal("cannot create lib directory:", err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "lib", "add.js"), []byte(`module.exports = function(a, b) { return a + b; };`), os.ModePerm); err != nil {
+		t.Fatal("cannot create add.js:", err)
+	}
+	if _, err := jsre.Run(`var counter = 0; var first = loadModule("test.js"); var second = loadModule("test");`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	val, err := jsre.Run(`[first.sum, counter, first === second].join(",")`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got, _ := val.ToString(); got != "5,1,true" {
+		t.Errorf("expected '5,1,true', got '%v'", got)
+	}
+	if _, err := jsre.Run(`loadModule("missing.js")`); err == nil {
+		t.Errorf("expected error for missing module")
+	}
+}
@@ -19,13 +19,17 @@ package web3ext
 
 var Modules = map[string]string{
 	"admin":      Admin_JS,
+	"bzz":        Bzz_JS,
 	"chequebook": Chequebook_JS,
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
 	"eth":        Eth_JS,
+	"les":        LES_JS,
+	"mailserver": MailServer_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
+	"pss":        Pss_JS,
 	"rpc":        RPC_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
@@ -99,12 +103,34 @@ web3._extend({
 			call: 'clique_discard',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getSchedule',
+			call: 'clique_getSchedule',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getVoteHistory',
+			call: 'clique_getVoteHistory',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getSignerStats',
+			call: 'clique_getSignerStats',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'proposals',
 			getter: 'clique_proposals'
 		}),
+		new web3._extend.Property({
+			name: 'schedule',
+			getter: 'clique_getSchedule'
+		}),
 	]
 });
 `
@@ -123,6 +149,22 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'disconnectPeer',
+			call: 'admin_disconnectPeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -134,6 +176,28 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'exportTransactions',
+			call: 'admin_exportTransactions',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'importTransactions',
+			call: 'admin_importTransactions',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'setTxPoolPolicy',
+			call: 'admin_setTxPoolPolicy',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setDownloaderConfig',
+			call: 'admin_setDownloaderConfig',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -165,14 +229,30 @@ web3._extend({
 			name: 'nodeInfo',
 			getter: 'admin_nodeInfo'
 		}),
+		new web3._extend.Property({
+			name: 'txPoolPolicy',
+			getter: 'admin_txPoolPolicy'
+		}),
+		new web3._extend.Property({
+			name: 'downloaderConfig',
+			getter: 'admin_downloaderConfig'
+		}),
 		new web3._extend.Property({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'protocolTraffic',
+			getter: 'admin_protocolTraffic'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'health',
+			getter: 'admin_health'
+		}),
 	]
 });
 `
@@ -219,7 +299,8 @@ web3._extend({
 		new web3._extend.Method({
 			name: 'metrics',
 			call: 'debug_metrics',
-			params: 1
+			params: 2,
+			inputFormatter: [null, null]
 		}),
 		new web3._extend.Method({
 			name: 'verbosity',
@@ -418,6 +499,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'eth_callBundle',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransaction',
 			call: 'eth_getRawTransactionByHash',
@@ -431,6 +518,39 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: function(args) {
+				return (web3._extend.utils.isString(args[0]) && args[0].indexOf('0x') === 0 && args[0].length === 66) ? 'eth_getBlockReceiptsByHash' : 'eth_getBlockReceipts';
+			},
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: function(receipts) {
+				var formatted = [];
+				for (var i = 0; i < receipts.length; i++) {
+					formatted.push(web3._extend.formatters.outputTransactionReceiptFormatter(receipts[i]));
+				}
+				return formatted;
+			}
+		}),
+		new web3._extend.Method({
+			name: 'gasPricePercentiles',
+			call: 'eth_gasPricePercentiles',
+			params: 1,
+			outputFormatter: function(prices) {
+				var formatted = [];
+				for (var i = 0; i < prices.length; i++) {
+					formatted.push(web3._extend.utils.toBigNumber(prices[i]));
+				}
+				return formatted;
+			}
+		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -449,6 +569,52 @@ web3._extend({
 });
 `
 
+const LES_JS = `
+web3._extend({
+	property: 'les',
+	methods: [
+		new web3._extend.Method({
+			name: 'setPriorityClient',
+			call: 'les_setPriorityClient',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'removePriorityClient',
+			call: 'les_removePriorityClient',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'addBudget',
+			call: 'les_addBudget',
+			params: 2
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'defaultCapacity',
+			getter: 'les_defaultCapacity'
+		}),
+		new web3._extend.Property({
+			name: 'priorityClients',
+			getter: 'les_priorityClients'
+		}),
+	]
+});
+`
+
+const MailServer_JS = `
+web3._extend({
+	property: 'mailserver',
+	methods: [
+		new web3._extend.Method({
+			name: 'envelopes',
+			call: 'mailserver_envelopes',
+			params: 1
+		}),
+	]
+});
+`
+
 const Miner_JS = `
 web3._extend({
 	property: 'miner',
@@ -517,6 +683,12 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'personal_signTypedData',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'ecRecover',
 			call: 'personal_ecRecover',
@@ -565,6 +737,31 @@ const Shh_JS = `
 web3._extend({
 	property: 'shh',
 	methods: [
+		new web3._extend.Method({
+			name: 'setBloomFilter',
+			call: 'shh_setBloomFilter',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'makeLightClient',
+			call: 'shh_makeLightClient',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'cancelLightClient',
+			call: 'shh_cancelLightClient',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getFilterMessages',
+			call: 'shh_getFilterMessages',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'deleteMessageFilter',
+			call: 'shh_deleteMessageFilter',
+			params: 1
+		}),
 	],
 	properties:
 	[
@@ -581,6 +778,123 @@ web3._extend({
 });
 `
 
+const Bzz_JS = `
+web3._extend({
+	property: 'bzz',
+	methods: [
+		new web3._extend.Method({
+			name: 'status',
+			call: 'bzz_status',
+			params: 0
+		}),
+	],
+	properties: []
+});
+`
+
+const Pss_JS = `
+web3._extend({
+	property: 'pss',
+	methods: [
+		new web3._extend.Method({
+			name: 'getAddress',
+			call: 'pss_getAddress',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getSymmetricKey',
+			call: 'pss_getSymmetricKey',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSymmetricAddressHint',
+			call: 'pss_getSymmetricAddressHint',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getAsymmetricAddressHint',
+			call: 'pss_getAsymmetricAddressHint',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setPeerPublicKey',
+			call: 'pss_setPeerPublicKey',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'stringToTopic',
+			call: 'pss_stringToTopic',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sendAsym',
+			call: 'pss_sendAsym',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'sendSym',
+			call: 'pss_sendSym',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getPeerTopics',
+			call: 'pss_getPeerTopics',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getPeerAddress',
+			call: 'pss_getPeerAddress',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'handshake',
+			call: 'pss_handshake',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'addHandshake',
+			call: 'pss_addHandshake',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeHandshake',
+			call: 'pss_removeHandshake',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getHandshakeKeys',
+			call: 'pss_getHandshakeKeys',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'getHandshakeKeyCapacity',
+			call: 'pss_getHandshakeKeyCapacity',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getHandshakePublicKey',
+			call: 'pss_getHandshakePublicKey',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'releaseHandshakeKey',
+			call: 'pss_releaseHandshakeKey',
+			params: 4
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'baseAddr',
+			getter: 'pss_baseAddr'
+		}),
+		new web3._extend.Property({
+			name: 'publicKey',
+			getter: 'pss_getPublicKey'
+		}),
+	]
+});
+`
+
 const SWARMFS_JS = `
 web3._extend({
 	property: 'swarmfs',
@@ -0,0 +1,96 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// PrivateLightServerAPI provides an API for the operator of a light server to
+// manage the service levels of its clients.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new light server management API.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server: server}
+}
+
+// DefaultCapacity returns the flow control recharge rate, in cost units per
+// millisecond, free clients are served with.
+func (api *PrivateLightServerAPI) DefaultCapacity() uint64 {
+	return api.server.defParams.MinRecharge
+}
+
+// SetPriorityClient grants priority service to a client, identified by its node
+// ID or enode URL. Priority clients are admitted even if the server is full and
+// are served with the given capacity (flow control recharge rate in cost units
+// per millisecond, zero meaning the default). If a budget is given, the service
+// is prepaid and the client falls back to free service once the budget is used
+// up, otherwise the service is unlimited. Changes apply from the next connection
+// of the client.
+func (api *PrivateLightServerAPI) SetPriorityClient(node string, capacity uint64, budget *uint64) error {
+	id, err := parseClientID(node)
+	if err != nil {
+		return err
+	}
+	api.server.priorityClients.set(id, capacity, budget)
+	return nil
+}
+
+// RemovePriorityClient revokes the priority service of a client. A connected
+// client is dropped upon its next request.
+func (api *PrivateLightServerAPI) RemovePriorityClient(node string) error {
+	id, err := parseClientID(node)
+	if err != nil {
+		return err
+	}
+	return api.server.priorityClients.remove(id)
+}
+
+// AddBudget tops up the prepaid budget of a priority client, returning the new
+// budget.
+func (api *PrivateLightServerAPI) AddBudget(node string, amount uint64) (uint64, error) {
+	id, err := parseClientID(node)
+	if err != nil {
+		return 0, err
+	}
+	return api.server.priorityClients.addBudget(id, amount)
+}
+
+// PriorityClients returns the service levels and usage of all the priority
+// clients, keyed by node ID.
+func (api *PrivateLightServerAPI) PriorityClients() map[string]PriorityClientInfo {
+	infos := make(map[string]PriorityClientInfo)
+	for id, info := range api.server.priorityClients.info() {
+		infos[id.String()] = info
+	}
+	return infos
+}
+
+// parseClientID parses a client identifier given either as a node ID or as an
+// enode URL.
+func parseClientID(node string) (discover.NodeID, error) {
+	n, err := discover.ParseNode(node)
+	if err != nil {
+		return discover.NodeID{}, fmt.Errorf("invalid client %q: %v", node, err)
+	}
+	return n.ID, nil
+}
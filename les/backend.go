@@ -127,7 +127,7 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 	}
 
 	leth.txPool = light.NewTxPool(leth.chainConfig, leth.blockchain, leth.relay)
-	if leth.protocolManager, err = NewProtocolManager(leth.chainConfig, true, ClientProtocolVersions, config.NetworkId, leth.eventMux, leth.engine, leth.peers, leth.blockchain, nil, chainDb, leth.odr, leth.relay, quitSync, &leth.wg); err != nil {
+	if leth.protocolManager, err = NewProtocolManager(leth.chainConfig, true, ClientProtocolVersions, config.NetworkId, leth.eventMux, leth.engine, leth.peers, leth.blockchain, nil, chainDb, leth.odr, leth.relay, config.ULC, quitSync, &leth.wg); err != nil {
 		return nil, err
 	}
 	leth.ApiBackend = &LesApiBackend{leth, nil}
@@ -213,6 +213,19 @@ func (s *LightEthereum) LesVersion() int                    { return int(s.proto
 func (s *LightEthereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 func (s *LightEthereum) EventMux() *event.TypeMux           { return s.eventMux }
 
+// Health implements node.HealthReporter, reporting the service ready while it
+// is connected to servers and not synchronising the header chain.
+func (s *LightEthereum) Health() node.ServiceHealth {
+	health := node.ServiceHealth{Ready: true, Healthy: true}
+	switch {
+	case s.peers.Len() == 0:
+		health.Ready, health.Message = false, "no servers connected"
+	case s.protocolManager.downloader.Synchronising():
+		health.Ready, health.Message = false, "header synchronisation in progress"
+	}
+	return health
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *LightEthereum) Protocols() []p2p.Protocol {
@@ -228,6 +241,12 @@ func (s *LightEthereum) Start(srvr *p2p.Server) error {
 	// clients are searching for the first advertised protocol in the list
 	protocolVersion := AdvertiseProtocolVersions[0]
 	s.serverPool.start(srvr, lesTopic(s.blockchain.Genesis().Hash(), protocolVersion))
+	if s.protocolManager.ulc != nil {
+		log.Warn("Ultra light client mode trusts the configured servers", "servers", len(s.protocolManager.ulc.servers), "fraction", s.protocolManager.ulc.minTrustedFraction)
+		for _, node := range s.protocolManager.ulc.servers {
+			srvr.AddPeer(node)
+		}
+	}
 	s.protocolManager.Start(s.config.LightPeers)
 	return nil
 }
@@ -366,6 +366,18 @@ func (f *lightFetcher) peerHasBlock(p *peer, hash common.Hash, number uint64) bo
 	return rawdb.ReadCanonicalHash(f.pm.chainDb, fp.root.number) == fp.root.hash && rawdb.ReadCanonicalHash(f.pm.chainDb, number) == hash
 }
 
+// trustedAnnounced reports whether a head was announced by enough of the trusted
+// servers for an ultra light client to accept it.
+func (f *lightFetcher) trustedAnnounced(hash common.Hash) bool {
+	var announced int
+	for p, fp := range f.peers {
+		if p.trusted && fp.nodeByHash[hash] != nil {
+			announced++
+		}
+	}
+	return f.pm.ulc.confirmed(announced)
+}
+
 // requestAmount calculates the amount of headers to be downloaded starting
 // from a certain head backwards
 func (f *lightFetcher) requestAmount(p *peer, n *fetcherTreeNode) uint64 {
@@ -400,7 +412,14 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	bestSyncing := false
 
 	for p, fp := range f.peers {
+		// Ultra light clients only follow heads confirmed by the trusted servers
+		if f.pm.ulc != nil && !p.trusted {
+			continue
+		}
 		for hash, n := range fp.nodeByHash {
+			if f.pm.ulc != nil && !f.trustedAnnounced(hash) {
+				continue
+			}
 			if !f.checkKnownNode(p, n) && !n.requested && (bestTd == nil || n.td.Cmp(bestTd) >= 0) {
 				amount := f.requestAmount(p, n)
 				if bestTd == nil || n.td.Cmp(bestTd) > 0 || amount < bestAmount {
@@ -430,6 +449,9 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 				f.lock.Lock()
 				defer f.lock.Unlock()
 
+				if f.pm.ulc != nil && !p.trusted {
+					return false
+				}
 				fp := f.peers[p]
 				return fp != nil && fp.nodeByHash[bestHash] != nil
 			},
@@ -504,7 +526,13 @@ func (f *lightFetcher) processResponse(req fetchRequest, resp fetchResponse) boo
 	for i, header := range resp.headers {
 		headers[int(req.amount)-1-i] = header
 	}
-	if _, err := f.chain.InsertHeaderChain(headers, 1); err != nil {
+	// Headers of heads confirmed by the trusted servers of an ultra light client
+	// are linked to the confirmed hash, so their seals need not be verified
+	checkFreq := 1
+	if f.pm.ulc != nil {
+		checkFreq = 0
+	}
+	if _, err := f.chain.InsertHeaderChain(headers, checkFreq); err != nil {
 		if err == consensus.ErrFutureBlock {
 			return true
 		}
@@ -60,7 +60,8 @@ type peer struct {
 
 	announceType, requestAnnounceType uint64
 
-	id string
+	id      string
+	trusted bool // Whether the peer is a trusted server of the ultra light client
 
 	headInfo *announceData
 	lock     sync.RWMutex
@@ -76,6 +77,8 @@ type peer struct {
 	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
 	fcCosts        requestCostTable
+
+	priority bool // Whether the client peer is served with operator granted priority
 }
 
 func newPeer(version int, network uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -404,13 +407,16 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 	send = send.add("headHash", head)
 	send = send.add("headNum", headNum)
 	send = send.add("genesisHash", genesis)
+	var params *flowcontrol.ServerParams
 	if server != nil {
+		params, p.priority = server.priorityClients.params(p.ID())
+
 		send = send.add("serveHeaders", nil)
 		send = send.add("serveChainSince", uint64(0))
 		send = send.add("serveStateSince", uint64(0))
 		send = send.add("txRelay", nil)
-		send = send.add("flowControl/BL", server.defParams.BufLimit)
-		send = send.add("flowControl/MRR", server.defParams.MinRecharge)
+		send = send.add("flowControl/BL", params.BufLimit)
+		send = send.add("flowControl/MRR", params.MinRecharge)
 		list := server.fcCostStats.getCurrentList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
@@ -464,7 +470,8 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("announceType", &p.announceType) != nil {
 			p.announceType = announceTypeSimple
 		}
-		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
+		p.fcServerParams = params
+		p.fcClient = flowcontrol.NewClientNode(server.fcManager, params)
 	} else {
 		if recv.get("serveChainSince", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve chain")
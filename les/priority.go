@@ -0,0 +1,251 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/les/flowcontrol"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// errUnknownPriorityClient is returned if an operation is requested on a
+	// client not granted priority service.
+	errUnknownPriorityClient = errors.New("unknown priority client")
+
+	// errUnlimitedBudget is returned if a budget top-up is requested for a client
+	// with unlimited, operator-granted service.
+	errUnlimitedBudget = errors.New("priority client has unlimited budget")
+)
+
+// priorityClientsKey is the database key the priority client table is stored at.
+var priorityClientsKey = []byte("_priorityClients")
+
+// priorityClient is the service level granted to a single client, along with
+// its usage of it.
+type priorityClient struct {
+	capacity  uint64 // Flow control recharge rate in cost units per millisecond
+	prepaid   bool   // Whether the service is limited by a prepaid budget
+	budget    uint64 // Remaining prepaid budget in cost units
+	used      uint64 // Total cost of the requests served with priority
+	connected bool   // Whether the client is currently connected with priority
+}
+
+// priorityClientRlp is the database representation of a priority client.
+type priorityClientRlp struct {
+	ID       discover.NodeID
+	Capacity uint64
+	Prepaid  bool
+	Budget   uint64
+	Used     uint64
+}
+
+// PriorityClientInfo is the publicly visible state of a priority client.
+type PriorityClientInfo struct {
+	Capacity  uint64  `json:"capacity"`  // Flow control recharge rate in cost units per millisecond
+	Budget    *uint64 `json:"budget"`    // Remaining prepaid budget in cost units (nil if unlimited)
+	Used      uint64  `json:"used"`      // Total cost of the requests served with priority
+	Connected bool    `json:"connected"` // Whether the client is connected with priority
+}
+
+// priorityClientPool tracks the clients granted a guaranteed service level by
+// the server operator. Priority clients are admitted even if the server is full
+// and are assigned flow control parameters proportional to their capacity. The
+// cost of their requests is charged against their budget, and once a prepaid
+// budget is exhausted the client is dropped, falling back to free service on
+// reconnect.
+type priorityClientPool struct {
+	db       ethdb.Database
+	defaults *flowcontrol.ServerParams // Flow control parameters of free clients
+	clients  map[discover.NodeID]*priorityClient
+	lock     sync.Mutex
+}
+
+// newPriorityClientPool creates a priority client pool, loading the previously
+// stored clients from the database.
+func newPriorityClientPool(db ethdb.Database, defaults *flowcontrol.ServerParams) *priorityClientPool {
+	pool := &priorityClientPool{
+		db:       db,
+		defaults: defaults,
+		clients:  make(map[discover.NodeID]*priorityClient),
+	}
+	if db != nil {
+		if data, err := db.Get(priorityClientsKey); err == nil {
+			var list []priorityClientRlp
+			if err := rlp.DecodeBytes(data, &list); err != nil {
+				log.Error("Failed to decode priority clients", "err", err)
+			}
+			for _, c := range list {
+				pool.clients[c.ID] = &priorityClient{capacity: c.Capacity, prepaid: c.Prepaid, budget: c.Budget, used: c.Used}
+			}
+		}
+	}
+	return pool
+}
+
+// store saves the priority clients and their usage into the database.
+func (pool *priorityClientPool) store() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.storeLocked()
+}
+
+// storeLocked saves the priority clients into the database. The pool lock must
+// be held by the caller.
+func (pool *priorityClientPool) storeLocked() {
+	if pool.db == nil {
+		return
+	}
+	list := make([]priorityClientRlp, 0, len(pool.clients))
+	for id, c := range pool.clients {
+		list = append(list, priorityClientRlp{ID: id, Capacity: c.capacity, Prepaid: c.prepaid, Budget: c.budget, Used: c.used})
+	}
+	data, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		log.Error("Failed to encode priority clients", "err", err)
+		return
+	}
+	if err := pool.db.Put(priorityClientsKey, data); err != nil {
+		log.Error("Failed to store priority clients", "err", err)
+	}
+}
+
+// set grants priority service to a client with the given capacity, limited by
+// a prepaid budget if one is given. The new service level applies from the next
+// connection of the client.
+func (pool *priorityClientPool) set(id discover.NodeID, capacity uint64, budget *uint64) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	c, ok := pool.clients[id]
+	if !ok {
+		c = new(priorityClient)
+		pool.clients[id] = c
+	}
+	c.capacity = capacity
+	c.prepaid, c.budget = budget != nil, 0
+	if budget != nil {
+		c.budget = *budget
+	}
+	pool.storeLocked()
+}
+
+// remove revokes the priority service of a client. If the client is connected,
+// it is dropped upon its next request.
+func (pool *priorityClientPool) remove(id discover.NodeID) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if _, ok := pool.clients[id]; !ok {
+		return errUnknownPriorityClient
+	}
+	delete(pool.clients, id)
+	pool.storeLocked()
+	return nil
+}
+
+// addBudget tops up the prepaid budget of a client, returning the new budget.
+func (pool *priorityClientPool) addBudget(id discover.NodeID, amount uint64) (uint64, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	c, ok := pool.clients[id]
+	if !ok {
+		return 0, errUnknownPriorityClient
+	}
+	if !c.prepaid {
+		return 0, errUnlimitedBudget
+	}
+	c.budget += amount
+	pool.storeLocked()
+	return c.budget, nil
+}
+
+// params returns the flow control parameters a connecting client is entitled
+// to, and whether it is served with priority.
+func (pool *priorityClientPool) params(id discover.NodeID) (*flowcontrol.ServerParams, bool) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	c, ok := pool.clients[id]
+	if !ok || (c.prepaid && c.budget == 0) {
+		return pool.defaults, false
+	}
+	capacity := c.capacity
+	if capacity == 0 {
+		capacity = pool.defaults.MinRecharge
+	}
+	// Scale the buffer too, so the client can burst for the same amount of time
+	return &flowcontrol.ServerParams{
+		BufLimit:    pool.defaults.BufLimit / pool.defaults.MinRecharge * capacity,
+		MinRecharge: capacity,
+	}, true
+}
+
+// setConnected marks a priority client as connected or disconnected.
+func (pool *priorityClientPool) setConnected(id discover.NodeID, connected bool) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if c, ok := pool.clients[id]; ok {
+		c.connected = connected
+	}
+}
+
+// charge accounts the cost of a request served to a priority client, returning
+// false if the client is not entitled to priority service any more.
+func (pool *priorityClientPool) charge(id discover.NodeID, cost uint64) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	c, ok := pool.clients[id]
+	if !ok {
+		return false
+	}
+	if c.prepaid {
+		if c.budget < cost {
+			c.budget = 0
+			return false
+		}
+		c.budget -= cost
+	}
+	c.used += cost
+	return true
+}
+
+// info returns the state of all the priority clients.
+func (pool *priorityClientPool) info() map[discover.NodeID]PriorityClientInfo {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	infos := make(map[discover.NodeID]PriorityClientInfo, len(pool.clients))
+	for id, c := range pool.clients {
+		info := PriorityClientInfo{Capacity: c.capacity, Used: c.used, Connected: c.connected}
+		if c.prepaid {
+			budget := c.budget
+			info.Budget = &budget
+		}
+		infos[id] = info
+	}
+	return infos
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/les/flowcontrol"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// Tests that priority clients are assigned their service levels, that their
+// usage is charged against their budgets and that the pool is persisted.
+func TestPriorityClientPool(t *testing.T) {
+	var (
+		db       = ethdb.NewMemDatabase()
+		defaults = &flowcontrol.ServerParams{BufLimit: 1000, MinRecharge: 10}
+		pool     = newPriorityClientPool(db, defaults)
+
+		free      = discover.NodeID{0x01}
+		unlimited = discover.NodeID{0x02}
+		prepaid   = discover.NodeID{0x03}
+		budget    = uint64(100)
+	)
+	pool.set(unlimited, 50, nil)
+	pool.set(prepaid, 0, &budget)
+
+	// Check the flow control parameters of the various clients
+	if params, priority := pool.params(free); priority || params != defaults {
+		t.Errorf("free client params mismatch: have %+v (priority %v), want defaults", params, priority)
+	}
+	if params, priority := pool.params(unlimited); !priority || params.MinRecharge != 50 || params.BufLimit != 5000 {
+		t.Errorf("unlimited client params mismatch: have %+v (priority %v)", params, priority)
+	}
+	if params, priority := pool.params(prepaid); !priority || params.MinRecharge != 10 || params.BufLimit != 1000 {
+		t.Errorf("prepaid client params mismatch: have %+v (priority %v)", params, priority)
+	}
+	// Charge some requests and check the budget enforcement
+	if pool.charge(free, 1) {
+		t.Errorf("free client charged as priority")
+	}
+	if !pool.charge(unlimited, 1000000) {
+		t.Errorf("unlimited client rejected")
+	}
+	if !pool.charge(prepaid, 60) {
+		t.Errorf("prepaid client rejected within budget")
+	}
+	if pool.charge(prepaid, 60) {
+		t.Errorf("prepaid client accepted over budget")
+	}
+	if _, priority := pool.params(prepaid); priority {
+		t.Errorf("exhausted prepaid client still has priority")
+	}
+	if _, err := pool.addBudget(unlimited, 10); err != errUnlimitedBudget {
+		t.Errorf("unlimited top-up error mismatch: have %v, want %v", err, errUnlimitedBudget)
+	}
+	if left, err := pool.addBudget(prepaid, 30); err != nil || left != 30 {
+		t.Errorf("prepaid top-up mismatch: have %d, %v, want 30", left, err)
+	}
+	pool.setConnected(unlimited, true)
+
+	// Reload the pool and check the usage was retained
+	pool.store()
+	pool = newPriorityClientPool(db, defaults)
+
+	infos := pool.info()
+	if len(infos) != 2 {
+		t.Fatalf("priority client count mismatch: have %d, want 2", len(infos))
+	}
+	if info := infos[unlimited]; info.Budget != nil || info.Used != 1000000 || info.Capacity != 50 || info.Connected {
+		t.Errorf("unlimited client info mismatch: %+v", info)
+	}
+	if info := infos[prepaid]; info.Budget == nil || *info.Budget != 30 || info.Used != 60 {
+		t.Errorf("prepaid client info mismatch: %+v", info)
+	}
+	if err := pool.remove(prepaid); err != nil {
+		t.Fatalf("failed to remove priority client: %v", err)
+	}
+	if err := pool.remove(prepaid); err != errUnknownPriorityClient {
+		t.Errorf("removal error mismatch: have %v, want %v", err, errUnknownPriorityClient)
+	}
+	if pool.charge(prepaid, 1) {
+		t.Errorf("removed client charged as priority")
+	}
+}
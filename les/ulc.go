@@ -0,0 +1,77 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+var errNoTrustedServers = errors.New("ultra light client mode requires trusted servers")
+
+// ulc holds the configuration of the ultra light client mode, in which new heads
+// are accepted without verifying their seals once a large enough fraction of the
+// trusted servers announced them.
+type ulc struct {
+	servers            []*discover.Node
+	trusted            map[discover.NodeID]struct{}
+	minTrustedFraction int
+}
+
+// newULC creates the ultra light client settings from the user configuration.
+func newULC(config *eth.ULCConfig) (*ulc, error) {
+	if len(config.TrustedServers) == 0 {
+		return nil, errNoTrustedServers
+	}
+	u := &ulc{
+		trusted:            make(map[discover.NodeID]struct{}),
+		minTrustedFraction: config.MinTrustedFraction,
+	}
+	for _, url := range config.TrustedServers {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted server %q: %v", url, err)
+		}
+		if _, ok := u.trusted[node.ID]; ok {
+			continue
+		}
+		u.servers = append(u.servers, node)
+		u.trusted[node.ID] = struct{}{}
+	}
+	if u.minTrustedFraction <= 0 || u.minTrustedFraction > 100 {
+		log.Warn("Invalid minimum trusted fraction, using default", "provided", u.minTrustedFraction, "updated", eth.DefaultULCMinTrustedFraction)
+		u.minTrustedFraction = eth.DefaultULCMinTrustedFraction
+	}
+	return u, nil
+}
+
+// isTrusted reports whether the node with the given ID is a trusted server.
+func (u *ulc) isTrusted(id discover.NodeID) bool {
+	_, ok := u.trusted[id]
+	return ok
+}
+
+// confirmed reports whether enough of the trusted servers announced a head for
+// it to be accepted. The fraction is measured against all the configured trusted
+// servers, so that a few connected ones cannot confirm heads on their own.
+func (u *ulc) confirmed(announced int) bool {
+	return announced > 0 && announced*100 >= len(u.servers)*u.minTrustedFraction
+}
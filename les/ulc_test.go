@@ -0,0 +1,96 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// testServerURL creates the enode URL of a server with the given ID byte.
+func testServerURL(id byte) string {
+	return fmt.Sprintf("enode://%s@127.0.0.1:30303", discover.NodeID{id})
+}
+
+// Tests that the ultra light client configuration is validated and sanitized.
+func TestULCConfig(t *testing.T) {
+	if _, err := newULC(&eth.ULCConfig{}); err != errNoTrustedServers {
+		t.Errorf("missing servers error mismatch: have %v, want %v", err, errNoTrustedServers)
+	}
+	if _, err := newULC(&eth.ULCConfig{TrustedServers: []string{"enode://invalid"}}); err == nil {
+		t.Errorf("invalid server accepted")
+	}
+	u, err := newULC(&eth.ULCConfig{TrustedServers: []string{testServerURL(1), testServerURL(2), testServerURL(1)}, MinTrustedFraction: 101})
+	if err != nil {
+		t.Fatalf("failed to create ultra light client config: %v", err)
+	}
+	if len(u.servers) != 2 {
+		t.Errorf("trusted server count mismatch: have %d, want %d", len(u.servers), 2)
+	}
+	if !u.isTrusted(discover.NodeID{1}) || !u.isTrusted(discover.NodeID{2}) || u.isTrusted(discover.NodeID{3}) {
+		t.Errorf("trusted servers mismatch")
+	}
+	if u.minTrustedFraction != eth.DefaultULCMinTrustedFraction {
+		t.Errorf("fraction mismatch: have %d, want %d", u.minTrustedFraction, eth.DefaultULCMinTrustedFraction)
+	}
+}
+
+// Tests that heads are only accepted once announced by enough trusted servers,
+// ignoring the announcements of untrusted ones.
+func TestULCTrustedAnnounced(t *testing.T) {
+	u, err := newULC(&eth.ULCConfig{TrustedServers: []string{testServerURL(1), testServerURL(2), testServerURL(3), testServerURL(4)}, MinTrustedFraction: 75})
+	if err != nil {
+		t.Fatalf("failed to create ultra light client config: %v", err)
+	}
+	f := &lightFetcher{pm: &ProtocolManager{ulc: u}, peers: make(map[*peer]*fetcherPeerInfo)}
+
+	head := common.Hash{0xff}
+	announce := func(trusted bool) {
+		fp := &fetcherPeerInfo{nodeByHash: map[common.Hash]*fetcherTreeNode{head: {hash: head}}}
+		f.peers[&peer{trusted: trusted}] = fp
+	}
+	if f.trustedAnnounced(head) {
+		t.Fatalf("head accepted without trusted servers")
+	}
+	// Untrusted announcements must not count towards the threshold
+	for i := 0; i < 5; i++ {
+		announce(false)
+	}
+	if f.trustedAnnounced(head) {
+		t.Fatalf("head accepted from untrusted servers")
+	}
+	// Two connected trusted servers out of four are below the 75% threshold,
+	// even if all the connected ones announced the head
+	announce(true)
+	announce(true)
+	if f.trustedAnnounced(head) {
+		t.Errorf("head accepted from 2 of 4 trusted servers")
+	}
+	f.peers[&peer{trusted: true}] = &fetcherPeerInfo{nodeByHash: make(map[common.Hash]*fetcherTreeNode)}
+	if f.trustedAnnounced(head) {
+		t.Errorf("head accepted from 2 of 4 trusted servers")
+	}
+	// Three out of four trusted servers reach the threshold
+	announce(true)
+	if !f.trustedAnnounced(head) {
+		t.Errorf("head rejected from 3 of 4 trusted servers")
+	}
+}
@@ -58,6 +58,40 @@ func InfluxDBWithTags(r metrics.Registry, d time.Duration, url, database, userna
 	rep.run()
 }
 
+// Reporter posts the metrics of a registry to InfluxDB each time Send is
+// called, for callers which schedule the posts themselves.
+type Reporter struct {
+	rep *reporter
+}
+
+// NewReporter creates a Reporter posting the metrics of r to the InfluxDB
+// database at url with the specified tags
+func NewReporter(r metrics.Registry, url, database, username, password, namespace string, tags map[string]string) (*Reporter, error) {
+	u, err := uurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	rep := &reporter{
+		reg:       r,
+		url:       *u,
+		database:  database,
+		username:  username,
+		password:  password,
+		namespace: namespace,
+		tags:      tags,
+		cache:     make(map[string]int64),
+	}
+	if err := rep.makeClient(); err != nil {
+		return nil, err
+	}
+	return &Reporter{rep: rep}, nil
+}
+
+// Send posts the current values of the metrics
+func (r *Reporter) Send() error {
+	return r.rep.send()
+}
+
 func (r *reporter) makeClient() (err error) {
 	r.client, err = client.NewClient(client.Config{
 		URL:      r.url,
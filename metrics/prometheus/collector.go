@@ -0,0 +1,131 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// quantiles are the quantiles exported for the summaries of histograms and
+// timers
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+// collector writes metrics in the Prometheus text exposition format
+type collector struct {
+	buff *bytes.Buffer
+}
+
+// newCollector creates a collector with an empty buffer
+func newCollector() *collector {
+	return &collector{
+		buff: &bytes.Buffer{},
+	}
+}
+
+// addCounter exports a counter as a gauge, as go-metrics counters can be
+// decremented while Prometheus counters never decrease
+func (c *collector) addCounter(name string, m metrics.Counter) {
+	c.writeGauge(name, float64(m.Count()))
+}
+
+func (c *collector) addGauge(name string, m metrics.Gauge) {
+	c.writeGauge(name, float64(m.Value()))
+}
+
+func (c *collector) addGaugeFloat64(name string, m metrics.GaugeFloat64) {
+	c.writeGauge(name, m.Value())
+}
+
+// addMeter exports the number of events of a meter as a counter, Prometheus
+// computes the rates itself
+func (c *collector) addMeter(name string, m metrics.Meter) {
+	name = mutateKey(name)
+	c.writeType(name, "counter")
+	c.writeSample(name, "", float64(m.Count()))
+}
+
+func (c *collector) addHistogram(name string, m metrics.Histogram) {
+	c.writeSummary(name, m.Percentiles(quantiles), float64(m.Sum()), m.Count())
+}
+
+// addTimer exports a timer as a summary of durations in nanoseconds
+func (c *collector) addTimer(name string, m metrics.Timer) {
+	c.writeSummary(name, m.Percentiles(quantiles), float64(m.Sum()), m.Count())
+}
+
+// addResettingTimer exports a resetting timer as a summary of the durations
+// in nanoseconds measured since it was last reset
+func (c *collector) addResettingTimer(name string, m metrics.ResettingTimer) {
+	count := len(m.Values())
+	if count == 0 {
+		return
+	}
+	ps := m.Percentiles(quantiles)
+	values := make([]float64, len(ps))
+	for i, p := range ps {
+		values[i] = float64(p)
+	}
+	c.writeSummary(name, values, m.Mean()*float64(count), int64(count))
+}
+
+func (c *collector) writeGauge(name string, value float64) {
+	name = mutateKey(name)
+	c.writeType(name, "gauge")
+	c.writeSample(name, "", value)
+}
+
+// writeSummary writes a summary with the values of the exported quantiles,
+// the sum and the number of the observations
+func (c *collector) writeSummary(name string, values []float64, sum float64, count int64) {
+	name = mutateKey(name)
+	c.writeType(name, "summary")
+	for i, q := range quantiles {
+		c.writeSample(name, fmt.Sprintf(`{quantile="%s"}`, formatFloat(q)), values[i])
+	}
+	c.writeSample(name+"_sum", "", sum)
+	c.writeSample(name+"_count", "", float64(count))
+}
+
+func (c *collector) writeType(name, typ string) {
+	fmt.Fprintf(c.buff, "# TYPE %s %s\n", name, typ)
+}
+
+func (c *collector) writeSample(name, labels string, value float64) {
+	fmt.Fprintf(c.buff, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+// formatFloat formats a value as Prometheus expects it
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// mutateKey replaces the characters which are not valid in Prometheus metric
+// names, like the dots and slashes of go-metrics names, with underscores
+func mutateKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, key)
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func init() {
+	metrics.Enabled = true
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter("api.get.count", reg).Inc(3)
+	metrics.GetOrRegisterGauge("netstore/peers", reg).Update(7)
+	metrics.GetOrRegisterMeter("chunks.delivered", reg).Mark(5)
+	histogram := metrics.GetOrRegisterHistogram("chunk.size", reg, metrics.NewUniformSample(100))
+	for i := int64(1); i <= 100; i++ {
+		histogram.Update(i)
+	}
+	timer := metrics.GetOrRegisterTimer("api.get.time", reg)
+	timer.Update(time.Second)
+	timer.Update(3 * time.Second)
+
+	server := httptest.NewServer(Handler(reg))
+	defer server.Close()
+	res, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE api_get_count gauge\napi_get_count 3\n",
+		"# TYPE netstore_peers gauge\nnetstore_peers 7\n",
+		"# TYPE chunks_delivered counter\nchunks_delivered 5\n",
+		"# TYPE chunk_size summary\n",
+		`chunk_size{quantile="0.5"} 50.5` + "\n",
+		`chunk_size{quantile="0.99"} 99.99` + "\n",
+		"chunk_size_sum 5050\nchunk_size_count 100\n",
+		"# TYPE api_get_time summary\n",
+		"api_get_time_sum 4e+09\napi_get_time_count 2\n",
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("expected %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestMutateKey(t *testing.T) {
+	for key, expected := range map[string]string{
+		"api.get.count":      "api_get_count",
+		"p2p/InboundTraffic": "p2p_InboundTraffic",
+		"swarm-node:ok":      "swarm_node:ok",
+	} {
+		if got := mutateKey(key); got != expected {
+			t.Errorf("%s: expected %s, got %s", key, expected, got)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package statsd pushes the metrics of a registry to a StatsD daemon or to
+// the plaintext listener of a Graphite carbon server at regular intervals.
+package statsd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// maxPacketSize is the maximum size of a StatsD UDP packet, which fits in
+// the MTU of most networks
+const maxPacketSize = 1432
+
+// StatsD pushes the metrics of r to the StatsD daemon listening on the UDP
+// address addr every d, prefixing their names with namespace. It blocks
+// forever, so it should be started in its own goroutine.
+func StatsD(r metrics.Registry, d time.Duration, addr, namespace string) {
+	rep := NewStatsDReporter(r, addr, namespace)
+	for range time.Tick(d) {
+		if err := rep.Send(); err != nil {
+			log.Warn("Unable to send metrics to StatsD", "addr", addr, "err", err)
+		}
+	}
+}
+
+// Graphite pushes the metrics of r to the carbon plaintext listener on the
+// TCP address addr every d, prefixing their names with namespace. It blocks
+// forever, so it should be started in its own goroutine.
+func Graphite(r metrics.Registry, d time.Duration, addr, namespace string) {
+	rep := NewGraphiteReporter(r, addr, namespace)
+	for range time.Tick(d) {
+		if err := rep.Send(); err != nil {
+			log.Warn("Unable to send metrics to Graphite", "addr", addr, "err", err)
+		}
+	}
+}
+
+// point is the value of a metric at the time of a push
+type point struct {
+	name    string
+	value   float64
+	counter bool // the value is a monotonic count rather than a gauge
+}
+
+// collect returns the points of the metrics of r, with histograms, meters
+// and timers broken down into their count, rates and percentiles
+func collect(r metrics.Registry, namespace string) []point {
+	var points []point
+	add := func(name, field string, value float64, counter bool) {
+		points = append(points, point{name: sanitize(namespace + name + "." + field), value: value, counter: counter})
+	}
+	r.Each(func(name string, i interface{}) {
+		switch metric := i.(type) {
+		case metrics.Counter:
+			add(name, "count", float64(metric.Count()), true)
+		case metrics.Gauge:
+			add(name, "gauge", float64(metric.Value()), false)
+		case metrics.GaugeFloat64:
+			add(name, "gauge", metric.Value(), false)
+		case metrics.Histogram:
+			h := metric.Snapshot()
+			ps := h.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+			add(name, "count", float64(h.Count()), true)
+			add(name, "min", float64(h.Min()), false)
+			add(name, "max", float64(h.Max()), false)
+			add(name, "mean", h.Mean(), false)
+			add(name, "stddev", h.StdDev(), false)
+			add(name, "p50", ps[0], false)
+			add(name, "p75", ps[1], false)
+			add(name, "p95", ps[2], false)
+			add(name, "p99", ps[3], false)
+			add(name, "p999", ps[4], false)
+		case metrics.Meter:
+			m := metric.Snapshot()
+			add(name, "count", float64(m.Count()), true)
+			add(name, "m1", m.Rate1(), false)
+			add(name, "m5", m.Rate5(), false)
+			add(name, "m15", m.Rate15(), false)
+			add(name, "mean", m.RateMean(), false)
+		case metrics.Timer:
+			t := metric.Snapshot()
+			ps := t.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+			add(name, "count", float64(t.Count()), true)
+			add(name, "min", float64(t.Min()), false)
+			add(name, "max", float64(t.Max()), false)
+			add(name, "mean", t.Mean(), false)
+			add(name, "stddev", t.StdDev(), false)
+			add(name, "p50", ps[0], false)
+			add(name, "p75", ps[1], false)
+			add(name, "p95", ps[2], false)
+			add(name, "p99", ps[3], false)
+			add(name, "p999", ps[4], false)
+			add(name, "m1", t.Rate1(), false)
+			add(name, "m5", t.Rate5(), false)
+			add(name, "m15", t.Rate15(), false)
+			add(name, "meanrate", t.RateMean(), false)
+		case metrics.ResettingTimer:
+			// the values of a resetting timer are those since the last push
+			t := metric.Snapshot()
+			val := t.Values()
+			if len(val) == 0 {
+				return
+			}
+			ps := t.Percentiles([]float64{50, 95, 99})
+			add(name, "count", float64(len(val)), false)
+			add(name, "min", float64(val[0]), false)
+			add(name, "max", float64(val[len(val)-1]), false)
+			add(name, "mean", t.Mean(), false)
+			add(name, "p50", float64(ps[0]), false)
+			add(name, "p95", float64(ps[1]), false)
+			add(name, "p99", float64(ps[2]), false)
+		}
+	})
+	return points
+}
+
+// sanitize replaces the characters of a metric name which are neither
+// alphanumeric nor separators with underscores, since colons and pipes
+// delimit StatsD lines and whitespace delimits Graphite lines
+func sanitize(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// StatsDReporter pushes the metrics of a registry to a StatsD daemon each
+// time Send is called. Counts are pushed as increments since the previous
+// push and all other values as gauges.
+type StatsDReporter struct {
+	reg       metrics.Registry
+	addr      string
+	namespace string
+	counts    map[string]float64 // counts at the previous push
+}
+
+// NewStatsDReporter creates a StatsDReporter pushing the metrics of r to the
+// UDP address addr, prefixing their names with namespace
+func NewStatsDReporter(r metrics.Registry, addr, namespace string) *StatsDReporter {
+	return &StatsDReporter{
+		reg:       r,
+		addr:      addr,
+		namespace: namespace,
+		counts:    make(map[string]float64),
+	}
+}
+
+// Send pushes the current values of the metrics
+func (r *StatsDReporter) Send() error {
+	conn, err := net.Dial("udp", r.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var buf bytes.Buffer
+	for _, p := range collect(r.reg, r.namespace) {
+		var line string
+		if p.counter {
+			delta := p.value - r.counts[p.name]
+			r.counts[p.name] = p.value
+			if delta == 0 {
+				continue
+			}
+			line = fmt.Sprintf("%s:%s|c\n", p.name, formatFloat(delta))
+		} else {
+			line = fmt.Sprintf("%s:%s|g\n", p.name, formatFloat(p.value))
+			if p.value < 0 {
+				// a signed gauge value changes the gauge by the value,
+				// so the gauge is reset before setting a negative value
+				line = fmt.Sprintf("%s:0|g\n", p.name) + line
+			}
+		}
+		if buf.Len() > 0 && buf.Len()+len(line) > maxPacketSize {
+			if _, err := conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		_, err = conn.Write(buf.Bytes())
+	}
+	return err
+}
+
+// GraphiteReporter pushes the metrics of a registry to a carbon server each
+// time Send is called, with the time of the push
+type GraphiteReporter struct {
+	reg       metrics.Registry
+	addr      string
+	namespace string
+}
+
+// NewGraphiteReporter creates a GraphiteReporter pushing the metrics of r to
+// the TCP address addr, prefixing their names with namespace
+func NewGraphiteReporter(r metrics.Registry, addr, namespace string) *GraphiteReporter {
+	return &GraphiteReporter{reg: r, addr: addr, namespace: namespace}
+}
+
+// Send pushes the current values of the metrics
+func (r *GraphiteReporter) Send() error {
+	conn, err := net.DialTimeout("tcp", r.addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	now := time.Now().Unix()
+	w := bufio.NewWriter(conn)
+	for _, p := range collect(r.reg, r.namespace) {
+		fmt.Fprintf(w, "%s %s %d\n", p.name, formatFloat(p.value), now)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package statsd
+
+import (
+	"bufio"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func init() {
+	metrics.Enabled = true
+}
+
+func newTestRegistry() (metrics.Registry, metrics.Counter) {
+	r := metrics.NewRegistry()
+	counter := metrics.NewCounter()
+	counter.Inc(3)
+	r.Register("chunks,stored", counter)
+	gauge := metrics.NewGauge()
+	gauge.Update(-2)
+	r.Register("peers", gauge)
+	return r, counter
+}
+
+// readPacket returns the sorted lines of a StatsD packet received by conn
+func readPacket(t *testing.T, conn net.PacketConn) []string {
+	buf := make([]byte, maxPacketSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(buf[:n])), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func TestStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	r, counter := newTestRegistry()
+	rep := NewStatsDReporter(r, conn.LocalAddr().String(), "swarm.")
+	if err := rep.Send(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"swarm.chunks_stored.count:3|c", "swarm.peers.gauge:-2|g", "swarm.peers.gauge:0|g"}
+	if lines := readPacket(t, conn); strings.Join(lines, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, lines)
+	}
+
+	// counters are pushed as increments since the previous push
+	counter.Inc(2)
+	if err := rep.Send(); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"swarm.chunks_stored.count:2|c", "swarm.peers.gauge:-2|g", "swarm.peers.gauge:0|g"}
+	if lines := readPacket(t, conn); strings.Join(lines, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, lines)
+	}
+}
+
+func TestGraphite(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	linesC := make(chan []string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(linesC)
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		sort.Strings(lines)
+		linesC <- lines
+	}()
+
+	r, _ := newTestRegistry()
+	rep := NewGraphiteReporter(r, listener.Addr().String(), "swarm.")
+	if err := rep.Send(); err != nil {
+		t.Fatal(err)
+	}
+	lines := <-linesC
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %v", lines)
+	}
+	for i, prefix := range []string{"swarm.chunks_stored.count 3 ", "swarm.peers.gauge -2 "} {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Fatalf("expected line with prefix %q, got %q", prefix, lines[i])
+		}
+	}
+}
@@ -19,7 +19,10 @@ package node
 import (
 	"context"
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -75,6 +78,65 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// DisconnectPeer drops the connection to a remote node, identified either by its
+// enode URL or its node ID, sending it the given disconnect reason. Unlike
+// RemovePeer, it does not prevent the peer from reconnecting later.
+func (api *PrivateAdminAPI) DisconnectPeer(id string, reason *string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	disc := p2p.DiscRequested
+	if reason != nil {
+		if disc, err = p2p.ParseDiscReason(*reason); err != nil {
+			return false, err
+		}
+	}
+	for _, peer := range server.Peers() {
+		if peer.ID() == node.ID {
+			peer.Disconnect(disc)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddTrustedPeer allows a remote node to always connect, even if slots are full
+func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.AddTrustedPeer(node)
+	return true, nil
+}
+
+// RemoveTrustedPeer removes a remote node from the trusted peer set, but it
+// does not disconnect it automatically.
+func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.RemoveTrustedPeer(node)
+	return true, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -157,7 +219,11 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		}
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts); err != nil {
+	auth, err := api.node.config.RPCAuth()
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts, auth); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -211,7 +277,11 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 		}
 	}
 
-	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll); err != nil {
+	auth, err := api.node.config.RPCAuth()
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll, auth); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -251,6 +321,16 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// ProtocolTraffic retrieves the number and size of the messages exchanged with
+// all peers, aggregated per protocol version.
+func (api *PublicAdminAPI) ProtocolTraffic() (map[string]*p2p.ProtocolStats, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.ProtocolTraffic(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -261,6 +341,12 @@ func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
 	return server.NodeInfo(), nil
 }
 
+// Health retrieves the aggregate readiness and health of the node and its
+// services.
+func (api *PublicAdminAPI) Health() (*Health, error) {
+	return api.node.Health()
+}
+
 // Datadir retrieves the current data directory the node is using.
 func (api *PublicAdminAPI) Datadir() string {
 	return api.node.DataDir()
@@ -270,16 +356,121 @@ func (api *PublicAdminAPI) Datadir() string {
 // both secure and unsecure RPC channels.
 type PublicDebugAPI struct {
 	node *Node // Node interfaced by this API
+
+	start  time.Time                     // Creation time of the API, the base of the first delta
+	deltas map[string]metricsDeltaMarker // Metric counts of the previous delta call
+	lock   sync.Mutex                    // Lock protecting the delta markers
 }
 
 // NewPublicDebugAPI creates a new API definition for the public debug methods
 // of the node itself.
 func NewPublicDebugAPI(node *Node) *PublicDebugAPI {
-	return &PublicDebugAPI{node: node}
+	return &PublicDebugAPI{
+		node:   node,
+		start:  time.Now(),
+		deltas: make(map[string]metricsDeltaMarker),
+	}
+}
+
+// MetricsFilter selects the metrics and their measurements to retrieve. All the
+// fields are optional, the zero value selecting everything with the defaults.
+type MetricsFilter struct {
+	Names       []string  `json:"names"`       // Glob patterns or prefixes of the metric names to retrieve
+	Rates       []string  `json:"rates"`       // Moving average rates to retrieve (1m, 5m, 15m, mean)
+	Percentiles []float64 `json:"percentiles"` // Percentiles of timers to retrieve, between 0 and 1
+	Delta       bool      `json:"delta"`       // Whether to include the counts since the previous delta call
 }
 
-// Metrics retrieves all the known system metric collected by the node.
-func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
+// defaultMetricsPercentiles are the timer percentiles retrieved if none are
+// requested explicitly.
+var defaultMetricsPercentiles = []float64{0.05, 0.2, 0.5, 0.8, 0.95}
+
+// metricsRates are the moving average rates of meters and timers which can be
+// selected by a MetricsFilter, along with their output fields.
+var metricsRates = []struct {
+	name      string                     // Name of the rate in the filter
+	raw       string                     // Field of the rate in raw output
+	formatted string                     // Field of the rate in formatted output (empty if none)
+	window    float64                    // Averaging window in seconds, zero for the mean
+	rate      func(metricsRater) float64 // Accessor of the rate
+}{
+	{"1m", "AvgRate01Min", "Avg01Min", 60, metricsRater.Rate1},
+	{"5m", "AvgRate05Min", "Avg05Min", 300, metricsRater.Rate5},
+	{"15m", "AvgRate15Min", "Avg15Min", 900, metricsRater.Rate15},
+	{"mean", "MeanRate", "", 0, metricsRater.RateMean},
+}
+
+// metricsRater is the common interface of meters and timers.
+type metricsRater interface {
+	Count() int64
+	Rate1() float64
+	Rate5() float64
+	Rate15() float64
+	RateMean() float64
+}
+
+// metricsDeltaMarker is the count of a metric at the time of a delta call.
+type metricsDeltaMarker struct {
+	count int64
+	time  time.Time
+}
+
+// match checks whether a metric name is selected by the filter.
+func (f *MetricsFilter) match(name string) bool {
+	if len(f.Names) == 0 {
+		return true
+	}
+	for _, pattern := range f.Names {
+		if strings.HasPrefix(name, pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Metrics retrieves all the known system metric collected by the node, or the
+// ones selected by the optional filter.
+//
+// In delta mode, the counts accumulated since the previous delta call and their
+// rate over the elapsed time are included too. The markers of the previous call
+// are shared by all the clients of the node.
+func (api *PublicDebugAPI) Metrics(raw bool, filter *MetricsFilter) (map[string]interface{}, error) {
+	if filter == nil {
+		filter = new(MetricsFilter)
+	}
+	for _, pattern := range filter.Names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %v", pattern, err)
+		}
+	}
+	rates := make(map[string]bool)
+	for _, name := range filter.Rates {
+		known := false
+		for _, rate := range metricsRates {
+			known = known || rate.name == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown rate %q", name)
+		}
+		rates[name] = true
+	}
+	if len(rates) == 0 {
+		for _, rate := range metricsRates {
+			rates[rate.name] = true
+		}
+	}
+	percentiles := filter.Percentiles
+	if len(percentiles) == 0 {
+		percentiles = defaultMetricsPercentiles
+	}
+	for _, p := range percentiles {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("invalid percentile %v", p)
+		}
+	}
 	// Create a rate formatter
 	units := []string{"", "K", "M", "G", "T", "E", "P"}
 	round := func(value float64, prec int) string {
@@ -292,9 +483,40 @@ func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
 	format := func(total float64, rate float64) string {
 		return fmt.Sprintf("%s (%s/s)", round(total, 0), round(rate, 2))
 	}
-	// Iterate over all the metrics, and just dump for now
+	// Create the delta tracker, measuring counts since the previous delta call
+	if filter.Delta {
+		api.lock.Lock()
+		defer api.lock.Unlock()
+	}
+	now := time.Now()
+	delta := func(name string, count int64, fields map[string]interface{}) {
+		if !filter.Delta {
+			return
+		}
+		last, ok := api.deltas[name]
+		if !ok {
+			last.time = api.start
+		}
+		api.deltas[name] = metricsDeltaMarker{count: count, time: now}
+
+		diff, rate := float64(count-last.count), 0.0
+		if elapsed := now.Sub(last.time).Seconds(); elapsed > 0 {
+			rate = diff / elapsed
+		}
+		if raw {
+			fields["Delta"], fields["DeltaRate"] = diff, rate
+		} else {
+			fields["Delta"] = format(diff, rate)
+		}
+	}
+	// Iterate over all the selected metrics, and just dump for now
 	counters := make(map[string]interface{})
 	metrics.DefaultRegistry.Each(func(name string, metric interface{}) {
+		if !filter.match(name) {
+			return
+		}
+		fullname := name
+
 		// Create or retrieve the counter hierarchy for this metric
 		root, parts := counters, strings.Split(name, "/")
 		for _, part := range parts[:len(parts)-1] {
@@ -306,81 +528,65 @@ func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
 		name = parts[len(parts)-1]
 
 		// Fill the counter with the metric details, formatting if requested
-		if raw {
-			switch metric := metric.(type) {
-			case metrics.Counter:
-				root[name] = map[string]interface{}{
-					"Overall": float64(metric.Count()),
-				}
-
-			case metrics.Meter:
-				root[name] = map[string]interface{}{
-					"AvgRate01Min": metric.Rate1(),
-					"AvgRate05Min": metric.Rate5(),
-					"AvgRate15Min": metric.Rate15(),
-					"MeanRate":     metric.RateMean(),
-					"Overall":      float64(metric.Count()),
+		fields := make(map[string]interface{})
+		switch metric := metric.(type) {
+		case metrics.Counter:
+			fields["Overall"] = float64(metric.Count())
+			delta(fullname, metric.Count(), fields)
+
+		case metrics.Meter:
+			fillMetricsRates(fields, metric, raw, rates, format)
+			delta(fullname, metric.Count(), fields)
+
+		case metrics.Timer:
+			fillMetricsRates(fields, metric, raw, rates, format)
+			delta(fullname, metric.Count(), fields)
+
+			values := metric.Percentiles(percentiles)
+			quantiles := make(map[string]interface{}, len(percentiles))
+			for i, p := range percentiles {
+				key := strconv.FormatFloat(p*100, 'f', -1, 64)
+				if raw {
+					quantiles[key] = values[i]
+				} else {
+					quantiles[key] = time.Duration(values[i]).String()
 				}
-
-			case metrics.Timer:
-				root[name] = map[string]interface{}{
-					"AvgRate01Min": metric.Rate1(),
-					"AvgRate05Min": metric.Rate5(),
-					"AvgRate15Min": metric.Rate15(),
-					"MeanRate":     metric.RateMean(),
-					"Overall":      float64(metric.Count()),
-					"Percentiles": map[string]interface{}{
-						"5":  metric.Percentile(0.05),
-						"20": metric.Percentile(0.2),
-						"50": metric.Percentile(0.5),
-						"80": metric.Percentile(0.8),
-						"95": metric.Percentile(0.95),
-					},
-				}
-
-			default:
-				root[name] = "Unknown metric type"
 			}
-		} else {
-			switch metric := metric.(type) {
-			case metrics.Counter:
-				root[name] = map[string]interface{}{
-					"Overall": float64(metric.Count()),
-				}
-
-			case metrics.Meter:
-				root[name] = map[string]interface{}{
-					"Avg01Min": format(metric.Rate1()*60, metric.Rate1()),
-					"Avg05Min": format(metric.Rate5()*300, metric.Rate5()),
-					"Avg15Min": format(metric.Rate15()*900, metric.Rate15()),
-					"Overall":  format(float64(metric.Count()), metric.RateMean()),
-				}
-
-			case metrics.Timer:
-				root[name] = map[string]interface{}{
-					"Avg01Min": format(metric.Rate1()*60, metric.Rate1()),
-					"Avg05Min": format(metric.Rate5()*300, metric.Rate5()),
-					"Avg15Min": format(metric.Rate15()*900, metric.Rate15()),
-					"Overall":  format(float64(metric.Count()), metric.RateMean()),
-					"Maximum":  time.Duration(metric.Max()).String(),
-					"Minimum":  time.Duration(metric.Min()).String(),
-					"Percentiles": map[string]interface{}{
-						"5":  time.Duration(metric.Percentile(0.05)).String(),
-						"20": time.Duration(metric.Percentile(0.2)).String(),
-						"50": time.Duration(metric.Percentile(0.5)).String(),
-						"80": time.Duration(metric.Percentile(0.8)).String(),
-						"95": time.Duration(metric.Percentile(0.95)).String(),
-					},
-				}
-
-			default:
-				root[name] = "Unknown metric type"
+			fields["Percentiles"] = quantiles
+			if !raw {
+				fields["Maximum"] = time.Duration(metric.Max()).String()
+				fields["Minimum"] = time.Duration(metric.Min()).String()
 			}
+
+		default:
+			root[name] = "Unknown metric type"
+			return
 		}
+		root[name] = fields
 	})
 	return counters, nil
 }
 
+// fillMetricsRates adds the overall count and the selected moving average rates
+// of a meter or timer to its output fields.
+func fillMetricsRates(fields map[string]interface{}, metric metricsRater, raw bool, rates map[string]bool, format func(float64, float64) string) {
+	if raw {
+		fields["Overall"] = float64(metric.Count())
+	} else {
+		fields["Overall"] = format(float64(metric.Count()), metric.RateMean())
+	}
+	for _, rate := range metricsRates {
+		if !rates[rate.name] {
+			continue
+		}
+		if raw {
+			fields[rate.raw] = rate.rate(metric)
+		} else if rate.formatted != "" {
+			fields[rate.formatted] = format(rate.rate(metric)*rate.window, rate.rate(metric))
+		}
+	}
+}
+
 // PublicWeb3API offers helper utils
 type PublicWeb3API struct {
 	stack *Node
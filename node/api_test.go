@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that debug_metrics only returns the metrics and measurements selected
+// by the filter, and tracks counts between delta calls.
+func TestMetricsFilter(t *testing.T) {
+	counter, other, timer := new(metrics.StandardCounter), new(metrics.StandardCounter), metrics.NilTimer{}
+	metrics.DefaultRegistry.Register("apitest/counter", counter)
+	metrics.DefaultRegistry.Register("apitest/other", other)
+	metrics.DefaultRegistry.Register("apitest/timer", timer)
+	defer metrics.DefaultRegistry.Unregister("apitest/counter")
+	defer metrics.DefaultRegistry.Unregister("apitest/other")
+	defer metrics.DefaultRegistry.Unregister("apitest/timer")
+
+	api := NewPublicDebugAPI(nil)
+
+	// Filter the metrics by name, checking the selected rates and percentiles
+	result, err := api.Metrics(true, &MetricsFilter{
+		Names:       []string{"apitest/c*", "apitest/tim"},
+		Rates:       []string{"1m"},
+		Percentiles: []float64{0.99},
+	})
+	if err != nil {
+		t.Fatalf("failed to retrieve metrics: %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("metric group count mismatch: have %d, want 1", len(result))
+	}
+	group := result["apitest"].(map[string]interface{})
+	if len(group) != 2 {
+		t.Fatalf("metric count mismatch: have %d, want 2: %v", len(group), group)
+	}
+	fields := group["timer"].(map[string]interface{})
+	if _, ok := fields["AvgRate01Min"]; !ok {
+		t.Errorf("selected rate missing: %v", fields)
+	}
+	if _, ok := fields["AvgRate05Min"]; ok {
+		t.Errorf("unselected rate returned: %v", fields)
+	}
+	if percentiles := fields["Percentiles"].(map[string]interface{}); len(percentiles) != 1 || percentiles["99"] == nil {
+		t.Errorf("percentiles mismatch: %v", percentiles)
+	}
+	// Check that the delta mode reports the counts since the previous call
+	for i, inc := range []int64{5, 3, 0} {
+		counter.Inc(inc)
+
+		result, err := api.Metrics(true, &MetricsFilter{Names: []string{"apitest/counter"}, Delta: true})
+		if err != nil {
+			t.Fatalf("delta %d: failed to retrieve metrics: %v", i, err)
+		}
+		fields := result["apitest"].(map[string]interface{})["counter"].(map[string]interface{})
+		if delta := fields["Delta"].(float64); delta != float64(inc) {
+			t.Errorf("delta %d: count mismatch: have %v, want %d", i, delta, inc)
+		}
+	}
+	// Check that invalid filters are rejected
+	if _, err := api.Metrics(true, &MetricsFilter{Rates: []string{"2m"}}); err == nil {
+		t.Errorf("unknown rate accepted")
+	}
+	if _, err := api.Metrics(true, &MetricsFilter{Percentiles: []float64{1.5}}); err == nil {
+		t.Errorf("invalid percentile accepted")
+	}
+}
@@ -18,6 +18,7 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -29,10 +30,13 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const (
@@ -66,6 +70,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DatabaseEngine is the name of the key-value store engine new databases are
+	// created with. Existing databases are always opened with the engine they were
+	// created with, and it is an error to request a different one.
+	DatabaseEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -82,6 +91,13 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreScryptN, KeyStoreScryptR and KeyStoreScryptP override the scrypt
+	// parameters the key store encrypts new keys with. Zero values retain the
+	// standard (or lightweight) defaults.
+	KeyStoreScryptN int `toml:",omitempty"`
+	KeyStoreScryptR int `toml:",omitempty"`
+	KeyStoreScryptP int `toml:",omitempty"`
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
@@ -119,6 +135,10 @@ type Config struct {
 	// exposed.
 	HTTPModules []string `toml:",omitempty"`
 
+	// HTTPTimeouts allows for customization of the timeout and keep-alive
+	// settings used by the HTTP RPC interface.
+	HTTPTimeouts rpc.HTTPTimeouts
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -145,6 +165,26 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSSubscriptionBuffer configures the buffering of the subscription
+	// notifications sent to the websocket RPC clients, so that slow clients
+	// cannot block the notifying services.
+	WSSubscriptionBuffer rpc.SubscriptionBuffer `toml:",omitempty"`
+
+	// JWTSecret is the path of the file holding the hex encoded shared secret of
+	// the JSON Web Tokens authenticating the clients of the HTTP and websocket RPC
+	// servers. If both JWTSecret and RPCTokens are empty, the clients do not need
+	// to authenticate.
+	JWTSecret string `toml:",omitempty"`
+
+	// RPCTokens maps the API tokens authenticating the clients of the HTTP and
+	// websocket RPC servers to the API namespaces they may call, "*" granting all
+	// namespaces.
+	RPCTokens map[string][]string `toml:",omitempty"`
+
+	// RPCLimits are the rate limits and execution timeouts of the calls to the
+	// HTTP and websocket RPC servers, keyed by method or API namespace.
+	RPCLimits map[string]rpc.MethodLimit `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -224,6 +264,24 @@ func DefaultWSEndpoint() string {
 	return config.WSEndpoint()
 }
 
+// RPCAuth returns the authentication of the clients of the HTTP and websocket
+// RPC servers, reading the JWT secret from its file.
+func (c *Config) RPCAuth() (*rpc.AuthConfig, error) {
+	auth := &rpc.AuthConfig{Tokens: c.RPCTokens}
+	if c.JWTSecret != "" {
+		data, err := ioutil.ReadFile(c.JWTSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT secret: %v", err)
+		}
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil || len(secret) < 32 {
+			return nil, fmt.Errorf("invalid JWT secret in %s, expected at least 32 hex encoded bytes", c.JWTSecret)
+		}
+		auth.JWTSecret = secret
+	}
+	return auth, nil
+}
+
 // NodeName returns the devp2p node identifier.
 func (c *Config) NodeName() string {
 	name := c.name()
@@ -285,6 +343,29 @@ func (c *Config) resolvePath(path string) string {
 	return filepath.Join(c.instanceDir(), path)
 }
 
+// openDatabaseWithFreezer opens a key-value database within the instance
+// directory, wrapping it with a freezer stored in the given directory, or the
+// ancient directory of the database if empty.
+func (c *Config) openDatabaseWithFreezer(name string, cache, handles int, freezer, namespace string) (ethdb.Database, error) {
+	root := c.resolvePath(name)
+	switch {
+	case freezer == "":
+		freezer = filepath.Join(root, "ancient")
+	case !filepath.IsAbs(freezer):
+		freezer = c.resolvePath(freezer)
+	}
+	kvdb, err := ethdb.Open(c.DatabaseEngine, root, cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, freezer, namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
 func (c *Config) instanceDir() string {
 	if c.DataDir == "" {
 		return ""
@@ -347,14 +428,24 @@ func (c *Config) parsePersistentNodes(path string) []*discover.Node {
 	if c.DataDir == "" {
 		return nil
 	}
-	if _, err := os.Stat(path); err != nil {
+	nodes, err := loadPersistentNodes(path)
+	if err != nil {
+		log.Error(fmt.Sprintf("Can't load node file %s: %v", path, err))
 		return nil
 	}
+	return nodes
+}
+
+// loadPersistentNodes loads a list of discovery node URLs from a .json file,
+// skipping invalid entries. A missing file is treated as an empty list.
+func loadPersistentNodes(path string) ([]*discover.Node, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
 	// Load the nodes from the config file.
 	var nodelist []string
 	if err := common.LoadJSON(path, &nodelist); err != nil {
-		log.Error(fmt.Sprintf("Can't load node file %s: %v", path, err))
-		return nil
+		return nil, err
 	}
 	// Interpret the list as a discovery node array
 	var nodes []*discover.Node
@@ -369,16 +460,26 @@ func (c *Config) parsePersistentNodes(path string) []*discover.Node {
 		}
 		nodes = append(nodes, node)
 	}
-	return nodes
+	return nodes, nil
 }
 
 // AccountConfig determines the settings for scrypt and keydirectory
-func (c *Config) AccountConfig() (int, int, string, error) {
-	scryptN := keystore.StandardScryptN
-	scryptP := keystore.StandardScryptP
+func (c *Config) AccountConfig() (keystore.ScryptParams, string, error) {
+	scrypt := keystore.ScryptParams{N: keystore.StandardScryptN, R: keystore.StandardScryptR, P: keystore.StandardScryptP}
 	if c.UseLightweightKDF {
-		scryptN = keystore.LightScryptN
-		scryptP = keystore.LightScryptP
+		scrypt.N, scrypt.P = keystore.LightScryptN, keystore.LightScryptP
+	}
+	if c.KeyStoreScryptN != 0 {
+		scrypt.N = c.KeyStoreScryptN
+	}
+	if c.KeyStoreScryptR != 0 {
+		scrypt.R = c.KeyStoreScryptR
+	}
+	if c.KeyStoreScryptP != 0 {
+		scrypt.P = c.KeyStoreScryptP
+	}
+	if err := scrypt.Validate(); err != nil {
+		return scrypt, "", err
 	}
 
 	var (
@@ -397,11 +498,14 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 	case c.KeyStoreDir != "":
 		keydir, err = filepath.Abs(c.KeyStoreDir)
 	}
-	return scryptN, scryptP, keydir, err
+	return scrypt, keydir, err
 }
 
 func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
-	scryptN, scryptP, keydir, err := conf.AccountConfig()
+	scrypt, keydir, err := conf.AccountConfig()
+	if err != nil {
+		return nil, "", err
+	}
 	var ephemeral string
 	if keydir == "" {
 		// There is no datadir.
@@ -417,7 +521,7 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	}
 	// Assemble the account manager and supported backends
 	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		keystore.NewKeyStoreWithParams(keydir, scrypt),
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
@@ -148,3 +148,45 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that the JWT secret of the RPC authentication is loaded from its file.
+func TestRPCAuthSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Without a secret or tokens clients don't authenticate
+	if auth, err := (&Config{}).RPCAuth(); err != nil || auth.Enabled() {
+		t.Fatalf("expected authentication to be disabled, got %v %v", auth, err)
+	}
+	secret := bytes.Repeat([]byte{0x42}, 32)
+	for _, test := range []struct {
+		content string
+		secret  []byte
+	}{
+		{"0x4242424242424242424242424242424242424242424242424242424242424242\n", secret},
+		{"4242424242424242424242424242424242424242424242424242424242424242", secret},
+		{"0x42", nil},
+		{"not hex", nil},
+	} {
+		path := filepath.Join(dir, "jwtsecret")
+		if err := ioutil.WriteFile(path, []byte(test.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		auth, err := (&Config{JWTSecret: path}).RPCAuth()
+		if test.secret == nil {
+			if err == nil {
+				t.Errorf("%q: expected an invalid secret", test.content)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(auth.JWTSecret, test.secret) || !auth.Enabled() {
+			t.Errorf("%q: unexpected secret %x, err %v", test.content, auth.JWTSecret, err)
+		}
+	}
+	if _, err := (&Config{JWTSecret: filepath.Join(dir, "missing")}).RPCAuth(); err == nil {
+		t.Fatal("expected a missing secret file to fail")
+	}
+}
@@ -55,9 +55,13 @@ func (e *DuplicateServiceError) Error() string {
 type StopError struct {
 	Server   error
 	Services map[reflect.Type]error
+	Hooks    []error
 }
 
 // Error generates a textual representation of the stop error.
 func (e *StopError) Error() string {
+	if len(e.Hooks) > 0 {
+		return fmt.Sprintf("server: %v, services: %v, hooks: %v", e.Server, e.Services, e.Hooks)
+	}
 	return fmt.Sprintf("server: %v, services: %v", e.Server, e.Services)
 }
@@ -0,0 +1,67 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+// ServiceHealth is the readiness and health reported by a service.
+type ServiceHealth struct {
+	Ready   bool   `json:"ready"`             // Whether the service is ready to serve requests (e.g. synchronised)
+	Healthy bool   `json:"healthy"`           // Whether the service is operating properly
+	Message string `json:"message,omitempty"` // Details of the reported state, if not ready or healthy
+}
+
+// HealthReporter is an optional interface of the services able to report their
+// readiness and health. Services not implementing it are considered ready and
+// healthy while running.
+type HealthReporter interface {
+	// Health retrieves the current readiness and health of the service. It is
+	// called concurrently with the operation of the service and must not block.
+	Health() ServiceHealth
+}
+
+// Health is the aggregate readiness and health of a node and its services.
+type Health struct {
+	Ready    bool                     `json:"ready"`    // Whether all the services are ready
+	Healthy  bool                     `json:"healthy"`  // Whether all the services are healthy
+	Peers    int                      `json:"peers"`    // Number of connected peers
+	Services map[string]ServiceHealth `json:"services"` // Health of the individual services, keyed by type
+}
+
+// Health retrieves the aggregate readiness and health of the running node.
+func (n *Node) Health() (*Health, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	health := &Health{
+		Ready:    true,
+		Healthy:  true,
+		Peers:    n.server.PeerCount(),
+		Services: make(map[string]ServiceHealth),
+	}
+	for _, kind := range n.serviceKinds {
+		status := ServiceHealth{Ready: true, Healthy: true}
+		if reporter, ok := n.services[kind].(HealthReporter); ok {
+			status = reporter.Health()
+		}
+		health.Ready = health.Ready && status.Ready
+		health.Healthy = health.Healthy && status.Healthy
+		health.Services[kind.String()] = status
+	}
+	return health, nil
+}
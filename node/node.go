@@ -50,6 +50,10 @@ type Node struct {
 
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
+	serviceKinds []reflect.Type           // Types of the running services (in startup order)
+
+	startHooks []func() error // Callbacks to run after startup (in registration order)
+	stopHooks  []func() error // Callbacks to run before shutdown (in reverse registration order)
 
 	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
@@ -134,6 +138,34 @@ func (n *Node) Register(constructor ServiceConstructor) error {
 	return nil
 }
 
+// RegisterStartHook injects a callback to run every time the node starts, after
+// all the services and RPC endpoints were started. Hooks run in the order they
+// were registered, a failing one aborting the startup of the node.
+func (n *Node) RegisterStartHook(hook func() error) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.server != nil {
+		return ErrNodeRunning
+	}
+	n.startHooks = append(n.startHooks, hook)
+	return nil
+}
+
+// RegisterStopHook injects a callback to run every time the node stops, before
+// any of the RPC endpoints and services are stopped. Hooks run in the reverse
+// order they were registered, all of them running even if some fail.
+func (n *Node) RegisterStopHook(hook func() error) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.server != nil {
+		return ErrNodeRunning
+	}
+	n.stopHooks = append(n.stopHooks, hook)
+	return nil
+}
+
 // Start create a live P2P node and starts running it.
 func (n *Node) Start() error {
 	n.lock.Lock()
@@ -167,6 +199,7 @@ func (n *Node) Start() error {
 
 	// Otherwise copy and specialize the P2P configuration
 	services := make(map[reflect.Type]Service)
+	kinds := make([]reflect.Type, 0, len(n.serviceFuncs))
 	for _, constructor := range n.serviceFuncs {
 		// Create a new context for the particular service
 		ctx := &ServiceContext{
@@ -188,45 +221,65 @@ func (n *Node) Start() error {
 			return &DuplicateServiceError{Kind: kind}
 		}
 		services[kind] = service
+		kinds = append(kinds, kind)
 	}
 	// Gather the protocols and start the freshly assembled P2P server
-	for _, service := range services {
-		running.Protocols = append(running.Protocols, service.Protocols()...)
+	for _, kind := range kinds {
+		running.Protocols = append(running.Protocols, services[kind].Protocols()...)
 	}
 	if err := running.Start(); err != nil {
 		return convertFileLockError(err)
 	}
-	// Start each of the services
-	started := []reflect.Type{}
-	for kind, service := range services {
+	// Start each of the services in dependency order
+	for i, kind := range kinds {
 		// Start the next service, stopping all previous upon failure
-		if err := service.Start(running); err != nil {
-			for _, kind := range started {
-				services[kind].Stop()
-			}
+		if err := services[kind].Start(running); err != nil {
+			stopServices(services, kinds[:i])
 			running.Stop()
 
 			return err
 		}
-		// Mark the service started for potential cleanup
-		started = append(started, kind)
 	}
-	// Lastly start the configured RPC interfaces
+	// Start the configured RPC interfaces and run the startup hooks
 	if err := n.startRPC(services); err != nil {
-		for _, service := range services {
-			service.Stop()
-		}
+		stopServices(services, kinds)
 		running.Stop()
 		return err
 	}
+	for _, hook := range n.startHooks {
+		if err := hook(); err != nil {
+			n.stopWS()
+			n.stopHTTP()
+			n.stopIPC()
+			n.stopInProc()
+			n.rpcAPIs = nil
+			stopServices(services, kinds)
+			running.Stop()
+			return err
+		}
+	}
 	// Finish initializing the startup
 	n.services = services
+	n.serviceKinds = kinds
 	n.server = running
 	n.stop = make(chan struct{})
 
+	n.watchNodeLists(running, n.stop)
 	return nil
 }
 
+// stopServices terminates the given services in the reverse order of their
+// startup, returning the errors of the ones failing to stop.
+func stopServices(services map[reflect.Type]Service, kinds []reflect.Type) map[reflect.Type]error {
+	failures := make(map[reflect.Type]error)
+	for i := len(kinds) - 1; i >= 0; i-- {
+		if err := services[kinds[i]].Stop(); err != nil {
+			failures[kinds[i]] = err
+		}
+	}
+	return failures
+}
+
 func (n *Node) openDataDir() error {
 	if n.config.DataDir == "" {
 		return nil // ephemeral
@@ -255,6 +308,10 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
 	}
+	auth, err := n.config.RPCAuth()
+	if err != nil {
+		return err
+	}
 	// Start the various API endpoints, terminating all in case of errors
 	if err := n.startInProc(apis); err != nil {
 		return err
@@ -263,12 +320,12 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts); err != nil {
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, auth); err != nil {
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
-	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll, auth); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
@@ -331,16 +388,17 @@ func (n *Node) stopIPC() {
 }
 
 // startHTTP initializes and starts the HTTP RPC endpoint.
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string) error {
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, auth *rpc.AuthConfig) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts)
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, n.config.HTTPTimeouts, auth)
 	if err != nil {
 		return err
 	}
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	handler.SetLimits(n.config.RPCLimits)
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "auth", auth.Enabled())
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -364,16 +422,18 @@ func (n *Node) stopHTTP() {
 }
 
 // startWS initializes and starts the websocket RPC endpoint.
-func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool, auth *rpc.AuthConfig) error {
 	// Short circuit if the WS endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, auth)
 	if err != nil {
 		return err
 	}
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	handler.SetLimits(n.config.RPCLimits)
+	handler.SetSubscriptionBuffer(n.config.WSSubscriptionBuffer)
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()), "auth", auth.Enabled())
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
 	n.wsListener = listener
@@ -407,21 +467,22 @@ func (n *Node) Stop() error {
 		return ErrNodeStopped
 	}
 
-	// Terminate the API, services and the p2p server.
+	// Run the shutdown hooks, then terminate the API, services and the p2p server.
+	failure := new(StopError)
+	for i := len(n.stopHooks) - 1; i >= 0; i-- {
+		if err := n.stopHooks[i](); err != nil {
+			failure.Hooks = append(failure.Hooks, err)
+		}
+	}
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
 	n.rpcAPIs = nil
-	failure := &StopError{
-		Services: make(map[reflect.Type]error),
-	}
-	for kind, service := range n.services {
-		if err := service.Stop(); err != nil {
-			failure.Services[kind] = err
-		}
-	}
+	failure.Services = stopServices(n.services, n.serviceKinds)
+
 	n.server.Stop()
 	n.services = nil
+	n.serviceKinds = nil
 	n.server = nil
 
 	// Release instance directory lock.
@@ -441,7 +502,7 @@ func (n *Node) Stop() error {
 		keystoreErr = os.RemoveAll(n.ephemeralKeystore)
 	}
 
-	if len(failure.Services) > 0 {
+	if len(failure.Services) > 0 || len(failure.Hooks) > 0 {
 		return failure
 	}
 	if keystoreErr != nil {
@@ -570,7 +631,20 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	if n.config.DataDir == "" {
 		return ethdb.NewMemDatabase(), nil
 	}
-	return ethdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return ethdb.Open(n.config.DatabaseEngine, n.config.resolvePath(name), cache, handles)
+}
+
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's instance
+// directory, moving the ancient chain segment into a freezer. The freezer is
+// stored in the ancient directory of the database unless another is given,
+// which is resolved against the instance directory if relative. If the node
+// is ephemeral, a memory database is returned.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer, namespace string) (ethdb.Database, error) {
+	if n.config.DataDir == "" {
+		return ethdb.NewMemDatabase(), nil
+	}
+	return n.config.openDatabaseWithFreezer(name, cache, handles, freezer, namespace)
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
@@ -573,3 +573,136 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that services are started in registration order and stopped in reverse,
+// with the lifecycle hooks running after startup and before shutdown.
+func TestServiceLifeCycleOrder(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	var events []string
+
+	ids := []string{"A", "B", "C"}
+	makers := []InstrumentingWrapper{InstrumentedServiceMakerA, InstrumentedServiceMakerB, InstrumentedServiceMakerC}
+	for i, maker := range makers {
+		id := ids[i]
+		constructor := func(*ServiceContext) (Service, error) {
+			return &InstrumentedService{
+				startHook: func(*p2p.Server) { events = append(events, "start "+id) },
+				stopHook:  func() { events = append(events, "stop "+id) },
+			}, nil
+		}
+		if err := stack.Register(maker(constructor)); err != nil {
+			t.Fatalf("service %s: registration failed: %v", id, err)
+		}
+	}
+	for _, id := range []string{"1", "2"} {
+		id := id
+		stack.RegisterStartHook(func() error { events = append(events, "start hook "+id); return nil })
+		stack.RegisterStopHook(func() error { events = append(events, "stop hook "+id); return nil })
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	if err := stack.RegisterStartHook(func() error { return nil }); err != ErrNodeRunning {
+		t.Errorf("hook registration on running node mismatch: have %v, want %v", err, ErrNodeRunning)
+	}
+	if err := stack.Stop(); err != nil {
+		t.Fatalf("failed to stop protocol stack: %v", err)
+	}
+	want := []string{
+		"start A", "start B", "start C", "start hook 1", "start hook 2",
+		"stop hook 2", "stop hook 1", "stop C", "stop B", "stop A",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("lifecycle event mismatch:\nhave %v\nwant %v", events, want)
+	}
+}
+
+// Tests that a failing startup hook aborts the startup, stopping all services,
+// and that failing shutdown hooks are reported without aborting the shutdown.
+func TestLifeCycleHookFailure(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	stopped := false
+	constructor := func(*ServiceContext) (Service, error) {
+		return &InstrumentedService{stopHook: func() { stopped = true }}, nil
+	}
+	if err := stack.Register(constructor); err != nil {
+		t.Fatalf("service registration failed: %v", err)
+	}
+	failure := errors.New("fail")
+	failing := true
+	stack.RegisterStartHook(func() error {
+		if failing {
+			return failure
+		}
+		return nil
+	})
+	stack.RegisterStopHook(func() error { return failure })
+
+	if err := stack.Start(); err != failure {
+		t.Fatalf("stack startup failure mismatch: have %v, want %v", err, failure)
+	}
+	if !stopped {
+		t.Fatalf("service not stopped after aborted startup")
+	}
+	failing, stopped = false, false
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	err = stack.Stop()
+	if serr, ok := err.(*StopError); !ok || len(serr.Hooks) != 1 || serr.Hooks[0] != failure {
+		t.Fatalf("stack shutdown failure mismatch: have %v", err)
+	}
+	if !stopped {
+		t.Fatalf("service not stopped after failing shutdown hook")
+	}
+}
+
+// healthReportingService is a test service reporting a configured health.
+type healthReportingService struct {
+	NoopService
+	health ServiceHealth
+}
+
+func (s *healthReportingService) Health() ServiceHealth { return s.health }
+
+// Tests that the health of the node aggregates the reports of its services.
+func TestNodeHealth(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if _, err := stack.Health(); err != ErrNodeStopped {
+		t.Fatalf("stopped node health error mismatch: have %v, want %v", err, ErrNodeStopped)
+	}
+	reporter := &healthReportingService{health: ServiceHealth{Ready: false, Healthy: true, Message: "syncing"}}
+	stack.Register(func(*ServiceContext) (Service, error) { return reporter, nil })
+	stack.Register(NewNoopService)
+
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	health, err := stack.Health()
+	if err != nil {
+		t.Fatalf("failed to retrieve health: %v", err)
+	}
+	if health.Ready || !health.Healthy {
+		t.Errorf("aggregate health mismatch: have ready %v healthy %v, want false true", health.Ready, health.Healthy)
+	}
+	if len(health.Services) != 2 {
+		t.Fatalf("service health count mismatch: have %d, want 2", len(health.Services))
+	}
+	if status := health.Services[reflect.TypeOf(reporter).String()]; status != reporter.health {
+		t.Errorf("reported health mismatch: have %+v, want %+v", status, reporter.health)
+	}
+	if status := health.Services[reflect.TypeOf(new(NoopService)).String()]; !status.Ready || !status.Healthy {
+		t.Errorf("default health mismatch: have %+v", status)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// nodeListReloadInterval is the interval at which the static and trusted node
+// lists of the data directory are checked for changes.
+var nodeListReloadInterval = 5 * time.Second
+
+// nodeListWatcher tracks a node list file of the data directory, applying the
+// nodes added to and removed from it to the running p2p server.
+type nodeListWatcher struct {
+	path   string
+	add    func(*discover.Node)
+	remove func(*discover.Node)
+
+	nodes   map[string]*discover.Node // Currently applied nodes, keyed by URL
+	modTime time.Time                 // Modification time of the last loaded file
+	size    int64                     // Size of the last loaded file, -1 if missing
+}
+
+// newNodeListWatcher creates a watcher for the given node list file, which was
+// loaded into the given nodes on startup.
+func newNodeListWatcher(path string, nodes []*discover.Node, add, remove func(*discover.Node)) *nodeListWatcher {
+	w := &nodeListWatcher{
+		path:   path,
+		add:    add,
+		remove: remove,
+		nodes:  make(map[string]*discover.Node),
+	}
+	for _, node := range nodes {
+		w.nodes[node.String()] = node
+	}
+	w.modTime, w.size = w.stat()
+	return w
+}
+
+// stat returns the modification time and size of the node list file.
+func (w *nodeListWatcher) stat() (time.Time, int64) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}, -1
+	}
+	return info.ModTime(), info.Size()
+}
+
+// reload loads the node list if the file changed since the last check, adding
+// the new nodes and removing the ones no longer listed. A deleted file removes
+// all the nodes, an unparsable one is ignored until it is fixed.
+func (w *nodeListWatcher) reload() {
+	modTime, size := w.stat()
+	if modTime.Equal(w.modTime) && size == w.size {
+		return
+	}
+	w.modTime, w.size = modTime, size
+
+	list, err := loadPersistentNodes(w.path)
+	if err != nil {
+		log.Warn("Failed to reload node list", "path", w.path, "err", err)
+		return
+	}
+	nodes := make(map[string]*discover.Node)
+	for _, node := range list {
+		nodes[node.String()] = node
+	}
+	var added, removed int
+	for url, node := range w.nodes {
+		if _, ok := nodes[url]; !ok {
+			w.remove(node)
+			removed++
+		}
+	}
+	for url, node := range nodes {
+		if _, ok := w.nodes[url]; !ok {
+			w.add(node)
+			added++
+		}
+	}
+	w.nodes = nodes
+	log.Info("Reloaded node list", "path", w.path, "added", added, "removed", removed)
+}
+
+// watchNodeLists keeps the static and trusted node lists loaded from the data
+// directory in sync with their files until the stop channel is closed.
+func (n *Node) watchNodeLists(server *p2p.Server, stop <-chan struct{}) {
+	if n.config.DataDir == "" {
+		return
+	}
+	var watchers []*nodeListWatcher
+	if n.config.P2P.StaticNodes == nil {
+		watchers = append(watchers, newNodeListWatcher(n.config.resolvePath(datadirStaticNodes), n.serverConfig.StaticNodes, server.AddPeer, server.RemovePeer))
+	}
+	if n.config.P2P.TrustedNodes == nil {
+		watchers = append(watchers, newNodeListWatcher(n.config.resolvePath(datadirTrustedNodes), n.serverConfig.TrustedNodes, server.AddTrustedPeer, server.RemoveTrustedPeer))
+	}
+	if len(watchers) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(nodeListReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				for _, w := range watchers {
+					w.reload()
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// Tests that changes of a node list file are applied to the server.
+func TestNodeListReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	urls := make([]string, 3)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("enode://%s@127.0.0.1:%d", discover.NodeID{byte(i + 1)}, 30303+i)
+	}
+	path := filepath.Join(dir, datadirStaticNodes)
+	stamp := time.Now()
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write node list: %v", err)
+		}
+		// Ensure every write is noticed, regardless of the file time resolution
+		stamp = stamp.Add(time.Second)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatalf("failed to update node list time: %v", err)
+		}
+	}
+	write(`["` + urls[0] + `"]`)
+
+	var added, removed []string
+	record := func(list *[]string) func(*discover.Node) {
+		return func(n *discover.Node) { *list = append(*list, n.String()) }
+	}
+	check := func(wantAdded, wantRemoved []string) {
+		t.Helper()
+		sort.Strings(added)
+		sort.Strings(removed)
+		if !reflect.DeepEqual(added, wantAdded) {
+			t.Errorf("added nodes mismatch: have %v, want %v", added, wantAdded)
+		}
+		if !reflect.DeepEqual(removed, wantRemoved) {
+			t.Errorf("removed nodes mismatch: have %v, want %v", removed, wantRemoved)
+		}
+		added, removed = nil, nil
+	}
+	nodes, err := loadPersistentNodes(path)
+	if err != nil {
+		t.Fatalf("failed to load node list: %v", err)
+	}
+	w := newNodeListWatcher(path, nodes, record(&added), record(&removed))
+
+	// An unchanged file must not be reloaded
+	w.reload()
+	check(nil, nil)
+
+	// Added and removed nodes must be applied
+	write(`["` + urls[1] + `", "` + urls[2] + `"]`)
+	w.reload()
+	check([]string{urls[1], urls[2]}, []string{urls[0]})
+
+	// Broken lists must be ignored until fixed
+	write(`["` + urls[1] + `",`)
+	w.reload()
+	check(nil, nil)
+
+	write(`["` + strings.Join(urls[1:], `", "`) + `"]`)
+	w.reload()
+	check(nil, nil)
+
+	// Deleting the file removes all the nodes
+	os.Remove(path)
+	w.reload()
+	check(nil, []string{urls[1], urls[2]})
+}
@@ -19,7 +19,9 @@
 package p2p
 
 import (
+	"fmt"
 	"net"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/metrics"
 )
@@ -69,3 +71,51 @@ func (c *meteredConn) Write(b []byte) (n int, err error) {
 	egressTrafficMeter.Mark(int64(n))
 	return
 }
+
+// protocolTraffic accumulates the traffic of a sub-protocol across all the peers
+// of a server, also metering it if the metrics system is enabled.
+type protocolTraffic struct {
+	msgIn, msgOut     uint64 // Number of messages read and written, accessed atomically
+	bytesIn, bytesOut uint64 // Size of the messages read and written, accessed atomically
+
+	ingressMsgMeter, ingressTrafficMeter metrics.Meter
+	egressMsgMeter, egressTrafficMeter   metrics.Meter
+}
+
+// newProtocolTraffic creates the traffic accumulator of a sub-protocol, along
+// with its meters.
+func newProtocolTraffic(name string, version uint) *protocolTraffic {
+	prefix := fmt.Sprintf("p2p/protocols/%s/%d/", name, version)
+	return &protocolTraffic{
+		ingressMsgMeter:     metrics.GetOrRegisterMeter(prefix+"InboundMessages", nil),
+		ingressTrafficMeter: metrics.GetOrRegisterMeter(prefix+"InboundTraffic", nil),
+		egressMsgMeter:      metrics.GetOrRegisterMeter(prefix+"OutboundMessages", nil),
+		egressTrafficMeter:  metrics.GetOrRegisterMeter(prefix+"OutboundTraffic", nil),
+	}
+}
+
+// markIn accounts for a message read from a peer.
+func (t *protocolTraffic) markIn(size uint32) {
+	atomic.AddUint64(&t.msgIn, 1)
+	atomic.AddUint64(&t.bytesIn, uint64(size))
+	t.ingressMsgMeter.Mark(1)
+	t.ingressTrafficMeter.Mark(int64(size))
+}
+
+// markOut accounts for a message written to a peer.
+func (t *protocolTraffic) markOut(size uint32) {
+	atomic.AddUint64(&t.msgOut, 1)
+	atomic.AddUint64(&t.bytesOut, uint64(size))
+	t.egressMsgMeter.Mark(1)
+	t.egressTrafficMeter.Mark(int64(size))
+}
+
+// info assembles the publicly visible statistics.
+func (t *protocolTraffic) info() *ProtocolStats {
+	return &ProtocolStats{
+		MessagesIn:  atomic.LoadUint64(&t.msgIn),
+		MessagesOut: atomic.LoadUint64(&t.msgOut),
+		BytesIn:     atomic.LoadUint64(&t.bytesIn),
+		BytesOut:    atomic.LoadUint64(&t.bytesOut),
+	}
+}
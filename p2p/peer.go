@@ -23,6 +23,7 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
@@ -44,6 +45,8 @@ const (
 	snappyProtocolVersion = 5
 
 	pingInterval = 15 * time.Second
+
+	latencyImpact = 0.25 // Impact a single ping round trip has on the average latency
 )
 
 const (
@@ -110,6 +113,10 @@ type Peer struct {
 	closed   chan struct{}
 	disc     chan DiscReason
 
+	pingSent  mclock.AbsTime // Time the last unanswered ping was sent at
+	latency   time.Duration  // Average round trip time of the pings
+	statsLock sync.Mutex     // Lock protecting the latency fields
+
 	// events receives message send / receive events if set
 	events *event.Feed
 }
@@ -165,7 +172,7 @@ func (p *Peer) String() string {
 
 // Inbound returns true if the peer is an inbound connection
 func (p *Peer) Inbound() bool {
-	return p.rw.flags&inboundConn != 0
+	return p.rw.is(inboundConn)
 }
 
 func newPeer(conn *conn, protocols []Protocol) *Peer {
@@ -243,6 +250,10 @@ func (p *Peer) pingLoop() {
 	for {
 		select {
 		case <-ping.C:
+			p.statsLock.Lock()
+			p.pingSent = mclock.Now()
+			p.statsLock.Unlock()
+
 			if err := SendItems(p.rw, pingMsg); err != nil {
 				p.protoErr <- err
 				return
@@ -275,6 +286,9 @@ func (p *Peer) handle(msg Msg) error {
 	case msg.Code == pingMsg:
 		msg.Discard()
 		go SendItems(p.rw, pongMsg)
+	case msg.Code == pongMsg:
+		p.measureLatency()
+		return msg.Discard()
 	case msg.Code == discMsg:
 		var reason [1]DiscReason
 		// This is the last message. We don't need to discard or
@@ -300,6 +314,24 @@ func (p *Peer) handle(msg Msg) error {
 	return nil
 }
 
+// measureLatency updates the average latency of the peer with the round trip
+// time of the last ping, if one is pending.
+func (p *Peer) measureLatency() {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+
+	if p.pingSent == 0 {
+		return
+	}
+	rtt := time.Duration(mclock.Now() - p.pingSent)
+	if p.latency == 0 {
+		p.latency = rtt
+	} else {
+		p.latency = time.Duration((1-latencyImpact)*float64(p.latency) + latencyImpact*float64(rtt))
+	}
+	p.pingSent = 0
+}
+
 func countMatchingProtocols(protocols []Protocol, caps []Cap) int {
 	n := 0
 	for _, cap := range caps {
@@ -327,7 +359,7 @@ outer:
 					offset -= old.Length
 				}
 				// Assign the new match
-				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw}
+				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw, stats: new(protoStats)}
 				offset += proto.Length
 
 				continue outer
@@ -356,6 +388,7 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 				err = errProtocolReturned
 			} else if err != io.EOF {
 				p.log.Trace(fmt.Sprintf("Protocol %s/%d failed", proto.Name, proto.Version), "err", err)
+				proto.stats.setError(err)
 			}
 			p.protoErr <- err
 			p.wg.Done()
@@ -382,16 +415,23 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+	stats  *protoStats // traffic statistics of the protocol
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	if msg.Code >= rw.Length {
-		return newPeerError(errInvalidMsgCode, "not handled")
+		err = newPeerError(errInvalidMsgCode, "not handled")
+		rw.stats.setError(err)
+		return err
 	}
 	msg.Code += rw.offset
 	select {
 	case <-rw.wstart:
-		err = rw.w.WriteMsg(msg)
+		if err = rw.w.WriteMsg(msg); err != nil {
+			rw.stats.setError(err)
+		} else {
+			rw.stats.markOut(msg.Size)
+		}
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
 		// otherwise. The calling protocol code should exit for errors
@@ -407,12 +447,75 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	select {
 	case msg := <-rw.in:
 		msg.Code -= rw.offset
+		rw.stats.markIn(msg.Size)
 		return msg, nil
 	case <-rw.closed:
 		return Msg{}, io.EOF
 	}
 }
 
+// protoStats tracks the traffic of a sub-protocol on a peer connection.
+type protoStats struct {
+	msgIn, msgOut     uint64 // Number of messages read and written, accessed atomically
+	bytesIn, bytesOut uint64 // Size of the messages read and written, accessed atomically
+
+	lastErr error // Last error the protocol encountered
+	lock    sync.Mutex
+
+	traffic *protocolTraffic // Server wide traffic of the protocol (nil if not tracked)
+}
+
+// markIn accounts for a message read from the peer.
+func (s *protoStats) markIn(size uint32) {
+	atomic.AddUint64(&s.msgIn, 1)
+	atomic.AddUint64(&s.bytesIn, uint64(size))
+	if s.traffic != nil {
+		s.traffic.markIn(size)
+	}
+}
+
+// markOut accounts for a message written to the peer.
+func (s *protoStats) markOut(size uint32) {
+	atomic.AddUint64(&s.msgOut, 1)
+	atomic.AddUint64(&s.bytesOut, uint64(size))
+	if s.traffic != nil {
+		s.traffic.markOut(size)
+	}
+}
+
+// setError records the last error encountered by the protocol.
+func (s *protoStats) setError(err error) {
+	s.lock.Lock()
+	s.lastErr = err
+	s.lock.Unlock()
+}
+
+// ProtocolStats contains the traffic statistics of a sub-protocol running on a
+// peer connection.
+type ProtocolStats struct {
+	MessagesIn  uint64 `json:"messagesIn"`          // Number of messages received
+	MessagesOut uint64 `json:"messagesOut"`         // Number of messages sent
+	BytesIn     uint64 `json:"bytesIn"`             // Total size of the messages received
+	BytesOut    uint64 `json:"bytesOut"`            // Total size of the messages sent
+	LastError   string `json:"lastError,omitempty"` // Last error encountered by the protocol
+}
+
+// info assembles the publicly visible statistics.
+func (s *protoStats) info() *ProtocolStats {
+	stats := &ProtocolStats{
+		MessagesIn:  atomic.LoadUint64(&s.msgIn),
+		MessagesOut: atomic.LoadUint64(&s.msgOut),
+		BytesIn:     atomic.LoadUint64(&s.bytesIn),
+		BytesOut:    atomic.LoadUint64(&s.bytesOut),
+	}
+	s.lock.Lock()
+	if s.lastErr != nil {
+		stats.LastError = s.lastErr.Error()
+	}
+	s.lock.Unlock()
+	return stats
+}
+
 // PeerInfo represents a short summary of the information known about a connected
 // peer. Sub-protocol independent fields are contained and initialized here, with
 // protocol specifics delegated to all connected sub-protocols.
@@ -421,13 +524,15 @@ type PeerInfo struct {
 	Name    string   `json:"name"` // Name of the node, including client type, version, OS, custom data
 	Caps    []string `json:"caps"` // Sum-protocols advertised by this particular peer
 	Network struct {
-		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
-		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
-		Inbound       bool   `json:"inbound"`
-		Trusted       bool   `json:"trusted"`
-		Static        bool   `json:"static"`
+		LocalAddress  string  `json:"localAddress"`  // Local endpoint of the TCP data connection
+		RemoteAddress string  `json:"remoteAddress"` // Remote endpoint of the TCP data connection
+		Inbound       bool    `json:"inbound"`
+		Trusted       bool    `json:"trusted"`
+		Static        bool    `json:"static"`
+		Latency       float64 `json:"latency"` // Average ping round trip time in milliseconds (0 if not measured yet)
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Protocols map[string]interface{}    `json:"protocols"` // Sub-protocol specific metadata fields
+	Stats     map[string]*ProtocolStats `json:"stats"`     // Sub-protocol traffic statistics
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -443,6 +548,7 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Name(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}),
+		Stats:     make(map[string]*ProtocolStats),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
@@ -450,6 +556,10 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
 
+	p.statsLock.Lock()
+	info.Network.Latency = float64(p.latency) / float64(time.Millisecond)
+	p.statsLock.Unlock()
+
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
 		protoInfo := interface{}("unknown")
@@ -461,6 +571,7 @@ func (p *Peer) Info() *PeerInfo {
 			}
 		}
 		info.Protocols[proto.Name] = protoInfo
+		info.Stats[proto.Name] = proto.stats.info()
 	}
 	return info
 }
@@ -19,6 +19,7 @@ package p2p
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 const (
@@ -99,6 +100,16 @@ func (d DiscReason) Error() string {
 	return d.String()
 }
 
+// ParseDiscReason looks up the disconnect reason with the given description.
+func ParseDiscReason(s string) (DiscReason, error) {
+	for reason, str := range discReasonToString {
+		if str != "" && strings.EqualFold(str, s) {
+			return DiscReason(reason), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown disconnect reason %q", s)
+}
+
 func discReasonForError(err error) DiscReason {
 	if reason, ok := err.(DiscReason); ok {
 		return reason
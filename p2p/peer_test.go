@@ -24,6 +24,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
 )
 
 var discard = Protocol{
@@ -120,6 +122,86 @@ func TestPeerProtoEncodeMsg(t *testing.T) {
 	}
 }
 
+// Tests that the traffic of the sub-protocols and the ping latency are reported
+// in the peer info.
+func TestPeerInfoStats(t *testing.T) {
+	done := make(chan struct{})
+	proto := Protocol{
+		Name:   "a",
+		Length: 5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+				t.Error(err)
+			}
+			if err := SendItems(rw, 3, "foo"); err != nil {
+				t.Error(err)
+			}
+			<-done
+			return nil
+		},
+	}
+	closer, rw, peer, _ := testPeer([]Protocol{proto})
+	defer closer()
+	defer close(done)
+
+	Send(rw, baseProtocolLength+2, []uint{1})
+	if err := ExpectMsg(rw, baseProtocolLength+3, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	// Fake an outstanding ping and answer it from the remote side
+	peer.statsLock.Lock()
+	peer.pingSent = mclock.Now() - mclock.AbsTime(10*time.Millisecond)
+	peer.statsLock.Unlock()
+
+	if err := SendItems(rw, pongMsg); err != nil {
+		t.Fatal(err)
+	}
+	var info *PeerInfo
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		info = peer.Info()
+		if info.Network.Latency >= 10 && info.Stats["a"] != nil && info.Stats["a"].MessagesOut > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stats not updated: latency %vms, protocol stats %+v", info.Network.Latency, info.Stats["a"])
+		}
+	}
+	stats := info.Stats["a"]
+	if stats.MessagesIn != 1 || stats.MessagesOut != 1 {
+		t.Errorf("message count mismatch: have %d/%d, want 1/1", stats.MessagesIn, stats.MessagesOut)
+	}
+	if stats.BytesIn == 0 || stats.BytesOut == 0 {
+		t.Errorf("traffic not counted: have %d/%d bytes", stats.BytesIn, stats.BytesOut)
+	}
+}
+
+func TestServerProtocolTraffic(t *testing.T) {
+	srv := new(Server)
+	if traffic := srv.protocolTraffic("test", 1); traffic != srv.protocolTraffic("test", 1) {
+		t.Fatalf("traffic accumulator recreated for the same protocol version")
+	}
+	peers := []*protoStats{
+		{traffic: srv.protocolTraffic("test", 1)},
+		{traffic: srv.protocolTraffic("test", 1)},
+		{traffic: srv.protocolTraffic("test", 2)},
+	}
+	peers[0].markIn(10)
+	peers[0].markOut(20)
+	peers[1].markIn(5)
+	peers[2].markOut(7)
+
+	if info := peers[0].info(); info.MessagesIn != 1 || info.BytesIn != 10 || info.MessagesOut != 1 || info.BytesOut != 20 {
+		t.Errorf("peer stats mismatch: %+v", info)
+	}
+	want := map[string]*ProtocolStats{
+		"test/1": {MessagesIn: 2, MessagesOut: 1, BytesIn: 15, BytesOut: 20},
+		"test/2": {MessagesOut: 1, BytesOut: 7},
+	}
+	if have := srv.ProtocolTraffic(); !reflect.DeepEqual(have, want) {
+		t.Errorf("protocol traffic mismatch: have %v, want %v", have, want)
+	}
+}
+
 func TestPeerPing(t *testing.T) {
 	closer, rw, _, _ := testPeer(nil)
 	defer closer()
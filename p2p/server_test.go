@@ -344,11 +344,13 @@ func TestServerAtCap(t *testing.T) {
 	}
 
 	// Inject a few connections to fill up the peer set.
+	var conns []*conn
 	for i := 0; i < 10; i++ {
 		c := newconn(randomID())
 		if err := srv.checkpoint(c, srv.addpeer); err != nil {
 			t.Fatalf("could not add conn %d: %v", i, err)
 		}
+		conns = append(conns, c)
 	}
 	// Try inserting a non-trusted connection.
 	c := newconn(randomID())
@@ -364,6 +366,33 @@ func TestServerAtCap(t *testing.T) {
 		t.Error("Server did not set trusted flag")
 	}
 
+	// Remove from trusted set and try again
+	srv.RemoveTrustedPeer(&discover.Node{ID: trustedID})
+	c = newconn(trustedID)
+	if err := srv.checkpoint(c, srv.posthandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for insert:", err)
+	}
+	// Add anotherID to trusted set and try again
+	anotherID := randomID()
+	srv.AddTrustedPeer(&discover.Node{ID: anotherID})
+	c = newconn(anotherID)
+	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
+		t.Error("unexpected error for trusted conn @posthandshake:", err)
+	}
+	if !c.is(trustedConn) {
+		t.Error("Server did not set trusted flag")
+	}
+	// Trusting a connected peer must flag its live connection
+	srv.AddTrustedPeer(&discover.Node{ID: conns[0].id})
+	srv.Peers() // wait for the run loop to process the update
+	if !conns[0].is(trustedConn) {
+		t.Error("Server did not set trusted flag on connected peer")
+	}
+	srv.RemoveTrustedPeer(&discover.Node{ID: conns[0].id})
+	srv.Peers()
+	if conns[0].is(trustedConn) {
+		t.Error("Server did not clear trusted flag on connected peer")
+	}
 }
 
 func TestServerSetupConn(t *testing.T) {
@@ -37,10 +37,12 @@ import (
 // SimAdapter is a NodeAdapter which creates in-memory simulation nodes and
 // connects them using net.Pipe or OS socket connections
 type SimAdapter struct {
-	pipe     func() (net.Conn, net.Conn, error)
-	mtx      sync.RWMutex
-	nodes    map[discover.NodeID]*SimNode
-	services map[string]ServiceFunc
+	pipe        func() (net.Conn, net.Conn, error)
+	mtx         sync.RWMutex
+	nodes       map[discover.NodeID]*SimNode
+	services    map[string]ServiceFunc
+	links       map[[2]discover.NodeID]*LinkConditions
+	defaultLink *LinkConditions
 }
 
 // NewSimAdapter creates a SimAdapter which is capable of running in-memory
@@ -106,7 +108,7 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 			PrivateKey:      config.PrivateKey,
 			MaxPeers:        math.MaxInt32,
 			NoDiscovery:     true,
-			Dialer:          s,
+			Dialer:          &simDialer{adapter: s, id: id},
 			EnableMsgEvents: config.EnableMsgEvents,
 		},
 		NoUSB:  true,
@@ -130,6 +132,12 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 // Dial implements the p2p.NodeDialer interface by connecting to the node using
 // an in-memory net.Pipe or OS socket connection
 func (s *SimAdapter) Dial(dest *discover.Node) (conn net.Conn, err error) {
+	return s.dial(discover.NodeID{}, dest)
+}
+
+// dial connects the node src to the node dest, the connection is subject to
+// the conditions of the link between them (see SetLinkConditions)
+func (s *SimAdapter) dial(src discover.NodeID, dest *discover.Node) (conn net.Conn, err error) {
 	node, ok := s.GetNode(dest.ID)
 	if !ok {
 		return nil, fmt.Errorf("unknown node: %s", dest.ID)
@@ -146,8 +154,11 @@ func (s *SimAdapter) Dial(dest *discover.Node) (conn net.Conn, err error) {
 	// this is simulated 'listening'
 	// asynchronously call the dialed destintion node's p2p server
 	// to set up connection on the 'listening' side
-	go srv.SetupConn(pipe1, 0, nil)
-	return pipe2, nil
+	conditions := func() *LinkConditions {
+		return s.linkConditions(src, dest.ID)
+	}
+	go srv.SetupConn(newLinkConn(pipe1, conditions), 0, nil)
+	return newLinkConn(pipe2, conditions), nil
 }
 
 // DialRPC implements the RPCDialer interface by creating an in-memory RPC
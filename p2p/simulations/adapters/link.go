@@ -0,0 +1,238 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package adapters
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// DefaultRetransmitDelay is the delay of a lost write if the link conditions
+// do not set one
+const DefaultRetransmitDelay = 200 * time.Millisecond
+
+// linkQueueSize is the number of writes a link buffers before the writer
+// blocks
+const linkQueueSize = 1024
+
+var errLinkClosed = errors.New("link closed")
+
+// LinkConditions are the network conditions of the connections between two
+// simulated nodes.
+//
+// Connections are reliable streams, so writes are never dropped: a lost write
+// is delivered after a retransmission delay, as it would be by TCP. Writes
+// are always delivered in order, so jitter never reorders them.
+type LinkConditions struct {
+	Latency         time.Duration // delay of every write
+	Jitter          time.Duration // maximum random delay added to the latency
+	Loss            float64       // fraction of the writes which are lost
+	RetransmitDelay time.Duration // delay of a lost write, DefaultRetransmitDelay if zero
+}
+
+// delay returns the delay of a write drawn from r
+func (c *LinkConditions) delay(r *rand.Rand) time.Duration {
+	d := c.Latency
+	if c.Jitter > 0 {
+		d += time.Duration(r.Int63n(int64(c.Jitter)))
+	}
+	if c.Loss > 0 && r.Float64() < c.Loss {
+		if c.RetransmitDelay > 0 {
+			d += c.RetransmitDelay
+		} else {
+			d += DefaultRetransmitDelay
+		}
+	}
+	return d
+}
+
+// linkKey returns the key of the link between two nodes, which is the same
+// in both directions
+func linkKey(one, other discover.NodeID) [2]discover.NodeID {
+	for i := range one {
+		if one[i] != other[i] {
+			if one[i] > other[i] {
+				one, other = other, one
+			}
+			break
+		}
+	}
+	return [2]discover.NodeID{one, other}
+}
+
+// SetLinkConditions sets the conditions of the connections between the two
+// nodes, a nil conditions removes them. Connections which are already
+// established are affected as well.
+func (s *SimAdapter) SetLinkConditions(one, other discover.NodeID, conditions *LinkConditions) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.links == nil {
+		s.links = make(map[[2]discover.NodeID]*LinkConditions)
+	}
+	if conditions == nil {
+		delete(s.links, linkKey(one, other))
+		return
+	}
+	c := *conditions
+	s.links[linkKey(one, other)] = &c
+}
+
+// SetDefaultLinkConditions sets the conditions of the connections between
+// nodes which have no conditions of their own, a nil conditions removes them
+func (s *SimAdapter) SetDefaultLinkConditions(conditions *LinkConditions) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if conditions == nil {
+		s.defaultLink = nil
+		return
+	}
+	c := *conditions
+	s.defaultLink = &c
+}
+
+// linkConditions returns the current conditions of the link between the two
+// nodes, nil if the link is perfect
+func (s *SimAdapter) linkConditions(one, other discover.NodeID) *LinkConditions {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	if c, ok := s.links[linkKey(one, other)]; ok {
+		return c
+	}
+	return s.defaultLink
+}
+
+// simDialer is the dialer of a simulation node, it lets the adapter apply the
+// conditions of the links of the dialing node
+type simDialer struct {
+	adapter *SimAdapter
+	id      discover.NodeID
+}
+
+// Dial implements the p2p.NodeDialer interface
+func (d *simDialer) Dial(dest *discover.Node) (net.Conn, error) {
+	return d.adapter.dial(d.id, dest)
+}
+
+// linkWrite is a write queued for delivery
+type linkWrite struct {
+	data []byte
+	at   time.Time
+}
+
+// linkConn is a connection delaying its writes by the conditions of its link.
+// Writes are queued and delivered in order by a separate goroutine, so the
+// latency does not limit the throughput of the connection.
+type linkConn struct {
+	net.Conn
+	conditions func() *LinkConditions
+
+	mu      sync.Mutex
+	rand    *rand.Rand
+	last    time.Time
+	pending int
+	err     error
+	queue   chan linkWrite
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newLinkConn(conn net.Conn, conditions func() *LinkConditions) *linkConn {
+	c := &linkConn{
+		Conn:       conn,
+		conditions: conditions,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		queue:      make(chan linkWrite, linkQueueSize),
+		closed:     make(chan struct{}),
+	}
+	go c.deliver()
+	return c
+}
+
+// Write queues p for delivery after the delay of the link conditions
+func (c *linkConn) Write(p []byte) (int, error) {
+	cond := c.conditions()
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return 0, err
+	}
+	if cond == nil && c.pending == 0 {
+		// no write is pending, so writing directly keeps the order
+		defer c.mu.Unlock()
+		return c.Conn.Write(p)
+	}
+	at := time.Now()
+	if cond != nil {
+		at = at.Add(cond.delay(c.rand))
+	}
+	if at.Before(c.last) {
+		at = c.last
+	}
+	c.last = at
+	c.pending++
+	c.mu.Unlock()
+
+	data := make([]byte, len(p))
+	copy(data, p)
+	select {
+	case c.queue <- linkWrite{data, at}:
+		return len(p), nil
+	case <-c.closed:
+		return 0, errLinkClosed
+	}
+}
+
+// deliver writes the queued writes to the connection at their delivery time
+func (c *linkConn) deliver() {
+	for {
+		select {
+		case w := <-c.queue:
+			if d := time.Until(w.at); d > 0 {
+				select {
+				case <-time.After(d):
+				case <-c.closed:
+					return
+				}
+			}
+			_, err := c.Conn.Write(w.data)
+			c.mu.Lock()
+			c.pending--
+			if err != nil {
+				c.err = err
+			}
+			c.mu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// Close closes the connection, dropping the writes which are not delivered
+func (c *linkConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
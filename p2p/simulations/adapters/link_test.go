@@ -0,0 +1,124 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package adapters
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// linkPipe returns a pipe whose first end writes with the given conditions
+func linkPipe(t *testing.T, conditions *LinkConditions) (net.Conn, net.Conn) {
+	c1, c2, err := netPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newLinkConn(c1, func() *LinkConditions { return conditions }), c2
+}
+
+// sendMsgs writes msgs numbered messages to c and reads them from other,
+// checking their order, and returns the time the last message took to arrive
+func sendMsgs(t *testing.T, c, other net.Conn, msgs int) time.Duration {
+	start := time.Now()
+	go func() {
+		for i := 0; i < msgs; i++ {
+			msg := make([]byte, 8)
+			binary.BigEndian.PutUint64(msg, uint64(i))
+			if _, err := c.Write(msg); err != nil {
+				return
+			}
+		}
+	}()
+	for i := 0; i < msgs; i++ {
+		msg := make([]byte, 8)
+		if _, err := io.ReadFull(other, msg); err != nil {
+			t.Fatal(err)
+		}
+		if n := binary.BigEndian.Uint64(msg); n != uint64(i) {
+			t.Fatalf("expected message %d, got %d", i, n)
+		}
+	}
+	return time.Since(start)
+}
+
+func TestLinkConnLatency(t *testing.T) {
+	c1, c2 := linkPipe(t, &LinkConditions{Latency: 100 * time.Millisecond})
+	defer c1.Close()
+	defer c2.Close()
+
+	// writes are delayed but not serialised by the latency
+	if d := sendMsgs(t, c1, c2, 20); d < 100*time.Millisecond || d > time.Second {
+		t.Fatalf("expected messages to arrive after about 100ms, took %v", d)
+	}
+}
+
+func TestLinkConnJitter(t *testing.T) {
+	c1, c2 := linkPipe(t, &LinkConditions{Jitter: 50 * time.Millisecond})
+	defer c1.Close()
+	defer c2.Close()
+
+	// jitter never reorders the writes
+	sendMsgs(t, c1, c2, 200)
+}
+
+func TestLinkConnLoss(t *testing.T) {
+	c1, c2 := linkPipe(t, &LinkConditions{Loss: 1, RetransmitDelay: 200 * time.Millisecond})
+	defer c1.Close()
+	defer c2.Close()
+
+	// lost writes are delivered after the retransmission delay
+	if d := sendMsgs(t, c1, c2, 5); d < 200*time.Millisecond {
+		t.Fatalf("expected lost messages to be retransmitted after 200ms, took %v", d)
+	}
+}
+
+func TestLinkConnPerfect(t *testing.T) {
+	c1, c2 := linkPipe(t, nil)
+	defer c1.Close()
+	defer c2.Close()
+
+	if d := sendMsgs(t, c1, c2, 100); d > 100*time.Millisecond {
+		t.Fatalf("expected messages over a perfect link to arrive immediately, took %v", d)
+	}
+}
+
+func TestSimAdapterLinkConditions(t *testing.T) {
+	s := NewSimAdapter(nil)
+	a, b, c := discover.NodeID{1}, discover.NodeID{2}, discover.NodeID{3}
+
+	if cond := s.linkConditions(a, b); cond != nil {
+		t.Fatalf("expected no conditions, got %v", cond)
+	}
+	s.SetDefaultLinkConditions(&LinkConditions{Latency: time.Second})
+	s.SetLinkConditions(a, b, &LinkConditions{Loss: 0.5})
+	if cond := s.linkConditions(b, a); cond == nil || cond.Loss != 0.5 {
+		t.Fatalf("expected the conditions of the link in both directions, got %v", cond)
+	}
+	if cond := s.linkConditions(a, c); cond == nil || cond.Latency != time.Second {
+		t.Fatalf("expected the default conditions, got %v", cond)
+	}
+	s.SetLinkConditions(b, a, nil)
+	s.SetDefaultLinkConditions(nil)
+	if cond := s.linkConditions(a, b); cond != nil {
+		t.Fatalf("expected the conditions to be removed, got %v", cond)
+	}
+}
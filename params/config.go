@@ -17,8 +17,10 @@
 package params
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -82,16 +84,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -118,11 +120,21 @@ type ChainConfig struct {
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
 
+	// Additional precompiled contracts of private networks
+	Precompiles map[common.Address]*PrecompileConfig `json:"precompiles,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 }
 
+// PrecompileConfig activates an additional precompiled contract, implemented by
+// the contract registered with the EVM under the given name.
+type PrecompileConfig struct {
+	Name  string   `json:"name"`  // Name of the registered contract implementation
+	Block *big.Int `json:"block"` // Activation block (nil = never, 0 = from genesis)
+}
+
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
 type EthashConfig struct{}
 
@@ -260,7 +272,49 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
-	return nil
+	return c.checkPrecompilesCompatible(newcfg, head)
+}
+
+// checkPrecompilesCompatible checks whether the activations of the additional
+// precompiled contracts can be changed at the given head, returning the error of
+// the lowest conflicting one.
+func (c *ChainConfig) checkPrecompilesCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+	addrs := make([]common.Address, 0, len(c.Precompiles)+len(newcfg.Precompiles))
+	for addr := range c.Precompiles {
+		addrs = append(addrs, addr)
+	}
+	for addr := range newcfg.Precompiles {
+		if _, ok := c.Precompiles[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var lowest *ConfigCompatError
+	for _, addr := range addrs {
+		var (
+			oldpc, newpc     = c.Precompiles[addr], newcfg.Precompiles[addr]
+			oldblk, newblk   *big.Int
+			oldname, newname string
+		)
+		if oldpc != nil {
+			oldblk, oldname = oldpc.Block, oldpc.Name
+		}
+		if newpc != nil {
+			newblk, newname = newpc.Block, newpc.Name
+		}
+		var err *ConfigCompatError
+		switch {
+		case isForkIncompatible(oldblk, newblk, head):
+			err = newCompatError(fmt.Sprintf("precompile %x activation block", addr), oldblk, newblk)
+		case isForked(oldblk, head) && oldname != newname:
+			err = newCompatError(fmt.Sprintf("precompile %x implementation", addr), oldblk, newblk)
+		}
+		if err != nil && (lowest == nil || err.RewindTo < lowest.RewindTo) {
+			lowest = err
+		}
+	}
+	return lowest
 }
 
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
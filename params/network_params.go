@@ -24,3 +24,10 @@ const (
 	// contains.
 	BloomBitsBlocks uint64 = 4096
 )
+
+const (
+	// ImmutabilityThreshold is the number of blocks after which a chain segment is
+	// considered immutable (i.e. soft finality). It is used by the chain freezer
+	// to decide which blocks can be moved out of the key-value database.
+	ImmutabilityThreshold = 90000
+)
@@ -0,0 +1,162 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// jwtMaxAge is how old the issuance time of a JWT without an expiration time
+// may be, so that intercepted tokens cannot be replayed later
+const jwtMaxAge = 60 * time.Second
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errInvalidToken = errors.New("invalid bearer token")
+)
+
+// AuthConfig configures the authentication of the clients of the HTTP and
+// WebSocket RPC endpoints, which must send an "Authorization: Bearer <token>"
+// header with their requests or WebSocket handshakes.
+//
+// The token is either a JSON Web Token signed with HMAC-SHA256 using the
+// shared secret JWTSecret, or one of the API tokens of Tokens. A JWT grants
+// the namespaces listed in its "namespaces" claim, or all namespaces if it has
+// none, and must have an expiration time or have been issued within the last
+// minute. An API token grants the namespaces it maps to, "*" granting all.
+type AuthConfig struct {
+	JWTSecret []byte
+	Tokens    map[string][]string
+}
+
+// Enabled reports whether the configuration requires clients to authenticate
+func (cfg *AuthConfig) Enabled() bool {
+	return cfg != nil && (len(cfg.JWTSecret) > 0 || len(cfg.Tokens) > 0)
+}
+
+// jwtClaims are the claims of the JWTs authenticating RPC clients
+type jwtClaims struct {
+	Namespaces []string `json:"namespaces,omitempty"`
+	jwt.StandardClaims
+}
+
+// permissions is the set of namespaces an authenticated client may call,
+// nil allowing all namespaces
+type permissions map[string]bool
+
+func newPermissions(namespaces []string) permissions {
+	perms := make(permissions)
+	for _, namespace := range namespaces {
+		if namespace == "*" {
+			return nil
+		}
+		perms[namespace] = true
+	}
+	return perms
+}
+
+func (p permissions) allows(namespace string) bool {
+	return p == nil || p[namespace]
+}
+
+type permissionsKey struct{}
+
+// permissionsFromContext returns the permissions of the client of a request,
+// all namespaces being allowed on endpoints which do not authenticate
+func permissionsFromContext(ctx context.Context) permissions {
+	perms, _ := ctx.Value(permissionsKey{}).(permissions)
+	return perms
+}
+
+// authenticate returns the permissions of the client of an HTTP request
+func (cfg *AuthConfig) authenticate(r *http.Request) (permissions, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, errMissingToken
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+
+	for t, namespaces := range cfg.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return newPermissions(namespaces), nil
+		}
+	}
+	if len(cfg.JWTSecret) == 0 {
+		return nil, errInvalidToken
+	}
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return cfg.JWTSecret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", errInvalidToken, err)
+	}
+	if claims.ExpiresAt == 0 && time.Since(time.Unix(claims.IssuedAt, 0)) > jwtMaxAge {
+		return nil, fmt.Errorf("%v: stale token without expiration time", errInvalidToken)
+	}
+	if len(claims.Namespaces) == 0 {
+		return nil, nil
+	}
+	return newPermissions(claims.Namespaces), nil
+}
+
+// newAuthHandler returns a handler which rejects the requests of clients
+// which do not authenticate and passes the permissions of the others to the
+// RPC server in the context of their requests.
+func newAuthHandler(cfg *AuthConfig, next http.Handler) http.Handler {
+	if !cfg.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var perms permissions
+		if r.Method == http.MethodOptions {
+			// CORS preflight requests carry no credentials, they may not call
+			// any method
+			perms = make(permissions)
+		} else {
+			var err error
+			if perms, err = cfg.authenticate(r); err != nil {
+				log.Debug("Rejected unauthenticated RPC request", "remote", r.RemoteAddr, "err", err)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="rpc"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), permissionsKey{}, perms)))
+	})
+}
+
+// withPermissions copies the permissions of the client of an HTTP request to
+// the context of its RPC calls
+func withPermissions(ctx context.Context, r *http.Request) context.Context {
+	if perms, ok := r.Context().Value(permissionsKey{}).(permissions); ok {
+		return context.WithValue(ctx, permissionsKey{}, perms)
+	}
+	return ctx
+}
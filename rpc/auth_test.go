@@ -0,0 +1,182 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/websocket"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newAuthTestServer(t *testing.T) *Server {
+	server := NewServer()
+	for _, name := range []string{"test", "other"} {
+		if err := server.RegisterName(name, new(Service)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return server
+}
+
+func newTestJWT(t *testing.T, secret []byte, claims jwtClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// authResponse is the response to a call, Code is the code of its error, 0
+// if the call succeeded
+type authResponse struct {
+	Error *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+func TestHTTPAuth(t *testing.T) {
+	cfg := &AuthConfig{
+		JWTSecret: testJWTSecret,
+		Tokens: map[string][]string{
+			"test-token": {"test"},
+			"all-token":  {"*"},
+		},
+	}
+	hs := httptest.NewServer(newAuthHandler(cfg, newAuthTestServer(t)))
+	defer hs.Close()
+
+	now := time.Now().Unix()
+	tests := []struct {
+		token  string
+		method string
+		status int
+		code   int
+	}{
+		{"", "test_rets", http.StatusUnauthorized, 0},
+		{"wrong-token", "test_rets", http.StatusUnauthorized, 0},
+		{"test-token", "test_rets", http.StatusOK, 0},
+		{"test-token", "other_rets", http.StatusOK, -32001},
+		{"all-token", "other_rets", http.StatusOK, 0},
+		// JWTs grant the namespaces of their claims, or all
+		{newTestJWT(t, testJWTSecret, jwtClaims{StandardClaims: jwt.StandardClaims{IssuedAt: now}}), "other_rets", http.StatusOK, 0},
+		{newTestJWT(t, testJWTSecret, jwtClaims{Namespaces: []string{"test"}, StandardClaims: jwt.StandardClaims{IssuedAt: now}}), "test_rets", http.StatusOK, 0},
+		{newTestJWT(t, testJWTSecret, jwtClaims{Namespaces: []string{"test"}, StandardClaims: jwt.StandardClaims{IssuedAt: now}}), "other_rets", http.StatusOK, -32001},
+		{newTestJWT(t, testJWTSecret, jwtClaims{StandardClaims: jwt.StandardClaims{ExpiresAt: now + 3600}}), "test_rets", http.StatusOK, 0},
+		// invalid, expired and stale JWTs are rejected
+		{newTestJWT(t, []byte("wrong secret"), jwtClaims{StandardClaims: jwt.StandardClaims{IssuedAt: now}}), "test_rets", http.StatusUnauthorized, 0},
+		{newTestJWT(t, testJWTSecret, jwtClaims{StandardClaims: jwt.StandardClaims{ExpiresAt: now - 10}}), "test_rets", http.StatusUnauthorized, 0},
+		{newTestJWT(t, testJWTSecret, jwtClaims{StandardClaims: jwt.StandardClaims{IssuedAt: now - 600}}), "test_rets", http.StatusUnauthorized, 0},
+	}
+	for i, test := range tests {
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + test.method + `","params":[]}`
+		req, _ := http.NewRequest("POST", hs.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res authResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+				t.Fatalf("test %d: %v", i, err)
+			}
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("test %d: expected status %d, got %d", i, test.status, resp.StatusCode)
+			continue
+		}
+		code := 0
+		if res.Error != nil {
+			code = res.Error.Code
+		}
+		if code != test.code {
+			t.Errorf("test %d: expected error code %d, got %d", i, test.code, code)
+		}
+	}
+
+	// preflight requests pass to the CORS handler, but may not call methods
+	req, _ := http.NewRequest("OPTIONS", hs.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"test_rets","params":[]}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var res authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Error == nil || res.Error.Code != -32001 {
+		t.Fatalf("expected an unauthenticated preflight request to be denied, got %+v", res)
+	}
+}
+
+func TestWebsocketAuth(t *testing.T) {
+	cfg := &AuthConfig{Tokens: map[string][]string{"test-token": {"test"}}}
+	hs := httptest.NewServer(newAuthHandler(cfg, newAuthTestServer(t).WebsocketHandler([]string{"*"})))
+	defer hs.Close()
+	endpoint := "ws" + strings.TrimPrefix(hs.URL, "http")
+
+	config, err := websocket.NewConfig(endpoint, "http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := websocket.DialConfig(config); err == nil {
+		t.Fatal("expected the handshake of an unauthenticated client to fail")
+	}
+
+	config.Header.Set("Authorization", "Bearer test-token")
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, test := range []struct {
+		method string
+		code   int
+	}{
+		{"test_rets", 0},
+		{"other_rets", -32001},
+	} {
+		if err := websocket.JSON.Send(conn, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": test.method, "params": []interface{}{}}); err != nil {
+			t.Fatal(err)
+		}
+		var res authResponse
+		if err := websocket.JSON.Receive(conn, &res); err != nil {
+			t.Fatal(err)
+		}
+		code := 0
+		if res.Error != nil {
+			code = res.Error.Code
+		}
+		if code != test.code {
+			t.Errorf("%s: expected error code %d, got %d", test.method, test.code, code)
+		}
+	}
+}
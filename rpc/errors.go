@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // request is for an unknown service
 type methodNotFoundError struct {
@@ -58,6 +61,43 @@ func (e *callbackError) ErrorCode() int { return -32000 }
 
 func (e *callbackError) Error() string { return e.message }
 
+// the client is not permitted to call methods of the service
+type permissionError struct{ service string }
+
+func (e *permissionError) ErrorCode() int { return -32001 }
+
+func (e *permissionError) Error() string {
+	return fmt.Sprintf("not permitted to call methods of the %s namespace", e.service)
+}
+
+// the call exceeds the rate limit of its method
+type limitExceededError struct{ method string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string {
+	return fmt.Sprintf("rate limit of %s exceeded", e.method)
+}
+
+// the call exceeds the execution timeout of its method
+type timeoutError struct {
+	method  string
+	timeout time.Duration
+}
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v", e.method, e.timeout)
+}
+
+// the call was canceled before it completed, e.g. because its client went away
+type canceledError struct{ method string }
+
+func (e *canceledError) ErrorCode() int { return -32000 }
+
+func (e *canceledError) Error() string { return fmt.Sprintf("%s was canceled", e.method) }
+
 // issued when a request is received after the server is issued to stop.
 type shutdownError struct{}
 
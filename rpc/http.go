@@ -41,6 +41,44 @@ const (
 
 var nullAddr, _ = net.ResolveTCPAddr("tcp", "127.0.0.1:0")
 
+// HTTPTimeouts represents the connection settings of the HTTP RPC server.
+type HTTPTimeouts struct {
+	// ReadTimeout is the maximum duration for reading an entire request,
+	// including the body, zero meaning no limit.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the maximum duration before timing out writes of the
+	// response, including the execution of the call, zero meaning no limit.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the maximum time to wait for the next request on a kept
+	// alive connection, zero meaning the read timeout is used.
+	IdleTimeout time.Duration
+
+	// DisableKeepAlive closes the connections after every request.
+	DisableKeepAlive bool
+}
+
+// DefaultHTTPTimeouts represents the default connection settings used by the
+// HTTP RPC server. Writes are not limited, the execution of the calls being
+// bounded by the method limits instead.
+var DefaultHTTPTimeouts = HTTPTimeouts{
+	ReadTimeout: 30 * time.Second,
+	IdleTimeout: 120 * time.Second,
+}
+
+// newHTTPServer creates an HTTP server applying the given connection settings.
+func newHTTPServer(handler http.Handler, timeouts HTTPTimeouts) *http.Server {
+	srv := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  timeouts.ReadTimeout,
+		WriteTimeout: timeouts.WriteTimeout,
+		IdleTimeout:  timeouts.IdleTimeout,
+	}
+	srv.SetKeepAlivesEnabled(!timeouts.DisableKeepAlive)
+	return srv
+}
+
 type httpConn struct {
 	client    *http.Client
 	req       *http.Request
@@ -168,6 +206,14 @@ func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
 	return &http.Server{Handler: handler}
 }
 
+// NewHTTPHandlerStack wraps an HTTP handler with the CORS and virtual host
+// checks of the RPC server, so that other HTTP APIs of the node can apply
+// the same policies.
+func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string) http.Handler {
+	handler := newCorsHandler(srv, cors)
+	return newVHostHandler(vhosts, handler)
+}
+
 // ServeHTTP serves JSON-RPC requests over HTTP.
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Permit dumb empty requests for remote health-checks (AWS)
@@ -185,6 +231,8 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
+	ctx = withPermissions(ctx, r)
+	ctx = context.WithValue(ctx, httpStatusKey{}, w.WriteHeader)
 
 	body := io.LimitReader(r.Body, maxRequestContentLength)
 	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
@@ -212,7 +260,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -17,6 +17,8 @@
 package rpc
 
 import (
+	"compress/gzip"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -52,3 +54,67 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+// Tests that HTTP responses are gzip compressed for the clients accepting it.
+func TestHTTPGzipResponse(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(newGzipHandler(server))
+	defer httpsrv.Close()
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"service_echo","params":["hello",10,{"S":"world"}]}`
+	for _, encoding := range []string{"", "gzip", "deflate, gzip;q=0.5", "gzip;q=0"} {
+		req, _ := http.NewRequest(http.MethodPost, httpsrv.URL, strings.NewReader(request))
+		req.Header.Set("content-type", contentType)
+		if encoding != "" {
+			req.Header.Set("accept-encoding", encoding)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("encoding %q: request failed: %v", encoding, err)
+		}
+		compressed := resp.Header.Get("content-encoding") == "gzip"
+		if want := acceptsGzip(req); compressed != want {
+			t.Errorf("encoding %q: compression mismatch: have %v, want %v", encoding, compressed, want)
+		}
+		body := resp.Body
+		if compressed {
+			if body, err = gzip.NewReader(resp.Body); err != nil {
+				t.Fatalf("encoding %q: invalid gzip stream: %v", encoding, err)
+			}
+		}
+		blob, err := ioutil.ReadAll(body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("encoding %q: failed to read response: %v", encoding, err)
+		}
+		if !strings.Contains(string(blob), `"result":{"String":"hello","Int":10,"Args":{"S":"world"}}`) {
+			t.Errorf("encoding %q: unexpected response: %s", encoding, blob)
+		}
+	}
+}
+
+func TestHTTPAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip", true},
+		{"gzip;q=0.8, deflate", true},
+		{"gzip; q=0", false},
+		{"deflate, br", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://url.com", nil)
+		if tt.header != "" {
+			req.Header.Set("accept-encoding", tt.header)
+		}
+		if have := acceptsGzip(req); have != tt.want {
+			t.Errorf("header %q: have %v, want %v", tt.header, have, tt.want)
+		}
+	}
+}
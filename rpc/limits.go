@@ -0,0 +1,159 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// MethodLimit limits the calls of an RPC method, or of all the methods of a
+// namespace. The execution timeout cancels the context of a call, so it only
+// applies to methods taking a context and honoring its cancellation, methods
+// without a context run to completion.
+type MethodLimit struct {
+	Rate    float64       // Calls per second, 0 for no limit
+	Burst   int           // Calls which may be made at once, at least 1
+	Timeout time.Duration // Maximum execution time of a call, 0 for no limit
+}
+
+// methodLimit is the state of the limit of a method or namespace
+type methodLimit struct {
+	MethodLimit
+
+	mu     sync.Mutex
+	tokens float64   // calls which may be made now
+	last   time.Time // time the tokens were last updated
+}
+
+// allow reports whether a call may be made now, refilling the tokens of the
+// limit at its rate up to its burst
+func (l *methodLimit) allow() bool {
+	if l.Rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(float64(l.Burst), l.tokens+now.Sub(l.last).Seconds()*l.Rate)
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// SetLimits sets the rate limits and execution timeouts of the calls of the
+// server, keyed by method ("eth_getLogs") or namespace ("debug"). The limit of
+// a method overrides the one of its namespace, and the rate limit of a
+// namespace is shared by all its methods. Calls exceeding their rate limit
+// fail with a "limit exceeded" error, and HTTP requests with status 429.
+func (s *Server) SetLimits(limits map[string]MethodLimit) {
+	state := make(map[string]*methodLimit, len(limits))
+	for key, limit := range limits {
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		state[key] = &methodLimit{MethodLimit: limit, tokens: float64(limit.Burst), last: time.Now()}
+	}
+	s.limitsMu.Lock()
+	s.limits = state
+	s.limitsMu.Unlock()
+}
+
+// limit returns the limit of an RPC method, nil if it has none
+func (s *Server) limit(method string) *methodLimit {
+	s.limitsMu.RLock()
+	defer s.limitsMu.RUnlock()
+
+	if limit, ok := s.limits[method]; ok {
+		return limit
+	}
+	if i := strings.Index(method, serviceMethodSeparator); i >= 0 {
+		return s.limits[method[:i]]
+	}
+	return nil
+}
+
+// checkRateLimit fails if a request exceeds the rate limit of its method
+func (s *Server) checkRateLimit(req *serverRequest) Error {
+	if limit := s.limit(req.method); limit != nil && !limit.allow() {
+		metrics.GetOrRegisterMeter("rpc/limited/"+req.method, nil).Mark(1)
+		return &limitExceededError{req.method}
+	}
+	return nil
+}
+
+// timeout returns the execution timeout of an RPC method, 0 if it has none
+func (s *Server) timeout(method string) time.Duration {
+	if limit := s.limit(method); limit != nil {
+		return limit.Timeout
+	}
+	return 0
+}
+
+type httpStatusKey struct{}
+
+// setHTTPStatus sets the status of the HTTP response of a request, if it was
+// received over HTTP
+func setHTTPStatus(ctx context.Context, code int) {
+	if set, ok := ctx.Value(httpStatusKey{}).(func(int)); ok {
+		set(code)
+	}
+}
+
+// ParseLimits parses comma separated method limits of the form
+// "<method or namespace>:<calls per second>[:<timeout>]", such as
+// "eth_getLogs:10:5s,debug:1:1m". The burst of the rate limits is the number
+// of calls per second, rounded up.
+func ParseLimits(s string) (map[string]MethodLimit, error) {
+	limits := make(map[string]MethodLimit)
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid limit %q", spec)
+		}
+		var (
+			limit MethodLimit
+			err   error
+		)
+		if limit.Rate, err = strconv.ParseFloat(parts[1], 64); err != nil || limit.Rate < 0 || math.IsNaN(limit.Rate) || math.IsInf(limit.Rate, 0) {
+			return nil, fmt.Errorf("invalid rate of limit %q", spec)
+		}
+		limit.Burst = int(math.Ceil(limit.Rate))
+		if len(parts) == 3 {
+			if limit.Timeout, err = time.ParseDuration(parts[2]); err != nil || limit.Timeout < 0 {
+				return nil, fmt.Errorf("invalid timeout of limit %q", spec)
+			}
+		}
+		limits[parts[0]] = limit
+	}
+	return limits, nil
+}
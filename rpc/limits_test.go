@@ -0,0 +1,177 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postCall calls a method over HTTP and returns the status of the response
+// and the code of the error of the call, 0 if it succeeded
+func postCall(t *testing.T, url, body string) (int, int) {
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var res authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil {
+		return resp.StatusCode, res.Error.Code
+	}
+	return resp.StatusCode, 0
+}
+
+func TestRateLimits(t *testing.T) {
+	server := newAuthTestServer(t)
+	server.SetLimits(map[string]MethodLimit{
+		"test_rets":  {Rate: 0.001, Burst: 2},
+		"other":      {Rate: 0.001},
+		"other_echo": {},
+	})
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	tests := []struct {
+		method string
+		status int
+		code   int
+	}{
+		{"test_rets", http.StatusOK, 0},
+		{"test_rets", http.StatusOK, 0},
+		{"test_rets", http.StatusTooManyRequests, -32005},
+		{"test_noArgsRets", http.StatusOK, 0},
+		// namespace limits are shared by its methods, unless overridden
+		{"other_rets", http.StatusOK, 0},
+		{"other_noArgsRets", http.StatusTooManyRequests, -32005},
+		{"other_echo", http.StatusOK, -32602},
+	}
+	for i, test := range tests {
+		status, code := postCall(t, hs.URL, `{"jsonrpc":"2.0","id":1,"method":"`+test.method+`","params":[]}`)
+		if status != test.status || code != test.code {
+			t.Errorf("test %d: expected status %d and error %d, got %d and %d", i, test.status, test.code, status, code)
+		}
+	}
+
+	// limited calls of a batch fail without failing the batch
+	resp, err := http.Post(hs.URL, contentType, strings.NewReader(`[{"jsonrpc":"2.0","id":1,"method":"test_rets","params":[]},{"jsonrpc":"2.0","id":2,"method":"test_noArgsRets","params":[]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var batch []authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || len(batch) != 2 || batch[0].Error == nil || batch[0].Error.Code != -32005 || batch[1].Error != nil {
+		t.Fatalf("unexpected batch response %d %+v", resp.StatusCode, batch)
+	}
+}
+
+func TestRateLimitRefill(t *testing.T) {
+	limit := &methodLimit{MethodLimit: MethodLimit{Rate: 10, Burst: 1}, tokens: 1, last: time.Now()}
+	if !limit.allow() || limit.allow() {
+		t.Fatal("expected a burst of 1 call")
+	}
+	limit.last = limit.last.Add(-150 * time.Millisecond)
+	if !limit.allow() || limit.allow() {
+		t.Fatal("expected a call to be allowed after refilling")
+	}
+}
+
+func TestExecutionTimeouts(t *testing.T) {
+	server := newAuthTestServer(t)
+	server.SetLimits(map[string]MethodLimit{"test_sleep": {Timeout: 50 * time.Millisecond}})
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	start := time.Now()
+	status, code := postCall(t, hs.URL, `{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[10000000000]}`)
+	if status != http.StatusOK || code != -32002 {
+		t.Fatalf("expected a timeout error, got status %d and error %d", status, code)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("call timed out after %v", elapsed)
+	}
+	if _, code := postCall(t, hs.URL, `{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[1000000]}`); code != 0 {
+		t.Fatalf("expected a call within its timeout to succeed, got error %d", code)
+	}
+	if _, code := postCall(t, hs.URL, `{"jsonrpc":"2.0","id":1,"method":"other_sleep","params":[100000000]}`); code != 0 {
+		t.Fatalf("expected a call without timeout to succeed, got error %d", code)
+	}
+
+	// methods without a context can't be canceled and are not timed out
+	server.SetLimits(map[string]MethodLimit{"test_rets": {Timeout: time.Nanosecond}})
+	if _, code := postCall(t, hs.URL, `{"jsonrpc":"2.0","id":1,"method":"test_rets","params":[]}`); code != 0 {
+		t.Fatalf("expected a call of a method without context to succeed, got error %d", code)
+	}
+}
+
+// TestExecutionTimeoutCanceled tests that a call with a timeout whose context
+// is canceled by its caller fails as canceled rather than timed out
+func TestExecutionTimeoutCanceled(t *testing.T) {
+	server := newAuthTestServer(t)
+	server.SetLimits(map[string]MethodLimit{"test_sleep": {Timeout: time.Minute}})
+
+	codec := NewJSONCodec(struct {
+		io.Reader
+		io.Writer
+		io.Closer
+	}{strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[10000000000]}`), ioutil.Discard, ioutil.NopCloser(nil)})
+	reqs, _, err := server.readRequest(codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	res, _ := server.handle(ctx, codec, reqs[0])
+	if resp, ok := res.(*jsonErrResponse); !ok || resp.Error.Code != -32000 || !strings.Contains(resp.Error.Message, "canceled") {
+		t.Fatalf("expected a cancellation error, got %+v", res)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("eth_getLogs:10:5s, debug:0.5:1m,admin:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]MethodLimit{
+		"eth_getLogs": {Rate: 10, Burst: 10, Timeout: 5 * time.Second},
+		"debug":       {Rate: 0.5, Burst: 1, Timeout: time.Minute},
+		"admin":       {},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Fatalf("got %+v, want %+v", limits, want)
+	}
+	for _, s := range []string{"eth_getLogs", ":1", "debug:x", "debug:-1", "debug:NaN", "debug:Inf", "debug:+Inf", "debug:-Inf", "debug:1:x", "debug:1:1s:1"} {
+		if _, err := ParseLimits(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
@@ -19,13 +19,16 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -148,7 +151,7 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 	// to send notification to clients. It is thight to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
 	if options&OptionSubscriptions == OptionSubscriptions {
-		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec))
+		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec, s.subscriptionBuffer()))
 	}
 	s.codecsMu.Lock()
 	if atomic.LoadInt32(&s.run) != 1 { // server stopped
@@ -300,6 +303,17 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	// methods with an execution timeout are canceled through their context,
+	// methods without a context can't be interrupted and run to completion
+	var timeout time.Duration
+	parent := ctx
+	if req.callb.hasCtx {
+		if timeout = s.timeout(req.method); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -310,6 +324,13 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 
 	// execute RPC method and return result
 	reply := req.callb.method.Func.Call(arguments)
+	if timeout > 0 && ctx.Err() != nil {
+		if parent.Err() != nil {
+			return codec.CreateErrorResponse(&req.id, &canceledError{req.method}), nil
+		}
+		metrics.GetOrRegisterMeter("rpc/timeout/"+req.method, nil).Mark(1)
+		return codec.CreateErrorResponse(&req.id, &timeoutError{req.method, timeout}), nil
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -324,12 +345,26 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
+// admit fails if the client may not call the method of the request, or if the
+// request exceeds the rate limit of the method.
+func (s *Server) admit(ctx context.Context, req *serverRequest) Error {
+	if !permissionsFromContext(ctx).allows(req.svcname) {
+		return &permissionError{req.svcname}
+	}
+	return s.checkRateLimit(req)
+}
+
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
 	var callback func()
 	if req.err != nil {
 		response = codec.CreateErrorResponse(&req.id, req.err)
+	} else if err := s.admit(ctx, req); err != nil {
+		if _, ok := err.(*limitExceededError); ok {
+			setHTTPStatus(ctx, http.StatusTooManyRequests)
+		}
+		response = codec.CreateErrorResponse(&req.id, err)
 	} else {
 		response, callback = s.handle(ctx, codec, req)
 	}
@@ -353,6 +388,8 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 	for i, req := range requests {
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
+		} else if err := s.admit(ctx, req); err != nil {
+			responses[i] = codec.CreateErrorResponse(&req.id, err)
 		} else {
 			var callback func()
 			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
@@ -394,7 +431,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if r.isPubSub && strings.HasSuffix(r.method, unsubscribeMethodSuffix) {
-			requests[i] = &serverRequest{id: r.id, isUnsubscribe: true}
+			requests[i] = &serverRequest{id: r.id, svcname: r.service, method: r.service + unsubscribeMethodSuffix, isUnsubscribe: true}
 			argTypes := []reflect.Type{reflect.TypeOf("")} // expect subscription id as first arg
 			if args, err := codec.ParseRequestArguments(argTypes, r.params); err == nil {
 				requests[i].args = args
@@ -411,7 +448,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 
 		if r.isPubSub { // eth_subscribe, r.method contains the subscription method name
 			if callb, ok := svc.subscriptions[r.method]; ok {
-				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: svc.name + subscribeMethodSuffix, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
@@ -428,7 +465,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: svc.name + serviceMethodSeparator + r.method, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
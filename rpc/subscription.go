@@ -19,7 +19,10 @@ package rpc
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 var (
@@ -29,6 +32,75 @@ var (
 	ErrSubscriptionNotFound = errors.New("subscription not found")
 )
 
+var (
+	droppedNotificationMeter    = metrics.NewRegisteredMeter("rpc/subscriptions/dropped", nil)
+	overflowedSubscriptionMeter = metrics.NewRegisteredMeter("rpc/subscriptions/overflowed", nil)
+)
+
+// OverflowPolicy determines how notifications are handled that do not fit into
+// the buffer of a subscription, because the client is not reading them fast
+// enough.
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest buffered notification to make room for
+	// the new one.
+	DropOldest OverflowPolicy = iota
+
+	// CloseOnOverflow closes the connection of the subscription, failing the
+	// notification with ErrSubscriptionQueueOverflow.
+	CloseOnOverflow
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (p OverflowPolicy) MarshalText() ([]byte, error) {
+	switch p {
+	case DropOldest:
+		return []byte("drop"), nil
+	case CloseOnOverflow:
+		return []byte("close"), nil
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %d", p)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *OverflowPolicy) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "drop":
+		*p = DropOldest
+	case "close":
+		*p = CloseOnOverflow
+	default:
+		return fmt.Errorf(`unknown overflow policy %q, want "drop" or "close"`, text)
+	}
+	return nil
+}
+
+// SubscriptionBuffer configures the buffering of the notifications sent to the
+// subscribers of a server. Buffered notifications are written to the client in
+// the background, so that slow clients do not block the notifying services.
+type SubscriptionBuffer struct {
+	Size   int            // Maximum number of notifications buffered per subscription, 0 to write them directly
+	Policy OverflowPolicy // Handling of notifications exceeding the buffer
+}
+
+// SetSubscriptionBuffer configures the buffering of the notifications sent to
+// the subscribers of the server. It applies to connections established after
+// the call.
+func (s *Server) SetSubscriptionBuffer(buffer SubscriptionBuffer) {
+	s.bufferMu.Lock()
+	s.buffer = buffer
+	s.bufferMu.Unlock()
+}
+
+// subscriptionBuffer returns the buffering configuration of new connections.
+func (s *Server) subscriptionBuffer() SubscriptionBuffer {
+	s.bufferMu.RLock()
+	defer s.bufferMu.RUnlock()
+	return s.buffer
+}
+
 // ID defines a pseudo random number that is used to identify RPC subscriptions.
 type ID string
 
@@ -38,6 +110,10 @@ type Subscription struct {
 	ID        ID
 	namespace string
 	err       chan error // closed on unsubscribe
+
+	queueMu sync.Mutex
+	queue   []interface{} // notifications waiting to be written, if buffered
+	wake    chan struct{} // signals new notifications in the queue
 }
 
 // Err returns a channel that is closed when the client send an unsubscribe request.
@@ -52,16 +128,18 @@ type notifierKey struct{}
 // Server callbacks use the notifier to send notifications.
 type Notifier struct {
 	codec    ServerCodec
+	buffer   SubscriptionBuffer
 	subMu    sync.RWMutex // guards active and inactive maps
 	active   map[ID]*Subscription
 	inactive map[ID]*Subscription
 }
 
 // newNotifier creates a new notifier that can be used to send subscription
-// notifications to the client.
-func newNotifier(codec ServerCodec) *Notifier {
+// notifications to the client, buffering them as configured.
+func newNotifier(codec ServerCodec, buffer SubscriptionBuffer) *Notifier {
 	return &Notifier{
 		codec:    codec,
+		buffer:   buffer,
 		active:   make(map[ID]*Subscription),
 		inactive: make(map[ID]*Subscription),
 	}
@@ -78,7 +156,7 @@ func NotifierFromContext(ctx context.Context) (*Notifier, bool) {
 // are dropped until the subscription is marked as active. This is done
 // by the RPC server after the subscription ID is send to the client.
 func (n *Notifier) CreateSubscription() *Subscription {
-	s := &Subscription{ID: NewID(), err: make(chan error)}
+	s := &Subscription{ID: NewID(), err: make(chan error), wake: make(chan struct{}, 1)}
 	n.subMu.Lock()
 	n.inactive[s.ID] = s
 	n.subMu.Unlock()
@@ -87,21 +165,85 @@ func (n *Notifier) CreateSubscription() *Subscription {
 
 // Notify sends a notification to the client with the given data as payload.
 // If an error occurs the RPC connection is closed and the error is returned.
+//
+// If the notifications are buffered, the notification is queued to be written
+// in the background. Exceeding the buffer either drops the oldest queued
+// notification or fails with ErrSubscriptionQueueOverflow.
 func (n *Notifier) Notify(id ID, data interface{}) error {
 	n.subMu.RLock()
 	defer n.subMu.RUnlock()
 
 	sub, active := n.active[id]
-	if active {
-		notification := n.codec.CreateNotification(string(id), sub.namespace, data)
-		if err := n.codec.Write(notification); err != nil {
+	if !active {
+		return nil
+	}
+	if n.buffer.Size > 0 {
+		return n.enqueue(sub, data)
+	}
+	notification := n.codec.CreateNotification(string(id), sub.namespace, data)
+	if err := n.codec.Write(notification); err != nil {
+		n.codec.Close()
+		return err
+	}
+	return nil
+}
+
+// enqueue adds a notification to the buffer of a subscription, applying the
+// overflow policy if the buffer is full.
+func (n *Notifier) enqueue(sub *Subscription, data interface{}) error {
+	sub.queueMu.Lock()
+	if len(sub.queue) >= n.buffer.Size {
+		if n.buffer.Policy == CloseOnOverflow {
+			sub.queueMu.Unlock()
+			overflowedSubscriptionMeter.Mark(1)
 			n.codec.Close()
-			return err
+			return ErrSubscriptionQueueOverflow
 		}
+		sub.queue[0] = nil
+		sub.queue = sub.queue[1:]
+		droppedNotificationMeter.Mark(1)
+	}
+	sub.queue = append(sub.queue, data)
+	sub.queueMu.Unlock()
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
 	}
 	return nil
 }
 
+// send writes the buffered notifications of a subscription to the client until
+// it is unsubscribed or the connection is closed.
+func (n *Notifier) send(sub *Subscription) {
+	for {
+		select {
+		case <-sub.wake:
+		case <-sub.err:
+			return
+		case <-n.codec.Closed():
+			return
+		}
+		for {
+			sub.queueMu.Lock()
+			if len(sub.queue) == 0 {
+				sub.queueMu.Unlock()
+				break
+			}
+			data := sub.queue[0]
+			sub.queue[0] = nil
+			sub.queue = sub.queue[1:]
+			sub.queueMu.Unlock()
+
+			notification := n.codec.CreateNotification(string(sub.ID), sub.namespace, data)
+			if err := n.codec.Write(notification); err != nil {
+				n.codec.Close()
+				return
+			}
+		}
+	}
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 func (n *Notifier) Closed() <-chan interface{} {
 	return n.codec.Closed()
@@ -131,5 +273,9 @@ func (n *Notifier) activate(id ID, namespace string) {
 		sub.namespace = namespace
 		n.active[id] = sub
 		delete(n.inactive, id)
+
+		if n.buffer.Size > 0 {
+			go n.send(sub)
+		}
 	}
 }
@@ -328,3 +328,68 @@ func TestSubscriptionMultipleNamespaces(t *testing.T) {
 		}
 	}
 }
+
+// newBufferedTestNotifier creates a notifier with the given buffer, writing to
+// a pipe that is only read when the test decodes from the returned decoder.
+func newBufferedTestNotifier(t *testing.T, buffer SubscriptionBuffer) (*Notifier, *Subscription, *json.Decoder) {
+	clientConn, serverConn := net.Pipe()
+	notifier := newNotifier(NewJSONCodec(serverConn), buffer)
+
+	sub := notifier.CreateSubscription()
+	notifier.activate(sub.ID, "eth")
+
+	// Queue a notification and wait until the writer blocks on it
+	if err := notifier.Notify(sub.ID, 0); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+	for i := 0; ; i++ {
+		sub.queueMu.Lock()
+		queued := len(sub.queue)
+		sub.queueMu.Unlock()
+		if queued == 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("notification not picked up by the writer")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return notifier, sub, json.NewDecoder(clientConn)
+}
+
+func TestSubscriptionBufferDropOldest(t *testing.T) {
+	notifier, sub, in := newBufferedTestNotifier(t, SubscriptionBuffer{Size: 2, Policy: DropOldest})
+	defer notifier.codec.Close()
+
+	// Overflowing the buffer must drop the oldest notifications
+	for i := 1; i <= 5; i++ {
+		if err := notifier.Notify(sub.ID, i); err != nil {
+			t.Fatalf("notification %d: failed to notify: %v", i, err)
+		}
+	}
+	for _, want := range []float64{0, 4, 5} {
+		var notification jsonNotification
+		if err := in.Decode(&notification); err != nil {
+			t.Fatalf("failed to read notification: %v", err)
+		}
+		if notification.Params.Subscription != string(sub.ID) || notification.Params.Result != want {
+			t.Fatalf("notification mismatch: have %v, want %v", notification.Params.Result, want)
+		}
+	}
+}
+
+func TestSubscriptionBufferCloseOnOverflow(t *testing.T) {
+	notifier, sub, _ := newBufferedTestNotifier(t, SubscriptionBuffer{Size: 1, Policy: CloseOnOverflow})
+
+	if err := notifier.Notify(sub.ID, 1); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+	if err := notifier.Notify(sub.ID, 2); err != ErrSubscriptionQueueOverflow {
+		t.Fatalf("overflow error mismatch: have %v, want %v", err, ErrSubscriptionQueueOverflow)
+	}
+	select {
+	case <-notifier.Closed():
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed on overflow")
+	}
+}
@@ -57,6 +57,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // full name of the method, e.g. eth_getLogs
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -74,6 +75,12 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	limitsMu sync.RWMutex
+	limits   map[string]*methodLimit
+
+	bufferMu sync.RWMutex
+	buffer   SubscriptionBuffer
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -0,0 +1,132 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package admin serves the profiling and runtime debug endpoints of a swarm
+// node on a dedicated admin port, so that a node can be profiled in
+// production without exposing the endpoints on its public HTTP gateway.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+)
+
+// startTime is the time the process started, reported as its uptime
+var startTime = time.Now()
+
+// RuntimeStats is the response of the /debug/runtime endpoint
+type RuntimeStats struct {
+	GoVersion    string        `json:"goVersion"`
+	NumCPU       int           `json:"numCPU"`
+	GOMAXPROCS   int           `json:"gomaxprocs"`
+	Goroutines   int           `json:"goroutines"`
+	CgoCalls     int64         `json:"cgoCalls"`
+	Uptime       time.Duration `json:"uptime"`
+	Alloc        uint64        `json:"alloc"`      // bytes of allocated heap objects
+	TotalAlloc   uint64        `json:"totalAlloc"` // cumulative bytes allocated
+	Sys          uint64        `json:"sys"`        // bytes obtained from the OS
+	HeapInuse    uint64        `json:"heapInuse"`
+	HeapIdle     uint64        `json:"heapIdle"`
+	HeapReleased uint64        `json:"heapReleased"`
+	HeapObjects  uint64        `json:"heapObjects"`
+	NumGC        uint32        `json:"numGC"`
+	PauseTotal   time.Duration `json:"pauseTotal"` // cumulative GC pause time
+	LastGC       time.Time     `json:"lastGC"`
+}
+
+// ReadRuntimeStats returns the current runtime stats of the process
+func ReadRuntimeStats() *RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return &RuntimeStats{
+		GoVersion:    runtime.Version(),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		Goroutines:   runtime.NumGoroutine(),
+		CgoCalls:     runtime.NumCgoCall(),
+		Uptime:       time.Since(startTime),
+		Alloc:        m.Alloc,
+		TotalAlloc:   m.TotalAlloc,
+		Sys:          m.Sys,
+		HeapInuse:    m.HeapInuse,
+		HeapIdle:     m.HeapIdle,
+		HeapReleased: m.HeapReleased,
+		HeapObjects:  m.HeapObjects,
+		NumGC:        m.NumGC,
+		PauseTotal:   time.Duration(m.PauseTotalNs),
+		LastGC:       time.Unix(0, int64(m.LastGC)),
+	}
+}
+
+// Handler returns the handler of the admin endpoints:
+//
+//	/debug/pprof/             the net/http/pprof profiles
+//	/debug/runtime            runtime and memory stats in JSON
+//	/debug/vars               the expvar variables
+//	/debug/metrics/prometheus the metrics registry in the Prometheus format
+//
+// If password is not empty, requests must authenticate with HTTP basic
+// authentication with username and password.
+func Handler(username, password string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/runtime", handleRuntime)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/debug/metrics/prometheus", prometheus.Handler(metrics.DefaultRegistry))
+	if password == "" {
+		return mux
+	}
+	return &basicAuth{username: username, password: password, handler: mux}
+}
+
+func handleRuntime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReadRuntimeStats())
+}
+
+// basicAuth passes the requests which authenticate with the credentials to
+// its handler
+type basicAuth struct {
+	username string
+	password string
+	handler  http.Handler
+}
+
+func (a *basicAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, password, ok := r.BasicAuth()
+	// compare both credentials in constant time so that the response time
+	// does not reveal which one is wrong
+	validUser := subtle.ConstantTimeCompare([]byte(username), []byte(a.username))
+	validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(a.password))
+	if !ok || validUser&validPassword != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="swarm admin"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	a.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	srv := httptest.NewServer(Handler("admin", "secret"))
+	defer srv.Close()
+
+	for _, test := range []struct {
+		username, password string
+		auth               bool
+		expectedCode       int
+	}{
+		{auth: false, expectedCode: http.StatusUnauthorized},
+		{username: "admin", password: "wrong", auth: true, expectedCode: http.StatusUnauthorized},
+		{username: "other", password: "secret", auth: true, expectedCode: http.StatusUnauthorized},
+		{username: "admin", password: "secret", auth: true, expectedCode: http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", srv.URL+"/debug/pprof/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.auth {
+			req.SetBasicAuth(test.username, test.password)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != test.expectedCode {
+			t.Fatalf("credentials %q:%q: expected status %d, got %d", test.username, test.password, test.expectedCode, res.StatusCode)
+		}
+	}
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	srv := httptest.NewServer(Handler("", ""))
+	defer srv.Close()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/debug/vars", "/debug/metrics/prometheus"} {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, res.StatusCode)
+		}
+	}
+
+	res, err := http.Get(srv.URL + "/debug/runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var stats RuntimeStats
+	if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Goroutines == 0 || stats.NumCPU == 0 || stats.Sys == 0 || stats.GoVersion == "" {
+		t.Fatalf("unexpected runtime stats %+v", stats)
+	}
+}
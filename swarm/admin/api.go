@@ -0,0 +1,129 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package admin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/log"
+	swarmmetrics "github.com/ethereum/go-ethereum/swarm/metrics"
+)
+
+// API is the RPC API of the admin namespace controlling the metrics and the
+// logging of a node at runtime, so that more detail can be captured during
+// an incident without restarting the node
+type API struct {
+	mu        sync.Mutex
+	logLevels map[string]int // log levels by module
+}
+
+// NewAPI creates the admin API
+func NewAPI() *API {
+	return &API{logLevels: make(map[string]int)}
+}
+
+// MetricsStatus returns the state of the metrics collection and export
+func (api *API) MetricsStatus() *swarmmetrics.Status {
+	return swarmmetrics.GetStatus()
+}
+
+// SetMetricsCollection enables or disables the collection of metrics
+func (api *API) SetMetricsCollection(enabled bool) {
+	swarmmetrics.SetCollection(enabled)
+}
+
+// SetMetricsExport pauses or resumes the export of metrics to the reporters
+// configured when the node started
+func (api *API) SetMetricsExport(enabled bool) {
+	swarmmetrics.SetExport(enabled)
+}
+
+// SetMetricsInterval sets the interval of the metrics export, e.g. "30s"
+func (api *API) SetMetricsInterval(interval string) error {
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return err
+	}
+	return swarmmetrics.SetExportInterval(d)
+}
+
+// SetLogLevel sets the log level of a module, such as swarm/storage, from
+// 0 (silent) to 5 (trace), or the global log level if module is empty.
+//
+// The log level of a module can only be raised above the global level, and
+// setting it to 0 resets it to the global level. The module levels replace
+// the ones set with the --vmodule flag.
+func (api *API) SetLogLevel(module string, level int) error {
+	if level < 0 || level > int(log.LvlTrace) {
+		return fmt.Errorf("invalid log level %d, expected 0-%d", level, log.LvlTrace)
+	}
+	if module == "" {
+		debug.Handler.Verbosity(level)
+		return nil
+	}
+	if strings.ContainsAny(module, "=, ") {
+		return fmt.Errorf("invalid module %q", module)
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	levels := make(map[string]int, len(api.logLevels)+1)
+	for m, l := range api.logLevels {
+		levels[m] = l
+	}
+	if level == 0 {
+		delete(levels, module)
+	} else {
+		levels[module] = level
+	}
+	if err := debug.Handler.Vmodule(vmodulePattern(levels)); err != nil {
+		return err
+	}
+	api.logLevels = levels
+	return nil
+}
+
+// LogLevels returns the log levels set with SetLogLevel by module
+func (api *API) LogLevels() map[string]int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	levels := make(map[string]int, len(api.logLevels))
+	for m, l := range api.logLevels {
+		levels[m] = l
+	}
+	return levels
+}
+
+// vmodulePattern returns the vmodule pattern of the module log levels, a
+// module matches the files of its package and of its subpackages unless it
+// is a pattern itself
+func vmodulePattern(levels map[string]int) string {
+	rules := make([]string, 0, len(levels))
+	for module, level := range levels {
+		pattern := module
+		if !strings.Contains(pattern, "*") && !strings.HasSuffix(pattern, ".go") {
+			pattern = strings.TrimSuffix(pattern, "/") + "/*"
+		}
+		rules = append(rules, fmt.Sprintf("%s=%d", pattern, level))
+	}
+	sort.Strings(rules)
+	return strings.Join(rules, ",")
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package admin
+
+import (
+	"testing"
+)
+
+func TestSetLogLevel(t *testing.T) {
+	api := NewAPI()
+	if err := api.SetLogLevel("swarm/storage", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.SetLogLevel("swarm/network/", 4); err != nil {
+		t.Fatal(err)
+	}
+	if pattern := vmodulePattern(api.LogLevels()); pattern != "swarm/network/*=4,swarm/storage/*=5" {
+		t.Fatalf("unexpected vmodule pattern %q", pattern)
+	}
+
+	// level 0 resets a module to the global level
+	if err := api.SetLogLevel("swarm/storage", 0); err != nil {
+		t.Fatal(err)
+	}
+	if levels := api.LogLevels(); len(levels) != 1 || levels["swarm/network/"] != 4 {
+		t.Fatalf("unexpected log levels %v", levels)
+	}
+
+	for _, test := range []struct {
+		module string
+		level  int
+	}{
+		{"swarm/storage", 6},
+		{"swarm/storage", -1},
+		{"swarm/storage=5", 3},
+		{"swarm/storage,p2p", 3},
+	} {
+		if err := api.SetLogLevel(test.module, test.level); err == nil {
+			t.Fatalf("expected an error setting the log level of %q to %d", test.module, test.level)
+		}
+	}
+}
+
+func TestSetMetricsInterval(t *testing.T) {
+	api := NewAPI()
+	if err := api.SetMetricsInterval("30s"); err != nil {
+		t.Fatal(err)
+	}
+	if interval := api.MetricsStatus().Interval; interval != "30s" {
+		t.Fatalf("expected interval 30s, got %s", interval)
+	}
+	for _, interval := range []string{"10ms", "thirty seconds"} {
+		if err := api.SetMetricsInterval(interval); err == nil {
+			t.Fatalf("expected an error setting the interval to %q", interval)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package admin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Config holds the settings of the admin endpoints
+type Config struct {
+	Enabled  bool
+	Addr     string // listening interface
+	Port     int
+	Username string
+	Password string // basic authentication is required if not empty
+}
+
+// DefaultConfig contains the default admin settings
+var DefaultConfig = Config{
+	Addr:     "127.0.0.1",
+	Port:     8550,
+	Username: "admin",
+}
+
+var (
+	adminEnabledFlag = cli.BoolFlag{
+		Name:  "admin",
+		Usage: "Enable the admin HTTP server with the pprof and runtime debug endpoints",
+	}
+	adminAddrFlag = cli.StringFlag{
+		Name:  "admin.addr",
+		Usage: "Admin HTTP server listening interface",
+		Value: DefaultConfig.Addr,
+	}
+	adminPortFlag = cli.IntFlag{
+		Name:  "admin.port",
+		Usage: "Admin HTTP server listening port",
+		Value: DefaultConfig.Port,
+	}
+	adminUsernameFlag = cli.StringFlag{
+		Name:  "admin.username",
+		Usage: "Admin HTTP basic authentication username",
+		Value: DefaultConfig.Username,
+	}
+	adminPasswordFlag = cli.StringFlag{
+		Name:   "admin.password",
+		Usage:  "Admin HTTP basic authentication password, no authentication if empty",
+		EnvVar: "SWARM_ADMIN_PASSWORD",
+	}
+)
+
+// Flags holds all command-line flags required for the admin server
+var Flags = []cli.Flag{
+	adminEnabledFlag,
+	adminAddrFlag,
+	adminPortFlag,
+	adminUsernameFlag,
+	adminPasswordFlag,
+}
+
+// SetConfig applies the admin flags set on the command line to cfg
+func SetConfig(ctx *cli.Context, cfg *Config) {
+	if ctx.GlobalIsSet(adminEnabledFlag.Name) {
+		cfg.Enabled = ctx.GlobalBool(adminEnabledFlag.Name)
+	}
+	if ctx.GlobalIsSet(adminAddrFlag.Name) {
+		cfg.Addr = ctx.GlobalString(adminAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(adminPortFlag.Name) {
+		cfg.Port = ctx.GlobalInt(adminPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(adminUsernameFlag.Name) {
+		cfg.Username = ctx.GlobalString(adminUsernameFlag.Name)
+	}
+	if password := ctx.GlobalString(adminPasswordFlag.Name); password != "" {
+		cfg.Password = password
+	}
+}
+
+// server is the admin server started by Setup
+var server *http.Server
+
+// Setup starts the admin server if it is enabled
+func Setup(cfg *Config) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	addr := net.JoinHostPort(cfg.Addr, strconv.Itoa(cfg.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if cfg.Password == "" {
+		if ip := net.ParseIP(cfg.Addr); ip == nil || !ip.IsLoopback() {
+			log.Warn("Admin server is reachable from the network without authentication", "addr", addr)
+		}
+	}
+	log.Info("Starting admin server", "addr", "http://"+listener.Addr().String()+"/debug/pprof", "auth", cfg.Password != "")
+	server = &http.Server{Handler: Handler(cfg.Username, cfg.Password)}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("Failure in running admin server", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Close stops the admin server started by Setup
+func Close() {
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	server = nil
+}
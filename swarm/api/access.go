@@ -0,0 +1,237 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/swarm/storage/encryption"
+)
+
+const (
+	AccessContentType = "application/bzz-access+json"
+	AccessTypeACT     = "act"
+)
+
+var (
+	ErrNoAccess        = errors.New("no access granted to this key")
+	ErrNotAccess       = errors.New("not an access manifest")
+	ErrNotPublisher    = errors.New("key is not the publisher of the access manifest")
+	ErrRevokePublisher = errors.New("cannot revoke the access of the publisher")
+)
+
+// AccessEntry is attached to the root entry of an access manifest, whose hash
+// is a reference encrypted with a session key. The other entries of the
+// manifest form an access control table mapping a lookup key derived from
+// the shared secret of the publisher and each grantee to the session key
+// encrypted for that grantee.
+//
+// Revoking a grantee removes its entry from the table of a new access
+// manifest, it cannot make a grantee forget a reference it already resolved.
+type AccessEntry struct {
+	Type      string `json:"type"`
+	Publisher string `json:"publisher"` // hex encoded compressed public key
+	Salt      []byte `json:"salt"`
+}
+
+// NewAccessManifest returns an access manifest granting the publisher and
+// the holders of the private keys of the grantees access to ref
+func NewAccessManifest(publisher *ecdsa.PrivateKey, ref []byte, grantees []*ecdsa.PublicKey) (*Manifest, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	sessionKey, err := encryption.GenerateRandomKey()
+	if err != nil {
+		return nil, err
+	}
+	encryptedRef, err := accessEncryption().Encrypt(ref, sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{
+		Entries: []ManifestEntry{{
+			Hash:        hex.EncodeToString(encryptedRef),
+			ContentType: AccessContentType,
+			Access: &AccessEntry{
+				Type:      AccessTypeACT,
+				Publisher: hex.EncodeToString(crypto.CompressPubkey(&publisher.PublicKey)),
+				Salt:      salt,
+			},
+		}},
+	}
+	grantees = append([]*ecdsa.PublicKey{&publisher.PublicKey}, grantees...)
+	if err := grantAccess(m, publisher, sessionKey, grantees); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GrantAccess grants the holders of the private keys of the grantees access
+// to the reference of the access manifest m published by publisher
+func GrantAccess(m *Manifest, publisher *ecdsa.PrivateKey, grantees []*ecdsa.PublicKey) error {
+	if err := checkPublisher(m, publisher); err != nil {
+		return err
+	}
+	sessionKey, err := sessionKey(m, publisher, &publisher.PublicKey)
+	if err != nil {
+		return err
+	}
+	return grantAccess(m, publisher, sessionKey, grantees)
+}
+
+// RevokeAccess removes the grantees from the access control table of the
+// access manifest m published by publisher
+func RevokeAccess(m *Manifest, publisher *ecdsa.PrivateKey, grantees []*ecdsa.PublicKey) error {
+	if err := checkPublisher(m, publisher); err != nil {
+		return err
+	}
+	access := m.Entries[0].Access
+	revoked := make(map[string]bool)
+	for _, grantee := range grantees {
+		if grantee.X.Cmp(publisher.X) == 0 && grantee.Y.Cmp(publisher.Y) == 0 {
+			return ErrRevokePublisher
+		}
+		secret, err := sharedSecret(publisher, grantee)
+		if err != nil {
+			return err
+		}
+		revoked[hex.EncodeToString(lookupKey(secret, access.Salt))] = true
+	}
+	entries := m.Entries[:1]
+	for _, entry := range m.Entries[1:] {
+		if !revoked[entry.Path] {
+			entries = append(entries, entry)
+		}
+	}
+	m.Entries = entries
+	return nil
+}
+
+// ResolveAccess returns the reference the access manifest m grants the
+// holder of key access to
+func ResolveAccess(m *Manifest, key *ecdsa.PrivateKey) ([]byte, error) {
+	if len(m.Entries) == 0 || m.Entries[0].Access == nil || m.Entries[0].Access.Type != AccessTypeACT {
+		return nil, ErrNotAccess
+	}
+	publisher, err := hex.DecodeString(m.Entries[0].Access.Publisher)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publisher key: %v", err)
+	}
+	publisherKey, err := crypto.DecompressPubkey(publisher)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publisher key: %v", err)
+	}
+	sessionKey, err := sessionKey(m, key, publisherKey)
+	if err != nil {
+		return nil, err
+	}
+	encryptedRef, err := hex.DecodeString(m.Entries[0].Hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted reference: %v", err)
+	}
+	return accessEncryption().Decrypt(encryptedRef, sessionKey)
+}
+
+// grantAccess adds the session key encrypted for each grantee to the access
+// control table of m, replacing existing entries for the grantee
+func grantAccess(m *Manifest, publisher *ecdsa.PrivateKey, sessionKey []byte, grantees []*ecdsa.PublicKey) error {
+	access := m.Entries[0].Access
+	for _, grantee := range grantees {
+		secret, err := sharedSecret(publisher, grantee)
+		if err != nil {
+			return err
+		}
+		encryptedKey, err := accessEncryption().Encrypt(sessionKey, accessKey(secret, access.Salt))
+		if err != nil {
+			return err
+		}
+		entry := ManifestEntry{
+			Hash: hex.EncodeToString(encryptedKey),
+			Path: hex.EncodeToString(lookupKey(secret, access.Salt)),
+		}
+		replaced := false
+		for i := range m.Entries[1:] {
+			if m.Entries[i+1].Path == entry.Path {
+				m.Entries[i+1] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			m.Entries = append(m.Entries, entry)
+		}
+	}
+	return nil
+}
+
+// sessionKey returns the session key m grants the holder of key access to,
+// the other party of the shared secret is the publisher unless the
+// publisher looks up its own entry
+func sessionKey(m *Manifest, key *ecdsa.PrivateKey, publisher *ecdsa.PublicKey) ([]byte, error) {
+	secret, err := sharedSecret(key, publisher)
+	if err != nil {
+		return nil, err
+	}
+	salt := m.Entries[0].Access.Salt
+	lookup := hex.EncodeToString(lookupKey(secret, salt))
+	for _, entry := range m.Entries[1:] {
+		if entry.Path != lookup {
+			continue
+		}
+		encryptedKey, err := hex.DecodeString(entry.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted session key: %v", err)
+		}
+		return accessEncryption().Decrypt(encryptedKey, accessKey(secret, salt))
+	}
+	return nil, ErrNoAccess
+}
+
+// checkPublisher checks that m is an access manifest published by publisher
+func checkPublisher(m *Manifest, publisher *ecdsa.PrivateKey) error {
+	if len(m.Entries) == 0 || m.Entries[0].Access == nil || m.Entries[0].Access.Type != AccessTypeACT {
+		return ErrNotAccess
+	}
+	if m.Entries[0].Access.Publisher != hex.EncodeToString(crypto.CompressPubkey(&publisher.PublicKey)) {
+		return ErrNotPublisher
+	}
+	return nil
+}
+
+// sharedSecret returns the ECDH shared secret of key and pub
+func sharedSecret(key *ecdsa.PrivateKey, pub *ecdsa.PublicKey) ([]byte, error) {
+	return ecies.ImportECDSA(key).GenerateShared(ecies.ImportECDSAPublic(pub), 16, 16)
+}
+
+func lookupKey(secret, salt []byte) []byte {
+	return crypto.Keccak256(secret, salt, []byte{0})
+}
+
+func accessKey(secret, salt []byte) []byte {
+	return crypto.Keccak256(secret, salt, []byte{1})
+}
+
+func accessEncryption() encryption.Encryption {
+	return encryption.New(0, 0, sha3.NewKeccak256)
+}
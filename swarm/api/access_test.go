@@ -0,0 +1,109 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestAccess(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	publisher, alice, bob, eve := keys[0], keys[1], keys[2], keys[3]
+	ref := bytes.Repeat([]byte{0xab}, 64)
+
+	// round trip the manifest through JSON like an upload and download
+	roundTrip := func(m *Manifest) *Manifest {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Manifest
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		return &decoded
+	}
+	checkAccess := func(m *Manifest, key *ecdsa.PrivateKey, granted bool) {
+		resolved, err := ResolveAccess(m, key)
+		if !granted {
+			if err != ErrNoAccess {
+				t.Fatalf("expected %v, got %x (%v)", ErrNoAccess, resolved, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resolved, ref) {
+			t.Fatalf("expected reference %x, got %x", ref, resolved)
+		}
+	}
+
+	m, err := NewAccessManifest(publisher, ref, []*ecdsa.PublicKey{&alice.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = roundTrip(m)
+	if bytes.Contains([]byte(m.Entries[0].Hash), []byte("abab")) {
+		t.Fatal("expected the reference to be encrypted")
+	}
+	checkAccess(m, publisher, true)
+	checkAccess(m, alice, true)
+	checkAccess(m, bob, false)
+
+	// only the publisher can grant access
+	if err := GrantAccess(m, alice, []*ecdsa.PublicKey{&eve.PublicKey}); err != ErrNotPublisher {
+		t.Fatalf("expected %v, got %v", ErrNotPublisher, err)
+	}
+	if err := GrantAccess(m, publisher, []*ecdsa.PublicKey{&bob.PublicKey, &bob.PublicKey}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(m.Entries))
+	}
+	m = roundTrip(m)
+	checkAccess(m, alice, true)
+	checkAccess(m, bob, true)
+	checkAccess(m, eve, false)
+
+	if err := RevokeAccess(m, publisher, []*ecdsa.PublicKey{&alice.PublicKey}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RevokeAccess(m, publisher, []*ecdsa.PublicKey{&publisher.PublicKey}); err != ErrRevokePublisher {
+		t.Fatalf("expected %v, got %v", ErrRevokePublisher, err)
+	}
+	m = roundTrip(m)
+	checkAccess(m, publisher, true)
+	checkAccess(m, alice, false)
+	checkAccess(m, bob, true)
+
+	if _, err := ResolveAccess(&Manifest{Entries: []ManifestEntry{{Hash: "00"}}}, bob); err != ErrNotAccess {
+		t.Fatalf("expected %v, got %v", ErrNotAccess, err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"os"
 	"path"
 	"strings"
 
@@ -38,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/multihash"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	"github.com/ethereum/go-ethereum/swarm/storage/mru"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
 )
 
 type ErrResourceReturn struct {
@@ -68,6 +70,8 @@ var (
 	apiRmFileFail      = metrics.NewRegisteredCounter("api.removefile.fail", nil)
 	apiAppendFileCount = metrics.NewRegisteredCounter("api.appendfile.count", nil)
 	apiAppendFileFail  = metrics.NewRegisteredCounter("api.appendfile.fail", nil)
+	apiSetAttrCount    = metrics.NewRegisteredCounter("api.setattr.count", nil)
+	apiSetAttrFail     = metrics.NewRegisteredCounter("api.setattr.fail", nil)
 	apiGetInvalid      = metrics.NewRegisteredCounter("api.get.invalid", nil)
 )
 
@@ -241,9 +245,56 @@ func (self *Api) Retrieve(addr storage.Address) (reader storage.LazySectionReade
 	return self.fileStore.Retrieve(addr)
 }
 
+// RetrieveContext is Retrieve with the reads traced as a part of the request
+// in ctx
+func (self *Api) RetrieveContext(ctx context.Context, addr storage.Address) (reader storage.LazySectionReader, isEncrypted bool) {
+	return self.fileStore.RetrieveContext(ctx, addr)
+}
+
+// RetrieveErasure is RetrieveContext for content erasure coded with the
+// given number of parity chunks per intermediate chunk, as recorded in its
+// manifest entry
+func (self *Api) RetrieveErasure(ctx context.Context, addr storage.Address, parity int) storage.LazySectionReader {
+	return self.fileStore.RetrieveErasure(ctx, addr, parity)
+}
+
+// RetrieveRange returns a reader of the length bytes of the content at addr
+// starting at offset, see FileStore.RetrieveRange
+func (self *Api) RetrieveRange(ctx context.Context, addr storage.Address, offset, length int64) (io.ReadSeeker, error) {
+	return self.fileStore.RetrieveRange(ctx, addr, offset, length)
+}
+
+// Verify checks that every chunk of the content at addr can be retrieved,
+// only querying the local store if local is true
+func (self *Api) Verify(addr storage.Address, local bool) (*storage.VerifyReport, error) {
+	return self.fileStore.Verify(storage.Reference(addr), local)
+}
+
+// VerifyErasure is Verify for content erasure coded with the given number of
+// parity chunks per intermediate chunk
+func (self *Api) VerifyErasure(addr storage.Address, local bool, parity int) (*storage.VerifyReport, error) {
+	return self.fileStore.VerifyErasure(storage.Reference(addr), local, parity)
+}
+
+// HasChunk reports whether the chunk at addr is in the local store
+func (self *Api) HasChunk(addr storage.Address) bool {
+	return self.fileStore.HasChunk(addr)
+}
+
+// PutChunk stores a single chunk given by its span and payload, see
+// FileStore.PutChunk
+func (self *Api) PutChunk(data []byte) (storage.Address, error) {
+	return self.fileStore.PutChunk(storage.ChunkData(data))
+}
+
 func (self *Api) Store(data io.Reader, size int64, toEncrypt bool) (addr storage.Address, wait func(), err error) {
+	return self.StoreContext(context.Background(), data, size, toEncrypt)
+}
+
+// StoreContext is Store traced as a part of the request in ctx
+func (self *Api) StoreContext(ctx context.Context, data io.Reader, size int64, toEncrypt bool) (addr storage.Address, wait func(), err error) {
 	log.Debug("api.store", "size", size)
-	return self.fileStore.Store(data, size, toEncrypt)
+	return self.fileStore.StoreContext(ctx, data, size, toEncrypt)
 }
 
 type ErrResolve error
@@ -288,14 +339,32 @@ func (self *Api) Resolve(uri *URI) (storage.Address, error) {
 
 // Put provides singleton manifest creation on top of FileStore store
 func (self *Api) Put(content, contentType string, toEncrypt bool) (k storage.Address, wait func(), err error) {
+	return self.PutErasure(content, contentType, toEncrypt, 0)
+}
+
+// PutErasure is Put with the content erasure coded with the given number of
+// parity chunks per intermediate chunk, which is recorded in the manifest.
+// The content is not erasure coded if parity is 0.
+func (self *Api) PutErasure(content, contentType string, toEncrypt bool, parity int) (k storage.Address, wait func(), err error) {
 	apiPutCount.Inc(1)
 	r := strings.NewReader(content)
-	key, waitContent, err := self.fileStore.Store(r, int64(len(content)), toEncrypt)
+	var (
+		key         storage.Address
+		waitContent func()
+	)
+	if parity > 0 {
+		key, waitContent, err = self.fileStore.StoreErasure(context.Background(), r, int64(len(content)), toEncrypt, parity)
+	} else {
+		key, waitContent, err = self.fileStore.Store(r, int64(len(content)), toEncrypt)
+	}
 	if err != nil {
 		apiPutFail.Inc(1)
 		return nil, nil, err
 	}
 	manifest := fmt.Sprintf(`{"entries":[{"hash":"%v","contentType":"%s"}]}`, key, contentType)
+	if parity > 0 {
+		manifest = fmt.Sprintf(`{"entries":[{"hash":"%v","contentType":"%s","parity":%d}]}`, key, contentType, parity)
+	}
 	r = strings.NewReader(manifest)
 	key, waitManifest, err := self.fileStore.Store(r, int64(len(manifest)), toEncrypt)
 	if err != nil {
@@ -308,10 +377,39 @@ func (self *Api) Put(content, contentType string, toEncrypt bool) (k storage.Add
 	}, nil
 }
 
+// GetEntry returns the entry of the manifest at manifestAddr which Get
+// resolves path to
+func (self *Api) GetEntry(manifestAddr storage.Address, path string) (*ManifestEntry, error) {
+	trie, err := loadManifest(self.fileStore, manifestAddr, nil)
+	if err != nil {
+		return nil, err
+	}
+	entry, _ := trie.getEntry(path)
+	if entry == nil {
+		return nil, fmt.Errorf("manifest entry for '%s' not found", path)
+	}
+	return &entry.ManifestEntry, nil
+}
+
 // Get uses iterative manifest retrieval and prefix matching
 // to resolve basePath to content using FileStore retrieve
 // it returns a section reader, mimeType, status, the key of the actual content and an error
 func (self *Api) Get(manifestAddr storage.Address, path string) (reader storage.LazySectionReader, mimeType string, status int, contentAddr storage.Address, err error) {
+	return self.GetContext(context.Background(), manifestAddr, path)
+}
+
+// GetContext is Get with the manifest lookup and the reads of the returned
+// reader traced as a part of the request in ctx
+func (self *Api) GetContext(ctx context.Context, manifestAddr storage.Address, path string) (reader storage.LazySectionReader, mimeType string, status int, contentAddr storage.Address, err error) {
+	_, sp := tracing.StartSpan(ctx, "api.get")
+	sp.SetTag("addr", manifestAddr.Hex())
+	sp.SetTag("path", path)
+	defer func() {
+		sp.SetTag("status", status)
+		sp.SetError(err)
+		sp.Finish()
+	}()
+
 	log.Debug("api.get", "key", manifestAddr, "path", path)
 	apiGetCount.Inc(1)
 	trie, err := loadManifest(self.fileStore, manifestAddr, nil)
@@ -332,7 +430,7 @@ func (self *Api) Get(manifestAddr storage.Address, path string) (reader storage.
 
 			// get the resource root chunk key
 			log.Trace("resource type", "key", manifestAddr, "hash", entry.Hash)
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
 			rsrc, err := self.resource.Load(storage.Address(common.FromHex(entry.Hash)))
 			if err != nil {
@@ -416,7 +514,7 @@ func (self *Api) Get(manifestAddr storage.Address, path string) (reader storage.
 		} else {
 			mimeType = entry.ContentType
 			log.Debug("content lookup key", "key", contentAddr, "mimetype", mimeType)
-			reader, _ = self.fileStore.Retrieve(contentAddr)
+			reader = self.fileStore.RetrieveErasure(ctx, contentAddr, entry.Parity)
 		}
 	} else {
 		// no entry found
@@ -504,6 +602,107 @@ func (self *Api) AddFile(mhash, path, fname string, content []byte, nameresolver
 
 }
 
+// AddSymlink adds a symbolic link to target under path/fname to the manifest
+func (self *Api) AddSymlink(mhash, path, fname, target string, nameresolver bool) (storage.Address, string, error) {
+	apiAddFileCount.Inc(1)
+
+	uri, err := Parse("bzz:/" + mhash)
+	if err != nil {
+		apiAddFileFail.Inc(1)
+		return nil, "", err
+	}
+	mkey, err := self.Resolve(uri)
+	if err != nil {
+		apiAddFileFail.Inc(1)
+		return nil, "", err
+	}
+
+	// trim the root dir we added
+	if path == "" {
+		apiAddFileFail.Inc(1)
+		return nil, "", fmt.Errorf("invalid path for %s: path is empty", fname)
+	} else if path[:1] == "/" {
+		path = path[1:]
+	}
+
+	entry := &ManifestEntry{
+		Path:       filepath.Join(path, fname),
+		Mode:       int64(os.ModeSymlink | 0777),
+		Size:       int64(len(target)),
+		ModTime:    time.Now(),
+		LinkTarget: target,
+	}
+
+	mw, err := self.NewManifestWriter(mkey, nil)
+	if err != nil {
+		apiAddFileFail.Inc(1)
+		return nil, "", err
+	}
+
+	fkey, err := mw.AddEntry(strings.NewReader(target), entry)
+	if err != nil {
+		apiAddFileFail.Inc(1)
+		return nil, "", err
+	}
+
+	newMkey, err := mw.Store()
+	if err != nil {
+		apiAddFileFail.Inc(1)
+		return nil, "", err
+	}
+
+	return fkey, newMkey.String(), nil
+}
+
+// SetFileAttrs sets the permission bits and the modification time of the
+// manifest entry under path/fname, the file type bits of the entry are kept
+func (self *Api) SetFileAttrs(mhash, path, fname string, perm os.FileMode, modTime time.Time, nameresolver bool) (string, error) {
+	apiSetAttrCount.Inc(1)
+
+	uri, err := Parse("bzz:/" + mhash)
+	if err != nil {
+		apiSetAttrFail.Inc(1)
+		return "", err
+	}
+	mkey, err := self.Resolve(uri)
+	if err != nil {
+		apiSetAttrFail.Inc(1)
+		return "", err
+	}
+
+	// trim the root dir we added
+	if path == "" {
+		apiSetAttrFail.Inc(1)
+		return "", fmt.Errorf("invalid path for %s: path is empty", fname)
+	} else if path[:1] == "/" {
+		path = path[1:]
+	}
+	fpath := filepath.Join(path, fname)
+
+	quitC := make(chan bool)
+	trie, err := loadManifest(self.fileStore, mkey, quitC)
+	if err != nil {
+		apiSetAttrFail.Inc(1)
+		return "", err
+	}
+	entry, fullpath := trie.getEntry(fpath)
+	if entry == nil || fullpath != fpath || entry.ContentType == ManifestType {
+		apiSetAttrFail.Inc(1)
+		return "", fmt.Errorf("manifest entry for '%s' not found", fpath)
+	}
+	updated := entry.ManifestEntry
+	updated.Path = fpath
+	updated.Mode = int64(os.FileMode(updated.Mode)&^os.ModePerm | perm.Perm())
+	updated.ModTime = modTime
+	trie.addEntry(newManifestTrieEntry(&updated, nil), quitC)
+
+	if err := trie.recalcAndStore(); err != nil {
+		apiSetAttrFail.Inc(1)
+		return "", err
+	}
+	return trie.ref.String(), nil
+}
+
 func (self *Api) RemoveFile(mhash, path, fname string, nameresolver bool) (string, error) {
 	apiRmFileCount.Inc(1)
 
@@ -654,6 +853,65 @@ func (self *Api) BuildDirectoryTree(mhash string, nameresolver bool) (addr stora
 	return addr, manifestEntryMap, nil
 }
 
+// GetManifestList lists the entries of the manifest at addr whose paths
+// start with prefix, paths continuing with a slash after the prefix are
+// grouped into common prefixes
+func (self *Api) GetManifestList(addr storage.Address, prefix string) (list ManifestList, err error) {
+	walker, err := self.NewManifestWalker(addr, nil)
+	if err != nil {
+		return
+	}
+
+	err = walker.Walk(func(entry *ManifestEntry) error {
+		// handle non-manifest files
+		if entry.ContentType != ManifestType {
+			// ignore the file if it doesn't have the specified prefix
+			if !strings.HasPrefix(entry.Path, prefix) {
+				return nil
+			}
+
+			// if the path after the prefix contains a slash, add a
+			// common prefix to the list, otherwise add the entry
+			suffix := strings.TrimPrefix(entry.Path, prefix)
+			if index := strings.Index(suffix, "/"); index > -1 {
+				list.CommonPrefixes = append(list.CommonPrefixes, prefix+suffix[:index+1])
+				return nil
+			}
+			if entry.Path == "" {
+				entry.Path = "/"
+			}
+			list.Entries = append(list.Entries, entry)
+			return nil
+		}
+
+		// if the manifest's path is a prefix of the specified prefix
+		// then just recurse into the manifest by returning nil and
+		// continuing the walk
+		if strings.HasPrefix(prefix, entry.Path) {
+			return nil
+		}
+
+		// if the manifest's path has the specified prefix, then if the
+		// path after the prefix contains a slash, add a common prefix
+		// to the list and skip the manifest, otherwise recurse into
+		// the manifest by returning nil and continuing the walk
+		if strings.HasPrefix(entry.Path, prefix) {
+			suffix := strings.TrimPrefix(entry.Path, prefix)
+			if index := strings.Index(suffix, "/"); index > -1 {
+				list.CommonPrefixes = append(list.CommonPrefixes, prefix+suffix[:index+1])
+				return SkipManifest
+			}
+			return nil
+		}
+
+		// the manifest neither has the prefix or needs recursing in to
+		// so just skip it
+		return SkipManifest
+	})
+
+	return list, err
+}
+
 // Look up mutable resource updates at specific periods and versions
 func (self *Api) ResourceLookup(ctx context.Context, addr storage.Address, period uint32, version uint32, maxLookup *mru.LookupParams) (string, []byte, error) {
 	var err error
@@ -682,6 +940,19 @@ func (self *Api) ResourceLookup(ctx context.Context, addr storage.Address, perio
 	return rsrc.Name(), data, nil
 }
 
+// Look up all mutable resource updates made between two periods
+func (self *Api) ResourceHistory(ctx context.Context, addr storage.Address, fromPeriod uint32, toPeriod uint32, limit int) (string, []*mru.Update, error) {
+	rsrc, err := self.resource.Load(addr)
+	if err != nil {
+		return "", nil, err
+	}
+	updates, err := self.resource.LookupHistory(ctx, rsrc.Name(), fromPeriod, toPeriod, limit)
+	if err != nil {
+		return "", nil, err
+	}
+	return rsrc.Name(), updates, nil
+}
+
 func (self *Api) ResourceCreate(ctx context.Context, name string, frequency uint64) (storage.Address, error) {
 	key, _, err := self.resource.New(ctx, name, frequency)
 	if err != nil {
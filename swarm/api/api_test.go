@@ -24,7 +24,9 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -121,6 +123,40 @@ func TestApiPut(t *testing.T) {
 	})
 }
 
+func TestApiPutErasure(t *testing.T) {
+	testApi(t, func(api *Api, toEncrypt bool) {
+		if toEncrypt {
+			return
+		}
+		content := strings.Repeat("hello", 10000)
+		exp := expResponse(content, "text/plain", 0)
+		addr, wait, err := api.PutErasure(content, exp.MimeType, false, 8)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wait()
+		resp := testGet(t, api, addr.Hex(), "")
+		checkResponse(t, resp, exp)
+
+		entry, err := api.GetEntry(addr, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.Parity != 8 {
+			t.Fatalf("expected parity 8 recorded in the manifest entry, got %+v", entry)
+		}
+
+		// 13 data chunks and 8 parity chunks under the root chunk
+		report, err := api.VerifyErasure(storage.Address(common.Hex2Bytes(entry.Hash)), false, entry.Parity)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if report.Chunks != 1+13+8 || len(report.Missing) != 0 {
+			t.Fatalf("expected 22 chunks with none missing, got %+v", report)
+		}
+	})
+}
+
 // testResolver implements the Resolver interface and either returns the given
 // hash if it is set, or returns a "name not found" error
 type testResolveValidator struct {
@@ -371,3 +407,98 @@ func TestMultiResolver(t *testing.T) {
 		})
 	}
 }
+
+func TestApiFileAttrs(t *testing.T) {
+	testApi(t, func(api *Api, toEncrypt bool) {
+		addr, err := api.NewManifest(toEncrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, mhash, err := api.AddFile(addr.Hex(), "/dir", "file", []byte("data"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, mhash, err = api.AddSymlink(mhash, "/dir", "link", "file", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+		mhash, err = api.SetFileAttrs(mhash, "/dir", "file", 0640, modTime, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.SetFileAttrs(mhash, "/dir", "missing", 0640, modTime, true); err == nil {
+			t.Fatal("expected error for missing entry")
+		}
+		if _, _, err := api.AddSymlink(mhash, "", "link", "file", true); err == nil {
+			t.Fatal("expected error for empty path")
+		}
+		if _, err := api.SetFileAttrs(mhash, "", "file", 0640, modTime, true); err == nil {
+			t.Fatal("expected error for empty path")
+		}
+
+		_, entries, err := api.BuildDirectoryTree(mhash, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, link := entries["dir/file"], entries["dir/link"]
+		if file == nil || os.FileMode(file.Mode) != 0640 || !file.ModTime.Equal(modTime) {
+			t.Fatalf("expected file with mode 0640 and mtime %v, got %+v", modTime, file)
+		}
+		if link == nil || !link.IsSymlink() || link.LinkTarget != "file" {
+			t.Fatalf("expected symlink to file, got %+v", link)
+		}
+		resp := testGet(t, api, mhash, "dir/link")
+		if resp.Content != "file" {
+			t.Fatalf("expected link target as content, got %q", resp.Content)
+		}
+	})
+}
+
+func TestGetManifestList(t *testing.T) {
+	testApi(t, func(api *Api, toEncrypt bool) {
+		addr, err := api.NewManifest(toEncrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mhash := addr.Hex()
+		for _, path := range []string{"/a", "/dir", "/dir/sub", "/dir/sub"} {
+			for _, name := range []string{"x", "y"} {
+				if _, mhash, err = api.AddFile(mhash, path, name, []byte(path+name), true); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		maddr := storage.Address(common.Hex2Bytes(mhash))
+
+		list, err := api.GetManifestList(maddr, "dir/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Entries) != 2 || list.Entries[0].Path != "dir/x" || list.Entries[1].Path != "dir/y" {
+			t.Fatalf("expected dir/x and dir/y, got %+v", list.Entries)
+		}
+		for _, p := range list.CommonPrefixes {
+			if p != "dir/sub/" {
+				t.Fatalf("expected common prefix dir/sub/, got %v", list.CommonPrefixes)
+			}
+		}
+
+		// listing parses the manifest once, later loads build new tries from
+		// the cache which are not affected by changes to earlier tries
+		if !manifestCache.Contains(maddr.Hex()) {
+			t.Fatal("expected listed manifest to be cached")
+		}
+		trie, err := loadManifest(api.fileStore, maddr, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trie.deleteEntry("a/x", nil)
+		if list, err = api.GetManifestList(maddr, "a/"); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Entries) != 2 {
+			t.Fatalf("expected cached manifest to be unchanged, got %+v", list.Entries)
+		}
+	})
+}
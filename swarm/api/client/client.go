@@ -26,15 +26,25 @@ import (
 	"io/ioutil"
 	"mime"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/textproto"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/multihash"
+	"github.com/ethereum/go-ethereum/swarm/storage"
 )
 
 var (
@@ -42,6 +52,9 @@ var (
 	DefaultClient  = NewClient(DefaultGateway)
 )
 
+// retryDelay is the time waited before a failed transfer is retried
+var retryDelay = time.Second
+
 func NewClient(gateway string) *Client {
 	return &Client{
 		Gateway: gateway,
@@ -51,6 +64,128 @@ func NewClient(gateway string) *Client {
 // Client wraps interaction with a swarm HTTP gateway.
 type Client struct {
 	Gateway string
+
+	// Progress is called with the number of content bytes sent by uploads
+	// and received by downloads if it is set, it may be called concurrently
+	// by parallel uploads and downloads
+	Progress func(n int)
+
+	// Timeout limits the duration of every request including the transfer
+	// of its body, there is no limit if it is zero
+	Timeout time.Duration
+
+	// Retries is the number of times an upload or download failing with a
+	// network error or a server error response is retried, the bytes
+	// reported to Progress by a failed attempt are reported again as
+	// negative. Raw downloads failing while their content is read continue
+	// where they failed, up to Retries times.
+	Retries int
+}
+
+// statusError is the error of a request answered with an unexpected HTTP
+// status
+type statusError struct {
+	code   int
+	status string
+}
+
+func newStatusError(res *http.Response) error {
+	return &statusError{code: res.StatusCode, status: res.Status}
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status: %s", e.status)
+}
+
+// isRetryable returns true if err is a network error or a server error
+// response, which might not occur again if the request is retried
+func isRetryable(err error) bool {
+	switch err := err.(type) {
+	case *statusError:
+		return err.code >= http.StatusInternalServerError
+	case net.Error:
+		return true
+	}
+	return err == io.ErrUnexpectedEOF
+}
+
+// httpClient returns the HTTP client used for the requests of the client
+func (c *Client) httpClient() *http.Client {
+	if c.Timeout == 0 {
+		return http.DefaultClient
+	}
+	return &http.Client{Timeout: c.Timeout}
+}
+
+// retry calls fn until it succeeds, fails with an error which is not
+// retryable or the retries of the client are used up, fn reports the bytes it
+// transfers to the progress function it is given
+func (c *Client) retry(fn func(progress func(n int)) error) error {
+	var err error
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			log.Warn("Retrying failed swarm transfer", "attempt", attempt, "retries", c.Retries, "err", err)
+			time.Sleep(retryDelay)
+		}
+		var count int64
+		err = fn(func(n int) {
+			atomic.AddInt64(&count, int64(n))
+			if c.Progress != nil {
+				c.Progress(n)
+			}
+		})
+		if err == nil {
+			return nil
+		}
+		if n := atomic.LoadInt64(&count); n > 0 && c.Progress != nil {
+			c.Progress(-int(n))
+		}
+		if !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// progressReader calls fn with the number of bytes read from the reader
+type progressReader struct {
+	io.Reader
+	fn func(n int)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.fn(n)
+	}
+	return n, err
+}
+
+// withProgress returns a reader reporting the reads from r to progress
+func withProgress(r io.Reader, progress func(n int)) io.Reader {
+	if progress == nil {
+		return r
+	}
+	return &progressReader{r, progress}
+}
+
+// uploaderWithProgress returns an uploader reporting the reads of the file
+// contents of uploader to progress, the files of uploader are not modified
+// so that it can upload them again
+func uploaderWithProgress(uploader Uploader, progress func(n int)) Uploader {
+	if progress == nil {
+		return uploader
+	}
+	return UploaderFunc(func(upload UploadFn) error {
+		return uploader.Upload(func(file *File) error {
+			f := *file
+			f.ReadCloser = struct {
+				io.Reader
+				io.Closer
+			}{withProgress(file.ReadCloser, progress), file.ReadCloser}
+			return upload(&f)
+		})
+	})
 }
 
 // UploadRaw uploads raw data to swarm and returns the resulting hash. If toEncrypt is true it
@@ -63,40 +198,136 @@ func (c *Client) UploadRaw(r io.Reader, size int64, toEncrypt bool) (string, err
 	if toEncrypt {
 		addr = "encrypt"
 	}
-	req, err := http.NewRequest("POST", c.Gateway+"/bzz-raw:/"+addr, r)
-	if err != nil {
-		return "", err
-	}
-	req.ContentLength = size
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
+	var hash string
+	upload := func(progress func(n int)) error {
+		req, err := http.NewRequest("POST", c.Gateway+"/bzz-raw:/"+addr, withProgress(r, progress))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = size
+		res, err := c.httpClient().Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return newStatusError(res)
+		}
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		hash = string(data)
+		return nil
 	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+
+	// only data which can be read again is retried
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return hash, upload(c.Progress)
 	}
-	data, err := ioutil.ReadAll(res.Body)
+	start, err := seeker.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return "", err
+		return hash, upload(c.Progress)
 	}
-	return string(data), nil
+	err = c.retry(func(progress func(n int)) error {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		return upload(progress)
+	})
+	return hash, err
 }
 
 // DownloadRaw downloads raw data from swarm and it returns a ReadCloser and a bool whether the
 // content was encrypted
 func (c *Client) DownloadRaw(hash string) (io.ReadCloser, bool, error) {
-	uri := c.Gateway + "/bzz-raw:/" + hash
-	res, err := http.DefaultClient.Get(uri)
+	var (
+		body        io.ReadCloser
+		isEncrypted bool
+	)
+	err := c.retry(func(func(n int)) error {
+		var err error
+		body, isEncrypted, err = c.downloadRaw(hash, 0, c.Progress)
+		return err
+	})
 	if err != nil {
 		return nil, false, err
 	}
-	if res.StatusCode != http.StatusOK {
+	return &retryReader{client: c, hash: hash, body: body}, isEncrypted, nil
+}
+
+// downloadRaw makes a single request for the raw data at hash from offset on,
+// reporting the reads of the returned content to progress
+func (c *Client) downloadRaw(hash string, offset int64, progress func(n int)) (io.ReadCloser, bool, error) {
+	req, err := http.NewRequest("GET", c.Gateway+"/bzz-raw:/"+hash, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	status := http.StatusOK
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		status = http.StatusPartialContent
+	}
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	if res.StatusCode != status {
 		res.Body.Close()
-		return nil, false, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+		return nil, false, newStatusError(res)
 	}
 	isEncrypted := (res.Header.Get("X-Decrypted") == "true")
-	return res.Body, isEncrypted, nil
+	body := struct {
+		io.Reader
+		io.Closer
+	}{withProgress(res.Body, progress), res.Body}
+	return body, isEncrypted, nil
+}
+
+// retryReader is the content of a raw download, if reading it fails the rest
+// of the content is requested again
+type retryReader struct {
+	client   *Client
+	hash     string
+	body     io.ReadCloser
+	offset   int64 // number of bytes read
+	attempts int   // number of times the content was requested again
+}
+
+func (r *retryReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if err == nil || err == io.EOF || !r.reopen(err) {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// reopen requests the content from the current offset on after reading it
+// failed with err, it returns false if err is not retryable or the retries of
+// the client are used up
+func (r *retryReader) reopen(err error) bool {
+	for isRetryable(err) && r.attempts < r.client.Retries {
+		r.attempts++
+		log.Warn("Retrying failed swarm download", "hash", r.hash, "offset", r.offset, "attempt", r.attempts, "retries", r.client.Retries, "err", err)
+		time.Sleep(retryDelay)
+		var body io.ReadCloser
+		if body, _, err = r.client.downloadRaw(r.hash, r.offset, r.client.Progress); err == nil {
+			r.body.Close()
+			r.body = body
+			return true
+		}
+	}
+	return false
+}
+
+func (r *retryReader) Close() error {
+	return r.body.Close()
 }
 
 // File represents a file in a swarm manifest and is used for uploading and
@@ -129,6 +360,29 @@ func Open(path string) (*File, error) {
 	}, nil
 }
 
+// openSymlink opens a local symbolic link which can then be passed to
+// client.Upload to upload the link itself rather than its target, the
+// content of the file is the link target
+func openSymlink(path string) (*File, error) {
+	stat, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		ReadCloser: ioutil.NopCloser(strings.NewReader(target)),
+		ManifestEntry: api.ManifestEntry{
+			Mode:       int64(stat.Mode()),
+			Size:       int64(len(target)),
+			ModTime:    stat.ModTime(),
+			LinkTarget: target,
+		},
+	}, nil
+}
+
 // Upload uploads a file to swarm and either adds it to an existing manifest
 // (if the manifest argument is non-empty) or creates a new manifest containing
 // the file, returning the resulting manifest hash (the file will then be
@@ -144,16 +398,20 @@ func (c *Client) Upload(file *File, manifest string, toEncrypt bool) (string, er
 // the given hash (i.e. it gets bzz:/<hash>/<path>)
 func (c *Client) Download(hash, path string) (*File, error) {
 	uri := c.Gateway + "/bzz:/" + hash + "/" + path
-	res, err := http.DefaultClient.Get(uri)
+	res, err := c.httpClient().Get(uri)
 	if err != nil {
 		return nil, err
 	}
 	if res.StatusCode != http.StatusOK {
 		res.Body.Close()
-		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+		return nil, newStatusError(res)
 	}
+	body := struct {
+		io.Reader
+		io.Closer
+	}{withProgress(res.Body, c.Progress), res.Body}
 	return &File{
-		ReadCloser: res.Body,
+		ReadCloser: body,
 		ManifestEntry: api.ManifestEntry{
 			ContentType: res.Header.Get("Content-Type"),
 			Size:        res.ContentLength,
@@ -174,7 +432,11 @@ func (c *Client) UploadDirectory(dir, defaultPath, manifest string, toEncrypt bo
 	} else if !stat.IsDir() {
 		return "", fmt.Errorf("not a directory: %s", dir)
 	}
-	return c.TarUpload(manifest, &DirectoryUploader{dir, defaultPath}, toEncrypt)
+	ignore, err := LoadIgnore(dir)
+	if err != nil {
+		return "", err
+	}
+	return c.TarUpload(manifest, &DirectoryUploader{Dir: dir, DefaultPath: defaultPath, Ignore: ignore}, toEncrypt)
 }
 
 // DownloadDirectory downloads the files contained in a swarm manifest under
@@ -193,19 +455,22 @@ func (c *Client) DownloadDirectory(hash, path, destDir string) error {
 		return err
 	}
 	req.Header.Set("Accept", "application/x-tar")
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected HTTP status: %s", res.Status)
+		return newStatusError(res)
 	}
 	tr := tar.NewReader(res.Body)
+	// symlinks are created once all regular files are written, so that no
+	// file is written through a link
+	links := make(map[string]string)
 	for {
 		hdr, err := tr.Next()
 		if err == io.EOF {
-			return nil
+			return createLinks(destDir, links)
 		} else if err != nil {
 			return err
 		}
@@ -214,7 +479,17 @@ func (c *Client) DownloadDirectory(hash, path, destDir string) error {
 			continue
 		}
 
-		dstPath := filepath.Join(destDir, filepath.Clean(strings.TrimPrefix(hdr.Name, path)))
+		dstPath, err := destPath(destDir, strings.TrimPrefix(hdr.Name, path))
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			if err := checkLinkTarget(destDir, dstPath, hdr.Linkname); err != nil {
+				return fmt.Errorf("%s: %v", hdr.Name, err)
+			}
+			links[dstPath] = hdr.Linkname
+			continue
+		}
 		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
 			return err
 		}
@@ -226,14 +501,50 @@ func (c *Client) DownloadDirectory(hash, path, destDir string) error {
 		if err != nil {
 			return err
 		}
-		n, err := io.Copy(dst, tr)
+		n, err := io.Copy(dst, withProgress(tr, c.Progress))
 		dst.Close()
 		if err != nil {
 			return err
 		} else if n != hdr.Size {
 			return fmt.Errorf("expected %s to be %d bytes but got %d", hdr.Name, hdr.Size, n)
 		}
+		// restore the attributes of existing files and those masked by umask
+		if hdr.Mode > 0 {
+			if err := os.Chmod(dstPath, mode); err != nil {
+				return err
+			}
+		}
+		if !hdr.ModTime.IsZero() {
+			if err := os.Chtimes(dstPath, hdr.ModTime, hdr.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// createLinks creates the symlinks of a downloaded directory, given as a map
+// from their paths in destDir to their targets
+func createLinks(destDir string, links map[string]string) error {
+	for dstPath, target := range links {
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return err
+		}
+		if err := os.Remove(dstPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Symlink(target, dstPath); err != nil {
+			return err
+		}
+	}
+	// the targets of links are checked lexically, links resolved through
+	// other links of the tree must still not point outside of destDir
+	for dstPath := range links {
+		if err := checkResolvedLink(destDir, dstPath); err != nil {
+			os.Remove(dstPath)
+			return err
+		}
 	}
+	return nil
 }
 
 // DownloadFile downloads a single file into the destination directory
@@ -266,20 +577,6 @@ func (c *Client) DownloadFile(hash, path, dest string) error {
 		return fmt.Errorf("got too many matches for this path")
 	}
 
-	uri := c.Gateway + "/bzz:/" + hash + "/" + path
-	req, err := http.NewRequest("GET", uri, nil)
-	if err != nil {
-		return err
-	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected HTTP status: expected 200 OK, got %d", res.StatusCode)
-	}
 	filename := ""
 	if hasDestinationFilename {
 		filename = dest
@@ -308,14 +605,26 @@ func (c *Client) DownloadFile(hash, path, dest string) error {
 		return err
 	}
 
-	dst, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer dst.Close()
+	uri := c.Gateway + "/bzz:/" + hash + "/" + path
+	return c.retry(func(progress func(n int)) error {
+		res, err := c.httpClient().Get(uri)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
 
-	_, err = io.Copy(dst, res.Body)
-	return err
+		if res.StatusCode != http.StatusOK {
+			return newStatusError(res)
+		}
+		dst, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+
+		_, err = io.Copy(dst, withProgress(res.Body, progress))
+		return err
+	})
 }
 
 // UploadManifest uploads the given manifest to swarm
@@ -341,6 +650,74 @@ func (c *Client) DownloadManifest(hash string) (*api.Manifest, bool, error) {
 	return &manifest, isEncrypted, nil
 }
 
+// RemovePath removes the entry with the given path from the swarm manifest
+// with the given hash (i.e. it deletes bzz:/<hash>/<path>), returning the
+// resulting manifest hash
+func (c *Client) RemovePath(hash, path string) (string, error) {
+	req, err := http.NewRequest("DELETE", c.Gateway+"/bzz:/"+hash+"/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", newStatusError(res)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Copy copies the entries found at srcPath in the swarm manifest with hash
+// srcHash to dstPath in the manifest with hash dstHash by reference, without
+// transferring any content, and returns the resulting manifest hash
+func (c *Client) Copy(srcHash, srcPath, dstHash, dstPath string) (string, error) {
+	query := url.Values{"copy": {srcHash + "/" + srcPath}}
+	req, err := http.NewRequest("PUT", c.Gateway+"/bzz:/"+dstHash+"/"+dstPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", newStatusError(res)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// UpdateResource publishes the manifest with the given hash as a multihash
+// update of an existing mutable resource, which is given by its name or the
+// hash of its resource manifest, so that bzz:/ requests for the resource
+// serve the content of the manifest
+func (c *Client) UpdateResource(resource, hash string) error {
+	mh, err := multihash.Encode(common.FromHex(hash), multihash.KECCAK_256)
+	if err != nil {
+		return err
+	}
+	body := strings.NewReader(hexutil.Encode(mh))
+	res, err := c.httpClient().Post(c.Gateway+"/bzz-resource:/"+resource, "application/octet-stream", body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return newStatusError(res)
+	}
+	return nil
+}
+
 // List list files in a swarm manifest which have the given prefix, grouping
 // common prefixes using "/" as a delimiter.
 //
@@ -359,19 +736,75 @@ func (c *Client) DownloadManifest(hash string) (*api.Manifest, bool, error) {
 //
 // where entries ending with "/" are common prefixes.
 func (c *Client) List(hash, prefix string) (*api.ManifestList, error) {
-	res, err := http.DefaultClient.Get(c.Gateway + "/bzz-list:/" + hash + "/" + prefix)
+	var list api.ManifestList
+	err := c.retry(func(func(n int)) error {
+		res, err := c.httpClient().Get(c.Gateway + "/bzz-list:/" + hash + "/" + prefix)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return newStatusError(res)
+		}
+		return json.NewDecoder(res.Body).Decode(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListTree returns the file entries of the manifest at hash whose paths
+// start with prefix, descending into the common prefixes of the listings
+func (c *Client) ListTree(hash, prefix string) ([]*api.ManifestEntry, error) {
+	list, err := c.List(hash, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*api.ManifestEntry
+	for _, entry := range list.Entries {
+		// the default path entry has no file name
+		if entry.Path == "" || entry.Path == "/" || entry.ContentType == api.ManifestType {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	seen := make(map[string]bool)
+	for _, p := range list.CommonPrefixes {
+		if seen[p] || p == prefix {
+			continue
+		}
+		seen[p] = true
+		sub, err := c.ListTree(hash, p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, sub...)
+	}
+	return entries, nil
+}
+
+// Verify checks that the node can retrieve every chunk of the content at
+// bzz:/<hash>/<path>, the content at hash itself is checked if path is empty.
+// Only the local store of the node is queried if local is true.
+func (c *Client) Verify(hash, path string, local bool) (*storage.VerifyReport, error) {
+	uri := c.Gateway + "/bzz-verify:/" + hash + "/" + path
+	if local {
+		uri += "?local=true"
+	}
+	res, err := c.httpClient().Get(uri)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+		return nil, newStatusError(res)
 	}
-	var list api.ManifestList
-	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+	var report storage.VerifyReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
 		return nil, err
 	}
-	return &list, nil
+	return &report, nil
 }
 
 // Uploader uploads files to swarm using a provided UploadFn
@@ -386,10 +819,12 @@ func (u UploaderFunc) Upload(upload UploadFn) error {
 }
 
 // DirectoryUploader uploads all files in a directory, optionally uploading
-// a file to the default path
+// a file to the default path, paths matched by Ignore and the ignore file
+// itself are skipped
 type DirectoryUploader struct {
 	Dir         string
 	DefaultPath string
+	Ignore      *Ignore
 }
 
 // Upload performs the upload of the directory and default path
@@ -407,18 +842,29 @@ func (d *DirectoryUploader) Upload(upload UploadFn) error {
 		if err != nil {
 			return err
 		}
+		relPath, err := filepath.Rel(d.Dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath != "." && (relPath == IgnoreFile || d.Ignore.Match(relPath, f.IsDir())) {
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if f.IsDir() {
 			return nil
 		}
-		file, err := Open(path)
-		if err != nil {
-			return err
+		open := Open
+		if f.Mode()&os.ModeSymlink != 0 {
+			open = openSymlink
 		}
-		relPath, err := filepath.Rel(d.Dir, path)
+		file, err := open(path)
 		if err != nil {
 			return err
 		}
-		file.Path = filepath.ToSlash(relPath)
+		file.Path = relPath
 		return upload(file)
 	})
 }
@@ -428,8 +874,13 @@ type FileUploader struct {
 	File *File
 }
 
-// Upload performs the upload of the file
+// Upload performs the upload of the file, the content of the file is rewound
+// if possible so that a failed upload can be retried (a content which cannot
+// be rewound fails the retry as it is shorter than the size of the file)
 func (f *FileUploader) Upload(upload UploadFn) error {
+	if seeker, ok := f.File.ReadCloser.(io.Seeker); ok {
+		seeker.Seek(0, io.SeekStart)
+	}
 	return upload(f.File)
 }
 
@@ -441,8 +892,6 @@ type UploadFn func(file *File) error
 // TarUpload uses the given Uploader to upload files to swarm as a tar stream,
 // returning the resulting manifest hash
 func (c *Client) TarUpload(hash string, uploader Uploader, toEncrypt bool) (string, error) {
-	reqR, reqW := io.Pipe()
-	defer reqR.Close()
 	addr := hash
 
 	// If there is a hash already (a manifest), then that manifest will determine if the upload has
@@ -452,59 +901,152 @@ func (c *Client) TarUpload(hash string, uploader Uploader, toEncrypt bool) (stri
 		// This is the built-in address for the encrypted upload endpoint
 		addr = "encrypt"
 	}
-	req, err := http.NewRequest("POST", c.Gateway+"/bzz:/"+addr, reqR)
+
+	// the tar stream is only generated while the request body is sent, so
+	// a retry generates it again
+	var manifest string
+	err := c.retry(func(progress func(n int)) error {
+		body := TarStream(uploaderWithProgress(uploader, progress))
+		defer body.Close()
+		req, err := http.NewRequest("POST", c.Gateway+"/bzz:/"+addr, body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-tar")
+
+		// use 'Expect: 100-continue' so we don't send the request body if
+		// the server refuses the request
+		req.Header.Set("Expect", "100-continue")
+
+		res, err := c.httpClient().Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return newStatusError(res)
+		}
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		manifest = string(data)
+		return nil
+	})
+	return manifest, err
+}
+
+// ParallelTarUpload uploads the files of the uploader like TarUpload but
+// as parallel concurrent tar streams with a share of the files each, the
+// resulting manifests are merged by reference into the manifest at hash.
+// Encrypted uploads are sent as a single tar stream.
+func (c *Client) ParallelTarUpload(hash string, uploader Uploader, toEncrypt bool, parallel int) (string, error) {
+	var count int
+	err := uploader.Upload(func(file *File) error {
+		count++
+		return file.Close()
+	})
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Content-Type", "application/x-tar")
+	if parallel > count {
+		parallel = count
+	}
+	if parallel <= 1 || toEncrypt || isEncryptedRef(hash) {
+		return c.TarUpload(hash, uploader, toEncrypt)
+	}
 
-	// use 'Expect: 100-continue' so we don't send the request body if
-	// the server refuses the request
-	req.Header.Set("Expect", "100-continue")
+	var (
+		manifests = make([]string, parallel)
+		errs      = make([]error, parallel)
+		wg        sync.WaitGroup
+	)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			base := ""
+			if i == 0 {
+				base = hash
+			}
+			manifests[i], errs[i] = c.TarUpload(base, shareUploader(uploader, i, parallel), false)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return "", err
+		}
+	}
+
+	manifest := manifests[0]
+	for _, share := range manifests[1:] {
+		err := c.retry(func(func(n int)) error {
+			merged, err := c.Copy(share, "", manifest, "")
+			if err == nil {
+				manifest = merged
+			}
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("could not merge manifest %s: %v", share, err)
+		}
+	}
+	return manifest, nil
+}
+
+// shareUploader returns an uploader of the i-th of every n files of uploader
+func shareUploader(uploader Uploader, i, n int) Uploader {
+	return UploaderFunc(func(upload UploadFn) error {
+		k := 0
+		return uploader.Upload(func(file *File) error {
+			k++
+			if (k-1)%n != i {
+				return file.Close()
+			}
+			return upload(file)
+		})
+	})
+}
 
-	tw := tar.NewWriter(reqW)
+// TarStream returns a tar stream of the files of the uploader in the format
+// of tar uploads, the files are added to the stream while it is read
+func TarStream(uploader Uploader) io.ReadCloser {
+	r, w := io.Pipe()
+	tw := tar.NewWriter(w)
 
 	// define an UploadFn which adds files to the tar stream
 	uploadFn := func(file *File) error {
 		hdr := &tar.Header{
 			Name:    file.Path,
-			Mode:    file.Mode,
+			Mode:    int64(os.FileMode(file.Mode).Perm()),
 			Size:    file.Size,
 			ModTime: file.ModTime,
 			Xattrs: map[string]string{
 				"user.swarm.content-type": file.ContentType,
 			},
 		}
+		if file.IsSymlink() {
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = file.LinkTarget
+			hdr.Size = 0
+			return tw.WriteHeader(hdr)
+		}
 		if err := tw.WriteHeader(hdr); err != nil {
 			return err
 		}
-		_, err = io.Copy(tw, file)
+		_, err := io.Copy(tw, file)
 		return err
 	}
 
-	// run the upload in a goroutine so we can send the request headers and
-	// wait for a '100 Continue' response before sending the tar stream
 	go func() {
 		err := uploader.Upload(uploadFn)
 		if err == nil {
 			err = tw.Close()
 		}
-		reqW.CloseWithError(err)
+		w.CloseWithError(err)
 	}()
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
-	}
-	data, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return "", err
-	}
-	return string(data), nil
+	return r
 }
 
 // MultipartUpload uses the given Uploader to upload files to swarm as a
@@ -541,20 +1083,20 @@ func (c *Client) MultipartUpload(hash string, uploader Uploader) (string, error)
 	// run the upload in a goroutine so we can send the request headers and
 	// wait for a '100 Continue' response before sending the multipart form
 	go func() {
-		err := uploader.Upload(uploadFn)
+		err := uploaderWithProgress(uploader, c.Progress).Upload(uploadFn)
 		if err == nil {
 			err = mw.Close()
 		}
 		reqW.CloseWithError(err)
 	}()
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+		return "", newStatusError(res)
 	}
 	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
@@ -17,16 +17,30 @@
 package client
 
 import (
+	"archive/tar"
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/swarm/api"
 	swarmhttp "github.com/ethereum/go-ethereum/swarm/api/http"
+	"github.com/ethereum/go-ethereum/swarm/multihash"
+	"github.com/ethereum/go-ethereum/swarm/storage"
 	"github.com/ethereum/go-ethereum/swarm/testutil"
 )
 
@@ -145,6 +159,20 @@ func testClientUploadDownloadFiles(toEncrypt bool, t *testing.T) {
 	// check both files have the other data
 	checkDownload(newHash, "", otherData)
 	checkDownload(newHash, "some/other/path", otherData)
+
+	// remove the other file from the manifest
+	newHash, err := client.RemovePath(newHash, "some/other/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(newHash, "", otherData)
+	list, err := client.List(newHash, "some/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) != 0 || len(list.CommonPrefixes) != 0 {
+		t.Fatalf("expected removed file to not be listed, got %v %v", list.Entries, list.CommonPrefixes)
+	}
 }
 
 var testDirFiles = []string{
@@ -238,6 +266,138 @@ func TestClientUploadDownloadDirectory(t *testing.T) {
 	}
 }
 
+// TestClientCopy tests copying files and directories between swarm
+// manifests by reference
+func TestClientCopy(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	client := NewClient(srv.URL)
+	src, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatalf("error uploading directory: %s", err)
+	}
+	dst, err := client.UploadManifest(&api.Manifest{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err = client.Copy(src, "file1.txt", dst, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err = client.Copy(src, "dir2", dst, "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := client.ListTree(dst, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"file1.txt":           "file1.txt",
+		"copy/file5.txt":      "dir2/file5.txt",
+		"copy/dir3/file6.txt": "dir2/dir3/file6.txt",
+		"copy/dir4/file7.txt": "dir2/dir4/file7.txt",
+		"copy/dir4/file8.txt": "dir2/dir4/file8.txt",
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(entries))
+	}
+	for _, entry := range entries {
+		content, ok := expected[entry.Path]
+		if !ok {
+			t.Fatalf("unexpected entry %s", entry.Path)
+		}
+		file, err := client.Download(dst, entry.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Fatalf("expected %s to contain %q, got %q", entry.Path, content, data)
+		}
+	}
+
+	if _, err := client.Copy(src, "missing", dst, ""); err == nil {
+		t.Fatal("expected error copying a missing path")
+	}
+}
+
+// TestClientUpdateResource tests that a manifest published as an update of a
+// mutable resource is served for the resource manifest
+func TestClientUpdateResource(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	upload := func(data string) string {
+		file := &File{
+			ReadCloser: ioutil.NopCloser(strings.NewReader(data)),
+			ManifestEntry: api.ManifestEntry{
+				ContentType: "text/plain",
+				Size:        int64(len(data)),
+			},
+		}
+		hash, err := client.Upload(file, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	first, second := upload("first"), upload("second")
+
+	// create the resource with the first manifest
+	mh, err := multihash.Encode(common.FromHex(first), multihash.KECCAK_256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Post(srv.URL+"/bzz-resource:/foo.eth/13", "application/octet-stream", strings.NewReader(hexutil.Encode(mh)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected HTTP status: %s", res.Status)
+	}
+	var resource storage.Address
+	if err := json.NewDecoder(res.Body).Decode(&resource); err != nil {
+		t.Fatal(err)
+	}
+
+	checkContent := func(expected string) {
+		file, err := client.Download(resource.Hex(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("expected resource content %q, got %q", expected, data)
+		}
+	}
+	checkContent("first")
+
+	if err := client.UpdateResource(resource.Hex(), second); err != nil {
+		t.Fatal(err)
+	}
+	checkContent("second")
+
+	if err := client.UpdateResource("0000000000000000000000000000000000000000000000000000000000000000", second); err == nil {
+		t.Fatal("expected an error updating an unknown resource")
+	}
+}
+
 // TestClientFileList tests listing files in a swarm manifest
 func TestClientFileList(t *testing.T) {
 	testClientFileList(false, t)
@@ -354,3 +514,542 @@ func TestClientMultipartUpload(t *testing.T) {
 		checkDownloadFile(file)
 	}
 }
+
+// TestClientUploadDownloadAttributes tests that file modes, modification
+// times and symbolic links round-trip through a directory upload
+func TestClientUploadDownloadAttributes(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "swarm-client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "script.sh")
+	if err := ioutil.WriteFile(file, []byte("#!/bin/sh"), 0751); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0751); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("script.sh", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(srv.URL)
+	hash, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatalf("error uploading directory: %s", err)
+	}
+
+	// check the attributes are listed
+	list, err := client.List(hash, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %v", len(list.Entries))
+	}
+	for _, entry := range list.Entries {
+		switch entry.Path {
+		case "link":
+			if !entry.IsSymlink() || entry.LinkTarget != "script.sh" {
+				t.Fatalf("expected symlink to script.sh, got %+v", entry)
+			}
+		case "script.sh":
+			if os.FileMode(entry.Mode) != 0751 || !entry.ModTime.Equal(modTime) {
+				t.Fatalf("expected mode 0751 and mtime %v, got %+v", modTime, entry)
+			}
+		default:
+			t.Fatalf("unexpected entry %+v", entry)
+		}
+	}
+
+	// check the attributes are restored
+	tmp, err := ioutil.TempDir("", "swarm-client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := client.DownloadDirectory(hash, "", tmp); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(filepath.Join(tmp, "script.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode() != 0751 || !stat.ModTime().Equal(modTime) {
+		t.Fatalf("expected mode 0751 and mtime %v, got %v and %v", modTime, stat.Mode(), stat.ModTime())
+	}
+	target, err := os.Readlink(filepath.Join(tmp, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "script.sh" {
+		t.Fatalf("expected link target script.sh, got %q", target)
+	}
+}
+
+// TestClientDownloadTree tests downloading a directory tree file by file,
+// resuming a partial download and rejecting corrupted content
+func TestClientDownloadTree(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	client := NewClient(srv.URL)
+	hash, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatalf("error uploading directory: %s", err)
+	}
+
+	tmp, err := ioutil.TempDir("", "swarm-client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	checkFiles := func() {
+		for _, file := range testDirFiles {
+			data, err := ioutil.ReadFile(filepath.Join(tmp, file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, []byte(file)) {
+				t.Fatalf("expected data to be %q, got %q", file, data)
+			}
+		}
+	}
+
+	stats, err := client.DownloadTree(hash, "", tmp, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Downloaded != len(testDirFiles) || stats.Skipped != 0 {
+		t.Fatalf("expected %d files downloaded, got %+v", len(testDirFiles), stats)
+	}
+	checkFiles()
+
+	// a resumed download only fetches missing and modified files
+	if err := os.Remove(filepath.Join(tmp, testDirFiles[0])); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, testDirFiles[1]), []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = client.DownloadTree(hash, "", tmp, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Downloaded != 2 || stats.Skipped != len(testDirFiles)-2 {
+		t.Fatalf("expected 2 files downloaded, got %+v", stats)
+	}
+	checkFiles()
+
+	// content which does not match the manifest entry is rejected
+	corrupt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupt"))
+	}))
+	defer corrupt.Close()
+	entry := &api.ManifestEntry{Path: "corrupt", Hash: strings.Repeat("00", 32)}
+	if _, _, err := NewClient(corrupt.URL).downloadEntry(entry, filepath.Join(tmp, "corrupt")); err == nil {
+		t.Fatal("expected error downloading content with a mismatching hash")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "corrupt")); !os.IsNotExist(err) {
+		t.Fatalf("expected no file for rejected content, got %v", err)
+	}
+}
+
+// TestClientProgress tests that the content bytes of uploads and downloads
+// are reported to the progress function of the client
+func TestClientProgress(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	var transferred int64
+	client := NewClient(srv.URL)
+	client.Progress = func(n int) {
+		atomic.AddInt64(&transferred, int64(n))
+	}
+
+	data := []byte("some data to upload")
+	hash, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.SwapInt64(&transferred, 0); n != int64(len(data)) {
+		t.Fatalf("expected %d uploaded bytes, got %d", len(data), n)
+	}
+	res, _, err := client.DownloadRaw(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(res); err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	if n := atomic.SwapInt64(&transferred, 0); n != int64(len(data)) {
+		t.Fatalf("expected %d downloaded bytes, got %d", len(data), n)
+	}
+
+	// only the file contents of tar uploads are reported
+	file := &File{
+		ReadCloser:    ioutil.NopCloser(bytes.NewReader(data)),
+		ManifestEntry: api.ManifestEntry{Path: "file.txt", Size: int64(len(data))},
+	}
+	if _, err := client.Upload(file, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.SwapInt64(&transferred, 0); n != int64(len(data)) {
+		t.Fatalf("expected %d uploaded bytes, got %d", len(data), n)
+	}
+}
+
+// TestClientRetries tests that failed transfers are retried and that the
+// bytes of failed attempts are not counted as progress
+func TestClientRetries(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = 10 * time.Millisecond
+
+	// the proxy fails every other request after reading its body
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	var requests int64
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1)%2 == 1 {
+			ioutil.ReadAll(r.Body)
+			http.Error(w, "flaky", http.StatusInternalServerError)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	var transferred int64
+	client := NewClient(flaky.URL)
+	client.Progress = func(n int) {
+		atomic.AddInt64(&transferred, int64(n))
+	}
+
+	data := []byte("some data to upload")
+	if _, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)), false); err == nil {
+		t.Fatal("expected an error without retries")
+	}
+	atomic.StoreInt64(&requests, 0)
+	atomic.StoreInt64(&transferred, 0)
+
+	client.Retries = 1
+	hash, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.SwapInt64(&transferred, 0); n != int64(len(data)) {
+		t.Fatalf("expected %d uploaded bytes, got %d", len(data), n)
+	}
+	atomic.StoreInt64(&requests, 0)
+	res, _, err := client.DownloadRaw(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := ioutil.ReadAll(res)
+	res.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatalf("expected downloaded data %q, got %q", data, downloaded)
+	}
+
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+	atomic.StoreInt64(&requests, 0)
+	atomic.StoreInt64(&transferred, 0)
+	manifest, err := client.UploadDirectory(dir, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	for _, file := range testDirFiles {
+		size += int64(len(file))
+	}
+	if n := atomic.SwapInt64(&transferred, 0); n != size {
+		t.Fatalf("expected %d uploaded bytes, got %d", size, n)
+	}
+
+	tmp, err := ioutil.TempDir("", "swarm-client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	atomic.StoreInt64(&requests, 1)
+	if _, err := client.DownloadTree(manifest, "", tmp, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range testDirFiles {
+		data, err := ioutil.ReadFile(filepath.Join(tmp, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != file {
+			t.Fatalf("expected %s to contain %q, got %q", file, file, data)
+		}
+	}
+}
+
+// TestClientRetriesClientError tests that requests answered with a client
+// error status are not retried
+func TestClientRetriesClientError(t *testing.T) {
+	var requests int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	client.Retries = 2
+	if _, _, err := client.DownloadRaw(strings.Repeat("00", 32)); err == nil {
+		t.Fatal("expected an error for missing content")
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+}
+
+// TestClientDownloadRawResume tests that a raw download failing while its
+// content is read continues where it failed
+func TestClientDownloadRawResume(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = 10 * time.Millisecond
+
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	var requests int64
+	// the connection of the first request is closed after half of the content
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	var transferred int64
+	client := NewClient(srv.URL)
+	client.Retries = 1
+	client.Progress = func(n int) {
+		atomic.AddInt64(&transferred, int64(n))
+	}
+	res, _, err := client.DownloadRaw(strings.Repeat("00", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := ioutil.ReadAll(res)
+	res.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatalf("expected %d downloaded bytes matching the content, got %d", len(data), len(downloaded))
+	}
+	if n := atomic.LoadInt64(&requests); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+	if n := atomic.LoadInt64(&transferred); n != int64(len(data)) {
+		t.Fatalf("expected %d downloaded bytes, got %d", len(data), n)
+	}
+}
+
+// TestClientUploadDelta tests that UploadDelta gives the same hash as a raw
+// upload and only sends the chunks which are not stored yet
+func TestClientUploadDelta(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	data := make([]byte, 3*storage.DefaultChunkSize+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	hash, stats, err := client.UploadDelta(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// four data chunks and their parent
+	if stats.Chunks != 5 || stats.Uploaded != 5 {
+		t.Fatalf("expected 5 chunks to be uploaded, got %d of %d", stats.Uploaded, stats.Chunks)
+	}
+	raw, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != raw {
+		t.Fatalf("expected hash %s, got %s", raw, hash)
+	}
+
+	// changing a data chunk changes it and the root chunk
+	data[storage.DefaultChunkSize] ^= 0xff
+	hash, stats, err = client.UploadDelta(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 2 {
+		t.Fatalf("expected 2 chunks to be uploaded, got %d", stats.Uploaded)
+	}
+	res, _, err := client.DownloadRaw(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := ioutil.ReadAll(res)
+	res.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded data mismatch")
+	}
+}
+
+// TestClientTimeout tests that requests fail once the timeout of the client
+// is exceeded
+func TestClientTimeout(t *testing.T) {
+	quit := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-quit:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	defer close(quit)
+
+	client := NewClient(slow.URL)
+	client.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	if _, _, err := client.DownloadRaw(strings.Repeat("00", 32)); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the request to time out, it took %v", elapsed)
+	}
+}
+
+// TestClientParallelTarUpload tests that a directory uploaded as parallel
+// tar streams gives the same manifest as a single tar stream
+func TestClientParallelTarUpload(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	var transferred int64
+	client := NewClient(srv.URL)
+	client.Progress = func(n int) {
+		atomic.AddInt64(&transferred, int64(n))
+	}
+	uploader := &DirectoryUploader{Dir: dir, DefaultPath: filepath.Join(dir, "file1.txt")}
+	expected, err := client.TarUpload("", uploader, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt64(&transferred, 0)
+	manifest, err := client.ParallelTarUpload("", uploader, false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest != expected {
+		t.Fatalf("expected manifest %s, got %s", expected, manifest)
+	}
+	size := int64(len("file1.txt"))
+	for _, file := range testDirFiles {
+		size += int64(len(file))
+	}
+	if n := atomic.LoadInt64(&transferred); n != size {
+		t.Fatalf("expected %d uploaded bytes, got %d", size, n)
+	}
+}
+
+// TestClientDownloadTreeContainment tests that entries and link targets
+// outside of the destination directory are rejected
+func TestClientDownloadTreeContainment(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "swarm-client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	destDir := filepath.Join(tmp, "dest")
+	if err := os.Mkdir(destDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := strings.Repeat("00", 32)
+	for _, entry := range []*api.ManifestEntry{
+		{Path: "../escape", Hash: hash},
+		{Path: "dir/../../escape", Hash: hash},
+		{Path: "abs", Mode: int64(os.ModeSymlink | 0777), LinkTarget: filepath.Join(tmp, "escape")},
+		{Path: "rel", Mode: int64(os.ModeSymlink | 0777), LinkTarget: "../escape"},
+		{Path: "dir/rel", Mode: int64(os.ModeSymlink | 0777), LinkTarget: "../../escape"},
+	} {
+		if _, err := NewClient("http://127.0.0.1:0").DownloadEntries([]*api.ManifestEntry{entry}, "", destDir, 1); err == nil {
+			t.Fatalf("expected error downloading %s", entry.Path)
+		}
+	}
+
+	// links resolved through other links of the tree are rejected as well
+	entries := []*api.ManifestEntry{
+		{Path: "up", Mode: int64(os.ModeSymlink | 0777), LinkTarget: "."},
+		{Path: "dir/link", Mode: int64(os.ModeSymlink | 0777), LinkTarget: "../up/.."},
+	}
+	if _, err := NewClient("http://127.0.0.1:0").DownloadEntries(entries, "", destDir, 1); err == nil {
+		t.Fatal("expected error downloading a link resolving outside of the destination directory")
+	}
+	if _, err := os.Lstat(filepath.Join(destDir, "dir", "link")); !os.IsNotExist(err) {
+		t.Fatalf("expected escaping link to be removed, got %v", err)
+	}
+
+	// the same checks apply to the tar stream of DownloadDirectory
+	for _, hdr := range []*tar.Header{
+		{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "abs", Typeflag: tar.TypeSymlink, Linkname: filepath.Join(tmp, "escape")},
+		{Name: "dir/rel", Typeflag: tar.TypeSymlink, Linkname: "../../escape"},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tw := tar.NewWriter(w)
+			tw.WriteHeader(hdr)
+			tw.Close()
+		}))
+		err := NewClient(srv.URL).DownloadDirectory(hash, "", destDir)
+		srv.Close()
+		if err == nil {
+			t.Fatalf("expected error downloading %s", hdr.Name)
+		}
+	}
+
+	files, err := ioutil.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the destination directory in %s, got %d files", tmp, len(files))
+	}
+}
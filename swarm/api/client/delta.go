@@ -0,0 +1,162 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// DeltaStats reports the outcome of an UploadDelta call
+type DeltaStats struct {
+	Chunks   int   // number of chunks of the content
+	Uploaded int   // number of chunks sent to the gateway
+	Bytes    int64 // number of chunk bytes sent to the gateway
+}
+
+// UploadDelta uploads raw data like UploadRaw, except that the data is split
+// into chunks locally and only the chunks which are not already stored by the
+// gateway are sent, so that content which differs from previously uploaded
+// content in a few places is uploaded by sending the changed chunks only.
+// Encryption is not supported, since the chunks of encrypted content differ
+// on every upload.
+func (c *Client) UploadDelta(r io.Reader, size int64) (string, *DeltaStats, error) {
+	if size < 0 {
+		return "", nil, errors.New("data size must not be negative")
+	}
+	store := &deltaStore{client: c}
+	fileStore := storage.NewFileStore(store, storage.NewFileStoreParams())
+	addr, wait, err := fileStore.Store(r, size, false)
+	if err != nil {
+		return "", nil, err
+	}
+	wait()
+	if err := store.error(); err != nil {
+		return "", nil, err
+	}
+	stats := &DeltaStats{
+		Chunks:   int(atomic.LoadInt64(&store.chunks)),
+		Uploaded: int(atomic.LoadInt64(&store.uploaded)),
+		Bytes:    atomic.LoadInt64(&store.bytes),
+	}
+	return addr.Hex(), stats, nil
+}
+
+// deltaStore is the chunk store of the splitter of UploadDelta, it sends the
+// chunks put to it to the gateway unless the gateway already stores them
+type deltaStore struct {
+	storage.FakeChunkStore
+	client *Client
+
+	chunks   int64
+	uploaded int64
+	bytes    int64
+
+	mu  sync.Mutex
+	err error // first failure to check or send a chunk
+}
+
+func (s *deltaStore) Put(chunk *storage.Chunk) {
+	defer s.FakeChunkStore.Put(chunk)
+
+	atomic.AddInt64(&s.chunks, 1)
+	if s.error() != nil {
+		return
+	}
+	var sent bool
+	err := s.client.retry(func(func(n int)) error {
+		has, err := s.client.hasChunk(chunk.Addr)
+		if err != nil || has {
+			return err
+		}
+		if err := s.client.putChunk(chunk); err != nil {
+			return err
+		}
+		sent = true
+		return nil
+	})
+	if sent {
+		atomic.AddInt64(&s.uploaded, 1)
+		atomic.AddInt64(&s.bytes, int64(len(chunk.SData)))
+	}
+	if err != nil {
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = fmt.Errorf("could not upload chunk %s: %v", chunk.Addr, err)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *deltaStore) error() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// hasChunk reports whether the gateway stores the chunk at addr
+func (c *Client) hasChunk(addr storage.Address) (bool, error) {
+	req, err := http.NewRequest("HEAD", c.Gateway+"/bzz-chunk:/"+addr.Hex(), nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, newStatusError(res)
+}
+
+// putChunk sends a chunk to the gateway, checking the address it was stored
+// at
+func (c *Client) putChunk(chunk *storage.Chunk) error {
+	req, err := http.NewRequest("POST", c.Gateway+"/bzz-chunk:/", bytes.NewReader(chunk.SData))
+	if err != nil {
+		return err
+	}
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return newStatusError(res)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if addr := string(data); addr != chunk.Addr.Hex() {
+		return fmt.Errorf("chunk stored at %s, expected %s", addr, chunk.Addr.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// DefaultDownloadParallelism is the default number of files fetched
+// concurrently by DownloadTree
+const DefaultDownloadParallelism = 8
+
+// DownloadStats reports the outcome of a DownloadTree call
+type DownloadStats struct {
+	Downloaded int   // number of files fetched
+	Skipped    int   // number of files already present with the expected content
+	Bytes      int64 // number of bytes fetched
+}
+
+// DownloadTree downloads the files of the manifest at hash whose paths
+// start with path to destDir, fetching parallel files concurrently
+//
+// The content of every fetched file is verified against the hash of its
+// manifest entry before it replaces the destination file, and destination
+// files which already have the content of their entry are not fetched
+// again, so an interrupted download resumes with the missing files. The
+// content of encrypted entries cannot be verified locally.
+func (c *Client) DownloadTree(hash, path, destDir string, parallel int) (*DownloadStats, error) {
+	entries, err := c.ListTree(hash, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not list manifest: %v", err)
+	}
+	return c.DownloadEntries(entries, path, destDir, parallel)
+}
+
+// DownloadEntries downloads the file entries listed by ListTree with the
+// given prefix like DownloadTree, the content of files which are already
+// present is reported to Progress as if it was fetched
+func (c *Client) DownloadEntries(entries []*api.ManifestEntry, path, destDir string, parallel int) (*DownloadStats, error) {
+	if parallel <= 0 {
+		parallel = DefaultDownloadParallelism
+	}
+
+	var (
+		stats DownloadStats
+		mu    sync.Mutex
+		errs  []string
+		wg    sync.WaitGroup
+		jobs  = make(chan *api.ManifestEntry)
+	)
+	download := func(entry *api.ManifestEntry) {
+		var (
+			n       int64
+			fetched bool
+		)
+		dstPath, err := destPath(destDir, strings.TrimPrefix(entry.Path, path))
+		if err == nil && entry.IsSymlink() {
+			err = checkLinkTarget(destDir, dstPath, entry.LinkTarget)
+		}
+		if err == nil {
+			n, fetched, err = c.downloadEntry(entry, dstPath)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.Path, err))
+		case fetched:
+			stats.Downloaded++
+			stats.Bytes += n
+		default:
+			stats.Skipped++
+			if c.Progress != nil && entry.Size > 0 {
+				c.Progress(int(entry.Size))
+			}
+		}
+	}
+
+	// symlinks are created once all regular files are written, so that no
+	// file is written through a link
+	var links []*api.ManifestEntry
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range jobs {
+				download(entry)
+			}
+		}()
+	}
+	for _, entry := range entries {
+		if entry.IsSymlink() {
+			links = append(links, entry)
+			continue
+		}
+		jobs <- entry
+	}
+	close(jobs)
+	wg.Wait()
+	for _, entry := range links {
+		download(entry)
+	}
+	// the targets of links are checked lexically, links resolved through
+	// other links of the tree must still not point outside of destDir
+	for _, entry := range links {
+		dstPath, err := destPath(destDir, strings.TrimPrefix(entry.Path, path))
+		if err != nil {
+			continue
+		}
+		if err := checkResolvedLink(destDir, dstPath); err != nil {
+			os.Remove(dstPath)
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.Path, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return &stats, fmt.Errorf("failed to download %d file(s): %s", len(errs), strings.Join(errs, "; "))
+	}
+	return &stats, nil
+}
+
+// destPath returns the path of the file name in destDir, it returns an error
+// if the path is outside of destDir
+func destPath(destDir, name string) (string, error) {
+	dstPath := filepath.Join(destDir, filepath.Clean(name))
+	rel, err := filepath.Rel(destDir, dstPath)
+	if err != nil {
+		return "", err
+	}
+	if isOutside(rel) {
+		return "", fmt.Errorf("path %q is outside of the destination directory", name)
+	}
+	return dstPath, nil
+}
+
+// checkLinkTarget returns an error if target is absolute or if a link at
+// dstPath to target would point outside of destDir
+func checkLinkTarget(destDir, dstPath, target string) error {
+	if target == "" || filepath.IsAbs(target) {
+		return fmt.Errorf("invalid link target %q", target)
+	}
+	rel, err := filepath.Rel(destDir, filepath.Join(filepath.Dir(dstPath), target))
+	if err != nil {
+		return err
+	}
+	if isOutside(rel) {
+		return fmt.Errorf("link target %q is outside of the destination directory", target)
+	}
+	return nil
+}
+
+// checkResolvedLink returns an error if the link at dstPath resolves to a
+// path outside of destDir, dangling links are accepted
+func checkResolvedLink(destDir, dstPath string) error {
+	resolved, err := filepath.EvalSymlinks(dstPath)
+	if err != nil {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return err
+	}
+	if isOutside(rel) {
+		return fmt.Errorf("link resolves to %q outside of the destination directory", resolved)
+	}
+	return nil
+}
+
+// isOutside returns true if the relative path rel leaves its base directory
+func isOutside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// downloadEntry writes the content of entry to dstPath unless it is already
+// there, it returns the number of bytes fetched and whether the entry was
+// fetched
+func (c *Client) downloadEntry(entry *api.ManifestEntry, dstPath string) (int64, bool, error) {
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return 0, false, err
+	}
+	if entry.IsSymlink() {
+		if target, err := os.Readlink(dstPath); err == nil && target == entry.LinkTarget {
+			return 0, false, nil
+		}
+		if err := os.Remove(dstPath); err != nil && !os.IsNotExist(err) {
+			return 0, false, err
+		}
+		return 0, true, os.Symlink(entry.LinkTarget, dstPath)
+	}
+
+	verify := !isEncryptedRef(entry.Hash)
+	if verify {
+		if h, err := contentHash(dstPath); err == nil && h == entry.Hash {
+			return 0, false, setFileAttrs(dstPath, entry)
+		}
+	}
+
+	// fetch to a temporary file which replaces the destination once complete
+	tmpPath := dstPath + ".part"
+	var n int64
+	err := c.retry(func(progress func(n int)) (err error) {
+		n, err = c.fetchEntry(entry, tmpPath, verify, progress)
+		return err
+	})
+	if err != nil {
+		return n, false, err
+	}
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		return n, false, err
+	}
+	return n, true, setFileAttrs(dstPath, entry)
+}
+
+// fetchEntry writes the content of entry to tmpPath, verifying it against
+// the hash of the entry if verify is set, the file is removed on failure
+func (c *Client) fetchEntry(entry *api.ManifestEntry, tmpPath string, verify bool, progress func(n int)) (int64, error) {
+	reader, _, err := c.downloadRaw(entry.Hash, 0, progress)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(tmp, reader)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return n, err
+	}
+	if verify {
+		h, err := contentHash(tmpPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			return n, err
+		}
+		if h != entry.Hash {
+			os.Remove(tmpPath)
+			return n, fmt.Errorf("content hash %s does not match manifest entry hash %s", h, entry.Hash)
+		}
+	}
+	return n, nil
+}
+
+// setFileAttrs applies the mode and modification time of the entry
+func setFileAttrs(path string, entry *api.ManifestEntry) error {
+	mode := os.FileMode(0644)
+	if entry.Mode > 0 {
+		mode = os.FileMode(entry.Mode).Perm()
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	if !entry.ModTime.IsZero() {
+		return os.Chtimes(path, entry.ModTime, entry.ModTime)
+	}
+	return nil
+}
+
+// isEncryptedRef returns true if the hex encoded reference includes a
+// decryption key
+func isEncryptedRef(ref string) bool {
+	return len(ref) > 2*storage.KeyLength
+}
+
+// contentHash returns the hex encoded swarm hash of the content of the file
+func contentHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	fileStore := storage.NewFileStore(&storage.FakeChunkStore{}, storage.NewFileStoreParams())
+	addr, wait, err := fileStore.Store(f, stat.Size(), false)
+	if err != nil {
+		return "", err
+	}
+	wait()
+	return addr.Hex(), nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile is the name of the file listing the paths of a directory
+// which are not uploaded, using gitignore patterns
+const IgnoreFile = ".swarmignore"
+
+// ignorePattern is a parsed line of an ignore file
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool // the pattern re-includes paths excluded by earlier patterns
+	dirOnly bool // the pattern only matches directories
+}
+
+// Ignore matches paths against the patterns of an ignore file
+//
+// The patterns follow gitignore: blank lines and lines starting with # are
+// skipped, a leading ! re-includes matching paths, a trailing / only
+// matches directories, patterns containing a / are relative to the
+// directory of the ignore file while others match names at any depth, *
+// and ? match within a path segment and ** matches across segments. The
+// last matching pattern decides whether a path is ignored.
+type Ignore struct {
+	patterns []ignorePattern
+}
+
+// ParseIgnore parses the patterns read from r
+func ParseIgnore(r io.Reader) (*Ignore, error) {
+	ignore := &Ignore{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var p ignorePattern
+		if strings.HasPrefix(text, "!") {
+			p.negate = true
+			text = text[1:]
+		} else if strings.HasPrefix(text, `\`) {
+			text = text[1:]
+		}
+		if strings.HasSuffix(text, "/") {
+			p.dirOnly = true
+			text = strings.TrimRight(text, "/")
+		}
+		if text == "" {
+			return nil, fmt.Errorf("invalid ignore pattern on line %d", line)
+		}
+		re, err := ignoreRegexp(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern on line %d: %v", line, err)
+		}
+		p.re = re
+		ignore.patterns = append(ignore.patterns, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ignore, nil
+}
+
+// LoadIgnore loads the ignore file of dir, it returns nil if dir has no
+// ignore file
+func LoadIgnore(dir string) (*Ignore, error) {
+	f, err := os.Open(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseIgnore(f)
+}
+
+// ignoreRegexp converts a gitignore pattern to a regular expression
+// matching slash separated relative paths
+func ignoreRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr bytes.Buffer
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// **/ matches zero or more directories
+					i++
+					expr.WriteString("(?:.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in %q", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// Match returns true if the slash separated path relative to the directory
+// of the ignore file is ignored
+func (ig *Ignore) Match(path string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+	ignored := false
+	for _, p := range ig.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(path) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestIgnore(t *testing.T) {
+	ignore, err := ParseIgnore(strings.NewReader(`
+# comment
+*.log
+!keep.log
+build/
+/root.txt
+docs/**/*.tmp
+a?c
+\#hash
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"debug.log", false, true},
+		{"dir/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false},
+		{"root.txt", false, true},
+		{"dir/root.txt", false, false},
+		{"docs/a.tmp", false, true},
+		{"docs/x/y/a.tmp", false, true},
+		{"a.tmp", false, false},
+		{"abc", false, true},
+		{"abbc", false, false},
+		{"#hash", false, true},
+		{"index.html", false, false},
+	} {
+		if got := ignore.Match(x.path, x.isDir); got != x.ignored {
+			t.Errorf("%s (dir %v): expected ignored %v, got %v", x.path, x.isDir, x.ignored, got)
+		}
+	}
+
+	if _, err := ParseIgnore(strings.NewReader("[abc")); err == nil {
+		t.Fatal("expected error for unterminated character class")
+	}
+	var nilIgnore *Ignore
+	if nilIgnore.Match("a", false) {
+		t.Fatal("expected nil ignore to match nothing")
+	}
+}
+
+func TestDirectoryUploaderIgnore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarm-ignore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		IgnoreFile:            "node_modules/\n*.log\n",
+		"index.html":          "index",
+		"error.log":           "log",
+		"js/app.js":           "app",
+		"node_modules/x/y.js": "dep",
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ignore, err := LoadIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader := &DirectoryUploader{Dir: dir, Ignore: ignore}
+	var paths []string
+	err = uploader.Upload(func(file *File) error {
+		paths = append(paths, file.Path)
+		return file.Close()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	if expected := []string{"index.html", "js/app.js"}; !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	// directories without an ignore file upload everything
+	if ignore, err := LoadIgnore(filepath.Join(dir, "js")); ignore != nil || err != nil {
+		t.Fatalf("expected no ignore file, got %v (%v)", ignore, err)
+	}
+}
@@ -47,6 +47,9 @@ type Config struct {
 	*storage.FileStoreParams
 	*storage.LocalStoreParams
 	*network.HiveParams
+	*network.KadParams
+	*network.PartitionParams
+	*network.TopicDiscoveryParams
 	Swap *swap.LocalProfile
 	Pss  *pss.PssParams
 	//*network.SyncParams
@@ -63,11 +66,18 @@ type Config struct {
 	SwapEnabled       bool
 	SyncEnabled       bool
 	DeliverySkipCheck bool
+	Compression       bool
 	SyncUpdateDelay   time.Duration
 	SwapApi           string
+	PriceOracle       string // "", "file:<path>" or "contract:<address>", see swap.NewPriceOracle
 	Cors              string
 	BzzAccount        string
 	BootNodes         string
+	BootnodesURL      string        // http(s):// or dns:// location of a signed bootnode list
+	BootnodesPubKey   string        // hex encoded public key the bootnode list is signed with
+	BootnodesRefresh  time.Duration // interval between bootnode list fetches
+	FuseCacheSize     int           // size of the swarmfs read cache in megabytes, 0 disables caching
+	FuseReadahead     int           // number of 128KB blocks read ahead of sequential swarmfs reads
 	privateKey        *ecdsa.PrivateKey
 }
 
@@ -75,9 +85,12 @@ type Config struct {
 func NewConfig() (self *Config) {
 
 	self = &Config{
-		LocalStoreParams: storage.NewDefaultLocalStoreParams(),
-		FileStoreParams:  storage.NewFileStoreParams(),
-		HiveParams:       network.NewHiveParams(),
+		LocalStoreParams:     storage.NewDefaultLocalStoreParams(),
+		FileStoreParams:      storage.NewFileStoreParams(),
+		HiveParams:           network.NewHiveParams(),
+		KadParams:            network.NewKadParams(),
+		PartitionParams:      network.NewPartitionParams(),
+		TopicDiscoveryParams: network.NewTopicDiscoveryParams(),
 		//SyncParams:    network.NewDefaultSyncParams(),
 		Swap:              swap.NewDefaultSwapParams(),
 		Pss:               pss.NewPssParams(),
@@ -90,9 +103,13 @@ func NewConfig() (self *Config) {
 		SwapEnabled:       false,
 		SyncEnabled:       true,
 		DeliverySkipCheck: false,
+		Compression:       false,
 		SyncUpdateDelay:   15 * time.Second,
 		SwapApi:           "",
 		BootNodes:         "",
+		BootnodesRefresh:  time.Hour,
+		FuseCacheSize:     64,
+		FuseReadahead:     8,
 	}
 
 	return
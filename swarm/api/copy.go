@@ -0,0 +1,132 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+var (
+	apiCopyCount = metrics.NewRegisteredCounter("api.copy.count", nil)
+	apiCopyFail  = metrics.NewRegisteredCounter("api.copy.fail", nil)
+)
+
+var (
+	// ErrCopyNotFound is returned by Copy if the source path matches no entry
+	ErrCopyNotFound = errors.New("source path not found")
+
+	// ErrCopyEncrypted is returned by Copy if the source manifest is
+	// encrypted and the destination manifest is not, as the references of the
+	// copied entries would disclose their decryption keys
+	ErrCopyEncrypted = errors.New("cannot copy from an encrypted to an unencrypted manifest")
+)
+
+// Copy adds the entries of the manifest at src found at srcPath to the
+// manifest at dst under dstPath and returns the address of the new manifest.
+// The new entries reference the content of the source entries, which is not
+// retrieved. If srcPath is the path of a file, it is copied to dstPath, or to
+// its base name below dstPath if dstPath is empty or ends with a slash,
+// otherwise every file below the directory srcPath is copied below dstPath.
+// Existing entries of the destination manifest are replaced. Entries of an
+// encrypted manifest can only be copied to another encrypted manifest.
+func (self *Api) Copy(src storage.Address, srcPath string, dst storage.Address, dstPath string) (storage.Address, error) {
+	apiCopyCount.Inc(1)
+	quitC := make(chan bool)
+	trie, err := loadManifest(self.fileStore, dst, quitC)
+	if err != nil {
+		apiCopyFail.Inc(1)
+		return nil, err
+	}
+	if !trie.encrypted && len(src) > storage.KeyLength {
+		apiCopyFail.Inc(1)
+		return nil, ErrCopyEncrypted
+	}
+	entries, err := self.copyEntries(src, srcPath, dstPath)
+	if err != nil {
+		apiCopyFail.Inc(1)
+		return nil, err
+	}
+	for _, entry := range entries {
+		trie.addEntry(newManifestTrieEntry(entry, nil), quitC)
+	}
+	if err := trie.recalcAndStore(); err != nil {
+		apiCopyFail.Inc(1)
+		return nil, err
+	}
+	return trie.ref, nil
+}
+
+// copyEntries returns the entries of the manifest at src found at srcPath
+// with their paths in the destination manifest
+func (self *Api) copyEntries(src storage.Address, srcPath, dstPath string) ([]*ManifestEntry, error) {
+	walker, err := self.NewManifestWalker(src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := srcPath
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var (
+		file    *ManifestEntry
+		entries []*ManifestEntry
+	)
+	err = walker.Walk(func(entry *ManifestEntry) error {
+		// only recurse into submanifests which may contain the source path
+		if entry.ContentType == ManifestType {
+			if strings.HasPrefix(srcPath, entry.Path) || strings.HasPrefix(entry.Path, prefix) {
+				return nil
+			}
+			return SkipManifest
+		}
+		if srcPath != "" && entry.Path == srcPath {
+			e := *entry
+			file = &e
+		} else if strings.HasPrefix(entry.Path, prefix) {
+			e := *entry
+			entries = append(entries, &e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if file != nil {
+		if dstPath == "" || strings.HasSuffix(dstPath, "/") {
+			dstPath += path.Base(srcPath)
+		}
+		file.Path = dstPath
+		return []*ManifestEntry{file}, nil
+	}
+	if len(entries) == 0 {
+		return nil, ErrCopyNotFound
+	}
+	if dstPath != "" && !strings.HasSuffix(dstPath, "/") {
+		dstPath += "/"
+	}
+	for _, entry := range entries {
+		entry.Path = dstPath + strings.TrimPrefix(entry.Path, prefix)
+	}
+	return entries, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+func TestApiCopy(t *testing.T) {
+	testApi(t, func(api *Api, toEncrypt bool) {
+		addr, err := api.NewManifest(toEncrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := addr.Hex()
+		for _, path := range []string{"/a", "/dir", "/dir/sub"} {
+			for _, name := range []string{"x", "y"} {
+				if _, src, err = api.AddFile(src, path, name, []byte(path+name), true); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		addr, err = api.NewManifest(toEncrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, dst, err := api.AddFile(addr.Hex(), "/site", "index.html", []byte("index"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcAddr := storage.Address(common.Hex2Bytes(src))
+		dstAddr := storage.Address(common.Hex2Bytes(dst))
+
+		// a file is copied to its base name below a directory
+		newAddr, err := api.Copy(srcAddr, "dir/x", dstAddr, "site/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp := testGet(t, api, newAddr.Hex(), "site/x"); resp.Content != "/dirx" {
+			t.Fatalf("expected copied file content /dirx, got %q", resp.Content)
+		}
+		newAddr, err = api.Copy(srcAddr, "a/y", newAddr, "site/other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp := testGet(t, api, newAddr.Hex(), "site/other"); resp.Content != "/ay" {
+			t.Fatalf("expected copied file content /ay, got %q", resp.Content)
+		}
+
+		// the files below a directory are copied below the destination path
+		newAddr, err = api.Copy(srcAddr, "dir", newAddr, "site/d")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, entries, err := api.BuildDirectoryTree(newAddr.Hex(), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]string{
+			"site/index.html": "index",
+			"site/x":          "/dirx",
+			"site/other":      "/ay",
+			"site/d/x":        "/dirx",
+			"site/d/y":        "/diry",
+			"site/d/sub/x":    "/dir/subx",
+			"site/d/sub/y":    "/dir/suby",
+		}
+		if len(entries) != len(expected) {
+			t.Fatalf("expected %d entries, got %d", len(expected), len(entries))
+		}
+		for path, content := range expected {
+			if entries[path] == nil {
+				t.Fatalf("expected entry %s", path)
+			}
+			if resp := testGet(t, api, newAddr.Hex(), path); resp.Content != content {
+				t.Fatalf("expected content %q at %s, got %q", content, path, resp.Content)
+			}
+		}
+
+		// the source manifest is not modified
+		if _, entries, err = api.BuildDirectoryTree(src, true); err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 6 {
+			t.Fatalf("expected 6 entries in the source manifest, got %d", len(entries))
+		}
+
+		if _, err := api.Copy(srcAddr, "missing", dstAddr, ""); err != ErrCopyNotFound {
+			t.Fatalf("expected ErrCopyNotFound, got %v", err)
+		}
+
+		// entries of an encrypted manifest are not copied to a public one
+		if toEncrypt {
+			public, err := api.NewManifest(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := api.Copy(srcAddr, "dir", public, ""); err != ErrCopyEncrypted {
+				t.Fatalf("expected ErrCopyEncrypted, got %v", err)
+			}
+		}
+	})
+}
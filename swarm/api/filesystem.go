@@ -18,6 +18,7 @@ package api
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -144,6 +145,7 @@ func (self *FileSystem) Upload(lpath, index string, toEncrypt bool) (string, err
 
 	trie := &manifestTrie{
 		fileStore: self.api.fileStore,
+		encrypted: toEncrypt,
 	}
 	quitC := make(chan bool)
 	for i, entry := range list {
@@ -207,8 +209,9 @@ func (self *FileSystem) Download(bzzpath, localpath string) error {
 	}
 
 	type downloadListEntry struct {
-		addr storage.Address
-		path string
+		addr   storage.Address
+		path   string
+		parity int
 	}
 
 	var list []*downloadListEntry
@@ -226,7 +229,7 @@ func (self *FileSystem) Download(bzzpath, localpath string) error {
 			prevPath = dir
 		}
 		if (mde == nil) && (path != dir+"/") {
-			list = append(list, &downloadListEntry{addr: addr, path: path})
+			list = append(list, &downloadListEntry{addr: addr, path: path, parity: entry.Parity})
 		}
 	})
 	if err != nil {
@@ -245,7 +248,7 @@ func (self *FileSystem) Download(bzzpath, localpath string) error {
 		}
 		go func(i int, entry *downloadListEntry) {
 			defer wg.Done()
-			err := retrieveToFile(quitC, self.api.fileStore, entry.addr, entry.path)
+			err := retrieveToFile(quitC, self.api.fileStore, entry.addr, entry.path, entry.parity)
 			if err != nil {
 				select {
 				case errC <- err:
@@ -268,12 +271,12 @@ func (self *FileSystem) Download(bzzpath, localpath string) error {
 	}
 }
 
-func retrieveToFile(quitC chan bool, fileStore *storage.FileStore, addr storage.Address, path string) error {
+func retrieveToFile(quitC chan bool, fileStore *storage.FileStore, addr storage.Address, path string, parity int) error {
 	f, err := os.Create(path) // TODO: basePath separators
 	if err != nil {
 		return err
 	}
-	reader, _ := fileStore.Retrieve(addr)
+	reader := fileStore.RetrieveErasure(context.Background(), addr, parity)
 	writer := bufio.NewWriter(f)
 	size, err := reader.Size(quitC)
 	if err != nil {
@@ -85,6 +85,42 @@ func TestApiDirUpload0(t *testing.T) {
 	})
 }
 
+// TestApiDirUploadEncrypted tests that the manifests of an encrypted upload
+// are encrypted, and that every entry references its content with the key to
+// decrypt it
+func TestApiDirUploadEncrypted(t *testing.T) {
+	testFileSystem(t, func(fs *FileSystem, toEncrypt bool) {
+		bzzhash, err := fs.Upload(filepath.Join("testdata", "test0"), "", toEncrypt)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		addr := storage.Address(common.Hex2Bytes(bzzhash))
+		if encrypted := len(addr) > storage.KeyLength; encrypted != toEncrypt {
+			t.Fatalf("expected encrypted manifest %v, got reference %s", toEncrypt, bzzhash)
+		}
+		walker, err := fs.api.NewManifestWalker(addr, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var count int
+		err = walker.Walk(func(entry *ManifestEntry) error {
+			count++
+			if entry.Encrypted() != toEncrypt {
+				t.Fatalf("expected encrypted entry %v, got %s with reference %s", toEncrypt, entry.Path, entry.Hash)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// img/logo.png, index.css, index.html and the submanifests of their
+		// common prefixes "i" and "index."
+		if count != 5 {
+			t.Fatalf("expected 5 entries, got %d", count)
+		}
+	})
+}
+
 func TestApiDirUploadModify(t *testing.T) {
 	testFileSystem(t, func(fs *FileSystem, toEncrypt bool) {
 		api := fs.api
@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// HealthCheck is a named check of the node, Check returns an error
+// describing the problem if the node fails it
+type HealthCheck struct {
+	Name  string
+	Check func() error
+}
+
+// HealthReport is the response of the /health and /ready endpoints
+type HealthReport struct {
+	Healthy bool              `json:"healthy"`
+	Checks  map[string]string `json:"checks"` // "ok" or the problem found by each check
+}
+
+// SetHealthChecks sets the checks of the /health endpoint, which reports
+// whether the node works, and the additional checks of the /ready endpoint,
+// which reports whether it can serve content from the network. Both respond
+// with 200 OK if all their checks pass and 503 Service Unavailable otherwise,
+// so that load balancers take unhealthy gateways out of rotation.
+func (s *Server) SetHealthChecks(health, ready []HealthCheck) {
+	s.checksMu.Lock()
+	defer s.checksMu.Unlock()
+	s.healthChecks = health
+	s.readyChecks = ready
+}
+
+// HandleHealth handles a GET request to /health or /ready by running the
+// checks of the endpoint concurrently
+func (s *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	s.checksMu.RLock()
+	checks := s.healthChecks
+	if r.URL.Path == "/ready" {
+		// a node which is not healthy is not ready either
+		checks = append(append([]HealthCheck{}, s.healthChecks...), s.readyChecks...)
+	}
+	s.checksMu.RUnlock()
+
+	report := runHealthChecks(checks)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if report.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// runHealthChecks runs the checks concurrently and reports their results
+func runHealthChecks(checks []HealthCheck) *HealthReport {
+	report := &HealthReport{
+		Healthy: true,
+		Checks:  make(map[string]string, len(checks)),
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			result := "ok"
+			err := check.Check()
+			if err != nil {
+				result = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Healthy = false
+			}
+			report.Checks[check.Name] = result
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
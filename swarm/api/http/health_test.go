@@ -0,0 +1,83 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/testutil"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	var server *Server
+	srv := testutil.NewTestSwarmServer(t, func(a *api.Api) testutil.TestServer {
+		server = NewServer(a)
+		return server
+	})
+	defer srv.Close()
+
+	var (
+		mu     sync.Mutex
+		synced error
+	)
+	server.SetHealthChecks(
+		[]HealthCheck{{Name: "store", Check: func() error { return nil }}},
+		[]HealthCheck{{Name: "sync", Check: func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			return synced
+		}}},
+	)
+
+	check := func(path string, expectedCode int, expectedChecks map[string]string) {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != expectedCode {
+			t.Fatalf("%s: expected status %d, got %d", path, expectedCode, res.StatusCode)
+		}
+		var report HealthReport
+		if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Healthy != (expectedCode == http.StatusOK) || len(report.Checks) != len(expectedChecks) {
+			t.Fatalf("%s: unexpected report %+v", path, report)
+		}
+		for name, result := range expectedChecks {
+			if report.Checks[name] != result {
+				t.Fatalf("%s: expected %q for check %s, got %q", path, result, name, report.Checks[name])
+			}
+		}
+	}
+
+	check("/health", http.StatusOK, map[string]string{"store": "ok"})
+	check("/ready", http.StatusOK, map[string]string{"store": "ok", "sync": "ok"})
+
+	// a failed readiness check does not affect the health of the node
+	mu.Lock()
+	synced = errors.New("not syncing")
+	mu.Unlock()
+	check("/health", http.StatusOK, map[string]string{"store": "ok"})
+	check("/ready", http.StatusServiceUnavailable, map[string]string{"store": "ok", "sync": "not syncing"})
+}
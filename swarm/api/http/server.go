@@ -35,6 +35,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -44,6 +45,7 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/api"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	"github.com/ethereum/go-ethereum/swarm/storage/mru"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
 	"github.com/pborman/uuid"
 	"github.com/rs/cors"
 )
@@ -54,9 +56,26 @@ type resourceResponse struct {
 	Update   storage.Address `json:"update"`
 }
 
+type resourceHistoryResponse struct {
+	Resource string                  `json:"resource"`
+	Updates  []resourceHistoryUpdate `json:"updates"`
+	Next     uint32                  `json:"next,omitempty"` // period to request the next page from
+}
+
+type resourceHistoryUpdate struct {
+	Period    uint32          `json:"period"`
+	Version   uint32          `json:"version"`
+	Update    storage.Address `json:"update"`
+	Data      hexutil.Bytes   `json:"data"`
+	Multihash bool            `json:"multihash,omitempty"`
+}
+
 var (
 	postRawCount    = metrics.NewRegisteredCounter("api.http.post.raw.count", nil)
 	postRawFail     = metrics.NewRegisteredCounter("api.http.post.raw.fail", nil)
+	postChunkCount  = metrics.NewRegisteredCounter("api.http.post.chunk.count", nil)
+	postChunkFail   = metrics.NewRegisteredCounter("api.http.post.chunk.fail", nil)
+	headChunkCount  = metrics.NewRegisteredCounter("api.http.head.chunk.count", nil)
 	postFilesCount  = metrics.NewRegisteredCounter("api.http.post.files.count", nil)
 	postFilesFail   = metrics.NewRegisteredCounter("api.http.post.files.fail", nil)
 	deleteCount     = metrics.NewRegisteredCounter("api.http.delete.count", nil)
@@ -70,6 +89,10 @@ var (
 	getFilesFail    = metrics.NewRegisteredCounter("api.http.get.files.fail", nil)
 	getListCount    = metrics.NewRegisteredCounter("api.http.get.list.count", nil)
 	getListFail     = metrics.NewRegisteredCounter("api.http.get.list.fail", nil)
+	getVerifyCount  = metrics.NewRegisteredCounter("api.http.get.verify.count", nil)
+	getVerifyFail   = metrics.NewRegisteredCounter("api.http.get.verify.fail", nil)
+	putCopyCount    = metrics.NewRegisteredCounter("api.http.put.copy.count", nil)
+	putCopyFail     = metrics.NewRegisteredCounter("api.http.put.copy.fail", nil)
 )
 
 // ServerConfig is the basic configuration needed for the HTTP server and also
@@ -84,8 +107,8 @@ type ServerConfig struct {
 // electron (chromium) api for registering bzz url scheme handlers:
 // https://github.com/atom/electron/blob/master/docs/api/protocol.md
 
-// starts up http server
-func StartHttpServer(api *api.Api, config *ServerConfig) {
+// starts up http server and returns the handler serving the requests
+func StartHttpServer(api *api.Api, config *ServerConfig) *Server {
 	var allowedOrigins []string
 	for _, domain := range strings.Split(config.CorsString, ",") {
 		allowedOrigins = append(allowedOrigins, strings.TrimSpace(domain))
@@ -96,17 +119,74 @@ func StartHttpServer(api *api.Api, config *ServerConfig) {
 		MaxAge:         600,
 		AllowedHeaders: []string{"*"},
 	})
-	hdlr := c.Handler(NewServer(api))
+	server := NewServer(api)
+	hdlr := c.Handler(server)
 
 	go http.ListenAndServe(config.Addr, hdlr)
+	return server
 }
 
 func NewServer(api *api.Api) *Server {
-	return &Server{api}
+	return &Server{
+		api:   api,
+		stats: newGatewayStats(),
+	}
 }
 
 type Server struct {
-	api *api.Api
+	api   *api.Api
+	stats *gatewayStats
+
+	checksMu     sync.RWMutex
+	healthChecks []HealthCheck // checks of /health
+	readyChecks  []HealthCheck // additional checks of /ready
+}
+
+// GatewayStats are the statistics of the requests served by the HTTP gateway
+type GatewayStats struct {
+	Started      time.Time        `json:"started"`
+	Requests     map[string]int64 `json:"requests"`     // number of requests by method
+	ClientErrors int64            `json:"clientErrors"` // number of 4xx responses
+	ServerErrors int64            `json:"serverErrors"` // number of 5xx responses
+}
+
+type gatewayStats struct {
+	mu    sync.Mutex
+	stats GatewayStats
+}
+
+func newGatewayStats() *gatewayStats {
+	return &gatewayStats{
+		stats: GatewayStats{
+			Started:  time.Now(),
+			Requests: make(map[string]int64),
+		},
+	}
+}
+
+func (g *gatewayStats) add(method string, code int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stats.Requests[method]++
+	switch {
+	case code >= 500:
+		g.stats.ServerErrors++
+	case code >= 400:
+		g.stats.ClientErrors++
+	}
+}
+
+// Stats returns the statistics of the requests served since the server
+// was created
+func (s *Server) Stats() *GatewayStats {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	stats := s.stats.stats
+	stats.Requests = make(map[string]int64, len(s.stats.stats.Requests))
+	for method, n := range s.stats.stats.Requests {
+		stats.Requests[method] = n
+	}
+	return &stats
 }
 
 // Request wraps http.Request and also includes the parsed bzz URI
@@ -146,7 +226,7 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 		Respond(w, r, "missing Content-Length header in request", http.StatusBadRequest)
 		return
 	}
-	addr, _, err := s.api.Store(r.Body, r.ContentLength, toEncrypt)
+	addr, _, err := s.api.StoreContext(r.Context(), r.Body, r.ContentLength, toEncrypt)
 	if err != nil {
 		postRawFail.Inc(1)
 		Respond(w, r, err.Error(), http.StatusInternalServerError)
@@ -160,6 +240,60 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 	fmt.Fprint(w, addr)
 }
 
+// HandlePostChunk handles a POST request to bzz-chunk:/ whose body is the
+// span and payload of a single unencrypted chunk, stores the chunk and
+// returns its address as a text/plain response
+func (s *Server) HandlePostChunk(w http.ResponseWriter, r *Request) {
+	log.Debug("handle.post.chunk", "ruid", r.ruid)
+
+	postChunkCount.Inc(1)
+	if r.uri.Addr != "" || r.uri.Path != "" {
+		postChunkFail.Inc(1)
+		Respond(w, r, "chunk POST request cannot contain an address or a path", http.StatusBadRequest)
+		return
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, 8+storage.DefaultChunkSize+1))
+	if err != nil {
+		postChunkFail.Inc(1)
+		Respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	addr, err := s.api.PutChunk(data)
+	if err == storage.ErrChunkInvalid {
+		postChunkFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("invalid chunk of %d bytes", len(data)), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		postChunkFail.Inc(1)
+		Respond(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Debug("stored chunk", "ruid", r.ruid, "key", addr)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, addr)
+}
+
+// HandleHeadChunk handles a HEAD request to bzz-chunk:/<addr> and responds
+// with status 200 if the chunk is in the local store and 404 otherwise
+func (s *Server) HandleHeadChunk(w http.ResponseWriter, r *Request) {
+	log.Debug("handle.head.chunk", "ruid", r.ruid)
+
+	headChunkCount.Inc(1)
+	addr := common.FromHex(r.uri.Addr)
+	if len(addr) != storage.KeyLength || r.uri.Path != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.api.HasChunk(storage.Address(addr)) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 // HandlePostFiles handles a POST request to
 // bzz:/<hash>/<path> which contains either a single file or multiple files
 // (either a tar archive or multipart form), adds those files either to an
@@ -228,36 +362,8 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 
 func (s *Server) handleTarUpload(req *Request, mw *api.ManifestWriter) error {
 	log.Debug("handle.tar.upload", "ruid", req.ruid)
-	tr := tar.NewReader(req.Body)
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			return nil
-		} else if err != nil {
-			return fmt.Errorf("error reading tar stream: %s", err)
-		}
-
-		// only store regular files
-		if !hdr.FileInfo().Mode().IsRegular() {
-			continue
-		}
-
-		// add the entry under the path from the request
-		path := path.Join(req.uri.Path, hdr.Name)
-		entry := &api.ManifestEntry{
-			Path:        path,
-			ContentType: hdr.Xattrs["user.swarm.content-type"],
-			Mode:        hdr.Mode,
-			Size:        hdr.Size,
-			ModTime:     hdr.ModTime,
-		}
-		log.Debug("adding path to new manifest", "ruid", req.ruid, "bytes", entry.Size, "path", entry.Path)
-		contentKey, err := mw.AddEntry(tr, entry)
-		if err != nil {
-			return fmt.Errorf("error adding manifest entry from tar stream: %s", err)
-		}
-		log.Debug("stored content", "ruid", req.ruid, "key", contentKey)
-	}
+	// add the entries under the path from the request
+	return mw.AddTar(req.Body, req.uri.Path)
 }
 
 func (s *Server) handleMultipartUpload(req *Request, boundary string, mw *api.ManifestWriter) error {
@@ -363,6 +469,58 @@ func (s *Server) HandleDelete(w http.ResponseWriter, r *Request) {
 	fmt.Fprint(w, newKey)
 }
 
+// HandlePutCopy handles a PUT request to bzz:/<manifest>/<path> with a
+// copy=<hash>/<path> query parameter by copying the source entries into the
+// manifest at the given path by reference, and returns the hash of the new
+// manifest as a text/plain response
+func (s *Server) HandlePutCopy(w http.ResponseWriter, r *Request) {
+	log.Debug("handle.put.copy", "ruid", r.ruid)
+
+	putCopyCount.Inc(1)
+	source := r.URL.Query().Get("copy")
+	if !strings.Contains(source, ":") {
+		source = "bzz:/" + source
+	}
+	srcURI, err := api.Parse(source)
+	if err != nil || srcURI.Scheme != "bzz" {
+		putCopyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("invalid copy source %q", source), http.StatusBadRequest)
+		return
+	}
+	srcKey, err := s.api.Resolve(srcURI)
+	if err != nil {
+		putCopyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("cannot resolve %s: %s", srcURI.Addr, err), http.StatusInternalServerError)
+		return
+	}
+	dstKey, err := s.api.Resolve(r.uri)
+	if err != nil {
+		putCopyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("cannot resolve %s: %s", r.uri.Addr, err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Debug(fmt.Sprintf("copying %s from manifest %s to %s in manifest %s", srcURI.Path, srcKey.Log(), r.uri.Path, dstKey.Log()), "ruid", r.ruid)
+	newKey, err := s.api.Copy(srcKey, srcURI.Path, dstKey, r.uri.Path)
+	if err == api.ErrCopyNotFound {
+		putCopyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("%s not found in manifest %s", srcURI.Path, srcURI.Addr), http.StatusNotFound)
+		return
+	} else if err == api.ErrCopyEncrypted {
+		putCopyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("cannot copy %s: %s", source, err), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		putCopyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("cannot copy %s: %s", source, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, newKey)
+}
+
 // Parses a resource update post url to corresponding action
 // possible combinations:
 // /			add multihash update to existing hash
@@ -516,6 +674,7 @@ func (s *Server) HandlePostResource(w http.ResponseWriter, r *Request) {
 // bzz-resource://<id> - get latest update
 // bzz-resource://<id>/<n> - get latest update on period n
 // bzz-resource://<id>/<n>/<m> - get update version m of period n
+// bzz-resource://<id>/history?from=<n>&to=<m>&limit=<l> - get all updates of periods n to m
 // <id> = ens name or hash
 func (s *Server) HandleGetResource(w http.ResponseWriter, r *Request) {
 	s.handleGetResource(w, r)
@@ -554,6 +713,10 @@ func (s *Server) handleGetResource(w http.ResponseWriter, r *Request) {
 	if len(r.uri.Path) > 0 {
 		params = strings.Split(r.uri.Path, "/")
 	}
+	if len(params) == 1 && params[0] == "history" {
+		s.handleGetResourceHistory(w, r, key)
+		return
+	}
 	var name string
 	var period uint64
 	var version uint64
@@ -596,6 +759,65 @@ func (s *Server) handleGetResource(w http.ResponseWriter, r *Request) {
 	http.ServeContent(w, &r.Request, "", now, bytes.NewReader(data))
 }
 
+// Retrieves the updates of a mutable resource between two periods as JSON, by default
+// from the first period to the latest, the limit is the number of periods holding
+// updates per page
+func (s *Server) handleGetResourceHistory(w http.ResponseWriter, r *Request, key storage.Address) {
+	log.Debug("handle.get.resource.history", "ruid", r.ruid)
+	query := r.URL.Query()
+	var from, to, limit uint64
+	var err error
+	if v := query.Get("from"); v != "" {
+		from, err = strconv.ParseUint(v, 10, 32)
+	}
+	if v := query.Get("to"); v != "" && err == nil {
+		to, err = strconv.ParseUint(v, 10, 32)
+	}
+	if v := query.Get("limit"); v != "" && err == nil {
+		limit, err = strconv.ParseUint(v, 10, 32)
+	}
+	if err != nil {
+		getFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("invalid mutable resource history request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	name, updates, err := s.api.ResourceHistory(r.Context(), key, uint32(from), uint32(to), int(limit))
+	if err != nil {
+		code, err2 := s.translateResourceError(w, r, "mutable resource history lookup fail", err)
+		Respond(w, r, err2.Error(), code)
+		return
+	}
+
+	res := &resourceHistoryResponse{
+		Resource: name,
+		Updates:  make([]resourceHistoryUpdate, 0, len(updates)),
+	}
+	periods := 0
+	for i, update := range updates {
+		if i == 0 || update.Period != updates[i-1].Period {
+			periods++
+		}
+		res.Updates = append(res.Updates, resourceHistoryUpdate{
+			Period:    update.Period,
+			Version:   update.Version,
+			Update:    update.Key,
+			Data:      update.Data,
+			Multihash: update.Multihash,
+		})
+	}
+	// a full page may be followed by more updates
+	if limit > 0 && uint64(periods) == limit {
+		last := updates[len(updates)-1].Period
+		if to == 0 || uint64(last) < to {
+			res.Next = last + 1
+		}
+	}
+	log.Debug("Found updates", "name", name, "ruid", r.ruid, "updates", len(updates), "next", res.Next)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
 func (s *Server) translateResourceError(w http.ResponseWriter, r *Request, supErr string, err error) (int, error) {
 	code := 0
 	defaultErr := fmt.Errorf("%s: %v", supErr, err)
@@ -680,18 +902,14 @@ func (s *Server) HandleGet(w http.ResponseWriter, r *Request) {
 		}
 		addr = storage.Address(common.Hex2Bytes(entry.Hash))
 	}
-	etag := common.Bytes2Hex(addr)
-	noneMatchEtag := r.Header.Get("If-None-Match")
-	w.Header().Set("ETag", etag) // set etag to manifest key or raw entry key.
-	if noneMatchEtag != "" {
-		if bytes.Equal(storage.Address(common.Hex2Bytes(noneMatchEtag)), addr) {
-			Respond(w, r, "Not Modified", http.StatusNotModified)
-			return
-		}
+	w.Header().Set("ETag", contentETag(addr)) // set etag to manifest key or raw entry key.
+	if etagMatches(r.Header.Get("If-None-Match"), addr) {
+		Respond(w, r, "Not Modified", http.StatusNotModified)
+		return
 	}
 
 	// check the root chunk exists by retrieving the file's size
-	reader, isEncrypted := s.api.Retrieve(addr)
+	reader, isEncrypted := s.api.RetrieveContext(r.Context(), addr)
 	if _, err := reader.Size(nil); err != nil {
 		getFail.Inc(1)
 		Respond(w, r, fmt.Sprintf("root chunk not found %s: %s", addr, err), http.StatusNotFound)
@@ -756,7 +974,10 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 		}
 
 		// retrieve the entry's key and size
-		reader, isEncrypted := s.api.Retrieve(storage.Address(common.Hex2Bytes(entry.Hash)))
+		reader, isEncrypted := s.api.RetrieveContext(r.Context(), storage.Address(common.Hex2Bytes(entry.Hash)))
+		if entry.Parity > 0 {
+			reader = s.api.RetrieveErasure(r.Context(), storage.Address(common.Hex2Bytes(entry.Hash)), entry.Parity)
+		}
 		size, err := reader.Size(nil)
 		if err != nil {
 			return err
@@ -766,13 +987,19 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 		// write a tar header for the entry
 		hdr := &tar.Header{
 			Name:    entry.Path,
-			Mode:    entry.Mode,
+			Mode:    int64(os.FileMode(entry.Mode).Perm()),
 			Size:    size,
 			ModTime: entry.ModTime,
 			Xattrs: map[string]string{
 				"user.swarm.content-type": entry.ContentType,
 			},
 		}
+		if entry.IsSymlink() {
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = entry.LinkTarget
+			hdr.Size = 0
+			return tw.WriteHeader(hdr)
+		}
 		if err := tw.WriteHeader(hdr); err != nil {
 			return err
 		}
@@ -793,6 +1020,47 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *Request) {
 	}
 }
 
+// HandleGetVerify handles a GET request to bzz-verify:/<manifest>/<path> and
+// responds with a JSON report of the chunks of the content at the path which
+// cannot be retrieved, the manifest is checked as raw content if the path is
+// empty. The parity chunks of erasure coded content are checked as well, as
+// recorded in its manifest entry. Chunks are only looked up in the local
+// store if the "local" query parameter is true.
+func (s *Server) HandleGetVerify(w http.ResponseWriter, r *Request) {
+	log.Debug("handle.get.verify", "ruid", r.ruid, "uri", r.uri)
+	getVerifyCount.Inc(1)
+
+	addr, err := s.api.Resolve(r.uri)
+	if err != nil {
+		getVerifyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("cannot resolve %s: %s", r.uri.Addr, err), http.StatusNotFound)
+		return
+	}
+	var parity int
+	if r.uri.Path != "" {
+		entry, err := s.api.GetEntry(addr, r.uri.Path)
+		if err != nil || entry.Hash == "" || entry.ContentType == api.ResourceContentType {
+			getVerifyFail.Inc(1)
+			Respond(w, r, fmt.Sprintf("cannot find %s in manifest %s", r.uri.Path, addr), http.StatusNotFound)
+			return
+		}
+		addr = storage.Address(common.Hex2Bytes(entry.Hash))
+		parity = entry.Parity
+	}
+	log.Debug("handle.get.verify: resolved", "ruid", r.ruid, "key", addr, "parity", parity)
+
+	local, _ := strconv.ParseBool(r.URL.Query().Get("local"))
+	report, err := s.api.VerifyErasure(addr, local, parity)
+	if err != nil {
+		getVerifyFail.Inc(1)
+		Respond(w, r, fmt.Sprintf("cannot verify %s: %s", addr, err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 // HandleGetList handles a GET request to bzz-list:/<manifest>/<path> and returns
 // a list of all files contained in <manifest> under <path> grouped into
 // common prefixes using "/" as a delimiter
@@ -844,60 +1112,27 @@ func (s *Server) HandleGetList(w http.ResponseWriter, r *Request) {
 	json.NewEncoder(w).Encode(&list)
 }
 
-func (s *Server) getManifestList(addr storage.Address, prefix string) (list api.ManifestList, err error) {
-	walker, err := s.api.NewManifestWalker(addr, nil)
-	if err != nil {
-		return
-	}
-
-	err = walker.Walk(func(entry *api.ManifestEntry) error {
-		// handle non-manifest files
-		if entry.ContentType != api.ManifestType {
-			// ignore the file if it doesn't have the specified prefix
-			if !strings.HasPrefix(entry.Path, prefix) {
-				return nil
-			}
-
-			// if the path after the prefix contains a slash, add a
-			// common prefix to the list, otherwise add the entry
-			suffix := strings.TrimPrefix(entry.Path, prefix)
-			if index := strings.Index(suffix, "/"); index > -1 {
-				list.CommonPrefixes = append(list.CommonPrefixes, prefix+suffix[:index+1])
-				return nil
-			}
-			if entry.Path == "" {
-				entry.Path = "/"
-			}
-			list.Entries = append(list.Entries, entry)
-			return nil
-		}
-
-		// if the manifest's path is a prefix of the specified prefix
-		// then just recurse into the manifest by returning nil and
-		// continuing the walk
-		if strings.HasPrefix(prefix, entry.Path) {
-			return nil
-		}
+// contentETag returns the strong entity tag of the content at addr. It is
+// quoted as required by RFC 7232, so that http.ServeContent can validate
+// If-Range requests resuming a ranged download against it.
+func contentETag(addr storage.Address) string {
+	return fmt.Sprintf("%q", common.Bytes2Hex(addr))
+}
 
-		// if the manifest's path has the specified prefix, then if the
-		// path after the prefix contains a slash, add a common prefix
-		// to the list and skip the manifest, otherwise recurse into
-		// the manifest by returning nil and continuing the walk
-		if strings.HasPrefix(entry.Path, prefix) {
-			suffix := strings.TrimPrefix(entry.Path, prefix)
-			if index := strings.Index(suffix, "/"); index > -1 {
-				list.CommonPrefixes = append(list.CommonPrefixes, prefix+suffix[:index+1])
-				return api.SkipManifest
-			}
-			return nil
+// etagMatches reports whether the If-None-Match header value lists the
+// entity tag of the content at addr, accepting unquoted tags as well.
+func etagMatches(header string, addr storage.Address) bool {
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+		if etag != "" && bytes.Equal(common.Hex2Bytes(etag), addr) {
+			return true
 		}
+	}
+	return false
+}
 
-		// the manifest neither has the prefix or needs recursing in to
-		// so just skip it
-		return api.SkipManifest
-	})
-
-	return list, nil
+func (s *Server) getManifestList(addr storage.Address, prefix string) (list api.ManifestList, err error) {
+	return s.api.GetManifestList(addr, prefix)
 }
 
 // HandleGetFile handles a GET request to bzz://<manifest>/<path> and responds
@@ -926,16 +1161,12 @@ func (s *Server) HandleGetFile(w http.ResponseWriter, r *Request) {
 
 	log.Debug("handle.get.file: resolved", "ruid", r.ruid, "key", manifestAddr)
 
-	reader, contentType, status, contentKey, err := s.api.Get(manifestAddr, r.uri.Path)
+	reader, contentType, status, contentKey, err := s.api.GetContext(r.Context(), manifestAddr, r.uri.Path)
 
-	etag := common.Bytes2Hex(contentKey)
-	noneMatchEtag := r.Header.Get("If-None-Match")
-	w.Header().Set("ETag", etag) // set etag to actual content key.
-	if noneMatchEtag != "" {
-		if bytes.Equal(storage.Address(common.Hex2Bytes(noneMatchEtag)), contentKey) {
-			Respond(w, r, "Not Modified", http.StatusNotModified)
-			return
-		}
+	w.Header().Set("ETag", contentETag(contentKey)) // set etag to actual content key.
+	if etagMatches(r.Header.Get("If-None-Match"), contentKey) {
+		Respond(w, r, "Not Modified", http.StatusNotModified)
+		return
 	}
 
 	if err != nil {
@@ -975,17 +1206,28 @@ func (s *Server) HandleGetFile(w http.ResponseWriter, r *Request) {
 	}
 
 	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Decrypted", fmt.Sprintf("%v", len(contentKey) > storage.KeyLength))
 	http.ServeContent(w, &r.Request, "", time.Now(), reader)
 }
 
 func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	defer metrics.GetOrRegisterResettingTimer(fmt.Sprintf("http.request.%s.time", r.Method), nil).UpdateSince(time.Now())
+	ctx, sp := tracing.StartSpan(r.Context(), "http.request")
+	r = r.WithContext(ctx)
 	req := &Request{Request: *r, ruid: uuid.New()[:8]}
 	metrics.GetOrRegisterCounter(fmt.Sprintf("http.request.%s", r.Method), nil).Inc(1)
 	log.Info("serving request", "ruid", req.ruid, "method", r.Method, "url", r.RequestURI)
+	sp.SetTag("ruid", req.ruid)
+	sp.SetTag("method", r.Method)
+	sp.SetTag("url", r.RequestURI)
 
 	// wrapping the ResponseWriter, so that we get the response code set by http.ServeContent
 	w := newLoggingResponseWriter(rw)
+	defer func() {
+		s.stats.add(r.Method, w.statusCode)
+		sp.SetTag("status", w.statusCode)
+		sp.Finish()
+	}()
 
 	if r.RequestURI == "/" && strings.Contains(r.Header.Get("Accept"), "text/html") {
 
@@ -996,6 +1238,11 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if (r.URL.Path == "/health" || r.URL.Path == "/ready") && (r.Method == "GET" || r.Method == "HEAD") {
+		s.HandleHealth(w, r)
+		return
+	}
+
 	if r.URL.Path == "/robots.txt" {
 		w.Header().Set("Last-Modified", time.Now().Format(http.TimeFormat))
 		fmt.Fprintf(w, "User-agent: *\nDisallow: /")
@@ -1024,11 +1271,13 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		if uri.Raw() {
 			log.Debug("handlePostRaw")
 			s.HandlePostRaw(w, req)
+		} else if uri.Chunk() {
+			s.HandlePostChunk(w, req)
 		} else if uri.Resource() {
 			log.Debug("handlePostResource")
 			s.HandlePostResource(w, req)
-		} else if uri.Immutable() || uri.List() || uri.Hash() {
-			log.Debug("POST not allowed on immutable, list or hash")
+		} else if uri.Immutable() || uri.List() || uri.Hash() || uri.Verify() {
+			log.Debug("POST not allowed on immutable, list, hash or verify")
 			Respond(w, req, fmt.Sprintf("POST method on scheme %s not allowed", uri.Scheme), http.StatusMethodNotAllowed)
 		} else {
 			log.Debug("handlePostFiles")
@@ -1036,11 +1285,22 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		}
 
 	case "PUT":
+		if uri.Scheme == "bzz" && r.URL.Query().Get("copy") != "" {
+			s.HandlePutCopy(w, req)
+			return
+		}
 		Respond(w, req, fmt.Sprintf("PUT method to %s not allowed", uri), http.StatusBadRequest)
 		return
 
+	case "HEAD":
+		if uri.Chunk() {
+			s.HandleHeadChunk(w, req)
+			return
+		}
+		Respond(w, req, fmt.Sprintf("HEAD method to %s not allowed", uri), http.StatusMethodNotAllowed)
+
 	case "DELETE":
-		if uri.Raw() {
+		if uri.Raw() || uri.Verify() || uri.Chunk() {
 			Respond(w, req, fmt.Sprintf("DELETE method to %s not allowed", uri), http.StatusBadRequest)
 			return
 		}
@@ -1048,6 +1308,11 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 
 	case "GET":
 
+		if uri.Chunk() {
+			Respond(w, req, fmt.Sprintf("GET method to %s not allowed", uri), http.StatusMethodNotAllowed)
+			return
+		}
+
 		if uri.Resource() {
 			s.HandleGetResource(w, req)
 			return
@@ -1063,6 +1328,11 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if uri.Verify() {
+			s.HandleGetVerify(w, req)
+			return
+		}
+
 		if r.Header.Get("Accept") == "application/x-tar" {
 			s.HandleGetFiles(w, req)
 			return
@@ -19,11 +19,14 @@ package http
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"strings"
@@ -351,6 +354,40 @@ func TestBzzResource(t *testing.T) {
 	if !bytes.Equal(databytes, b) {
 		t.Fatalf("Expected body '%x', got '%x'", databytes, b)
 	}
+
+	// get the history of updates 1.1 and 1.2
+	log.Info("get history")
+	url = fmt.Sprintf("%s/bzz-resource:/%s/history?from=1&limit=1", srv.URL, correctManifestAddrHex)
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("err %s", resp.Status)
+	}
+	history := &resourceHistoryResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(history); err != nil {
+		t.Fatal(err)
+	}
+	if history.Resource != keybytes {
+		t.Fatalf("Expected resource '%s', got '%s'", keybytes, history.Resource)
+	}
+	if len(history.Updates) != 2 {
+		t.Fatalf("Expected 2 updates, got %d", len(history.Updates))
+	}
+	for i, expected := range [][]byte{databytes, data} {
+		update := history.Updates[i]
+		if update.Period != 1 || update.Version != uint32(i+1) {
+			t.Fatalf("Expected update 1.%d, got %d.%d", i+1, update.Period, update.Version)
+		}
+		if !bytes.Equal(expected, update.Data) {
+			t.Fatalf("Expected update 1.%d data '%x', got '%x'", i+1, expected, update.Data)
+		}
+	}
+	if history.Next != 2 {
+		t.Fatalf("Expected next page at period 2, got %d", history.Next)
+	}
 }
 
 func TestBzzGetPath(t *testing.T) {
@@ -682,3 +719,280 @@ func TestMethodsNotAllowed(t *testing.T) {
 	}
 
 }
+
+func TestGatewayStats(t *testing.T) {
+	var server *Server
+	srv := testutil.NewTestSwarmServer(t, func(a *api.Api) testutil.TestServer {
+		server = NewServer(a)
+		return server
+	})
+	defer srv.Close()
+
+	for _, c := range []struct {
+		method string
+		url    string
+		code   int
+	}{
+		{"GET", srv.URL + "/robots.txt", http.StatusOK},
+		{"GET", srv.URL + "/invalid", http.StatusBadRequest},
+		{"POST", srv.URL + "/bzz-list:/", http.StatusMethodNotAllowed},
+	} {
+		req, err := http.NewRequest(c.method, c.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != c.code {
+			t.Fatalf("%s %s: expected status %d, got %d", c.method, c.url, c.code, res.StatusCode)
+		}
+	}
+
+	stats := server.Stats()
+	if stats.Requests["GET"] != 2 || stats.Requests["POST"] != 1 {
+		t.Fatalf("unexpected request counts %v", stats.Requests)
+	}
+	if stats.ClientErrors != 2 || stats.ServerErrors != 0 {
+		t.Fatalf("expected 2 client and no server errors, got %d and %d", stats.ClientErrors, stats.ServerErrors)
+	}
+}
+
+// TestBzzGetENS tests that bzz requests of ENS names serve the content the
+// names resolve to as the names are updated
+func TestBzzGetENS(t *testing.T) {
+	srv := testutil.NewTestSwarmServerWithResolver(t, serverFunc, map[string]common.Hash{})
+	defer srv.Close()
+
+	upload := func(content string) common.Hash {
+		res, err := http.Post(srv.URL+"/bzz:/", "text/plain", strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected HTTP status: %s", res.Status)
+		}
+		hash, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return common.HexToHash(string(hash))
+	}
+	get := func(expectedStatus int, expectedContent string) {
+		res, err := http.Get(srv.URL + "/bzz:/test.eth/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != expectedStatus {
+			t.Fatalf("expected HTTP status %d, got %s", expectedStatus, res.Status)
+		}
+		if expectedStatus != http.StatusOK {
+			return
+		}
+		content, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expectedContent {
+			t.Fatalf("expected content %q, got %q", expectedContent, content)
+		}
+	}
+
+	get(http.StatusNotFound, "")
+
+	srv.Resolver.Set("test.eth", upload("first"))
+	get(http.StatusOK, "first")
+
+	srv.Resolver.Set("test.eth", upload("second"))
+	get(http.StatusOK, "second")
+
+	srv.Resolver.Delete("test.eth")
+	get(http.StatusNotFound, "")
+}
+
+// TestBzzRawLoad tests that concurrent bzz-raw uploads and downloads of the
+// server are served without errors
+func TestBzzRawLoad(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	report := testutil.RunLoad(&testutil.HTTPTarget{URL: srv.URL}, testutil.LoadConfig{
+		Concurrency: 4,
+		Uploads:     8,
+		Downloads:   2,
+		Size:        10000,
+	})
+	if report.Errors != 0 {
+		t.Fatalf("%d errors, the first one: %v", report.Errors, report.Err)
+	}
+	if report.Uploads.Count != 8 || report.Downloads.Count != 16 {
+		t.Fatalf("expected 8 uploads and 16 downloads, got %d and %d", report.Uploads.Count, report.Downloads.Count)
+	}
+	t.Logf("load report:\n%v", report)
+}
+
+// TestBzzRawRange tests that single and multiple byte range requests are
+// served from the chunk tree, and that If-Range validates against the ETag
+func TestBzzRawRange(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	data := make([]byte, 3*4096*128+1000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	addr, wait, err := srv.FileStore.Store(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	url := fmt.Sprintf("%s/bzz-raw:/%s", srv.URL, addr)
+
+	get := func(header map[string]string) *http.Response {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// a single range spanning several data chunks
+	res := get(map[string]string{"Range": "bytes=600000-1200000"})
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d", http.StatusPartialContent, res.StatusCode)
+	}
+	if want := fmt.Sprintf("bytes 600000-1200000/%d", len(data)); res.Header.Get("Content-Range") != want {
+		t.Fatalf("expected Content-Range %q, got %q", want, res.Header.Get("Content-Range"))
+	}
+	if !bytes.Equal(body, data[600000:1200001]) {
+		t.Fatal("range content mismatch")
+	}
+	etag := res.Header.Get("ETag")
+
+	// a suffix range and a range within a single chunk as multipart/byteranges
+	res = get(map[string]string{"Range": "bytes=10-20,-100"})
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected status %d, got %d", http.StatusPartialContent, res.StatusCode)
+	}
+	mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("expected multipart/byteranges content, got %q", res.Header.Get("Content-Type"))
+	}
+	reader := multipart.NewReader(res.Body, params["boundary"])
+	for _, want := range [][]byte{data[10:21], data[len(data)-100:]} {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("part %q content mismatch", part.Header.Get("Content-Range"))
+		}
+	}
+
+	// If-Range with the ETag of the content is honoured, a different one
+	// results in the full content
+	for tag, status := range map[string]int{etag: http.StatusPartialContent, `"00"`: http.StatusOK} {
+		res := get(map[string]string{"Range": "bytes=0-99", "If-Range": tag})
+		res.Body.Close()
+		if res.StatusCode != status {
+			t.Fatalf("If-Range %s: expected status %d, got %d", tag, status, res.StatusCode)
+		}
+	}
+
+	// a range past the end of the content is not satisfiable
+	res = get(map[string]string{"Range": fmt.Sprintf("bytes=%d-", len(data)+1)})
+	res.Body.Close()
+	if res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("expected status %d, got %d", http.StatusRequestedRangeNotSatisfiable, res.StatusCode)
+	}
+}
+
+// TestBzzChunk tests that single chunks are stored with POST requests to
+// bzz-chunk:/ and that HEAD requests report whether a chunk is stored
+func TestBzzChunk(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t, serverFunc)
+	defer srv.Close()
+
+	data := []byte("chunk payload")
+	chunk := make([]byte, 8+len(data))
+	binary.LittleEndian.PutUint64(chunk, uint64(len(data)))
+	copy(chunk[8:], data)
+	hasher := storage.MakeHashFunc(storage.DefaultHash)()
+	hasher.ResetWithLength(chunk[:8])
+	hasher.Write(chunk[8:])
+	addr := storage.Address(hasher.Sum(nil))
+
+	head := func(addr string) int {
+		res, err := http.Head(srv.URL + "/bzz-chunk:/" + addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if code := head(addr.Hex()); code != http.StatusNotFound {
+		t.Fatalf("expected status %d for a missing chunk, got %d", http.StatusNotFound, code)
+	}
+
+	res, err := http.Post(srv.URL+"/bzz-chunk:/", "application/octet-stream", bytes.NewReader(chunk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, res.StatusCode, body)
+	}
+	if string(body) != addr.Hex() {
+		t.Fatalf("expected chunk address %s, got %s", addr.Hex(), body)
+	}
+	if code := head(addr.Hex()); code != http.StatusOK {
+		t.Fatalf("expected status %d for a stored chunk, got %d", http.StatusOK, code)
+	}
+	if code := head("1234"); code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid address, got %d", http.StatusBadRequest, code)
+	}
+
+	for _, invalid := range [][]byte{chunk[:4], make([]byte, 8+storage.DefaultChunkSize+1)} {
+		res, err := http.Post(srv.URL+"/bzz-chunk:/", "application/octet-stream", bytes.NewReader(invalid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status %d for a chunk of %d bytes, got %d", http.StatusBadRequest, len(invalid), res.StatusCode)
+		}
+	}
+	res, err = http.Get(srv.URL + "/bzz-chunk:/" + addr.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d for GET, got %d", http.StatusMethodNotAllowed, res.StatusCode)
+	}
+}
@@ -17,18 +17,22 @@
 package api
 
 import (
+	"archive/tar"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/swarm/storage"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -36,22 +40,62 @@ const (
 	ResourceContentType = "application/bzz-resource"
 
 	manifestSizeLimit = 5 * 1024 * 1024
+	manifestCacheSize = 256 // number of parsed manifests kept in memory
 )
 
+// manifestCache caches parsed manifests by address, manifests are content
+// addressed so cached entries never become stale
+var manifestCache, _ = lru.New(manifestCacheSize)
+
+// parsedManifest is a cached manifest, tries are modified while they are
+// used so every load builds a new trie from a copy of the entries
+type parsedManifest struct {
+	entries   []ManifestEntry
+	encrypted bool
+}
+
+func (m *parsedManifest) trie(fileStore *storage.FileStore, quitC chan bool) *manifestTrie {
+	trie := &manifestTrie{
+		fileStore: fileStore,
+		encrypted: m.encrypted,
+	}
+	for _, entry := range m.entries {
+		trie.addEntry(newManifestTrieEntry(&entry, nil), quitC)
+	}
+	return trie
+}
+
 // Manifest represents a swarm manifest
 type Manifest struct {
 	Entries []ManifestEntry `json:"entries,omitempty"`
 }
 
 // ManifestEntry represents an entry in a swarm manifest
+// Mode holds the os.FileMode bits of the file, symbolic links have the
+// os.ModeSymlink bit set and their target stored as both LinkTarget and
+// the content of the entry
 type ManifestEntry struct {
-	Hash        string    `json:"hash,omitempty"`
-	Path        string    `json:"path,omitempty"`
-	ContentType string    `json:"contentType,omitempty"`
-	Mode        int64     `json:"mode,omitempty"`
-	Size        int64     `json:"size,omitempty"`
-	ModTime     time.Time `json:"mod_time,omitempty"`
-	Status      int       `json:"status,omitempty"`
+	Hash        string       `json:"hash,omitempty"`
+	Path        string       `json:"path,omitempty"`
+	ContentType string       `json:"contentType,omitempty"`
+	Mode        int64        `json:"mode,omitempty"`
+	Size        int64        `json:"size,omitempty"`
+	ModTime     time.Time    `json:"mod_time,omitempty"`
+	LinkTarget  string       `json:"linkTarget,omitempty"`
+	Status      int          `json:"status,omitempty"`
+	Access      *AccessEntry `json:"access,omitempty"`
+	Parity      int          `json:"parity,omitempty"` // parity chunks per intermediate chunk of erasure coded content
+}
+
+// IsSymlink returns true if the entry is a symbolic link
+func (e *ManifestEntry) IsSymlink() bool {
+	return os.FileMode(e.Mode)&os.ModeSymlink != 0
+}
+
+// Encrypted returns true if the entry references encrypted content, the
+// reference then holds the decryption key of the content after its hash
+func (e *ManifestEntry) Encrypted() bool {
+	return len(e.Hash) > 2*storage.KeyLength
 }
 
 // ManifestList represents the result of listing files in a manifest
@@ -104,6 +148,17 @@ func (a *Api) NewManifestWriter(addr storage.Address, quitC chan bool) (*Manifes
 	return &ManifestWriter{a, trie, quitC}, nil
 }
 
+// NewEmptyManifestWriter returns a writer of a new manifest which is only
+// kept in memory until it is stored, so no chunks are retrieved while the
+// manifest is written
+func (a *Api) NewEmptyManifestWriter(toEncrypt bool, quitC chan bool) *ManifestWriter {
+	trie := &manifestTrie{
+		fileStore: a.fileStore,
+		encrypted: toEncrypt,
+	}
+	return &ManifestWriter{a, trie, quitC}
+}
+
 // AddEntry stores the given data and adds the resulting key to the manifest
 func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Address, error) {
 
@@ -117,6 +172,48 @@ func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Add
 	return key, nil
 }
 
+// AddTar stores the regular files and symbolic links of the tar stream r
+// and adds them to the manifest under basePath, the content type of a file
+// is read from its user.swarm.content-type extended attribute
+func (m *ManifestWriter) AddTar(r io.Reader, basePath string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading tar stream: %s", err)
+		}
+
+		// only store regular files and symbolic links
+		mode := hdr.FileInfo().Mode()
+		if !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+			continue
+		}
+
+		entry := &ManifestEntry{
+			Path:        path.Join(basePath, hdr.Name),
+			ContentType: hdr.Xattrs["user.swarm.content-type"],
+			Mode:        int64(mode),
+			Size:        hdr.Size,
+			ModTime:     hdr.ModTime,
+		}
+		var content io.Reader = tr
+		if hdr.Typeflag == tar.TypeSymlink {
+			// the link target is stored as the content of the entry
+			entry.LinkTarget = hdr.Linkname
+			entry.Size = int64(len(hdr.Linkname))
+			content = strings.NewReader(hdr.Linkname)
+		}
+		log.Debug("adding path to new manifest", "bytes", entry.Size, "path", entry.Path)
+		contentKey, err := m.AddEntry(content, entry)
+		if err != nil {
+			return fmt.Errorf("error adding manifest entry from tar stream: %s", err)
+		}
+		log.Debug("stored content", "key", contentKey)
+	}
+}
+
 // RemoveEntry removes the given path from the manifest
 func (m *ManifestWriter) RemoveEntry(path string) error {
 	m.trie.deleteEntry(path, m.quitC)
@@ -206,13 +303,30 @@ type manifestTrieEntry struct {
 
 func loadManifest(fileStore *storage.FileStore, hash storage.Address, quitC chan bool) (trie *manifestTrie, err error) { // non-recursive, subtrees are downloaded on-demand
 	log.Trace("manifest lookup", "key", hash)
+	if m, ok := manifestCache.Get(hash.Hex()); ok {
+		return m.(*parsedManifest).trie(fileStore, quitC), nil
+	}
 	// retrieve manifest via FileStore
 	manifestReader, isEncrypted := fileStore.Retrieve(hash)
 	log.Trace("reader retrieved", "key", hash)
-	return readManifest(manifestReader, hash, fileStore, isEncrypted, quitC)
+	m, err := parseManifest(manifestReader, hash, isEncrypted, quitC)
+	if err != nil {
+		return nil, err
+	}
+	manifestCache.Add(hash.Hex(), m)
+	return m.trie(fileStore, quitC), nil
 }
 
 func readManifest(manifestReader storage.LazySectionReader, hash storage.Address, fileStore *storage.FileStore, isEncrypted bool, quitC chan bool) (trie *manifestTrie, err error) { // non-recursive, subtrees are downloaded on-demand
+	m, err := parseManifest(manifestReader, hash, isEncrypted, quitC)
+	if err != nil {
+		return nil, err
+	}
+	return m.trie(fileStore, quitC), nil
+}
+
+// parseManifest reads and decodes the entries of the manifest
+func parseManifest(manifestReader storage.LazySectionReader, hash storage.Address, isEncrypted bool, quitC chan bool) (m *parsedManifest, err error) {
 
 	// TODO check size for oversized manifests
 	size, err := manifestReader.Size(quitC)
@@ -250,12 +364,9 @@ func readManifest(manifestReader storage.LazySectionReader, hash storage.Address
 
 	log.Trace("manifest entries", "key", hash, "len", len(man.Entries))
 
-	trie = &manifestTrie{
-		fileStore: fileStore,
-		encrypted: isEncrypted,
-	}
+	m = &parsedManifest{encrypted: isEncrypted}
 	for _, entry := range man.Entries {
-		trie.addEntry(entry, quitC)
+		m.entries = append(m.entries, entry.ManifestEntry)
 	}
 	return
 }
@@ -392,6 +503,11 @@ func (self *manifestTrie) loadSubTrie(entry *manifestTrieEntry, quitC chan bool)
 	if entry.subtrie == nil {
 		hash := common.Hex2Bytes(entry.Hash)
 		entry.subtrie, err = loadManifest(self.fileStore, hash, quitC)
+		if err != nil {
+			return
+		}
+		// the entries of an encrypted manifest must not be stored in the clear
+		entry.subtrie.encrypted = entry.subtrie.encrypted || self.encrypted
 		entry.Hash = "" // might not match, should be recalculated
 	}
 	return
@@ -33,6 +33,7 @@ func TestParseURI(t *testing.T) {
 		expectImmutable           bool
 		expectList                bool
 		expectHash                bool
+		expectVerify              bool
 		expectDeprecatedRaw       bool
 		expectDeprecatedImmutable bool
 		expectValidKey            bool
@@ -114,6 +115,11 @@ func TestParseURI(t *testing.T) {
 			expectURI:  &URI{Scheme: "bzz-hash"},
 			expectHash: true,
 		},
+		{
+			uri:          "bzz-verify:/abc123/path",
+			expectURI:    &URI{Scheme: "bzz-verify", Addr: "abc123", Path: "path"},
+			expectVerify: true,
+		},
 		{
 			uri:        "bzz-list:",
 			expectURI:  &URI{Scheme: "bzz-list"},
@@ -164,6 +170,9 @@ func TestParseURI(t *testing.T) {
 		if actual.Hash() != x.expectHash {
 			t.Fatalf("expected %s hash to be %t, got %t", x.uri, x.expectHash, actual.Hash())
 		}
+		if actual.Verify() != x.expectVerify {
+			t.Fatalf("expected %s verify to be %t, got %t", x.uri, x.expectVerify, actual.Verify())
+		}
 		if x.expectValidKey {
 			if actual.Address() == nil {
 				t.Fatalf("expected %s to return a valid key, got nil", x.uri)
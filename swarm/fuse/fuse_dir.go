@@ -21,10 +21,13 @@ package fuse
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"golang.org/x/net/context"
 )
@@ -36,6 +39,7 @@ var (
 	_ fs.NodeCreater         = (*SwarmDir)(nil)
 	_ fs.NodeRemover         = (*SwarmDir)(nil)
 	_ fs.NodeMkdirer         = (*SwarmDir)(nil)
+	_ fs.NodeSymlinker       = (*SwarmDir)(nil)
 )
 
 type SwarmDir struct {
@@ -44,6 +48,7 @@ type SwarmDir struct {
 	path        string
 	directories []*SwarmDir
 	files       []*SwarmFile
+	loaded      bool // entries are listed from the manifest on first access
 
 	mountInfo *MountInfo
 	lock      *sync.RWMutex
@@ -63,6 +68,55 @@ func NewSwarmDir(fullpath string, minfo *MountInfo) *SwarmDir {
 	return newdir
 }
 
+// load lists the entries of the directory from the latest manifest of the
+// mount, it is a no-op once the directory is loaded
+func (sd *SwarmDir) load() error {
+	sd.lock.Lock()
+	defer sd.lock.Unlock()
+	if sd.loaded {
+		return nil
+	}
+
+	prefix := strings.TrimPrefix(sd.path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	sd.mountInfo.lock.RLock()
+	addr := common.Hex2Bytes(sd.mountInfo.LatestManifest)
+	sd.mountInfo.lock.RUnlock()
+	list, err := sd.mountInfo.swarmApi.GetManifestList(addr, prefix)
+	if err != nil {
+		log.Warn("swarmfs could not list directory", "path", sd.path, "err", err)
+		return err
+	}
+	log.Debug("swarmfs loaded directory", "path", sd.path, "dirs", len(list.CommonPrefixes), "files", len(list.Entries))
+
+	seen := make(map[string]bool)
+	for _, p := range list.CommonPrefixes {
+		name := strings.TrimSuffix(p, "/")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		sd.directories = append(sd.directories, NewSwarmDir("/"+name, sd.mountInfo))
+	}
+	for _, entry := range list.Entries {
+		name := strings.TrimPrefix(entry.Path, prefix)
+		if name == "" || name == "/" {
+			// the default entry of the manifest has no file name
+			continue
+		}
+		file := NewSwarmFile(sd.path, name, sd.mountInfo)
+		file.addr = common.Hex2Bytes(entry.Hash)
+		file.mode = os.FileMode(entry.Mode)
+		file.modTime = entry.ModTime
+		file.linkTarget = entry.LinkTarget
+		sd.files = append(sd.files, file)
+	}
+	sd.loaded = true
+	return nil
+}
+
 func (sd *SwarmDir) Attr(ctx context.Context, a *fuse.Attr) error {
 	a.Inode = sd.inode
 	a.Mode = os.ModeDir | 0700
@@ -73,6 +127,9 @@ func (sd *SwarmDir) Attr(ctx context.Context, a *fuse.Attr) error {
 
 func (sd *SwarmDir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.LookupResponse) (fs.Node, error) {
 	log.Debug("swarmfs", "Lookup", req.Name)
+	if err := sd.load(); err != nil {
+		return nil, err
+	}
 	for _, n := range sd.files {
 		if n.name == req.Name {
 			return n, nil
@@ -88,9 +145,16 @@ func (sd *SwarmDir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *f
 
 func (sd *SwarmDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
 	log.Debug("swarmfs ReadDirAll")
+	if err := sd.load(); err != nil {
+		return nil, err
+	}
 	var children []fuse.Dirent
 	for _, file := range sd.files {
-		children = append(children, fuse.Dirent{Inode: file.inode, Type: fuse.DT_File, Name: file.name})
+		typ := fuse.DT_File
+		if file.linkTarget != "" {
+			typ = fuse.DT_Link
+		}
+		children = append(children, fuse.Dirent{Inode: file.inode, Type: typ, Name: file.name})
 	}
 	for _, dir := range sd.directories {
 		children = append(children, fuse.Dirent{Inode: dir.inode, Type: fuse.DT_Dir, Name: dir.name})
@@ -100,9 +164,14 @@ func (sd *SwarmDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
 
 func (sd *SwarmDir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
 	log.Debug("swarmfs Create", "path", sd.path, "req.Name", req.Name)
+	if err := sd.load(); err != nil {
+		return nil, nil, err
+	}
 
 	newFile := NewSwarmFile(sd.path, req.Name, sd.mountInfo)
 	newFile.fileSize = 0 // 0 means, file is not in swarm yet and it is just created
+	newFile.mode = req.Mode
+	newFile.modTime = time.Now()
 
 	sd.lock.Lock()
 	defer sd.lock.Unlock()
@@ -113,6 +182,9 @@ func (sd *SwarmDir) Create(ctx context.Context, req *fuse.CreateRequest, resp *f
 
 func (sd *SwarmDir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
 	log.Debug("swarmfs Remove", "path", sd.path, "req.Name", req.Name)
+	if err := sd.load(); err != nil {
+		return err
+	}
 
 	if req.Dir && sd.directories != nil {
 		newDirs := []*SwarmDir{}
@@ -150,10 +222,31 @@ func (sd *SwarmDir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
 
 func (sd *SwarmDir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error) {
 	log.Debug("swarmfs Mkdir", "path", sd.path, "req.Name", req.Name)
+	if err := sd.load(); err != nil {
+		return nil, err
+	}
 	newDir := NewSwarmDir(filepath.Join(sd.path, req.Name), sd.mountInfo)
+	newDir.loaded = true // a new directory has no entries in swarm
 	sd.lock.Lock()
 	defer sd.lock.Unlock()
 	sd.directories = append(sd.directories, newDir)
 
 	return newDir, nil
 }
+
+func (sd *SwarmDir) Symlink(ctx context.Context, req *fuse.SymlinkRequest) (fs.Node, error) {
+	log.Debug("swarmfs Symlink", "path", sd.path, "req.Name", req.NewName, "target", req.Target)
+	if err := sd.load(); err != nil {
+		return nil, err
+	}
+	newFile := NewSwarmFile(sd.path, req.NewName, sd.mountInfo)
+	if err := addSymlinkToSwarm(newFile, req.Target); err != nil {
+		return nil, err
+	}
+
+	sd.lock.Lock()
+	defer sd.lock.Unlock()
+	sd.files = append(sd.files, newFile)
+
+	return newFile, nil
+}
@@ -23,6 +23,9 @@ import (
 	"io"
 	"os"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
@@ -41,18 +44,24 @@ var (
 )
 
 var (
-	_ fs.Node         = (*SwarmFile)(nil)
-	_ fs.HandleReader = (*SwarmFile)(nil)
-	_ fs.HandleWriter = (*SwarmFile)(nil)
+	_ fs.Node           = (*SwarmFile)(nil)
+	_ fs.HandleReader   = (*SwarmFile)(nil)
+	_ fs.HandleWriter   = (*SwarmFile)(nil)
+	_ fs.NodeReadlinker = (*SwarmFile)(nil)
+	_ fs.NodeSetattrer  = (*SwarmFile)(nil)
 )
 
 type SwarmFile struct {
-	inode    uint64
-	name     string
-	path     string
-	addr     storage.Address
-	fileSize int64
-	reader   storage.LazySectionReader
+	inode      uint64
+	name       string
+	path       string
+	addr       storage.Address
+	fileSize   int64
+	reader     storage.LazySectionReader
+	mode       os.FileMode // mode from the manifest entry, 0 if unknown
+	modTime    time.Time
+	linkTarget string // target of a symbolic link
+	readEnd    int64  // end offset of the last read, accessed atomically
 
 	mountInfo *MountInfo
 	lock      *sync.RWMutex
@@ -76,11 +85,19 @@ func NewSwarmFile(path, fname string, minfo *MountInfo) *SwarmFile {
 func (sf *SwarmFile) Attr(ctx context.Context, a *fuse.Attr) error {
 	log.Debug("swarmfs Attr", "path", sf.path)
 	a.Inode = sf.inode
-	//TODO: need to get permission as argument
 	a.Mode = 0700
+	if sf.mode != 0 {
+		a.Mode = sf.mode
+	}
+	a.Mtime = sf.modTime
+	a.Crtime = sf.modTime // manifests do not record creation times, used by OS X only
 	a.Uid = uint32(os.Getuid())
 	a.Gid = uint32(os.Getegid())
 
+	if sf.linkTarget != "" {
+		a.Size = uint64(len(sf.linkTarget))
+		return nil
+	}
 	if sf.fileSize == -1 {
 		reader, _ := sf.mountInfo.swarmApi.Retrieve(sf.addr)
 		quitC := make(chan bool)
@@ -94,6 +111,37 @@ func (sf *SwarmFile) Attr(ctx context.Context, a *fuse.Attr) error {
 	return nil
 }
 
+// Readlink returns the target of a symbolic link
+func (sf *SwarmFile) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	if sf.linkTarget == "" {
+		return "", fuse.Errno(syscall.EINVAL)
+	}
+	return sf.linkTarget, nil
+}
+
+// Setattr updates the permissions and the modification time of the file,
+// other attributes are not stored in swarm
+func (sf *SwarmFile) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) error {
+	log.Debug("swarmfs Setattr", "path", sf.path, "req.String", req.String())
+	if req.Valid.Mode() || req.Valid.Mtime() {
+		mode := sf.mode
+		if mode == 0 {
+			mode = 0700
+		}
+		if req.Valid.Mode() {
+			mode = mode&^os.ModePerm | req.Mode.Perm()
+		}
+		modTime := sf.modTime
+		if req.Valid.Mtime() {
+			modTime = req.Mtime
+		}
+		if err := setFileAttrsInSwarm(sf, mode, modTime); err != nil {
+			return err
+		}
+	}
+	return sf.Attr(ctx, &resp.Attr)
+}
+
 func (sf *SwarmFile) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
 	log.Debug("swarmfs Read", "path", sf.path, "req.String", req.String())
 	sf.lock.RLock()
@@ -102,7 +150,15 @@ func (sf *SwarmFile) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse
 		sf.reader, _ = sf.mountInfo.swarmApi.Retrieve(sf.addr)
 	}
 	buf := make([]byte, req.Size)
-	n, err := sf.reader.ReadAt(buf, req.Offset)
+	var n int
+	var err error
+	if cache := sf.mountInfo.cache; cache != nil && sf.fileSize >= 0 {
+		// a read continuing where the previous one ended is sequential
+		sequential := atomic.SwapInt64(&sf.readEnd, req.Offset+int64(req.Size)) == req.Offset
+		n, err = cache.ReadAt(sf.addr, sf.reader, sf.fileSize, buf, req.Offset, sequential)
+	} else {
+		n, err = sf.reader.ReadAt(buf, req.Offset)
+	}
 	if err == io.ErrUnexpectedEOF || err == io.EOF {
 		err = nil
 	}
@@ -0,0 +1,108 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fuse
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MountTableFile is the name of the mount table file in the data directory
+const MountTableFile = "swarmfs-mounts.json"
+
+// mountRecord is the mount table entry of an active mount
+type mountRecord struct {
+	MountPoint     string    `json:"mountPoint"`
+	StartManifest  string    `json:"startManifest"`
+	LatestManifest string    `json:"latestManifest"`
+	Mounted        time.Time `json:"mounted"`
+}
+
+// mountTable persists the active mounts to a file, so that they can be
+// re-established when the node restarts
+type mountTable struct {
+	path string
+	lock sync.Mutex
+}
+
+func newMountTable(path string) *mountTable {
+	return &mountTable{path: path}
+}
+
+// load returns the records of the table, a missing file is an empty table
+func (t *mountTable) load() (map[string]*mountRecord, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	records := make(map[string]*mountRecord)
+	data, err := ioutil.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*mountRecord
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		records[r.MountPoint] = r
+	}
+	return records, nil
+}
+
+// save replaces the contents of the table with records
+// the file is written to a temporary file first and renamed, so that a
+// crash never leaves a truncated table behind
+func (t *mountTable) save(records map[string]*mountRecord) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	list := make([]*mountRecord, 0, len(records))
+	for _, r := range records {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].MountPoint < list[j].MountPoint })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// update loads the table, applies fn to the records and saves the result
+func (t *mountTable) update(fn func(records map[string]*mountRecord)) error {
+	records, err := t.load()
+	if err != nil {
+		return err
+	}
+	fn(records)
+	return t.save(records)
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fuse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMountTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarmfs-mounttable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	table := newMountTable(filepath.Join(dir, "data", MountTableFile))
+
+	// a missing table is empty
+	records, err := table.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("expected empty table, got %v", records)
+	}
+
+	err = table.update(func(records map[string]*mountRecord) {
+		records["/mnt/a"] = &mountRecord{MountPoint: "/mnt/a", StartManifest: "aa", LatestManifest: "ab"}
+		records["/mnt/b"] = &mountRecord{MountPoint: "/mnt/b", StartManifest: "ba", LatestManifest: "ba"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = table.update(func(records map[string]*mountRecord) {
+		delete(records, "/mnt/b")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err = newMountTable(table.path).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %v", records)
+	}
+	if r := records["/mnt/a"]; r == nil || r.StartManifest != "aa" || r.LatestManifest != "ab" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if _, err := os.Stat(table.path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("expected temporary file to be renamed, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fuse
+
+import (
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	readCacheBlockSize = 128 * 1024 // size of the cached blocks, the maximum size of a kernel read request
+
+	DefaultReadCacheSize = 64 // default read cache size in megabytes
+	DefaultReadahead     = 8  // default number of blocks read ahead of sequential reads
+)
+
+var (
+	readCacheHitCount   = metrics.NewRegisteredCounter("swarmfs.readcache.hit", nil)
+	readCacheMissCount  = metrics.NewRegisteredCounter("swarmfs.readcache.miss", nil)
+	readCacheAheadCount = metrics.NewRegisteredCounter("swarmfs.readcache.readahead", nil)
+)
+
+// blockKey identifies a block of the content at addr
+type blockKey struct {
+	addr  string
+	index int64
+}
+
+// blockFetch is a block retrieval in progress, concurrent reads of the
+// same block wait for the same retrieval
+type blockFetch struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// readCache is a cache of fixed size blocks of file contents shared by the
+// files of all mounts, content is addressed by hash so cached blocks never
+// need to be invalidated
+// reads which continue where the previous read of a file ended trigger the
+// background retrieval of the following blocks
+type readCache struct {
+	blocks    *lru.Cache
+	readahead int
+
+	lock    sync.Mutex
+	pending map[blockKey]*blockFetch
+}
+
+// newReadCache creates a cache of size megabytes reading ahead readahead
+// blocks, it returns nil if size is not positive, which disables caching
+func newReadCache(size, readahead int) *readCache {
+	n := size * 1024 * 1024 / readCacheBlockSize
+	if n <= 0 {
+		return nil
+	}
+	if readahead < 0 {
+		readahead = 0
+	}
+	if readahead > n/2 {
+		readahead = n / 2
+	}
+	blocks, _ := lru.New(n)
+	return &readCache{
+		blocks:    blocks,
+		readahead: readahead,
+		pending:   make(map[blockKey]*blockFetch),
+	}
+}
+
+// ReadAt reads len(p) bytes at off of the content at addr with size bytes
+// from the cache, missing blocks are retrieved from reader
+// if sequential is set, the blocks following the read are retrieved in
+// the background
+func (c *readCache) ReadAt(addr storage.Address, reader io.ReaderAt, size int64, p []byte, off int64, sequential bool) (int, error) {
+	if off >= size {
+		return 0, io.EOF
+	}
+	key := addr.Hex()
+	var n int
+	for n < len(p) && off+int64(n) < size {
+		pos := off + int64(n)
+		index := pos / readCacheBlockSize
+		data, err := c.block(blockKey{key, index}, reader)
+		if err != nil {
+			return n, err
+		}
+		start := int(pos - index*readCacheBlockSize)
+		if start >= len(data) {
+			break
+		}
+		n += copy(p[n:], data[start:])
+	}
+	if sequential && c.readahead > 0 {
+		last := (off + int64(n) - 1) / readCacheBlockSize
+		for i := last + 1; i <= last+int64(c.readahead) && i*readCacheBlockSize < size; i++ {
+			c.prefetch(blockKey{key, i}, reader)
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// block returns the block from the cache or retrieves it
+func (c *readCache) block(key blockKey, reader io.ReaderAt) ([]byte, error) {
+	if data, ok := c.blocks.Get(key); ok {
+		readCacheHitCount.Inc(1)
+		return data.([]byte), nil
+	}
+	readCacheMissCount.Inc(1)
+	f := c.fetch(key, reader)
+	<-f.done
+	return f.data, f.err
+}
+
+// prefetch retrieves the block in the background if it is not cached
+func (c *readCache) prefetch(key blockKey, reader io.ReaderAt) {
+	if c.blocks.Contains(key) {
+		return
+	}
+	c.lock.Lock()
+	_, ok := c.pending[key]
+	c.lock.Unlock()
+	if !ok {
+		readCacheAheadCount.Inc(1)
+		c.fetch(key, reader)
+	}
+}
+
+// fetch starts the retrieval of the block unless one is already running
+func (c *readCache) fetch(key blockKey, reader io.ReaderAt) *blockFetch {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if f, ok := c.pending[key]; ok {
+		return f
+	}
+	f := &blockFetch{done: make(chan struct{})}
+	c.pending[key] = f
+	go func() {
+		data := make([]byte, readCacheBlockSize)
+		n, err := reader.ReadAt(data, key.index*readCacheBlockSize)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		f.data, f.err = data[:n], err
+		if err == nil {
+			c.blocks.Add(key, f.data)
+		}
+		c.lock.Lock()
+		delete(c.pending, key)
+		c.lock.Unlock()
+		close(f.done)
+	}()
+	return f
+}
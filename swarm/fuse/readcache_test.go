@@ -0,0 +1,95 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fuse
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// countingReader counts the reads of the underlying reader
+type countingReader struct {
+	*bytes.Reader
+	reads int32
+}
+
+func (r *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	atomic.AddInt32(&r.reads, 1)
+	return r.Reader.ReadAt(p, off)
+}
+
+func TestReadCache(t *testing.T) {
+	if newReadCache(0, DefaultReadahead) != nil {
+		t.Fatal("expected a cache of size 0 to be disabled")
+	}
+
+	size := 5*readCacheBlockSize + 100
+	content := make([]byte, size)
+	rand.Read(content)
+	reader := &countingReader{Reader: bytes.NewReader(content)}
+	addr := storage.Address(make([]byte, 32))
+	cache := newReadCache(1, 2)
+
+	// reads spanning blocks return the content
+	buf := make([]byte, 4096)
+	off := int64(readCacheBlockSize - 100)
+	n, err := cache.ReadAt(addr, reader, int64(size), buf, off, false)
+	if err != nil || n != len(buf) {
+		t.Fatalf("expected %v bytes, got %v (%v)", len(buf), n, err)
+	}
+	if !bytes.Equal(buf, content[off:off+int64(n)]) {
+		t.Fatal("content mismatch")
+	}
+	if reads := atomic.LoadInt32(&reader.reads); reads != 2 {
+		t.Fatalf("expected 2 block reads, got %v", reads)
+	}
+
+	// cached blocks are not read again
+	if _, err := cache.ReadAt(addr, reader, int64(size), buf, off, false); err != nil {
+		t.Fatal(err)
+	}
+	if reads := atomic.LoadInt32(&reader.reads); reads != 2 {
+		t.Fatalf("expected no more block reads, got %v", reads)
+	}
+
+	// sequential reads retrieve the following blocks in the background
+	if _, err := cache.ReadAt(addr, reader, int64(size), buf, off, true); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !cache.blocks.Contains(blockKey{addr.Hex(), 3}) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for readahead")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cache.blocks.Contains(blockKey{addr.Hex(), 4}) {
+		t.Fatal("expected readahead to be limited to 2 blocks")
+	}
+
+	// reads at the end of the content are short
+	n, err = cache.ReadAt(addr, reader, int64(size), buf, int64(size-10), false)
+	if err != io.EOF || n != 10 || !bytes.Equal(buf[:n], content[size-10:]) {
+		t.Fatalf("expected 10 bytes and EOF, got %v (%v)", n, err)
+	}
+}
@@ -28,6 +28,8 @@ const (
 	mountTimeout    = time.Second * 5
 	unmountTimeout  = time.Second * 10
 	maxFuseMounts   = 5
+
+	mountCheckInterval = time.Second * 30 // interval between health checks of the active mounts
 )
 
 var (
@@ -42,6 +44,9 @@ type SwarmFS struct {
 	swarmApi     *api.Api
 	activeMounts map[string]*MountInfo
 	swarmFsLock  *sync.RWMutex
+	table        *mountTable   // persisted active mounts, nil if mounts are not restored
+	cache        *readCache    // cache of file contents read through the mounts, nil if disabled
+	quit         chan struct{} // closed on Stop to end the health checks
 }
 
 func NewSwarmFS(api *api.Api) *SwarmFS {
@@ -50,12 +55,31 @@ func NewSwarmFS(api *api.Api) *SwarmFS {
 			swarmApi:     api,
 			swarmFsLock:  &sync.RWMutex{},
 			activeMounts: map[string]*MountInfo{},
+			cache:        newReadCache(DefaultReadCacheSize, DefaultReadahead),
 		}
 	})
 	return swarmfs
 
 }
 
+// SetMountTable enables recording the active mounts in the file at path,
+// mounts found in the table are re-established by Start
+func (swarmfs *SwarmFS) SetMountTable(path string) {
+	swarmfs.swarmFsLock.Lock()
+	defer swarmfs.swarmFsLock.Unlock()
+	swarmfs.table = newMountTable(path)
+}
+
+// SetReadCache replaces the read cache of the mounts with a cache of size
+// megabytes which reads readahead blocks ahead of sequential reads, a size
+// of 0 disables caching
+// the cache is only used by mounts established after the call
+func (swarmfs *SwarmFS) SetReadCache(size, readahead int) {
+	swarmfs.swarmFsLock.Lock()
+	defer swarmfs.swarmFsLock.Unlock()
+	swarmfs.cache = newReadCache(size, readahead)
+}
+
 // Inode numbers need to be unique, they are used for caching inside fuse
 func NewInode() uint64 {
 	inodeLock.Lock()
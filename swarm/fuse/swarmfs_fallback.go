@@ -20,9 +20,14 @@ package fuse
 
 import (
 	"errors"
+	"runtime"
 )
 
-var errNoFUSE = errors.New("FUSE is not supported on this platform")
+// swarmfs mounts are only supported through the FUSE implementations of
+// Linux, FreeBSD and macOS (osxfuse). Windows is not supported: mounting
+// with WinFsp needs a cgofuse based implementation of the file system, which
+// is not part of this package.
+var errNoFUSE = errors.New("FUSE is not supported on " + runtime.GOOS + ", swarmfs mounts require Linux, FreeBSD or macOS")
 
 func isFUSEUnsupportedError(err error) bool {
 	return err == errNoFUSE
@@ -46,6 +51,8 @@ func (self *SwarmFS) Listmounts() ([]*MountInfo, error) {
 	return nil, errNoFUSE
 }
 
+func (self *SwarmFS) Start() {}
+
 func (self *SwarmFS) Stop() error {
 	return nil
 }
@@ -23,13 +23,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/swarm/api"
 )
@@ -42,6 +40,8 @@ var (
 	errAlreadyMounted       = errors.New("mount point is already serving")
 )
 
+const volumeNameLength = 8 // length of the manifest hash prefix in volume names shown in Finder
+
 func isFUSEUnsupportedError(err error) bool {
 	if perr, ok := err.(*os.PathError); ok {
 		return perr.Op == "open" && perr.Path == "/dev/fuse"
@@ -58,6 +58,8 @@ type MountInfo struct {
 	fuseConnection *fuse.Conn
 	swarmApi       *api.Api
 	lock           *sync.RWMutex
+	serveDone      chan struct{} // closed when the FUSE connection is no longer served
+	cache          *readCache
 }
 
 func NewMountInfo(mhash, mpoint string, sapi *api.Api) *MountInfo {
@@ -75,11 +77,18 @@ func NewMountInfo(mhash, mpoint string, sapi *api.Api) *MountInfo {
 }
 
 func (swarmfs *SwarmFS) Mount(mhash, mountpoint string) (*MountInfo, error) {
+	return swarmfs.mount(mhash, mhash, mountpoint)
+}
+
+// mount serves the manifest mhash at mountpoint, start is the manifest the
+// mount was originally started with, which differs from mhash when a mount
+// is re-established after changes were made to it
+func (swarmfs *SwarmFS) mount(start, mhash, mountpoint string) (*MountInfo, error) {
 	log.Info("swarmfs", "mounting hash", mhash, "mount point", mountpoint)
 	if mountpoint == "" {
 		return nil, errEmptyMountPoint
 	}
-	if !strings.HasPrefix(mountpoint, "/") {
+	if !filepath.IsAbs(mountpoint) {
 		return nil, errNoRelativeMountPoint
 	}
 	cleanedMountPoint, err := filepath.Abs(filepath.Clean(mountpoint))
@@ -101,50 +110,31 @@ func (swarmfs *SwarmFS) Mount(mhash, mountpoint string) (*MountInfo, error) {
 		return nil, errAlreadyMounted
 	}
 
-	log.Trace("swarmfs mount: getting manifest tree")
-	_, manifestEntryMap, err := swarmfs.swarmApi.BuildDirectoryTree(mhash, true)
+	uri, err := api.Parse("bzz:/" + mhash)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := swarmfs.swarmApi.Resolve(uri)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Trace("swarmfs mount: building mount info")
 	mi := NewMountInfo(mhash, cleanedMountPoint, swarmfs.swarmApi)
+	mi.StartManifest = start
+	mi.LatestManifest = addr.Hex()
+	mi.cache = swarmfs.cache
 
-	dirTree := map[string]*SwarmDir{}
+	// directories are loaded on first access, the root is loaded up front to
+	// check the manifest
 	rootDir := NewSwarmDir("/", mi)
 	log.Trace("swarmfs mount", "rootDir", rootDir)
 	mi.rootDir = rootDir
-
-	log.Trace("swarmfs mount: traversing manifest map")
-	for suffix, entry := range manifestEntryMap {
-		addr := common.Hex2Bytes(entry.Hash)
-		fullpath := "/" + suffix
-		basepath := filepath.Dir(fullpath)
-		parentDir := rootDir
-		dirUntilNow := ""
-		paths := strings.Split(basepath, "/")
-		for i := range paths {
-			if paths[i] != "" {
-				thisDir := paths[i]
-				dirUntilNow = dirUntilNow + "/" + thisDir
-
-				if _, ok := dirTree[dirUntilNow]; !ok {
-					dirTree[dirUntilNow] = NewSwarmDir(dirUntilNow, mi)
-					parentDir.directories = append(parentDir.directories, dirTree[dirUntilNow])
-					parentDir = dirTree[dirUntilNow]
-
-				} else {
-					parentDir = dirTree[dirUntilNow]
-				}
-			}
-		}
-		thisFile := NewSwarmFile(basepath, filepath.Base(fullpath), mi)
-		thisFile.addr = addr
-
-		parentDir.files = append(parentDir.files, thisFile)
+	if err := rootDir.load(); err != nil {
+		return nil, err
 	}
 
-	fconn, err := fuse.Mount(cleanedMountPoint, fuse.FSName("swarmfs"), fuse.VolumeName(mhash))
+	fconn, err := fuse.Mount(cleanedMountPoint, mountOptions(mhash)...)
 	if isFUSEUnsupportedError(err) {
 		log.Error("swarmfs error - FUSE not installed", "mountpoint", cleanedMountPoint, "err", err)
 		return nil, err
@@ -154,9 +144,11 @@ func (swarmfs *SwarmFS) Mount(mhash, mountpoint string) (*MountInfo, error) {
 		return nil, err
 	}
 	mi.fuseConnection = fconn
+	mi.serveDone = make(chan struct{})
 
 	serverr := make(chan error, 1)
 	go func() {
+		defer close(mi.serveDone)
 		log.Info("swarmfs", "serving hash", mhash, "at", cleanedMountPoint)
 		filesys := &SwarmRoot{root: rootDir}
 		if err := fs.Serve(fconn, filesys); err != nil {
@@ -182,10 +174,36 @@ func (swarmfs *SwarmFS) Mount(mhash, mountpoint string) (*MountInfo, error) {
 	}
 
 	swarmfs.activeMounts[cleanedMountPoint] = mi
+	swarmfs.record(mi)
 	return mi, nil
 }
 
+// mountOptions returns the FUSE options of a mount of mhash
+// the OS X only options show the mount as a local volume named after the
+// manifest in Finder, and refuse the AppleDouble files and extended
+// attributes Finder would otherwise store in swarm, other platforms
+// ignore them
+func mountOptions(mhash string) []fuse.MountOption {
+	name := mhash
+	if len(name) > volumeNameLength {
+		name = name[:volumeNameLength]
+	}
+	return []fuse.MountOption{
+		fuse.FSName("swarmfs"),
+		fuse.VolumeName("swarm-" + name),
+		fuse.LocalVolume(),
+		fuse.NoAppleDouble(),
+		fuse.NoAppleXattr(),
+	}
+}
+
 func (swarmfs *SwarmFS) Unmount(mountpoint string) (*MountInfo, error) {
+	return swarmfs.unmount(mountpoint, true)
+}
+
+// unmount unmounts mountpoint and removes it from the mount table if
+// forget is set, mounts that are not forgotten are restored on restart
+func (swarmfs *SwarmFS) unmount(mountpoint string, forget bool) (*MountInfo, error) {
 	swarmfs.swarmFsLock.Lock()
 	defer swarmfs.swarmFsLock.Unlock()
 
@@ -211,6 +229,11 @@ func (swarmfs *SwarmFS) Unmount(mountpoint string) (*MountInfo, error) {
 
 	mountInfo.fuseConnection.Close()
 	delete(swarmfs.activeMounts, cleanedMountPoint)
+	if forget {
+		swarmfs.forget(cleanedMountPoint)
+	} else {
+		swarmfs.record(mountInfo)
+	}
 
 	succString := fmt.Sprintf("swarmfs unmounting %v succeeded", cleanedMountPoint)
 	log.Info(succString)
@@ -229,10 +252,167 @@ func (swarmfs *SwarmFS) Listmounts() []*MountInfo {
 	return rows
 }
 
+// Start re-establishes the mounts recorded in the mount table and starts
+// the periodic health checks of the active mounts
+func (swarmfs *SwarmFS) Start() {
+	swarmfs.swarmFsLock.Lock()
+	defer swarmfs.swarmFsLock.Unlock()
+
+	if swarmfs.quit != nil {
+		return
+	}
+	swarmfs.quit = make(chan struct{})
+	go func() {
+		swarmfs.restoreMounts()
+		swarmfs.checkMountsLoop(swarmfs.quit)
+	}()
+}
+
 func (swarmfs *SwarmFS) Stop() bool {
-	for mp := range swarmfs.activeMounts {
-		mountInfo := swarmfs.activeMounts[mp]
-		swarmfs.Unmount(mountInfo.MountPoint)
+	swarmfs.swarmFsLock.Lock()
+	if swarmfs.quit != nil {
+		close(swarmfs.quit)
+		swarmfs.quit = nil
+	}
+	swarmfs.swarmFsLock.Unlock()
+
+	// mounts unmounted on shutdown stay in the mount table to be restored
+	for _, mountInfo := range swarmfs.Listmounts() {
+		swarmfs.unmount(mountInfo.MountPoint, false)
 	}
 	return true
 }
+
+// record writes the mount to the mount table
+// caller must hold the swarmfs lock
+func (swarmfs *SwarmFS) record(mi *MountInfo) {
+	if swarmfs.table == nil {
+		return
+	}
+	err := swarmfs.table.update(func(records map[string]*mountRecord) {
+		r := records[mi.MountPoint]
+		if r == nil || r.StartManifest != mi.StartManifest {
+			r = &mountRecord{
+				MountPoint:    mi.MountPoint,
+				StartManifest: mi.StartManifest,
+				Mounted:       time.Now(),
+			}
+			records[mi.MountPoint] = r
+		}
+		r.LatestManifest = mi.LatestManifest
+	})
+	if err != nil {
+		log.Warn("swarmfs could not record mount", "mountpoint", mi.MountPoint, "err", err)
+	}
+}
+
+// forget removes the mount point from the mount table
+// caller must hold the swarmfs lock
+func (swarmfs *SwarmFS) forget(mountpoint string) {
+	if swarmfs.table == nil {
+		return
+	}
+	err := swarmfs.table.update(func(records map[string]*mountRecord) {
+		delete(records, mountpoint)
+	})
+	if err != nil {
+		log.Warn("swarmfs could not update mount table", "mountpoint", mountpoint, "err", err)
+	}
+}
+
+// restoreMounts re-establishes the mounts of the mount table from their
+// latest manifests, stale mounts left behind by a previous run are
+// cleaned up first and records of removed mount points are dropped
+func (swarmfs *SwarmFS) restoreMounts() {
+	swarmfs.swarmFsLock.RLock()
+	table := swarmfs.table
+	swarmfs.swarmFsLock.RUnlock()
+	if table == nil {
+		return
+	}
+	records, err := table.load()
+	if err != nil {
+		log.Error("swarmfs could not load mount table", "path", table.path, "err", err)
+		return
+	}
+	for mp, r := range records {
+		if _, err := os.Lstat(mp); os.IsNotExist(err) {
+			log.Warn("swarmfs dropping mount of removed mount point", "mountpoint", mp)
+			swarmfs.swarmFsLock.Lock()
+			swarmfs.forget(mp)
+			swarmfs.swarmFsLock.Unlock()
+			continue
+		}
+		cleanStaleMount(mp)
+		if _, err := swarmfs.mount(r.StartManifest, r.LatestManifest, mp); err != nil {
+			log.Error("swarmfs could not restore mount", "mountpoint", mp, "manifest", r.LatestManifest, "err", err)
+			continue
+		}
+		log.Info("swarmfs restored mount", "mountpoint", mp, "manifest", r.LatestManifest)
+	}
+}
+
+// checkMountsLoop runs the health checks of the active mounts until quit
+// is closed
+func (swarmfs *SwarmFS) checkMountsLoop(quit chan struct{}) {
+	ticker := time.NewTicker(mountCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			swarmfs.checkMounts()
+		}
+	}
+}
+
+// checkMounts records the latest manifests of the active mounts and
+// re-establishes the mounts which are no longer served
+func (swarmfs *SwarmFS) checkMounts() {
+	for _, mi := range swarmfs.Listmounts() {
+		err := mi.healthy()
+		if err == nil {
+			swarmfs.swarmFsLock.Lock()
+			swarmfs.record(mi)
+			swarmfs.swarmFsLock.Unlock()
+			continue
+		}
+		log.Warn("swarmfs mount is not healthy, remounting", "mountpoint", mi.MountPoint, "err", err)
+		swarmfs.swarmFsLock.Lock()
+		mi.fuseConnection.Close()
+		delete(swarmfs.activeMounts, mi.MountPoint)
+		swarmfs.swarmFsLock.Unlock()
+
+		cleanStaleMount(mi.MountPoint)
+		if _, err := swarmfs.mount(mi.StartManifest, mi.LatestManifest, mi.MountPoint); err != nil {
+			log.Error("swarmfs could not remount", "mountpoint", mi.MountPoint, "err", err)
+		}
+	}
+}
+
+// healthy checks that the mount is still served and its mount point
+// responds
+func (mi *MountInfo) healthy() error {
+	select {
+	case <-mi.serveDone:
+		return errors.New("FUSE connection closed")
+	default:
+	}
+	_, err := os.Stat(mi.MountPoint)
+	return err
+}
+
+// cleanStaleMount unmounts mountpoint if it is a mount which is no longer
+// served, such as one left behind by a crashed node
+func cleanStaleMount(mountpoint string) {
+	if _, err := os.Stat(mountpoint); err == nil {
+		return
+	}
+	log.Info("swarmfs cleaning up stale mount", "mountpoint", mountpoint)
+	if err := fuse.Unmount(mountpoint); err != nil {
+		if err := externalUnmount(mountpoint); err != nil {
+			log.Warn("swarmfs could not clean up stale mount", "mountpoint", mountpoint, "err", err)
+		}
+	}
+}
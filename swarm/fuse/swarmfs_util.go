@@ -21,8 +21,10 @@ package fuse
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -51,11 +53,15 @@ func addFileToSwarm(sf *SwarmFile, content []byte, size int) error {
 	if err != nil {
 		return err
 	}
+	if mhash, err = keepFileMode(sf, mhash); err != nil {
+		return err
+	}
 
 	sf.lock.Lock()
 	defer sf.lock.Unlock()
 	sf.addr = fkey
 	sf.fileSize = int64(size)
+	sf.modTime = time.Now()
 
 	sf.mountInfo.lock.Lock()
 	defer sf.mountInfo.lock.Unlock()
@@ -65,6 +71,56 @@ func addFileToSwarm(sf *SwarmFile, content []byte, size int) error {
 	return nil
 }
 
+func addSymlinkToSwarm(sf *SwarmFile, target string) error {
+	fkey, mhash, err := sf.mountInfo.swarmApi.AddSymlink(sf.mountInfo.LatestManifest, sf.path, sf.name, target, true)
+	if err != nil {
+		return err
+	}
+
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	sf.addr = fkey
+	sf.fileSize = int64(len(target))
+	sf.mode = os.ModeSymlink | 0777
+	sf.modTime = time.Now()
+	sf.linkTarget = target
+
+	sf.mountInfo.lock.Lock()
+	defer sf.mountInfo.lock.Unlock()
+	sf.mountInfo.LatestManifest = mhash
+
+	log.Info("swarmfs added new symlink:", "fname", sf.name, "target", target, "new Manifest hash", mhash)
+	return nil
+}
+
+// keepFileMode sets the permissions of the file in the manifest written
+// with the default permissions of new content
+func keepFileMode(sf *SwarmFile, mhash string) (string, error) {
+	if sf.mode == 0 || sf.mode.Perm() == 0700 {
+		return mhash, nil
+	}
+	return sf.mountInfo.swarmApi.SetFileAttrs(mhash, sf.path, sf.name, sf.mode, time.Now(), true)
+}
+
+func setFileAttrsInSwarm(sf *SwarmFile, mode os.FileMode, modTime time.Time) error {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	// files not yet written to swarm keep the attributes until they are
+	if sf.addr != nil {
+		mhash, err := sf.mountInfo.swarmApi.SetFileAttrs(sf.mountInfo.LatestManifest, sf.path, sf.name, mode, modTime, true)
+		if err != nil {
+			return err
+		}
+		sf.mountInfo.lock.Lock()
+		sf.mountInfo.LatestManifest = mhash
+		sf.mountInfo.lock.Unlock()
+		log.Info("swarmfs set file attributes:", "fname", sf.name, "mode", mode, "new Manifest hash", mhash)
+	}
+	sf.mode = mode
+	sf.modTime = modTime
+	return nil
+}
+
 func removeFileFromSwarm(sf *SwarmFile) error {
 	mkey, err := sf.mountInfo.swarmApi.RemoveFile(sf.mountInfo.LatestManifest, sf.path, sf.name, true)
 	if err != nil {
@@ -80,6 +136,9 @@ func removeFileFromSwarm(sf *SwarmFile) error {
 }
 
 func removeDirectoryFromSwarm(sd *SwarmDir) error {
+	if err := sd.load(); err != nil {
+		return err
+	}
 	if len(sd.directories) == 0 && len(sd.files) == 0 {
 		return nil
 	}
@@ -106,11 +165,15 @@ func appendToExistingFileInSwarm(sf *SwarmFile, content []byte, offset int64, le
 	if err != nil {
 		return err
 	}
+	if mhash, err = keepFileMode(sf, mhash); err != nil {
+		return err
+	}
 
 	sf.lock.Lock()
 	defer sf.lock.Unlock()
 	sf.addr = fkey
 	sf.fileSize = sf.fileSize + int64(len(content))
+	sf.modTime = time.Now()
 
 	sf.mountInfo.lock.Lock()
 	defer sf.mountInfo.lock.Unlock()
@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	httpapi "github.com/ethereum/go-ethereum/swarm/api/http"
+)
+
+// ensHealthTimeout is the time an ENS API has to respond to a health check
+const ensHealthTimeout = 5 * time.Second
+
+// healthChecks returns the checks of the /health endpoint of the gateway,
+// which fail if the node does not work
+func (self *Swarm) healthChecks() []httpapi.HealthCheck {
+	return []httpapi.HealthCheck{
+		{Name: "store", Check: self.lstore.DbStore.Check},
+	}
+}
+
+// readyChecks returns the additional checks of the /ready endpoint of the
+// gateway, which fail if the node cannot serve content from the network
+func (self *Swarm) readyChecks() []httpapi.HealthCheck {
+	checks := []httpapi.HealthCheck{
+		{Name: "kademlia", Check: self.checkKademlia},
+		{Name: "sync", Check: self.checkSync},
+	}
+	for endpoint, client := range self.ensClients {
+		client := client
+		checks = append(checks, httpapi.HealthCheck{
+			Name: "ens:" + endpoint,
+			Check: func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), ensHealthTimeout)
+				defer cancel()
+				_, err := client.HeaderByNumber(ctx, nil)
+				return err
+			},
+		})
+	}
+	return checks
+}
+
+// checkKademlia fails if the node has no peers or the partition watchdog
+// reports it is partitioned from the network
+func (self *Swarm) checkKademlia() error {
+	if self.kad.Status().Connected == 0 {
+		return errors.New("no connected peers")
+	}
+	if status := self.partition.Status(); status.Partitioned {
+		return fmt.Errorf("partitioned: %s", strings.Join(status.Reasons, ", "))
+	}
+	return nil
+}
+
+// checkSync fails if syncing is enabled but the node syncs with no peers
+func (self *Swarm) checkSync() error {
+	status := self.streamer.SyncStatus()
+	if !status.Enabled {
+		return nil
+	}
+	if status.Syncing == 0 {
+		return fmt.Errorf("not syncing with any of %d peers", status.Peers)
+	}
+	return nil
+}
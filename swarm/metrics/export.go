@@ -0,0 +1,206 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	gethmetrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultExportInterval is the default interval of the metrics export
+	DefaultExportInterval = 10 * time.Second
+	// MinExportInterval is the shortest interval the export can be set to
+	MinExportInterval = time.Second
+)
+
+// reporter pushes the metrics of the default registry to an external
+// system on each call of Send
+type reporter interface {
+	Send() error
+}
+
+// exporter pushes the metrics to the reporters set up from the
+// configuration at an interval which can be changed at runtime
+type exporter struct {
+	mu        sync.Mutex
+	reporters map[string]reporter // by name, e.g. influxdb
+	interval  time.Duration
+	enabled   bool
+	resetC    chan struct{} // signals a change of the interval
+	started   bool
+}
+
+var export = &exporter{
+	reporters: make(map[string]reporter),
+	interval:  DefaultExportInterval,
+	enabled:   true,
+	resetC:    make(chan struct{}, 1),
+}
+
+// start starts pushing the metrics to the reporters
+func (e *exporter) start(reporters map[string]reporter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name, r := range reporters {
+		e.reporters[name] = r
+	}
+	if !e.started && len(e.reporters) > 0 {
+		e.started = true
+		go e.loop()
+	}
+}
+
+func (e *exporter) loop() {
+	for {
+		e.mu.Lock()
+		timer := time.NewTimer(e.interval)
+		e.mu.Unlock()
+		select {
+		case <-timer.C:
+			e.send()
+		case <-e.resetC:
+			timer.Stop()
+		}
+	}
+}
+
+func (e *exporter) send() {
+	e.mu.Lock()
+	if !e.enabled {
+		e.mu.Unlock()
+		return
+	}
+	reporters := make(map[string]reporter, len(e.reporters))
+	for name, r := range e.reporters {
+		reporters[name] = r
+	}
+	e.mu.Unlock()
+
+	for name, r := range reporters {
+		if err := r.Send(); err != nil {
+			log.Warn("Failed to export metrics", "reporter", name, "err", err)
+		}
+	}
+}
+
+// SetExportInterval sets the interval of the metrics export
+func SetExportInterval(interval time.Duration) error {
+	if interval < MinExportInterval {
+		return fmt.Errorf("export interval %v is shorter than %v", interval, MinExportInterval)
+	}
+	export.mu.Lock()
+	export.interval = interval
+	export.mu.Unlock()
+	select {
+	case export.resetC <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// SetExport pauses or resumes the metrics export
+func SetExport(enabled bool) {
+	export.mu.Lock()
+	defer export.mu.Unlock()
+	export.enabled = enabled
+}
+
+var (
+	collectionMu sync.Mutex
+	// stash holds the metrics which were unregistered when the collection
+	// was disabled, so that they keep their values when it is enabled again
+	stash map[string]interface{}
+)
+
+// SetCollection enables or disables the collection of metrics at runtime.
+//
+// Metrics are created as no-ops while the collection is disabled. Disabling
+// the collection unregisters the metrics, so that the metrics looked up by
+// name afterwards are no-ops. Enabling it unregisters the no-op metrics and
+// registers the previous ones again. Meters and timers are left registered,
+// since they stop for good when they are unregistered.
+func SetCollection(enabled bool) {
+	collectionMu.Lock()
+	defer collectionMu.Unlock()
+	if enabled == gethmetrics.Enabled {
+		return
+	}
+	reg := gethmetrics.DefaultRegistry
+	if !enabled {
+		gethmetrics.Enabled = false
+		stash = make(map[string]interface{})
+		reg.Each(func(name string, metric interface{}) {
+			switch metric.(type) {
+			case gethmetrics.Meter, gethmetrics.Timer:
+				return
+			}
+			stash[name] = metric
+			reg.Unregister(name)
+		})
+		log.Info("Disabled metrics collection")
+		return
+	}
+	gethmetrics.Enabled = true
+	reg.Each(func(name string, metric interface{}) {
+		switch metric.(type) {
+		case gethmetrics.NilCounter, gethmetrics.NilGauge, gethmetrics.NilGaugeFloat64, gethmetrics.NilHistogram,
+			gethmetrics.NilMeter, gethmetrics.NilTimer, gethmetrics.NilResettingTimer:
+			reg.Unregister(name)
+		}
+	})
+	for name, metric := range stash {
+		reg.Unregister(name)
+		reg.Register(name, metric)
+	}
+	stash = nil
+	log.Info("Enabled metrics collection")
+}
+
+// Status is the state of the metrics collection and export
+type Status struct {
+	Collecting bool     `json:"collecting"`
+	Exporting  bool     `json:"exporting"`
+	Interval   string   `json:"interval"`
+	Reporters  []string `json:"reporters"`
+}
+
+// GetStatus returns the state of the metrics collection and export
+func GetStatus() *Status {
+	collectionMu.Lock()
+	collecting := gethmetrics.Enabled
+	collectionMu.Unlock()
+
+	export.mu.Lock()
+	defer export.mu.Unlock()
+	status := &Status{
+		Collecting: collecting,
+		Exporting:  export.enabled,
+		Interval:   export.interval.String(),
+		Reporters:  []string{},
+	}
+	for name := range export.reporters {
+		status.Reporters = append(status.Reporters, name)
+	}
+	sort.Strings(status.Reporters)
+	return status
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"testing"
+
+	gethmetrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestSetCollection(t *testing.T) {
+	gethmetrics.Enabled = true
+	defer SetCollection(false)
+
+	counter := gethmetrics.GetOrRegisterCounter("test.collection.count", nil)
+	counter.Inc(3)
+
+	// metrics are no-ops while the collection is disabled
+	SetCollection(false)
+	if status := GetStatus(); status.Collecting {
+		t.Fatal("expected collection to be disabled")
+	}
+	gethmetrics.GetOrRegisterCounter("test.collection.count", nil).Inc(1)
+	gethmetrics.GetOrRegisterCounter("test.collection.other", nil).Inc(1)
+
+	// the metrics keep their values when the collection is enabled again,
+	// and the no-op metrics are replaced
+	SetCollection(true)
+	if count := gethmetrics.GetOrRegisterCounter("test.collection.count", nil).Count(); count != 3 {
+		t.Fatalf("expected count 3, got %d", count)
+	}
+	other := gethmetrics.GetOrRegisterCounter("test.collection.other", nil)
+	other.Inc(2)
+	if count := other.Count(); count != 2 {
+		t.Fatalf("expected count 2, got %d", count)
+	}
+}
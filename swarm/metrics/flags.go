@@ -17,15 +17,42 @@
 package metrics
 
 import (
-	"time"
-
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/log"
 	gethmetrics "github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/metrics/influxdb"
+	"github.com/ethereum/go-ethereum/metrics/statsd"
 	"gopkg.in/urfave/cli.v1"
 )
 
+// Config holds the settings of the metrics export to InfluxDB, StatsD and
+// Graphite. Metrics collection itself is enabled with the --metrics flag,
+// which is read when the process starts.
+type Config struct {
+	InfluxDBExport   bool
+	InfluxDBEndpoint string
+	InfluxDBDatabase string
+	InfluxDBUsername string
+	InfluxDBPassword string
+	InfluxDBHostTag  string
+
+	StatsDExport     bool
+	StatsDEndpoint   string // UDP address of the StatsD daemon
+	GraphiteExport   bool
+	GraphiteEndpoint string // TCP address of the carbon plaintext listener
+	Prefix           string // prefix of the metric names pushed to StatsD and Graphite
+}
+
+// DefaultConfig contains the default metrics export settings
+var DefaultConfig = Config{
+	InfluxDBEndpoint: "http://127.0.0.1:8086",
+	InfluxDBDatabase: "metrics",
+	InfluxDBHostTag:  "localhost",
+	StatsDEndpoint:   "127.0.0.1:8125",
+	GraphiteEndpoint: "127.0.0.1:2003",
+	Prefix:           "swarm.",
+}
+
 var (
 	metricsEnableInfluxDBExportFlag = cli.BoolFlag{
 		Name:  "metrics.influxdb.export",
@@ -34,22 +61,22 @@ var (
 	metricsInfluxDBEndpointFlag = cli.StringFlag{
 		Name:  "metrics.influxdb.endpoint",
 		Usage: "Metrics InfluxDB endpoint",
-		Value: "http://127.0.0.1:8086",
+		Value: DefaultConfig.InfluxDBEndpoint,
 	}
 	metricsInfluxDBDatabaseFlag = cli.StringFlag{
 		Name:  "metrics.influxdb.database",
 		Usage: "Metrics InfluxDB database",
-		Value: "metrics",
+		Value: DefaultConfig.InfluxDBDatabase,
 	}
 	metricsInfluxDBUsernameFlag = cli.StringFlag{
 		Name:  "metrics.influxdb.username",
 		Usage: "Metrics InfluxDB username",
-		Value: "",
+		Value: DefaultConfig.InfluxDBUsername,
 	}
 	metricsInfluxDBPasswordFlag = cli.StringFlag{
 		Name:  "metrics.influxdb.password",
 		Usage: "Metrics InfluxDB password",
-		Value: "",
+		Value: DefaultConfig.InfluxDBPassword,
 	}
 	// The `host` tag is part of every measurement sent to InfluxDB. Queries on tags are faster in InfluxDB.
 	// It is used so that we can group all nodes and average a measurement across all of them, but also so
@@ -58,7 +85,32 @@ var (
 	metricsInfluxDBHostTagFlag = cli.StringFlag{
 		Name:  "metrics.influxdb.host.tag",
 		Usage: "Metrics InfluxDB `host` tag attached to all measurements",
-		Value: "localhost",
+		Value: DefaultConfig.InfluxDBHostTag,
+	}
+	metricsEnableStatsDExportFlag = cli.BoolFlag{
+		Name:  "metrics.statsd.export",
+		Usage: "Enable metrics export/push to an external StatsD daemon",
+	}
+	metricsStatsDEndpointFlag = cli.StringFlag{
+		Name:  "metrics.statsd.endpoint",
+		Usage: "Metrics StatsD UDP endpoint",
+		Value: DefaultConfig.StatsDEndpoint,
+	}
+	metricsEnableGraphiteExportFlag = cli.BoolFlag{
+		Name:  "metrics.graphite.export",
+		Usage: "Enable metrics export/push to an external Graphite carbon server",
+	}
+	metricsGraphiteEndpointFlag = cli.StringFlag{
+		Name:  "metrics.graphite.endpoint",
+		Usage: "Metrics Graphite carbon plaintext TCP endpoint",
+		Value: DefaultConfig.GraphiteEndpoint,
+	}
+	// The prefix distinguishes the metrics of the nodes pushing to the same
+	// StatsD daemon or Graphite server, e.g. swarm.node7.
+	metricsPrefixFlag = cli.StringFlag{
+		Name:  "metrics.prefix",
+		Usage: "Metrics name prefix for the StatsD and Graphite export",
+		Value: DefaultConfig.Prefix,
 	}
 )
 
@@ -67,25 +119,74 @@ var Flags = []cli.Flag{
 	utils.MetricsEnabledFlag,
 	metricsEnableInfluxDBExportFlag,
 	metricsInfluxDBEndpointFlag, metricsInfluxDBDatabaseFlag, metricsInfluxDBUsernameFlag, metricsInfluxDBPasswordFlag, metricsInfluxDBHostTagFlag,
+	metricsEnableStatsDExportFlag, metricsStatsDEndpointFlag,
+	metricsEnableGraphiteExportFlag, metricsGraphiteEndpointFlag,
+	metricsPrefixFlag,
 }
 
-func Setup(ctx *cli.Context) {
+// SetConfig applies the metrics flags set on the command line to cfg
+func SetConfig(ctx *cli.Context, cfg *Config) {
+	if ctx.GlobalIsSet(metricsEnableInfluxDBExportFlag.Name) {
+		cfg.InfluxDBExport = ctx.GlobalBool(metricsEnableInfluxDBExportFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsInfluxDBEndpointFlag.Name) {
+		cfg.InfluxDBEndpoint = ctx.GlobalString(metricsInfluxDBEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsInfluxDBDatabaseFlag.Name) {
+		cfg.InfluxDBDatabase = ctx.GlobalString(metricsInfluxDBDatabaseFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsInfluxDBUsernameFlag.Name) {
+		cfg.InfluxDBUsername = ctx.GlobalString(metricsInfluxDBUsernameFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsInfluxDBPasswordFlag.Name) {
+		cfg.InfluxDBPassword = ctx.GlobalString(metricsInfluxDBPasswordFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsInfluxDBHostTagFlag.Name) {
+		cfg.InfluxDBHostTag = ctx.GlobalString(metricsInfluxDBHostTagFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsEnableStatsDExportFlag.Name) {
+		cfg.StatsDExport = ctx.GlobalBool(metricsEnableStatsDExportFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsStatsDEndpointFlag.Name) {
+		cfg.StatsDEndpoint = ctx.GlobalString(metricsStatsDEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsEnableGraphiteExportFlag.Name) {
+		cfg.GraphiteExport = ctx.GlobalBool(metricsEnableGraphiteExportFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsGraphiteEndpointFlag.Name) {
+		cfg.GraphiteEndpoint = ctx.GlobalString(metricsGraphiteEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(metricsPrefixFlag.Name) {
+		cfg.Prefix = ctx.GlobalString(metricsPrefixFlag.Name)
+	}
+}
+
+// Setup starts the metrics export to the reporters enabled in cfg if metrics
+// collection is enabled. The export interval and state can be changed at
+// runtime with SetExportInterval and SetExport.
+func Setup(cfg *Config) {
 	if gethmetrics.Enabled {
 		log.Info("Enabling swarm metrics collection")
-		var (
-			enableExport = ctx.GlobalBool(metricsEnableInfluxDBExportFlag.Name)
-			endpoint     = ctx.GlobalString(metricsInfluxDBEndpointFlag.Name)
-			database     = ctx.GlobalString(metricsInfluxDBDatabaseFlag.Name)
-			username     = ctx.GlobalString(metricsInfluxDBUsernameFlag.Name)
-			password     = ctx.GlobalString(metricsInfluxDBPasswordFlag.Name)
-			hosttag      = ctx.GlobalString(metricsInfluxDBHostTagFlag.Name)
-		)
-
-		if enableExport {
+		reporters := make(map[string]reporter)
+		if cfg.InfluxDBExport {
 			log.Info("Enabling swarm metrics export to InfluxDB")
-			go influxdb.InfluxDBWithTags(gethmetrics.DefaultRegistry, 10*time.Second, endpoint, database, username, password, "swarm.", map[string]string{
-				"host": hosttag,
+			r, err := influxdb.NewReporter(gethmetrics.DefaultRegistry, cfg.InfluxDBEndpoint, cfg.InfluxDBDatabase, cfg.InfluxDBUsername, cfg.InfluxDBPassword, "swarm.", map[string]string{
+				"host": cfg.InfluxDBHostTag,
 			})
+			if err != nil {
+				log.Error("Unable to export metrics to InfluxDB", "endpoint", cfg.InfluxDBEndpoint, "err", err)
+			} else {
+				reporters["influxdb"] = r
+			}
+		}
+		if cfg.StatsDExport {
+			log.Info("Enabling swarm metrics export to StatsD", "endpoint", cfg.StatsDEndpoint)
+			reporters["statsd"] = statsd.NewStatsDReporter(gethmetrics.DefaultRegistry, cfg.StatsDEndpoint, cfg.Prefix)
+		}
+		if cfg.GraphiteExport {
+			log.Info("Enabling swarm metrics export to Graphite", "endpoint", cfg.GraphiteEndpoint)
+			reporters["graphite"] = statsd.NewGraphiteReporter(gethmetrics.DefaultRegistry, cfg.GraphiteEndpoint, cfg.Prefix)
 		}
+		export.start(reporters)
 	}
 }
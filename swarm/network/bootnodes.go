@@ -0,0 +1,289 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+/*
+Bootnodes fetches the list of swarm bootnodes from a remote source and keeps
+the node connected to the nodes on the latest list, so that bootstrap
+infrastructure can be rotated without a new release.
+
+The list is signed by a key known to the node and is published either
+* over HTTP(S) as a JSON encoded BootnodeList, or
+* in the TXT records of a domain (dns://<domain>), with one record for each
+  enode URL and one record of the form "seq=<seq> sig=<hex signature>"
+*/
+
+var (
+	errBootnodeListSignature = errors.New("invalid bootnode list signature")
+	errBootnodeListOld       = errors.New("bootnode list is older than the current one")
+)
+
+// BootnodeList is a signed list of bootnode enode URLs
+// Seq must increase with every new list published by the source
+type BootnodeList struct {
+	Seq   uint64        `json:"seq"`
+	Nodes []string      `json:"nodes"`
+	Sig   hexutil.Bytes `json:"sig"`
+}
+
+// sigHash returns the hash of the list that is signed
+// the nodes are sorted as DNS does not preserve the order of records
+func (l *BootnodeList) sigHash() []byte {
+	nodes := append([]string{}, l.Nodes...)
+	sort.Strings(nodes)
+	data, _ := rlp.EncodeToBytes([]interface{}{l.Seq, nodes})
+	return crypto.Keccak256(data)
+}
+
+// Sign signs the list with the private key
+func (l *BootnodeList) Sign(key *ecdsa.PrivateKey) (err error) {
+	l.Sig, err = crypto.Sign(l.sigHash(), key)
+	return err
+}
+
+// Verify checks that the list is signed with the private key of pubkey
+func (l *BootnodeList) Verify(pubkey *ecdsa.PublicKey) error {
+	if len(l.Sig) != 65 {
+		return errBootnodeListSignature
+	}
+	if !crypto.VerifySignature(crypto.CompressPubkey(pubkey), l.sigHash(), l.Sig[:64]) {
+		return errBootnodeListSignature
+	}
+	return nil
+}
+
+// BootnodesParams holds the config options of Bootnodes
+type BootnodesParams struct {
+	URL             string           // http(s):// or dns:// location of the list
+	PubKey          *ecdsa.PublicKey // key the list must be signed with
+	RefreshInterval time.Duration    // interval between list fetches
+}
+
+// NewBootnodesParams returns bootnodes params with default values
+func NewBootnodesParams() *BootnodesParams {
+	return &BootnodesParams{
+		RefreshInterval: time.Hour,
+	}
+}
+
+// Bootnodes keeps the node connected to the bootnodes of a remote signed list
+type Bootnodes struct {
+	*BootnodesParams
+	fetch      func() (*BootnodeList, error)
+	addPeer    func(*discover.Node)
+	removePeer func(*discover.Node)
+
+	mu    sync.Mutex
+	seq   uint64
+	nodes map[discover.NodeID]*discover.Node
+	quit  chan struct{}
+}
+
+// NewBootnodes is the Bootnodes constructor
+func NewBootnodes(params *BootnodesParams) (*Bootnodes, error) {
+	if params.PubKey == nil {
+		return nil, errors.New("bootnode list public key not set")
+	}
+	if params.RefreshInterval <= 0 {
+		return nil, fmt.Errorf("invalid bootnode list refresh interval %v", params.RefreshInterval)
+	}
+	b := &Bootnodes{
+		BootnodesParams: params,
+		nodes:           make(map[discover.NodeID]*discover.Node),
+	}
+	u, err := url.Parse(params.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bootnode list url %q: %v", params.URL, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		b.fetch = func() (*BootnodeList, error) { return fetchBootnodesHTTP(params.URL) }
+	case "dns":
+		b.fetch = func() (*BootnodeList, error) { return fetchBootnodesDNS(u.Host) }
+	default:
+		return nil, fmt.Errorf("unsupported bootnode list url scheme %q", u.Scheme)
+	}
+	return b, nil
+}
+
+// Start fetches the list and connects to the bootnodes, then refreshes the
+// list periodically
+func (b *Bootnodes) Start(server *p2p.Server) error {
+	b.start(server.AddPeer, server.RemovePeer)
+	return nil
+}
+
+func (b *Bootnodes) start(addPeer, removePeer func(*discover.Node)) {
+	b.addPeer = addPeer
+	b.removePeer = removePeer
+	b.quit = make(chan struct{})
+	go b.loop()
+}
+
+// Stop terminates the refresh loop
+func (b *Bootnodes) Stop() error {
+	close(b.quit)
+	return nil
+}
+
+func (b *Bootnodes) loop() {
+	ticker := time.NewTicker(b.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := b.refresh(); err != nil {
+			log.Warn("bootnode list refresh failed", "url", b.URL, "err", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// refresh fetches and verifies the list, connects to the bootnodes that are
+// new on the list and disconnects from the ones that were removed from it
+func (b *Bootnodes) refresh() error {
+	list, err := b.fetch()
+	if err != nil {
+		return err
+	}
+	if err := list.Verify(b.PubKey); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if list.Seq < b.seq {
+		return errBootnodeListOld
+	}
+	if list.Seq == b.seq && len(b.nodes) > 0 {
+		return nil
+	}
+	nodes := make(map[discover.NodeID]*discover.Node)
+	for _, url := range list.Nodes {
+		n, err := discover.ParseNode(url)
+		if err != nil {
+			log.Warn("invalid bootnode on list", "url", url, "err", err)
+			continue
+		}
+		nodes[n.ID] = n
+	}
+	for id, n := range b.nodes {
+		if _, ok := nodes[id]; !ok {
+			log.Debug("bootnode removed", "node", n)
+			b.removePeer(n)
+		}
+	}
+	for id, n := range nodes {
+		if _, ok := b.nodes[id]; !ok {
+			log.Debug("bootnode added", "node", n)
+			b.addPeer(n)
+		}
+	}
+	b.seq = list.Seq
+	b.nodes = nodes
+	log.Info("bootnode list updated", "seq", list.Seq, "nodes", len(nodes))
+	return nil
+}
+
+// fetchBootnodesHTTP retrieves the JSON encoded list from the url
+func fetchBootnodesHTTP(url string) (*BootnodeList, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	list := &BootnodeList{}
+	if err := json.NewDecoder(res.Body).Decode(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// fetchBootnodesDNS retrieves the list from the TXT records of the domain
+func fetchBootnodesDNS(domain string) (*BootnodeList, error) {
+	records, err := net.LookupTXT(domain)
+	if err != nil {
+		return nil, err
+	}
+	return parseBootnodeRecords(records)
+}
+
+// parseBootnodeRecords constructs the list from the DNS TXT records
+func parseBootnodeRecords(records []string) (*BootnodeList, error) {
+	list := &BootnodeList{}
+	var signed bool
+	for _, r := range records {
+		if strings.HasPrefix(r, "enode://") {
+			list.Nodes = append(list.Nodes, r)
+			continue
+		}
+		for _, field := range strings.Fields(r) {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "seq":
+				seq, err := strconv.ParseUint(kv[1], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid bootnode list seq %q: %v", kv[1], err)
+				}
+				list.Seq = seq
+			case "sig":
+				sig, err := hexutil.Decode(kv[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid bootnode list signature %q: %v", kv[1], err)
+				}
+				list.Sig = sig
+				signed = true
+			}
+		}
+	}
+	if !signed {
+		return nil, errors.New("bootnode list signature record not found")
+	}
+	return list, nil
+}
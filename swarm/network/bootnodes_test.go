@@ -0,0 +1,181 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func newTestBootnode(t *testing.T) string {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return discover.NewNode(discover.PubkeyID(&key.PublicKey), net.IP{127, 0, 0, 1}, 30303, 30303).String()
+}
+
+func TestBootnodeListVerify(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	list := &BootnodeList{Seq: 1, Nodes: []string{newTestBootnode(t), newTestBootnode(t)}}
+	if err := list.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Verify(&key.PublicKey); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+	if err := list.Verify(&other.PublicKey); err != errBootnodeListSignature {
+		t.Fatalf("expected %v for wrong key, got %v", errBootnodeListSignature, err)
+	}
+
+	// the signature does not depend on the order of the nodes
+	list.Nodes[0], list.Nodes[1] = list.Nodes[1], list.Nodes[0]
+	if err := list.Verify(&key.PublicKey); err != nil {
+		t.Fatalf("expected valid signature after reordering, got %v", err)
+	}
+
+	list.Seq++
+	if err := list.Verify(&key.PublicKey); err != errBootnodeListSignature {
+		t.Fatalf("expected %v for modified list, got %v", errBootnodeListSignature, err)
+	}
+}
+
+func TestParseBootnodeRecords(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	list := &BootnodeList{Seq: 7, Nodes: []string{newTestBootnode(t), newTestBootnode(t)}}
+	if err := list.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	records := []string{
+		list.Nodes[1],
+		fmt.Sprintf("seq=%d sig=%s", list.Seq, hexutil.Encode(list.Sig)),
+		"v=spf1 -all",
+		list.Nodes[0],
+	}
+	parsed, err := parseBootnodeRecords(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Seq != 7 || len(parsed.Nodes) != 2 {
+		t.Fatalf("unexpected list %+v", parsed)
+	}
+	if err := parsed.Verify(&key.PublicKey); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	if _, err := parseBootnodeRecords(records[:1]); err == nil {
+		t.Fatal("expected error for records without signature")
+	}
+}
+
+// TestBootnodesRefreshInterval checks that a list which would never or
+// continuously be refreshed is rejected
+func TestBootnodesRefreshInterval(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		if _, err := NewBootnodes(&BootnodesParams{URL: "dns://bootnodes.example.org", PubKey: &key.PublicKey, RefreshInterval: interval}); err == nil {
+			t.Fatalf("expected error for refresh interval %v", interval)
+		}
+	}
+}
+
+// TestBootnodesRefresh checks that nodes are added and removed
+// as the list served over HTTP is rotated
+func TestBootnodesRefresh(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	nodes := []string{newTestBootnode(t), newTestBootnode(t), newTestBootnode(t)}
+
+	var mu sync.Mutex
+	var served *BootnodeList
+	serve := func(seq uint64, nodes ...string) {
+		list := &BootnodeList{Seq: seq, Nodes: nodes}
+		if err := list.Sign(key); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		served = list
+		mu.Unlock()
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(served)
+	}))
+	defer srv.Close()
+
+	b, err := NewBootnodes(&BootnodesParams{URL: srv.URL, PubKey: &key.PublicKey, RefreshInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers := make(map[string]bool)
+	b.addPeer = func(n *discover.Node) { peers[n.String()] = true }
+	b.removePeer = func(n *discover.Node) { delete(peers, n.String()) }
+
+	check := func(expected ...string) {
+		t.Helper()
+		if len(peers) != len(expected) {
+			t.Fatalf("expected %d peers, got %d", len(expected), len(peers))
+		}
+		for _, n := range expected {
+			if !peers[n] {
+				t.Fatalf("expected peer %s", n)
+			}
+		}
+	}
+
+	serve(1, nodes[0], nodes[1])
+	if err := b.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	check(nodes[0], nodes[1])
+
+	serve(2, nodes[1], nodes[2])
+	if err := b.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	check(nodes[1], nodes[2])
+
+	// an older list must not be applied
+	serve(1, nodes[0])
+	if err := b.refresh(); err != errBootnodeListOld {
+		t.Fatalf("expected %v, got %v", errBootnodeListOld, err)
+	}
+	check(nodes[1], nodes[2])
+
+	// a list signed with another key must not be applied
+	other, _ := crypto.GenerateKey()
+	forged := &BootnodeList{Seq: 3, Nodes: []string{nodes[0]}}
+	forged.Sign(other)
+	mu.Lock()
+	served = forged
+	mu.Unlock()
+	if err := b.refresh(); err != errBootnodeListSignature {
+		t.Fatalf("expected %v, got %v", errBootnodeListSignature, err)
+	}
+	check(nodes[1], nodes[2])
+}
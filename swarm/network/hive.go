@@ -17,14 +17,18 @@
 package network
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/swarm/state"
 )
 
@@ -57,6 +61,15 @@ type Overlay interface {
 	Healthy(*PeerPot) *Health
 }
 
+// ChangeNotifier is implemented by overlays that can notify subscribers about
+// changes of their depth and of their nearest neighbour set (eg., Kademlia)
+type ChangeNotifier interface {
+	SubscribeDepthChange(chan<- DepthChange) event.Subscription
+	SubscribeNeighbourhoodChange(chan<- NeighbourhoodChange) event.Subscription
+}
+
+var errNoChangeNotifier = errors.New("overlay does not support change notifications")
+
 // HiveParams holds the config options to hive
 type HiveParams struct {
 	Discovery             bool  // if want discovery of not
@@ -206,6 +219,72 @@ func (h *Hive) PeerInfo(id discover.NodeID) interface{} {
 	}
 }
 
+// DepthChanges is an RPC subscription (hive_subscribe("depthChanges")) that
+// sends a DepthChange notification each time the saturation depth or
+// the neighbourhood depth of the overlay changes
+func (h *Hive) DepthChanges(ctx context.Context) (*rpc.Subscription, error) {
+	cn, ok := h.Overlay.(ChangeNotifier)
+	if !ok {
+		return nil, errNoChangeNotifier
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	changes := make(chan DepthChange)
+	sub := cn.SubscribeDepthChange(changes)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case c := <-changes:
+				if err := notifier.Notify(rpcSub.ID, c); err != nil {
+					log.Warn(fmt.Sprintf("%08x depth change notification failed: %v", h.BaseAddr()[:4], err))
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// NeighbourhoodChanges is an RPC subscription (hive_subscribe("neighbourhoodChanges"))
+// that sends a NeighbourhoodChange notification each time the set of connected
+// nearest neighbours of the overlay changes
+func (h *Hive) NeighbourhoodChanges(ctx context.Context) (*rpc.Subscription, error) {
+	cn, ok := h.Overlay.(ChangeNotifier)
+	if !ok {
+		return nil, errNoChangeNotifier
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	changes := make(chan NeighbourhoodChange)
+	sub := cn.SubscribeNeighbourhoodChange(changes)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case c := <-changes:
+				if err := notifier.Notify(rpcSub.ID, c); err != nil {
+					log.Warn(fmt.Sprintf("%08x neighbourhood change notification failed: %v", h.BaseAddr()[:4], err))
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // ToAddr returns the serialisable version of u
 func ToAddr(pa OverlayPeer) *BzzAddr {
 	if addr, ok := pa.(*BzzAddr); ok {
@@ -25,6 +25,8 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/swarm/pot"
 )
@@ -61,8 +63,9 @@ type KadParams struct {
 	RetryInterval  int64 // initial interval before a peer is first redialed
 	RetryExponent  int   // exponent to multiply retry intervals with
 	MaxRetries     int   // maximum number of redial attempts
+	MaxConns       int   // total connection budget, 0 means unlimited
 	// function to sanction or prevent suggesting a peer
-	Reachable func(OverlayAddr) bool
+	Reachable func(OverlayAddr) bool `toml:"-" json:"-"`
 }
 
 // NewKadParams returns a params struct with default values
@@ -89,6 +92,39 @@ type Kademlia struct {
 	nDepth     int      // stores the last neighbourhood depth
 	nDepthC    chan int // returned by DepthC function to signal neighbourhood depth change
 	addrCountC chan int // returned by AddrCountC function to signal peer count change
+
+	depthFeed event.Feed  // posts DepthChange events
+	nnFeed    event.Feed  // posts NeighbourhoodChange events
+	lastDepth DepthChange // last posted depths
+	lastNN    string      // concatenated addresses of the last posted nearest neighbour set
+
+	changeSeq   uint64     // sequence number of the last collected change
+	postLock    sync.Mutex // orders the posting of changes outside of the kademlia lock
+	postedDepth uint64     // sequence number of the last posted DepthChange
+	postedNN    uint64     // sequence number of the last posted NeighbourhoodChange
+}
+
+// kadChanges are the change events collected while the kademlia lock is held,
+// to be posted once it is released
+type kadChanges struct {
+	depth    *DepthChange
+	depthSeq uint64
+	nn       *NeighbourhoodChange
+	nnSeq    uint64
+}
+
+// DepthChange is posted to subscribers whenever the saturation depth or the
+// neighbourhood depth of the kademlia table changes
+type DepthChange struct {
+	Depth              uint8 `json:"depth"`              // depth of saturation
+	NeighbourhoodDepth int   `json:"neighbourhoodDepth"` // proximity order of the nearest neighbour set
+}
+
+// NeighbourhoodChange is posted to subscribers whenever the set of connected
+// nearest neighbours changes
+type NeighbourhoodChange struct {
+	Depth int             `json:"depth"` // neighbourhood depth
+	Peers []hexutil.Bytes `json:"peers"` // overlay addresses of the nearest neighbours
 }
 
 // NewKademlia creates a Kademlia table for base address addr
@@ -213,8 +249,14 @@ func (k *Kademlia) Register(peers []OverlayAddr) error {
 // lowest bincount below depth
 // naturally if there is an empty row it returns a peer for that
 func (k *Kademlia) SuggestPeer() (a OverlayAddr, o int, want bool) {
+	var changes *kadChanges
+	defer func() { k.postChanges(changes) }()
 	k.lock.Lock()
 	defer k.lock.Unlock()
+	// do not suggest peers once the connection budget is used up
+	if k.MaxConns > 0 && k.conns.Size() >= k.MaxConns {
+		return nil, 0, false
+	}
 	minsize := k.MinBinSize
 	depth := k.neighbourhoodDepth()
 	// if there is a callable neighbour within the current proxBin, connect
@@ -277,12 +319,15 @@ func (k *Kademlia) SuggestPeer() (a OverlayAddr, o int, want bool) {
 	if uint8(nxt) < k.depth {
 		k.depth = uint8(nxt)
 		changed = true
+		changes = k.collectChanges()
 	}
 	return a, nxt, changed
 }
 
 // On inserts the peer as a kademlia peer into the live peers
 func (k *Kademlia) On(p OverlayConn) (uint8, bool) {
+	var changes *kadChanges
+	defer func() { k.postChanges(changes) }()
 	k.lock.Lock()
 	defer k.lock.Unlock()
 	e := newEntry(p)
@@ -316,6 +361,7 @@ func (k *Kademlia) On(p OverlayConn) (uint8, bool) {
 		k.depth = depth
 	}
 	k.sendNeighbourhoodDepthChange()
+	changes = k.collectChanges()
 	return k.depth, changed
 }
 
@@ -345,6 +391,86 @@ func (k *Kademlia) sendNeighbourhoodDepthChange() {
 	}
 }
 
+// SubscribeDepthChange subscribes the given channel to saturation and
+// neighbourhood depth changes.
+// Events are sent after the kademlia lock is released, so the receiver may
+// call back into Kademlia, but a receiver not consuming the events blocks
+// further changes from being posted.
+func (k *Kademlia) SubscribeDepthChange(ch chan<- DepthChange) event.Subscription {
+	return k.depthFeed.Subscribe(ch)
+}
+
+// SubscribeNeighbourhoodChange subscribes the given channel to changes of the
+// set of connected nearest neighbours.
+// Events are sent after the kademlia lock is released, so the receiver may
+// call back into Kademlia, but a receiver not consuming the events blocks
+// further changes from being posted.
+func (k *Kademlia) SubscribeNeighbourhoodChange(ch chan<- NeighbourhoodChange) event.Subscription {
+	return k.nnFeed.Subscribe(ch)
+}
+
+// collectChanges returns the DepthChange and/or the NeighbourhoodChange event
+// to send to the subscribers if the depths or the nearest neighbour set differ
+// from the ones collected last time, nil if there is no change
+// caller must hold the lock
+func (k *Kademlia) collectChanges() *kadChanges {
+	var changes kadChanges
+	nDepth := k.neighbourhoodDepth()
+	dc := DepthChange{
+		Depth:              k.depth,
+		NeighbourhoodDepth: nDepth,
+	}
+	if dc != k.lastDepth {
+		k.lastDepth = dc
+		k.changeSeq++
+		changes.depth, changes.depthSeq = &dc, k.changeSeq
+	}
+	var peers []hexutil.Bytes
+	var key []byte
+	k.conns.EachNeighbour(k.base, pof, func(val pot.Val, po int) bool {
+		if po < nDepth {
+			return false
+		}
+		addr := val.(*entry).Address()
+		peers = append(peers, hexutil.Bytes(addr))
+		key = append(key, addr...)
+		return true
+	})
+	if string(key) != k.lastNN {
+		k.lastNN = string(key)
+		k.changeSeq++
+		changes.nn, changes.nnSeq = &NeighbourhoodChange{
+			Depth: nDepth,
+			Peers: peers,
+		}, k.changeSeq
+	}
+	if changes.depth == nil && changes.nn == nil {
+		return nil
+	}
+	return &changes
+}
+
+// postChanges sends the change events collected by collectChanges to the
+// subscribers. Changes collected concurrently may be posted in any order, an
+// event older than the last posted one of the same kind is dropped, so that
+// the last event received reflects the current state.
+// caller must not hold the lock
+func (k *Kademlia) postChanges(changes *kadChanges) {
+	if changes == nil {
+		return
+	}
+	k.postLock.Lock()
+	defer k.postLock.Unlock()
+	if changes.depth != nil && changes.depthSeq > k.postedDepth {
+		k.postedDepth = changes.depthSeq
+		k.depthFeed.Send(*changes.depth)
+	}
+	if changes.nn != nil && changes.nnSeq > k.postedNN {
+		k.postedNN = changes.nnSeq
+		k.nnFeed.Send(*changes.nn)
+	}
+}
+
 // AddrCountC returns the channel that sends a new
 // address count value on each change.
 // Not receiving from the returned channel will block Register function
@@ -358,6 +484,8 @@ func (k *Kademlia) AddrCountC() <-chan int {
 
 // Off removes a peer from among live peers
 func (k *Kademlia) Off(p OverlayConn) {
+	var changes *kadChanges
+	defer func() { k.postChanges(changes) }()
 	k.lock.Lock()
 	defer k.lock.Unlock()
 	var del bool
@@ -380,6 +508,7 @@ func (k *Kademlia) Off(p OverlayConn) {
 			k.addrCountC <- k.addrs.Size()
 		}
 		k.sendNeighbourhoodDepthChange()
+		changes = k.collectChanges()
 	}
 }
 
@@ -756,6 +885,63 @@ func (k *Kademlia) Healthy(pp *PeerPot) *Health {
 	return &Health{knownn, gotnn, countnn, culpritsnn, full, k.string()}
 }
 
+// BinStatus is the number of known and connected peers in a proximity order
+// bin of the kademlia table
+type BinStatus struct {
+	Bin       int `json:"bin"`
+	Known     int `json:"known"`
+	Connected int `json:"connected"`
+}
+
+// KademliaStatus is a summary of the kademlia table
+type KademliaStatus struct {
+	BaseAddr           hexutil.Bytes `json:"baseAddr"`
+	Depth              uint8         `json:"depth"`              // depth of saturation
+	NeighbourhoodDepth int           `json:"neighbourhoodDepth"` // proximity order of the nearest neighbour set
+	Known              int           `json:"known"`
+	Connected          int           `json:"connected"`
+	Bins               []BinStatus   `json:"bins"` // bins beyond MaxProxDisplay are merged into the last one
+}
+
+// Status returns the number of known and connected peers in each bin of the
+// kademlia table up to the deepest non-empty bin
+func (k *Kademlia) Status() *KademliaStatus {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	bins := make([]BinStatus, k.MaxProxDisplay)
+	last := -1
+	count := func(p *pot.Pot, connected bool) {
+		p.EachBin(k.base, pof, 0, func(po, size int, f func(func(val pot.Val, i int) bool) bool) bool {
+			if po >= k.MaxProxDisplay {
+				po = k.MaxProxDisplay - 1
+			}
+			if connected {
+				bins[po].Connected += size
+			} else {
+				bins[po].Known += size
+			}
+			if po > last {
+				last = po
+			}
+			return true
+		})
+	}
+	count(k.conns, true)
+	count(k.addrs, false)
+	bins = bins[:last+1]
+	for i := range bins {
+		bins[i].Bin = i
+	}
+	return &KademliaStatus{
+		BaseAddr:           hexutil.Bytes(k.base),
+		Depth:              k.depth,
+		NeighbourhoodDepth: k.neighbourhoodDepth(),
+		Known:              k.addrs.Size(),
+		Connected:          k.conns.Size(),
+		Bins:               bins,
+	}
+}
+
 func logEmptyBins(ebs []int) string {
 	var ebss []string
 	for _, eb := range ebs {
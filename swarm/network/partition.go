@@ -0,0 +1,283 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+/*
+PartitionWatchdog periodically checks whether the neighbourhood of the node
+appears to be disconnected from the wider network. A partition is suspected if
+* the node has fewer live connections than the nearest neighbour set needs
+* the saturation depth collapsed compared to its recent maximum
+* most recent network retrievals failed
+
+A PartitionStatus is posted to subscribers and the network.partition gauge is
+updated whenever the suspicion is raised or cleared.
+*/
+
+var (
+	partitionGauge   = metrics.NewRegisteredGauge("network.partition", nil)
+	partitionCounter = metrics.NewRegisteredCounter("network.partition.detected", nil)
+)
+
+// reasons a partition is suspected
+const (
+	PartitionIsolated          = "isolated"
+	PartitionDepthCollapsed    = "depth collapsed"
+	PartitionRetrievalsFailing = "retrievals failing"
+)
+
+// PartitionParams holds the config options of PartitionWatchdog
+type PartitionParams struct {
+	PartitionCheckInterval time.Duration // interval between checks
+	PartitionDepthHistory  int           // number of checks the maximum depth is taken over
+	PartitionDepthCollapse int           // drop of the saturation depth considered a collapse
+	PartitionWindow        int           // number of recent retrievals considered
+	PartitionMinSamples    int           // minimum number of retrievals before they are considered
+	PartitionFailureRatio  float64       // ratio of failed retrievals considered failing
+}
+
+// NewPartitionParams returns partition params with default values
+func NewPartitionParams() *PartitionParams {
+	return &PartitionParams{
+		PartitionCheckInterval: time.Minute,
+		PartitionDepthHistory:  10,
+		PartitionDepthCollapse: 2,
+		PartitionWindow:        100,
+		PartitionMinSamples:    10,
+		PartitionFailureRatio:  0.8,
+	}
+}
+
+// PartitionStatus is the result of a partition check
+type PartitionStatus struct {
+	Partitioned bool      `json:"partitioned"`
+	Reasons     []string  `json:"reasons"`
+	Conns       int       `json:"conns"`      // number of live connections
+	Depth       uint8     `json:"depth"`      // saturation depth
+	MaxDepth    uint8     `json:"maxDepth"`   // maximum saturation depth over the recent checks
+	Retrievals  int       `json:"retrievals"` // number of recent retrievals
+	Failures    int       `json:"failures"`   // number of recent failed retrievals
+	Time        time.Time `json:"time"`
+}
+
+// PartitionWatchdog detects when the node appears to be partitioned
+// from the rest of the network
+type PartitionWatchdog struct {
+	*PartitionParams
+	kad  *Kademlia
+	feed event.Feed
+
+	mu         sync.Mutex
+	depths     []uint8 // saturation depths of the recent checks
+	retrievals []bool  // outcomes of the recent retrievals, ring buffer
+	next       int     // position of the next retrieval outcome in the ring
+	status     PartitionStatus
+	quit       chan struct{}
+}
+
+// NewPartitionWatchdog is the PartitionWatchdog constructor, a non-positive
+// check interval or retrieval window takes the default value
+func NewPartitionWatchdog(kad *Kademlia, params *PartitionParams) *PartitionWatchdog {
+	defaults := NewPartitionParams()
+	if params == nil {
+		params = defaults
+	}
+	if params.PartitionCheckInterval <= 0 || params.PartitionWindow <= 0 {
+		p := *params
+		if p.PartitionCheckInterval <= 0 {
+			log.Warn("Invalid partition check interval, using default", "provided", p.PartitionCheckInterval, "updated", defaults.PartitionCheckInterval)
+			p.PartitionCheckInterval = defaults.PartitionCheckInterval
+		}
+		if p.PartitionWindow <= 0 {
+			log.Warn("Invalid partition retrieval window, using default", "provided", p.PartitionWindow, "updated", defaults.PartitionWindow)
+			p.PartitionWindow = defaults.PartitionWindow
+		}
+		params = &p
+	}
+	return &PartitionWatchdog{
+		PartitionParams: params,
+		kad:             kad,
+	}
+}
+
+// Retrieved records the outcome of a network retrieval
+func (w *PartitionWatchdog) Retrieved(ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.retrievals) < w.PartitionWindow {
+		w.retrievals = append(w.retrievals, ok)
+		return
+	}
+	w.retrievals[w.next] = ok
+	w.next = (w.next + 1) % w.PartitionWindow
+}
+
+// SubscribePartition subscribes the given channel to changes of the
+// partition status
+func (w *PartitionWatchdog) SubscribePartition(ch chan<- PartitionStatus) event.Subscription {
+	return w.feed.Subscribe(ch)
+}
+
+// Status returns the result of the last check
+func (w *PartitionWatchdog) Status() PartitionStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Start starts the periodic checks
+func (w *PartitionWatchdog) Start() {
+	w.quit = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.PartitionCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.check()
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the periodic checks
+func (w *PartitionWatchdog) Stop() {
+	if w.quit != nil {
+		close(w.quit)
+	}
+}
+
+// check evaluates the partition signals and notifies subscribers
+// if the partition status changed
+func (w *PartitionWatchdog) check() PartitionStatus {
+	w.kad.lock.RLock()
+	conns := w.kad.conns.Size()
+	depth := w.kad.depth
+	minConns := w.kad.MinProxBinSize
+	w.kad.lock.RUnlock()
+
+	w.mu.Lock()
+	w.depths = append(w.depths, depth)
+	if len(w.depths) > w.PartitionDepthHistory {
+		w.depths = w.depths[len(w.depths)-w.PartitionDepthHistory:]
+	}
+	status := PartitionStatus{
+		Conns:      conns,
+		Depth:      depth,
+		Retrievals: len(w.retrievals),
+		Time:       time.Now(),
+	}
+	for _, d := range w.depths {
+		if d > status.MaxDepth {
+			status.MaxDepth = d
+		}
+	}
+	for _, ok := range w.retrievals {
+		if !ok {
+			status.Failures++
+		}
+	}
+
+	if conns < minConns {
+		status.Reasons = append(status.Reasons, PartitionIsolated)
+	}
+	if int(status.MaxDepth)-int(depth) >= w.PartitionDepthCollapse {
+		status.Reasons = append(status.Reasons, PartitionDepthCollapsed)
+	}
+	if status.Retrievals >= w.PartitionMinSamples && float64(status.Failures) >= w.PartitionFailureRatio*float64(status.Retrievals) {
+		status.Reasons = append(status.Reasons, PartitionRetrievalsFailing)
+	}
+	status.Partitioned = len(status.Reasons) > 0
+
+	changed := status.Partitioned != w.status.Partitioned
+	w.status = status
+	w.mu.Unlock()
+
+	if !changed {
+		return status
+	}
+	if status.Partitioned {
+		log.Warn("network partition suspected", "reasons", status.Reasons, "conns", conns, "depth", depth, "maxdepth", status.MaxDepth, "failures", status.Failures, "retrievals", status.Retrievals)
+		partitionGauge.Update(1)
+		partitionCounter.Inc(1)
+	} else {
+		log.Info("network partition cleared", "conns", conns, "depth", depth)
+		partitionGauge.Update(0)
+	}
+	w.feed.Send(status)
+	return status
+}
+
+// PartitionAPI is the RPC API of PartitionWatchdog
+type PartitionAPI struct {
+	watchdog *PartitionWatchdog
+}
+
+// NewPartitionAPI is the PartitionAPI constructor
+func NewPartitionAPI(w *PartitionWatchdog) *PartitionAPI {
+	return &PartitionAPI{
+		watchdog: w,
+	}
+}
+
+// PartitionStatus returns the result of the last partition check
+func (api *PartitionAPI) PartitionStatus() PartitionStatus {
+	return api.watchdog.Status()
+}
+
+// PartitionChanges is an RPC subscription (hive_subscribe("partitionChanges"))
+// that sends a PartitionStatus notification each time a partition is
+// suspected or cleared
+func (api *PartitionAPI) PartitionChanges(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+	changes := make(chan PartitionStatus)
+	sub := api.watchdog.SubscribePartition(changes)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case s := <-changes:
+				if err := notifier.Notify(rpcSub.ID, s); err != nil {
+					log.Warn(fmt.Sprintf("partition change notification failed: %v", err))
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
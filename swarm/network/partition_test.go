@@ -0,0 +1,102 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPartitionWatchdog(t *testing.T) {
+	k := newTestKademlia("00000000")
+	params := NewPartitionParams()
+	params.PartitionWindow = 10
+	params.PartitionMinSamples = 5
+	w := NewPartitionWatchdog(k.Kademlia, params)
+
+	changes := make(chan PartitionStatus, 10)
+	sub := w.SubscribePartition(changes)
+	defer sub.Unsubscribe()
+
+	expect := func(partitioned bool, reasons ...string) {
+		t.Helper()
+		select {
+		case s := <-changes:
+			if s.Partitioned != partitioned {
+				t.Fatalf("expected partitioned %v, got %v", partitioned, s.Partitioned)
+			}
+			if len(reasons) > 0 && !reflect.DeepEqual(s.Reasons, reasons) {
+				t.Fatalf("expected reasons %v, got %v", reasons, s.Reasons)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for partition status change")
+		}
+	}
+
+	// no connections
+	w.check()
+	expect(true, PartitionIsolated)
+
+	k.On("01000000", "10000000", "11000000")
+	w.check()
+	expect(false)
+
+	// few retrievals are not considered
+	for i := 0; i < 4; i++ {
+		w.Retrieved(false)
+	}
+	w.check()
+	if s := w.Status(); s.Partitioned {
+		t.Fatalf("expected no partition, got %+v", s)
+	}
+
+	w.Retrieved(false)
+	w.check()
+	expect(true, PartitionRetrievalsFailing)
+
+	// successful retrievals push the failures out of the window
+	for i := 0; i < 8; i++ {
+		w.Retrieved(true)
+	}
+	w.check()
+	expect(false)
+	if s := w.Status(); s.Retrievals != 10 || s.Failures != 2 {
+		t.Fatalf("expected 2 failures out of 10 retrievals, got %d out of %d", s.Failures, s.Retrievals)
+	}
+}
+
+func TestPartitionWatchdogDefaults(t *testing.T) {
+	k := newTestKademlia("00000000")
+	params := NewPartitionParams()
+	params.PartitionCheckInterval = 0
+	params.PartitionWindow = -1
+	w := NewPartitionWatchdog(k.Kademlia, params)
+
+	defaults := NewPartitionParams()
+	if w.PartitionCheckInterval != defaults.PartitionCheckInterval {
+		t.Fatalf("expected check interval %v, got %v", defaults.PartitionCheckInterval, w.PartitionCheckInterval)
+	}
+	if w.PartitionWindow != defaults.PartitionWindow {
+		t.Fatalf("expected retrieval window %d, got %d", defaults.PartitionWindow, w.PartitionWindow)
+	}
+	for i := 0; i < 2*defaults.PartitionWindow; i++ {
+		w.Retrieved(false)
+	}
+	w.Start()
+	w.Stop()
+}
@@ -39,12 +39,18 @@ const (
 	ProtocolMaxMsgSize = 10 * 1024 * 1024
 	// timeout for waiting
 	bzzHandshakeTimeout = 3000 * time.Millisecond
+	// CapabilityCompression is advertised in the bzz handshake by nodes
+	// that accept snappy compressed chunk data
+	CapabilityCompression = "snappy"
+	// bzzLegacyVersion is the last bzz version whose handshake has no
+	// capabilities, it is still offered to connect to older nodes
+	bzzLegacyVersion = 3
 )
 
 // BzzSpec is the spec of the generic swarm handshake
 var BzzSpec = &protocols.Spec{
 	Name:       "bzz",
-	Version:    3,
+	Version:    4,
 	MaxMsgSize: 10 * 1024 * 1024,
 	Messages: []interface{}{
 		HandshakeMsg{},
@@ -94,6 +100,7 @@ type BzzConfig struct {
 	UnderlayAddr []byte // node's underlay address
 	HiveParams   *HiveParams
 	NetworkID    uint64
+	Compression  bool // whether to advertise and use compression of chunk data
 }
 
 // Bzz is the swarm protocol bundle
@@ -105,6 +112,15 @@ type Bzz struct {
 	handshakes   map[discover.NodeID]*HandshakeMsg
 	streamerSpec *protocols.Spec
 	streamerRun  func(*BzzPeer) error
+	streamers    []streamerVersion // older versions of the streamer protocol still offered
+	capabilities []string
+}
+
+// streamerVersion is an older version of the streamer protocol offered along
+// the current one, with its own spec and run function
+type streamerVersion struct {
+	spec *protocols.Spec
+	run  func(*BzzPeer) error
 }
 
 // NewBzz is the swarm protocol constructor
@@ -113,6 +129,10 @@ type Bzz struct {
 // * overlay driver
 // * peer store
 func NewBzz(config *BzzConfig, kad Overlay, store state.Store, streamerSpec *protocols.Spec, streamerRun func(*BzzPeer) error) *Bzz {
+	var capabilities []string
+	if config.Compression {
+		capabilities = append(capabilities, CapabilityCompression)
+	}
 	return &Bzz{
 		Hive:         NewHive(config.HiveParams, kad, store),
 		NetworkID:    config.NetworkID,
@@ -120,9 +140,17 @@ func NewBzz(config *BzzConfig, kad Overlay, store state.Store, streamerSpec *pro
 		handshakes:   make(map[discover.NodeID]*HandshakeMsg),
 		streamerRun:  streamerRun,
 		streamerSpec: streamerSpec,
+		capabilities: capabilities,
 	}
 }
 
+// AddStreamerVersion offers an older version of the streamer protocol along
+// the one given to NewBzz, so that nodes which do not support the current
+// version can still exchange chunks
+func (b *Bzz) AddStreamerVersion(spec *protocols.Spec, run func(*BzzPeer) error) {
+	b.streamers = append(b.streamers, streamerVersion{spec, run})
+}
+
 // UpdateLocalAddr updates underlayaddress of the running node
 func (b *Bzz) UpdateLocalAddr(byteaddr []byte) *BzzAddr {
 	b.localAddr = b.localAddr.Update(&BzzAddr{
@@ -150,6 +178,13 @@ func (b *Bzz) Protocols() []p2p.Protocol {
 			Run:      b.runBzz,
 			NodeInfo: b.NodeInfo,
 		},
+		{
+			Name:     BzzSpec.Name,
+			Version:  bzzLegacyVersion,
+			Length:   BzzSpec.Length(),
+			Run:      b.runBzzVersion(bzzLegacyVersion),
+			NodeInfo: b.NodeInfo,
+		},
 		{
 			Name:     DiscoverySpec.Name,
 			Version:  DiscoverySpec.Version,
@@ -166,6 +201,14 @@ func (b *Bzz) Protocols() []p2p.Protocol {
 			Length:  b.streamerSpec.Length(),
 			Run:     b.RunProtocol(b.streamerSpec, b.streamerRun),
 		})
+		for _, s := range b.streamers {
+			protocol = append(protocol, p2p.Protocol{
+				Name:    s.spec.Name,
+				Version: s.spec.Version,
+				Length:  s.spec.Length(),
+				Run:     b.RunProtocol(s.spec, s.run),
+			})
+		}
 	}
 	return protocol
 }
@@ -205,10 +248,11 @@ func (b *Bzz) RunProtocol(spec *protocols.Spec, run func(*BzzPeer) error) func(*
 		}
 		// the handshake has succeeded so construct the BzzPeer and run the protocol
 		peer := &BzzPeer{
-			Peer:       protocols.NewPeer(p, rw, spec),
-			localAddr:  b.localAddr,
-			BzzAddr:    handshake.peerAddr,
-			lastActive: time.Now(),
+			Peer:         protocols.NewPeer(p, rw, spec),
+			localAddr:    b.localAddr,
+			BzzAddr:      handshake.peerAddr,
+			lastActive:   time.Now(),
+			capabilities: commonCapabilities(handshake.Capabilities, handshake.peerCapabilities),
 		}
 		return run(peer)
 	}
@@ -222,38 +266,52 @@ func (b *Bzz) performHandshake(p *protocols.Peer, handshake *HandshakeMsg) error
 		close(handshake.done)
 		cancel()
 	}()
-	rsh, err := p.Handshake(ctx, handshake, b.checkHandshake)
+	rsh, err := p.Handshake(ctx, handshake, b.checkHandshake(handshake.Version))
 	if err != nil {
 		handshake.err = err
 		return err
 	}
 	handshake.peerAddr = rsh.(*HandshakeMsg).Addr
+	handshake.peerCapabilities = rsh.(*HandshakeMsg).Capabilities
 	return nil
 }
 
 // runBzz is the p2p protocol run function for the bzz base protocol
 // that negotiates the bzz handshake
 func (b *Bzz) runBzz(p *p2p.Peer, rw p2p.MsgReadWriter) error {
-	handshake, _ := b.GetHandshake(p.ID())
-	if !<-handshake.init {
-		return fmt.Errorf("%08x: bzz already started on peer %08x", b.localAddr.Over()[:4], ToOverlayAddr(p.ID().Bytes())[:4])
-	}
-	close(handshake.init)
-	defer b.removeHandshake(p.ID())
-	peer := protocols.NewPeer(p, rw, BzzSpec)
-	err := b.performHandshake(peer, handshake)
-	if err != nil {
-		log.Warn(fmt.Sprintf("%08x: handshake failed with remote peer %08x: %v", b.localAddr.Over()[:4], ToOverlayAddr(p.ID().Bytes())[:4], err))
+	return b.runBzzVersion(BzzSpec.Version)(p, rw)
+}
 
-		return err
-	}
-	// fail if we get another handshake
-	msg, err := rw.ReadMsg()
-	if err != nil {
-		return err
+// runBzzVersion returns the p2p protocol run function for the given version
+// of the bzz base protocol, the handshake of the legacy version does not
+// include the capabilities
+func (b *Bzz) runBzzVersion(version uint) func(*p2p.Peer, p2p.MsgReadWriter) error {
+	return func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+		handshake, _ := b.GetHandshake(p.ID())
+		if !<-handshake.init {
+			return fmt.Errorf("%08x: bzz already started on peer %08x", b.localAddr.Over()[:4], ToOverlayAddr(p.ID().Bytes())[:4])
+		}
+		close(handshake.init)
+		defer b.removeHandshake(p.ID())
+		handshake.Version = uint64(version)
+		if version <= bzzLegacyVersion {
+			handshake.Capabilities = nil
+		}
+		peer := protocols.NewPeer(p, rw, BzzSpec)
+		err := b.performHandshake(peer, handshake)
+		if err != nil {
+			log.Warn(fmt.Sprintf("%08x: handshake failed with remote peer %08x: %v", b.localAddr.Over()[:4], ToOverlayAddr(p.ID().Bytes())[:4], err))
+
+			return err
+		}
+		// fail if we get another handshake
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		msg.Discard()
+		return errors.New("received multiple handshakes")
 	}
-	msg.Discard()
-	return errors.New("received multiple handshakes")
 }
 
 // BzzPeer is the bzz protocol view of a protocols.Peer (itself an extension of p2p.Peer)
@@ -263,6 +321,7 @@ type BzzPeer struct {
 	localAddr       *BzzAddr  // local Peers address
 	*BzzAddr                  // remote address -> implements Addr interface = protocols.Peer
 	lastActive      time.Time // time is updated whenever mutexes are releasing
+	capabilities    []string  // capabilities supported by both nodes
 }
 
 func NewBzzTestPeer(p *protocols.Peer, addr *BzzAddr) *BzzPeer {
@@ -283,12 +342,39 @@ func (p *BzzPeer) LastActive() time.Time {
 	return p.lastActive
 }
 
+// HasCapability returns true if the capability was advertised
+// in the handshake by both the local node and the peer
+func (p *BzzPeer) HasCapability(c string) bool {
+	for _, pc := range p.capabilities {
+		if pc == c {
+			return true
+		}
+	}
+	return false
+}
+
+// commonCapabilities returns the capabilities that are in both lists
+func commonCapabilities(local, remote []string) (caps []string) {
+	for _, l := range local {
+		for _, r := range remote {
+			if l == r {
+				caps = append(caps, l)
+				break
+			}
+		}
+	}
+	return caps
+}
+
 /*
  Handshake
 
 * Version: 8 byte integer version of the protocol
 * NetworkID: 8 byte integer network identifier
 * Addr: the address advertised by the node including underlay and overlay connecctions
+* Capabilities: optional features supported by the node, sent from version 4
+  on. They are always empty in version 3 handshakes, which then encode like
+  the handshakes of nodes that do not know about capabilities
 */
 type HandshakeMsg struct {
 	Version   uint64
@@ -297,27 +383,35 @@ type HandshakeMsg struct {
 
 	// peerAddr is the address received in the peer handshake
 	peerAddr *BzzAddr
+	// peerCapabilities are the capabilities received in the peer handshake
+	peerCapabilities []string
 
 	init chan bool
 	done chan struct{}
 	err  error
+
+	// the rlp tail tag requires Capabilities to be the last field
+	Capabilities []string `rlp:"tail"`
 }
 
 // String pretty prints the handshake
 func (bh *HandshakeMsg) String() string {
-	return fmt.Sprintf("Handshake: Version: %v, NetworkID: %v, Addr: %v", bh.Version, bh.NetworkID, bh.Addr)
+	return fmt.Sprintf("Handshake: Version: %v, NetworkID: %v, Addr: %v, Capabilities: %v", bh.Version, bh.NetworkID, bh.Addr, bh.Capabilities)
 }
 
-// Perform initiates the handshake and validates the remote handshake message
-func (b *Bzz) checkHandshake(hs interface{}) error {
-	rhs := hs.(*HandshakeMsg)
-	if rhs.NetworkID != b.NetworkID {
-		return fmt.Errorf("network id mismatch %d (!= %d)", rhs.NetworkID, b.NetworkID)
-	}
-	if rhs.Version != uint64(BzzSpec.Version) {
-		return fmt.Errorf("version mismatch %d (!= %d)", rhs.Version, BzzSpec.Version)
+// checkHandshake returns the function validating the remote handshake
+// message of the negotiated bzz version
+func (b *Bzz) checkHandshake(version uint64) func(interface{}) error {
+	return func(hs interface{}) error {
+		rhs := hs.(*HandshakeMsg)
+		if rhs.NetworkID != b.NetworkID {
+			return fmt.Errorf("network id mismatch %d (!= %d)", rhs.NetworkID, b.NetworkID)
+		}
+		if rhs.Version != version {
+			return fmt.Errorf("version mismatch %d (!= %d)", rhs.Version, version)
+		}
+		return nil
 	}
-	return nil
 }
 
 // removeHandshake removes handshake for peer with peerID
@@ -335,11 +429,12 @@ func (b *Bzz) GetHandshake(peerID discover.NodeID) (*HandshakeMsg, bool) {
 	handshake, found := b.handshakes[peerID]
 	if !found {
 		handshake = &HandshakeMsg{
-			Version:   uint64(BzzSpec.Version),
-			NetworkID: b.NetworkID,
-			Addr:      b.localAddr,
-			init:      make(chan bool, 1),
-			done:      make(chan struct{}),
+			Version:      uint64(BzzSpec.Version),
+			NetworkID:    b.NetworkID,
+			Addr:         b.localAddr,
+			Capabilities: b.capabilities,
+			init:         make(chan bool, 1),
+			done:         make(chan struct{}),
 		}
 		// when handhsake is first created for a remote peer
 		// it is initialised with the init
@@ -17,6 +17,7 @@
 package network
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
@@ -28,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/protocols"
 	p2ptest "github.com/ethereum/go-ethereum/p2p/testing"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 var (
@@ -186,7 +188,7 @@ func (s *bzzTester) testHandshake(lhs, rhs *HandshakeMsg, disconnects ...*p2ptes
 
 func correctBzzHandshake(addr *BzzAddr) *HandshakeMsg {
 	return &HandshakeMsg{
-		Version:   3,
+		Version:   4,
 		NetworkID: DefaultNetworkID,
 		Addr:      addr,
 	}
@@ -199,7 +201,7 @@ func TestBzzHandshakeNetworkIDMismatch(t *testing.T) {
 
 	err := s.testHandshake(
 		correctBzzHandshake(addr),
-		&HandshakeMsg{Version: 3, NetworkID: 321, Addr: NewAddrFromNodeID(id)},
+		&HandshakeMsg{Version: 4, NetworkID: 321, Addr: NewAddrFromNodeID(id)},
 		&p2ptest.Disconnect{Peer: id, Error: fmt.Errorf("Handshake error: Message handler error: (msg code 0): network id mismatch 321 (!= 3)")},
 	)
 
@@ -216,7 +218,7 @@ func TestBzzHandshakeVersionMismatch(t *testing.T) {
 	err := s.testHandshake(
 		correctBzzHandshake(addr),
 		&HandshakeMsg{Version: 0, NetworkID: 3, Addr: NewAddrFromNodeID(id)},
-		&p2ptest.Disconnect{Peer: id, Error: fmt.Errorf("Handshake error: Message handler error: (msg code 0): version mismatch 0 (!= 3)")},
+		&p2ptest.Disconnect{Peer: id, Error: fmt.Errorf("Handshake error: Message handler error: (msg code 0): version mismatch 0 (!= 4)")},
 	)
 
 	if err != nil {
@@ -231,10 +233,96 @@ func TestBzzHandshakeSuccess(t *testing.T) {
 
 	err := s.testHandshake(
 		correctBzzHandshake(addr),
-		&HandshakeMsg{Version: 3, NetworkID: 3, Addr: NewAddrFromNodeID(id)},
+		&HandshakeMsg{Version: 4, NetworkID: 3, Addr: NewAddrFromNodeID(id)},
 	)
 
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+// TestBzzHandshakeCapabilities checks that capabilities are only sent in the
+// handshake of the current bzz version, not in the one of the legacy version
+func TestBzzHandshakeCapabilities(t *testing.T) {
+	for _, version := range []uint{BzzSpec.Version, bzzLegacyVersion} {
+		addr := RandomAddr()
+		config := &BzzConfig{
+			OverlayAddr:  addr.Over(),
+			UnderlayAddr: addr.Under(),
+			HiveParams:   NewHiveParams(),
+			NetworkID:    DefaultNetworkID,
+			Compression:  true,
+		}
+		bzz := NewBzz(config, NewKademlia(addr.OAddr, NewKadParams()), nil, nil, nil)
+		s := &bzzTester{
+			addr:           addr,
+			ProtocolTester: p2ptest.NewProtocolTester(t, NewNodeIDFromAddr(addr), 1, bzz.runBzzVersion(version)),
+		}
+		id := s.IDs[0]
+
+		lhs := &HandshakeMsg{Version: uint64(version), NetworkID: DefaultNetworkID, Addr: addr}
+		rhs := &HandshakeMsg{Version: uint64(version), NetworkID: DefaultNetworkID, Addr: NewAddrFromNodeID(id)}
+		if version > bzzLegacyVersion {
+			lhs.Capabilities = []string{CapabilityCompression}
+			rhs.Capabilities = []string{CapabilityCompression}
+		}
+		err := s.testHandshake(lhs, rhs)
+		s.Stop()
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+	}
+}
+
+func TestBzzHandshakeCapabilitiesEncoding(t *testing.T) {
+	addr := RandomAddr()
+	// handshake of a node that does not know about capabilities
+	old := struct {
+		Version   uint64
+		NetworkID uint64
+		Addr      *BzzAddr
+	}{3, 3, addr}
+	data, err := rlp.EncodeToBytes(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := &HandshakeMsg{}
+	if err := rlp.DecodeBytes(data, hs); err != nil {
+		t.Fatalf("expected handshake without capabilities to decode, got %v", err)
+	}
+	if len(hs.Capabilities) != 0 {
+		t.Fatalf("expected no capabilities, got %v", hs.Capabilities)
+	}
+	// handshake without capabilities encodes the same way
+	enc, err := rlp.EncodeToBytes(&HandshakeMsg{Version: 3, NetworkID: 3, Addr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, data) {
+		t.Fatalf("expected encoding %x, got %x", data, enc)
+	}
+
+	enc, err = rlp.EncodeToBytes(&HandshakeMsg{Version: 3, NetworkID: 3, Addr: addr, Capabilities: []string{CapabilityCompression}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs = &HandshakeMsg{}
+	if err := rlp.DecodeBytes(enc, hs); err != nil {
+		t.Fatal(err)
+	}
+	if len(hs.Capabilities) != 1 || hs.Capabilities[0] != CapabilityCompression {
+		t.Fatalf("expected capabilities %v, got %v", []string{CapabilityCompression}, hs.Capabilities)
+	}
+}
+
+func TestBzzPeerCapabilities(t *testing.T) {
+	p := &BzzPeer{
+		capabilities: commonCapabilities([]string{CapabilityCompression, "foo"}, []string{"bar", CapabilityCompression}),
+	}
+	if !p.HasCapability(CapabilityCompression) {
+		t.Fatalf("expected capability %q to be shared", CapabilityCompression)
+	}
+	if p.HasCapability("foo") || p.HasCapability("bar") {
+		t.Fatalf("expected only capability %q to be shared, got %v", CapabilityCompression, p.capabilities)
+	}
+}
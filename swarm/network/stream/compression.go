@@ -0,0 +1,59 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/golang/snappy"
+)
+
+var (
+	// the ratio of compression is compressedBytes / uncompressedBytes
+	compressionUncompressedBytes = metrics.NewRegisteredCounter("network.stream.compression.uncompressed_bytes", nil)
+	compressionCompressedBytes   = metrics.NewRegisteredCounter("network.stream.compression.compressed_bytes", nil)
+	compressionTimer             = metrics.NewRegisteredResettingTimer("network.stream.compression.time", nil)
+	decompressionTimer           = metrics.NewRegisteredResettingTimer("network.stream.decompression.time", nil)
+)
+
+// compressChunkData returns the snappy encoding of chunk data
+func compressChunkData(data []byte) []byte {
+	defer compressionTimer.UpdateSince(time.Now())
+	compressed := snappy.Encode(nil, data)
+	compressionUncompressedBytes.Inc(int64(len(data)))
+	compressionCompressedBytes.Inc(int64(len(compressed)))
+	return compressed
+}
+
+// decompressChunkData decodes snappy compressed chunk data
+// the length of the decoded data is checked before decoding to prevent
+// peers from making the node allocate large buffers
+func decompressChunkData(data []byte) ([]byte, error) {
+	defer decompressionTimer.UpdateSince(time.Now())
+	l, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	// chunk data is at most the chunk size plus 8 bytes reserved for length
+	if int64(l) > storage.DefaultChunkSize+8 {
+		return nil, fmt.Errorf("decompressed chunk data too long: %d", l)
+	}
+	return snappy.Decode(nil, data)
+}
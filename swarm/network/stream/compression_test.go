@@ -0,0 +1,46 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/golang/snappy"
+)
+
+func TestCompressChunkData(t *testing.T) {
+	data := bytes.Repeat([]byte("swarm"), int(storage.DefaultChunkSize)/5)
+	compressed := compressChunkData(data)
+	if len(compressed) >= len(data) {
+		t.Fatalf("expected compressed data to be shorter than %d, got %d", len(data), len(compressed))
+	}
+	decompressed, err := decompressChunkData(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Fatal("decompressed data does not match the original")
+	}
+
+	// data that decodes to more than a chunk is rejected
+	tooLong := snappy.Encode(nil, make([]byte, 2*storage.DefaultChunkSize))
+	if _, err := decompressChunkData(tooLong); err == nil {
+		t.Fatal("expected error decompressing data longer than a chunk")
+	}
+}
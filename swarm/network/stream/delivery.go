@@ -19,6 +19,7 @@ package stream
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -26,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/swarm/network"
+	swapsvc "github.com/ethereum/go-ethereum/swarm/services/swap"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 )
 
@@ -43,17 +45,26 @@ var (
 )
 
 type Delivery struct {
-	db       *storage.DBAPI
-	overlay  network.Overlay
-	receiveC chan *ChunkDeliveryMsg
-	getPeer  func(discover.NodeID) *Peer
+	db         *storage.DBAPI
+	overlay    network.Overlay
+	receiveC   chan *ChunkDeliveryMsg
+	getPeer    func(discover.NodeID) *Peer
+	reputation *Reputation
+	requests   *requests
+	traffic    *Traffic
+	prices     swapsvc.PriceOracle
 }
 
 func NewDelivery(overlay network.Overlay, db *storage.DBAPI) *Delivery {
+	prices := swapsvc.NewDefaultPrices()
 	d := &Delivery{
-		db:       db,
-		overlay:  overlay,
-		receiveC: make(chan *ChunkDeliveryMsg, deliveryCap),
+		db:         db,
+		overlay:    overlay,
+		receiveC:   make(chan *ChunkDeliveryMsg, deliveryCap),
+		reputation: NewReputation(),
+		requests:   newRequests(),
+		traffic:    NewTraffic(),
+		prices:     swapsvc.NewStaticPriceOracle(prices.Retrieval, prices.Storage),
 	}
 
 	go d.processReceivedChunks()
@@ -146,6 +157,7 @@ func (d *Delivery) handleRetrieveRequestMsg(sp *Peer, req *RetrieveRequestMsg) e
 	streamer := s.Server.(*SwarmChunkServer)
 	chunk, created := d.db.GetOrCreateRequest(req.Addr)
 	if chunk.ReqC != nil {
+		d.requests.add(req.Addr, sp.ID().String())
 		if created {
 			if err := d.RequestFromPeers(chunk.Addr[:], true, sp.ID()); err != nil {
 				log.Warn("unable to forward chunk request", "peer", sp.ID(), "key", chunk.Addr, "err", err)
@@ -162,6 +174,7 @@ func (d *Delivery) handleRetrieveRequestMsg(sp *Peer, req *RetrieveRequestMsg) e
 			select {
 			case <-chunk.ReqC:
 				log.Debug("retrieve request ReqC closed", "peer", sp.ID(), "hash", req.Addr, "time", time.Since(start))
+				d.requests.done(req.Addr)
 			case <-t.C:
 				log.Debug("retrieve request timeout", "peer", sp.ID(), "hash", req.Addr)
 				chunk.SetErrored(storage.ErrChunkTimeout)
@@ -170,7 +183,7 @@ func (d *Delivery) handleRetrieveRequestMsg(sp *Peer, req *RetrieveRequestMsg) e
 			chunk.SetErrored(nil)
 
 			if req.SkipCheck {
-				err := sp.Deliver(chunk, s.priority)
+				err := sp.Deliver(chunk, s.priority, false)
 				if err != nil {
 					log.Warn("ERROR in handleRetrieveRequestMsg, DROPPING peer!", "err", err)
 					sp.Drop(err)
@@ -183,7 +196,7 @@ func (d *Delivery) handleRetrieveRequestMsg(sp *Peer, req *RetrieveRequestMsg) e
 	// TODO: call the retrieve function of the outgoing syncer
 	if req.SkipCheck {
 		log.Trace("deliver", "peer", sp.ID(), "hash", chunk.Addr)
-		return sp.Deliver(chunk, s.priority)
+		return sp.Deliver(chunk, s.priority, false)
 	}
 	streamer.deliveryC <- chunk.Addr[:]
 	return nil
@@ -197,6 +210,13 @@ type ChunkDeliveryMsg struct {
 
 func (d *Delivery) handleChunkDeliveryMsg(sp *Peer, req *ChunkDeliveryMsg) error {
 	req.peer = sp
+	if sp.compression {
+		data, err := decompressChunkData(req.SData)
+		if err != nil {
+			return fmt.Errorf("chunk delivery %v: %v", req.Addr, err)
+		}
+		req.SData = data
+	}
 	d.receiveC <- req
 	return nil
 }
@@ -220,23 +240,43 @@ R:
 			continue R
 		default:
 		}
+		// chunks without an outstanding retrieve request were received by syncing
+		price := d.prices.StoragePrice()
+		if d.requests.done(req.Addr) {
+			price = d.prices.RetrievalPrice()
+		}
 		chunk.SData = req.SData
 		d.db.Put(chunk)
 
 		go func(req *ChunkDeliveryMsg) {
 			err := chunk.WaitToStore()
+			d.reputation.Delivered(req.peer.ID(), req.Addr, err != storage.ErrChunkInvalid)
 			if err == storage.ErrChunkInvalid {
 				req.peer.Drop(err)
+				return
+			}
+			d.traffic.Received(req.peer.ID(), len(req.SData), price)
+			if err := req.peer.account(-1); err != nil {
+				log.Warn("SWAP accounting of chunk delivery failed", "peer", req.peer.ID(), "err", err)
 			}
 		}(req)
 	}
 }
 
+// requestCandidate is a peer that a retrieve request may be sent to
+type requestCandidate struct {
+	peer  *Peer
+	po    int
+	score float64
+}
+
 // RequestFromPeers sends a chunk retrieve request to
+// the closest peer to the chunk address. Peers with a low reputation
+// score are only tried if no other peer is available and among equally
+// close peers the one with the highest score is preferred.
 func (d *Delivery) RequestFromPeers(hash []byte, skipCheck bool, peersToSkip ...discover.NodeID) error {
-	var success bool
-	var err error
 	requestFromPeersCount.Inc(1)
+	var candidates []*requestCandidate
 	d.overlay.EachConn(hash, 255, func(p network.OverlayConn, po int, nn bool) bool {
 		spId := p.(network.Peer).ID()
 		for _, p := range peersToSkip {
@@ -250,19 +290,36 @@ func (d *Delivery) RequestFromPeers(hash []byte, skipCheck bool, peersToSkip ...
 			log.Warn("Delivery.RequestFromPeers: peer not found", "id", spId)
 			return true
 		}
+		candidates = append(candidates, &requestCandidate{
+			peer:  sp,
+			po:    po,
+			score: d.reputation.Score(spId),
+		})
+		return true
+	})
+	// candidates are in the order of proximity to the chunk address
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if lowi, lowj := ci.score < lowReputationScore, cj.score < lowReputationScore; lowi != lowj {
+			return lowj
+		}
+		if ci.po != cj.po {
+			return ci.po > cj.po
+		}
+		return ci.score > cj.score
+	})
+	for _, c := range candidates {
 		// TODO: skip light nodes that do not accept retrieve requests
-		err = sp.SendPriority(&RetrieveRequestMsg{
+		err := c.peer.SendPriority(&RetrieveRequestMsg{
 			Addr:      hash,
 			SkipCheck: skipCheck,
 		}, Top)
 		if err != nil {
-			return true
+			continue
 		}
 		requestFromPeersEachCount.Inc(1)
-		success = true
-		return false
-	})
-	if success {
+		d.reputation.Requested(c.peer.ID(), hash)
+		d.requests.sent(hash, c.peer.ID())
 		return nil
 	}
 	return errors.New("no peer found")
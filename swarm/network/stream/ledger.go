@@ -0,0 +1,366 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/swarm/state"
+)
+
+const (
+	LedgerTraffic         = "traffic"          // chunk traffic with a peer aggregated over a ledger period
+	LedgerPaymentSent     = "payment-sent"     // cheque sent to a peer
+	LedgerPaymentReceived = "payment-received" // cheque received from a peer
+
+	LedgerFormatJSON = "json"
+	LedgerFormatCSV  = "csv"
+)
+
+var (
+	ledgerPeriod          = time.Hour            // traffic is aggregated per peer over periods of this length
+	ledgerFlushInterval   = time.Minute          // interval between writes of the accounting state to the store
+	ledgerMaxExportPeriod = 366 * 24 * time.Hour // maximum time range of an export
+
+	trafficKey      = "accounting-traffic" // state store key of the per-peer traffic totals
+	swapBalancesKey = "accounting-swap"    // state store key of the per-peer SWAP balances
+
+	errLedgerNoKey     = errors.New("ledger export requires a signing key")
+	errLedgerRange     = errors.New("invalid ledger time range")
+	errLedgerSignature = errors.New("invalid ledger signature")
+)
+
+// LedgerEntry is a record of the accounting ledger
+// traffic entries aggregate the chunks exchanged with a peer over the
+// ledger period starting at Time, payment entries record a single
+// cheque where Amount is the cumulative amount of the cheque
+type LedgerEntry struct {
+	Time           time.Time       `json:"time"`
+	Peer           string          `json:"peer"`
+	Kind           string          `json:"kind"`
+	ChunksSent     uint64          `json:"chunksSent,omitempty"`
+	ChunksReceived uint64          `json:"chunksReceived,omitempty"`
+	BytesSent      uint64          `json:"bytesSent,omitempty"`
+	BytesReceived  uint64          `json:"bytesReceived,omitempty"`
+	ValueSent      *big.Int        `json:"valueSent,omitempty"`
+	ValueReceived  *big.Int        `json:"valueReceived,omitempty"`
+	Units          uint64          `json:"units,omitempty"`
+	Contract       *common.Address `json:"contract,omitempty"`
+	Amount         *big.Int        `json:"amount,omitempty"`
+}
+
+// Ledger records the chunk traffic and payments exchanged with peers in
+// the state store, so that earnings can be audited over time ranges
+// entries are kept in buckets of ledgerPeriod, pending entries are
+// merged into their bucket when the ledger is flushed
+type Ledger struct {
+	store state.Store
+
+	mu       sync.Mutex
+	start    time.Time                        // start of the current period
+	traffic  map[discover.NodeID]*PeerTraffic // traffic of the current period not yet flushed
+	payments []LedgerEntry                    // payments not yet flushed
+}
+
+// NewLedger is the Ledger constructor
+func NewLedger(store state.Store) *Ledger {
+	return &Ledger{
+		store:   store,
+		start:   time.Now().UTC().Truncate(ledgerPeriod),
+		traffic: make(map[discover.NodeID]*PeerTraffic),
+	}
+}
+
+// ledgerKey returns the state store key of the bucket of the period
+// starting at t
+func ledgerKey(t time.Time) string {
+	return fmt.Sprintf("ledger-%d", t.Unix())
+}
+
+// rollover flushes the current period if it is over and starts a new one,
+// if the flush fails the pending entries are kept in the current period
+// and the rollover is retried with the next entry
+// caller must hold the lock
+func (l *Ledger) rollover(now time.Time) {
+	if now.Before(l.start.Add(ledgerPeriod)) {
+		return
+	}
+	if err := l.flush(); err != nil {
+		log.Warn("ledger flush failed", "err", err)
+		return
+	}
+	l.start = now.UTC().Truncate(ledgerPeriod)
+}
+
+// account returns the pending traffic of the peer in the current period
+// caller must hold the lock
+func (l *Ledger) account(id discover.NodeID) *PeerTraffic {
+	l.rollover(time.Now())
+	a := l.traffic[id]
+	if a == nil {
+		a = &PeerTraffic{
+			ValueSent:     new(big.Int),
+			ValueReceived: new(big.Int),
+		}
+		l.traffic[id] = a
+	}
+	return a
+}
+
+// sent records a chunk of size bytes delivered to the peer at price
+func (l *Ledger) sent(id discover.NodeID, size int, price *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a := l.account(id)
+	a.ChunksSent++
+	a.BytesSent += uint64(size)
+	a.ValueSent.Add(a.ValueSent, price)
+}
+
+// received records a chunk of size bytes received from the peer at price
+func (l *Ledger) received(id discover.NodeID, size int, price *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a := l.account(id)
+	a.ChunksReceived++
+	a.BytesReceived += uint64(size)
+	a.ValueReceived.Add(a.ValueReceived, price)
+}
+
+// payment records a cheque exchanged with the peer
+func (l *Ledger) payment(id discover.NodeID, kind string, units uint64, contract common.Address, amount *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.rollover(now)
+	l.payments = append(l.payments, LedgerEntry{
+		Time:     now.UTC(),
+		Peer:     id.String(),
+		Kind:     kind,
+		Units:    units,
+		Contract: &contract,
+		Amount:   new(big.Int).Set(amount),
+	})
+}
+
+// Flush writes the pending entries to the state store
+func (l *Ledger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flush()
+}
+
+// flush merges the pending entries into the bucket of the current period
+// caller must hold the lock
+func (l *Ledger) flush() error {
+	if len(l.traffic) == 0 && len(l.payments) == 0 {
+		return nil
+	}
+	key := ledgerKey(l.start)
+	var entries []LedgerEntry
+	if err := l.store.Get(key, &entries); err != nil && err != state.ErrNotFound {
+		return err
+	}
+	for id, a := range l.traffic {
+		peer := id.String()
+		var e *LedgerEntry
+		for i := range entries {
+			if entries[i].Kind == LedgerTraffic && entries[i].Peer == peer {
+				e = &entries[i]
+				break
+			}
+		}
+		if e == nil {
+			entries = append(entries, LedgerEntry{
+				Time:          l.start,
+				Peer:          peer,
+				Kind:          LedgerTraffic,
+				ValueSent:     new(big.Int),
+				ValueReceived: new(big.Int),
+			})
+			e = &entries[len(entries)-1]
+		}
+		e.ChunksSent += a.ChunksSent
+		e.ChunksReceived += a.ChunksReceived
+		e.BytesSent += a.BytesSent
+		e.BytesReceived += a.BytesReceived
+		e.ValueSent.Add(e.ValueSent, a.ValueSent)
+		e.ValueReceived.Add(e.ValueReceived, a.ValueReceived)
+	}
+	entries = append(entries, l.payments...)
+	if err := l.store.Put(key, entries); err != nil {
+		return err
+	}
+	l.traffic = make(map[discover.NodeID]*PeerTraffic)
+	l.payments = nil
+	return nil
+}
+
+// Entries returns the entries with times in the range [from, to)
+func (l *Ledger) Entries(from, to time.Time) ([]LedgerEntry, error) {
+	if !from.Before(to) || to.Sub(from) > ledgerMaxExportPeriod {
+		return nil, errLedgerRange
+	}
+	if err := l.Flush(); err != nil {
+		return nil, err
+	}
+	var result []LedgerEntry
+	for t := from.UTC().Truncate(ledgerPeriod); t.Before(to); t = t.Add(ledgerPeriod) {
+		var entries []LedgerEntry
+		if err := l.store.Get(ledgerKey(t), &entries); err != nil {
+			if err == state.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.Time.Before(from) && e.Time.Before(to) {
+				result = append(result, e)
+			}
+		}
+	}
+	return result, nil
+}
+
+// SignedLedger is an export of ledger entries signed by the node, Data
+// holds the entries encoded in Format
+type SignedLedger struct {
+	From   time.Time      `json:"from"`
+	To     time.Time      `json:"to"`
+	Format string         `json:"format"`
+	Data   string         `json:"data"`
+	Signer common.Address `json:"signer"`
+	Sig    hexutil.Bytes  `json:"sig"`
+}
+
+// sigHash returns the hash signed by the node, covering the time range,
+// the format and the encoded entries
+func (s *SignedLedger) sigHash() []byte {
+	return crypto.Keccak256(
+		[]byte(strconv.FormatInt(s.From.Unix(), 10)),
+		[]byte(strconv.FormatInt(s.To.Unix(), 10)),
+		[]byte(s.Format),
+		[]byte(s.Data),
+	)
+}
+
+// Verify checks that the export is signed by its signer
+func (s *SignedLedger) Verify() error {
+	pub, err := crypto.SigToPub(s.sigHash(), s.Sig)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pub) != s.Signer {
+		return errLedgerSignature
+	}
+	return nil
+}
+
+// Export encodes the entries in the range [from, to) in format and signs
+// the result with key
+func (l *Ledger) Export(from, to time.Time, format string, key *ecdsa.PrivateKey) (*SignedLedger, error) {
+	if key == nil {
+		return nil, errLedgerNoKey
+	}
+	entries, err := l.Entries(from, to)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch format {
+	case LedgerFormatJSON, "":
+		format = LedgerFormatJSON
+		if entries == nil {
+			entries = []LedgerEntry{}
+		}
+		data, err = json.Marshal(entries)
+	case LedgerFormatCSV:
+		data, err = encodeLedgerCSV(entries)
+	default:
+		return nil, fmt.Errorf("unknown ledger format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &SignedLedger{
+		From:   from.UTC(),
+		To:     to.UTC(),
+		Format: format,
+		Data:   string(data),
+		Signer: crypto.PubkeyToAddress(key.PublicKey),
+	}
+	s.Sig, err = crypto.Sign(s.sigHash(), key)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// encodeLedgerCSV encodes the entries as CSV with a header row
+func encodeLedgerCSV(entries []LedgerEntry) ([]byte, error) {
+	bigString := func(v *big.Int) string {
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+	uintString := func(v uint64) string {
+		return strconv.FormatUint(v, 10)
+	}
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	w.Write([]string{"time", "peer", "kind", "chunksSent", "chunksReceived", "bytesSent", "bytesReceived", "valueSent", "valueReceived", "units", "contract", "amount"})
+	for _, e := range entries {
+		var contract string
+		if e.Contract != nil {
+			contract = e.Contract.Hex()
+		}
+		w.Write([]string{
+			e.Time.Format(time.RFC3339),
+			e.Peer,
+			e.Kind,
+			uintString(e.ChunksSent),
+			uintString(e.ChunksReceived),
+			uintString(e.BytesSent),
+			uintString(e.BytesReceived),
+			bigString(e.ValueSent),
+			bigString(e.ValueReceived),
+			uintString(e.Units),
+			contract,
+			bigString(e.Amount),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/swarm/state"
+)
+
+func TestLedger(t *testing.T) {
+	store := state.NewInmemoryStore()
+	tr := NewTraffic()
+	tr.ledger = NewLedger(store)
+	a := discover.NodeID{1}
+	b := discover.NodeID{2}
+	price := big.NewInt(3)
+
+	tr.Sent(a, 4096, price)
+	tr.Received(b, 4096, price)
+	// traffic flushed in several steps is merged into one entry per peer
+	if err := tr.ledger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	tr.Sent(a, 4096, price)
+	tr.ledger.payment(b, LedgerPaymentReceived, 10, common.Address{3}, big.NewInt(30))
+
+	now := time.Now()
+	entries, err := tr.ledger.Entries(now.Add(-ledgerPeriod), now.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	for _, e := range entries {
+		switch {
+		case e.Peer == a.String() && e.Kind == LedgerTraffic:
+			if e.ChunksSent != 2 || e.BytesSent != 8192 || e.ValueSent.Int64() != 6 {
+				t.Fatalf("unexpected traffic entry %+v", e)
+			}
+		case e.Peer == b.String() && e.Kind == LedgerTraffic:
+			if e.ChunksReceived != 1 || e.ValueReceived.Int64() != 3 {
+				t.Fatalf("unexpected traffic entry %+v", e)
+			}
+		case e.Peer == b.String() && e.Kind == LedgerPaymentReceived:
+			if e.Units != 10 || e.Amount.Int64() != 30 || *e.Contract != (common.Address{3}) {
+				t.Fatalf("unexpected payment entry %+v", e)
+			}
+		default:
+			t.Fatalf("unexpected entry %+v", e)
+		}
+	}
+
+	// entries outside of the range are excluded
+	entries, err = tr.ledger.Entries(now.Add(time.Hour), now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no entries, got %+v", entries)
+	}
+	if _, err := tr.ledger.Entries(now, now); err != errLedgerRange {
+		t.Fatalf("expected range error, got %v", err)
+	}
+
+	// traffic totals are restored from the store
+	if err := tr.save(store); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewTraffic()
+	if err := restored.load(store); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.Peer(a); got.ChunksSent != 2 || got.ValueSent.Int64() != 6 {
+		t.Fatalf("expected restored traffic, got %+v", got)
+	}
+}
+
+func TestLedgerExport(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLedger(state.NewInmemoryStore())
+	l.sent(discover.NodeID{1}, 4096, big.NewInt(3))
+	l.payment(discover.NodeID{1}, LedgerPaymentSent, 5, common.Address{2}, big.NewInt(15))
+	from, to := time.Now().Add(-ledgerPeriod), time.Now().Add(time.Second)
+
+	if _, err := l.Export(from, to, LedgerFormatJSON, nil); err != errLedgerNoKey {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+	if _, err := l.Export(from, to, "xml", key); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+
+	s, err := l.Export(from, to, LedgerFormatJSON, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	var entries []LedgerEntry
+	if err := json.Unmarshal([]byte(s.Data), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	s.Data = strings.Replace(s.Data, "15", "16", 1)
+	if err := s.Verify(); err != errLedgerSignature {
+		t.Fatalf("expected signature error for tampered ledger, got %v", err)
+	}
+
+	s, err = l.Export(from, to, LedgerFormatCSV, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(s.Data), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "time,peer,kind") {
+		t.Fatalf("unexpected csv export %q", s.Data)
+	}
+}
+
+// failingStore is a state store which fails to write while fail is set
+type failingStore struct {
+	state.Store
+	fail bool
+}
+
+func (s *failingStore) Put(key string, i interface{}) error {
+	if s.fail {
+		return errors.New("put failed")
+	}
+	return s.Store.Put(key, i)
+}
+
+// TestLedgerRolloverFlushFailure tests that the ledger period only advances
+// once the pending entries of the previous period are saved
+func TestLedgerRolloverFlushFailure(t *testing.T) {
+	store := &failingStore{Store: state.NewInmemoryStore()}
+	l := NewLedger(store)
+	id := discover.NodeID{1}
+	l.sent(id, 4096, big.NewInt(3))
+
+	start := l.start
+	next := start.Add(ledgerPeriod)
+	store.fail = true
+	l.mu.Lock()
+	l.rollover(next)
+	l.mu.Unlock()
+	if l.start != start {
+		t.Fatalf("expected period to stay at %v after failed flush, got %v", start, l.start)
+	}
+	if a := l.traffic[id]; a == nil || a.ChunksSent != 1 {
+		t.Fatalf("expected pending traffic to be kept, got %+v", a)
+	}
+
+	store.fail = false
+	l.mu.Lock()
+	l.rollover(next)
+	l.mu.Unlock()
+	if l.start != next {
+		t.Fatalf("expected period %v after flush, got %v", next, l.start)
+	}
+	entries, err := l.Entries(start, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ChunksSent != 1 {
+		t.Fatalf("expected the traffic entry of the previous period, got %+v", entries)
+	}
+}
+
+// TestRegistryStopTwice tests that the registry can be stopped more than once
+func TestRegistryStopTwice(t *testing.T) {
+	tester, streamer, _, teardown, err := newStreamerTester(t)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tester.Stop()
+
+	if err := streamer.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := streamer.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
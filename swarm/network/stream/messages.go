@@ -320,7 +320,7 @@ func (p *Peer) handleWantedHashesMsg(req *WantedHashesMsg) error {
 			}
 			chunk := storage.NewChunk(hash, nil)
 			chunk.SData = data
-			if err := p.Deliver(chunk, s.priority); err != nil {
+			if err := p.Deliver(chunk, s.priority, true); err != nil {
 				return err
 			}
 		}
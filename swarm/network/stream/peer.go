@@ -19,6 +19,7 @@ package stream
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/protocols"
 	pq "github.com/ethereum/go-ethereum/swarm/network/priorityqueue"
 	"github.com/ethereum/go-ethereum/swarm/network/stream/intervals"
+	"github.com/ethereum/go-ethereum/swarm/services/swap/swap"
 	"github.com/ethereum/go-ethereum/swarm/state"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 )
@@ -60,6 +62,11 @@ type Peer struct {
 	// on creating a new client in offered hashes handler.
 	clientParams map[Stream]*clientParams
 	quit         chan struct{}
+	// compression is true if chunk data is exchanged snappy compressed
+	compression bool
+	swapMu      sync.RWMutex
+	swap        *swap.Swap // SWAP accounting with the peer, nil if not set up
+	swapSellAt  *big.Int   // chunk price offered in the SWAP profile sent to the peer
 }
 
 // NewPeer is the constructor for Peer
@@ -83,12 +90,32 @@ func NewPeer(peer *protocols.Peer, streamer *Registry) *Peer {
 }
 
 // Deliver sends a storeRequestMsg protocol message to the peer
-func (p *Peer) Deliver(chunk *storage.Chunk, priority uint8) error {
+// chunk data is compressed if compression was negotiated with the peer
+// the delivery is accounted for if SWAP is set up with the peer
+// syncing is true if the chunk is delivered for storage rather than
+// for a retrieve request, which determines its price
+func (p *Peer) Deliver(chunk *storage.Chunk, priority uint8, syncing bool) error {
+	if err := p.account(1); err != nil {
+		return err
+	}
+	data := chunk.SData
+	if p.compression {
+		data = compressChunkData(data)
+	}
 	msg := &ChunkDeliveryMsg{
 		Addr:  chunk.Addr,
-		SData: chunk.SData,
+		SData: data,
+	}
+	if err := p.SendPriority(msg, priority); err != nil {
+		return err
+	}
+	prices := p.streamer.delivery.prices
+	price := prices.RetrievalPrice()
+	if syncing {
+		price = prices.StoragePrice()
 	}
-	return p.SendPriority(msg, priority)
+	p.streamer.delivery.traffic.Sent(p.ID(), len(chunk.SData), price)
+	return nil
 }
 
 // SendPriority sends message to the peer using the outgoing priority queue
@@ -257,8 +284,23 @@ func (p *Peer) getOrSetClient(s Stream, from, to uint64) (c *client, created boo
 		}
 	}
 
-	if err := p.streamer.intervalsStore.Put(intervalsKey, intervals.NewIntervals(from)); err != nil {
-		return nil, false, err
+	// history intervals persisted in a previous session with the same peer
+	// are kept, so that syncing continues where it was interrupted
+	keep := false
+	if !s.Live {
+		err := p.streamer.intervalsStore.Get(intervalsKey, &intervals.Intervals{})
+		switch err {
+		case nil:
+			keep = true
+		case state.ErrNotFound:
+		default:
+			log.Error("stream set client: get intervals", "stream", s, "peer", p, "err", err)
+		}
+	}
+	if !keep {
+		if err := p.streamer.intervalsStore.Put(intervalsKey, intervals.NewIntervals(from)); err != nil {
+			return nil, false, err
+		}
 	}
 
 	next := make(chan error, 1)
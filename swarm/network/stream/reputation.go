@@ -0,0 +1,185 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+var (
+	// retrieve requests not answered within this period count as timeouts
+	reputationRequestTimeout = 10 * time.Second
+	// peers with a score below this value are deprioritised for retrieve requests
+	lowReputationScore = 0.5
+	// records of disconnected peers are dropped after this period, so that
+	// peers reconnecting shortly after are still scored by their records
+	reputationRetention = time.Hour
+)
+
+const (
+	invalidChunkWeight = 4               // an invalid chunk weighs as much as this many timeouts
+	latencyScale       = time.Second     // latency that halves the score of a peer
+	latencySmoothing   = 0.2             // weight of a new sample in the latency moving average
+	reputationNeutral  = float64(1)      // score of a peer without any records
+	pendingRequestsCap = 4 * deliveryCap // number of pending requests tracked per peer
+)
+
+// PeerScore holds the retrieval statistics and the derived reputation score
+// of a peer
+type PeerScore struct {
+	Delivered uint64        `json:"delivered"` // number of valid chunks delivered
+	Invalid   uint64        `json:"invalid"`   // number of invalid chunks delivered
+	Timeouts  uint64        `json:"timeouts"`  // number of unanswered retrieve requests
+	Latency   time.Duration `json:"latency"`   // moving average of the delivery latency
+	Score     float64       `json:"score"`     // reputation score between 0 and 1
+}
+
+// score calculates the reputation score from the statistics
+// peers without records have a neutral score of 1
+func (s *PeerScore) score() float64 {
+	good := float64(s.Delivered) + 1
+	bad := float64(s.Invalid)*invalidChunkWeight + float64(s.Timeouts)
+	return good / (good + bad) / (1 + float64(s.Latency)/float64(latencyScale))
+}
+
+// peerRecord is the reputation bookkeeping of a single peer
+type peerRecord struct {
+	PeerScore
+	pending map[string]time.Time // outstanding retrieve requests by chunk address
+	removed time.Time            // time the peer disconnected, zero while connected
+}
+
+// Reputation tracks the behaviour of peers serving retrieve requests
+// and scores them so that reliable peers are preferred
+type Reputation struct {
+	mu    sync.RWMutex
+	peers map[discover.NodeID]*peerRecord
+}
+
+// NewReputation is the Reputation constructor
+func NewReputation() *Reputation {
+	return &Reputation{
+		peers: make(map[discover.NodeID]*peerRecord),
+	}
+}
+
+// record returns the record for the peer, creating it if needed
+// caller must hold the lock
+func (r *Reputation) record(id discover.NodeID) *peerRecord {
+	rec := r.peers[id]
+	if rec == nil {
+		rec = &peerRecord{
+			PeerScore: PeerScore{Score: reputationNeutral},
+			pending:   make(map[string]time.Time),
+		}
+		r.peers[id] = rec
+	}
+	return rec
+}
+
+// Requested registers a retrieve request for addr sent to the peer
+// if the peer does not deliver within reputationRequestTimeout, a timeout is recorded
+func (r *Reputation) Requested(id discover.NodeID, addr []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := r.record(id)
+	rec.removed = time.Time{}
+	if len(rec.pending) >= pendingRequestsCap {
+		return
+	}
+	key := string(addr)
+	rec.pending[key] = time.Now()
+	time.AfterFunc(reputationRequestTimeout, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := rec.pending[key]; !ok {
+			return
+		}
+		delete(rec.pending, key)
+		rec.Timeouts++
+		rec.Score = rec.score()
+	})
+}
+
+// Delivered records the delivery of the chunk addr by the peer
+// valid reports whether the delivered chunk was found valid
+func (r *Reputation) Delivered(id discover.NodeID, addr []byte, valid bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := r.record(id)
+	if !valid {
+		rec.Invalid++
+	} else {
+		rec.Delivered++
+	}
+	key := string(addr)
+	if at, ok := rec.pending[key]; ok {
+		delete(rec.pending, key)
+		latency := time.Since(at)
+		if rec.Latency == 0 {
+			rec.Latency = latency
+		} else {
+			rec.Latency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(rec.Latency))
+		}
+	}
+	rec.Score = rec.score()
+}
+
+// Removed marks the record of the peer as disconnected and drops the records
+// of peers disconnected for longer than reputationRetention
+func (r *Reputation) Removed(id discover.NodeID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if rec, ok := r.peers[id]; ok {
+		rec.removed = now
+	}
+	for id, rec := range r.peers {
+		if !rec.removed.IsZero() && now.Sub(rec.removed) > reputationRetention {
+			delete(r.peers, id)
+		}
+	}
+}
+
+// Score returns the reputation score of the peer
+func (r *Reputation) Score(id discover.NodeID) float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rec, ok := r.peers[id]; ok {
+		return rec.Score
+	}
+	return reputationNeutral
+}
+
+// Scores returns a copy of the statistics of all peers with records
+func (r *Reputation) Scores() map[discover.NodeID]PeerScore {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scores := make(map[discover.NodeID]PeerScore, len(r.peers))
+	for id, rec := range r.peers {
+		scores[id] = rec.PeerScore
+	}
+	return scores
+}
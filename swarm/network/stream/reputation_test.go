@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func TestReputation(t *testing.T) {
+	defer func(d time.Duration) { reputationRequestTimeout = d }(reputationRequestTimeout)
+	reputationRequestTimeout = 50 * time.Millisecond
+
+	r := NewReputation()
+	good := discover.NodeID{1}
+	bad := discover.NodeID{2}
+	flaky := discover.NodeID{3}
+
+	if s := r.Score(good); s != reputationNeutral {
+		t.Fatalf("expected neutral score %v for unknown peer, got %v", reputationNeutral, s)
+	}
+
+	for i := byte(0); i < 4; i++ {
+		addr := []byte{i}
+		r.Requested(good, addr)
+		r.Delivered(good, addr, true)
+		r.Requested(bad, addr)
+		r.Delivered(bad, addr, false)
+		r.Requested(flaky, addr)
+	}
+	time.Sleep(4 * reputationRequestTimeout)
+
+	scores := r.Scores()
+	if s := scores[good]; s.Delivered != 4 || s.Invalid != 0 || s.Timeouts != 0 {
+		t.Fatalf("unexpected statistics for good peer: %+v", s)
+	}
+	if s := scores[bad]; s.Delivered != 0 || s.Invalid != 4 || s.Timeouts != 0 {
+		t.Fatalf("unexpected statistics for bad peer: %+v", s)
+	}
+	if s := scores[flaky]; s.Delivered != 0 || s.Invalid != 0 || s.Timeouts != 4 {
+		t.Fatalf("unexpected statistics for flaky peer: %+v", s)
+	}
+	if r.Score(good) < lowReputationScore {
+		t.Fatalf("expected good peer to have score at least %v, got %v", lowReputationScore, r.Score(good))
+	}
+	if r.Score(bad) >= lowReputationScore {
+		t.Fatalf("expected bad peer to have score below %v, got %v", lowReputationScore, r.Score(bad))
+	}
+	if r.Score(flaky) >= lowReputationScore {
+		t.Fatalf("expected flaky peer to have score below %v, got %v", lowReputationScore, r.Score(flaky))
+	}
+}
+
+// TestReputationRetention tests that the records of disconnected peers are
+// kept for reputationRetention and dropped after
+func TestReputationRetention(t *testing.T) {
+	defer func(d time.Duration) { reputationRetention = d }(reputationRetention)
+	reputationRetention = 50 * time.Millisecond
+
+	r := NewReputation()
+	a := discover.NodeID{1}
+	b := discover.NodeID{2}
+	r.Requested(a, []byte{0})
+	r.Delivered(a, []byte{0}, false)
+	r.Requested(b, []byte{0})
+	r.Delivered(b, []byte{0}, false)
+
+	// a reconnecting peer keeps its record
+	r.Removed(a)
+	r.Removed(b)
+	r.Requested(a, []byte{1})
+	if s := r.Scores()[a]; s.Invalid != 1 {
+		t.Fatalf("expected record of reconnected peer to be kept, got %+v", s)
+	}
+
+	time.Sleep(2 * reputationRetention)
+	r.Removed(discover.NodeID{3})
+	scores := r.Scores()
+	if _, ok := scores[b]; ok {
+		t.Fatalf("expected record of disconnected peer to be dropped, got %+v", scores[b])
+	}
+	if _, ok := scores[a]; !ok {
+		t.Fatal("expected record of connected peer to be kept")
+	}
+}
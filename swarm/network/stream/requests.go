@@ -0,0 +1,159 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// requests older than this are considered abandoned and are not listed
+var requestMaxAge = 10 * time.Minute
+
+// number of tracked requests above which abandoned requests are removed
+const requestsPruneSize = 1024
+
+// RequestOriginLocal is the origin of retrieve requests made by the node itself
+const RequestOriginLocal = "local"
+
+// RequestInfo describes an outstanding network retrieve request
+type RequestInfo struct {
+	Addr     storage.Address `json:"addr"`
+	Created  time.Time       `json:"created"`
+	Age      time.Duration   `json:"age"`
+	Origins  []string        `json:"origins"`  // "local" or IDs of the requesting peers
+	Attempts int             `json:"attempts"` // number of times the request was sent to peers
+	Peers    []string        `json:"peers"`    // IDs of the peers the request was sent to
+}
+
+// requests keeps track of outstanding network retrieve requests
+// so that they can be inspected
+type requests struct {
+	mu      sync.Mutex
+	pending map[string]*RequestInfo
+}
+
+func newRequests() *requests {
+	return &requests{
+		pending: make(map[string]*RequestInfo),
+	}
+}
+
+// get returns the request for addr, creating it if needed
+// caller must hold the lock
+func (r *requests) get(addr []byte) *RequestInfo {
+	req := r.pending[string(addr)]
+	if req == nil {
+		req = &RequestInfo{
+			Addr:    storage.Address(append([]byte{}, addr...)),
+			Created: time.Now(),
+		}
+		r.pending[string(addr)] = req
+	}
+	return req
+}
+
+// prune removes the abandoned requests
+// caller must hold the lock
+func (r *requests) prune() {
+	for key, req := range r.pending {
+		if time.Since(req.Created) > requestMaxAge {
+			delete(r.pending, key)
+		}
+	}
+}
+
+// add records that the chunk addr was requested by origin
+func (r *requests) add(addr []byte, origin string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) >= requestsPruneSize {
+		r.prune()
+	}
+	req := r.get(addr)
+	for _, o := range req.Origins {
+		if o == origin {
+			return
+		}
+	}
+	req.Origins = append(req.Origins, origin)
+}
+
+// sent records that the request for chunk addr was sent to the peer
+func (r *requests) sent(addr []byte, id discover.NodeID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req := r.get(addr)
+	req.Attempts++
+	req.Peers = append(req.Peers, id.String())
+}
+
+// done removes the request for chunk addr
+// it returns false if there was no outstanding request for the chunk
+func (r *requests) done(addr []byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.pending[string(addr)]
+	delete(r.pending, string(addr))
+	return ok
+}
+
+// list returns the outstanding requests, the oldest first
+// abandoned requests are removed
+func (r *requests) list() []RequestInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+	now := time.Now()
+	list := make([]RequestInfo, 0, len(r.pending))
+	for _, req := range r.pending {
+		info := *req
+		info.Age = now.Sub(req.Created)
+		info.Origins = append([]string{}, req.Origins...)
+		info.Peers = append([]string{}, req.Peers...)
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+	return list
+}
+
+// RequestsAPI is the debug API to inspect the outstanding retrieve requests
+type RequestsAPI struct {
+	delivery *Delivery
+}
+
+// NewRequestsAPI is the RequestsAPI constructor
+func NewRequestsAPI(d *Delivery) *RequestsAPI {
+	return &RequestsAPI{
+		delivery: d,
+	}
+}
+
+// Requests returns the outstanding network retrieve requests, the oldest first
+func (api *RequestsAPI) Requests() []RequestInfo {
+	return api.delivery.requests.list()
+}
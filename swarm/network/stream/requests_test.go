@@ -0,0 +1,67 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func TestRequests(t *testing.T) {
+	r := newRequests()
+	peer := discover.NodeID{1}
+	origin := discover.NodeID{2}.String()
+
+	r.add([]byte{1}, RequestOriginLocal)
+	r.sent([]byte{1}, peer)
+	r.sent([]byte{1}, peer)
+	r.add([]byte{2}, origin)
+	r.add([]byte{2}, origin)
+	r.sent([]byte{2}, peer)
+
+	list := r.list()
+	if len(list) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(list))
+	}
+	first, second := list[0], list[1]
+	if !bytes.Equal(first.Addr, []byte{1}) || !bytes.Equal(second.Addr, []byte{2}) {
+		t.Fatalf("expected requests in order of creation, got %v, %v", first.Addr, second.Addr)
+	}
+	if first.Attempts != 2 || len(first.Peers) != 2 {
+		t.Fatalf("expected 2 attempts for the first request, got %d (%v)", first.Attempts, first.Peers)
+	}
+	if len(first.Origins) != 1 || first.Origins[0] != RequestOriginLocal {
+		t.Fatalf("expected origin %q, got %v", RequestOriginLocal, first.Origins)
+	}
+	if len(second.Origins) != 1 || second.Origins[0] != origin {
+		t.Fatalf("expected origin %q, got %v", origin, second.Origins)
+	}
+
+	r.done([]byte{1})
+	if list := r.list(); len(list) != 1 {
+		t.Fatalf("expected 1 request after done, got %d", len(list))
+	}
+
+	defer func(d time.Duration) { requestMaxAge = d }(requestMaxAge)
+	requestMaxAge = 0
+	if list := r.list(); len(list) != 0 {
+		t.Fatalf("expected abandoned requests to be removed, got %d", len(list))
+	}
+}
@@ -18,11 +18,14 @@ package stream
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -32,8 +35,10 @@ import (
 	"github.com/ethereum/go-ethereum/swarm/network"
 	"github.com/ethereum/go-ethereum/swarm/network/stream/intervals"
 	"github.com/ethereum/go-ethereum/swarm/pot"
+	swapsvc "github.com/ethereum/go-ethereum/swarm/services/swap"
 	"github.com/ethereum/go-ethereum/swarm/state"
 	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
 )
 
 const (
@@ -60,6 +65,15 @@ type Registry struct {
 	delivery       *Delivery
 	intervalsStore state.Store
 	doRetrieve     bool
+	doSync         bool
+	swapProfile    *swapsvc.LocalProfile // local SWAP profile, nil if SWAP is disabled
+	swapBackend    chequebook.Backend
+	swapPrices     swapsvc.PriceOracle // prices offered in the SWAP profile, the configured ones if nil
+	swapBalances   *swapBalances       // SWAP balances with peers, kept across connections
+	ledger         *Ledger             // accounting ledger, nil without a state store
+	ledgerKey      *ecdsa.PrivateKey   // signs ledger exports
+	quit           chan struct{}
+	quitOnce       sync.Once
 }
 
 // RegistryOptions holds optional values for NewRegistry constructor.
@@ -68,6 +82,10 @@ type RegistryOptions struct {
 	DoSync          bool
 	DoRetrieve      bool
 	SyncUpdateDelay time.Duration
+	Swap            *swapsvc.LocalProfile // enables SWAP accounting if set
+	SwapBackend     chequebook.Backend    // backend for the SWAP chequebook contracts
+	Prices          swapsvc.PriceOracle   // chunk prices used for accounting and offered with SWAP, default and configured prices if nil
+	LedgerKey       *ecdsa.PrivateKey     // key signing accounting ledger exports
 }
 
 // NewRegistry is Streamer constructor
@@ -87,9 +105,30 @@ func NewRegistry(addr *network.BzzAddr, delivery *Delivery, db *storage.DBAPI, i
 		delivery:       delivery,
 		intervalsStore: intervalsStore,
 		doRetrieve:     options.DoRetrieve,
+		doSync:         options.DoSync,
+		swapProfile:    options.Swap,
+		swapBackend:    options.SwapBackend,
+		swapBalances:   newSwapBalances(),
+		ledgerKey:      options.LedgerKey,
+		quit:           make(chan struct{}),
 	}
 	streamer.api = NewAPI(streamer)
 	delivery.getPeer = streamer.getPeer
+	if options.Prices != nil {
+		delivery.prices = options.Prices
+		streamer.swapPrices = options.Prices
+	}
+	if intervalsStore != nil {
+		// accounting state is kept across restarts
+		if err := delivery.traffic.load(intervalsStore); err != nil {
+			log.Error("unable to load accounting state", "err", err)
+		}
+		if err := streamer.swapBalances.load(intervalsStore); err != nil {
+			log.Error("unable to load SWAP balances", "err", err)
+		}
+		streamer.ledger = NewLedger(intervalsStore)
+		delivery.traffic.ledger = streamer.ledger
+	}
 	streamer.RegisterServerFunc(swarmChunkServerStreamName, func(_ *Peer, _ string, _ bool) (Server, error) {
 		return NewSwarmChunkServer(delivery.db), nil
 	})
@@ -259,6 +298,10 @@ func (r *Registry) Subscribe(peerId discover.NodeID, s Stream, h *Range, priorit
 		return fmt.Errorf("peer not found %v", peerId)
 	}
 
+	if h != nil {
+		h = r.resumeRange(peer, s, h)
+	}
+
 	var to uint64
 	if !s.Live && h != nil {
 		to = h.To
@@ -288,6 +331,31 @@ func (r *Registry) Subscribe(peerId discover.NodeID, s Stream, h *Range, priorit
 	return peer.SendPriority(msg, priority)
 }
 
+// resumeRange returns the history range to be requested from the peer.
+// If intervals for the peer's history stream are persisted from a previous
+// session, the start of the range is moved to the first interval that is not
+// yet synced, so that the peer does not offer the same chunks again after
+// a reconnection.
+func (r *Registry) resumeRange(p *Peer, s Stream, h *Range) *Range {
+	i := &intervals.Intervals{}
+	key := peerStreamIntervalsKey(p, getHistoryStream(s))
+	if err := r.intervalsStore.Get(key, i); err != nil {
+		if err != state.ErrNotFound {
+			log.Error("stream resume: get history intervals", "stream", s, "peer", p.ID(), "err", err)
+		}
+		return h
+	}
+	from, _ := i.Next()
+	if from <= h.From {
+		return h
+	}
+	if h.To > 0 && from > h.To {
+		from = h.To
+	}
+	log.Debug("stream resume", "peer", p.ID(), "stream", s, "history", h, "from", from)
+	return NewRange(from, h.To)
+}
+
 func (r *Registry) Unsubscribe(peerId discover.NodeID, s Stream) error {
 	peer := r.getPeer(peerId)
 	if peer == nil {
@@ -323,8 +391,17 @@ func (r *Registry) Quit(peerId discover.NodeID, s Stream) error {
 	return peer.Send(msg)
 }
 
-func (r *Registry) Retrieve(chunk *storage.Chunk) error {
-	return r.delivery.RequestFromPeers(chunk.Addr[:], r.skipCheck)
+// Retrieve requests the chunk from the peers, the request is traced as a
+// part of the request in ctx
+func (r *Registry) Retrieve(ctx context.Context, chunk *storage.Chunk) error {
+	_, sp := tracing.StartSpan(ctx, "stream.retrieve")
+	defer sp.Finish()
+	sp.SetTag("addr", chunk.Addr.Hex())
+
+	r.delivery.requests.add(chunk.Addr, RequestOriginLocal)
+	err := r.delivery.RequestFromPeers(chunk.Addr[:], r.skipCheck)
+	sp.SetError(err)
+	return err
 }
 
 func (r *Registry) NodeInfo() interface{} {
@@ -358,6 +435,7 @@ func (r *Registry) deletePeer(peer *Peer) {
 	delete(r.peers, peer.ID())
 	metrics.GetOrRegisterGauge("registry.peers", nil).Update(int64(len(r.peers)))
 	r.peersMu.Unlock()
+	r.delivery.reputation.Removed(peer.ID())
 }
 
 func (r *Registry) peersCount() (c int) {
@@ -367,13 +445,74 @@ func (r *Registry) peersCount() (c int) {
 	return
 }
 
+// SyncStatus is a summary of the SYNC streams of the registry
+type SyncStatus struct {
+	Enabled bool `json:"enabled"` // whether subscriptions to SYNC streams are requested
+	Peers   int  `json:"peers"`   // number of stream protocol peers
+	Syncing int  `json:"syncing"` // number of peers with SYNC streams
+	Servers int  `json:"servers"` // SYNC streams served to peers
+	Clients int  `json:"clients"` // SYNC streams received from peers
+}
+
+// SyncStatus returns the number of peers and SYNC streams the node syncs
+// chunks with
+func (r *Registry) SyncStatus() *SyncStatus {
+	status := &SyncStatus{Enabled: r.doSync}
+	r.peersMu.RLock()
+	defer r.peersMu.RUnlock()
+	status.Peers = len(r.peers)
+	for _, peer := range r.peers {
+		var servers, clients int
+		peer.serverMu.RLock()
+		for stream := range peer.servers {
+			if stream.Name == "SYNC" {
+				servers++
+			}
+		}
+		peer.serverMu.RUnlock()
+		peer.clientMu.RLock()
+		for stream := range peer.clients {
+			if stream.Name == "SYNC" {
+				clients++
+			}
+		}
+		peer.clientMu.RUnlock()
+		if servers+clients > 0 {
+			status.Syncing++
+		}
+		status.Servers += servers
+		status.Clients += clients
+	}
+	return status
+}
+
 // Run protocol run function
 func (r *Registry) Run(p *network.BzzPeer) error {
+	return r.run(p, true)
+}
+
+// RunLegacy is the run function of version 3 of the streamer protocol, it
+// runs the protocol without SWAP accounting, which older nodes do not support
+func (r *Registry) RunLegacy(p *network.BzzPeer) error {
+	return r.run(p, false)
+}
+
+// run runs the streamer protocol with the peer, exchanging SWAP profiles if
+// the negotiated protocol version supports SWAP and SWAP is enabled
+func (r *Registry) run(p *network.BzzPeer, swap bool) error {
 	sp := NewPeer(p.Peer, r)
+	sp.compression = p.HasCapability(network.CapabilityCompression)
 	r.setPeer(sp)
 	defer r.deletePeer(sp)
 	defer close(sp.quit)
 	defer sp.close()
+	defer sp.stopSwap()
+
+	if swap && r.swapProfile != nil {
+		if err := sp.sendSwapProfile(); err != nil {
+			return err
+		}
+	}
 
 	if r.doRetrieve {
 		err := r.Subscribe(p.ID(), NewStream(swarmChunkServerStreamName, "", false), nil, Top)
@@ -459,6 +598,14 @@ func (r *Registry) runProtocol(p *p2p.Peer, rw p2p.MsgReadWriter) error {
 	return r.Run(bzzPeer)
 }
 
+func (r *Registry) runLegacyProtocol(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	peer := protocols.NewPeer(p, rw, LegacySpec)
+	bzzPeer := network.NewBzzTestPeer(peer, r.addr)
+	r.delivery.overlay.On(bzzPeer)
+	defer r.delivery.overlay.Off(bzzPeer)
+	return r.RunLegacy(bzzPeer)
+}
+
 // HandleMsg is the message handler that delegates incoming messages
 func (p *Peer) HandleMsg(msg interface{}) error {
 	switch msg := msg.(type) {
@@ -490,6 +637,12 @@ func (p *Peer) HandleMsg(msg interface{}) error {
 	case *RequestSubscriptionMsg:
 		return p.handleRequestSubscription(msg)
 
+	case *SwapProfileMsg:
+		return p.handleSwapProfileMsg(msg)
+
+	case *PaymentMsg:
+		return p.handlePaymentMsg(msg)
+
 	case *QuitMsg:
 		return p.handleQuitMsg(msg)
 
@@ -644,6 +797,28 @@ func (c *clientParams) clientCreated() {
 
 // Spec is the spec of the streamer protocol
 var Spec = &protocols.Spec{
+	Name:       "stream",
+	Version:    4,
+	MaxMsgSize: 10 * 1024 * 1024,
+	Messages: []interface{}{
+		UnsubscribeMsg{},
+		OfferedHashesMsg{},
+		WantedHashesMsg{},
+		TakeoverProofMsg{},
+		SubscribeMsg{},
+		RetrieveRequestMsg{},
+		ChunkDeliveryMsg{},
+		SubscribeErrorMsg{},
+		RequestSubscriptionMsg{},
+		QuitMsg{},
+		SwapProfileMsg{},
+		PaymentMsg{},
+	},
+}
+
+// LegacySpec is the spec of version 3 of the streamer protocol, which has no
+// SWAP messages. It is still offered to connect to older nodes.
+var LegacySpec = &protocols.Spec{
 	Name:       "stream",
 	Version:    3,
 	MaxMsgSize: 10 * 1024 * 1024,
@@ -671,6 +846,12 @@ func (r *Registry) Protocols() []p2p.Protocol {
 			// NodeInfo: ,
 			// PeerInfo: ,
 		},
+		{
+			Name:    LegacySpec.Name,
+			Version: LegacySpec.Version,
+			Length:  LegacySpec.Length(),
+			Run:     r.runLegacyProtocol,
+		},
 	}
 }
 
@@ -682,18 +863,66 @@ func (r *Registry) APIs() []rpc.API {
 			Service:   r.api,
 			Public:    true,
 		},
+		{
+			Namespace: "stream",
+			Version:   "3.0",
+			Service:   NewRequestsAPI(r.delivery),
+			Public:    false,
+		},
 	}
 }
 
 func (r *Registry) Start(server *p2p.Server) error {
 	log.Info("Streamer started")
+	if r.ledger != nil {
+		go r.persistAccounting()
+	}
 	return nil
 }
 
 func (r *Registry) Stop() error {
+	r.quitOnce.Do(func() { close(r.quit) })
+	if r.ledger != nil {
+		return r.saveAccounting()
+	}
 	return nil
 }
 
+// persistAccounting periodically writes the accounting state to the store
+func (r *Registry) persistAccounting() {
+	ticker := time.NewTicker(ledgerFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.saveAccounting(); err != nil {
+				log.Warn("unable to save accounting state", "err", err)
+			}
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// saveAccounting writes the per-peer traffic, the SWAP balances and the
+// pending ledger entries to the store
+func (r *Registry) saveAccounting() error {
+	if err := r.delivery.traffic.save(r.intervalsStore); err != nil {
+		return err
+	}
+	r.peersMu.RLock()
+	for id, p := range r.peers {
+		if s := p.getSwap(); s != nil {
+			r.swapBalances.set(id, s.Balance())
+		}
+	}
+	r.peersMu.RUnlock()
+	if err := r.swapBalances.save(r.intervalsStore); err != nil {
+		return err
+	}
+	return r.ledger.Flush()
+}
+
 type Range struct {
 	From, To uint64
 }
@@ -737,3 +966,38 @@ func (api *API) SubscribeStream(peerId discover.NodeID, s Stream, history *Range
 func (api *API) UnsubscribeStream(peerId discover.NodeID, s Stream) error {
 	return api.streamer.Unsubscribe(peerId, s)
 }
+
+// PeerScores returns the retrieval statistics and reputation scores
+// of peers, keyed by their node IDs
+func (api *API) PeerScores() map[string]PeerScore {
+	scores := make(map[string]PeerScore)
+	for id, s := range api.streamer.delivery.reputation.Scores() {
+		scores[id.String()] = s
+	}
+	return scores
+}
+
+// PeerTraffic returns the chunk traffic exchanged with peers and the
+// resulting balances, keyed by their node IDs
+func (api *API) PeerTraffic() map[string]PeerTraffic {
+	traffic := make(map[string]PeerTraffic)
+	for id, t := range api.streamer.delivery.traffic.Peers() {
+		traffic[id.String()] = t
+	}
+	return traffic
+}
+
+// PeerBalance returns the chunk traffic exchanged with the peer
+func (api *API) PeerBalance(peerId discover.NodeID) PeerTraffic {
+	return api.streamer.delivery.traffic.Peer(peerId)
+}
+
+// ExportLedger returns the accounting ledger entries between the unix
+// times from (inclusive) and to (exclusive) encoded in format ("json" or
+// "csv") and signed by the node
+func (api *API) ExportLedger(from, to int64, format string) (*SignedLedger, error) {
+	if api.streamer.ledger == nil {
+		return nil, errors.New("accounting ledger not available")
+	}
+	return api.streamer.ledger.Export(time.Unix(from, 0), time.Unix(to, 0), format, api.streamer.ledgerKey)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/crypto/sha3"
 	p2ptest "github.com/ethereum/go-ethereum/p2p/testing"
+	"github.com/ethereum/go-ethereum/swarm/network/stream/intervals"
 )
 
 func TestStreamerSubscribe(t *testing.T) {
@@ -214,6 +215,54 @@ func TestStreamerDownstreamSubscribeUnsubscribeMsgExchange(t *testing.T) {
 	}
 }
 
+func TestStreamerDownstreamSubscribeResume(t *testing.T) {
+	tester, streamer, _, teardown, err := newStreamerTester(t)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamer.RegisterClientFunc("foo", func(p *Peer, t string, live bool) (Client, error) {
+		return newTestClient(t), nil
+	})
+
+	peerID := tester.IDs[0]
+	stream := NewStream("foo", "", true)
+
+	// intervals synced in a previous session with the same peer
+	i := intervals.NewIntervals(1)
+	i.Add(1, 20)
+	key := peerStreamIntervalsKey(streamer.getPeer(peerID), getHistoryStream(stream))
+	if err := streamer.intervalsStore.Put(key, i); err != nil {
+		t.Fatal(err)
+	}
+
+	err = streamer.Subscribe(peerID, stream, NewRange(0, 0), Top)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err = tester.TestExchanges(
+		p2ptest.Exchange{
+			Label: "Subscribe message",
+			Expects: []p2ptest.Expect{
+				{
+					Code: 4,
+					Msg: &SubscribeMsg{
+						Stream:   stream,
+						History:  NewRange(21, 0),
+						Priority: Top,
+					},
+					Peer: peerID,
+				},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestStreamerUpstreamSubscribeUnsubscribeMsgExchange(t *testing.T) {
 	tester, streamer, _, teardown, err := newStreamerTester(t)
 	defer teardown()
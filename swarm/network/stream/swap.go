@@ -0,0 +1,320 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	swapsvc "github.com/ethereum/go-ethereum/swarm/services/swap"
+	"github.com/ethereum/go-ethereum/swarm/services/swap/swap"
+	"github.com/ethereum/go-ethereum/swarm/state"
+)
+
+/*
+SWAP accounting of the streamer protocol
+
+If SWAP is enabled, peers exchange their SWAP profiles (prices, payment and
+disconnect thresholds and chequebook details) with SwapProfileMsg when the
+protocol starts. The SWAP messages were added in version 4 of the protocol,
+peers running version 3 exchange chunks without SWAP accounting. Every chunk delivered to the peer, both for retrieve requests
+and syncing, is credited to the local node and every chunk received from the
+peer that was needed and found valid is debited. Once the debt of the local
+node reaches the payment threshold of the peer, a chequebook cheque is sent
+with PaymentMsg; if the peer's debt reaches the local disconnect threshold,
+the peer is dropped.
+
+The balance with a peer is kept when it disconnects and restored when SWAP is
+set up with it again, so that a peer cannot clear its debt by reconnecting. If
+the registry has a state store, the balances are saved along the accounting
+state and survive restarts.
+
+If the registry has a price oracle, the chunk price offered in the profile is
+the retrieval price of the oracle when the peer connects, it applies to the
+cheques exchanged with the peer until it disconnects.
+*/
+
+var (
+	swapPaymentsSentCount     = metrics.NewRegisteredCounter("network.stream.swap.payments.sent", nil)
+	swapPaymentsReceivedCount = metrics.NewRegisteredCounter("network.stream.swap.payments.received", nil)
+)
+
+// SwapProfileMsg is the protocol msg to exchange the SWAP profiles
+type SwapProfileMsg struct {
+	BuyAt       *big.Int       // accepted max price for chunk
+	SellAt      *big.Int       // offered sale price for chunk
+	PayAt       uint64         // threshold that triggers payment request
+	DropAt      uint64         // threshold that triggers disconnect
+	Contract    common.Address // address of chequebook contract
+	Beneficiary common.Address // recipient address for swarm sales revenue
+	PublicKey   hexutil.Bytes  // public key the cheques are signed with
+}
+
+func (m SwapProfileMsg) String() string {
+	return fmt.Sprintf("SwapProfileMsg: buy at: %v, sell at: %v, pay at: %v, drop at: %v, contract: %x", m.BuyAt, m.SellAt, m.PayAt, m.DropAt, m.Contract[:4])
+}
+
+// PaymentMsg is the protocol msg to pay for units of service with a cheque
+type PaymentMsg struct {
+	Units   uint64
+	Promise *chequebook.Cheque
+}
+
+func (m PaymentMsg) String() string {
+	return fmt.Sprintf("PaymentMsg: units: %v, cheque: %v", m.Units, m.Promise)
+}
+
+// swapProtocol implements swap.Protocol for a streamer peer
+type swapProtocol struct {
+	peer *Peer
+}
+
+// Pay sends the cheque for units of service to the peer
+func (sp *swapProtocol) Pay(units int, promise swap.Promise) {
+	cheque, ok := promise.(*chequebook.Cheque)
+	if !ok {
+		log.Error("SWAP payment with unknown promise type", "peer", sp.peer.ID(), "type", fmt.Sprintf("%T", promise))
+		return
+	}
+	err := sp.peer.SendPriority(&PaymentMsg{
+		Units:   uint64(units),
+		Promise: cheque,
+	}, Top)
+	if err != nil {
+		log.Warn("SWAP payment failed", "peer", sp.peer.ID(), "units", units, "err", err)
+		return
+	}
+	if l := sp.peer.streamer.ledger; l != nil {
+		l.payment(sp.peer.ID(), LedgerPaymentSent, uint64(units), cheque.Contract, cheque.Amount)
+	}
+	swapPaymentsSentCount.Inc(1)
+}
+
+// Drop disconnects the peer
+func (sp *swapProtocol) Drop() {
+	sp.peer.Drop(errors.New("SWAP disconnect threshold reached"))
+}
+
+func (sp *swapProtocol) String() string {
+	return sp.peer.ID().TerminalString()
+}
+
+// sendSwapProfile sends the local SWAP profile to the peer
+func (p *Peer) sendSwapProfile() error {
+	local := p.streamer.swapProfile
+	sellAt := local.SellAt
+	if prices := p.streamer.swapPrices; prices != nil {
+		sellAt = new(big.Int).Set(prices.RetrievalPrice())
+	}
+	p.swapMu.Lock()
+	p.swapSellAt = sellAt
+	p.swapMu.Unlock()
+	return p.SendPriority(&SwapProfileMsg{
+		BuyAt:       local.BuyAt,
+		SellAt:      sellAt,
+		PayAt:       uint64(local.PayAt),
+		DropAt:      uint64(local.DropAt),
+		Contract:    local.Contract,
+		Beneficiary: local.Beneficiary,
+		PublicKey:   common.FromHex(local.PublicKey),
+	}, Top)
+}
+
+// handleSwapProfileMsg sets up SWAP with the peer with the received profile,
+// the profile is ignored if SWAP is not enabled so that peers with and
+// without SWAP can still exchange chunks. Only one profile is accepted per
+// connection, as a new one would reset the balance with the peer.
+func (p *Peer) handleSwapProfileMsg(req *SwapProfileMsg) error {
+	local := p.streamer.swapProfile
+	if local == nil {
+		log.Debug("SWAP not enabled, ignoring SWAP profile", "peer", p.ID())
+		return nil
+	}
+	if req.BuyAt == nil || req.SellAt == nil {
+		return errors.New("invalid SWAP profile: missing prices")
+	}
+	remote := &swapsvc.RemoteProfile{
+		Profile: &swap.Profile{
+			BuyAt:  req.BuyAt,
+			SellAt: req.SellAt,
+			PayAt:  uint(req.PayAt),
+			DropAt: uint(req.DropAt),
+		},
+		PayProfile: &swapsvc.PayProfile{
+			PublicKey:   hexutil.Encode(req.PublicKey),
+			Contract:    req.Contract,
+			Beneficiary: req.Beneficiary,
+		},
+	}
+	s, err := swapsvc.NewSwap(p.localSwapProfile(), remote, p.streamer.swapBackend, &swapProtocol{peer: p})
+	if err != nil {
+		return err
+	}
+	p.swapMu.Lock()
+	if p.swap != nil {
+		p.swapMu.Unlock()
+		s.Stop()
+		return errors.New("SWAP profile already received")
+	}
+	if balance := p.streamer.swapBalances.get(p.ID()); balance != 0 {
+		s.SetBalance(balance)
+	}
+	p.swap = s
+	p.swapMu.Unlock()
+	return nil
+}
+
+// localSwapProfile returns the local SWAP profile with the chunk price
+// offered to the peer
+func (p *Peer) localSwapProfile() *swapsvc.LocalProfile {
+	local := p.streamer.swapProfile
+	p.swapMu.RLock()
+	sellAt := p.swapSellAt
+	p.swapMu.RUnlock()
+	if sellAt == nil || sellAt.Cmp(local.SellAt) == 0 {
+		return local
+	}
+	profile := *local.Profile
+	profile.SellAt = sellAt
+	params := *local.Params
+	params.Profile = &profile
+	offered := *local
+	offered.Params = &params
+	return &offered
+}
+
+// handlePaymentMsg credits the peer with the units paid by the cheque,
+// payments are ignored if SWAP is not set up with the peer
+func (p *Peer) handlePaymentMsg(req *PaymentMsg) error {
+	s := p.getSwap()
+	if s == nil {
+		log.Debug("SWAP not set up with peer, ignoring payment", "peer", p.ID(), "units", req.Units)
+		return nil
+	}
+	if req.Promise == nil {
+		return errors.New("invalid SWAP payment: missing cheque")
+	}
+	if err := s.Receive(int(req.Units), req.Promise); err != nil {
+		return err
+	}
+	if l := p.streamer.ledger; l != nil {
+		l.payment(p.ID(), LedgerPaymentReceived, req.Units, req.Promise.Contract, req.Promise.Amount)
+	}
+	swapPaymentsReceivedCount.Inc(1)
+	return nil
+}
+
+// getSwap returns the SWAP instance of the peer,
+// nil if SWAP is not set up with the peer
+func (p *Peer) getSwap() *swap.Swap {
+	p.swapMu.RLock()
+	defer p.swapMu.RUnlock()
+	return p.swap
+}
+
+// account adds n units of service to the SWAP balance with the peer
+// n > 0 if the local node provided the service, n < 0 if the peer did
+func (p *Peer) account(n int) error {
+	s := p.getSwap()
+	if s == nil {
+		return nil
+	}
+	return s.Add(n)
+}
+
+// stopSwap stops the SWAP instance of the peer
+func (p *Peer) stopSwap() {
+	p.swapMu.Lock()
+	s := p.swap
+	p.swap = nil
+	p.swapMu.Unlock()
+	if s != nil {
+		p.streamer.swapBalances.set(p.ID(), s.Balance())
+		s.Stop()
+	}
+}
+
+// swapBalances keeps the SWAP balances with peers while they are not
+// connected
+type swapBalances struct {
+	mu       sync.Mutex
+	balances map[discover.NodeID]int
+}
+
+func newSwapBalances() *swapBalances {
+	return &swapBalances{
+		balances: make(map[discover.NodeID]int),
+	}
+}
+
+// get returns the balance with the peer
+func (b *swapBalances) get(id discover.NodeID) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.balances[id]
+}
+
+// set records the balance with the peer
+func (b *swapBalances) set(id discover.NodeID, balance int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if balance == 0 {
+		delete(b.balances, id)
+		return
+	}
+	b.balances[id] = balance
+}
+
+// load restores the balances saved in the store
+func (b *swapBalances) load(store state.Store) error {
+	var saved map[string]int
+	if err := store.Get(swapBalancesKey, &saved); err != nil {
+		if err == state.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, balance := range saved {
+		nodeID, err := discover.HexID(id)
+		if err != nil {
+			return err
+		}
+		b.balances[nodeID] = balance
+	}
+	return nil
+}
+
+// save writes the balances to the store
+func (b *swapBalances) save(store state.Store) error {
+	b.mu.Lock()
+	saved := make(map[string]int, len(b.balances))
+	for id, balance := range b.balances {
+		saved[id.String()] = balance
+	}
+	b.mu.Unlock()
+	return store.Put(swapBalancesKey, saved)
+}
@@ -0,0 +1,340 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
+	"github.com/ethereum/go-ethereum/core"
+	p2ptest "github.com/ethereum/go-ethereum/p2p/testing"
+	swapsvc "github.com/ethereum/go-ethereum/swarm/services/swap"
+	"github.com/ethereum/go-ethereum/swarm/services/swap/swap"
+)
+
+type testOutPayment struct{}
+
+func (o *testOutPayment) Issue(amount *big.Int) (swap.Promise, error) {
+	return &chequebook.Cheque{Amount: amount}, nil
+}
+
+func (o *testOutPayment) AutoDeposit(time.Duration, *big.Int, *big.Int) {}
+
+func (o *testOutPayment) Stop() {}
+
+type testInPayment struct{}
+
+func (i *testInPayment) Receive(promise swap.Promise) (*big.Int, error) {
+	return promise.(*chequebook.Cheque).Amount, nil
+}
+
+func (i *testInPayment) AutoCash(time.Duration, *big.Int) {}
+
+func (i *testInPayment) Stop() {}
+
+// TestSwapAccounting checks that a payment is sent once the debt reaches
+// the payment threshold of the peer and that received payments are credited
+func TestSwapAccounting(t *testing.T) {
+	tester, streamer, _, teardown, err := newStreamerTester(t)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerID := tester.IDs[0]
+	sp := streamer.getPeer(peerID)
+
+	price := big.NewInt(10)
+	profile := &swap.Profile{
+		BuyAt:  price,
+		SellAt: price,
+		PayAt:  2,
+		DropAt: 100,
+	}
+	params := &swap.Params{
+		Profile:  profile,
+		Strategy: &swap.Strategy{},
+	}
+	s, err := swap.New(params, swap.Payment{
+		Out:   &testOutPayment{},
+		In:    &testInPayment{},
+		Buys:  true,
+		Sells: true,
+	}, &swapProtocol{peer: sp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetRemote(profile)
+	sp.swapMu.Lock()
+	sp.swap = s
+	sp.swapMu.Unlock()
+
+	// two chunks received from the peer reach its payment threshold
+	if err := sp.account(-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.account(-1); err != nil {
+		t.Fatal(err)
+	}
+	err = tester.TestExchanges(p2ptest.Exchange{
+		Label: "Payment",
+		Expects: []p2ptest.Expect{
+			{
+				Code: 11,
+				Msg: &PaymentMsg{
+					Units:   2,
+					Promise: &chequebook.Cheque{Amount: big.NewInt(20)},
+				},
+				Peer: peerID,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := s.Balance(); b != 0 {
+		t.Fatalf("expected balance 0 after payment, got %v", b)
+	}
+
+	// chunks delivered to the peer are credited
+	for i := 0; i < 3; i++ {
+		if err := sp.account(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = tester.TestExchanges(p2ptest.Exchange{
+		Label: "Receive payment",
+		Triggers: []p2ptest.Trigger{
+			{
+				Code: 11,
+				Msg: &PaymentMsg{
+					Units:   3,
+					Promise: &chequebook.Cheque{Amount: big.NewInt(30)},
+				},
+				Peer: peerID,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if b := s.Balance(); b != 0 {
+		t.Fatalf("expected balance 0 after received payment, got %v", b)
+	}
+}
+
+// TestSwapNotEnabled checks that a node without SWAP ignores the SWAP
+// messages of a peer with SWAP enabled instead of disconnecting it
+func TestSwapNotEnabled(t *testing.T) {
+	tester, streamer, _, teardown, err := newStreamerTester(t)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerID := tester.IDs[0]
+
+	err = tester.TestExchanges(p2ptest.Exchange{
+		Label: "SWAP messages",
+		Triggers: []p2ptest.Trigger{
+			{
+				Code: 10,
+				Msg: &SwapProfileMsg{
+					BuyAt:  big.NewInt(10),
+					SellAt: big.NewInt(10),
+					PayAt:  2,
+					DropAt: 100,
+				},
+				Peer: peerID,
+			},
+			{
+				Code: 11,
+				Msg: &PaymentMsg{
+					Units:   1,
+					Promise: &chequebook.Cheque{Amount: big.NewInt(10)},
+				},
+				Peer: peerID,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	sp := streamer.getPeer(peerID)
+	if sp == nil {
+		t.Fatal("expected peer with SWAP enabled to stay connected")
+	}
+	if sp.getSwap() != nil {
+		t.Fatal("expected SWAP not to be set up with the peer")
+	}
+}
+
+// TestSwapProfilePrice checks that the chunk price offered in the SWAP profile
+// is the retrieval price of the price oracle and that it is the price of the
+// SWAP accounting with the peer
+func TestSwapProfilePrice(t *testing.T) {
+	profile := swapsvc.NewDefaultSwapParams()
+	price := big.NewInt(42)
+	tester, streamer, _, teardown, err := newStreamerTesterWithOptions(t, &RegistryOptions{
+		SkipCheck: defaultSkipCheck,
+		Swap:      profile,
+		Prices:    swapsvc.NewStaticPriceOracle(price, big.NewInt(7)),
+	})
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerID := tester.IDs[0]
+
+	err = tester.TestExchanges(p2ptest.Exchange{
+		Label: "SWAP profile",
+		Expects: []p2ptest.Expect{
+			{
+				Code: 10,
+				Msg: &SwapProfileMsg{
+					BuyAt:     profile.BuyAt,
+					SellAt:    price,
+					PayAt:     uint64(profile.PayAt),
+					DropAt:    uint64(profile.DropAt),
+					PublicKey: []byte{},
+				},
+				Peer: peerID,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := streamer.getPeer(peerID).localSwapProfile()
+	if local.SellAt.Cmp(price) != 0 {
+		t.Fatalf("expected SWAP sale price %v, got %v", price, local.SellAt)
+	}
+	if profile.SellAt.Cmp(price) == 0 {
+		t.Fatalf("expected configured sale price to be kept, got %v", profile.SellAt)
+	}
+}
+
+// TestSwapProfileOnce checks that only the first SWAP profile of a peer is
+// accepted, so that a peer cannot reset its balance by sending a new one
+func TestSwapProfileOnce(t *testing.T) {
+	tester, streamer, _, teardown, err := newStreamerTesterWithOptions(t, &RegistryOptions{
+		SkipCheck:   defaultSkipCheck,
+		Swap:        swapsvc.NewDefaultSwapParams(),
+		SwapBackend: backends.NewSimulatedBackend(core.GenesisAlloc{}),
+	})
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := streamer.getPeer(tester.IDs[0])
+
+	profile := &SwapProfileMsg{
+		BuyAt:  big.NewInt(10),
+		SellAt: big.NewInt(10),
+		PayAt:  2,
+		DropAt: 100,
+	}
+	if err := sp.handleSwapProfileMsg(profile); err != nil {
+		t.Fatal(err)
+	}
+	s := sp.getSwap()
+	if s == nil {
+		t.Fatal("expected SWAP to be set up with the peer")
+	}
+	s.Add(-1)
+	if err := sp.handleSwapProfileMsg(profile); err == nil {
+		t.Fatal("expected error for a second SWAP profile")
+	}
+	if sp.getSwap() != s {
+		t.Fatal("expected SWAP with the peer not to be replaced")
+	}
+	if b := s.Balance(); b != -1 {
+		t.Fatalf("expected balance -1, got %v", b)
+	}
+}
+
+// TestSwapLegacyVersion checks that version 3 of the streamer protocol, which
+// has no SWAP messages, is still offered along version 4
+func TestSwapLegacyVersion(t *testing.T) {
+	_, streamer, _, teardown, err := newStreamerTester(t)
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make(map[uint]uint64)
+	for _, p := range streamer.Protocols() {
+		versions[p.Version] = p.Length
+	}
+	if l, ok := versions[4]; !ok || l != 12 {
+		t.Fatalf("expected version 4 with 12 messages, got %v", versions)
+	}
+	if l, ok := versions[3]; !ok || l != 10 {
+		t.Fatalf("expected version 3 with 10 messages, got %v", versions)
+	}
+}
+
+// TestSwapBalanceKept checks that the SWAP balance with a peer is restored
+// when SWAP is set up with it again and that it is saved to the state store
+func TestSwapBalanceKept(t *testing.T) {
+	tester, streamer, _, teardown, err := newStreamerTesterWithOptions(t, &RegistryOptions{
+		SkipCheck:   defaultSkipCheck,
+		Swap:        swapsvc.NewDefaultSwapParams(),
+		SwapBackend: backends.NewSimulatedBackend(core.GenesisAlloc{}),
+	})
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerID := tester.IDs[0]
+	sp := streamer.getPeer(peerID)
+
+	profile := &SwapProfileMsg{
+		BuyAt:  big.NewInt(10),
+		SellAt: big.NewInt(10),
+		PayAt:  100,
+		DropAt: 100,
+	}
+	if err := sp.handleSwapProfileMsg(profile); err != nil {
+		t.Fatal(err)
+	}
+	sp.getSwap().SetBalance(-3)
+	sp.stopSwap()
+
+	// the peer reconnects and sends its profile again
+	if err := sp.handleSwapProfileMsg(profile); err != nil {
+		t.Fatal(err)
+	}
+	if b := sp.getSwap().Balance(); b != -3 {
+		t.Fatalf("expected restored balance -3, got %v", b)
+	}
+	sp.getSwap().SetBalance(-5)
+
+	if err := streamer.saveAccounting(); err != nil {
+		t.Fatal(err)
+	}
+	balances := newSwapBalances()
+	if err := balances.load(streamer.intervalsStore); err != nil {
+		t.Fatal(err)
+	}
+	if b := balances.get(peerID); b != -5 {
+		t.Fatalf("expected saved balance -5, got %v", b)
+	}
+}
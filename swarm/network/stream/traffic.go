@@ -0,0 +1,180 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/swarm/state"
+)
+
+var (
+	trafficChunksSentCount     = metrics.NewRegisteredCounter("network.stream.traffic.chunks.sent", nil)
+	trafficChunksReceivedCount = metrics.NewRegisteredCounter("network.stream.traffic.chunks.received", nil)
+	trafficBytesSentCount      = metrics.NewRegisteredCounter("network.stream.traffic.bytes.sent", nil)
+	trafficBytesReceivedCount  = metrics.NewRegisteredCounter("network.stream.traffic.bytes.received", nil)
+)
+
+// PeerTraffic holds the chunk traffic exchanged with a peer
+// it is tracked regardless of whether SWAP is enabled
+type PeerTraffic struct {
+	ChunksSent     uint64   `json:"chunksSent"`     // number of chunks delivered to the peer
+	ChunksReceived uint64   `json:"chunksReceived"` // number of valid chunks received from the peer
+	BytesSent      uint64   `json:"bytesSent"`      // chunk data bytes delivered to the peer
+	BytesReceived  uint64   `json:"bytesReceived"`  // chunk data bytes received from the peer
+	Balance        int64    `json:"balance"`        // chunks sent minus chunks received, positive if the peer is indebted
+	ValueSent      *big.Int `json:"valueSent"`      // value of the chunks delivered to the peer at the prices of the oracle (wei)
+	ValueReceived  *big.Int `json:"valueReceived"`  // value of the chunks received from the peer at the prices of the oracle (wei)
+}
+
+// copy returns a deep copy of the account
+func (a *PeerTraffic) copy() PeerTraffic {
+	c := *a
+	c.ValueSent = new(big.Int).Set(a.ValueSent)
+	c.ValueReceived = new(big.Int).Set(a.ValueReceived)
+	return c
+}
+
+// Traffic keeps per-peer accounts of the chunks exchanged
+type Traffic struct {
+	mu     sync.RWMutex
+	peers  map[discover.NodeID]*PeerTraffic
+	ledger *Ledger // records the traffic over time, nil if not persisted
+}
+
+// NewTraffic is the Traffic constructor
+func NewTraffic() *Traffic {
+	return &Traffic{
+		peers: make(map[discover.NodeID]*PeerTraffic),
+	}
+}
+
+// account returns the account of the peer, creating it if needed
+// caller must hold the lock
+func (t *Traffic) account(id discover.NodeID) *PeerTraffic {
+	a := t.peers[id]
+	if a == nil {
+		a = &PeerTraffic{
+			ValueSent:     new(big.Int),
+			ValueReceived: new(big.Int),
+		}
+		t.peers[id] = a
+	}
+	return a
+}
+
+// Sent records a chunk of size bytes delivered to the peer at price
+func (t *Traffic) Sent(id discover.NodeID, size int, price *big.Int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a := t.account(id)
+	a.ChunksSent++
+	a.BytesSent += uint64(size)
+	a.Balance++
+	a.ValueSent.Add(a.ValueSent, price)
+	if t.ledger != nil {
+		t.ledger.sent(id, size, price)
+	}
+	trafficChunksSentCount.Inc(1)
+	trafficBytesSentCount.Inc(int64(size))
+}
+
+// Received records a chunk of size bytes received from the peer at price
+func (t *Traffic) Received(id discover.NodeID, size int, price *big.Int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	a := t.account(id)
+	a.ChunksReceived++
+	a.BytesReceived += uint64(size)
+	a.Balance--
+	a.ValueReceived.Add(a.ValueReceived, price)
+	if t.ledger != nil {
+		t.ledger.received(id, size, price)
+	}
+	trafficChunksReceivedCount.Inc(1)
+	trafficBytesReceivedCount.Inc(int64(size))
+}
+
+// Peer returns the account of the peer
+func (t *Traffic) Peer(id discover.NodeID) PeerTraffic {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if a, ok := t.peers[id]; ok {
+		return a.copy()
+	}
+	return PeerTraffic{
+		ValueSent:     new(big.Int),
+		ValueReceived: new(big.Int),
+	}
+}
+
+// Peers returns a copy of the accounts of all peers
+func (t *Traffic) Peers() map[discover.NodeID]PeerTraffic {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	peers := make(map[discover.NodeID]PeerTraffic, len(t.peers))
+	for id, a := range t.peers {
+		peers[id] = a.copy()
+	}
+	return peers
+}
+
+// load restores the accounts saved in the store
+func (t *Traffic) load(store state.Store) error {
+	var saved map[string]*PeerTraffic
+	if err := store.Get(trafficKey, &saved); err != nil {
+		if err == state.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, a := range saved {
+		nodeID, err := discover.HexID(id)
+		if err != nil {
+			return err
+		}
+		if a.ValueSent == nil {
+			a.ValueSent = new(big.Int)
+		}
+		if a.ValueReceived == nil {
+			a.ValueReceived = new(big.Int)
+		}
+		t.peers[nodeID] = a
+	}
+	return nil
+}
+
+// save writes the accounts to the store
+func (t *Traffic) save(store state.Store) error {
+	t.mu.RLock()
+	saved := make(map[string]*PeerTraffic, len(t.peers))
+	for id, a := range t.peers {
+		c := a.copy()
+		saved[id.String()] = &c
+	}
+	t.mu.RUnlock()
+	return store.Put(trafficKey, saved)
+}
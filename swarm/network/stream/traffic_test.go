@@ -0,0 +1,67 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stream
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func TestTraffic(t *testing.T) {
+	tr := NewTraffic()
+	a := discover.NodeID{1}
+	b := discover.NodeID{2}
+	retrieval := big.NewInt(3)
+	storage := big.NewInt(2)
+
+	tr.Sent(a, 4096, retrieval)
+	tr.Sent(a, 4096, storage)
+	tr.Received(a, 100, retrieval)
+	tr.Received(b, 4096, storage)
+
+	expected := PeerTraffic{
+		ChunksSent:     2,
+		ChunksReceived: 1,
+		BytesSent:      8192,
+		BytesReceived:  100,
+		Balance:        1,
+		ValueSent:      big.NewInt(5),
+		ValueReceived:  big.NewInt(3),
+	}
+	if got := tr.Peer(a); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+	if got := tr.Peer(b).Balance; got != -1 {
+		t.Fatalf("expected balance -1, got %v", got)
+	}
+	if got := tr.Peer(discover.NodeID{3}); got.ChunksSent != 0 || got.ValueSent.Sign() != 0 {
+		t.Fatalf("expected empty account for unknown peer, got %+v", got)
+	}
+
+	// returned accounts are copies
+	peers := tr.Peers()
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 accounts, got %v", len(peers))
+	}
+	peers[a].ValueSent.SetInt64(0)
+	if got := tr.Peer(a).ValueSent; got.Cmp(big.NewInt(5)) != 0 {
+		t.Fatalf("expected value sent 5, got %v", got)
+	}
+}
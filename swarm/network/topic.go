@@ -0,0 +1,169 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/discv5"
+)
+
+/*
+TopicDiscovery advertises the node under the topic of its swarm network on the
+v5 discovery DHT and searches the topic for other swarm nodes, so that nodes of
+the same network find each other instead of random-walking the global node table
+
+Nodes found are dialed as they come in. Once enough nodes were found, the search
+is slowed down to only pick up nodes joining the network later.
+*/
+
+var errDiscV5Disabled = errors.New("v5 discovery not enabled")
+
+// BzzTopic returns the discovery topic swarm nodes of the network advertise
+func BzzTopic(networkID uint64) discv5.Topic {
+	return discv5.Topic(fmt.Sprintf("BZZ@%d", networkID))
+}
+
+// TopicDiscoveryParams holds the config options of TopicDiscovery
+type TopicDiscoveryParams struct {
+	FastSearchInterval time.Duration // interval between topic lookups until enough nodes are found
+	SlowSearchInterval time.Duration // interval between topic lookups afterwards
+	TargetNodes        int           // number of nodes found after which the search slows down
+}
+
+// NewTopicDiscoveryParams returns topic discovery params with default values
+func NewTopicDiscoveryParams() *TopicDiscoveryParams {
+	return &TopicDiscoveryParams{
+		FastSearchInterval: 100 * time.Millisecond,
+		SlowSearchInterval: time.Minute,
+		TargetNodes:        25,
+	}
+}
+
+// TopicDiscovery registers and searches the swarm topic of a network
+type TopicDiscovery struct {
+	*TopicDiscoveryParams
+	topic   discv5.Topic
+	self    discover.NodeID
+	addPeer func(*discover.Node)
+
+	mu    sync.Mutex
+	nodes map[discover.NodeID]struct{}
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewTopicDiscovery is the TopicDiscovery constructor
+func NewTopicDiscovery(networkID uint64, params *TopicDiscoveryParams) *TopicDiscovery {
+	return &TopicDiscovery{
+		TopicDiscoveryParams: params,
+		topic:                BzzTopic(networkID),
+		nodes:                make(map[discover.NodeID]struct{}),
+	}
+}
+
+// Start advertises the node under the topic and starts searching it, adding the
+// nodes found as peers of the server
+func (t *TopicDiscovery) Start(server *p2p.Server) error {
+	if server.DiscV5 == nil {
+		return errDiscV5Disabled
+	}
+	t.start(server.Self().ID, server.DiscV5.RegisterTopic, server.DiscV5.SearchTopic, server.AddPeer)
+	return nil
+}
+
+func (t *TopicDiscovery) start(self discover.NodeID, register func(discv5.Topic, <-chan struct{}), search func(discv5.Topic, <-chan time.Duration, chan<- *discv5.Node, chan<- bool), addPeer func(*discover.Node)) {
+	t.self = self
+	t.addPeer = addPeer
+	t.quit = make(chan struct{})
+
+	setPeriod := make(chan time.Duration, 2) // room for both the fast and slow period
+	found := make(chan *discv5.Node, 100)
+
+	t.wg.Add(3)
+	go func() {
+		defer t.wg.Done()
+		register(t.topic, t.quit)
+	}()
+	go func() {
+		defer t.wg.Done()
+		search(t.topic, setPeriod, found, nil)
+	}()
+	go t.loop(setPeriod, found)
+}
+
+// Stop withdraws the topic advertisement and terminates the search
+func (t *TopicDiscovery) Stop() error {
+	close(t.quit)
+	t.wg.Wait()
+	return nil
+}
+
+// Found returns the number of distinct swarm nodes found so far
+func (t *TopicDiscovery) Found() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.nodes)
+}
+
+func (t *TopicDiscovery) loop(setPeriod chan time.Duration, found <-chan *discv5.Node) {
+	defer t.wg.Done()
+
+	setPeriod <- t.FastSearchInterval
+	fast := true
+	for {
+		select {
+		case n := <-found:
+			if !t.add(n) {
+				continue
+			}
+			if fast && t.Found() >= t.TargetNodes {
+				log.Debug("enough swarm nodes found, slowing down topic search", "topic", t.topic, "nodes", t.TargetNodes)
+				fast = false
+				setPeriod <- t.SlowSearchInterval
+			}
+		case <-t.quit:
+			// closing the period channel ends the search
+			close(setPeriod)
+			return
+		}
+	}
+}
+
+// add dials the node found by the search if it is a new one
+func (t *TopicDiscovery) add(n *discv5.Node) bool {
+	id := discover.NodeID(n.ID)
+	if id == t.self {
+		return false
+	}
+	t.mu.Lock()
+	_, known := t.nodes[id]
+	t.nodes[id] = struct{}{}
+	t.mu.Unlock()
+	if known {
+		return false
+	}
+	log.Debug("swarm node found by topic discovery", "topic", t.topic, "node", id)
+	t.addPeer(discover.NewNode(id, n.IP, n.UDP, n.TCP))
+	return true
+}
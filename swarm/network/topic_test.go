@@ -0,0 +1,102 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/discv5"
+)
+
+func TestTopicDiscovery(t *testing.T) {
+	params := NewTopicDiscoveryParams()
+	params.TargetNodes = 2
+	td := NewTopicDiscovery(DefaultNetworkID, params)
+
+	var (
+		registered = make(chan discv5.Topic, 1)
+		periods    = make(chan time.Duration, 10)
+		searchDone = make(chan struct{})
+		found      chan<- *discv5.Node
+		ready      = make(chan struct{})
+		added      = make(chan discover.NodeID, 10)
+	)
+	register := func(topic discv5.Topic, stop <-chan struct{}) {
+		registered <- topic
+		<-stop
+	}
+	search := func(topic discv5.Topic, setPeriod <-chan time.Duration, f chan<- *discv5.Node, lookup chan<- bool) {
+		found = f
+		close(ready)
+		for period := range setPeriod {
+			periods <- period
+		}
+		close(searchDone)
+	}
+	self := discover.NodeID{1}
+	td.start(self, register, search, func(n *discover.Node) { added <- n.ID })
+
+	if topic := <-registered; topic != BzzTopic(DefaultNetworkID) {
+		t.Fatalf("registered topic mismatch: have %s, want %s", topic, BzzTopic(DefaultNetworkID))
+	}
+	if period := <-periods; period != params.FastSearchInterval {
+		t.Fatalf("initial search period mismatch: have %v, want %v", period, params.FastSearchInterval)
+	}
+	<-ready
+
+	// the node itself and duplicates are not dialed
+	for _, id := range []discv5.NodeID{{1}, {2}, {2}, {3}} {
+		found <- discv5.NewNode(id, net.IP{127, 0, 0, 1}, 30303, 30303)
+	}
+	for _, want := range []discover.NodeID{{2}, {3}} {
+		select {
+		case id := <-added:
+			if id != want {
+				t.Fatalf("dialed node mismatch: have %x, want %x", id[:4], want[:4])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("node %x not dialed", want[:4])
+		}
+	}
+	// enough nodes were found, so the search slows down
+	select {
+	case period := <-periods:
+		if period != params.SlowSearchInterval {
+			t.Fatalf("search period mismatch: have %v, want %v", period, params.SlowSearchInterval)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("search not slowed down")
+	}
+	if n := td.Found(); n != 2 {
+		t.Fatalf("found node count mismatch: have %d, want %d", n, 2)
+	}
+	select {
+	case id := <-added:
+		t.Fatalf("unexpected node dialed: %x", id[:4])
+	default:
+	}
+
+	td.Stop()
+	select {
+	case <-searchDone:
+	case <-time.After(time.Second):
+		t.Fatal("search not terminated")
+	}
+}
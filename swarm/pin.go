@@ -0,0 +1,115 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swarm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// PinInfo is a pinned chunk tree, the size of its content and the number of
+// times it is pinned
+type PinInfo struct {
+	Root  storage.Address `json:"root"`
+	Size  int64           `json:"size"`
+	Count int             `json:"count"`
+}
+
+// PinAPI implements the bzz_pin, bzz_unpin and bzz_pins RPC methods
+type PinAPI struct {
+	swarm *Swarm
+}
+
+// Pin excludes the chunks of the content at ref, a hash or an ENS name, from
+// garbage collection. If recursive is true and the content is a manifest, the
+// content of all of its entries is pinned as well, with the parity chunks of
+// erasure coded entries. It returns the roots of the pinned chunk trees.
+func (self *PinAPI) Pin(ref string, recursive bool) ([]storage.Address, error) {
+	return self.update(ref, recursive, self.swarm.lstore.PinErasure)
+}
+
+// Unpin unpins the content at ref once, and the content of its entries if
+// recursive is true and it is a manifest. Content pinned several times, e.g.
+// because it is referenced by several recursively pinned manifests, stays
+// pinned until it is unpinned as many times.
+func (self *PinAPI) Unpin(ref string, recursive bool) ([]storage.Address, error) {
+	return self.update(ref, recursive, func(root storage.Address, _ int) error {
+		return self.swarm.lstore.Unpin(root)
+	})
+}
+
+// Pins returns the pinned chunk trees with the sizes of their content
+func (self *PinAPI) Pins() ([]PinInfo, error) {
+	trees, err := self.swarm.lstore.ListPins()
+	if err != nil {
+		return nil, err
+	}
+	pins := make([]PinInfo, 0, len(trees))
+	for _, tree := range trees {
+		pins = append(pins, PinInfo{Root: tree.Root, Size: tree.Size, Count: tree.Count})
+	}
+	return pins, nil
+}
+
+// update applies f to the root of the content at ref and, if recursive is
+// true, to the roots of the content of its manifest entries with the number
+// of parity chunks of the content
+func (self *PinAPI) update(ref string, recursive bool, f func(storage.Address, int) error) ([]storage.Address, error) {
+	if !strings.Contains(ref, ":") {
+		ref = "bzz:/" + ref
+	}
+	uri, err := api.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := self.swarm.api.Resolve(uri)
+	if err != nil {
+		return nil, err
+	}
+	if err := f(addr, 0); err != nil {
+		return nil, fmt.Errorf("%s: %v", addr, err)
+	}
+	roots := []storage.Address{addr}
+	if !recursive {
+		return roots, nil
+	}
+
+	walker, err := self.swarm.api.NewManifestWalker(addr, nil)
+	if err != nil {
+		// not a manifest, there are no entries
+		return roots, nil
+	}
+	err = walker.Walk(func(entry *api.ManifestEntry) error {
+		if entry.Hash == "" {
+			return nil
+		}
+		root := storage.Address(common.Hex2Bytes(entry.Hash))
+		// entries referencing content which was already unpinned are skipped
+		if err := f(root, entry.Parity); err == storage.ErrNotPinned {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", entry.Path, err)
+		}
+		roots = append(roots, root)
+		return nil
+	})
+	return roots, err
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swarm
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/swarm/api"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// TestPinAPI tests that content referenced by several recursively pinned
+// manifests stays pinned until all of them are unpinned, and that encrypted
+// content is pinned with the size of its data
+func TestPinAPI(t *testing.T) {
+	config := api.NewConfig()
+
+	dir, err := ioutil.TempDir("", "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config.Path = dir
+
+	privkey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Init(privkey)
+
+	swarm, err := NewSwarm(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinAPI := &PinAPI{swarm}
+
+	first, wait, err := swarm.api.Put("shared", "text/plain", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	_, second, err := swarm.api.AddFile(first.Hex(), "/", "other.txt", []byte("other"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := swarm.api.GetEntry(first, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := entry.Hash
+
+	// counts returns the number of times each pinned tree is pinned
+	counts := func() map[string]int {
+		pins, err := pinAPI.Pins()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := make(map[string]int)
+		for _, pin := range pins {
+			counts[pin.Root.Hex()] = pin.Count
+			if pin.Root.Hex() == shared && pin.Size != 6 {
+				t.Fatalf("expected size 6 for the shared content, got %d", pin.Size)
+			}
+		}
+		return counts
+	}
+
+	roots, err := pinAPI.Pin(first.Hex(), true)
+	if err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+	if len(roots) != 2 {
+		t.Fatalf("expected the manifest and its entry to be pinned, got %v", roots)
+	}
+	if roots, err = pinAPI.Pin(second, true); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+	if len(roots) != 3 {
+		t.Fatalf("expected the manifest and its 2 entries to be pinned, got %v", roots)
+	}
+	if c := counts(); len(c) != 4 || c[shared] != 2 {
+		t.Fatalf("expected 4 pinned trees with the shared content pinned twice, got %v", c)
+	}
+
+	if _, err := pinAPI.Unpin(first.Hex(), true); err != nil {
+		t.Fatalf("failed to unpin: %v", err)
+	}
+	if c := counts(); len(c) != 3 || c[shared] != 1 {
+		t.Fatalf("expected the shared content to stay pinned, got %v", c)
+	}
+	if _, err := pinAPI.Unpin(second, true); err != nil {
+		t.Fatalf("failed to unpin: %v", err)
+	}
+	if c := counts(); len(c) != 0 {
+		t.Fatalf("expected no pinned trees, got %v", c)
+	}
+	if _, err := pinAPI.Unpin(second, false); err == nil {
+		t.Fatal("expected error unpinning content which is not pinned")
+	}
+
+	// encrypted content is pinned with the decryption key of its reference
+	encrypted, wait, err := swarm.api.Put("secret content", "text/plain", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if roots, err = pinAPI.Pin(encrypted.Hex(), true); err != nil {
+		t.Fatalf("failed to pin encrypted content: %v", err)
+	}
+	if len(roots) != 2 || len(roots[1]) != 2*storage.KeyLength {
+		t.Fatalf("expected the encrypted manifest and its entry to be pinned, got %v", roots)
+	}
+	pins, err := pinAPI.Pins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int64 = -1
+	for _, pin := range pins {
+		if pin.Root.Hex() == roots[1].Hex() {
+			size = pin.Size
+		}
+	}
+	if size != int64(len("secret content")) {
+		t.Fatalf("expected size %d for the encrypted content, got %d", len("secret content"), size)
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swap
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
+	"github.com/ethereum/go-ethereum/contracts/chequebook/contract"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/services/swap/swap"
+)
+
+var (
+	cashGasLimit      = uint64(2000000)  // gas limit of a cash transaction
+	cashTimeout       = 60 * time.Second // timeout of the backend calls of a cashing attempt
+	cashResultsLength = 100              // number of cashing results kept for inspection
+
+	cashedCount        = metrics.NewRegisteredCounter("swap.cashier.cashed", nil)
+	cashFailedCount    = metrics.NewRegisteredCounter("swap.cashier.failed", nil)
+	cashDeferredCount  = metrics.NewRegisteredCounter("swap.cashier.deferred", nil)
+	cashedAmountCount  = metrics.NewRegisteredCounter("swap.cashier.amount.gwei", nil)
+	uncashedCountGauge = metrics.NewRegisteredGauge("swap.cashier.uncashed", nil)
+)
+
+// CashResult describes a cashing attempt
+type CashResult struct {
+	Contract common.Address `json:"contract"` // chequebook the cheque was drawn on
+	Amount   *big.Int       `json:"amount"`   // amount cashed (wei)
+	TxHash   string         `json:"txHash"`   // hash of the cash transaction
+	Nonce    uint64         `json:"nonce"`    // nonce of the cash transaction
+	GasPrice *big.Int       `json:"gasPrice"` // gas price of the cash transaction (wei)
+	Error    string         `json:"error"`    // reason of the failure, empty on success
+	Time     time.Time      `json:"time"`
+}
+
+// UncashedCheque describes the last received cheque of a chequebook
+type UncashedCheque struct {
+	Contract common.Address `json:"contract"`
+	Amount   *big.Int       `json:"amount"`   // cumulative amount of the cheque (wei)
+	Uncashed *big.Int       `json:"uncashed"` // amount not yet cashed (wei), nil if unknown
+	Received time.Time      `json:"received"` // when the first uncashed cheque was received
+}
+
+// receivedCheque is the last cheque received from a chequebook
+type receivedCheque struct {
+	cheque   *chequebook.Cheque
+	cashed   *big.Int  // amount already cashed, nil until known
+	received time.Time // when the first uncashed cheque was received
+}
+
+// Cashier cashes the cheques received from peers in the background.
+// The last cheque of a chequebook is cashed when its uncashed amount
+// reaches AutoCashThreshold or when the first uncashed cheque is older
+// than AutoCashMaxAge. Transactions are sent from the account of the
+// private key with gas prices suggested by the backend, deferred while
+// above AutoCashMaxGasPrice, and locally tracked nonces so that cheques
+// of several chequebooks can be cashed in quick succession.
+type Cashier struct {
+	*swap.Strategy
+	key     *ecdsa.PrivateKey
+	from    common.Address
+	backend chequebook.Backend
+
+	mu       sync.Mutex
+	cheques  map[common.Address]*receivedCheque
+	results  []CashResult
+	nonce    uint64
+	nonceSet bool
+	quit     chan struct{}
+}
+
+// NewCashier is the Cashier constructor
+func NewCashier(key *ecdsa.PrivateKey, backend chequebook.Backend, strategy *swap.Strategy) *Cashier {
+	return &Cashier{
+		Strategy: strategy,
+		key:      key,
+		from:     crypto.PubkeyToAddress(key.PublicKey),
+		backend:  backend,
+		cheques:  make(map[common.Address]*receivedCheque),
+	}
+}
+
+// Received records a verified cheque received from a peer
+func (c *Cashier) Received(cheque *chequebook.Cheque) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rc := c.cheques[cheque.Contract]
+	if rc == nil {
+		rc = &receivedCheque{}
+		c.cheques[cheque.Contract] = rc
+	}
+	if rc.cheque == nil || rc.cashed != nil && rc.cashed.Cmp(rc.cheque.Amount) >= 0 {
+		rc.received = time.Now()
+	}
+	rc.cheque = cheque
+	uncashedCountGauge.Update(int64(len(c.cheques)))
+}
+
+// Start starts the periodic cashing
+func (c *Cashier) Start() {
+	c.quit = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(c.AutoCashInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.cashDue()
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the periodic cashing
+func (c *Cashier) Stop() {
+	if c.quit != nil {
+		close(c.quit)
+	}
+}
+
+// cashDue cashes the cheques that crossed the amount or age thresholds
+func (c *Cashier) cashDue() {
+	c.mu.Lock()
+	var due []*receivedCheque
+	for _, rc := range c.cheques {
+		due = append(due, rc)
+	}
+	c.mu.Unlock()
+
+	for _, rc := range due {
+		if err := c.cashIfDue(rc); err != nil {
+			log.Warn("cheque cashing failed", "err", err)
+		}
+	}
+}
+
+// cashIfDue cashes the cheque if one of the thresholds is crossed
+//
+// The lock is only held to read and record the state of the cheque, not
+// during the backend calls, so that received cheques and the API are not
+// blocked by a slow backend.
+func (c *Cashier) cashIfDue(rc *receivedCheque) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cashTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	cheque, cashed, received := rc.cheque, rc.cashed, rc.received
+	c.mu.Unlock()
+
+	session, err := c.session(ctx, cheque.Contract)
+	if err != nil {
+		return err
+	}
+	if cashed == nil {
+		sent, err := session.Sent(cheque.Beneficiary)
+		if err != nil {
+			return fmt.Errorf("unable to get the cashed amount of chequebook %v: %v", cheque.Contract.Hex(), err)
+		}
+		c.mu.Lock()
+		if rc.cashed == nil || rc.cashed.Cmp(sent) < 0 {
+			rc.cashed = sent
+		}
+		cashed = rc.cashed
+		c.mu.Unlock()
+	}
+	uncashed := new(big.Int).Sub(cheque.Amount, cashed)
+	if uncashed.Sign() <= 0 {
+		c.mu.Lock()
+		// a newer cheque may have been received in the meantime
+		if c.cheques[cheque.Contract] == rc && rc.cheque == cheque {
+			delete(c.cheques, cheque.Contract)
+			uncashedCountGauge.Update(int64(len(c.cheques)))
+		}
+		c.mu.Unlock()
+		return nil
+	}
+	overdue := c.AutoCashMaxAge > 0 && time.Since(received) >= c.AutoCashMaxAge
+	if c.AutoCashThreshold != nil && uncashed.Cmp(c.AutoCashThreshold) < 0 && !overdue {
+		return nil
+	}
+
+	gasPrice, err := c.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get gas price: %v", err)
+	}
+	if c.AutoCashMaxGasPrice != nil && gasPrice.Cmp(c.AutoCashMaxGasPrice) > 0 {
+		log.Debug("cheque cashing deferred: gas price too high", "contract", cheque.Contract.Hex(), "gasprice", gasPrice, "max", c.AutoCashMaxGasPrice)
+		cashDeferredCount.Inc(1)
+		return nil
+	}
+	nonce, err := c.nextNonce(ctx)
+	if err != nil {
+		return err
+	}
+	session.TransactOpts.Nonce = new(big.Int).SetUint64(nonce)
+	session.TransactOpts.GasPrice = gasPrice
+
+	result := CashResult{
+		Contract: cheque.Contract,
+		Amount:   uncashed,
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Time:     time.Now(),
+	}
+	result.TxHash, err = cheque.Cash(session)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		// the nonce is fetched again as the backend state is unknown
+		c.nonceSet = false
+		result.Error = err.Error()
+		c.addResult(result)
+		cashFailedCount.Inc(1)
+		return fmt.Errorf("unable to cash cheque of chequebook %v: %v", cheque.Contract.Hex(), err)
+	}
+	if rc.cashed.Cmp(cheque.Amount) < 0 {
+		rc.cashed = cheque.Amount
+	}
+	c.addResult(result)
+	cashedCount.Inc(1)
+	cashedAmountCount.Inc(new(big.Int).Div(uncashed, big.NewInt(1e9)).Int64())
+	log.Info("cheque cashed", "contract", cheque.Contract.Hex(), "amount", uncashed, "tx", result.TxHash, "nonce", nonce, "gasprice", gasPrice)
+	return nil
+}
+
+// session returns a chequebook contract session transacting from the
+// cashier account
+func (c *Cashier) session(ctx context.Context, address common.Address) (*contract.ChequebookSession, error) {
+	chbook, err := contract.NewChequebook(address, c.backend)
+	if err != nil {
+		return nil, err
+	}
+	opts := bind.NewKeyedTransactor(c.key)
+	opts.GasLimit = cashGasLimit
+	opts.Context = ctx
+	return &contract.ChequebookSession{
+		Contract:     chbook,
+		CallOpts:     bind.CallOpts{Context: ctx},
+		TransactOpts: *opts,
+	}, nil
+}
+
+// nextNonce reserves the nonce of the next cash transaction, the nonce is
+// fetched from the backend initially and after failed transactions
+// caller must not hold the lock
+func (c *Cashier) nextNonce(ctx context.Context) (uint64, error) {
+	c.mu.Lock()
+	nonceSet := c.nonceSet
+	c.mu.Unlock()
+
+	if !nonceSet {
+		nonce, err := c.backend.PendingNonceAt(ctx, c.from)
+		if err != nil {
+			return 0, fmt.Errorf("unable to get nonce: %v", err)
+		}
+		c.mu.Lock()
+		if !c.nonceSet {
+			c.nonce = nonce
+			c.nonceSet = true
+		}
+		c.mu.Unlock()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nonce := c.nonce
+	c.nonce++
+	return nonce, nil
+}
+
+// addResult records the result of a cashing attempt
+// caller must hold the lock
+func (c *Cashier) addResult(r CashResult) {
+	c.results = append(c.results, r)
+	if len(c.results) > cashResultsLength {
+		c.results = c.results[len(c.results)-cashResultsLength:]
+	}
+}
+
+// Results returns the recent cashing attempts, the latest last
+func (c *Cashier) Results() []CashResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CashResult{}, c.results...)
+}
+
+// Uncashed returns the last received cheques of the chequebooks with
+// amounts that are not yet cashed
+func (c *Cashier) Uncashed() []UncashedCheque {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var list []UncashedCheque
+	for addr, rc := range c.cheques {
+		uc := UncashedCheque{
+			Contract: addr,
+			Amount:   rc.cheque.Amount,
+			Received: rc.received,
+		}
+		if rc.cashed != nil {
+			uc.Uncashed = new(big.Int).Sub(rc.cheque.Amount, rc.cashed)
+		}
+		list = append(list, uc)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Received.Before(list[j].Received)
+	})
+	return list
+}
+
+// cashingInbox is an incoming payment handler that hands the received
+// cheques over to the cashier instead of cashing them itself
+type cashingInbox struct {
+	*chequebook.Inbox
+	cashier *Cashier
+}
+
+// Receive verifies the cheque and records it with the cashier
+func (in *cashingInbox) Receive(promise swap.Promise) (*big.Int, error) {
+	amount, err := in.Inbox.Receive(promise)
+	if err != nil {
+		return nil, err
+	}
+	in.cashier.Received(promise.(*chequebook.Cheque))
+	return amount, nil
+}
+
+// AutoCash is a noop as cashing is done by the cashier
+func (in *cashingInbox) AutoCash(time.Duration, *big.Int) {}
+
+// CashierAPI is the RPC API of the Cashier
+type CashierAPI struct {
+	cashier *Cashier
+}
+
+// NewCashierAPI is the CashierAPI constructor
+func NewCashierAPI(c *Cashier) *CashierAPI {
+	return &CashierAPI{
+		cashier: c,
+	}
+}
+
+// CashResults returns the recent cashing attempts, the latest last
+func (api *CashierAPI) CashResults() []CashResult {
+	return api.cashier.Results()
+}
+
+// Uncashed returns the received cheques that are not yet cashed
+func (api *CashierAPI) Uncashed() []UncashedCheque {
+	return api.cashier.Uncashed()
+}
@@ -0,0 +1,225 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swap
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/contracts/chequebook"
+	"github.com/ethereum/go-ethereum/contracts/chequebook/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/swarm/services/swap/swap"
+)
+
+func TestCashier(t *testing.T) {
+	ownerKey, _ := crypto.GenerateKey()
+	beneficiaryKey, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(ownerKey.PublicKey)
+	beneficiary := crypto.PubkeyToAddress(beneficiaryKey.PublicKey)
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		owner:       {Balance: big.NewInt(1000000000)},
+		beneficiary: {Balance: big.NewInt(1000000000)},
+	})
+
+	opts := bind.NewKeyedTransactor(ownerKey)
+	opts.Value = big.NewInt(1000)
+	addr, _, _, err := contract.DeployChequebook(opts, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	dir, err := ioutil.TempDir("", "swarm-cashier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	chbook, err := chequebook.NewChequebook(filepath.Join(dir, "chequebook.json"), addr, ownerKey, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashier := NewCashier(beneficiaryKey, backend, &swap.Strategy{
+		AutoCashInterval:  time.Second,
+		AutoCashThreshold: big.NewInt(50),
+		AutoCashMaxAge:    time.Hour,
+	})
+	issue := func(amount int64) {
+		cheque, err := chbook.Issue(beneficiary, big.NewInt(amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cashier.Received(cheque)
+	}
+
+	// below the threshold nothing is cashed
+	issue(30)
+	cashier.cashDue()
+	if n := len(cashier.Results()); n != 0 {
+		t.Fatalf("expected no cashing, got %v results", n)
+	}
+	uncashed := cashier.Uncashed()
+	if len(uncashed) != 1 || uncashed[0].Uncashed.Int64() != 30 {
+		t.Fatalf("expected 30 uncashed, got %+v", uncashed)
+	}
+
+	// deferred while the gas price is above the limit
+	issue(30)
+	cashier.AutoCashMaxGasPrice = big.NewInt(0)
+	cashier.cashDue()
+	if n := len(cashier.Results()); n != 0 {
+		t.Fatalf("expected cashing to be deferred, got %v results", n)
+	}
+
+	// cashed once the threshold is crossed
+	cashier.AutoCashMaxGasPrice = nil
+	cashier.cashDue()
+	results := cashier.Results()
+	if len(results) != 1 || results[0].Error != "" || results[0].Amount.Int64() != 60 || results[0].Nonce != 0 {
+		t.Fatalf("expected successful cashing of 60 with nonce 0, got %+v", results)
+	}
+	backend.Commit()
+
+	// overdue cheques are cashed below the threshold with the next nonce
+	issue(10)
+	cashier.AutoCashMaxAge = time.Nanosecond
+	cashier.cashDue()
+	results = cashier.Results()
+	if len(results) != 2 || results[1].Error != "" || results[1].Amount.Int64() != 10 || results[1].Nonce != 1 {
+		t.Fatalf("expected successful cashing of 10 with nonce 1, got %+v", results)
+	}
+	backend.Commit()
+
+	sent, err := contract.NewChequebookCaller(addr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cashed, err := sent.Sent(&bind.CallOpts{Context: context.Background()}, beneficiary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cashed.Int64() != 70 {
+		t.Fatalf("expected 70 cashed on chain, got %v", cashed)
+	}
+
+	// fully cashed cheques are dropped
+	cashier.cashDue()
+	if uncashed := cashier.Uncashed(); len(uncashed) != 0 {
+		t.Fatalf("expected no uncashed cheques, got %+v", uncashed)
+	}
+}
+
+// blockingBackend is a chequebook backend whose gas price suggestions block
+// until released
+type blockingBackend struct {
+	chequebook.Backend
+	called  chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	close(b.called)
+	<-b.release
+	return b.Backend.SuggestGasPrice(ctx)
+}
+
+// TestCashierUnlockedBackendCalls checks that cheques can be received and
+// inspected while a cashing attempt waits for the backend
+func TestCashierUnlockedBackendCalls(t *testing.T) {
+	ownerKey, _ := crypto.GenerateKey()
+	beneficiaryKey, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(ownerKey.PublicKey)
+	beneficiary := crypto.PubkeyToAddress(beneficiaryKey.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{
+		owner:       {Balance: big.NewInt(1000000000)},
+		beneficiary: {Balance: big.NewInt(1000000000)},
+	})
+
+	opts := bind.NewKeyedTransactor(ownerKey)
+	opts.Value = big.NewInt(1000)
+	addr, _, _, err := contract.DeployChequebook(opts, sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+
+	dir, err := ioutil.TempDir("", "swarm-cashier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	chbook, err := chequebook.NewChequebook(filepath.Join(dir, "chequebook.json"), addr, ownerKey, sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &blockingBackend{Backend: sim, called: make(chan struct{}), release: make(chan struct{})}
+	cashier := NewCashier(beneficiaryKey, backend, &swap.Strategy{
+		AutoCashInterval:  time.Second,
+		AutoCashThreshold: big.NewInt(50),
+	})
+	cheque, err := chbook.Issue(beneficiary, big.NewInt(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cashier.Received(cheque)
+
+	done := make(chan struct{})
+	go func() {
+		cashier.cashDue()
+		close(done)
+	}()
+	<-backend.called
+
+	// the cashier is not locked while waiting for the gas price
+	next, err := chbook.Issue(beneficiary, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan struct{})
+	go func() {
+		cashier.Received(next)
+		cashier.Uncashed()
+		close(received)
+	}()
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cashier locked during backend call")
+	}
+	close(backend.release)
+	<-done
+
+	// only the amount of the first cheque was cashed
+	results := cashier.Results()
+	if len(results) != 1 || results[0].Error != "" || results[0].Amount.Int64() != 60 {
+		t.Fatalf("expected successful cashing of 60, got %+v", results)
+	}
+	uncashed := cashier.Uncashed()
+	if len(uncashed) != 1 || uncashed[0].Uncashed.Int64() != 10 {
+		t.Fatalf("expected 10 uncashed, got %+v", uncashed)
+	}
+}
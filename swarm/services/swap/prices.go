@@ -0,0 +1,252 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	retrievalPrice = big.NewInt(20000000000) // default price of a retrieved chunk (wei)
+	storagePrice   = big.NewInt(20000000000) // default price of a chunk pushed for storage (wei)
+
+	priceRefreshInterval = 10 * time.Minute // interval between price updates of file and contract oracles
+	priceCallTimeout     = 30 * time.Second // timeout of the contract price calls
+)
+
+// PriceOracle supplies the per-chunk prices used by the accounting layer
+// prices are in wei and must not be modified by the caller
+type PriceOracle interface {
+	RetrievalPrice() *big.Int // price of a chunk delivered for a retrieve request
+	StoragePrice() *big.Int   // price of a chunk delivered for storage (syncing)
+}
+
+// Prices is a pair of per-chunk prices
+type Prices struct {
+	Retrieval *big.Int `json:"retrieval"`
+	Storage   *big.Int `json:"storage"`
+}
+
+// NewDefaultPrices returns the default prices
+func NewDefaultPrices() *Prices {
+	return &Prices{
+		Retrieval: new(big.Int).Set(retrievalPrice),
+		Storage:   new(big.Int).Set(storagePrice),
+	}
+}
+
+// StaticPriceOracle supplies fixed prices
+type StaticPriceOracle struct {
+	prices Prices
+}
+
+// NewStaticPriceOracle is the StaticPriceOracle constructor
+func NewStaticPriceOracle(retrieval, storage *big.Int) *StaticPriceOracle {
+	return &StaticPriceOracle{
+		prices: Prices{
+			Retrieval: retrieval,
+			Storage:   storage,
+		},
+	}
+}
+
+// RetrievalPrice implements PriceOracle
+func (o *StaticPriceOracle) RetrievalPrice() *big.Int {
+	return o.prices.Retrieval
+}
+
+// StoragePrice implements PriceOracle
+func (o *StaticPriceOracle) StoragePrice() *big.Int {
+	return o.prices.Storage
+}
+
+// updatingPriceOracle supplies prices that are periodically updated by a
+// fetch function, the last known prices are kept if an update fails
+type updatingPriceOracle struct {
+	name  string
+	fetch func() (*Prices, error)
+
+	mu     sync.RWMutex
+	prices Prices
+	quit   chan struct{}
+}
+
+func newUpdatingPriceOracle(name string, fetch func() (*Prices, error)) (*updatingPriceOracle, error) {
+	o := &updatingPriceOracle{
+		name:  name,
+		fetch: fetch,
+		quit:  make(chan struct{}),
+	}
+	if err := o.update(); err != nil {
+		return nil, err
+	}
+	go o.loop()
+	return o, nil
+}
+
+func (o *updatingPriceOracle) loop() {
+	ticker := time.NewTicker(priceRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := o.update(); err != nil {
+				log.Warn("price update failed", "oracle", o.name, "err", err)
+			}
+		case <-o.quit:
+			return
+		}
+	}
+}
+
+func (o *updatingPriceOracle) update() error {
+	prices, err := o.fetch()
+	if err != nil {
+		return err
+	}
+	if prices.Retrieval == nil || prices.Storage == nil {
+		return fmt.Errorf("incomplete prices from %s", o.name)
+	}
+	if prices.Retrieval.Sign() < 0 || prices.Storage.Sign() < 0 {
+		return fmt.Errorf("negative prices from %s", o.name)
+	}
+	o.mu.Lock()
+	o.prices = *prices
+	o.mu.Unlock()
+	log.Debug("prices updated", "oracle", o.name, "retrieval", prices.Retrieval, "storage", prices.Storage)
+	return nil
+}
+
+// RetrievalPrice implements PriceOracle
+func (o *updatingPriceOracle) RetrievalPrice() *big.Int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.prices.Retrieval
+}
+
+// StoragePrice implements PriceOracle
+func (o *updatingPriceOracle) StoragePrice() *big.Int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.prices.Storage
+}
+
+// Stop terminates the price updates
+func (o *updatingPriceOracle) Stop() {
+	close(o.quit)
+}
+
+// FilePriceOracle supplies the prices of a JSON file encoding Prices,
+// the file is read again periodically so that prices can be changed
+// without restarting the node
+type FilePriceOracle struct {
+	*updatingPriceOracle
+}
+
+// NewFilePriceOracle is the FilePriceOracle constructor
+func NewFilePriceOracle(path string) (*FilePriceOracle, error) {
+	o, err := newUpdatingPriceOracle("file:"+path, func() (*Prices, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		prices := &Prices{}
+		if err := json.NewDecoder(f).Decode(prices); err != nil {
+			return nil, fmt.Errorf("invalid price file %s: %v", path, err)
+		}
+		return prices, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &FilePriceOracle{o}, nil
+}
+
+// priceContractABI is the interface of price contracts, the contracts are
+// expected to expose the prices in wei as constant functions
+const priceContractABI = `[{"constant":true,"inputs":[],"name":"retrievalPrice","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"storagePrice","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// ContractPriceOracle supplies the prices published by a contract
+type ContractPriceOracle struct {
+	*updatingPriceOracle
+}
+
+// NewContractPriceOracle is the ContractPriceOracle constructor
+func NewContractPriceOracle(address common.Address, backend bind.ContractCaller) (*ContractPriceOracle, error) {
+	parsed, err := abi.JSON(strings.NewReader(priceContractABI))
+	if err != nil {
+		return nil, err
+	}
+	contract := bind.NewBoundContract(address, parsed, backend, nil, nil)
+	o, err := newUpdatingPriceOracle("contract:"+address.Hex(), func() (*Prices, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), priceCallTimeout)
+		defer cancel()
+		opts := &bind.CallOpts{Context: ctx}
+		prices := &Prices{
+			Retrieval: new(big.Int),
+			Storage:   new(big.Int),
+		}
+		if err := contract.Call(opts, &prices.Retrieval, "retrievalPrice"); err != nil {
+			return nil, err
+		}
+		if err := contract.Call(opts, &prices.Storage, "storagePrice"); err != nil {
+			return nil, err
+		}
+		return prices, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ContractPriceOracle{o}, nil
+}
+
+// NewPriceOracle constructs the price oracle given by the spec
+// * "" for the default static prices
+// * "file:<path>" for a FilePriceOracle
+// * "contract:<address>" for a ContractPriceOracle, backend must not be nil
+func NewPriceOracle(spec string, backend bind.ContractCaller) (PriceOracle, error) {
+	switch {
+	case spec == "":
+		prices := NewDefaultPrices()
+		return NewStaticPriceOracle(prices.Retrieval, prices.Storage), nil
+	case strings.HasPrefix(spec, "file:"):
+		return NewFilePriceOracle(strings.TrimPrefix(spec, "file:"))
+	case strings.HasPrefix(spec, "contract:"):
+		addr := strings.TrimPrefix(spec, "contract:")
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid price contract address %q", addr)
+		}
+		if backend == nil {
+			return nil, fmt.Errorf("price contract %s requires an ethereum backend", addr)
+		}
+		return NewContractPriceOracle(common.HexToAddress(addr), backend)
+	}
+	return nil, fmt.Errorf("unknown price oracle %q", spec)
+}
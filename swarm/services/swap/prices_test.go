@@ -0,0 +1,88 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swap
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewPriceOracle(t *testing.T) {
+	o, err := NewPriceOracle("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.RetrievalPrice().Cmp(retrievalPrice) != 0 || o.StoragePrice().Cmp(storagePrice) != 0 {
+		t.Fatalf("expected default prices, got %v and %v", o.RetrievalPrice(), o.StoragePrice())
+	}
+
+	for _, spec := range []string{
+		"contract:0xinvalid",
+		"contract:0x0000000000000000000000000000000000000001",
+		"file:/nonexistent/prices.json",
+		"unknown",
+	} {
+		if _, err := NewPriceOracle(spec, nil); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestFilePriceOracle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarm-prices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prices.json")
+
+	if err := ioutil.WriteFile(path, []byte(`{"retrieval": 5, "storage": 7}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewFilePriceOracle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+	if o.RetrievalPrice().Int64() != 5 || o.StoragePrice().Int64() != 7 {
+		t.Fatalf("expected prices 5 and 7, got %v and %v", o.RetrievalPrice(), o.StoragePrice())
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"retrieval": 6, "storage": 8}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.update(); err != nil {
+		t.Fatal(err)
+	}
+	if o.RetrievalPrice().Int64() != 6 || o.StoragePrice().Int64() != 8 {
+		t.Fatalf("expected prices 6 and 8, got %v and %v", o.RetrievalPrice(), o.StoragePrice())
+	}
+
+	// invalid prices keep the last known ones
+	if err := ioutil.WriteFile(path, []byte(`{"retrieval": -1, "storage": 8}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.update(); err == nil {
+		t.Fatal("expected error for negative price")
+	}
+	if o.RetrievalPrice().Cmp(big.NewInt(6)) != 0 {
+		t.Fatalf("expected price 6, got %v", o.RetrievalPrice())
+	}
+}
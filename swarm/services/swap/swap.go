@@ -45,6 +45,8 @@ import (
 var (
 	autoCashInterval     = 300 * time.Second           // default interval for autocash
 	autoCashThreshold    = big.NewInt(50000000000000)  // threshold that triggers autocash (wei)
+	autoCashMaxAge       = 24 * time.Hour              // age of the first uncashed cheque that triggers autocash
+	autoCashMaxGasPrice  = big.NewInt(100000000000)    // gas price above which autocash is deferred (wei)
 	autoDepositInterval  = 300 * time.Second           // default interval for autocash
 	autoDepositThreshold = big.NewInt(50000000000000)  // threshold that triggers autodeposit (wei)
 	autoDepositBuffer    = big.NewInt(100000000000000) // buffer that is surplus for fork protection etc (wei)
@@ -63,6 +65,7 @@ const (
 type LocalProfile struct {
 	*swap.Params
 	*PayProfile
+	cashier *Cashier
 }
 
 // RemoteProfile combines a PayProfile with *swap.Profile
@@ -97,6 +100,8 @@ func NewDefaultSwapParams() *LocalProfile {
 			Strategy: &swap.Strategy{
 				AutoCashInterval:     autoCashInterval,
 				AutoCashThreshold:    autoCashThreshold,
+				AutoCashMaxAge:       autoCashMaxAge,
+				AutoCashMaxGasPrice:  autoCashMaxGasPrice,
 				AutoDepositInterval:  autoDepositInterval,
 				AutoDepositThreshold: autoDepositThreshold,
 				AutoDepositBuffer:    autoDepositBuffer,
@@ -159,11 +164,17 @@ func NewSwap(localProfile *LocalProfile, remoteProfile *RemoteProfile, backend c
 	}
 
 	pm := swap.Payment{
-		In:    in,
 		Out:   out,
 		Buys:  out != nil,
 		Sells: in != nil,
 	}
+	if in != nil {
+		pm.In = in
+		// received cheques are cashed by the cashier if there is one
+		if localProfile.cashier != nil {
+			pm.In = &cashingInbox{Inbox: in, cashier: localProfile.cashier}
+		}
+	}
 	swapInstance, err = swap.New(localProfile.Params, pm, proto)
 	if err != nil {
 		return
@@ -193,6 +204,12 @@ func (lp *LocalProfile) Chequebook() *chequebook.Chequebook {
 	return lp.chbook
 }
 
+// SetCashier sets the cashier that cashes the cheques received from peers
+// instead of the per-peer inboxes
+func (lp *LocalProfile) SetCashier(c *Cashier) {
+	lp.cashier = c
+}
+
 // PrivateKey accessor
 func (lp *LocalProfile) PrivateKey() *ecdsa.PrivateKey {
 	return lp.privateKey
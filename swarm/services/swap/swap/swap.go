@@ -43,6 +43,8 @@ type Profile struct {
 type Strategy struct {
 	AutoCashInterval     time.Duration // default interval for autocash
 	AutoCashThreshold    *big.Int      // threshold that triggers autocash (wei)
+	AutoCashMaxAge       time.Duration // age of the first uncashed cheque that triggers autocash
+	AutoCashMaxGasPrice  *big.Int      // gas price above which autocash is deferred (wei), no limit if nil
 	AutoDepositInterval  time.Duration // default interval for autocash
 	AutoDepositThreshold *big.Int      // threshold that triggers autodeposit (wei)
 	AutoDepositBuffer    *big.Int      // buffer that is surplus for fork protection etc (wei)
@@ -190,6 +192,14 @@ func (swap *Swap) Balance() int {
 	return swap.balance
 }
 
+// SetBalance restores the balance with the peer, e.g. from an earlier
+// connection, the thresholds apply from the next Add
+func (swap *Swap) SetBalance(balance int) {
+	defer swap.lock.Unlock()
+	swap.lock.Lock()
+	swap.balance = balance
+}
+
 // send (units) is called when payment is due
 // In case of insolvency no promise is issued and sent, safe against fraud
 // No return value: no error = payment is opportunistic = hang in till dropped
@@ -0,0 +1,70 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package swarm
+
+import (
+	"time"
+
+	httpapi "github.com/ethereum/go-ethereum/swarm/api/http"
+	"github.com/ethereum/go-ethereum/swarm/network"
+	"github.com/ethereum/go-ethereum/swarm/network/stream"
+)
+
+// StoreStatus is the number of chunks in the local store and its capacity
+type StoreStatus struct {
+	Chunks   uint64 `json:"chunks"`
+	Capacity uint64 `json:"capacity"`
+}
+
+// Status is a summary of the health of a running swarm node
+type Status struct {
+	Uptime  time.Duration           `json:"uptime"`
+	Overlay *network.KademliaStatus `json:"overlay"`
+	Store   *StoreStatus            `json:"store"`
+	Sync    *stream.SyncStatus      `json:"sync"`
+	Gateway *httpapi.GatewayStats   `json:"gateway,omitempty"` // nil if the HTTP gateway is disabled
+}
+
+// Status returns the connectivity, storage, syncing and HTTP gateway
+// status of the node
+func (self *Swarm) Status() *Status {
+	status := &Status{
+		Uptime: time.Since(startTime),
+		Store: &StoreStatus{
+			Chunks:   self.lstore.DbStore.Size(),
+			Capacity: self.lstore.DbStore.Capacity(),
+		},
+		Sync: self.streamer.SyncStatus(),
+	}
+	if kad, ok := self.bzz.Hive.Overlay.(*network.Kademlia); ok {
+		status.Overlay = kad.Status()
+	}
+	if self.gateway != nil {
+		status.Gateway = self.gateway.Stats()
+	}
+	return status
+}
+
+// StatusAPI implements the bzz_status RPC method
+type StatusAPI struct {
+	swarm *Swarm
+}
+
+// Status returns the health summary of the node
+func (api *StatusAPI) Status() *Status {
+	return api.swarm.Status()
+}
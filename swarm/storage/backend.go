@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// DefaultBackend is the name of the key-value store backing the LDBStore
+// unless another one is selected.
+const DefaultBackend = "leveldb"
+
+// ErrKeyNotFound is returned by ChunkStoreBackend.Get if the key is not in the
+// store.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ChunkStoreBackend is a sorted key-value store the LDBStore keeps its chunks,
+// indices and counters in.
+type ChunkStoreBackend interface {
+	Put(key []byte, value []byte) error
+	// Get returns ErrKeyNotFound if the key is not in the store
+	Get(key []byte) ([]byte, error)
+	Delete(key []byte) error
+	// Iterate calls f with the entries of the store in ascending key order,
+	// starting at the first key not less than start, until f returns false.
+	// The key and value passed to f are only valid during the call. The
+	// entries are read from a snapshot, so f may modify the store.
+	Iterate(start []byte, f func(key, value []byte) bool) error
+	// Count returns the number of keys with the given prefix
+	Count(prefix []byte) (uint64, error)
+	// NewBatch creates a batch of writes which are applied atomically by Write
+	NewBatch() BackendBatch
+	Write(batch BackendBatch) error
+	Close()
+}
+
+// BackendBatch collects writes to a ChunkStoreBackend. It can only be written
+// by the backend which created it.
+type BackendBatch interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+	// Len returns the number of writes in the batch
+	Len() int
+}
+
+// Backend opens the chunk store backend in the given directory, creating it
+// if it does not exist yet.
+type Backend func(path string) (ChunkStoreBackend, error)
+
+// backends are the key-value stores of the chunk databases, the one a database
+// was created with is recorded in the file BACKEND of its directory.
+var backends = ethdb.NewEngineRegistry("chunk store backend", "BACKEND", DefaultBackend, Backend(func(path string) (ChunkStoreBackend, error) {
+	return NewLDBDatabase(path)
+}))
+
+// RegisterBackend makes a chunk store backend available under the given name.
+// It panics if a backend is already registered with the same name.
+func RegisterBackend(name string, backend Backend) {
+	backends.Register(name, backend)
+}
+
+// Backends returns the sorted names of the registered chunk store backends.
+func Backends() []string {
+	return backends.Names()
+}
+
+// OpenBackend opens the chunk database in the given directory with the named
+// backend, creating it if it does not exist yet. An existing database can only
+// be opened with the backend it was created with. If no backend is named, the
+// one the database was created with is used, or the default one for new
+// databases.
+func OpenBackend(name string, path string) (ChunkStoreBackend, error) {
+	open, name, recorded, err := backends.Select(name, path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := open.(Backend)(path)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		if err := backends.Record(name, path); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testLDBBackend is a LevelDB backend registered under another name, to test
+// the backend selection
+type testLDBBackend struct {
+	*LDBDatabase
+}
+
+func init() {
+	RegisterBackend("test", func(path string) (ChunkStoreBackend, error) {
+		db, err := NewLDBDatabase(path)
+		if err != nil {
+			return nil, err
+		}
+		return &testLDBBackend{db}, nil
+	})
+}
+
+// Tests the ChunkStoreBackend contract on all the registered backends.
+func TestBackends(t *testing.T) {
+	for _, name := range Backends() {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "swarm-backend-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			db, err := OpenBackend(name, dir)
+			if err != nil {
+				t.Fatalf("failed to open backend: %v", err)
+			}
+			defer db.Close()
+			testBackend(t, db)
+		})
+	}
+}
+
+func testBackend(t *testing.T, db ChunkStoreBackend) {
+	if err := db.Put([]byte{1, 1}, []byte("a")); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	if value, err := db.Get([]byte{1, 1}); err != nil || !bytes.Equal(value, []byte("a")) {
+		t.Fatalf("Get returned %q, %v", value, err)
+	}
+	batch := db.NewBatch()
+	batch.Put([]byte{1, 2}, []byte("b"))
+	batch.Put([]byte{2, 1}, []byte("c"))
+	batch.Put([]byte{3}, nil)
+	batch.Delete([]byte{1, 1})
+	if batch.Len() != 4 {
+		t.Fatalf("expected 4 writes in the batch, got %d", batch.Len())
+	}
+	if err := db.Write(batch); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if _, err := db.Get([]byte{1, 1}); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound for deleted key, got %v", err)
+	}
+	if count, err := db.Count([]byte{1}); err != nil || count != 1 {
+		t.Fatalf("expected 1 key with prefix, got %d, %v", count, err)
+	}
+
+	var keys, values [][]byte
+	err := db.Iterate([]byte{1, 2}, func(key, value []byte) bool {
+		keys = append(keys, append([]byte{}, key...))
+		values = append(values, append([]byte{}, value...))
+		return len(keys) < 2
+	})
+	if err != nil {
+		t.Fatalf("Iterate error: %v", err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys[0], []byte{1, 2}) || !bytes.Equal(keys[1], []byte{2, 1}) {
+		t.Fatalf("unexpected iterated keys %x", keys)
+	}
+	if !bytes.Equal(values[1], []byte("c")) {
+		t.Fatalf("unexpected iterated value %q", values[1])
+	}
+	if err := db.Delete([]byte{2, 1}); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := db.Get([]byte{2, 1}); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound for deleted key, got %v", err)
+	}
+}
+
+// Tests that chunk databases remember the backend they were created with and
+// refuse to be opened with any other one.
+func TestBackendSelection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarm-backend-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := OpenBackend("unknown", filepath.Join(dir, "unknown")); err == nil {
+		t.Fatal("opened chunk database with unknown backend")
+	}
+	custom := filepath.Join(dir, "custom")
+	db, err := OpenBackend("test", custom)
+	if err != nil {
+		t.Fatalf("failed to create custom database: %v", err)
+	}
+	db.Close()
+
+	if _, err := OpenBackend(DefaultBackend, custom); err == nil {
+		t.Fatal("opened custom database with default backend")
+	}
+	db, err = OpenBackend("", custom)
+	if err != nil {
+		t.Fatalf("failed to reopen custom database: %v", err)
+	}
+	if _, ok := db.(*testLDBBackend); !ok {
+		t.Fatalf("custom database reopened with wrong backend: %T", db)
+	}
+	db.Close()
+
+	// Chunk databases created before backends were recorded are LevelDB ones
+	legacy := filepath.Join(dir, "legacy")
+	ldb, err := NewLDBDatabase(legacy)
+	if err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	ldb.Close()
+
+	if _, err := OpenBackend("test", legacy); err == nil {
+		t.Fatal("opened legacy database with custom backend")
+	}
+	db, err = OpenBackend("", legacy)
+	if err != nil {
+		t.Fatalf("failed to reopen legacy database: %v", err)
+	}
+	if _, ok := db.(*LDBDatabase); !ok {
+		t.Fatalf("legacy database reopened with wrong backend: %T", db)
+	}
+	db.Close()
+}
@@ -16,15 +16,18 @@
 package storage
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
 )
 
 /*
@@ -72,6 +75,39 @@ const (
 type ChunkerParams struct {
 	chunkSize int64
 	hashSize  int64
+	workers   int // maximum number of chunk processors, ChunkProcessors if 0
+}
+
+// maxWorkers returns the maximum number of chunk processors of the chunker
+func (self *ChunkerParams) maxWorkers() int64 {
+	if self.workers <= 0 {
+		return ChunkProcessors
+	}
+	return int64(self.workers)
+}
+
+// waitSplit waits for a splitter to report completion on errC. It only times
+// out if no chunk is processed for splitTimeout, so that the splitting of
+// large content throttled by a slow chunk store does not fail as long as it
+// makes progress.
+func waitSplit(errC chan error, processed *uint64) error {
+	timer := time.NewTimer(splitTimeout)
+	defer timer.Stop()
+
+	last := atomic.LoadUint64(processed)
+	for {
+		select {
+		case err := <-errC:
+			return err
+		case <-timer.C:
+			current := atomic.LoadUint64(processed)
+			if current == last {
+				return errOperationTimedOut
+			}
+			last = current
+			timer.Reset(splitTimeout)
+		}
+	}
 }
 
 type SplitterParams struct {
@@ -95,16 +131,18 @@ type JoinerParams struct {
 }
 
 type TreeChunker struct {
-	branches int64
-	hashFunc SwarmHasher
-	dataSize int64
-	data     io.Reader
+	processed uint64 // number of chunks processed, accessed atomically
+	branches  int64
+	hashFunc  SwarmHasher
+	dataSize  int64
+	data      io.Reader
 	// calculated
 	addr        Address
 	depth       int
 	hashSize    int64        // self.hashFunc.New().Size()
 	chunkSize   int64        // hashSize* branches
 	workerCount int64        // the number of worker routines used
+	maxWorkers  int64        // the maximum number of worker routines
 	workerLock  sync.RWMutex // lock for the worker count
 	jobC        chan *hashJob
 	wg          *sync.WaitGroup
@@ -160,7 +198,8 @@ func NewTreeJoiner(params *JoinerParams) *TreeChunker {
 	self.depth = params.depth
 	self.chunkSize = self.hashSize * self.branches
 	self.workerCount = 0
-	self.jobC = make(chan *hashJob, 2*ChunkProcessors)
+	self.maxWorkers = params.maxWorkers()
+	self.jobC = make(chan *hashJob, 2*self.maxWorkers)
 	self.wg = &sync.WaitGroup{}
 	self.errC = make(chan error)
 	self.quitC = make(chan bool)
@@ -193,7 +232,8 @@ func NewTreeSplitter(params *TreeSplitterParams) *TreeChunker {
 	self.chunkSize = self.hashSize * self.branches
 	self.putter = params.putter
 	self.workerCount = 0
-	self.jobC = make(chan *hashJob, 2*ChunkProcessors)
+	self.maxWorkers = params.maxWorkers()
+	self.jobC = make(chan *hashJob, 2*self.maxWorkers)
 	self.wg = &sync.WaitGroup{}
 	self.errC = make(chan error)
 	self.quitC = make(chan bool)
@@ -262,15 +302,9 @@ func (self *TreeChunker) Split() (k Address, wait func(), err error) {
 
 	defer close(self.quitC)
 	defer self.putter.Close()
-	select {
-	case err := <-self.errC:
-		if err != nil {
-			return nil, nil, err
-		}
-	case <-time.NewTimer(splitTimeout).C:
-		return nil, nil, errOperationTimedOut
+	if err := waitSplit(self.errC, &self.processed); err != nil {
+		return nil, nil, err
 	}
-
 	return key, self.putter.Wait, nil
 }
 
@@ -335,7 +369,7 @@ func (self *TreeChunker) split(depth int, treeSize int64, addr Address, size int
 	childrenWg.Wait()
 
 	worker := self.getWorkerCount()
-	if int64(len(self.jobC)) > worker && worker < ChunkProcessors {
+	if int64(len(self.jobC)) > worker && worker < self.maxWorkers {
 		self.runWorker()
 
 	}
@@ -359,10 +393,14 @@ func (self *TreeChunker) runWorker() {
 
 				h, err := self.putter.Put(job.chunk)
 				if err != nil {
-					self.errC <- err
+					select {
+					case self.errC <- err:
+					case <-self.quitC:
+					}
 					return
 				}
 				copy(job.key, h)
+				atomic.AddUint64(&self.processed, 1)
 				job.parentWg.Done()
 			case <-self.quitC:
 				return
@@ -384,7 +422,9 @@ type LazyChunkReader struct {
 	branches  int64 // inherit from chunker
 	hashSize  int64 // inherit from chunker
 	depth     int
+	parity    int // parity chunks per intermediate chunk, see erasureSplitter
 	getter    Getter
+	ctx       context.Context // context of the request reading the content
 }
 
 func (self *TreeChunker) Join() *LazyChunkReader {
@@ -395,7 +435,17 @@ func (self *TreeChunker) Join() *LazyChunkReader {
 		hashSize:  self.hashSize,
 		depth:     self.depth,
 		getter:    self.getter,
+		ctx:       context.Background(),
+	}
+}
+
+// get returns the data of the chunk at ref, passing ctx on to the getter if
+// it takes part in tracing
+func (self *LazyChunkReader) get(ctx context.Context, ref Reference) (ChunkData, error) {
+	if getter, ok := self.getter.(contextGetter); ok {
+		return getter.GetContext(ctx, ref)
 	}
+	return self.getter.Get(ref)
 }
 
 // Size is meant to be called on the LazySectionReader
@@ -404,7 +454,7 @@ func (self *LazyChunkReader) Size(quitC chan bool) (n int64, err error) {
 
 	log.Debug("lazychunkreader.size", "key", self.key)
 	if self.chunkData == nil {
-		chunkData, err := self.getter.Get(Reference(self.key))
+		chunkData, err := self.get(self.ctx, Reference(self.key))
 		if err != nil {
 			return 0, err
 		}
@@ -427,6 +477,11 @@ func (self *LazyChunkReader) Size(quitC chan bool) (n int64, err error) {
 func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 	metrics.GetOrRegisterCounter("lazychunkreader.readat", nil).Inc(1)
 
+	ctx, sp := tracing.StartSpan(self.ctx, "lcr.read")
+	defer sp.Finish()
+	sp.SetTag("off", off)
+	sp.SetTag("len", len(b))
+
 	// this is correct, a swarm doc cannot be zero length, so no EOF is expected
 	if len(b) == 0 {
 		return 0, nil
@@ -435,8 +490,12 @@ func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 	size, err := self.Size(quitC)
 	if err != nil {
 		log.Error("lazychunkreader.readat.size", "size", size, "err", err)
+		sp.SetError(err)
 		return 0, err
 	}
+	if off >= size {
+		return 0, io.EOF
+	}
 
 	errC := make(chan error)
 
@@ -455,7 +514,7 @@ func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 		length *= self.chunkSize
 	}
 	wg.Add(1)
-	go self.join(b, off, off+length, depth, treeSize/self.branches, self.chunkData, &wg, errC, quitC)
+	go self.join(ctx, b, off, off+length, depth, treeSize/self.branches, self.chunkData, &wg, errC, quitC)
 	go func() {
 		wg.Wait()
 		close(errC)
@@ -464,6 +523,7 @@ func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 	err = <-errC
 	if err != nil {
 		log.Error("lazychunkreader.readat.errc", "err", err)
+		sp.SetError(err)
 		close(quitC)
 		return 0, err
 	}
@@ -473,7 +533,7 @@ func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 	return len(b), nil
 }
 
-func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, treeSize int64, chunkData ChunkData, parentWg *sync.WaitGroup, errC chan error, quitC chan bool) {
+func (self *LazyChunkReader) join(ctx context.Context, b []byte, off int64, eoff int64, depth int, treeSize int64, chunkData ChunkData, parentWg *sync.WaitGroup, errC chan error, quitC chan bool) {
 	defer parentWg.Done()
 	// find appropriate block level
 	for chunkData.Size() < treeSize && depth > self.depth {
@@ -518,9 +578,14 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 			wg.Wait()
 		}
 		wg.Add(1)
+		parent := chunkData
 		go func(j int64) {
 			childKey := chunkData[8+j*self.hashSize : 8+(j+1)*self.hashSize]
-			chunkData, err := self.getter.Get(Reference(childKey))
+			chunkData, err := self.get(ctx, Reference(childKey))
+			if err != nil && self.parity > 0 {
+				log.Debug("lazychunkreader.join.recover", "key", fmt.Sprintf("%x", childKey), "err", err)
+				chunkData, err = self.recover(ctx, parent, depth, treeSize, j)
+			}
 			if err != nil {
 				log.Error("lazychunkreader.join", "key", fmt.Sprintf("%x", childKey), "err", err)
 				select {
@@ -532,7 +597,7 @@ func (self *LazyChunkReader) join(b []byte, off int64, eoff int64, depth int, tr
 			if soff < off {
 				soff = off
 			}
-			self.join(b[soff-off:seoff-off], soff-roff, seoff-roff, depth-1, treeSize/self.branches, chunkData, wg, errC, quitC)
+			self.join(ctx, b[soff-off:seoff-off], soff-roff, seoff-roff, depth-1, treeSize/self.branches, chunkData, wg, errC, quitC)
 		}(i)
 	} //for
 }
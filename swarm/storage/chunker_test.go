@@ -26,6 +26,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/swarm/testutil/datagen"
 )
 
 /*
@@ -39,9 +40,19 @@ type test interface {
 
 type chunkerTester struct {
 	inputs map[uint64][]byte
+	gen    *datagen.Generator
 	t      test
 }
 
+// generateRandomData returns random data generated from the seed of the
+// tester, which is logged when the first data is generated
+func (self *chunkerTester) generateRandomData(l int) (r io.Reader, slice []byte) {
+	if self.gen == nil {
+		self.gen = datagen.New(self.t, datagen.Random)
+	}
+	return generateRandomData(self.gen, l)
+}
+
 // fakeChunkStore doesn't store anything, just implements the ChunkStore interface
 // It can be used to inject into a hasherStore if you don't want to actually store data just do the
 // hashing
@@ -95,7 +106,7 @@ func testRandomData(usePyramid bool, hash string, n int, tester *chunkerTester)
 	input, found := tester.inputs[uint64(n)]
 	var data io.Reader
 	if !found {
-		data, input = generateRandomData(n)
+		data, input = tester.generateRandomData(n)
 		tester.inputs[uint64(n)] = input
 	} else {
 		data = io.LimitReader(bytes.NewReader(input), int64(n))
@@ -191,7 +202,7 @@ func TestDataAppend(t *testing.T) {
 		input, found := tester.inputs[uint64(n)]
 		var data io.Reader
 		if !found {
-			data, input = generateRandomData(n)
+			data, input = tester.generateRandomData(n)
 			tester.inputs[uint64(n)] = input
 		} else {
 			data = io.LimitReader(bytes.NewReader(input), int64(n))
@@ -210,7 +221,7 @@ func TestDataAppend(t *testing.T) {
 		appendInput, found := tester.inputs[uint64(m)]
 		var appendData io.Reader
 		if !found {
-			appendData, appendInput = generateRandomData(m)
+			appendData, appendInput = tester.generateRandomData(m)
 			tester.inputs[uint64(m)] = appendInput
 		} else {
 			appendData = io.LimitReader(bytes.NewReader(appendInput), int64(m))
@@ -64,3 +64,22 @@ func (m *MapChunkStore) Get(addr Address) (*Chunk, error) {
 
 func (m *MapChunkStore) Close() {
 }
+
+// FakeChunkStore doesn't store anything, just implements the ChunkStore interface
+// It can be used to compute the swarm hash of content without storing its chunks
+type FakeChunkStore struct {
+}
+
+// Put doesn't store anything it is just here to implement ChunkStore
+func (f *FakeChunkStore) Put(chunk *Chunk) {
+	chunk.markAsStored()
+}
+
+// Get doesn't retrieve anything, it always returns ErrChunkNotFound
+func (f *FakeChunkStore) Get(Address) (*Chunk, error) {
+	return nil, ErrChunkNotFound
+}
+
+// Close doesn't do anything it is just here to implement ChunkStore
+func (f *FakeChunkStore) Close() {
+}
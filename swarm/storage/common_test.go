@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/swarm/testutil/datagen"
 	colorable "github.com/mattn/go-colorable"
 )
 
@@ -143,11 +144,10 @@ func (r *brokenLimitedReader) Read(buf []byte) (int, error) {
 	return r.lr.Read(buf)
 }
 
-func generateRandomData(l int) (r io.Reader, slice []byte) {
-	slice = make([]byte, l)
-	if _, err := rand.Read(slice); err != nil {
-		panic("rand error")
-	}
+// generateRandomData returns the next l bytes of gen, so that the data of a
+// failed test can be reproduced from the seed it logged
+func generateRandomData(gen *datagen.Generator, l int) (r io.Reader, slice []byte) {
+	slice = gen.Bytes(l)
 	r = io.LimitReader(bytes.NewReader(slice), int64(l))
 	return
 }
@@ -20,16 +20,19 @@ package storage
 // no need for queueing/caching
 
 import (
-	"fmt"
+	"errors"
 
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const openFileLimit = 128
 
+var errForeignBatch = errors.New("batch created by another backend")
+
+// LDBDatabase is the LevelDB ChunkStoreBackend
 type LDBDatabase struct {
 	db *leveldb.DB
 }
@@ -46,19 +49,19 @@ func NewLDBDatabase(file string) (*LDBDatabase, error) {
 	return database, nil
 }
 
-func (self *LDBDatabase) Put(key []byte, value []byte) {
+func (self *LDBDatabase) Put(key []byte, value []byte) error {
 	metrics.GetOrRegisterCounter("ldbdatabase.put", nil).Inc(1)
 
-	err := self.db.Put(key, value, nil)
-	if err != nil {
-		fmt.Println("Error put", err)
-	}
+	return self.db.Put(key, value, nil)
 }
 
 func (self *LDBDatabase) Get(key []byte) ([]byte, error) {
 	metrics.GetOrRegisterCounter("ldbdatabase.get", nil).Inc(1)
 
 	dat, err := self.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrKeyNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -79,16 +82,43 @@ func (self *LDBDatabase) LastKnownTD() []byte {
 	return data
 }
 
-func (self *LDBDatabase) NewIterator() iterator.Iterator {
-	metrics.GetOrRegisterCounter("ldbdatabase.newiterator", nil).Inc(1)
+func (self *LDBDatabase) Iterate(start []byte, f func(key, value []byte) bool) error {
+	metrics.GetOrRegisterCounter("ldbdatabase.iterate", nil).Inc(1)
 
-	return self.db.NewIterator(nil, nil)
+	it := self.db.NewIterator(&util.Range{Start: start}, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if !f(it.Key(), it.Value()) {
+			break
+		}
+	}
+	return it.Error()
 }
 
-func (self *LDBDatabase) Write(batch *leveldb.Batch) error {
+func (self *LDBDatabase) Count(prefix []byte) (uint64, error) {
+	it := self.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+
+	var count uint64
+	for it.Next() {
+		count++
+	}
+	return count, it.Error()
+}
+
+func (self *LDBDatabase) NewBatch() BackendBatch {
+	return new(leveldb.Batch)
+}
+
+func (self *LDBDatabase) Write(batch BackendBatch) error {
 	metrics.GetOrRegisterCounter("ldbdatabase.write", nil).Inc(1)
 
-	return self.db.Write(batch, nil)
+	b, ok := batch.(*leveldb.Batch)
+	if !ok {
+		return errForeignBatch
+	}
+	return self.db.Write(b, nil)
 }
 
 func (self *LDBDatabase) Close() {
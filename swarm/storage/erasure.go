@@ -0,0 +1,231 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/storage/erasure"
+)
+
+var errErasureEncrypted = errors.New("erasure coding is not supported for encrypted content")
+
+/*
+erasureSplitter builds a chunk tree in which every intermediate chunk
+references parity chunks after its children. These are computed with a
+Reed-Solomon code over the children, each padded to the length of the first
+one, so that the data of up to parity-many missing children of an intermediate
+chunk can be reconstructed from its other children and parity chunks.
+
+An intermediate chunk holds the references of at most branches-parity
+children, so the tree can only be read by a LazyChunkReader knowing the
+number of parity chunks. Encrypted content cannot be erasure coded, as the
+length of decrypted intermediate chunks is derived from a tree without parity
+chunks.
+*/
+type erasureSplitter struct {
+	data     io.Reader
+	putter   Putter
+	parity   int
+	branches int64 // children per intermediate chunk
+	hashSize int64
+	coders   map[int]*erasure.Coder
+}
+
+func newErasureSplitter(data io.Reader, putter Putter, parity int) (*erasureSplitter, error) {
+	hashSize := putter.RefSize()
+	if hashSize != KeyLength {
+		return nil, errErasureEncrypted
+	}
+	branches := DefaultChunkSize/hashSize - int64(parity)
+	if parity <= 0 || branches < 1 {
+		return nil, ErrInvalidParity
+	}
+	return &erasureSplitter{
+		data:     data,
+		putter:   putter,
+		parity:   parity,
+		branches: branches,
+		hashSize: hashSize,
+		coders:   make(map[int]*erasure.Coder),
+	}, nil
+}
+
+// Split stores the chunks of the content and returns the root reference
+func (s *erasureSplitter) Split(size int64) (Reference, func(), error) {
+	defer s.putter.Close()
+
+	depth := 0
+	treeSize := DefaultChunkSize
+	for ; treeSize < size; treeSize *= s.branches {
+		depth++
+	}
+	ref, _, err := s.split(depth, treeSize/s.branches, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, s.putter.Wait, nil
+}
+
+// split mirrors TreeChunker.split, also returning the data of the chunk
+func (s *erasureSplitter) split(depth int, treeSize int64, size int64) (Reference, ChunkData, error) {
+	for depth > 0 && size < treeSize {
+		treeSize /= s.branches
+		depth--
+	}
+	if depth == 0 {
+		chunk := make(ChunkData, size+8)
+		binary.LittleEndian.PutUint64(chunk[:8], uint64(size))
+		if _, err := io.ReadFull(s.data, chunk[8:]); err != nil {
+			return nil, nil, err
+		}
+		ref, err := s.putter.Put(chunk)
+		return ref, chunk, err
+	}
+	branchCnt := (size + treeSize - 1) / treeSize
+	chunk := make(ChunkData, 8, 8+(branchCnt+int64(s.parity))*s.hashSize)
+	binary.LittleEndian.PutUint64(chunk[:8], uint64(size))
+
+	shards := make([][]byte, int(branchCnt)+s.parity)
+	for i := int64(0); i < branchCnt; i++ {
+		// the last item can have shorter data
+		secSize := treeSize
+		if i == branchCnt-1 {
+			secSize = size - i*treeSize
+		}
+		ref, data, err := s.split(depth-1, treeSize/s.branches, secSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		chunk = append(chunk, ref...)
+		shards[i] = data
+	}
+	parity, err := s.encode(shards[:branchCnt])
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, data := range parity {
+		ref, err := s.putter.Put(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		chunk = append(chunk, ref...)
+	}
+	ref, err := s.putter.Put(chunk)
+	return ref, chunk, err
+}
+
+// encode returns the parity chunks of the given children
+func (s *erasureSplitter) encode(children [][]byte) ([][]byte, error) {
+	coder, ok := s.coders[len(children)]
+	if !ok {
+		var err error
+		if coder, err = erasure.New(len(children), s.parity); err != nil {
+			return nil, err
+		}
+		s.coders[len(children)] = coder
+	}
+	shards := padShards(children, len(children[0]), s.parity)
+	if err := coder.Encode(shards); err != nil {
+		return nil, err
+	}
+	return shards[len(children):], nil
+}
+
+// padShards returns the given chunks padded with zeros to size, followed by
+// room for extra shards. Missing chunks are left nil.
+func padShards(chunks [][]byte, size int, extra int) [][]byte {
+	shards := make([][]byte, len(chunks)+extra)
+	for i, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+		shards[i] = make([]byte, size)
+		copy(shards[i], chunk)
+	}
+	return shards
+}
+
+// childLength returns the length of a chunk in a tree with parity chunks,
+// depth and treeSize being those its parent passes on to it
+func (self *LazyChunkReader) childLength(depth int, treeSize int64, size int64) int64 {
+	for size < treeSize && depth > self.depth {
+		treeSize /= self.branches
+		depth--
+	}
+	if depth == self.depth {
+		return size + 8
+	}
+	branchCnt := (size + treeSize - 1) / treeSize
+	return 8 + (branchCnt+int64(self.parity))*self.hashSize
+}
+
+// recover reconstructs the data of child j of the intermediate chunk at the
+// given depth from its other children and its parity chunks, treeSize being
+// the size of the subtree of a child.
+func (self *LazyChunkReader) recover(ctx context.Context, parent ChunkData, depth int, treeSize int64, j int64) (ChunkData, error) {
+	metrics.GetOrRegisterCounter("lazychunkreader.erasure.recover", nil).Inc(1)
+
+	size := parent.Size()
+	branchCnt := (size + treeSize - 1) / treeSize
+	refCnt := branchCnt + int64(self.parity)
+	if int64(len(parent)-8) != refCnt*self.hashSize {
+		return nil, fmt.Errorf("chunk with %d references is not erasure coded with %d parity chunks", (len(parent)-8)/int(self.hashSize), self.parity)
+	}
+	childSize := func(i int64) int64 {
+		if i == branchCnt-1 {
+			return size - i*treeSize
+		}
+		return treeSize
+	}
+	shardSize := self.childLength(depth-1, treeSize/self.branches, childSize(0))
+
+	// retrieve the other children and the parity chunks
+	chunks := make([][]byte, refCnt)
+	wg := sync.WaitGroup{}
+	for i := int64(0); i < refCnt; i++ {
+		if i == j {
+			continue
+		}
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+			ref := parent[8+i*self.hashSize : 8+(i+1)*self.hashSize]
+			if data, err := self.get(ctx, Reference(ref)); err == nil && int64(len(data)) <= shardSize {
+				chunks[i] = data
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	coder, err := erasure.New(int(branchCnt), self.parity)
+	if err != nil {
+		return nil, err
+	}
+	shards := padShards(chunks, int(shardSize), 0)
+	if err := coder.Reconstruct(shards); err != nil {
+		metrics.GetOrRegisterCounter("lazychunkreader.erasure.recover.fail", nil).Inc(1)
+		return nil, err
+	}
+	return ChunkData(shards[j][:self.childLength(depth-1, treeSize/self.branches, childSize(j))]), nil
+}
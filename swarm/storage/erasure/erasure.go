@@ -0,0 +1,226 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package erasure implements a systematic Reed-Solomon erasure code over
+// GF(2^8), which computes parity shards from data shards so that the data can
+// be reconstructed from any data-many of the shards.
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxShards is the maximum total number of data and parity shards.
+const MaxShards = 256
+
+var (
+	ErrShardCount   = errors.New("invalid number of shards")
+	ErrShardSize    = errors.New("shards differ in size")
+	ErrTooFewShards = errors.New("too few shards to reconstruct the data")
+)
+
+// Coder computes and recovers the parity shards of a fixed number of data
+// shards. The parity shards are the data shards multiplied by a Cauchy matrix,
+// any square submatrix of which is invertible, so that any data-many shards
+// determine the others.
+type Coder struct {
+	data   int
+	parity int
+	matrix [][]byte // parity rows of the encoding matrix
+}
+
+// New creates a Coder for the given numbers of data and parity shards.
+func New(data, parity int) (*Coder, error) {
+	if data <= 0 || parity < 0 || data+parity > MaxShards {
+		return nil, fmt.Errorf("%v: %d data and %d parity shards", ErrShardCount, data, parity)
+	}
+	matrix := make([][]byte, parity)
+	for i := range matrix {
+		matrix[i] = make([]byte, data)
+		for j := range matrix[i] {
+			// data+i and j are distinct field elements, so their sum is never 0
+			matrix[i][j] = gfInv(byte(data+i) ^ byte(j))
+		}
+	}
+	return &Coder{data: data, parity: parity, matrix: matrix}, nil
+}
+
+// Encode computes the parity shards, shards[data:], from the data shards,
+// shards[:data], which must have the same length. Missing parity shards are
+// allocated.
+func (c *Coder) Encode(shards [][]byte) error {
+	if len(shards) != c.data+c.parity {
+		return ErrShardCount
+	}
+	size := len(shards[0])
+	for _, shard := range shards[:c.data] {
+		if len(shard) != size {
+			return ErrShardSize
+		}
+	}
+	for i := 0; i < c.parity; i++ {
+		if len(shards[c.data+i]) != size {
+			shards[c.data+i] = make([]byte, size)
+		}
+		combine(shards[c.data+i], c.matrix[i], shards[:c.data])
+	}
+	return nil
+}
+
+// Reconstruct fills in the missing shards, which are nil, from the present
+// ones. At least data-many shards of the same length must be present.
+func (c *Coder) Reconstruct(shards [][]byte) error {
+	if len(shards) != c.data+c.parity {
+		return ErrShardCount
+	}
+	// pick the first data-many present shards
+	var (
+		present []int
+		size    = -1
+	)
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if size == -1 {
+			size = len(shard)
+		} else if len(shard) != size {
+			return ErrShardSize
+		}
+		if len(present) < c.data {
+			present = append(present, i)
+		}
+	}
+	if len(present) < c.data {
+		return ErrTooFewShards
+	}
+	// the rows of the encoding matrix which produced the picked shards,
+	// inverted, recover the data shards from them
+	rows := make([][]byte, c.data)
+	inputs := make([][]byte, c.data)
+	for r, i := range present {
+		if i < c.data {
+			rows[r] = make([]byte, c.data)
+			rows[r][i] = 1
+		} else {
+			rows[r] = append([]byte{}, c.matrix[i-c.data]...)
+		}
+		inputs[r] = shards[i]
+	}
+	decode, err := invert(rows)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < c.data; i++ {
+		if shards[i] == nil {
+			shards[i] = make([]byte, size)
+			combine(shards[i], decode[i], inputs)
+		}
+	}
+	for i := 0; i < c.parity; i++ {
+		if shards[c.data+i] == nil {
+			shards[c.data+i] = make([]byte, size)
+			combine(shards[c.data+i], c.matrix[i], shards[:c.data])
+		}
+	}
+	return nil
+}
+
+// combine sets out to the linear combination of the inputs with the given
+// coefficients
+func combine(out []byte, coeffs []byte, inputs [][]byte) {
+	for i := range out {
+		out[i] = 0
+	}
+	for j, coeff := range coeffs {
+		if coeff == 0 {
+			continue
+		}
+		for i, b := range inputs[j] {
+			out[i] ^= gfMul(coeff, b)
+		}
+	}
+}
+
+// invert returns the inverse of a square matrix by Gauss-Jordan elimination,
+// modifying the matrix
+func invert(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	inv := make([][]byte, n)
+	for i := range inv {
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && m[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errors.New("singular matrix")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		scale := gfInv(m[col][col])
+		for j := 0; j < n; j++ {
+			m[col][j] = gfMul(m[col][j], scale)
+			inv[col][j] = gfMul(inv[col][j], scale)
+		}
+		for row := 0; row < n; row++ {
+			if row == col || m[row][col] == 0 {
+				continue
+			}
+			factor := m[row][col]
+			for j := 0; j < n; j++ {
+				m[row][j] ^= gfMul(factor, m[col][j])
+				inv[row][j] ^= gfMul(factor, inv[col][j])
+			}
+		}
+	}
+	return inv, nil
+}
+
+// Arithmetic in GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1
+var (
+	gfExp [510]byte
+	gfLog [256]int
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfExp[i+255] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-gfLog[a]]
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package erasure
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestReconstruct(t *testing.T) {
+	for _, test := range []struct {
+		data, parity int
+	}{
+		{1, 1},
+		{4, 2},
+		{120, 8},
+		{128, 128},
+	} {
+		coder, err := New(test.data, test.parity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := make([][]byte, test.data+test.parity)
+		for i := 0; i < test.data; i++ {
+			shards[i] = make([]byte, 100)
+			rand.Read(shards[i])
+		}
+		if err := coder.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		original := make([][]byte, len(shards))
+		copy(original, shards)
+
+		// drop as many random shards as there are parity shards
+		for _, i := range rand.Perm(len(shards))[:test.parity] {
+			shards[i] = nil
+		}
+		if err := coder.Reconstruct(shards); err != nil {
+			t.Fatalf("%d+%d: %v", test.data, test.parity, err)
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], original[i]) {
+				t.Fatalf("%d+%d: shard %d mismatch", test.data, test.parity, i)
+			}
+		}
+
+		// one more missing shard is too many
+		for _, i := range rand.Perm(len(shards))[:test.parity+1] {
+			shards[i] = nil
+		}
+		if err := coder.Reconstruct(shards); err != ErrTooFewShards {
+			t.Fatalf("%d+%d: expected ErrTooFewShards, got %v", test.data, test.parity, err)
+		}
+	}
+}
@@ -42,4 +42,7 @@ var (
 	ErrChunkForward     = errors.New("cannot forward")
 	ErrChunkUnavailable = errors.New("chunk unavailable")
 	ErrChunkTimeout     = errors.New("timeout")
+	ErrNotPinned        = errors.New("chunk tree not pinned")
+	ErrInvalidRange     = errors.New("invalid content range")
+	ErrInvalidParity    = errors.New("invalid number of parity chunks")
 )
@@ -17,7 +17,12 @@
 package storage
 
 import (
+	"context"
 	"io"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
 )
 
 /*
@@ -36,15 +41,28 @@ const (
 	defaultLDBCapacity                = 5000000 // capacity for LevelDB, by default 5*10^6*4096 bytes == 20GB
 	defaultCacheCapacity              = 500     // capacity for in-memory chunks' cache
 	defaultChunkRequestsCacheCapacity = 5000000 // capacity for container holding outgoing requests for chunks. should be set to LevelDB capacity
+	pendingChunksPerWorker            = 128     // chunks a splitter worker may have put before they are stored
 )
 
 type FileStore struct {
 	ChunkStore
 	hashFunc SwarmHasher
+	dedup    bool
+	workers  int
 }
 
 type FileStoreParams struct {
-	Hash string
+	Hash    string
+	Dedup   bool // skip writing the chunks already in the chunk store
+	Workers int  // maximum number of chunk processors per upload, ChunkProcessors if 0
+}
+
+// StoreResult is the outcome of storing content with StoreWithResult
+type StoreResult struct {
+	Addr        Address // root address of the content
+	Chunks      uint64  // number of chunks of the content
+	DedupChunks uint64  // chunks already in the store, which were not written again
+	DedupBytes  uint64  // total length of the deduplicated chunks
 }
 
 func NewFileStoreParams() *FileStoreParams {
@@ -67,9 +85,15 @@ func NewLocalFileStore(datadir string, basekey []byte) (*FileStore, error) {
 
 func NewFileStore(store ChunkStore, params *FileStoreParams) *FileStore {
 	hashFunc := MakeHashFunc(params.Hash)
+	workers := params.Workers
+	if workers <= 0 {
+		workers = ChunkProcessors
+	}
 	return &FileStore{
 		ChunkStore: store,
 		hashFunc:   hashFunc,
+		dedup:      params.Dedup,
+		workers:    workers,
 	}
 }
 
@@ -79,17 +103,165 @@ func NewFileStore(store ChunkStore, params *FileStoreParams) *FileStore {
 // report error if retrieval of chunks within requested range time out.
 // It returns a reader with the chunk data and whether the content was encrypted
 func (self *FileStore) Retrieve(addr Address) (reader *LazyChunkReader, isEncrypted bool) {
+	return self.RetrieveContext(context.Background(), addr)
+}
+
+// RetrieveContext is Retrieve with the reads of the returned reader traced as
+// a part of the request in ctx
+func (self *FileStore) RetrieveContext(ctx context.Context, addr Address) (reader *LazyChunkReader, isEncrypted bool) {
 	isEncrypted = len(addr) > self.hashFunc().Size()
 	getter := NewHasherStore(self.ChunkStore, self.hashFunc, isEncrypted)
 	reader = TreeJoin(addr, getter, 0)
+	reader.ctx = ctx
 	return
 }
 
+// RetrieveRange returns a reader of the length bytes of the content at addr
+// starting at offset, truncated at the end of the content. Reads seek into
+// the chunk tree, so only the chunks covering the range are retrieved.
+func (self *FileStore) RetrieveRange(ctx context.Context, addr Address, offset, length int64) (io.ReadSeeker, error) {
+	reader, _ := self.RetrieveContext(ctx, addr)
+	size, err := reader.Size(nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || length < 0 || offset > size {
+		return nil, ErrInvalidRange
+	}
+	if length > size-offset {
+		length = size - offset
+	}
+	return io.NewSectionReader(reader, offset, length), nil
+}
+
 // Public API. Main entry point for document storage directly. Used by the
 // FS-aware API and httpaccess
 func (self *FileStore) Store(data io.Reader, size int64, toEncrypt bool) (addr Address, wait func(), err error) {
+	return self.StoreContext(context.Background(), data, size, toEncrypt)
+}
+
+// StoreContext is Store with the chunking of the data traced as a part of
+// the request in ctx
+func (self *FileStore) StoreContext(ctx context.Context, data io.Reader, size int64, toEncrypt bool) (addr Address, wait func(), err error) {
+	result, wait, err := self.StoreWithResult(ctx, data, size, toEncrypt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Addr, wait, nil
+}
+
+// StoreWithResult is StoreContext also reporting the chunks of the content
+// which were already in the chunk store. These are not written again if the
+// FileStore deduplicates chunks, which only applies to unencrypted content.
+// The content is split by a pool of at most FileStoreParams.Workers chunk
+// processors, which wait for the chunk store to catch up if it falls behind.
+func (self *FileStore) StoreWithResult(ctx context.Context, data io.Reader, size int64, toEncrypt bool) (result *StoreResult, wait func(), err error) {
+	_, sp := tracing.StartSpan(ctx, "filestore.store")
+	defer sp.Finish()
+	sp.SetTag("size", size)
+
+	putter := NewHasherStore(self.ChunkStore, self.hashFunc, toEncrypt)
+	putter.dedup = self.dedup
+	putter.limitPending(self.workers * pendingChunksPerWorker)
+	params := NewPyramidSplitterParams(nil, data, putter, putter, DefaultChunkSize)
+	params.workers = self.workers
+	addr, wait, err := NewPyramidSplitter(params).Split()
+	sp.SetError(err)
+	if err != nil {
+		return nil, nil, err
+	}
+	result = &StoreResult{
+		Addr:        addr,
+		Chunks:      atomic.LoadUint64(&putter.chunkCnt),
+		DedupChunks: atomic.LoadUint64(&putter.dedupCnt),
+		DedupBytes:  atomic.LoadUint64(&putter.dedupBytes),
+	}
+	metrics.GetOrRegisterCounter("filestore.dedup.chunks", nil).Inc(int64(result.DedupChunks))
+	metrics.GetOrRegisterCounter("filestore.dedup.bytes", nil).Inc(int64(result.DedupBytes))
+	sp.SetTag("dedup", result.DedupChunks)
+	return result, wait, nil
+}
+
+// StoreErasure is StoreContext with the content erasure coded: every
+// intermediate chunk of the tree references the given number of parity
+// chunks, from which up to as many of its missing children can be
+// reconstructed. The content can only be retrieved with RetrieveErasure and
+// the same number of parity chunks, which is thus recorded in the manifest
+// entry of the content. Encrypted content cannot be erasure coded.
+func (self *FileStore) StoreErasure(ctx context.Context, data io.Reader, size int64, toEncrypt bool, parity int) (addr Address, wait func(), err error) {
+	_, sp := tracing.StartSpan(ctx, "filestore.store.erasure")
+	defer sp.Finish()
+	sp.SetTag("size", size)
+	sp.SetTag("parity", parity)
+
 	putter := NewHasherStore(self.ChunkStore, self.hashFunc, toEncrypt)
-	return PyramidSplit(data, putter, putter)
+	splitter, err := newErasureSplitter(data, putter, parity)
+	if err != nil {
+		return nil, nil, err
+	}
+	ref, wait, err := splitter.Split(size)
+	sp.SetError(err)
+	return Address(ref), wait, err
+}
+
+// RetrieveErasure is RetrieveContext for content stored with StoreErasure,
+// reconstructing the chunks which cannot be retrieved from the parity chunks
+func (self *FileStore) RetrieveErasure(ctx context.Context, addr Address, parity int) *LazyChunkReader {
+	reader, _ := self.RetrieveContext(ctx, addr)
+	if parity > 0 {
+		reader.parity = parity
+		reader.branches -= int64(parity)
+	}
+	return reader
+}
+
+// StoreResumable is Store in resumable mode: the progress of the splitter is
+// checkpointed in the local store under the given upload id, so that if the
+// upload is interrupted, calling StoreResumable again with the same id and
+// content skips the chunks already stored and continues where the previous
+// call left off. A checkpoint is only used if the content starts with the
+// bytes it was taken with, otherwise seekable content is split again from the
+// start. Encrypted uploads are not checkpointed, so that their keys are not
+// persisted. It returns once all the chunks are stored and the checkpoint is
+// removed. The chunk store must be backed by an LDBStore.
+func (self *FileStore) StoreResumable(ctx context.Context, id string, data io.Reader, size int64, toEncrypt bool) (addr Address, err error) {
+	_, sp := tracing.StartSpan(ctx, "filestore.store.resumable")
+	defer sp.Finish()
+	sp.SetTag("size", size)
+
+	store, ok := self.ChunkStore.(checkpointStore)
+	if !ok {
+		return nil, errResumableUnsupported
+	}
+	splitter := newResumableSplitter(ctx, id, store, data, size, toEncrypt, func() Putter {
+		return NewHasherStore(self.ChunkStore, self.hashFunc, toEncrypt)
+	})
+	ref, err := splitter.Split()
+	sp.SetError(err)
+	return Address(ref), err
+}
+
+// HasChunk reports whether the chunk with the given address is in the local
+// chunk store
+func (self *FileStore) HasChunk(addr Address) bool {
+	store, ok := self.ChunkStore.(dedupChunkStore)
+	return ok && store.Has(addr)
+}
+
+// PutChunk stores a single unencrypted chunk given by its span and payload,
+// such as a chunk of content split by a client, and returns its address
+func (self *FileStore) PutChunk(data ChunkData) (Address, error) {
+	if len(data) < 8 || int64(len(data)) > 8+DefaultChunkSize {
+		return nil, ErrChunkInvalid
+	}
+	putter := NewHasherStore(self.ChunkStore, self.hashFunc, false)
+	ref, err := putter.Put(data)
+	putter.Close()
+	if err != nil {
+		return nil, err
+	}
+	putter.Wait()
+	return Address(ref), nil
 }
 
 func (self *FileStore) HashSize() int {
@@ -18,10 +18,16 @@ package storage
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"io/ioutil"
 	"os"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/swarm/testutil/datagen"
 )
 
 const testDataSize = 0x1000000
@@ -48,7 +54,7 @@ func testFileStoreRandom(toEncrypt bool, t *testing.T) {
 	fileStore := NewFileStore(localStore, NewFileStoreParams())
 	defer os.RemoveAll("/tmp/bzz")
 
-	reader, slice := generateRandomData(testDataSize)
+	reader, slice := generateRandomData(datagen.New(t, datagen.Random), testDataSize)
 	key, wait, err := fileStore.Store(reader, testDataSize, toEncrypt)
 	if err != nil {
 		t.Errorf("Store error: %v", err)
@@ -109,7 +115,7 @@ func testDPA_capacity(toEncrypt bool, t *testing.T) {
 		DbStore:  db,
 	}
 	fileStore := NewFileStore(localStore, NewFileStoreParams())
-	reader, slice := generateRandomData(testDataSize)
+	reader, slice := generateRandomData(datagen.New(t, datagen.Random), testDataSize)
 	key, wait, err := fileStore.Store(reader, testDataSize, toEncrypt)
 	if err != nil {
 		t.Errorf("Store error: %v", err)
@@ -162,3 +168,391 @@ func testDPA_capacity(toEncrypt bool, t *testing.T) {
 		t.Errorf("Comparison error after clearing memStore.")
 	}
 }
+
+// TestFileStoreResumable tests that resumable uploads build the same chunk
+// tree as the tree chunker, and that an interrupted upload continues from its
+// last checkpoint
+func TestFileStoreResumable(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	localStore := &LocalStore{
+		memStore: NewMemStore(NewDefaultStoreParams(), db),
+		DbStore:  db,
+	}
+	fileStore := NewFileStore(localStore, NewFileStoreParams())
+	gen := datagen.New(t, datagen.Random)
+
+	treeRoot := func(data []byte) Address {
+		putter := NewHasherStore(NewMapChunkStore(), MakeHashFunc(DefaultHash), false)
+		addr, wait, err := TreeSplit(bytes.NewReader(data), int64(len(data)), putter)
+		if err != nil {
+			t.Fatalf("TreeSplit error: %v", err)
+		}
+		wait()
+		return addr
+	}
+	for _, size := range []int{0, 1, 4095, 4096, 4097, 4096 * 128, 4096*128 + 1, 4096 * 129, 4096*128*128 + 4096} {
+		data := gen.Bytes(size)
+		addr, err := fileStore.StoreResumable(context.Background(), "test", bytes.NewReader(data), int64(size), false)
+		if err != nil {
+			t.Fatalf("size %d: StoreResumable error: %v", size, err)
+		}
+		if want := treeRoot(data); !bytes.Equal(addr, want) {
+			t.Fatalf("size %d: root mismatch: got %v, want %v", size, addr, want)
+		}
+	}
+
+	// interrupt an upload after two and a half checkpoint intervals
+	size := 3*resumableCheckpointInterval*int(DefaultChunkSize) + 1000
+	data := gen.Bytes(size)
+	errAt := 5 * resumableCheckpointInterval * int(DefaultChunkSize) / 2
+	broken := brokenLimitReader(bytes.NewReader(data), size, errAt)
+	if _, err := fileStore.StoreResumable(context.Background(), "interrupted", broken, int64(size), false); err == nil {
+		t.Fatal("expected error from interrupted upload")
+	}
+	blob, err := db.GetUploadCheckpoint("interrupted")
+	if err != nil {
+		t.Fatalf("checkpoint not found: %v", err)
+	}
+	var cp uploadCheckpoint
+	if err := rlp.DecodeBytes(blob, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(2 * resumableCheckpointInterval * DefaultChunkSize); cp.Offset != want {
+		t.Fatalf("checkpoint offset mismatch: got %d, want %d", cp.Offset, want)
+	}
+
+	// a resumed upload of different content must start over
+	other := make([]byte, size)
+	copy(other, data)
+	other[0] ^= 0xff
+	unseekable := struct{ io.Reader }{bytes.NewReader(other)}
+	if _, err := fileStore.StoreResumable(context.Background(), "interrupted", unseekable, int64(size), false); err != errResumableMismatch {
+		t.Fatalf("expected errResumableMismatch for unseekable content, got %v", err)
+	}
+	if _, err := db.GetUploadCheckpoint("interrupted"); err == nil {
+		t.Fatal("checkpoint of different content not removed")
+	}
+	interrupt := func() {
+		broken := brokenLimitReader(bytes.NewReader(data), size, errAt)
+		if _, err := fileStore.StoreResumable(context.Background(), "interrupted", broken, int64(size), false); err == nil {
+			t.Fatal("expected error from interrupted upload")
+		}
+	}
+	interrupt()
+	addr, err := fileStore.StoreResumable(context.Background(), "interrupted", bytes.NewReader(other), int64(size), false)
+	if err != nil {
+		t.Fatalf("StoreResumable of different content error: %v", err)
+	}
+	if want := treeRoot(other); !bytes.Equal(addr, want) {
+		t.Fatalf("different content root mismatch: got %v, want %v", addr, want)
+	}
+
+	// the resumed upload continues from the checkpoint
+	interrupt()
+	addr, err = fileStore.StoreResumable(context.Background(), "interrupted", bytes.NewReader(data), int64(size), false)
+	if err != nil {
+		t.Fatalf("resumed StoreResumable error: %v", err)
+	}
+	if want := treeRoot(data); !bytes.Equal(addr, want) {
+		t.Fatalf("resumed upload root mismatch: got %v, want %v", addr, want)
+	}
+	if _, err := db.GetUploadCheckpoint("interrupted"); err == nil {
+		t.Fatal("checkpoint not removed after the upload completed")
+	}
+	reader, _ := fileStore.Retrieve(addr)
+	result := make([]byte, size)
+	if _, err := reader.ReadAt(result, 0); err != io.EOF {
+		t.Fatalf("Retrieve error: %v", err)
+	}
+	if !bytes.Equal(result, data) {
+		t.Fatal("retrieved data mismatch")
+	}
+}
+
+// TestFileStoreResumableMissingChunk tests that a checkpoint referencing a
+// chunk which is no longer stored is discarded
+func TestFileStoreResumableMissingChunk(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	fileStore := NewFileStore(db, NewFileStoreParams())
+
+	size := 3*resumableCheckpointInterval*int(DefaultChunkSize) + 1000
+	data := datagen.New(t, datagen.Random).Bytes(size)
+	errAt := 5 * resumableCheckpointInterval * int(DefaultChunkSize) / 2
+	if _, err := fileStore.StoreResumable(context.Background(), "missing", brokenLimitReader(bytes.NewReader(data), size, errAt), int64(size), false); err == nil {
+		t.Fatal("expected error from interrupted upload")
+	}
+	blob, err := db.GetUploadCheckpoint("missing")
+	if err != nil {
+		t.Fatalf("checkpoint not found: %v", err)
+	}
+	var cp uploadCheckpoint
+	if err := rlp.DecodeBytes(blob, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Refs) == 0 || len(cp.Refs[len(cp.Refs)-1]) == 0 {
+		t.Fatal("checkpoint references no chunks")
+	}
+	// remove the index entry of the chunk as the garbage collector does
+	if err := db.db.Delete(getIndexKey(Address(cp.Refs[len(cp.Refs)-1][0]))); err != nil {
+		t.Fatal(err)
+	}
+
+	splitter := newResumableSplitter(context.Background(), "missing", db, bytes.NewReader(data), int64(size), false, func() Putter {
+		return NewHasherStore(db, MakeHashFunc(DefaultHash), false)
+	})
+	if splitter.cp.Offset != 0 {
+		t.Fatalf("expected checkpoint to be discarded, got offset %d", splitter.cp.Offset)
+	}
+}
+
+// TestFileStoreResumableEncrypted tests that encrypted uploads are not
+// checkpointed
+func TestFileStoreResumableEncrypted(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	fileStore := NewFileStore(db, NewFileStoreParams())
+
+	size := 3*resumableCheckpointInterval*int(DefaultChunkSize) + 1000
+	data := datagen.New(t, datagen.Random).Bytes(size)
+	errAt := 5 * resumableCheckpointInterval * int(DefaultChunkSize) / 2
+	if _, err := fileStore.StoreResumable(context.Background(), "encrypted", brokenLimitReader(bytes.NewReader(data), size, errAt), int64(size), true); err == nil {
+		t.Fatal("expected error from interrupted upload")
+	}
+	if _, err := db.GetUploadCheckpoint("encrypted"); err == nil {
+		t.Fatal("encrypted upload was checkpointed")
+	}
+}
+
+func TestFileStoreRetrieveRange(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	localStore := &LocalStore{
+		memStore: NewMemStore(NewDefaultStoreParams(), db),
+		DbStore:  db,
+	}
+	fileStore := NewFileStore(localStore, NewFileStoreParams())
+
+	size := 5*4096*128 + 123
+	reader, data := generateRandomData(datagen.New(t, datagen.Random), size)
+	addr, wait, err := fileStore.Store(reader, int64(size), false)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wait()
+
+	for _, r := range [][2]int64{{0, 1}, {4095, 2}, {600000, 1200000}, {int64(size) - 10, 100}, {int64(size), 10}} {
+		section, err := fileStore.RetrieveRange(context.Background(), addr, r[0], r[1])
+		if err != nil {
+			t.Fatalf("range %v: RetrieveRange error: %v", r, err)
+		}
+		end := r[0] + r[1]
+		if end > int64(size) {
+			end = int64(size)
+		}
+		// read the second half of the range first to test seeking
+		half := (end - r[0]) / 2
+		if _, err := section.Seek(half, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		tail, err := ioutil.ReadAll(section)
+		if err != nil {
+			t.Fatalf("range %v: read error: %v", r, err)
+		}
+		if !bytes.Equal(tail, data[r[0]+half:end]) {
+			t.Fatalf("range %v: content mismatch", r)
+		}
+	}
+	if _, err := fileStore.RetrieveRange(context.Background(), addr, int64(size)+1, 1); err != ErrInvalidRange {
+		t.Fatalf("expected ErrInvalidRange, got %v", err)
+	}
+}
+
+// TestFileStoreDedup tests that storing content which mostly consists of
+// chunks already in the store only writes the new chunks
+func TestFileStoreDedup(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	localStore := &LocalStore{
+		memStore: NewMemStore(NewDefaultStoreParams(), db),
+		DbStore:  db,
+	}
+	params := NewFileStoreParams()
+	params.Dedup = true
+	fileStore := NewFileStore(localStore, params)
+
+	store := func(data []byte, toEncrypt bool) *StoreResult {
+		result, wait, err := fileStore.StoreWithResult(context.Background(), bytes.NewReader(data), int64(len(data)), toEncrypt)
+		if err != nil {
+			t.Fatalf("Store error: %v", err)
+		}
+		wait()
+		return result
+	}
+	// two full intermediate chunks of data chunks under the root
+	size := 2 * 128 * int(DefaultChunkSize)
+	data := datagen.New(t, datagen.Random).Bytes(size)
+	if result := store(data, false); result.Chunks != 259 || result.DedupChunks != 0 {
+		t.Fatalf("expected 259 chunks and none deduplicated, got %+v", result)
+	}
+
+	// changing the last data chunk changes it, its parent and the root
+	modified := append([]byte{}, data...)
+	modified[size-1]++
+	result := store(modified, false)
+	if result.Chunks != 259 || result.DedupChunks != 256 {
+		t.Fatalf("expected 259 chunks of which 256 deduplicated, got %+v", result)
+	}
+	if want := uint64(255*(DefaultChunkSize+8) + 8 + 128*32); result.DedupBytes != want {
+		t.Fatalf("expected %d bytes deduplicated, got %d", want, result.DedupBytes)
+	}
+	reader, _ := fileStore.Retrieve(result.Addr)
+	got := make([]byte, size)
+	if _, err := reader.ReadAt(got, 0); err != io.EOF {
+		t.Fatalf("Retrieve error: %v", err)
+	}
+	if !bytes.Equal(got, modified) {
+		t.Fatal("retrieved data mismatch")
+	}
+
+	// encrypted chunks are never deduplicated
+	store(data, true)
+	if result := store(data, true); result.DedupChunks != 0 {
+		t.Fatalf("expected no encrypted chunks deduplicated, got %d", result.DedupChunks)
+	}
+}
+
+// slowChunkStore is a MapChunkStore taking a while to store each chunk, which
+// records the maximum number of chunks being stored at the same time
+type slowChunkStore struct {
+	*MapChunkStore
+	mu         sync.Mutex
+	pending    int
+	maxPending int
+}
+
+func (s *slowChunkStore) Put(chunk *Chunk) {
+	s.mu.Lock()
+	s.pending++
+	if s.pending > s.maxPending {
+		s.maxPending = s.pending
+	}
+	s.mu.Unlock()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.mu.Lock()
+		s.pending--
+		s.mu.Unlock()
+		s.MapChunkStore.Put(chunk)
+	}()
+}
+
+// TestFileStoreWorkers tests that the chunks being stored are bounded by the
+// number of workers of the FileStore when the chunk store falls behind
+func TestFileStoreWorkers(t *testing.T) {
+	store := &slowChunkStore{MapChunkStore: NewMapChunkStore()}
+	params := NewFileStoreParams()
+	params.Workers = 2
+	fileStore := NewFileStore(store, params)
+
+	size := 4 * 128 * int(DefaultChunkSize)
+	data := datagen.New(t, datagen.Random).Bytes(size)
+	addr, wait, err := fileStore.Store(bytes.NewReader(data), int64(size), false)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wait()
+	if max := params.Workers * pendingChunksPerWorker; store.maxPending > max {
+		t.Fatalf("expected at most %d chunks being stored, got %d", max, store.maxPending)
+	}
+
+	reader, _ := fileStore.Retrieve(addr)
+	got := make([]byte, size)
+	if _, err := reader.ReadAt(got, 0); err != io.EOF {
+		t.Fatalf("Retrieve error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("retrieved data mismatch")
+	}
+}
+
+// TestFileStoreErasure tests that erasure coded content can be retrieved with
+// as many chunks missing per intermediate chunk as it has parity chunks
+func TestFileStoreErasure(t *testing.T) {
+	store := NewMapChunkStore()
+	fileStore := NewFileStore(store, NewFileStoreParams())
+	parity := 8
+
+	// three intermediate chunks under the root
+	size := 300 * int(DefaultChunkSize)
+	data := datagen.New(t, datagen.Random).Bytes(size)
+	addr, wait, err := fileStore.StoreErasure(context.Background(), bytes.NewReader(data), int64(size), false, parity)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wait()
+	if _, _, err := fileStore.StoreErasure(context.Background(), bytes.NewReader(data), int64(size), true, parity); err != errErasureEncrypted {
+		t.Fatalf("expected errErasureEncrypted, got %v", err)
+	}
+
+	retrieve := func() error {
+		reader := fileStore.RetrieveErasure(context.Background(), addr, parity)
+		got := make([]byte, size)
+		if _, err := reader.ReadAt(got, 0); err != io.EOF {
+			return err
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatal("retrieved data mismatch")
+		}
+		return nil
+	}
+	if err := retrieve(); err != nil {
+		t.Fatalf("Retrieve error: %v", err)
+	}
+
+	ref := func(chunk *Chunk, i int) string {
+		return Address(chunk.SData[8+i*KeyLength : 8+(i+1)*KeyLength]).Hex()
+	}
+	root := store.chunks[addr.Hex()]
+	if refs := (len(root.SData) - 8) / KeyLength; refs != 3+parity {
+		t.Fatalf("expected %d references in the root chunk, got %d", 3+parity, refs)
+	}
+	// remove the second intermediate chunk and the first data chunks of the
+	// first one, which both are recovered from parity chunks
+	first := store.chunks[ref(root, 0)]
+	delete(store.chunks, ref(root, 1))
+	for i := 0; i < parity; i++ {
+		delete(store.chunks, ref(first, i))
+	}
+	if err := retrieve(); err != nil {
+		t.Fatalf("Retrieve error with missing chunks: %v", err)
+	}
+
+	// one more missing data chunk is too many
+	delete(store.chunks, ref(first, parity))
+	if err := retrieve(); err == nil {
+		t.Fatal("expected Retrieve error with too many missing chunks")
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Names of the built-in garbage collection policies
+const (
+	GCPolicyLRU       = "lru"
+	GCPolicyLFU       = "lfu"
+	GCPolicyProximity = "proximity"
+)
+
+// DefaultGCPolicy is the name of the garbage collection policy the LDBStore
+// uses unless another one is selected.
+const DefaultGCPolicy = GCPolicyLRU
+
+// GCCandidate describes a chunk the garbage collector may evict. Pinned chunks
+// are never candidates, whatever the policy. Hits is approximate, as repeated
+// accesses of a chunk before the index is written count once.
+type GCCandidate struct {
+	Addr      Address
+	Access    uint64 // value of the access counter at the last access of the chunk
+	Hits      uint64 // number of times the chunk was accessed since it was stored
+	Proximity uint8  // proximity order of the chunk address to the base key
+}
+
+// GCPolicy decides which chunks are evicted when the LDBStore exceeds its
+// capacity. The garbage collector orders the candidates with Less and evicts
+// them from the front.
+type GCPolicy interface {
+	Name() string
+	// Less reports whether a should be evicted before b
+	Less(a, b *GCCandidate) bool
+}
+
+// LRUPolicy evicts the least recently accessed chunks first.
+type LRUPolicy struct{}
+
+func (LRUPolicy) Name() string { return GCPolicyLRU }
+
+func (LRUPolicy) Less(a, b *GCCandidate) bool {
+	return a.Access < b.Access
+}
+
+// LFUPolicy evicts the least frequently accessed chunks first, and the least
+// recently accessed ones among chunks accessed equally often.
+type LFUPolicy struct{}
+
+func (LFUPolicy) Name() string { return GCPolicyLFU }
+
+func (LFUPolicy) Less(a, b *GCCandidate) bool {
+	if a.Hits != b.Hits {
+		return a.Hits < b.Hits
+	}
+	return a.Access < b.Access
+}
+
+// ProximityPolicy evicts the chunks farthest from the base key first, as the
+// node is least responsible for storing them, and the least recently accessed
+// ones among chunks in the same proximity bin.
+type ProximityPolicy struct{}
+
+func (ProximityPolicy) Name() string { return GCPolicyProximity }
+
+func (ProximityPolicy) Less(a, b *GCCandidate) bool {
+	if a.Proximity != b.Proximity {
+		return a.Proximity < b.Proximity
+	}
+	return a.Access < b.Access
+}
+
+var gcPolicies = map[string]GCPolicy{
+	GCPolicyLRU:       LRUPolicy{},
+	GCPolicyLFU:       LFUPolicy{},
+	GCPolicyProximity: ProximityPolicy{},
+}
+
+// GCPolicies returns the names of the built-in garbage collection policies.
+func GCPolicies() []string {
+	return []string{GCPolicyLRU, GCPolicyLFU, GCPolicyProximity}
+}
+
+// NewGCPolicy returns the built-in garbage collection policy with the given
+// name, or the default one if the name is empty.
+func NewGCPolicy(name string) (GCPolicy, error) {
+	if name == "" {
+		name = DefaultGCPolicy
+	}
+	policy, ok := gcPolicies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown garbage collection policy %q (available: %s)", name, strings.Join(GCPolicies(), ", "))
+	}
+	return policy, nil
+}
+
+// gcMetrics records the eviction decisions of a garbage collection policy, so
+// that operators can compare how the policies treat their chunks.
+type gcMetrics struct {
+	evicted   metrics.Counter   // chunks evicted
+	retained  metrics.Counter   // candidates considered but kept
+	pinned    metrics.Counter   // pinned chunks skipped
+	age       metrics.Histogram // accesses since the last access of evicted chunks
+	hits      metrics.Histogram // hits of evicted chunks
+	proximity metrics.Histogram // proximity order of evicted chunks
+}
+
+func newGCMetrics(policy string) *gcMetrics {
+	prefix := "ldbstore.collectgarbage." + policy
+	sample := func() metrics.Sample { return metrics.NewExpDecaySample(1028, 0.015) }
+	return &gcMetrics{
+		evicted:   metrics.GetOrRegisterCounter(prefix+".evicted", nil),
+		retained:  metrics.GetOrRegisterCounter(prefix+".retained", nil),
+		pinned:    metrics.GetOrRegisterCounter(prefix+".pinned", nil),
+		age:       metrics.GetOrRegisterHistogram(prefix+".evicted.age", nil, sample()),
+		hits:      metrics.GetOrRegisterHistogram(prefix+".evicted.hits", nil, sample()),
+		proximity: metrics.GetOrRegisterHistogram(prefix+".evicted.proximity", nil, sample()),
+	}
+}
+
+// evict records the eviction of a candidate, accessCnt being the current value
+// of the access counter
+func (m *gcMetrics) evict(c *GCCandidate, accessCnt uint64) {
+	m.evicted.Inc(1)
+	if accessCnt > c.Access {
+		m.age.Update(int64(accessCnt - c.Access))
+	} else {
+		m.age.Update(0)
+	}
+	m.hits.Update(int64(c.Hits))
+	m.proximity.Update(int64(c.Proximity))
+}
@@ -17,10 +17,14 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/swarm/storage/encryption"
 )
 
@@ -37,6 +41,12 @@ type hasherStore struct {
 	refSize         int64 // reference size (content hash + possibly encryption key)
 	wg              *sync.WaitGroup
 	closed          chan struct{}
+	pending         chan struct{} // bounds the chunks put but not yet stored, unbounded if nil
+
+	dedup      bool   // skip storing unencrypted chunks already in the store
+	chunkCnt   uint64 // number of chunks put
+	dedupCnt   uint64 // number of chunks found in the store
+	dedupBytes uint64 // total length of the chunks found in the store
 }
 
 func newChunkEncryption(chunkSize, refSize int64) *chunkEncryption {
@@ -85,7 +95,17 @@ func (h *hasherStore) Put(chunkData ChunkData) (Reference, error) {
 		}
 	}
 	chunk := h.createChunk(c, size)
-
+	atomic.AddUint64(&h.chunkCnt, 1)
+
+	// Encrypted chunks are never deduplicated, as their addresses depend on
+	// the random encryption key
+	if h.dedup && encryptionKey == nil {
+		if store, ok := h.store.(dedupChunkStore); ok && store.Has(chunk.Addr) {
+			atomic.AddUint64(&h.dedupCnt, 1)
+			atomic.AddUint64(&h.dedupBytes, uint64(len(c)))
+			return Reference(chunk.Addr), nil
+		}
+	}
 	h.storeChunk(chunk)
 
 	return Reference(append(chunk.Addr, encryptionKey...)), nil
@@ -95,13 +115,24 @@ func (h *hasherStore) Put(chunkData ChunkData) (Reference, error) {
 // If the data is encrypted and the reference contains an encryption key, it will be decrypted before
 // return.
 func (h *hasherStore) Get(ref Reference) (ChunkData, error) {
+	return h.GetContext(context.Background(), ref)
+}
+
+// GetContext is Get passing the context of the read to the ChunkStore, if it
+// takes part in the tracing of retrievals
+func (h *hasherStore) GetContext(ctx context.Context, ref Reference) (ChunkData, error) {
 	key, encryptionKey, err := parseReference(ref, h.hashSize)
 	if err != nil {
 		return nil, err
 	}
 	toDecrypt := (encryptionKey != nil)
 
-	chunk, err := h.store.Get(key)
+	var chunk *Chunk
+	if store, ok := h.store.(contextChunkStore); ok {
+		chunk, err = store.GetContext(ctx, key)
+	} else {
+		chunk, err = h.store.Get(key)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +163,7 @@ func (h *hasherStore) Wait() {
 }
 
 func (h *hasherStore) createHash(chunkData ChunkData) Address {
+	defer metrics.GetOrRegisterResettingTimer("chunker.hash.time", nil).UpdateSince(time.Now())
 	hasher := h.hashFunc()
 	hasher.ResetWithLength(chunkData[:8]) // 8 bytes of length
 	hasher.Write(chunkData[8:])           // minus 8 []byte length
@@ -205,10 +237,30 @@ func (h *hasherStore) RefSize() int64 {
 	return h.refSize
 }
 
+// limitPending makes Put block while max chunks put with the hasherStore are
+// not stored yet, so that a chunk store falling behind, e.g. during a LevelDB
+// compaction, slows down the chunker instead of piling up chunks in memory.
+// The chunk store must mark every chunk put as stored.
+func (h *hasherStore) limitPending(max int) {
+	h.pending = make(chan struct{}, max)
+}
+
 func (h *hasherStore) storeChunk(chunk *Chunk) {
+	if h.pending != nil {
+		select {
+		case h.pending <- struct{}{}:
+		default:
+			start := time.Now()
+			h.pending <- struct{}{}
+			metrics.GetOrRegisterResettingTimer("hasherstore.backpressure.time", nil).UpdateSince(start)
+		}
+	}
 	h.wg.Add(1)
 	go func() {
 		<-chunk.dbStoredC
+		if h.pending != nil {
+			<-h.pending
+		}
 		h.wg.Done()
 	}()
 	h.store.Put(chunk)
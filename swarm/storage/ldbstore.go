@@ -32,10 +32,13 @@ import (
 	"io/ioutil"
 	"sort"
 	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/swarm/storage/encryption"
 	"github.com/ethereum/go-ethereum/swarm/storage/mock"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
@@ -54,19 +57,23 @@ var (
 	keyDataIdx     = []byte{4}
 	keyData        = byte(6)
 	keyDistanceCnt = byte(7)
+	keyPin         = byte(8)  // number of pinned trees a chunk belongs to
+	keyPinRoot     = byte(9)  // root chunks of the pinned trees
+	keyUpload      = byte(10) // splitter checkpoints of resumable uploads
 )
 
 type gcItem struct {
-	idx    uint64
-	value  uint64
-	idxKey []byte
-	po     uint8
+	idx       uint64
+	idxKey    []byte
+	candidate *GCCandidate
 }
 
 type LDBStoreParams struct {
 	*StoreParams
-	Path string
-	Po   func(Address) uint8
+	Path     string
+	Backend  string   // name of the chunk store backend, see OpenBackend
+	GCPolicy GCPolicy // chunks evicted first when over capacity, LRU if nil
+	Po       func(Address) uint8
 }
 
 // NewLDBStoreParams constructs LDBStoreParams with the specified values.
@@ -79,7 +86,7 @@ func NewLDBStoreParams(storeparams *StoreParams, path string) *LDBStoreParams {
 }
 
 type LDBStore struct {
-	db *LDBDatabase
+	db ChunkStoreBackend
 
 	// this should be stored in db, accessed transactionally
 	entryCnt  uint64 // number of items in the LevelDB
@@ -88,12 +95,14 @@ type LDBStore struct {
 	capacity  uint64
 	bucketCnt []uint64
 
-	hashfunc SwarmHasher
-	po       func(Address) uint8
+	hashfunc  SwarmHasher
+	po        func(Address) uint8
+	gcPolicy  GCPolicy
+	gcMetrics *gcMetrics
 
 	batchC   chan bool
 	batchesC chan struct{}
-	batch    *leveldb.Batch
+	batch    BackendBatch
 	lock     sync.RWMutex
 
 	// Functions encodeDataFunc is used to bypass
@@ -113,20 +122,25 @@ func NewLDBStore(params *LDBStoreParams) (s *LDBStore, err error) {
 	s = new(LDBStore)
 	s.hashfunc = params.Hash
 
+	s.db, err = OpenBackend(params.Backend, params.Path)
+	if err != nil {
+		return nil, err
+	}
+
 	s.batchC = make(chan bool)
 	s.batchesC = make(chan struct{}, 1)
 	go s.writeBatches()
-	s.batch = new(leveldb.Batch)
+	s.batch = s.db.NewBatch()
 	// associate encodeData with default functionality
 	s.encodeDataFunc = encodeData
 
-	s.db, err = NewLDBDatabase(params.Path)
-	if err != nil {
-		return nil, err
-	}
-
 	s.po = params.Po
 	s.setCapacity(params.DbCapacity)
+	s.gcPolicy = params.GCPolicy
+	if s.gcPolicy == nil {
+		s.gcPolicy = LRUPolicy{}
+	}
+	s.gcMetrics = newGCMetrics(s.gcPolicy.Name())
 
 	s.bucketCnt = make([]uint64, 0x100)
 	for i := 0; i < 0x100; i++ {
@@ -170,6 +184,22 @@ func NewMockDbStore(params *LDBStoreParams, mockStore *mock.NodeStore) (s *LDBSt
 type dpaDBIndex struct {
 	Idx    uint64
 	Access uint64
+	// Hits holds the number of accesses of the chunk as its only element. It
+	// is optional so that indices stored before accesses were counted decode.
+	Hits []uint64 `rlp:"tail"`
+}
+
+// hits returns the number of times the chunk was accessed
+func (index *dpaDBIndex) hits() uint64 {
+	if len(index.Hits) == 0 {
+		return 0
+	}
+	return index.Hits[0]
+}
+
+// hit counts an access of the chunk
+func (index *dpaDBIndex) hit() {
+	index.Hits = []uint64{index.hits() + 1}
 }
 
 func BytesToU64(data []byte) uint64 {
@@ -242,81 +272,126 @@ func decodeOldData(data []byte, chunk *Chunk) {
 	chunk.Size = int64(binary.BigEndian.Uint64(data[0:8]))
 }
 
-func (s *LDBStore) collectGarbage(ratio float32) {
-	metrics.GetOrRegisterCounter("ldbstore.collectgarbage", nil).Inc(1)
+func getPinKey(addr Address) []byte {
+	key := make([]byte, len(addr)+1)
+	key[0] = keyPin
+	copy(key[1:], addr[:])
+	return key
+}
 
-	it := s.db.NewIterator()
-	defer it.Release()
+func getUploadKey(id string) []byte {
+	return append([]byte{keyUpload}, id...)
+}
+
+func getPinRootKey(addr Address) []byte {
+	key := make([]byte, len(addr)+1)
+	key[0] = keyPinRoot
+	copy(key[1:], addr[:])
+	return key
+}
+
+// collectGarbage deletes ratio of the unpinned chunks, picked by the garbage
+// collection policy of the store, returning the number of chunks deleted
+func (s *LDBStore) collectGarbage(ratio float32) int {
+	metrics.GetOrRegisterCounter("ldbstore.collectgarbage", nil).Inc(1)
 
 	garbage := []*gcItem{}
 	gcnt := 0
 
-	for ok := it.Seek([]byte{keyIndex}); ok && (gcnt < maxGCitems) && (uint64(gcnt) < s.entryCnt); ok = it.Next() {
-		itkey := it.Key()
-
-		if (itkey == nil) || (itkey[0] != keyIndex) {
-			break
+	s.db.Iterate([]byte{keyIndex}, func(itkey, val []byte) bool {
+		if (gcnt >= maxGCitems) || (uint64(gcnt) >= s.entryCnt) || (itkey[0] != keyIndex) {
+			return false
 		}
 
-		// it.Key() contents change on next call to it.Next(), so we must copy it
-		key := make([]byte, len(it.Key()))
-		copy(key, it.Key())
-
-		val := it.Value()
+		// the iterated key is only valid during the call, so we must copy it
+		key := make([]byte, len(itkey))
+		copy(key, itkey)
 
 		var index dpaDBIndex
 
 		hash := key[1:]
+		if _, err := s.db.Get(getPinKey(hash)); err == nil {
+			s.gcMetrics.pinned.Inc(1)
+			return true
+		}
 		decodeIndex(val, &index)
-		po := s.po(hash)
 
 		gci := &gcItem{
 			idxKey: key,
 			idx:    index.Idx,
-			value:  index.Access, // the smaller, the more likely to be gc'd. see sort comparator below.
-			po:     po,
+			candidate: &GCCandidate{
+				Addr:      Address(hash),
+				Access:    index.Access,
+				Hits:      index.hits(),
+				Proximity: s.po(hash),
+			},
 		}
 
 		garbage = append(garbage, gci)
 		gcnt++
-	}
+		return true
+	})
 
-	sort.Slice(garbage[:gcnt], func(i, j int) bool { return garbage[i].value < garbage[j].value })
+	// the chunks the policy orders first are gc'd
+	sort.Slice(garbage[:gcnt], func(i, j int) bool {
+		return s.gcPolicy.Less(garbage[i].candidate, garbage[j].candidate)
+	})
 
 	cutoff := int(float32(gcnt) * ratio)
 	metrics.GetOrRegisterCounter("ldbstore.collectgarbage.delete", nil).Inc(int64(cutoff))
+	s.gcMetrics.retained.Inc(int64(gcnt - cutoff))
 
 	for i := 0; i < cutoff; i++ {
-		s.delete(garbage[i].idx, garbage[i].idxKey, garbage[i].po)
+		s.gcMetrics.evict(garbage[i].candidate, s.accessCnt)
+		s.delete(garbage[i].idx, garbage[i].idxKey, garbage[i].candidate.Proximity)
 	}
+	return cutoff
 }
 
 // Export writes all chunks from the store to a tar archive, returning the
 // number of chunks written.
 func (s *LDBStore) Export(out io.Writer) (int64, error) {
+	return s.export(out, false)
+}
+
+// ExportPinned writes the chunks of the pinned chunk trees to a tar archive,
+// returning the number of chunks written. The pins themselves are not part of
+// the archive, the trees must be pinned again once it is imported.
+func (s *LDBStore) ExportPinned(out io.Writer) (int64, error) {
+	return s.export(out, true)
+}
+
+// export writes the chunks of the store, or only the pinned ones if pinned is
+// set, to a tar archive
+func (s *LDBStore) export(out io.Writer, pinned bool) (int64, error) {
 	tw := tar.NewWriter(out)
 	defer tw.Close()
 
-	it := s.db.NewIterator()
-	defer it.Release()
-	var count int64
-	for ok := it.Seek([]byte{keyIndex}); ok; ok = it.Next() {
-		key := it.Key()
-		if (key == nil) || (key[0] != keyIndex) {
-			break
+	var (
+		count    int64
+		writeErr error
+	)
+	err := s.db.Iterate([]byte{keyIndex}, func(key, value []byte) bool {
+		if key[0] != keyIndex {
+			return false
 		}
 
 		var index dpaDBIndex
 
 		hash := key[1:]
-		decodeIndex(it.Value(), &index)
+		if pinned {
+			if _, err := s.db.Get(getPinKey(hash)); err != nil {
+				return true
+			}
+		}
+		decodeIndex(value, &index)
 		po := s.po(hash)
 		datakey := getDataKey(index.Idx, po)
 		log.Trace("store.export", "dkey", fmt.Sprintf("%x", datakey), "dataidx", index.Idx, "po", po)
 		data, err := s.db.Get(datakey)
 		if err != nil {
 			log.Warn(fmt.Sprintf("Chunk %x found but could not be accessed: %v", key[:], err))
-			continue
+			return true
 		}
 
 		hdr := &tar.Header{
@@ -324,16 +399,19 @@ func (s *LDBStore) Export(out io.Writer) (int64, error) {
 			Mode: 0644,
 			Size: int64(len(data)),
 		}
-		if err := tw.WriteHeader(hdr); err != nil {
-			return count, err
+		if writeErr = tw.WriteHeader(hdr); writeErr != nil {
+			return false
 		}
-		if _, err := tw.Write(data); err != nil {
-			return count, err
+		if _, writeErr = tw.Write(data); writeErr != nil {
+			return false
 		}
 		count++
+		return true
+	})
+	if writeErr != nil {
+		return count, writeErr
 	}
-
-	return count, nil
+	return count, err
 }
 
 // of chunks read.
@@ -382,22 +460,16 @@ func (s *LDBStore) Import(in io.Reader) (int64, error) {
 
 func (s *LDBStore) Cleanup() {
 	//Iterates over the database and checks that there are no faulty chunks
-	it := s.db.NewIterator()
-	startPosition := []byte{keyIndex}
-	it.Seek(startPosition)
-	var key []byte
 	var errorsFound, total int
-	for it.Valid() {
-		key = it.Key()
-		if (key == nil) || (key[0] != keyIndex) {
-			break
+	s.db.Iterate([]byte{keyIndex}, func(key, value []byte) bool {
+		if key[0] != keyIndex {
+			return false
 		}
 		total++
 		var index dpaDBIndex
-		err := decodeIndex(it.Value(), &index)
+		err := decodeIndex(value, &index)
 		if err != nil {
-			it.Next()
-			continue
+			return true
 		}
 		data, err := s.db.Get(getDataKey(index.Idx, s.po(Address(key[1:]))))
 		if err != nil {
@@ -413,25 +485,19 @@ func (s *LDBStore) Cleanup() {
 				s.delete(index.Idx, getIndexKey(key[1:]), s.po(Address(key[1:])))
 			}
 		}
-		it.Next()
-	}
-	it.Release()
+		return true
+	})
 	log.Warn(fmt.Sprintf("Found %v errors out of %v entries", errorsFound, total))
 }
 
 func (s *LDBStore) ReIndex() {
 	//Iterates over the database and checks that there are no faulty chunks
-	it := s.db.NewIterator()
-	startPosition := []byte{keyOldData}
-	it.Seek(startPosition)
-	var key []byte
 	var errorsFound, total int
-	for it.Valid() {
-		key = it.Key()
-		if (key == nil) || (key[0] != keyOldData) {
-			break
+	s.db.Iterate([]byte{keyOldData}, func(itkey, data []byte) bool {
+		if itkey[0] != keyOldData {
+			return false
 		}
-		data := it.Value()
+		key := common.CopyBytes(itkey)
 		hasher := s.hashfunc()
 		hasher.Write(data)
 		hash := hasher.Sum(nil)
@@ -448,7 +514,7 @@ func (s *LDBStore) ReIndex() {
 		copy(newKey[2:], key[1:])
 		newValue := append(hash, data...)
 
-		batch := new(leveldb.Batch)
+		batch := s.db.NewBatch()
 		batch.Delete(key)
 		s.bucketCnt[oldCntKey[1]]--
 		batch.Put(oldCntKey, U64ToBytes(s.bucketCnt[oldCntKey[1]]))
@@ -456,16 +522,15 @@ func (s *LDBStore) ReIndex() {
 		s.bucketCnt[newCntKey[1]]++
 		batch.Put(newCntKey, U64ToBytes(s.bucketCnt[newCntKey[1]]))
 		s.db.Write(batch)
-		it.Next()
-	}
-	it.Release()
+		return true
+	})
 	log.Warn(fmt.Sprintf("Found %v errors out of %v entries", errorsFound, total))
 }
 
 func (s *LDBStore) delete(idx uint64, idxKey []byte, po uint8) {
 	metrics.GetOrRegisterCounter("ldbstore.delete", nil).Inc(1)
 
-	batch := new(leveldb.Batch)
+	batch := s.db.NewBatch()
 	batch.Delete(idxKey)
 	batch.Delete(getDataKey(idx, po))
 	s.entryCnt--
@@ -491,6 +556,24 @@ func (s *LDBStore) Size() uint64 {
 	return s.entryCnt
 }
 
+// Capacity returns the maximum number of chunks kept in the store
+func (s *LDBStore) Capacity() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.capacity
+}
+
+// Check returns an error if the database of the store cannot be read, for
+// example because it is closed or corrupted
+func (s *LDBStore) Check() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if _, err := s.db.Get(keyEntryCnt); err != nil && err != ErrKeyNotFound {
+		return err
+	}
+	return nil
+}
+
 func (s *LDBStore) CurrentStorageIndex() uint64 {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -500,6 +583,11 @@ func (s *LDBStore) CurrentStorageIndex() uint64 {
 func (s *LDBStore) Put(chunk *Chunk) {
 	metrics.GetOrRegisterCounter("ldbstore.put", nil).Inc(1)
 	log.Trace("ldbstore.put", "key", chunk.Addr)
+	start := time.Now()
+	outcome := outcomeNew
+	defer func() {
+		updateTimer("ldbstore.put", outcome, start)
+	}()
 
 	ikey := getIndexKey(chunk.Addr)
 	var index dpaDBIndex
@@ -519,7 +607,9 @@ func (s *LDBStore) Put(chunk *Chunk) {
 		}()
 	} else {
 		log.Trace("ldbstore.put: chunk already exists, only update access", "key", chunk.Addr)
+		outcome = outcomeExists
 		decodeIndex(idata, &index)
+		index.hit()
 		chunk.markAsStored()
 	}
 	index.Access = s.accessCnt
@@ -557,7 +647,7 @@ func (s *LDBStore) writeBatches() {
 		a := s.accessCnt
 		c := s.batchC
 		s.batchC = make(chan bool)
-		s.batch = new(leveldb.Batch)
+		s.batch = s.db.NewBatch()
 		err := s.writeBatch(b, e, d, a)
 		// TODO: set this error on the batch, then tell the chunk
 		if err != nil {
@@ -565,7 +655,10 @@ func (s *LDBStore) writeBatches() {
 		}
 		close(c)
 		for e > s.capacity {
-			s.collectGarbage(gcArrayFreeRatio)
+			if s.collectGarbage(gcArrayFreeRatio) == 0 {
+				log.Warn("Pinned chunks exceed the store capacity", "entries", e, "capacity", s.capacity)
+				break
+			}
 			e = s.entryCnt
 		}
 		s.lock.Unlock()
@@ -574,7 +667,7 @@ func (s *LDBStore) writeBatches() {
 }
 
 // must be called non concurrently
-func (s *LDBStore) writeBatch(b *leveldb.Batch, entryCnt, dataIdx, accessCnt uint64) error {
+func (s *LDBStore) writeBatch(b BackendBatch, entryCnt, dataIdx, accessCnt uint64) error {
 	b.Put(keyEntryCnt, U64ToBytes(entryCnt))
 	b.Put(keyDataIdx, U64ToBytes(dataIdx))
 	b.Put(keyAccessCnt, U64ToBytes(accessCnt))
@@ -609,6 +702,7 @@ func (s *LDBStore) tryAccessIdx(ikey []byte, index *dpaDBIndex) bool {
 	s.batch.Put(keyAccessCnt, U64ToBytes(s.accessCnt))
 	s.accessCnt++
 	index.Access = s.accessCnt
+	index.hit()
 	idata = encodeIndex(index)
 	s.batch.Put(ikey, idata)
 	select {
@@ -618,9 +712,23 @@ func (s *LDBStore) tryAccessIdx(ikey []byte, index *dpaDBIndex) bool {
 	return true
 }
 
+// Has reports whether the chunk with the given address is in the store,
+// without counting it as an access
+func (s *LDBStore) Has(addr Address) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, err := s.db.Get(getIndexKey(addr))
+	return err == nil
+}
+
 func (s *LDBStore) Get(addr Address) (chunk *Chunk, err error) {
 	metrics.GetOrRegisterCounter("ldbstore.get", nil).Inc(1)
 	log.Trace("ldbstore.get", "key", addr)
+	start := time.Now()
+	defer func() {
+		updateTimer("ldbstore.get", getOutcome(err), start)
+	}()
 
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -647,6 +755,9 @@ func (s *LDBStore) get(addr Address) (chunk *Chunk, err error) {
 			log.Trace("ldbstore.get retrieve", "key", addr, "indexkey", indx.Idx, "datakey", fmt.Sprintf("%x", datakey), "proximity", proximity)
 			if err != nil {
 				log.Trace("ldbstore.get chunk found but could not be accessed", "key", addr, "err", err)
+				if err == ErrKeyNotFound {
+					err = ErrChunkNotFound
+				}
 				s.delete(indx.Idx, getIndexKey(addr), s.po(addr))
 				return
 			}
@@ -701,9 +812,244 @@ func (s *LDBStore) setCapacity(c uint64) {
 			ratio = 1
 		}
 		for s.entryCnt > c {
-			s.collectGarbage(ratio)
+			if s.collectGarbage(ratio) == 0 {
+				log.Warn("Pinned chunks exceed the store capacity", "entries", s.entryCnt, "capacity", c)
+				break
+			}
+		}
+	}
+}
+
+// PinnedTree is a pinned chunk tree
+type PinnedTree struct {
+	Root   Address `json:"root"` // with the decryption key of an encrypted tree
+	Size   int64   `json:"size"` // size of the data of the tree
+	Parity int     `json:"parity,omitempty"`
+	Count  int     `json:"count"` // number of times the tree is pinned
+}
+
+// encodePinnedTree encodes the parity, count and size of a pinned tree as the
+// value of its root key
+func encodePinnedTree(pin *PinnedTree) []byte {
+	data := make([]byte, 24)
+	binary.BigEndian.PutUint64(data[0:8], uint64(pin.Parity))
+	binary.BigEndian.PutUint64(data[8:16], uint64(pin.Count))
+	binary.BigEndian.PutUint64(data[16:24], uint64(pin.Size))
+	return data
+}
+
+// decodePinnedTree decodes the value of the root key of a pinned tree, pins
+// recorded before trees were counted only hold the parity
+func decodePinnedTree(root Address, data []byte) *PinnedTree {
+	pin := &PinnedTree{
+		Root:   root,
+		Parity: int(BytesToU64(data)),
+		Count:  1,
+	}
+	if len(data) >= 24 {
+		pin.Count = int(binary.BigEndian.Uint64(data[8:16]))
+		pin.Size = int64(binary.BigEndian.Uint64(data[16:24]))
+	}
+	return pin
+}
+
+// Pin excludes all the chunks of the chunk tree with the given root from
+// garbage collection, until the tree is unpinned. The root of an encrypted
+// tree is its reference with the decryption key. All the chunks of the tree
+// must be present in the store. A tree pinned several times stays pinned
+// until it is unpinned as many times, and chunks shared by several pinned
+// trees stay pinned until all of them are unpinned.
+func (s *LDBStore) Pin(root Address) error {
+	return s.PinErasure(root, 0)
+}
+
+// PinErasure is Pin for a chunk tree stored with FileStore.StoreErasure and
+// the given number of parity chunks, which are pinned as well. The number of
+// parity chunks is recorded with the pin so that Unpin finds the same chunks.
+func (s *LDBStore) PinErasure(root Address, parity int) error {
+	metrics.GetOrRegisterCounter("ldbstore.pin", nil).Inc(1)
+
+	switch len(root) {
+	case KeyLength:
+	case KeyLength + encryption.KeyLength:
+		if parity > 0 {
+			return errErasureEncrypted
+		}
+	default:
+		return fmt.Errorf("invalid reference length %d", len(root))
+	}
+	if parity < 0 || int64(parity) >= DefaultChunkSize/int64(len(root)) {
+		return ErrInvalidParity
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rootKey := getPinRootKey(root)
+	if data, err := s.db.Get(rootKey); err == nil {
+		pin := decodePinnedTree(root, data)
+		if pin.Parity != parity {
+			return fmt.Errorf("chunk tree pinned with %d parity chunks", pin.Parity)
+		}
+		pin.Count++
+		return s.db.Put(rootKey, encodePinnedTree(pin))
+	}
+	batch, size, err := s.updatePins(root, parity, 1)
+	if err != nil {
+		return err
+	}
+	batch.Put(rootKey, encodePinnedTree(&PinnedTree{Size: size, Parity: parity, Count: 1}))
+	return s.db.Write(batch)
+}
+
+// Unpin unpins the chunk tree with the given root once, once it is unpinned
+// as many times as it was pinned its chunks are collectable again, unless they
+// are part of other pinned trees.
+func (s *LDBStore) Unpin(root Address) error {
+	metrics.GetOrRegisterCounter("ldbstore.unpin", nil).Inc(1)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rootKey := getPinRootKey(root)
+	data, err := s.db.Get(rootKey)
+	if err != nil {
+		return ErrNotPinned
+	}
+	pin := decodePinnedTree(root, data)
+	if pin.Count > 1 {
+		pin.Count--
+		return s.db.Put(rootKey, encodePinnedTree(pin))
+	}
+	batch, _, err := s.updatePins(root, pin.Parity, -1)
+	if err != nil {
+		return err
+	}
+	batch.Delete(rootKey)
+	return s.db.Write(batch)
+}
+
+// updatePins walks the chunk tree of root and returns a batch changing the pin
+// count of each of its chunks by delta for every occurrence of the chunk in the
+// tree, and the size of the data of the tree. The store lock must be held by
+// the caller.
+func (s *LDBStore) updatePins(root Address, parity int, delta int64) (BackendBatch, int64, error) {
+	counts := make(map[string]int64)
+	size, err := s.walkTree(root, parity, func(addr Address) {
+		counts[string(addr)] += delta
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	batch := s.db.NewBatch()
+	for addr, change := range counts {
+		key := getPinKey(Address(addr))
+		data, _ := s.db.Get(key)
+		if cnt := int64(BytesToU64(data)) + change; cnt > 0 {
+			batch.Put(key, U64ToBytes(uint64(cnt)))
+		} else {
+			batch.Delete(key)
+		}
+	}
+	return batch, size, nil
+}
+
+// walkTree calls f with the address of every chunk of the chunk tree with the
+// given reference, intermediate chunks before their children, and returns the
+// size of the data of the tree. The chunks of an encrypted tree are decrypted
+// with the keys of their references. The intermediate chunks of a tree erasure
+// coded with parity chunks reference them after their children, see
+// erasureSplitter. Parity chunks are leaves, their span bytes are part of the
+// code and meaningless.
+func (s *LDBStore) walkTree(ref Address, parity int, f func(Address)) (int64, error) {
+	data, err := s.walkChunk(ref)
+	if err != nil {
+		return 0, err
+	}
+	f(ref[:KeyLength])
+	size := binary.LittleEndian.Uint64(data[:8])
+	if size <= uint64(DefaultChunkSize) {
+		return int64(size), nil
+	}
+	// find the number of children from the size of their subtrees
+	branches := uint64(DefaultChunkSize/int64(len(ref)) - int64(parity))
+	treeSize := uint64(DefaultChunkSize)
+	for treeSize*branches < size {
+		treeSize *= branches
+	}
+	children := int((size + treeSize - 1) / treeSize)
+	refs := data[8:]
+	for i := 0; (i+1)*len(ref) <= len(refs); i++ {
+		child := Address(refs[i*len(ref) : (i+1)*len(ref)])
+		if i < children {
+			if _, err := s.walkTree(child, parity, f); err != nil {
+				return 0, err
+			}
+			continue
 		}
+		if _, err := s.walkChunk(child); err != nil {
+			return 0, err
+		}
+		f(child)
+	}
+	return int64(size), nil
+}
+
+// walkChunk returns the data of a chunk of a tree walked by walkTree,
+// decrypted if the reference holds a decryption key
+func (s *LDBStore) walkChunk(ref Address) ([]byte, error) {
+	addr := ref[:KeyLength]
+	chunk, err := s.get(addr)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %v: %v", addr.Log(), err)
+	}
+	data := chunk.SData
+	if len(ref) > KeyLength {
+		h := &hasherStore{
+			chunkEncryption: newChunkEncryption(DefaultChunkSize, int64(len(ref))),
+			refSize:         int64(len(ref)),
+		}
+		if data, err = h.decryptChunkData(data, encryption.Key(ref[KeyLength:])); err != nil {
+			return nil, fmt.Errorf("chunk %v: %v", addr.Log(), err)
+		}
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("chunk %v: invalid data length %d", addr.Log(), len(data))
 	}
+	return data, nil
+}
+
+// ListPins returns the pinned chunk trees.
+func (s *LDBStore) ListPins() ([]*PinnedTree, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var pins []*PinnedTree
+	err := s.db.Iterate([]byte{keyPinRoot}, func(key, value []byte) bool {
+		if key[0] != keyPinRoot {
+			return false
+		}
+		pins = append(pins, decodePinnedTree(Address(common.CopyBytes(key[1:])), value))
+		return true
+	})
+	return pins, err
+}
+
+// PutUploadCheckpoint persists the splitter progress of the resumable upload
+// with the given id, replacing its previous checkpoint.
+func (s *LDBStore) PutUploadCheckpoint(id string, data []byte) error {
+	return s.db.Put(getUploadKey(id), data)
+}
+
+// GetUploadCheckpoint returns the last persisted splitter progress of the
+// resumable upload with the given id.
+func (s *LDBStore) GetUploadCheckpoint(id string) ([]byte, error) {
+	return s.db.Get(getUploadKey(id))
+}
+
+// DeleteUploadCheckpoint removes the checkpoint of the resumable upload with
+// the given id once it completed or was abandoned.
+func (s *LDBStore) DeleteUploadCheckpoint(id string) error {
+	return s.db.Delete(getUploadKey(id))
 }
 
 func (s *LDBStore) Close() {
@@ -716,24 +1062,16 @@ func (s *LDBStore) SyncIterator(since uint64, until uint64, po uint8, f func(Add
 
 	sincekey := getDataKey(since, po)
 	untilkey := getDataKey(until, po)
-	it := s.db.NewIterator()
-	defer it.Release()
-
-	for ok := it.Seek(sincekey); ok; ok = it.Next() {
+	return s.db.Iterate(sincekey, func(dbkey, val []byte) bool {
 		metrics.GetOrRegisterCounter("ldbstore.synciterator.seek", nil).Inc(1)
 
-		dbkey := it.Key()
 		if dbkey[0] != keyData || dbkey[1] != po || bytes.Compare(untilkey, dbkey) < 0 {
-			break
+			return false
 		}
 		key := make([]byte, 32)
-		val := it.Value()
 		copy(key, val[:32])
-		if !f(Address(key), binary.BigEndian.Uint64(dbkey[2:])) {
-			break
-		}
-	}
-	return it.Error()
+		return f(Address(key), binary.BigEndian.Uint64(dbkey[2:]))
+	})
 }
 
 func databaseExists(path string) bool {
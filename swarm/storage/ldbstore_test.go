@@ -17,8 +17,12 @@
 package storage
 
 import (
+	"archive/tar"
 	"bytes"
+	"context"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"sync"
@@ -27,7 +31,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/swarm/storage/mock/mem"
+	"github.com/ethereum/go-ethereum/swarm/testutil/datagen"
 
 	ldberrors "github.com/syndtr/goleveldb/leveldb/errors"
 )
@@ -510,3 +517,394 @@ func TestLDBStoreRemoveThenCollectGarbage(t *testing.T) {
 		t.Fatal("expected to get the same data back, but got smth else")
 	}
 }
+
+func TestLDBStoreCheck(t *testing.T) {
+	db, err := newTestDbStore(false, true)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer os.RemoveAll(db.dir)
+
+	if err := db.Check(); err != nil {
+		t.Fatalf("expected an open store to pass the check, got %v", err)
+	}
+	db.Close()
+	if err := db.Check(); err == nil {
+		t.Fatal("expected a closed store to fail the check")
+	}
+}
+
+func TestLDBStoreTimers(t *testing.T) {
+	ldb, cleanup := newLDBStore(t)
+	defer cleanup()
+
+	chunk := GenerateRandomChunk(DefaultChunkSize)
+	if _, err := ldb.Get(chunk.Addr); err != ErrChunkNotFound {
+		t.Fatalf("expected ErrChunkNotFound, got %v", err)
+	}
+	ldb.Put(chunk)
+	if err := chunk.WaitToStore(); err != nil {
+		t.Fatal(err)
+	}
+	ldb.Put(chunk)
+	if _, err := ldb.Get(chunk.Addr); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"ldbstore.get.miss.time", "ldbstore.get.hit.time", "ldbstore.put.new.time", "ldbstore.put.exists.time"} {
+		if metrics.DefaultRegistry.Get(name) == nil {
+			t.Fatalf("expected timer %s to be registered", name)
+		}
+	}
+}
+
+// TestLDBStorePin tests that the chunks of pinned chunk trees survive garbage
+// collection until the trees are unpinned.
+func TestLDBStorePin(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	db.setCapacity(5000)
+	localStore := &LocalStore{
+		memStore: NewMemStore(NewDefaultStoreParams(), db),
+		DbStore:  db,
+	}
+	fileStore := NewFileStore(localStore, NewFileStoreParams())
+
+	size := 200 * DefaultChunkSize
+	reader, slice := generateRandomData(datagen.New(t, datagen.Random), int(size))
+	key, wait, err := fileStore.Store(reader, size, false)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wait()
+	root := Address(key)
+
+	if err := localStore.Pin(root); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+	if err := localStore.Pin(root); err != nil {
+		t.Fatalf("failed to pin again: %v", err)
+	}
+	pins, err := localStore.ListPins()
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+	if len(pins) != 1 || !bytes.Equal(pins[0].Root, root) || pins[0].Count != 2 || pins[0].Size != size {
+		t.Fatalf("pin list mismatch: have %+v, want root %v pinned twice with size %d", pins[0], root, size)
+	}
+	// fill the store way beyond its capacity and check the tree is retained
+	db.setCapacity(50)
+	for i := 0; i < 200; i++ {
+		db.Put(GenerateRandomChunk(DefaultChunkSize))
+	}
+	time.Sleep(time.Second)
+
+	localStore.memStore = NewMemStore(NewDefaultStoreParams(), db)
+	result := make([]byte, len(slice))
+	retrieved, _ := fileStore.Retrieve(key)
+	if _, err := retrieved.ReadAt(result, 0); err != nil && err != io.EOF {
+		t.Fatalf("failed to retrieve pinned content: %v", err)
+	}
+	if !bytes.Equal(result, slice) {
+		t.Fatalf("pinned content mismatch")
+	}
+	// the tree stays pinned until it is unpinned as many times as it was pinned
+	if err := localStore.Unpin(root); err != nil {
+		t.Fatalf("failed to unpin: %v", err)
+	}
+	if pins, _ := localStore.ListPins(); len(pins) != 1 || pins[0].Count != 1 {
+		t.Fatalf("expected tree pinned once after unpin, got %v", pins)
+	}
+	db.setCapacity(1)
+	if _, err := db.Get(root); err != nil {
+		t.Fatalf("root chunk collected while still pinned: %v", err)
+	}
+	// unpin and check the tree is collected
+	if err := localStore.Unpin(root); err != nil {
+		t.Fatalf("failed to unpin: %v", err)
+	}
+	if err := localStore.Unpin(root); err != ErrNotPinned {
+		t.Fatalf("unpin error mismatch: have %v, want %v", err, ErrNotPinned)
+	}
+	if pins, _ := localStore.ListPins(); len(pins) != 0 {
+		t.Fatalf("pin list not empty after unpin: %v", pins)
+	}
+	db.setCapacity(1)
+	if _, err := db.Get(root); err != ErrChunkNotFound {
+		t.Fatalf("unpinned root chunk not collected: %v", err)
+	}
+}
+
+// TestLDBStorePinErasure tests that all the chunks of erasure coded content,
+// including the parity chunks, are pinned and unpinned
+func TestLDBStorePinErasure(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	localStore := &LocalStore{
+		memStore: NewMemStore(NewDefaultStoreParams(), db),
+		DbStore:  db,
+	}
+	fileStore := NewFileStore(localStore, NewFileStoreParams())
+	parity := 8
+
+	size := 300 * DefaultChunkSize
+	data := datagen.New(t, datagen.Random).Bytes(int(size))
+	key, wait, err := fileStore.StoreErasure(context.Background(), bytes.NewReader(data), size, false, parity)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wait()
+	root := Address(key)
+
+	if err := localStore.PinErasure(root, parity); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+	var pinned int
+	db.db.Iterate([]byte{keyPin}, func(key, _ []byte) bool {
+		if key[0] != keyPin {
+			return false
+		}
+		pinned++
+		return true
+	})
+	// 300 leaves, 3 intermediate chunks, the root and their parity chunks
+	if want := 300 + 4 + 4*parity; pinned != want {
+		t.Fatalf("expected %d pinned chunks, got %d", want, pinned)
+	}
+	if err := localStore.Unpin(root); err != nil {
+		t.Fatalf("failed to unpin: %v", err)
+	}
+	pinned = 0
+	db.db.Iterate([]byte{keyPin}, func(key, _ []byte) bool {
+		if key[0] != keyPin {
+			return false
+		}
+		pinned++
+		return true
+	})
+	if pinned != 0 {
+		t.Fatalf("expected no pinned chunks after unpin, got %d", pinned)
+	}
+	if err := localStore.PinErasure(root, int(DefaultChunkSize/KeyLength)); err != ErrInvalidParity {
+		t.Fatalf("expected ErrInvalidParity, got %v", err)
+	}
+}
+
+// TestLDBStorePinEncrypted tests that the chunks of encrypted content are
+// pinned with the reference holding the decryption key
+func TestLDBStorePinEncrypted(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	localStore := &LocalStore{
+		memStore: NewMemStore(NewDefaultStoreParams(), db),
+		DbStore:  db,
+	}
+	fileStore := NewFileStore(localStore, NewFileStoreParams())
+
+	size := 100 * DefaultChunkSize
+	reader, _ := generateRandomData(datagen.New(t, datagen.Random), int(size))
+	key, wait, err := fileStore.Store(reader, size, true)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wait()
+	root := Address(key)
+
+	if err := localStore.Pin(root); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+	var pinned int
+	db.db.Iterate([]byte{keyPin}, func(key, _ []byte) bool {
+		if key[0] != keyPin {
+			return false
+		}
+		pinned++
+		return true
+	})
+	// 100 leaves, 2 intermediate chunks and the root
+	if pinned != 103 {
+		t.Fatalf("expected 103 pinned chunks, got %d", pinned)
+	}
+	pins, err := localStore.ListPins()
+	if err != nil {
+		t.Fatalf("failed to list pins: %v", err)
+	}
+	if len(pins) != 1 || !bytes.Equal(pins[0].Root, root) || pins[0].Size != size {
+		t.Fatalf("pin list mismatch: have %+v, want root %v with size %d", pins[0], root, size)
+	}
+	if err := localStore.PinErasure(root, 1); err != errErasureEncrypted {
+		t.Fatalf("expected errErasureEncrypted, got %v", err)
+	}
+}
+
+// TestLDBStoreExportPinned tests that only the chunks of pinned chunk trees
+// are exported by ExportPinned
+func TestLDBStoreExportPinned(t *testing.T) {
+	tdb, err := newTestDbStore(false, false)
+	if err != nil {
+		t.Fatalf("init dbStore failed: %v", err)
+	}
+	defer tdb.close()
+	db := tdb.LDBStore
+	localStore := &LocalStore{
+		memStore: NewMemStore(NewDefaultStoreParams(), db),
+		DbStore:  db,
+	}
+	fileStore := NewFileStore(localStore, NewFileStoreParams())
+
+	size := 10 * DefaultChunkSize
+	reader, _ := generateRandomData(datagen.New(t, datagen.Random), int(size))
+	key, wait, err := fileStore.Store(reader, size, false)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wait()
+	other := GenerateRandomChunk(DefaultChunkSize)
+	db.Put(other)
+	if err := other.WaitToStore(); err != nil {
+		t.Fatal(err)
+	}
+	if err := localStore.Pin(Address(key)); err != nil {
+		t.Fatalf("failed to pin: %v", err)
+	}
+
+	var pinned []string
+	if _, err := db.walkTree(Address(key), 0, func(addr Address) {
+		pinned = append(pinned, hex.EncodeToString(addr))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	count, err := db.ExportPinned(&buf)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if count != int64(len(pinned)) {
+		t.Fatalf("expected %d exported chunks, got %d", len(pinned), count)
+	}
+	exported := make(map[string]bool)
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		exported[hdr.Name] = true
+	}
+	for _, name := range pinned {
+		if !exported[name] {
+			t.Fatalf("pinned chunk %s not exported", name)
+		}
+	}
+	if exported[hex.EncodeToString(other.Addr)] {
+		t.Fatalf("unpinned chunk %s exported", other.Addr)
+	}
+}
+
+// TestLDBStoreGCPolicy tests that the garbage collection policy of the store
+// picks the chunks to evict
+func TestLDBStoreGCPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy  GCPolicy
+		evicted func(i int) bool
+	}{
+		// the frequently accessed chunks were accessed least recently
+		{LRUPolicy{}, func(i int) bool { return i < 10 }},
+		// the oldest of the chunks accessed once
+		{LFUPolicy{}, func(i int) bool { return i >= 10 && i < 20 }},
+	} {
+		t.Run(test.policy.Name(), func(t *testing.T) {
+			ldb, cleanup := newLDBStore(t)
+			defer cleanup()
+			ldb.gcPolicy = test.policy
+			ldb.gcMetrics = newGCMetrics(test.policy.Name())
+
+			// flush waits for the accesses so far to be written, as accesses
+			// read the index from the database and not the pending batch
+			flush := func() {
+				ldb.lock.RLock()
+				c := ldb.batchC
+				ldb.lock.RUnlock()
+				<-c
+			}
+
+			chunks := make([]*Chunk, 100)
+			for i := range chunks {
+				chunks[i] = GenerateRandomChunk(DefaultChunkSize)
+				ldb.Put(chunks[i])
+			}
+			for i := range chunks {
+				<-chunks[i].dbStoredC
+			}
+			// access the first chunks often, then each of the others once
+			for n := 0; n < 3; n++ {
+				for i := 0; i < 10; i++ {
+					if _, err := ldb.Get(chunks[i].Addr); err != nil {
+						t.Fatal(err)
+					}
+				}
+				flush()
+			}
+			for i := 10; i < len(chunks); i++ {
+				if _, err := ldb.Get(chunks[i].Addr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			flush()
+
+			ldb.lock.Lock()
+			evicted := ldb.collectGarbage(0.1)
+			ldb.lock.Unlock()
+			if evicted != 10 {
+				t.Fatalf("evicted chunk count mismatch: have %d, want 10", evicted)
+			}
+			for i, c := range chunks {
+				_, err := ldb.Get(c.Addr)
+				if test.evicted(i) && err != ErrChunkNotFound {
+					t.Errorf("chunk %d not evicted: %v", i, err)
+				}
+				if !test.evicted(i) && err != nil {
+					t.Errorf("chunk %d evicted: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+// TestLDBStoreIndexHits tests that indices stored before chunk accesses were
+// counted are still decoded
+func TestLDBStoreIndexHits(t *testing.T) {
+	old, err := rlp.EncodeToBytes(struct{ Idx, Access uint64 }{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index dpaDBIndex
+	if err := decodeIndex(old, &index); err != nil {
+		t.Fatal(err)
+	}
+	if index.Idx != 1 || index.Access != 2 || index.hits() != 0 {
+		t.Fatalf("index mismatch: %+v", index)
+	}
+	index.hit()
+	index.hit()
+	if err := decodeIndex(encodeIndex(&index), &index); err != nil {
+		t.Fatal(err)
+	}
+	if index.hits() != 2 {
+		t.Fatalf("hits mismatch: have %d, want 2", index.hits())
+	}
+}
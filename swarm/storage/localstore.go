@@ -30,6 +30,8 @@ import (
 type LocalStoreParams struct {
 	*StoreParams
 	ChunkDbPath string
+	Backend     string           // chunk store backend, the default one if empty
+	GCPolicy    string           // garbage collection policy, see NewGCPolicy
 	Validators  []ChunkValidator `toml:"-"`
 }
 
@@ -59,6 +61,12 @@ type LocalStore struct {
 // This constructor uses MemStore and DbStore as components
 func NewLocalStore(params *LocalStoreParams, mockStore *mock.NodeStore) (*LocalStore, error) {
 	ldbparams := NewLDBStoreParams(params.StoreParams, params.ChunkDbPath)
+	ldbparams.Backend = params.Backend
+	gcPolicy, err := NewGCPolicy(params.GCPolicy)
+	if err != nil {
+		return nil, err
+	}
+	ldbparams.GCPolicy = gcPolicy
 	dbStore, err := NewMockDbStore(ldbparams, mockStore)
 	if err != nil {
 		return nil, err
@@ -72,6 +80,12 @@ func NewLocalStore(params *LocalStoreParams, mockStore *mock.NodeStore) (*LocalS
 
 func NewTestLocalStoreForAddr(params *LocalStoreParams) (*LocalStore, error) {
 	ldbparams := NewLDBStoreParams(params.StoreParams, params.ChunkDbPath)
+	ldbparams.Backend = params.Backend
+	gcPolicy, err := NewGCPolicy(params.GCPolicy)
+	if err != nil {
+		return nil, err
+	}
+	ldbparams.GCPolicy = gcPolicy
 	dbStore, err := NewLDBStore(ldbparams)
 	if err != nil {
 		return nil, err
@@ -221,6 +235,48 @@ func (self *LocalStore) RequestsCacheLen() int {
 	return self.memStore.requests.Len()
 }
 
+// Has reports whether the chunk with the given address is in the local store
+func (self *LocalStore) Has(addr Address) bool {
+	return self.DbStore.Has(addr)
+}
+
+// Pin excludes the chunks of the chunk tree with the given root from garbage
+// collection, see LDBStore.Pin
+func (self *LocalStore) Pin(root Address) error {
+	return self.DbStore.Pin(root)
+}
+
+// PinErasure is Pin for erasure coded content, see LDBStore.PinErasure
+func (self *LocalStore) PinErasure(root Address, parity int) error {
+	return self.DbStore.PinErasure(root, parity)
+}
+
+// Unpin unpins the chunk tree with the given root once, see LDBStore.Unpin
+func (self *LocalStore) Unpin(root Address) error {
+	return self.DbStore.Unpin(root)
+}
+
+// ListPins returns the pinned chunk trees
+func (self *LocalStore) ListPins() ([]*PinnedTree, error) {
+	return self.DbStore.ListPins()
+}
+
+// PutUploadCheckpoint persists the splitter progress of a resumable upload,
+// see LDBStore.PutUploadCheckpoint
+func (self *LocalStore) PutUploadCheckpoint(id string, data []byte) error {
+	return self.DbStore.PutUploadCheckpoint(id, data)
+}
+
+// GetUploadCheckpoint returns the splitter progress of a resumable upload
+func (self *LocalStore) GetUploadCheckpoint(id string) ([]byte, error) {
+	return self.DbStore.GetUploadCheckpoint(id)
+}
+
+// DeleteUploadCheckpoint removes the checkpoint of a resumable upload
+func (self *LocalStore) DeleteUploadCheckpoint(id string) error {
+	return self.DbStore.DeleteUploadCheckpoint(id)
+}
+
 // Close the local store
 func (self *LocalStore) Close() {
 	self.DbStore.Close()
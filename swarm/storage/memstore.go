@@ -20,6 +20,7 @@ package storage
 
 import (
 	"sync"
+	"time"
 
 	lru "github.com/hashicorp/golang-lru"
 )
@@ -68,10 +69,14 @@ func NewMemStore(params *StoreParams, _ *LDBStore) (m *MemStore) {
 	}
 }
 
-func (m *MemStore) Get(addr Address) (*Chunk, error) {
+func (m *MemStore) Get(addr Address) (chunk *Chunk, err error) {
 	if m.disabled {
 		return nil, ErrChunkNotFound
 	}
+	start := time.Now()
+	defer func() {
+		updateTimer("memstore.get", getOutcome(err), start)
+	}()
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
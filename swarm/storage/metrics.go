@@ -0,0 +1,52 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// outcomes of the operations of the stores tagging their latency timers
+const (
+	outcomeHit     = "hit"     // the chunk was found
+	outcomeMiss    = "miss"    // the chunk was not found
+	outcomeError   = "error"   // the operation failed
+	outcomeNew     = "new"     // the chunk was added to the store
+	outcomeExists  = "exists"  // the chunk was already in the store
+	outcomeTimeout = "timeout" // the chunk was not retrieved in time
+)
+
+// getOutcome returns the outcome of a chunk lookup returning err
+func getOutcome(err error) string {
+	switch err {
+	case nil:
+		return outcomeHit
+	case ErrChunkNotFound:
+		return outcomeMiss
+	default:
+		return outcomeError
+	}
+}
+
+// updateTimer records the duration of an operation since start in the
+// latency timer of the operation tagged with its outcome, such as
+// ldbstore.get.miss.time
+func updateTimer(operation, outcome string, start time.Time) {
+	metrics.GetOrRegisterResettingTimer(operation+"."+outcome+".time", nil).UpdateSince(start)
+}
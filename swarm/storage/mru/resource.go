@@ -22,6 +22,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"path/filepath"
 	"sync"
@@ -112,16 +113,18 @@ type LookupParams struct {
 // version of the resource update data.
 type resource struct {
 	*bytes.Reader
-	Multihash  bool
-	name       string
-	nameHash   common.Hash
-	startBlock uint64
-	lastPeriod uint32
-	lastKey    storage.Address
-	frequency  uint64
-	version    uint32
-	data       []byte
-	updated    time.Time
+	Multihash   bool
+	name        string
+	nameHash    common.Hash
+	startBlock  uint64
+	lastPeriod  uint32
+	lastKey     storage.Address
+	frequency   uint64
+	version     uint32
+	data        []byte
+	updated     time.Time
+	authorized  []common.Address // addresses allowed to update, the owner of the name if empty
+	authVersion uint32           // version of the current authorization list
 }
 
 // TODO Expire content after a defined period (to force resync)
@@ -147,15 +150,34 @@ func (self *resource) Name() string {
 func (self *resource) UnmarshalBinary(data []byte) error {
 	self.startBlock = binary.LittleEndian.Uint64(data[:8])
 	self.frequency = binary.LittleEndian.Uint64(data[8:16])
-	self.name = string(data[16:])
+	// a valid name never contains a zero byte, which separates it from the
+	// authorization list
+	name := data[16:]
+	self.authorized = nil
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		authorized, err := decodeAuthorized(name[i+1:])
+		if err != nil {
+			return err
+		}
+		self.authorized = authorized
+		name = name[:i]
+	}
+	self.name = string(name)
 	return nil
 }
 
 func (self *resource) MarshalBinary() ([]byte, error) {
-	b := make([]byte, 16+len(self.name))
+	size := 16 + len(self.name)
+	if len(self.authorized) > 0 {
+		size += 1 + len(self.authorized)*common.AddressLength
+	}
+	b := make([]byte, size)
 	binary.LittleEndian.PutUint64(b, self.startBlock)
 	binary.LittleEndian.PutUint64(b[8:], self.frequency)
 	copy(b[16:], []byte(self.name))
+	if len(self.authorized) > 0 {
+		copy(b[17+len(self.name):], encodeAuthorized(self.authorized))
+	}
 	return b, nil
 }
 
@@ -187,6 +209,11 @@ type ownerValidator interface {
 //
 // (0x0000|startblock|frequency|identifier)
 //
+// If the resource may only be updated by a set of authorized addresses, the
+// identifier is followed by a zero byte and the 20 byte addresses:
+//
+// (0x0000|startblock|frequency|identifier|0x00|address|address|...)
+//
 // (The two first zero-value bytes are used for disambiguation by the chunk validator,
 // and update chunk will always have a value > 0 there.)
 //
@@ -220,6 +247,11 @@ type ownerValidator interface {
 //
 // headerlength is a 16 bit value containing the byte length of period|version|name
 //
+// Changes of the authorization list of a resource are made as updates of
+// period 0, which holds no data updates, with the new list of addresses as
+// data. Each new list must be signed by an address of the previous one, so the
+// list can change without changing the key of the metadata chunk.
+//
 // TODO: Include modtime in chunk data + signature
 type Handler struct {
 	chunkStore      *storage.NetStore
@@ -294,6 +326,12 @@ func (self *Handler) Validate(addr storage.Address, data []byte) bool {
 		log.Error("Invalid resource chunk")
 		return false
 	} else if signature == nil {
+		// updates of resources with an authorization list must be signed, which can only
+		// be checked for resources in the index
+		if rsrc := self.get(ens.EnsNode(name).Hex()); rsrc != nil && len(rsrc.authorized) > 0 {
+			log.Error("Unsigned update of resource with authorization list", "name", name)
+			return false
+		}
 		return bytes.Equal(self.resourceHash(period, version, ens.EnsNode(name)), addr)
 	}
 
@@ -303,7 +341,7 @@ func (self *Handler) Validate(addr storage.Address, data []byte) bool {
 		log.Error("Invalid signature on resource chunk")
 		return false
 	}
-	ok, _ := self.checkAccess(name, addrSig)
+	ok, _ := self.checkAuthorized(name, addrSig)
 	return ok
 }
 
@@ -372,6 +410,14 @@ func (self *Handler) chunkSize() int64 {
 //
 // The start block of the resource update will be the actual current block height of the connected network.
 func (self *Handler) New(ctx context.Context, name string, frequency uint64) (storage.Address, *resource, error) {
+	return self.NewAuthorized(ctx, name, frequency, nil)
+}
+
+// Creates a new root entry for a mutable resource like New, which can only be updated by
+// the given addresses instead of the owner of the name.
+//
+// The authorized addresses can be changed with Authorize.
+func (self *Handler) NewAuthorized(ctx context.Context, name string, frequency uint64, authorized []common.Address) (storage.Address, *resource, error) {
 
 	// frequency 0 is invalid
 	if frequency == 0 {
@@ -403,16 +449,22 @@ func (self *Handler) New(ctx context.Context, name string, frequency uint64) (st
 		}
 	}
 
+	if authorized != nil {
+		if err := checkAuthorizedList(authorized); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// get our blockheight at this time
 	currentblock, err := self.getBlock(ctx, name)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	chunk := self.newMetaChunk(name, currentblock, frequency)
+	chunk := self.newMetaChunk(name, currentblock, frequency, authorized)
 
 	self.chunkStore.Put(chunk)
-	log.Debug("new resource", "name", name, "key", nameHash, "startBlock", currentblock, "frequency", frequency)
+	log.Debug("new resource", "name", name, "key", nameHash, "startBlock", currentblock, "frequency", frequency, "authorized", len(authorized))
 
 	// create the internal index for the resource and populate it with the data of the first version
 	rsrc := &resource{
@@ -421,25 +473,29 @@ func (self *Handler) New(ctx context.Context, name string, frequency uint64) (st
 		name:       name,
 		nameHash:   nameHash,
 		updated:    time.Now(),
+		authorized: authorized,
 	}
 	self.set(nameHash.Hex(), rsrc)
 
 	return chunk.Addr, rsrc, nil
 }
 
-func (self *Handler) newMetaChunk(name string, startBlock uint64, frequency uint64) *storage.Chunk {
+func (self *Handler) newMetaChunk(name string, startBlock uint64, frequency uint64, authorized []common.Address) *storage.Chunk {
 	// the metadata chunk points to data of first blockheight + update frequency
 	// from this we know from what blockheight we should look for updates, and how often
 	// it also contains the name of the resource, so we know what resource we are working with
-	data := make([]byte, metadataChunkOffsetSize+len(name))
+	// and the addresses authorized to update it, if any
+	rsrc := &resource{
+		startBlock: startBlock,
+		frequency:  frequency,
+		name:       name,
+		authorized: authorized,
+	}
+	metadata, _ := rsrc.MarshalBinary()
 
 	// root block has first two bytes both set to 0, which distinguishes from update bytes
-	val := make([]byte, 8)
-	binary.LittleEndian.PutUint64(val, startBlock)
-	copy(data[2:10], val)
-	binary.LittleEndian.PutUint64(val, frequency)
-	copy(data[10:18], val)
-	copy(data[18:], []byte(name))
+	data := make([]byte, 2+len(metadata))
+	copy(data[2:], metadata)
 
 	// the key of the metadata chunk is content-addressed
 	// if it wasn't we couldn't replace it later
@@ -452,8 +508,7 @@ func (self *Handler) newMetaChunk(name string, startBlock uint64, frequency uint
 
 	// make the chunk and send it to swarm
 	chunk := storage.NewChunk(key, nil)
-	chunk.SData = make([]byte, metadataChunkOffsetSize+len(name))
-	copy(chunk.SData, data)
+	chunk.SData = data
 	return chunk
 }
 
@@ -559,6 +614,106 @@ func (self *Handler) LookupPrevious(ctx context.Context, nameHash common.Hash, m
 	return self.lookup(rsrc, rsrc.lastPeriod, rsrc.version, false, maxLookup)
 }
 
+// Update is a historical version of a mutable resource, as returned by LookupHistory
+type Update struct {
+	Period    uint32
+	Version   uint32
+	Key       storage.Address
+	Data      []byte
+	Multihash bool
+}
+
+// Retrieves all versions of the resource update identified by `name` made in the periods
+// from fromPeriod to toPeriod, in ascending order
+//
+// A fromPeriod of 0 starts at the first period, a toPeriod of 0 ends at the next update
+// period after the current block height. If limit is larger than 0, at most limit periods
+// holding updates are returned, with all their versions, and the next page starts at the
+// period after the last returned one.
+//
+// Versions not made by an authorized address are skipped, as in the lookups of single
+// versions. The resource index is not changed.
+func (self *Handler) LookupHistory(ctx context.Context, name string, fromPeriod uint32, toPeriod uint32, limit int) ([]*Update, error) {
+
+	// we can't look for anything without a store
+	if self.chunkStore == nil {
+		return nil, NewError(ErrInit, "Call Handler.SetStore() before performing lookups")
+	}
+
+	rsrc := self.get(ens.EnsNode(name).Hex())
+	if rsrc == nil {
+		return nil, NewError(ErrNothingToReturn, "resource not loaded")
+	}
+
+	// period 0 does not exist
+	if fromPeriod == 0 {
+		fromPeriod = 1
+	}
+	if toPeriod == 0 {
+		currentblock, err := self.getBlock(ctx, rsrc.name)
+		if err != nil {
+			return nil, err
+		}
+		toPeriod, err = getNextPeriod(rsrc.startBlock, currentblock, rsrc.frequency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fromPeriod > toPeriod {
+		return nil, NewError(ErrInvalidValue, fmt.Sprintf("First period %d is after last period %d", fromPeriod, toPeriod))
+	}
+	if self.queryMaxPeriods.Limit && toPeriod-fromPeriod > self.queryMaxPeriods.Max {
+		return nil, NewError(ErrPeriodDepth, fmt.Sprintf("History exceeded max period hops (%d)", self.queryMaxPeriods.Max))
+	}
+
+	var updates []*Update
+	var periods int
+	for period := fromPeriod; period <= toPeriod; period++ {
+		if limit > 0 && periods == limit {
+			break
+		}
+		found := false
+		for version := uint32(1); ; version++ {
+			select {
+			case <-ctx.Done():
+				return nil, NewError(ErrIO, fmt.Sprintf("History lookup interrupted: %v", ctx.Err()))
+			default:
+			}
+			key := self.resourceHash(period, version, rsrc.nameHash)
+			chunk, err := self.chunkStore.GetWithTimeout(key, defaultRetrieveTimeout)
+			if err != nil {
+				break
+			}
+			if !self.isAuthorizedUpdate(rsrc, chunk) {
+				log.Trace("unauthorized resource update skipped", "name", rsrc.name, "period", period, "version", version, "key", key)
+				continue
+			}
+			_, _, _, updatename, data, multihash, err := self.parseUpdate(chunk.SData)
+			if err != nil || updatename != rsrc.name {
+				log.Trace("invalid resource update skipped", "name", rsrc.name, "period", period, "version", version, "key", key)
+				continue
+			}
+			updates = append(updates, &Update{
+				Period:    period,
+				Version:   version,
+				Key:       key,
+				Data:      data,
+				Multihash: multihash,
+			})
+			found = true
+		}
+		if found {
+			periods++
+		}
+		// the last period would overflow the loop counter
+		if period == math.MaxUint32 {
+			break
+		}
+	}
+	log.Trace("resource history", "name", rsrc.name, "from", fromPeriod, "to", toPeriod, "limit", limit, "updates", len(updates))
+	return updates, nil
+}
+
 // base code for public lookup methods
 func (self *Handler) lookup(rsrc *resource, period uint32, version uint32, refresh bool, maxLookup *LookupParams) (*resource, error) {
 
@@ -597,18 +752,28 @@ func (self *Handler) lookup(rsrc *resource, period uint32, version uint32, refre
 				return self.updateIndex(rsrc, chunk)
 			}
 			// check if we have versions > 1. If a version fails, the previous version is used and returned.
+			// versions not made by an authorized address are skipped
 			log.Trace("rsrc update version 1 found, checking for version updates", "period", period, "key", key)
+			var found *storage.Chunk
+			if self.isAuthorizedUpdate(rsrc, chunk) {
+				found = chunk
+			}
 			for {
 				newversion := version + 1
 				key := self.resourceHash(period, newversion, rsrc.nameHash)
 				newchunk, err := self.chunkStore.GetWithTimeout(key, defaultRetrieveTimeout)
 				if err != nil {
-					return self.updateIndex(rsrc, chunk)
+					break
+				}
+				if self.isAuthorizedUpdate(rsrc, newchunk) {
+					found = newchunk
 				}
-				chunk = newchunk
 				version = newversion
 				log.Trace("version update found, checking next", "version", version, "period", period, "key", key)
 			}
+			if found != nil {
+				return self.updateIndex(rsrc, found)
+			}
 		}
 		log.Trace("rsrc update not found, checking previous period", "period", period, "key", key)
 		period--
@@ -635,10 +800,15 @@ func (self *Handler) Load(addr storage.Address) (*resource, error) {
 
 	// create the index entry
 	rsrc := &resource{}
-	rsrc.UnmarshalBinary(chunk.SData[2:])
+	if err := rsrc.UnmarshalBinary(chunk.SData[2:]); err != nil {
+		return nil, NewError(ErrCorruptData, fmt.Sprintf("Invalid metadata chunk: %v", err))
+	}
 	rsrc.nameHash = ens.EnsNode(rsrc.name)
+	if len(rsrc.authorized) > 0 {
+		self.loadAuthorization(rsrc)
+	}
 	self.set(rsrc.nameHash.Hex(), rsrc)
-	log.Trace("resource index load", "rootkey", addr, "name", rsrc.name, "namehash", rsrc.nameHash, "startblock", rsrc.startBlock, "frequency", rsrc.frequency)
+	log.Trace("resource index load", "rootkey", addr, "name", rsrc.name, "namehash", rsrc.nameHash, "startblock", rsrc.startBlock, "frequency", rsrc.frequency, "authorized", len(rsrc.authorized), "authversion", rsrc.authVersion)
 	return rsrc, nil
 }
 
@@ -654,12 +824,18 @@ func (self *Handler) updateIndex(rsrc *resource, chunk *storage.Chunk) (*resourc
 
 	// check signature (if signer algorithm is present)
 	// \TODO maybe this check is redundant if also checked upon retrieval of chunk
-	if signature != nil {
+	if signature == nil && len(rsrc.authorized) > 0 {
+		return nil, NewError(ErrUnauthorized, fmt.Sprintf("Unsigned update of %s, which has an authorization list", rsrc.name))
+	} else if signature != nil {
+		var addr common.Address
 		digest := self.keyDataHash(chunk.Addr, data)
-		_, err = getAddressFromDataSig(digest, *signature)
+		addr, err = getAddressFromDataSig(digest, *signature)
 		if err != nil {
 			return nil, NewError(ErrUnauthorized, fmt.Sprintf("Invalid signature: %v", err))
 		}
+		if len(rsrc.authorized) > 0 && !rsrc.isAuthorized(addr) {
+			return nil, NewError(ErrUnauthorized, fmt.Sprintf("Address %x is not authorized to update %s", addr, rsrc.name))
+		}
 	}
 
 	// update our rsrcs entry map
@@ -753,15 +929,13 @@ func (self *Handler) parseUpdate(chunkdata []byte) (*Signature, uint32, uint32,
 	data = make([]byte, intdatalength)
 	copy(data, chunkdata[cursor:cursor+intdatalength])
 
-	// omit signatures if we have no validator
+	// the signature is present if the chunk data continues after the update data,
+	// it is parsed without a signer too so that authorization lists can be checked
 	var signature *Signature
 	cursor += intdatalength
-	if self.signer != nil {
-		sigdata := chunkdata[cursor : cursor+signatureLength]
-		if len(sigdata) > 0 {
-			signature = &Signature{}
-			copy(signature[:], sigdata)
-		}
+	if len(chunkdata) >= cursor+signatureLength {
+		signature = &Signature{}
+		copy(signature[:], chunkdata[cursor:cursor+signatureLength])
 	}
 
 	return signature, period, version, name, data, multihash, nil
@@ -861,7 +1035,7 @@ func (self *Handler) update(ctx context.Context, name string, data []byte, multi
 		}
 		if self.signer != nil {
 			// check if the signer has access to update
-			ok, err := self.checkAccess(name, addr)
+			ok, err := self.checkAuthorized(name, addr)
 			if err != nil {
 				return nil, NewError(ErrIO, fmt.Sprintf("Access check fail: %v", err))
 			} else if !ok {
@@ -0,0 +1,208 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package mru
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/ens"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// authorization lists are stored as updates of period 0, which holds no data updates
+const authorizationPeriod = 0
+
+// MaxAuthorized is the maximum number of addresses authorized to update a resource,
+// so that the authorization list fits in the metadata chunk and in an update chunk
+const MaxAuthorized = 128
+
+// Checks if address is in the authorization list of the resource
+func (self *resource) isAuthorized(address common.Address) bool {
+	for _, addr := range self.authorized {
+		if addr == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorized returns the addresses authorized to update the resource identified by `name`,
+// or nil if updates are authorized by the owner of the name.
+func (self *Handler) Authorized(name string) ([]common.Address, error) {
+	rsrc := self.get(ens.EnsNode(name).Hex())
+	if rsrc == nil {
+		return nil, NewError(ErrNotFound, fmt.Sprintf(" object '%s' not in index", name))
+	} else if len(rsrc.authorized) == 0 {
+		return nil, nil
+	}
+	authorized := make([]common.Address, len(rsrc.authorized))
+	copy(authorized, rsrc.authorized)
+	return authorized, nil
+}
+
+// Authorize replaces the addresses authorized to update the resource identified by `name`.
+//
+// The resource must have been created with an authorization list by NewAuthorized, and
+// the new list must be signed by an address of the current one. Addresses can thus be added
+// and revoked by any authorized address without changing the key of the metadata chunk.
+//
+// Like Update, it uses the authorization list currently loaded in the resources map entry.
+func (self *Handler) Authorize(ctx context.Context, name string, authorized []common.Address) (storage.Address, error) {
+
+	// we can't update anything without a store
+	if self.chunkStore == nil {
+		return nil, NewError(ErrInit, "Call Handler.SetStore() before updating")
+	}
+
+	// only a signer can prove it is authorized
+	if self.signer == nil {
+		return nil, NewError(ErrUnauthorized, "Authorization lists can only be changed with a signer")
+	}
+
+	if err := checkAuthorizedList(authorized); err != nil {
+		return nil, err
+	}
+
+	nameHash := ens.EnsNode(name)
+	rsrc := self.get(nameHash.Hex())
+	if rsrc == nil {
+		return nil, NewError(ErrNotFound, fmt.Sprintf(" object '%s' not in index", name))
+	} else if len(rsrc.authorized) == 0 {
+		return nil, NewError(ErrInvalidValue, fmt.Sprintf("Resource %s has no authorization list", name))
+	}
+
+	// the list is stored as the data of the next version of period 0
+	version := rsrc.authVersion + 1
+	key := self.resourceHash(authorizationPeriod, version, nameHash)
+	data := encodeAuthorized(authorized)
+	digest := self.keyDataHash(key, data)
+	signature, err := self.signer.Sign(digest)
+	if err != nil {
+		return nil, NewError(ErrInvalidSignature, fmt.Sprintf("Sign fail: %v", err))
+	}
+	addr, err := getAddressFromDataSig(digest, signature)
+	if err != nil {
+		return nil, NewError(ErrInvalidSignature, fmt.Sprintf("Invalid data/signature: %v", err))
+	}
+	if !rsrc.isAuthorized(addr) {
+		return nil, NewError(ErrUnauthorized, fmt.Sprintf("Address %x is not authorized to update %s", addr, name))
+	}
+
+	chunk := newUpdateChunk(key, &signature, authorizationPeriod, version, name, data, len(data))
+	self.chunkStore.Put(chunk)
+	log.Debug("resource authorization", "name", name, "key", key, "version", version, "authorized", len(authorized))
+
+	rsrc.authorized = authorized
+	rsrc.authVersion = version
+	return key, nil
+}
+
+// Checks if address can update the resource identified by `name`
+//
+// If the resource is loaded and has an authorization list, the address must be in the list,
+// otherwise it must be the owner of the name
+func (self *Handler) checkAuthorized(name string, address common.Address) (bool, error) {
+	rsrc := self.get(ens.EnsNode(name).Hex())
+	if rsrc == nil || len(rsrc.authorized) == 0 {
+		return self.checkAccess(name, address)
+	}
+	return rsrc.isAuthorized(address), nil
+}
+
+// Checks if the signer of an update chunk is in the authorization list of the resource
+//
+// Updates of resources with an authorization list must be signed, others are accepted
+func (self *Handler) isAuthorizedUpdate(rsrc *resource, chunk *storage.Chunk) bool {
+	if len(rsrc.authorized) == 0 {
+		return true
+	}
+	signature, _, _, _, data, _, err := self.parseUpdate(chunk.SData)
+	if err != nil || signature == nil {
+		return false
+	}
+	addr, err := getAddressFromDataSig(self.keyDataHash(chunk.Addr, data), *signature)
+	if err != nil {
+		return false
+	}
+	return rsrc.isAuthorized(addr)
+}
+
+// Retrieves the changes of the authorization list of a resource made after its creation
+//
+// Versions of period 0 are iterated until one is not found or not signed by an address
+// of the previous authorization list.
+func (self *Handler) loadAuthorization(rsrc *resource) {
+	for {
+		version := rsrc.authVersion + 1
+		key := self.resourceHash(authorizationPeriod, version, rsrc.nameHash)
+		chunk, err := self.chunkStore.GetWithTimeout(key, defaultRetrieveTimeout)
+		if err != nil {
+			return
+		}
+		if !self.isAuthorizedUpdate(rsrc, chunk) {
+			log.Warn("unauthorized resource authorization list", "name", rsrc.name, "key", key, "version", version)
+			return
+		}
+		_, period, _, name, data, _, err := self.parseUpdate(chunk.SData)
+		if err != nil || period != authorizationPeriod || name != rsrc.name {
+			log.Warn("invalid resource authorization list", "name", rsrc.name, "key", key, "version", version)
+			return
+		}
+		authorized, err := decodeAuthorized(data)
+		if err != nil || len(authorized) == 0 {
+			log.Warn("invalid resource authorization list", "name", rsrc.name, "key", key, "version", version, "err", err)
+			return
+		}
+		log.Trace("resource authorization list found", "name", rsrc.name, "key", key, "version", version, "authorized", len(authorized))
+		rsrc.authorized = authorized
+		rsrc.authVersion = version
+	}
+}
+
+// Checks that an authorization list is not empty and fits in a chunk
+func checkAuthorizedList(authorized []common.Address) error {
+	if len(authorized) == 0 {
+		return NewError(ErrInvalidValue, "Authorization list cannot be empty")
+	} else if len(authorized) > MaxAuthorized {
+		return NewError(ErrDataOverflow, fmt.Sprintf("Authorization list overflow: %d / %d addresses", len(authorized), MaxAuthorized))
+	}
+	return nil
+}
+
+// Serializes an authorization list as the concatenation of its addresses
+func encodeAuthorized(authorized []common.Address) []byte {
+	data := make([]byte, 0, len(authorized)*common.AddressLength)
+	for _, addr := range authorized {
+		data = append(data, addr[:]...)
+	}
+	return data
+}
+
+// Parses an authorization list serialized by encodeAuthorized
+func decodeAuthorized(data []byte) ([]common.Address, error) {
+	if len(data)%common.AddressLength != 0 {
+		return nil, fmt.Errorf("authorization list length %d is not a multiple of %d", len(data), common.AddressLength)
+	}
+	authorized := make([]common.Address, len(data)/common.AddressLength)
+	for i := range authorized {
+		authorized[i] = common.BytesToAddress(data[i*common.AddressLength : (i+1)*common.AddressLength])
+	}
+	return authorized, nil
+}
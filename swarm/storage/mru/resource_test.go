@@ -316,6 +316,45 @@ func TestHandler(t *testing.T) {
 		t.Fatalf("expeected previous to fail, returned period %d version %d data %v", rsrc2.lastPeriod, rsrc2.version, rsrc2.data)
 	}
 
+	// all updates in order
+	history, err := rh2.LookupHistory(ctx, safeName, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(updates) {
+		t.Fatalf("history has %d updates, expected %d", len(history), len(updates))
+	}
+	for i, update := range history {
+		if !bytes.Equal(update.Data, []byte(updates[i])) {
+			t.Fatalf("resource data (history) was %v, expected %v", update.Data, updates[i])
+		}
+		if !bytes.Equal(update.Key, resourcekey[updates[i]]) {
+			t.Fatalf("resource key (history) was %v, expected %v", update.Key, resourcekey[updates[i]])
+		}
+	}
+
+	// a page of the first two periods
+	history, err = rh2.LookupHistory(ctx, safeName, 1, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Period != 1 || history[1].Period != 2 {
+		t.Fatalf("expected updates of periods 1 and 2, got %d updates", len(history))
+	}
+
+	// all versions of a single period
+	history, err = rh2.LookupHistory(ctx, safeName, 3, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Version != 1 || history[1].Version != 2 {
+		t.Fatalf("expected versions 1 and 2 of period 3, got %d updates", len(history))
+	}
+
+	// periods are given in ascending order
+	if _, err = rh2.LookupHistory(ctx, safeName, 3, 2, 0); err == nil {
+		t.Fatal("expected history lookup with first period after last period to fail")
+	}
 }
 
 // create ENS enabled resource update, with and without valid owner
@@ -377,6 +416,133 @@ func TestENSOwner(t *testing.T) {
 	}
 }
 
+// make updates with the keys of an authorization list, and change the list
+func TestAuthorization(t *testing.T) {
+
+	// signers containing private keys
+	var signers []*GenericSigner
+	var addrs []common.Address
+	for i := 0; i < 3; i++ {
+		signer, err := newTestSigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+		addrs = append(addrs, crypto.PubkeyToAddress(signer.PrivKey.PublicKey))
+	}
+
+	// make fake backend, set up rpc and create resourcehandler
+	backend := &fakeBackend{
+		blocknumber: int64(startBlock),
+	}
+	rh, _, teardownTest, err := setupTest(backend, nil, signers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardownTest()
+
+	// create a new resource which the first two signers can update
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rootChunkKey, _, err := rh.NewAuthorized(ctx, safeName, resourceFrequency, addrs[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := func(signer int, data string) error {
+		rh.signer = signers[signer]
+		_, err := rh.Update(ctx, safeName, []byte(data))
+		return err
+	}
+	if err := update(0, "foo"); err != nil {
+		t.Fatalf("Update by authorized signer fail: %v", err)
+	}
+	if err := update(1, "bar"); err != nil {
+		t.Fatalf("Update by authorized signer fail: %v", err)
+	}
+	if err := update(2, "baz"); err == nil {
+		t.Fatal("Expected update by unauthorized signer to fail")
+	}
+
+	// the first signer cannot change the list after the second one revokes it
+	rh.signer = signers[1]
+	if _, err := rh.Authorize(ctx, safeName, addrs[1:]); err != nil {
+		t.Fatalf("Authorize fail: %v", err)
+	}
+	rh.signer = signers[0]
+	if _, err := rh.Authorize(ctx, safeName, addrs[:1]); err == nil {
+		t.Fatal("Expected authorization by revoked signer to fail")
+	}
+	if err := update(0, "foo"); err == nil {
+		t.Fatal("Expected update by revoked signer to fail")
+	}
+	if err := update(2, "baz"); err != nil {
+		t.Fatalf("Update by authorized signer fail: %v", err)
+	}
+
+	// the changed list is loaded with the unchanged metadata chunk
+	rsrc, err := rh.Load(rootChunkKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsrc.authVersion != 1 {
+		t.Fatalf("Expected authorization list version 1, got %d", rsrc.authVersion)
+	}
+	authorized, err := rh.Authorized(safeName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authorized) != 2 || authorized[0] != addrs[1] || authorized[1] != addrs[2] {
+		t.Fatalf("Expected authorized addresses %x, got %x", addrs[1:], authorized)
+	}
+	rsrc, err = rh.LookupLatest(ctx, nameHash, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rsrc.data, []byte("baz")) {
+		t.Fatalf("Expected latest update data 'baz', got '%s'", rsrc.data)
+	}
+
+	// an unsigned authorization list and data update are rejected by a node which loaded the resource
+	listData := encodeAuthorized(addrs[:1])
+	listChunk := newUpdateChunk(rh.resourceHash(authorizationPeriod, 2, nameHash), nil, authorizationPeriod, 2, safeName, listData, len(listData))
+	if rh.Validate(listChunk.Addr, listChunk.SData) {
+		t.Fatal("Expected validation of unsigned authorization list to fail")
+	}
+	period := rsrc.lastPeriod + 1
+	data := []byte("qux")
+	updateChunk := newUpdateChunk(rh.resourceHash(period, 1, nameHash), nil, period, 1, safeName, data, len(data))
+	if rh.Validate(updateChunk.Addr, updateChunk.SData) {
+		t.Fatal("Expected validation of unsigned update to fail")
+	}
+
+	// and ignored if they were stored by a node which did not
+	rh.resourceLock.Lock()
+	delete(rh.resources, nameHash.Hex())
+	rh.resourceLock.Unlock()
+	rh.chunkStore.Put(listChunk)
+	rh.chunkStore.Put(updateChunk)
+	if err := listChunk.GetErrored(); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateChunk.GetErrored(); err != nil {
+		t.Fatal(err)
+	}
+	rsrc, err = rh.Load(rootChunkKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsrc.authVersion != 1 || len(rsrc.authorized) != 2 || rsrc.authorized[0] != addrs[1] {
+		t.Fatalf("Expected authorization list version 1 of %x, got version %d of %x", addrs[1:], rsrc.authVersion, rsrc.authorized)
+	}
+	history, err := rh.LookupHistory(ctx, safeName, period, period, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("Expected unsigned update to be ignored, got %d updates", len(history))
+	}
+}
+
 func TestMultihash(t *testing.T) {
 
 	// signer containing private key
@@ -568,7 +734,7 @@ func TestChunkValidator(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	chunk = rh.newMetaChunk(safeName, startBlock, resourceFrequency)
+	chunk = rh.newMetaChunk(safeName, startBlock, resourceFrequency, nil)
 	if !rh.Validate(chunk.Addr, chunk.SData) {
 		t.Fatal("Chunk validator fail on metadata chunk")
 	}
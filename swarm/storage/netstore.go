@@ -17,9 +17,11 @@
 package storage
 
 import (
+	"context"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
 )
 
 var (
@@ -43,10 +45,12 @@ var (
 // access by calling network is blocking with a timeout
 type NetStore struct {
 	localStore *LocalStore
-	retrieve   func(chunk *Chunk) error
+	retrieve   func(ctx context.Context, chunk *Chunk) error
 }
 
-func NewNetStore(localStore *LocalStore, retrieve func(chunk *Chunk) error) *NetStore {
+// NewNetStore creates a NetStore requesting the chunks missing from
+// localStore with retrieve, which is called with the context of the request
+func NewNetStore(localStore *LocalStore, retrieve func(ctx context.Context, chunk *Chunk) error) *NetStore {
 	return &NetStore{localStore, retrieve}
 }
 
@@ -57,6 +61,16 @@ func NewNetStore(localStore *LocalStore, retrieve func(chunk *Chunk) error) *Net
 // ErrChunkNotFound is returned by get, until the netStoreRetryTimeout
 // is reached.
 func (self *NetStore) Get(addr Address) (chunk *Chunk, err error) {
+	return self.GetContext(context.Background(), addr)
+}
+
+// GetContext is Get passing the context of the request to the network
+// retrieval, so that it is traced as a part of the request
+func (self *NetStore) GetContext(ctx context.Context, addr Address) (chunk *Chunk, err error) {
+	ctx, sp := tracing.StartSpan(ctx, "netstore.get")
+	defer sp.Finish()
+	sp.SetTag("addr", addr.Hex())
+
 	timer := time.NewTimer(netStoreRetryTimeout)
 	defer timer.Stop()
 
@@ -84,7 +98,7 @@ func (self *NetStore) Get(addr Address) (chunk *Chunk, err error) {
 		defer limiter.Stop()
 
 		for {
-			chunk, err := self.get(addr, 0)
+			chunk, err := self.get(ctx, addr, 0)
 			if err != ErrChunkNotFound {
 				// break retry only if the error is nil
 				// or other error then ErrChunkNotFound
@@ -117,19 +131,21 @@ func (self *NetStore) Get(addr Address) (chunk *Chunk, err error) {
 	case r := <-resultC:
 		return r.chunk, r.err
 	case <-timer.C:
+		sp.SetError(ErrChunkNotFound)
 		return nil, ErrChunkNotFound
 	}
 }
 
 // GetWithTimeout makes a single retrieval attempt for a chunk with a explicit timeout parameter
 func (self *NetStore) GetWithTimeout(addr Address, timeout time.Duration) (chunk *Chunk, err error) {
-	return self.get(addr, timeout)
+	return self.get(context.Background(), addr, timeout)
 }
 
-func (self *NetStore) get(addr Address, timeout time.Duration) (chunk *Chunk, err error) {
+func (self *NetStore) get(ctx context.Context, addr Address, timeout time.Duration) (chunk *Chunk, err error) {
 	if timeout == 0 {
 		timeout = searchTimeout
 	}
+	start := time.Now()
 	if self.retrieve == nil {
 		chunk, err = self.localStore.Get(addr)
 		if err == nil {
@@ -147,10 +163,11 @@ func (self *NetStore) get(addr Address, timeout time.Duration) (chunk *Chunk, er
 		}
 
 		if created {
-			err := self.retrieve(chunk)
+			err := self.retrieve(ctx, chunk)
 			if err != nil {
 				// mark chunk request as failed so that we can retry it later
 				chunk.SetErrored(ErrChunkUnavailable)
+				updateTimer("netstore.fetch", outcomeError, start)
 				return nil, err
 			}
 		}
@@ -163,10 +180,12 @@ func (self *NetStore) get(addr Address, timeout time.Duration) (chunk *Chunk, er
 	case <-t.C:
 		// mark chunk request as failed so that we can retry
 		chunk.SetErrored(ErrChunkNotFound)
+		updateTimer("netstore.fetch", outcomeTimeout, start)
 		return nil, ErrChunkNotFound
 	case <-chunk.ReqC:
 	}
 	chunk.SetErrored(nil)
+	updateTimer("netstore.fetch", outcomeHit, start)
 	return chunk, nil
 }
 
@@ -175,6 +194,27 @@ func (self *NetStore) Put(chunk *Chunk) {
 	self.localStore.Put(chunk)
 }
 
+// Has reports whether the chunk with the given address is in the local store
+func (self *NetStore) Has(addr Address) bool {
+	return self.localStore.Has(addr)
+}
+
+// PutUploadCheckpoint persists the splitter progress of a resumable upload
+// in the local store
+func (self *NetStore) PutUploadCheckpoint(id string, data []byte) error {
+	return self.localStore.PutUploadCheckpoint(id, data)
+}
+
+// GetUploadCheckpoint returns the splitter progress of a resumable upload
+func (self *NetStore) GetUploadCheckpoint(id string) ([]byte, error) {
+	return self.localStore.GetUploadCheckpoint(id)
+}
+
+// DeleteUploadCheckpoint removes the checkpoint of a resumable upload
+func (self *NetStore) DeleteUploadCheckpoint(id string) error {
+	return self.localStore.DeleteUploadCheckpoint(id)
+}
+
 // Close chunk store
 func (self *NetStore) Close() {
 	self.localStore.Close()
@@ -17,11 +17,13 @@
 package storage
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"io"
 	"io/ioutil"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -65,8 +67,8 @@ var (
 )
 
 const (
-	ChunkProcessors = 8
-	splitTimeout    = time.Minute * 5
+	ChunkProcessors = 8               // default maximum number of chunk processors of a splitter
+	splitTimeout    = time.Minute * 5 // maximum time a splitter may not make progress
 )
 
 const (
@@ -138,6 +140,7 @@ type chunkJob struct {
 }
 
 type PyramidChunker struct {
+	processed   uint64 // number of chunks processed, accessed atomically
 	chunkSize   int64
 	hashSize    int64
 	branches    int64
@@ -146,6 +149,7 @@ type PyramidChunker struct {
 	getter      Getter
 	key         Address
 	workerCount int64
+	maxWorkers  int64
 	workerLock  sync.RWMutex
 	jobC        chan *chunkJob
 	wg          *sync.WaitGroup
@@ -165,7 +169,8 @@ func NewPyramidSplitter(params *PyramidSplitterParams) (self *PyramidChunker) {
 	self.getter = params.getter
 	self.key = params.addr
 	self.workerCount = 0
-	self.jobC = make(chan *chunkJob, 2*ChunkProcessors)
+	self.maxWorkers = params.maxWorkers()
+	self.jobC = make(chan *chunkJob, 2*self.maxWorkers)
 	self.wg = &sync.WaitGroup{}
 	self.errC = make(chan error)
 	self.quitC = make(chan bool)
@@ -182,6 +187,7 @@ func (self *PyramidChunker) Join(addr Address, getter Getter, depth int) LazySec
 		branches:  self.branches,
 		hashSize:  self.hashSize,
 		getter:    getter,
+		ctx:       context.Background(),
 	}
 }
 
@@ -224,12 +230,8 @@ func (self *PyramidChunker) Split() (k Address, wait func(), err error) {
 	defer close(self.quitC)
 	defer self.putter.Close()
 
-	select {
-	case err := <-self.errC:
-		if err != nil {
-			return nil, nil, err
-		}
-	case <-time.NewTimer(splitTimeout).C:
+	if err := waitSplit(self.errC, &self.processed); err != nil {
+		return nil, nil, err
 	}
 	return self.rootKey, self.putter.Wait, nil
 
@@ -255,14 +257,9 @@ func (self *PyramidChunker) Append() (k Address, wait func(), err error) {
 	defer close(self.quitC)
 	defer self.putter.Close()
 
-	select {
-	case err := <-self.errC:
-		if err != nil {
-			return nil, nil, err
-		}
-	case <-time.NewTimer(splitTimeout).C:
+	if err := waitSplit(self.errC, &self.processed); err != nil {
+		return nil, nil, err
 	}
-
 	return self.rootKey, self.putter.Wait, nil
 
 }
@@ -288,11 +285,15 @@ func (self *PyramidChunker) processChunk(id int64, job *chunkJob) {
 
 	ref, err := self.putter.Put(job.chunk)
 	if err != nil {
-		self.errC <- err
+		select {
+		case self.errC <- err:
+		case <-self.quitC:
+		}
 	}
 
 	// report hash of this chunk one level up (keys corresponds to the proper subslice of the parent chunk)
 	copy(job.key, ref)
+	atomic.AddUint64(&self.processed, 1)
 
 	// send off new chunk to storage
 	job.parentWg.Done()
@@ -516,7 +517,7 @@ func (self *PyramidChunker) prepareChunks(isAppend bool) {
 		}
 
 		workers := self.getWorkerCount()
-		if int64(len(self.jobC)) > workers && workers < ChunkProcessors {
+		if int64(len(self.jobC)) > workers && workers < self.maxWorkers {
 			self.incrementWorkerCount()
 			go self.processor(self.workerCount)
 		}
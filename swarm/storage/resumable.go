@@ -0,0 +1,272 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// resumableCheckpointInterval is the number of data chunks stored between two
+// checkpoints of a resumable upload, i.e. at most this many chunks are split
+// again after an interruption.
+const resumableCheckpointInterval = 128
+
+var (
+	errResumableUnsupported = errors.New("chunk store does not support resumable uploads")
+	errResumableMismatch    = errors.New("content does not match the upload checkpoint")
+)
+
+// checkpointStore is a ChunkStore able to persist the progress of resumable
+// uploads, such as the LDBStore and the stores built on top of it.
+type checkpointStore interface {
+	Has(Address) bool
+	PutUploadCheckpoint(id string, data []byte) error
+	GetUploadCheckpoint(id string) ([]byte, error)
+	DeleteUploadCheckpoint(id string) error
+}
+
+// uploadCheckpoint is the persisted progress of a resumable upload. All the
+// chunks it references are stored. Encrypted uploads are not checkpointed,
+// since their references include the decryption keys.
+type uploadCheckpoint struct {
+	Size   uint64
+	Offset uint64        // number of bytes of the content already split
+	Prefix []byte        // keccak256 hash of the first Offset bytes of the content
+	Refs   [][]Reference // completed children of the open intermediate chunks, from the root down
+}
+
+/*
+resumableSplitter builds the same chunk tree as the TreeChunker, but it splits
+the content sequentially, so that its progress can be described by the offset
+in the content and the references of the completed subtrees along the path to
+it. This progress is checkpointed to the store periodically, and a splitter
+created with the checkpoint of an interrupted upload continues where the
+previous one left off, provided that the content it was given starts with
+the same bytes and the chunks referenced by the checkpoint are still stored.
+*/
+type resumableSplitter struct {
+	ctx      context.Context
+	id       string
+	store    checkpointStore
+	data     io.Reader
+	putter   Putter
+	mkPutter func() Putter
+	branches int64
+	hashSize int64
+	encrypt  bool
+	cp       *uploadCheckpoint
+	prefix   hash.Hash // hash of the content split so far
+	pending  int       // data chunks stored since the last checkpoint
+}
+
+func newResumableSplitter(ctx context.Context, id string, store checkpointStore, data io.Reader, size int64, toEncrypt bool, mkPutter func() Putter) *resumableSplitter {
+	putter := mkPutter()
+	s := &resumableSplitter{
+		ctx:      ctx,
+		id:       id,
+		store:    store,
+		data:     data,
+		putter:   putter,
+		mkPutter: mkPutter,
+		hashSize: putter.RefSize(),
+		branches: DefaultChunkSize / putter.RefSize(),
+		encrypt:  toEncrypt,
+		cp:       &uploadCheckpoint{Size: uint64(size)},
+		prefix:   sha3.NewKeccak256(),
+	}
+	if toEncrypt {
+		return s
+	}
+	// Continue from the last checkpoint of the same upload if there is one
+	blob, err := store.GetUploadCheckpoint(id)
+	if err != nil {
+		return s
+	}
+	var cp uploadCheckpoint
+	if err := rlp.DecodeBytes(blob, &cp); err != nil {
+		log.Warn("Discarding invalid upload checkpoint", "id", id, "err", err)
+		return s
+	}
+	if cp.Size != s.cp.Size || cp.Offset > cp.Size {
+		log.Warn("Discarding checkpoint of a different upload", "id", id, "size", cp.Size, "offset", cp.Offset)
+		return s
+	}
+	// the chunks of the completed subtrees might have been garbage collected
+	// since the checkpoint was taken
+	for _, refs := range cp.Refs {
+		for _, ref := range refs {
+			if !store.Has(Address(ref)) {
+				log.Warn("Discarding checkpoint referencing a missing chunk", "id", id, "ref", Address(ref))
+				return s
+			}
+		}
+	}
+	log.Debug("Resuming upload", "id", id, "offset", cp.Offset, "size", cp.Size)
+	s.cp = &cp
+	return s
+}
+
+// Split stores the chunks of the content not covered by the checkpoint and
+// returns the root reference once all the chunks are stored. The checkpoint
+// is removed when the upload completes.
+func (s *resumableSplitter) Split() (Reference, error) {
+	if err := s.skip(); err != nil {
+		return nil, err
+	}
+	depth := 0
+	treeSize := DefaultChunkSize
+	for ; treeSize < int64(s.cp.Size); treeSize *= s.branches {
+		depth++
+	}
+	ref, err := s.split(depth, treeSize/s.branches, int64(s.cp.Size), 0)
+	s.putter.Close()
+	if err != nil {
+		return nil, err
+	}
+	s.putter.Wait()
+	if err := s.store.DeleteUploadCheckpoint(s.id); err != nil {
+		log.Warn("Failed to delete upload checkpoint", "id", s.id, "err", err)
+	}
+	return ref, nil
+}
+
+// skip advances the content past the bytes split before the checkpoint,
+// checking that they are the bytes the checkpoint was taken with. If they are
+// not, the checkpoint is discarded and the upload starts over, which requires
+// the content to be seekable.
+func (s *resumableSplitter) skip() error {
+	if s.cp.Offset == 0 {
+		return nil
+	}
+	seeker, seekable := s.data.(io.Seeker)
+	var start int64
+	if seekable {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+	if _, err := io.CopyN(s.prefix, s.data, int64(s.cp.Offset)); err != nil {
+		return err
+	}
+	if bytes.Equal(s.prefix.Sum(nil), s.cp.Prefix) {
+		return nil
+	}
+	log.Warn("Discarding checkpoint of different content", "id", s.id, "offset", s.cp.Offset)
+	if err := s.store.DeleteUploadCheckpoint(s.id); err != nil {
+		log.Warn("Failed to delete upload checkpoint", "id", s.id, "err", err)
+	}
+	if !seekable {
+		return errResumableMismatch
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	s.cp = &uploadCheckpoint{Size: s.cp.Size}
+	s.prefix.Reset()
+	return nil
+}
+
+// split mirrors TreeChunker.split, skipping the subtrees recorded as completed
+// in the checkpoint. level is the index of the chunk among the open
+// intermediate chunks.
+func (s *resumableSplitter) split(depth int, treeSize int64, size int64, level int) (Reference, error) {
+	for depth > 0 && size < treeSize {
+		treeSize /= s.branches
+		depth--
+	}
+	if depth == 0 {
+		return s.splitData(size)
+	}
+	// open the intermediate chunk, unless it was already open when the
+	// checkpoint was taken
+	if level == len(s.cp.Refs) {
+		s.cp.Refs = append(s.cp.Refs, nil)
+	}
+	branchCnt := (size + treeSize - 1) / treeSize
+	for i := int64(len(s.cp.Refs[level])); i < branchCnt; i++ {
+		// the last item can have shorter data
+		secSize := treeSize
+		if i == branchCnt-1 {
+			secSize = size - i*treeSize
+		}
+		ref, err := s.split(depth-1, treeSize/s.branches, secSize, level+1)
+		if err != nil {
+			return nil, err
+		}
+		s.cp.Refs[level] = append(s.cp.Refs[level], ref)
+		if err := s.checkpoint(); err != nil {
+			return nil, err
+		}
+	}
+	chunk := make([]byte, 8, 8+branchCnt*s.hashSize)
+	binary.LittleEndian.PutUint64(chunk, uint64(size))
+	for _, ref := range s.cp.Refs[level] {
+		chunk = append(chunk, ref...)
+	}
+	s.cp.Refs = s.cp.Refs[:level]
+	return s.putter.Put(chunk)
+}
+
+// splitData reads and stores the next data chunk of the content.
+func (s *resumableSplitter) splitData(size int64) (Reference, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	default:
+	}
+	chunk := make([]byte, size+8)
+	binary.LittleEndian.PutUint64(chunk[:8], uint64(size))
+	if _, err := io.ReadFull(s.data, chunk[8:]); err != nil {
+		return nil, err
+	}
+	s.prefix.Write(chunk[8:])
+	s.cp.Offset += uint64(size)
+	s.pending++
+	return s.putter.Put(chunk)
+}
+
+// checkpoint persists the progress of the upload once enough data chunks were
+// stored since the last checkpoint. It waits for the chunks put so far to be
+// stored, so that the checkpoint never references missing chunks.
+func (s *resumableSplitter) checkpoint() error {
+	if s.encrypt || s.pending < resumableCheckpointInterval {
+		return nil
+	}
+	s.putter.Close()
+	s.putter.Wait()
+	s.cp.Prefix = s.prefix.Sum(nil)
+	blob, err := rlp.EncodeToBytes(s.cp)
+	if err != nil {
+		return err
+	}
+	if err := s.store.PutUploadCheckpoint(s.id, blob); err != nil {
+		return err
+	}
+	s.putter = s.mkPutter()
+	s.pending = 0
+	return nil
+}
@@ -18,6 +18,7 @@ package storage
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/rand"
 	"encoding/binary"
@@ -312,6 +313,24 @@ type Getter interface {
 	Get(Reference) (ChunkData, error)
 }
 
+// contextGetter is a Getter passing the context of a read on, so that the
+// chunk retrievals of the read are traced
+type contextGetter interface {
+	GetContext(context.Context, Reference) (ChunkData, error)
+}
+
+// dedupChunkStore is a ChunkStore which can tell whether it already holds a
+// chunk, so that storing it again can be skipped
+type dedupChunkStore interface {
+	Has(Address) bool
+}
+
+// contextChunkStore is a ChunkStore whose retrievals are traced as a part
+// of the request in the given context
+type contextChunkStore interface {
+	GetContext(context.Context, Address) (*Chunk, error)
+}
+
 // NOTE: this returns invalid data if chunk is encrypted
 func (c ChunkData) Size() int64 {
 	return int64(binary.LittleEndian.Uint64(c[:8]))
@@ -0,0 +1,205 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/swarm/storage/encryption"
+)
+
+// verifyParallelism is the number of chunks retrieved concurrently by Verify
+const verifyParallelism = 16
+
+// MissingChunk is a chunk of a chunk tree which could not be retrieved, the
+// data under the chunk starts at Offset and is Length bytes long, the length
+// of the data under a missing root chunk is unknown
+type MissingChunk struct {
+	Address Address `json:"address"`
+	Depth   int     `json:"depth"` // 0 for the root chunk
+	Offset  int64   `json:"offset"`
+	Length  int64   `json:"length,omitempty"`
+	Parity  bool    `json:"parity,omitempty"` // parity chunk of the intermediate chunk of the subtree at Offset
+	Error   string  `json:"error"`
+}
+
+// VerifyReport is the result of the reachability check of a chunk tree
+type VerifyReport struct {
+	Size    int64           `json:"size"`   // size of the data, 0 if the root chunk is missing
+	Chunks  int             `json:"chunks"` // number of chunks retrieved
+	Missing []*MissingChunk `json:"missing,omitempty"`
+}
+
+// Verify retrieves every chunk of the chunk tree of ref and reports the
+// chunks which cannot be retrieved, the subtrees of missing chunks are not
+// visited. If local is true and the chunks are stored in a NetStore, only
+// its local store is queried and nothing is retrieved from the network.
+func (f *FileStore) Verify(ref Reference, local bool) (*VerifyReport, error) {
+	return f.VerifyErasure(ref, local, 0)
+}
+
+// VerifyErasure is Verify for content stored with StoreErasure and the given
+// number of parity chunks, the parity chunks of the intermediate chunks are
+// retrieved as well.
+func (f *FileStore) VerifyErasure(ref Reference, local bool, parity int) (*VerifyReport, error) {
+	store := f.ChunkStore
+	if netStore, ok := store.(*NetStore); ok && local {
+		store = netStore.localStore
+	}
+	hashSize := f.hashFunc().Size()
+	refSize := hashSize
+	switch len(ref) {
+	case hashSize:
+	case hashSize + encryption.KeyLength:
+		refSize += encryption.KeyLength
+	default:
+		return nil, fmt.Errorf("invalid reference length %d", len(ref))
+	}
+	if parity > 0 && refSize != hashSize {
+		return nil, errErasureEncrypted
+	}
+	if parity < 0 || int64(parity) >= DefaultChunkSize/int64(refSize) {
+		return nil, ErrInvalidParity
+	}
+
+	v := &treeVerifier{
+		getter:   NewHasherStore(store, f.hashFunc, refSize != hashSize),
+		parity:   int64(parity),
+		branches: DefaultChunkSize/int64(refSize) - int64(parity),
+		hashSize: hashSize,
+		refSize:  refSize,
+		sem:      make(chan struct{}, verifyParallelism),
+	}
+	v.wg.Add(1)
+	v.sem <- struct{}{}
+	go v.verify(ref, 0, 0, 0, false)
+	v.wg.Wait()
+
+	missing := v.report.Missing
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Offset != missing[j].Offset {
+			return missing[i].Offset < missing[j].Offset
+		}
+		if missing[i].Depth != missing[j].Depth {
+			return missing[i].Depth < missing[j].Depth
+		}
+		return !missing[i].Parity && missing[j].Parity
+	})
+	return &v.report, nil
+}
+
+type treeVerifier struct {
+	getter   Getter
+	parity   int64 // parity chunks per intermediate chunk
+	branches int64 // children per intermediate chunk
+	hashSize int
+	refSize  int
+
+	sem    chan struct{} // limits the number of concurrent retrievals
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	report VerifyReport
+}
+
+// verify checks the subtree of the chunk with reference ref which covers
+// length bytes of data from offset, or only the chunk if it is a parity
+// chunk. It is started with a slot of the semaphore acquired, so that the
+// goroutines waiting for a retrieval are limited as well as the retrievals,
+// and releases it once the chunk is retrieved.
+func (v *treeVerifier) verify(ref Reference, depth int, offset, length int64, parity bool) {
+	defer v.wg.Done()
+
+	data, err := v.getter.Get(ref)
+	<-v.sem
+
+	v.mu.Lock()
+	if err == nil && len(data) < 8 {
+		err = fmt.Errorf("invalid chunk data length %d", len(data))
+	}
+	if err != nil {
+		v.report.Missing = append(v.report.Missing, &MissingChunk{
+			Address: Address(ref[:v.hashSize]),
+			Depth:   depth,
+			Offset:  offset,
+			Length:  length,
+			Parity:  parity,
+			Error:   err.Error(),
+		})
+		v.mu.Unlock()
+		return
+	}
+	v.report.Chunks++
+	if parity {
+		v.mu.Unlock()
+		return
+	}
+	size := data.Size()
+	if depth == 0 {
+		v.report.Size = size
+	}
+	v.mu.Unlock()
+	if size <= DefaultChunkSize {
+		return
+	}
+
+	// find the size of the data under each child of this intermediate chunk
+	treeSize := DefaultChunkSize
+	for treeSize*v.branches < size {
+		treeSize *= v.branches
+	}
+	refs := data.Data()
+	children := (size + treeSize - 1) / treeSize
+	for i := int64(0); i < children; i++ {
+		if int64(len(refs)) < (i+1)*int64(v.refSize) {
+			v.mu.Lock()
+			v.report.Missing = append(v.report.Missing, &MissingChunk{
+				Depth:  depth + 1,
+				Offset: offset + i*treeSize,
+				Length: size - i*treeSize,
+				Error:  "reference missing from intermediate chunk",
+			})
+			v.mu.Unlock()
+			return
+		}
+		childLength := treeSize
+		if size-i*treeSize < treeSize {
+			childLength = size - i*treeSize
+		}
+		v.wg.Add(1)
+		v.sem <- struct{}{}
+		go v.verify(Reference(refs[i*int64(v.refSize):(i+1)*int64(v.refSize)]), depth+1, offset+i*treeSize, childLength, false)
+	}
+	// the parity chunks follow the children
+	for i := children; i < children+v.parity; i++ {
+		if int64(len(refs)) < (i+1)*int64(v.refSize) {
+			v.mu.Lock()
+			v.report.Missing = append(v.report.Missing, &MissingChunk{
+				Depth:  depth + 1,
+				Offset: offset,
+				Parity: true,
+				Error:  "parity reference missing from intermediate chunk",
+			})
+			v.mu.Unlock()
+			return
+		}
+		v.wg.Add(1)
+		v.sem <- struct{}{}
+		go v.verify(Reference(refs[i*int64(v.refSize):(i+1)*int64(v.refSize)]), depth+1, offset, 0, true)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/testutil/datagen"
+)
+
+func TestFileStoreVerify(t *testing.T) {
+	testFileStoreVerify(false, t)
+	testFileStoreVerify(true, t)
+}
+
+func testFileStoreVerify(toEncrypt bool, t *testing.T) {
+	// the root chunk has a full first subtree and a second one with 2 leaves
+	size := DefaultChunkSize*DefaultChunkSize/32 + 5000
+	store := NewMapChunkStore()
+	fileStore := NewFileStore(store, NewFileStoreParams())
+	reader, _ := generateRandomData(datagen.New(t, datagen.Random), int(size))
+	addr, wait, err := fileStore.Store(reader, size, toEncrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	total := len(store.chunks)
+
+	report, err := fileStore.Verify(Reference(addr), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Size != size || report.Chunks != total || len(report.Missing) != 0 {
+		t.Fatalf("expected %d bytes in %d chunks with none missing, got %+v", size, total, report)
+	}
+	if toEncrypt {
+		return
+	}
+
+	// remove the root of the second subtree
+	root, err := store.Get(addr[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := Address(root.SData[8+32 : 8+64])
+	delete(store.chunks, second.Hex())
+	report, err = fileStore.Verify(Reference(addr), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Chunks != total-3 || len(report.Missing) != 1 {
+		t.Fatalf("expected %d chunks and 1 missing, got %+v", total-3, report)
+	}
+	missing := report.Missing[0]
+	if !missing.Address.isEqual(second) || missing.Depth != 1 || missing.Offset != size-5000 || missing.Length != 5000 {
+		t.Fatalf("unexpected missing chunk %+v", missing)
+	}
+
+	// remove the root chunk
+	delete(store.chunks, addr.Hex())
+	report, err = fileStore.Verify(Reference(addr), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Chunks != 0 || len(report.Missing) != 1 || report.Missing[0].Depth != 0 {
+		t.Fatalf("expected the root chunk to be missing, got %+v", report)
+	}
+
+	if _, err := fileStore.Verify(Reference(addr[:10]), false); err == nil {
+		t.Fatal("expected error for invalid reference")
+	}
+}
+
+// TestFileStoreVerifyErasure tests that all the children and parity chunks of
+// the intermediate chunks of erasure coded content are verified
+func TestFileStoreVerifyErasure(t *testing.T) {
+	store := NewMapChunkStore()
+	fileStore := NewFileStore(store, NewFileStoreParams())
+	parity := 8
+
+	// two full intermediate chunks under the root and a third one with 10
+	// leaves, each of them and the root with 8 parity chunks
+	size := 250 * DefaultChunkSize
+	data := datagen.New(t, datagen.Random).Bytes(int(size))
+	addr, wait, err := fileStore.StoreErasure(context.Background(), bytes.NewReader(data), size, false, parity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	total := len(store.chunks)
+	if total != 250+4+4*parity {
+		t.Fatalf("expected %d chunks, got %d", 250+4+4*parity, total)
+	}
+
+	report, err := fileStore.VerifyErasure(Reference(addr), false, parity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Size != size || report.Chunks != total || len(report.Missing) != 0 {
+		t.Fatalf("expected %d bytes in %d chunks with none missing, got %+v", size, total, report)
+	}
+
+	// remove the last parity chunk of the third intermediate chunk
+	ref := func(chunk *Chunk, i int) Address {
+		return Address(chunk.SData[8+i*KeyLength : 8+(i+1)*KeyLength])
+	}
+	third := store.chunks[ref(store.chunks[addr.Hex()], 2).Hex()]
+	removed := ref(third, 10+parity-1)
+	delete(store.chunks, removed.Hex())
+	report, err = fileStore.VerifyErasure(Reference(addr), false, parity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Chunks != total-1 || len(report.Missing) != 1 {
+		t.Fatalf("expected %d chunks and 1 missing, got %+v", total-1, report)
+	}
+	missing := report.Missing[0]
+	if !missing.Address.isEqual(removed) || !missing.Parity || missing.Depth != 2 || missing.Offset != 240*DefaultChunkSize {
+		t.Fatalf("unexpected missing chunk %+v", missing)
+	}
+
+	if _, err := fileStore.VerifyErasure(Reference(addr), false, int(DefaultChunkSize/KeyLength)); err != ErrInvalidParity {
+		t.Fatalf("expected ErrInvalidParity, got %v", err)
+	}
+}
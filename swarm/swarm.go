@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/contracts/chequebook"
 	"github.com/ethereum/go-ethereum/contracts/ens"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -40,12 +41,14 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/protocols"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/swarm/admin"
 	"github.com/ethereum/go-ethereum/swarm/api"
 	httpapi "github.com/ethereum/go-ethereum/swarm/api/http"
 	"github.com/ethereum/go-ethereum/swarm/fuse"
 	"github.com/ethereum/go-ethereum/swarm/network"
 	"github.com/ethereum/go-ethereum/swarm/network/stream"
 	"github.com/ethereum/go-ethereum/swarm/pss"
+	"github.com/ethereum/go-ethereum/swarm/services/swap"
 	"github.com/ethereum/go-ethereum/swarm/state"
 	"github.com/ethereum/go-ethereum/swarm/storage"
 	"github.com/ethereum/go-ethereum/swarm/storage/mock"
@@ -59,6 +62,10 @@ var (
 	stopCounter        = metrics.NewRegisteredCounter("stack,stop", nil)
 	uptimeGauge        = metrics.NewRegisteredGauge("stack.uptime", nil)
 	requestsCacheGauge = metrics.NewRegisteredGauge("storage.cache.requests.size", nil)
+
+	// retrievals not answered within this period count as failed
+	// for partition detection
+	retrieveWatchTimeout = 10 * time.Second
 )
 
 // the swarm stack
@@ -76,6 +83,14 @@ type Swarm struct {
 	lstore      *storage.LocalStore // local store, needs to store for releasing resources after node stopped
 	sfs         *fuse.SwarmFS       // need this to cleanup all the active mounts on node exit
 	ps          *pss.Pss
+	bootnodes   *network.Bootnodes // remote bootnode list, nil if not configured
+	partition   *network.PartitionWatchdog
+	topics      *network.TopicDiscovery // discv5 topic advertisement and search, nil if discv5 is disabled
+	prices      swap.PriceOracle
+	cashier     *swap.Cashier   // cashes received cheques, nil if SWAP is disabled
+	gateway     *httpapi.Server // HTTP gateway, nil if disabled
+	kad         *network.Kademlia
+	ensClients  map[string]*ensClient // ENS API clients by endpoint
 }
 
 type SwarmAPI struct {
@@ -97,6 +112,17 @@ func (self *Swarm) API() *SwarmAPI {
 // If mockStore is not nil, it will be used as the storage for chunk data.
 // MockStore should be used only for testing.
 func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err error) {
+	return newSwarm(config, mockStore, nil)
+}
+
+// NewEmbeddedSwarm creates a swarm service running inside an Ethereum node.
+// Besides the configured ENS API endpoints, ENS names are resolved through the
+// given RPC client of the hosting node, against the configured ENS root.
+func NewEmbeddedSwarm(config *api.Config, ensBackend *rpc.Client) (*Swarm, error) {
+	return newSwarm(config, nil, ensBackend)
+}
+
+func newSwarm(config *api.Config, mockStore *mock.NodeStore, ensBackend *rpc.Client) (self *Swarm, err error) {
 
 	if bytes.Equal(common.FromHex(config.PublicKey), storage.ZeroAddr) {
 		return nil, fmt.Errorf("empty public key")
@@ -139,6 +165,22 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 		OverlayAddr:  addr.OAddr,
 		UnderlayAddr: addr.UAddr,
 		HiveParams:   config.HiveParams,
+		Compression:  config.Compression,
+	}
+
+	if config.BootnodesURL != "" {
+		pubkey := crypto.ToECDSAPub(common.FromHex(config.BootnodesPubKey))
+		if pubkey == nil || pubkey.X == nil {
+			return nil, fmt.Errorf("invalid bootnode list public key: %q", config.BootnodesPubKey)
+		}
+		self.bootnodes, err = network.NewBootnodes(&network.BootnodesParams{
+			URL:             config.BootnodesURL,
+			PubKey:          pubkey,
+			RefreshInterval: config.BootnodesRefresh,
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	stateStore, err := state.NewDBStore(filepath.Join(config.Path, "state-store.db"))
@@ -148,14 +190,25 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 
 	// set up high level api
 	var resolver *api.MultiResolver
-	if len(config.EnsAPIs) > 0 {
+	if len(config.EnsAPIs) > 0 || ensBackend != nil {
 		opts := []api.MultiResolverOption{}
+		if ensBackend != nil {
+			r, err := newEnsClientWithBackend(ensBackend, config.EnsRoot, self.privateKey)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, api.MultiResolverOptionWithResolver(r, ""))
+		}
 		for _, c := range config.EnsAPIs {
 			tld, endpoint, addr := parseEnsAPIAddress(c)
 			r, err := newEnsClient(endpoint, addr, config, self.privateKey)
 			if err != nil {
 				return nil, err
 			}
+			if self.ensClients == nil {
+				self.ensClients = make(map[string]*ensClient)
+			}
+			self.ensClients[endpoint] = r
 			opts = append(opts, api.MultiResolverOptionWithResolver(r, tld))
 
 		}
@@ -171,19 +224,42 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 	db := storage.NewDBAPI(self.lstore)
 	to := network.NewKademlia(
 		common.FromHex(config.BzzKey),
-		network.NewKadParams(),
+		config.KadParams,
 	)
+	self.kad = to
 	delivery := stream.NewDelivery(to, db)
 
-	self.streamer = stream.NewRegistry(addr, delivery, db, stateStore, &stream.RegistryOptions{
+	prices, err := swap.NewPriceOracle(config.PriceOracle, backend)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set up price oracle: %v", err)
+	}
+	self.prices = prices
+
+	streamOpts := &stream.RegistryOptions{
 		SkipCheck:       config.DeliverySkipCheck,
 		DoSync:          config.SyncEnabled,
 		DoRetrieve:      true,
 		SyncUpdateDelay: config.SyncUpdateDelay,
-	})
+		LedgerKey:       self.privateKey,
+	}
+	// without an oracle the chunk price of the SWAP profile is the configured one
+	if config.PriceOracle != "" {
+		streamOpts.Prices = prices
+	}
+	if config.SwapEnabled {
+		streamOpts.Swap = config.Swap
+		streamOpts.SwapBackend = backend
+		if backend != nil && config.Swap.PrivateKey() != nil {
+			self.cashier = swap.NewCashier(config.Swap.PrivateKey(), backend, config.Swap.Strategy)
+			config.Swap.SetCashier(self.cashier)
+		}
+	}
+	self.streamer = stream.NewRegistry(addr, delivery, db, stateStore, streamOpts)
+
+	self.partition = network.NewPartitionWatchdog(to, config.PartitionParams)
 
 	// set up NetStore, the cloud storage local access layer
-	netStore := storage.NewNetStore(self.lstore, self.streamer.Retrieve)
+	netStore := storage.NewNetStore(self.lstore, self.retrieve)
 	// Swarm Hash Merklised Chunking for Arbitrary-length Document/File storage
 	self.fileStore = storage.NewFileStore(netStore, self.config.FileStoreParams)
 
@@ -224,6 +300,7 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 	log.Debug(fmt.Sprintf("Set up local storage"))
 
 	self.bzz = network.NewBzz(bzzconfig, to, stateStore, stream.Spec, self.streamer.Run)
+	self.bzz.AddStreamerVersion(stream.LegacySpec, self.streamer.RunLegacy)
 
 	// Pss = postal service over swarm (devp2p over bzz)
 	self.ps, err = pss.NewPss(to, config.Pss)
@@ -239,6 +316,8 @@ func NewSwarm(config *api.Config, mockStore *mock.NodeStore) (self *Swarm, err e
 	log.Debug(fmt.Sprintf("-> Web3 virtual server API"))
 
 	self.sfs = fuse.NewSwarmFS(self.api)
+	self.sfs.SetMountTable(filepath.Join(config.Path, fuse.MountTableFile))
+	self.sfs.SetReadCache(config.FuseCacheSize, config.FuseReadahead)
 	log.Debug("-> Initializing Fuse file system")
 
 	return self, nil
@@ -285,8 +364,6 @@ func newEnsClient(endpoint string, addr common.Address, config *api.Config, priv
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to ENS API %s: %s", endpoint, err)
 	}
-	ethClient := ethclient.NewClient(client)
-
 	ensRoot := config.EnsRoot
 	if addr != (common.Address{}) {
 		ensRoot = addr
@@ -298,16 +375,25 @@ func newEnsClient(endpoint string, addr common.Address, config *api.Config, priv
 			log.Warn(fmt.Sprintf("could not determine ENS contract address, using default %s", ensRoot), "err", err)
 		}
 	}
+	log.Debug(fmt.Sprintf("-> Swarm Domain Name Registrar %v @ address %v", endpoint, ensRoot.Hex()))
+	return newEnsClientWithBackend(client, ensRoot, privkey)
+}
+
+// newEnsClientWithBackend creates a new ENS client for the ENS root contract at
+// the given address, interacting with the chain over an already connected RPC
+// client.
+func newEnsClientWithBackend(client *rpc.Client, ensRoot common.Address, privkey *ecdsa.PrivateKey) (*ensClient, error) {
+	ethClient := ethclient.NewClient(client)
+
 	transactOpts := bind.NewKeyedTransactor(privkey)
 	dns, err := ens.NewENS(transactOpts, ensRoot, ethClient)
 	if err != nil {
 		return nil, err
 	}
-	log.Debug(fmt.Sprintf("-> Swarm Domain Name Registrar %v @ address %v", endpoint, ensRoot.Hex()))
 	return &ensClient{
 		ENS:    dns,
 		Client: ethClient,
-	}, err
+	}, nil
 }
 
 // detectEnsAddr determines the ENS contract address by getting both the
@@ -367,6 +453,9 @@ func (self *Swarm) Start(srv *p2p.Server) error {
 			return fmt.Errorf("Unable to set chequebook for SWAP: %v", err)
 		}
 		log.Debug(fmt.Sprintf("-> cheque book for SWAP: %v", self.config.Swap.Chequebook()))
+		if self.cashier != nil {
+			self.cashier.Start()
+		}
 	} else {
 		log.Debug(fmt.Sprintf("SWAP disabled: no cheque book set"))
 	}
@@ -385,13 +474,27 @@ func (self *Swarm) Start(srv *p2p.Server) error {
 		log.Info("Pss started")
 	}
 
+	self.partition.Start()
+
+	if self.bootnodes != nil {
+		self.bootnodes.Start(srv)
+		log.Info("Bootnode list refresh started", "url", self.config.BootnodesURL)
+	}
+
+	if srv.DiscV5 != nil {
+		self.topics = network.NewTopicDiscovery(self.config.NetworkId, self.config.TopicDiscoveryParams)
+		self.topics.Start(srv)
+		log.Info("Swarm topic discovery started", "topic", network.BzzTopic(self.config.NetworkId))
+	}
+
 	// start swarm http proxy server
 	if self.config.Port != "" {
 		addr := net.JoinHostPort(self.config.ListenAddr, self.config.Port)
-		go httpapi.StartHttpServer(self.api, &httpapi.ServerConfig{
+		self.gateway = httpapi.StartHttpServer(self.api, &httpapi.ServerConfig{
 			Addr:       addr,
 			CorsString: self.config.Cors,
 		})
+		self.gateway.SetHealthChecks(self.healthChecks(), self.readyChecks())
 	}
 
 	log.Debug(fmt.Sprintf("Swarm http proxy started on port: %v", self.config.Port))
@@ -402,11 +505,34 @@ func (self *Swarm) Start(srv *p2p.Server) error {
 
 	self.periodicallyUpdateGauges()
 
+	// re-establish the swarmfs mounts of the previous run
+	self.sfs.Start()
+
 	startCounter.Inc(1)
 	self.streamer.Start(srv)
 	return nil
 }
 
+// retrieve requests the chunk from the network and reports the outcome
+// of the retrieval to the partition watchdog
+func (self *Swarm) retrieve(ctx context.Context, chunk *storage.Chunk) error {
+	if err := self.streamer.Retrieve(ctx, chunk); err != nil {
+		self.partition.Retrieved(false)
+		return err
+	}
+	go func() {
+		timer := time.NewTimer(retrieveWatchTimeout)
+		defer timer.Stop()
+		select {
+		case <-chunk.ReqC:
+			self.partition.Retrieved(true)
+		case <-timer.C:
+			self.partition.Retrieved(false)
+		}
+	}()
+	return nil
+}
+
 func (self *Swarm) periodicallyUpdateGauges() {
 	ticker := time.NewTicker(updateGaugesPeriod)
 
@@ -428,6 +554,19 @@ func (self *Swarm) Stop() error {
 	if self.ps != nil {
 		self.ps.Stop()
 	}
+	if self.bootnodes != nil {
+		self.bootnodes.Stop()
+	}
+	if self.topics != nil {
+		self.topics.Stop()
+	}
+	self.partition.Stop()
+	if s, ok := self.prices.(interface{ Stop() }); ok {
+		s.Stop()
+	}
+	if self.cashier != nil {
+		self.cashier.Stop()
+	}
 	if ch := self.config.Swap.Chequebook(); ch != nil {
 		ch.Stop()
 		ch.Save()
@@ -472,6 +611,12 @@ func (self *Swarm) APIs() []rpc.API {
 			Service:   &Info{self.config, chequebook.ContractParams},
 			Public:    true,
 		},
+		{
+			Namespace: "bzz",
+			Version:   "3.0",
+			Service:   &StatusAPI{self},
+			Public:    true,
+		},
 		// admin APIs
 		{
 			Namespace: "bzz",
@@ -479,6 +624,18 @@ func (self *Swarm) APIs() []rpc.API {
 			Service:   api.NewControl(self.api, self.bzz.Hive),
 			Public:    false,
 		},
+		{
+			Namespace: "bzz",
+			Version:   "3.0",
+			Service:   &PinAPI{self},
+			Public:    false,
+		},
+		{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   admin.NewAPI(),
+			Public:    false,
+		},
 		{
 			Namespace: "chequebook",
 			Version:   chequebook.Version,
@@ -509,6 +666,19 @@ func (self *Swarm) APIs() []rpc.API {
 	}
 
 	apis = append(apis, self.bzz.APIs()...)
+	apis = append(apis, rpc.API{
+		Namespace: "hive",
+		Version:   "3.0",
+		Service:   network.NewPartitionAPI(self.partition),
+	})
+	if self.cashier != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "swap",
+			Version:   "1.0",
+			Service:   swap.NewCashierAPI(self.cashier),
+			Public:    false,
+		})
+	}
 
 	if self.ps != nil {
 		apis = append(apis, self.ps.APIs()...)
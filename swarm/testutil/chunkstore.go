@@ -0,0 +1,138 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// FaultyChunkStore is a ChunkStore which injects failures into the chunk
+// retrievals of the store it wraps. Random faults are drawn from a source
+// with a fixed seed, so the same sequence of requests fails the same way
+// on every run.
+type FaultyChunkStore struct {
+	storage.ChunkStore
+
+	mu          sync.Mutex
+	rand        *rand.Rand
+	latency     time.Duration
+	errorRate   float64
+	corruptRate float64
+	unavailable map[string]bool
+	corrupted   map[string]bool
+}
+
+// NewFaultyChunkStore returns a store without faults wrapping store, random
+// faults are drawn from a source seeded with seed
+func NewFaultyChunkStore(store storage.ChunkStore, seed int64) *FaultyChunkStore {
+	return &FaultyChunkStore{
+		ChunkStore:  store,
+		rand:        rand.New(rand.NewSource(seed)),
+		unavailable: make(map[string]bool),
+		corrupted:   make(map[string]bool),
+	}
+}
+
+// SetLatency delays every Put and Get by d
+func (s *FaultyChunkStore) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetErrorRate makes the given fraction of the retrievals fail with
+// ErrChunkNotFound
+func (s *FaultyChunkStore) SetErrorRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorRate = rate
+}
+
+// SetCorruptionRate makes the given fraction of the retrievals return
+// chunks with corrupted data
+func (s *FaultyChunkStore) SetCorruptionRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.corruptRate = rate
+}
+
+// SetUnavailable makes the retrievals of the chunks at addrs fail with
+// ErrChunkNotFound, or succeed again if unavailable is false
+func (s *FaultyChunkStore) SetUnavailable(unavailable bool, addrs ...storage.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, addr := range addrs {
+		if unavailable {
+			s.unavailable[addr.Hex()] = true
+		} else {
+			delete(s.unavailable, addr.Hex())
+		}
+	}
+}
+
+// SetCorrupted makes the retrievals of the chunks at addrs return corrupted
+// data, or the stored data again if corrupted is false
+func (s *FaultyChunkStore) SetCorrupted(corrupted bool, addrs ...storage.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, addr := range addrs {
+		if corrupted {
+			s.corrupted[addr.Hex()] = true
+		} else {
+			delete(s.corrupted, addr.Hex())
+		}
+	}
+}
+
+// Put stores the chunk in the wrapped store after the configured latency
+func (s *FaultyChunkStore) Put(chunk *storage.Chunk) {
+	s.mu.Lock()
+	latency := s.latency
+	s.mu.Unlock()
+	time.Sleep(latency)
+	s.ChunkStore.Put(chunk)
+}
+
+// Get retrieves the chunk from the wrapped store after the configured
+// latency, unless the retrieval is made to fail. The data of corrupted
+// chunks is a modified copy, the stored chunk is never changed.
+func (s *FaultyChunkStore) Get(addr storage.Address) (*storage.Chunk, error) {
+	s.mu.Lock()
+	latency := s.latency
+	fail := s.unavailable[addr.Hex()] || s.errorRate > 0 && s.rand.Float64() < s.errorRate
+	corrupt := s.corrupted[addr.Hex()] || s.corruptRate > 0 && s.rand.Float64() < s.corruptRate
+	s.mu.Unlock()
+
+	time.Sleep(latency)
+	if fail {
+		return nil, storage.ErrChunkNotFound
+	}
+	chunk, err := s.ChunkStore.Get(addr)
+	if err != nil || !corrupt || len(chunk.SData) == 0 {
+		return chunk, err
+	}
+	corrupted := storage.NewChunk(chunk.Addr, nil)
+	corrupted.Size = chunk.Size
+	corrupted.SData = make([]byte, len(chunk.SData))
+	copy(corrupted.SData, chunk.SData)
+	corrupted.SData[len(corrupted.SData)-1] ^= 0xff
+	return corrupted, nil
+}
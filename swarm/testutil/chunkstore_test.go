@@ -0,0 +1,129 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+func TestFaultyChunkStore(t *testing.T) {
+	store := NewFaultyChunkStore(storage.NewMapChunkStore(), 1)
+	addr := storage.Address(bytes.Repeat([]byte{1}, 32))
+	chunk := storage.NewChunk(addr, nil)
+	chunk.SData = []byte("chunk data")
+	store.Put(chunk)
+
+	get := func() []byte {
+		c, err := store.Get(addr)
+		if err != nil {
+			return nil
+		}
+		return c.SData
+	}
+	if data := get(); !bytes.Equal(data, chunk.SData) {
+		t.Fatalf("expected %q, got %q", chunk.SData, data)
+	}
+
+	store.SetUnavailable(true, addr)
+	if _, err := store.Get(addr); err != storage.ErrChunkNotFound {
+		t.Fatalf("expected ErrChunkNotFound for an unavailable chunk, got %v", err)
+	}
+	store.SetUnavailable(false, addr)
+
+	store.SetCorrupted(true, addr)
+	if data := get(); data == nil || bytes.Equal(data, chunk.SData) {
+		t.Fatalf("expected corrupted data, got %q", data)
+	}
+	if string(chunk.SData) != "chunk data" {
+		t.Fatalf("stored chunk was modified: %q", chunk.SData)
+	}
+	store.SetCorrupted(false, addr)
+
+	// the same seed fails the same retrievals
+	failures := func(seed int64) []bool {
+		s := NewFaultyChunkStore(storage.NewMapChunkStore(), seed)
+		s.Put(chunk)
+		s.SetErrorRate(0.5)
+		var failed []bool
+		for i := 0; i < 100; i++ {
+			_, err := s.Get(addr)
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+	first, second := failures(42), failures(42)
+	var count int
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("retrieval %d failed differently with the same seed", i)
+		}
+		if first[i] {
+			count++
+		}
+	}
+	if count == 0 || count == len(first) {
+		t.Fatalf("expected some of the retrievals to fail, %d of %d failed", count, len(first))
+	}
+
+	store.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	get()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected the retrieval to take at least 50ms, took %v", elapsed)
+	}
+}
+
+// TestFaultyChunkStoreRetrieve tests that retrieving content fails when one
+// of its chunks is unavailable
+func TestFaultyChunkStoreRetrieve(t *testing.T) {
+	store := NewFaultyChunkStore(storage.NewMapChunkStore(), 1)
+	fileStore := storage.NewFileStore(store, storage.NewFileStoreParams())
+
+	data := bytes.Repeat([]byte("swarm"), 2000)
+	addr, wait, err := fileStore.Store(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait()
+
+	retrieve := func() ([]byte, error) {
+		reader, _ := fileStore.Retrieve(addr)
+		retrieved := make([]byte, len(data))
+		if _, err := reader.ReadAt(retrieved, 0); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return retrieved, nil
+	}
+	if retrieved, err := retrieve(); err != nil || !bytes.Equal(retrieved, data) {
+		t.Fatalf("expected the content to be retrieved, got error %v", err)
+	}
+
+	// the first data chunk is referenced at the start of the root chunk
+	root, err := store.Get(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetUnavailable(true, storage.Address(root.SData[8:8+len(addr)]))
+	if _, err := retrieve(); err == nil {
+		t.Fatal("expected retrieval of content with an unavailable chunk to fail")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ChurnEvent stops or restarts a node of a TestNetwork
+type ChurnEvent struct {
+	Delay time.Duration // time to wait after the previous event
+	Node  int
+	Up    bool // the node is restarted if true and stopped otherwise
+}
+
+func (e ChurnEvent) String() string {
+	if e.Up {
+		return fmt.Sprintf("restart of node %d", e.Node)
+	}
+	return fmt.Sprintf("stop of node %d", e.Node)
+}
+
+// RandomChurn returns a schedule of n events, each stopping a running node
+// or restarting a stopped one out of nodes, with at most maxDown of them
+// stopped at any time. The schedule only depends on the seed.
+func RandomChurn(seed int64, nodes []int, maxDown, n int, delay time.Duration) []ChurnEvent {
+	if maxDown <= 0 || len(nodes) == 0 {
+		return nil
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	down := make(map[int]bool)
+	events := make([]ChurnEvent, 0, n)
+	for len(events) < n {
+		node := nodes[rnd.Intn(len(nodes))]
+		if !down[node] && len(down) >= maxDown {
+			continue
+		}
+		if down[node] {
+			delete(down, node)
+		} else {
+			down[node] = true
+		}
+		events = append(events, ChurnEvent{Delay: delay, Node: node, Up: !down[node]})
+	}
+	return events
+}
+
+// RunChurn plays the schedule of events on the network, calling check after
+// every event, and returns the first error of an event or a check
+func (tn *TestNetwork) RunChurn(events []ChurnEvent, check func() error) error {
+	for _, e := range events {
+		time.Sleep(e.Delay)
+		var err error
+		if e.Up {
+			err = tn.StartNode(e.Node)
+		} else {
+			err = tn.StopNode(e.Node)
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", e, err)
+		}
+		if err := check(); err != nil {
+			return fmt.Errorf("after %v: %v", e, err)
+		}
+	}
+	return nil
+}
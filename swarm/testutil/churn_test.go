@@ -0,0 +1,109 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRandomChurn(t *testing.T) {
+	nodes := []int{1, 2, 3, 4}
+	events := RandomChurn(1, nodes, 2, 50, time.Millisecond)
+	if len(events) != 50 {
+		t.Fatalf("expected 50 events, got %d", len(events))
+	}
+	if !reflect.DeepEqual(events, RandomChurn(1, nodes, 2, 50, time.Millisecond)) {
+		t.Fatal("expected the same schedule for the same seed")
+	}
+	down := make(map[int]bool)
+	for i, e := range events {
+		if e.Node < 1 || e.Node > 4 {
+			t.Fatalf("event %d: unexpected node %d", i, e.Node)
+		}
+		if e.Up != down[e.Node] {
+			t.Fatalf("event %d: %v of a node which is not stopped", i, e)
+		}
+		down[e.Node] = !e.Up
+		var count int
+		for _, d := range down {
+			if d {
+				count++
+			}
+		}
+		if count > 2 {
+			t.Fatalf("event %d: %d nodes stopped", i, count)
+		}
+	}
+	if events := RandomChurn(1, nodes, 0, 50, time.Millisecond); len(events) != 0 {
+		t.Fatalf("expected no events without stopped nodes, got %d", len(events))
+	}
+}
+
+// TestNetworkChurn tests that content retrieved by all the nodes of a network
+// stays available on the running nodes while nodes are stopped and restarted
+func TestNetworkChurn(t *testing.T) {
+	tn := NewTestNetwork(t, 4, FullTopology)
+	defer tn.Close()
+
+	data := make([]byte, 10000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := tn.Upload(0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retrieve := func(i int) error {
+		retrieved, err := tn.Retrieve(i, addr)
+		if err != nil {
+			return fmt.Errorf("node %d: %v", i, err)
+		}
+		if !bytes.Equal(retrieved, data) {
+			return fmt.Errorf("node %d: retrieved content differs from the uploaded one", i)
+		}
+		return nil
+	}
+	for i := range tn.Nodes {
+		if err := retrieve(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// restarted nodes keep the content they stored
+	check := func() error {
+		for i := range tn.Nodes {
+			if !tn.Up(i) {
+				continue
+			}
+			if !tn.Has(i, addr) {
+				return fmt.Errorf("node %d: content is not stored", i)
+			}
+			if err := retrieve(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := tn.RunChurn(RandomChurn(1, []int{0, 1, 2, 3}, 2, 12, 0), check); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package datagen generates reproducible random test data. A test logs the
+// seed of its data, and a failing run is reproduced by setting the seed with
+// the SWARM_TEST_SEED environment variable.
+//
+// The package has no swarm dependencies, so that the internal tests of any
+// swarm package can use it.
+package datagen
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SeedEnvVar is the environment variable setting the seed of the data
+// generated by tests
+const SeedEnvVar = "SWARM_TEST_SEED"
+
+// Profile is the compressibility of generated data
+type Profile int
+
+const (
+	Random   Profile = iota // uniformly random bytes, incompressible
+	Text                    // random lowercase letters and spaces, partly compressible
+	Repeated                // a random block repeated, highly compressible
+)
+
+// repeatedBlockSize is the size of the block repeated by the Repeated profile
+const repeatedBlockSize = 256
+
+const textAlphabet = "abcdefghijklmnopqrstuvwxyz     "
+
+// TB is the part of testing.TB used to log the seeds of tests
+type TB interface {
+	Fatalf(format string, args ...interface{})
+	Logf(format string, args ...interface{})
+}
+
+// Seed returns the seed set with SWARM_TEST_SEED, or a new seed if it is not
+// set, and logs it on t
+func Seed(t TB) int64 {
+	seed := time.Now().UnixNano()
+	if s := os.Getenv(SeedEnvVar); s != "" {
+		var err error
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			t.Fatalf("invalid %s: %v", SeedEnvVar, err)
+		}
+	}
+	t.Logf("random data seed %d, set %s=%d to reproduce", seed, SeedEnvVar, seed)
+	return seed
+}
+
+// Generator generates data of a profile from a seed. Generators with the
+// same seed and profile generate the same data for the same sequence of
+// calls.
+type Generator struct {
+	seed    int64
+	profile Profile
+	rand    *rand.Rand
+	block   []byte
+	pos     int
+}
+
+// New returns a generator of data of the profile from the seed logged on t
+func New(t TB, profile Profile) *Generator {
+	return NewGenerator(Seed(t), profile)
+}
+
+// NewGenerator returns a generator of data of the profile from seed
+func NewGenerator(seed int64, profile Profile) *Generator {
+	g := &Generator{
+		seed:    seed,
+		profile: profile,
+		rand:    rand.New(rand.NewSource(seed)),
+	}
+	if profile == Repeated {
+		g.block = make([]byte, repeatedBlockSize)
+		g.rand.Read(g.block)
+	}
+	return g
+}
+
+// Seed returns the seed of the generator
+func (g *Generator) Seed() int64 {
+	return g.seed
+}
+
+// Read fills p with the next generated bytes, it never fails
+func (g *Generator) Read(p []byte) (int, error) {
+	switch g.profile {
+	case Text:
+		for i := range p {
+			p[i] = textAlphabet[g.rand.Intn(len(textAlphabet))]
+		}
+	case Repeated:
+		for i := range p {
+			p[i] = g.block[g.pos]
+			g.pos = (g.pos + 1) % len(g.block)
+		}
+	default:
+		g.rand.Read(p)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the next size generated bytes
+func (g *Generator) Bytes(size int) []byte {
+	data := make([]byte, size)
+	g.Read(data)
+	return data
+}
+
+// Reader returns a reader of the next size generated bytes, which are
+// generated as they are read
+func (g *Generator) Reader(size int) io.Reader {
+	return io.LimitReader(g, int64(size))
+}
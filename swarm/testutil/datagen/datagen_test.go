@@ -0,0 +1,74 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package datagen
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestGeneratorReproducible(t *testing.T) {
+	for _, profile := range []Profile{Random, Text, Repeated} {
+		g1, g2 := NewGenerator(42, profile), NewGenerator(42, profile)
+		// the same sequence of calls generates the same data
+		data1 := append(g1.Bytes(1000), g1.Bytes(5000)...)
+		data2, err := ioutil.ReadAll(g2.Reader(1000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data2 = append(data2, g2.Bytes(5000)...)
+		if !bytes.Equal(data1, data2) {
+			t.Fatalf("profile %d: expected the same data for the same seed", profile)
+		}
+		if bytes.Equal(data1, NewGenerator(43, profile).Bytes(6000)) {
+			t.Fatalf("profile %d: expected different data for a different seed", profile)
+		}
+	}
+}
+
+func TestGeneratorProfiles(t *testing.T) {
+	compressed := func(data []byte) float64 {
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.BestCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+		w.Close()
+		return float64(buf.Len()) / float64(len(data))
+	}
+	random := compressed(NewGenerator(1, Random).Bytes(100000))
+	text := compressed(NewGenerator(1, Text).Bytes(100000))
+	repeated := compressed(NewGenerator(1, Repeated).Bytes(100000))
+	if random < 0.95 || text > 0.8 || text < 0.5 || repeated > 0.05 {
+		t.Fatalf("unexpected compression ratios random=%.2f text=%.2f repeated=%.2f", random, text, repeated)
+	}
+}
+
+func TestSeedFromEnv(t *testing.T) {
+	defer os.Setenv(SeedEnvVar, os.Getenv(SeedEnvVar))
+	os.Setenv(SeedEnvVar, "1234")
+	if seed := Seed(t); seed != 1234 {
+		t.Fatalf("expected seed 1234, got %d", seed)
+	}
+	if !bytes.Equal(New(t, Random).Bytes(100), NewGenerator(1234, Random).Bytes(100)) {
+		t.Fatal("expected the data of the seed set in the environment")
+	}
+}
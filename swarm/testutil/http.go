@@ -18,14 +18,17 @@ package testutil
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/metrics/influxdb"
@@ -50,7 +53,54 @@ func (f *fakeBackend) HeaderByNumber(context context.Context, _ string, bigblock
 	}, nil
 }
 
+// TestResolver is an ENS resolver of the names it is given, which can be
+// changed while the server is running
+type TestResolver struct {
+	mu    sync.RWMutex
+	names map[string]common.Hash
+}
+
+// NewTestResolver returns a resolver of the given names
+func NewTestResolver(names map[string]common.Hash) *TestResolver {
+	r := &TestResolver{names: make(map[string]common.Hash)}
+	for name, hash := range names {
+		r.names[name] = hash
+	}
+	return r
+}
+
+// Resolve returns the hash the name is registered with
+func (r *TestResolver) Resolve(name string) (common.Hash, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hash, ok := r.names[name]
+	if !ok {
+		return common.Hash{}, fmt.Errorf("DNS name not found: %q", name)
+	}
+	return hash, nil
+}
+
+// Set registers the name with hash, replacing its previous hash if any
+func (r *TestResolver) Set(name string, hash common.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[name] = hash
+}
+
+// Delete unregisters the name
+func (r *TestResolver) Delete(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.names, name)
+}
+
 func NewTestSwarmServer(t *testing.T, serverFunc func(*api.Api) TestServer) *TestSwarmServer {
+	return NewTestSwarmServerWithResolver(t, serverFunc, nil)
+}
+
+// NewTestSwarmServerWithResolver starts a test swarm server which resolves
+// ENS names with the given names if they are not nil
+func NewTestSwarmServerWithResolver(t *testing.T, serverFunc func(*api.Api) TestServer, names map[string]common.Hash) *TestSwarmServer {
 	dir, err := ioutil.TempDir("", "swarm-storage-test")
 	if err != nil {
 		t.Fatal(err)
@@ -82,11 +132,21 @@ func NewTestSwarmServer(t *testing.T, serverFunc func(*api.Api) TestServer) *Tes
 		t.Fatal(err)
 	}
 
-	a := api.NewApi(fileStore, nil, rh)
+	// without names the api has no resolver, the same as a node without ENS
+	var (
+		resolver *TestResolver
+		dns      api.Resolver
+	)
+	if names != nil {
+		resolver = NewTestResolver(names)
+		dns = resolver
+	}
+	a := api.NewApi(fileStore, dns, rh)
 	srv := httptest.NewServer(serverFunc(a))
 	return &TestSwarmServer{
 		Server:    srv,
 		FileStore: fileStore,
+		Resolver:  resolver,
 		dir:       dir,
 		Hasher:    storage.MakeHashFunc(storage.DefaultHash)(),
 		cleanup: func() {
@@ -102,6 +162,7 @@ type TestSwarmServer struct {
 	*httptest.Server
 	Hasher    storage.SwarmHash
 	FileStore *storage.FileStore
+	Resolver  *TestResolver // nil if the server has no ENS resolver
 	dir       string
 	cleanup   func()
 }
@@ -0,0 +1,225 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+// LoadTarget is the swarm content store driven by a load test
+type LoadTarget interface {
+	Upload(data []byte) (string, error)
+	Download(ref string) ([]byte, error)
+}
+
+// FileStoreTarget is a LoadTarget storing content in a FileStore directly
+type FileStoreTarget struct {
+	FileStore *storage.FileStore
+}
+
+// Upload stores data and waits until all its chunks are stored
+func (f *FileStoreTarget) Upload(data []byte) (string, error) {
+	addr, wait, err := f.FileStore.Store(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		return "", err
+	}
+	wait()
+	return addr.Hex(), nil
+}
+
+// Download retrieves the content at ref
+func (f *FileStoreTarget) Download(ref string) ([]byte, error) {
+	reader, _ := f.FileStore.Retrieve(storage.Address(common.FromHex(ref)))
+	size, err := reader.Size(nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(io.NewSectionReader(reader, 0, size))
+}
+
+// HTTPTarget is a LoadTarget storing raw content through the HTTP API of a
+// swarm node, such as a TestSwarmServer
+type HTTPTarget struct {
+	URL string
+}
+
+// Upload posts data to the bzz-raw endpoint
+func (h *HTTPTarget) Upload(data []byte) (string, error) {
+	res, err := http.Post(h.URL+"/bzz-raw:/", "application/octet-stream", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	ref, err := ioutil.ReadAll(res.Body)
+	return string(ref), err
+}
+
+// Download gets the content at ref from the bzz-raw endpoint
+func (h *HTTPTarget) Download(ref string) ([]byte, error) {
+	res, err := http.Get(h.URL + "/bzz-raw:/" + ref)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// LoadConfig configures the workload of a load test
+type LoadConfig struct {
+	Concurrency int // number of concurrent workers, 1 if not positive
+	Uploads     int // number of uploads of random content
+	Downloads   int // number of downloads of every uploaded content
+	Size        int // size of every uploaded content in bytes
+}
+
+// LatencyStats summarises the latencies of the operations of a load test
+type LatencyStats struct {
+	Count      int
+	Throughput float64 // bytes transferred per second of the whole workload
+	P50        time.Duration
+	P90        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+func (s LatencyStats) String() string {
+	return fmt.Sprintf("count=%d throughput=%.0fB/s p50=%v p90=%v p99=%v max=%v", s.Count, s.Throughput, s.P50, s.P90, s.P99, s.Max)
+}
+
+// newLatencyStats returns the statistics of operations transferring size
+// bytes each with the given latencies during a workload of duration d
+func newLatencyStats(latencies []time.Duration, size int, d time.Duration) LatencyStats {
+	stats := LatencyStats{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return stats
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p int) time.Duration {
+		return latencies[(len(latencies)-1)*p/100]
+	}
+	if d > 0 {
+		stats.Throughput = float64(size*len(latencies)) / d.Seconds()
+	}
+	stats.P50 = percentile(50)
+	stats.P90 = percentile(90)
+	stats.P99 = percentile(99)
+	stats.Max = latencies[len(latencies)-1]
+	return stats
+}
+
+// LoadReport is the result of a load test
+type LoadReport struct {
+	Uploads   LatencyStats
+	Downloads LatencyStats
+	Errors    int           // failed operations and downloads of wrong content
+	Err       error         // the first error, nil if there were none
+	Duration  time.Duration // wall time of the whole workload
+}
+
+func (r *LoadReport) String() string {
+	return fmt.Sprintf("uploads: %v\ndownloads: %v\nerrors: %d, duration: %v", r.Uploads, r.Downloads, r.Errors, r.Duration)
+}
+
+// RunLoad drives target with the workload of config and reports the
+// latencies of its operations. Every content is uploaded and then
+// downloaded and checked config.Downloads times by the same worker.
+func RunLoad(target LoadTarget, config LoadConfig) *LoadReport {
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var (
+		mu        sync.Mutex
+		uploads   []time.Duration
+		downloads []time.Duration
+		errors    int
+		firstErr  error
+		wg        sync.WaitGroup
+		jobs      = make(chan struct{})
+	)
+	record := func(latencies *[]time.Duration, start time.Time, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if errors == 0 {
+				firstErr = err
+			}
+			errors++
+			return
+		}
+		*latencies = append(*latencies, time.Since(start))
+	}
+	worker := func() {
+		defer wg.Done()
+		for range jobs {
+			data := make([]byte, config.Size)
+			rand.Read(data)
+
+			start := time.Now()
+			ref, err := target.Upload(data)
+			record(&uploads, start, err)
+			if err != nil {
+				continue
+			}
+			for i := 0; i < config.Downloads; i++ {
+				start := time.Now()
+				downloaded, err := target.Download(ref)
+				if err == nil && !bytes.Equal(downloaded, data) {
+					err = fmt.Errorf("downloaded content of %s differs from the uploaded one", ref)
+				}
+				record(&downloads, start, err)
+			}
+		}
+	}
+
+	start := time.Now()
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go worker()
+	}
+	for i := 0; i < config.Uploads; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+
+	d := time.Since(start)
+	return &LoadReport{
+		Uploads:   newLatencyStats(uploads, config.Size, d),
+		Downloads: newLatencyStats(downloads, config.Size, d),
+		Errors:    errors,
+		Err:       firstErr,
+		Duration:  d,
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/swarm/storage"
+)
+
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	stats := newLatencyStats(latencies, 1000, time.Second)
+	if stats.Count != 100 || stats.Throughput != 100000 {
+		t.Fatalf("expected 100 operations at 100000B/s, got %d at %vB/s", stats.Count, stats.Throughput)
+	}
+	if stats.P50 != 50*time.Millisecond || stats.P90 != 90*time.Millisecond || stats.P99 != 99*time.Millisecond || stats.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected percentiles %v", stats)
+	}
+}
+
+func TestRunLoad(t *testing.T) {
+	config := LoadConfig{
+		Concurrency: 4,
+		Uploads:     8,
+		Downloads:   2,
+		Size:        10000,
+	}
+	fileStore := storage.NewFileStore(storage.NewMapChunkStore(), storage.NewFileStoreParams())
+	report := RunLoad(&FileStoreTarget{FileStore: fileStore}, config)
+	if report.Errors != 0 {
+		t.Fatalf("%d errors, the first one: %v", report.Errors, report.Err)
+	}
+	if report.Uploads.Count != 8 || report.Downloads.Count != 16 {
+		t.Fatalf("expected 8 uploads and 16 downloads, got %d and %d", report.Uploads.Count, report.Downloads.Count)
+	}
+
+	// downloads of chunks which cannot be retrieved are reported as errors
+	store := NewFaultyChunkStore(storage.NewMapChunkStore(), 1)
+	store.SetErrorRate(1)
+	report = RunLoad(&FileStoreTarget{FileStore: storage.NewFileStore(store, storage.NewFileStoreParams())}, config)
+	if report.Errors != 16 || report.Err == nil {
+		t.Fatalf("expected 16 failed downloads, got %d errors", report.Errors)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/swarm/network"
+	"github.com/ethereum/go-ethereum/swarm/network/stream"
+	"github.com/ethereum/go-ethereum/swarm/state"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/storage/mock/mem"
+)
+
+// Topology returns the pairs of indexes of the nodes to connect in a
+// network of n nodes
+type Topology func(n int) [][2]int
+
+// ChainTopology connects every node to the next one
+func ChainTopology(n int) [][2]int {
+	var conns [][2]int
+	for i := 0; i < n-1; i++ {
+		conns = append(conns, [2]int{i, i + 1})
+	}
+	return conns
+}
+
+// RingTopology connects the nodes in a chain and the last node to the
+// first one
+func RingTopology(n int) [][2]int {
+	conns := ChainTopology(n)
+	if n > 2 {
+		conns = append(conns, [2]int{n - 1, 0})
+	}
+	return conns
+}
+
+// StarTopology connects every node to the first one
+func StarTopology(n int) [][2]int {
+	var conns [][2]int
+	for i := 1; i < n; i++ {
+		conns = append(conns, [2]int{0, i})
+	}
+	return conns
+}
+
+// FullTopology connects every node to every other node
+func FullTopology(n int) [][2]int {
+	var conns [][2]int
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			conns = append(conns, [2]int{i, j})
+		}
+	}
+	return conns
+}
+
+// TestNode is an in-process swarm node of a TestNetwork, it stores chunks in
+// an in-memory global store, syncs them to its peers and retrieves missing
+// chunks from them
+type TestNode struct {
+	ID         discover.NodeID
+	Addr       *network.BzzAddr
+	Kademlia   *network.Kademlia
+	Registry   *stream.Registry
+	LocalStore *storage.LocalStore
+	NetStore   *storage.NetStore
+	FileStore  *storage.FileStore
+
+	dir     string
+	stopped bool
+}
+
+// TestNetwork is a network of in-process swarm nodes connected by in-memory
+// pipes
+type TestNetwork struct {
+	Net   *simulations.Network
+	Nodes []*TestNode
+
+	adapter *adapters.SimAdapter
+	store   *mem.GlobalStore
+	conns   [][2]int
+	mu      sync.Mutex
+	byID    map[discover.NodeID]*TestNode
+}
+
+// NewTestNetwork starts a network of n swarm nodes connected in the given
+// topology and waits until all the connections are established
+func NewTestNetwork(t *testing.T, n int, topology Topology) *TestNetwork {
+	tn := newTestNetwork()
+	for i := 0; i < n; i++ {
+		conf := adapters.RandomNodeConfig()
+		if _, err := tn.Net.NewNodeWithConfig(conf); err != nil {
+			tn.Close()
+			t.Fatal(err)
+		}
+		if err := tn.Net.Start(conf.ID); err != nil {
+			tn.Close()
+			t.Fatal(err)
+		}
+		tn.mu.Lock()
+		tn.Nodes = append(tn.Nodes, tn.byID[conf.ID])
+		tn.mu.Unlock()
+	}
+
+	tn.conns = topology(n)
+	for _, conn := range tn.conns {
+		if err := tn.Net.Connect(tn.Nodes[conn[0]].ID, tn.Nodes[conn[1]].ID); err != nil {
+			tn.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := tn.waitConnected(); err != nil {
+		tn.Close()
+		t.Fatal(err)
+	}
+	return tn
+}
+
+// newTestNetwork returns a network without nodes
+func newTestNetwork() *TestNetwork {
+	tn := &TestNetwork{
+		store: mem.NewGlobalStore(),
+		byID:  make(map[discover.NodeID]*TestNode),
+	}
+	tn.adapter = adapters.NewSimAdapter(adapters.Services{
+		"streamer": tn.newService,
+	})
+	tn.Net = simulations.NewNetwork(tn.adapter, &simulations.NetworkConfig{
+		ID:             "swarm-test-network",
+		DefaultService: "streamer",
+	})
+	return tn
+}
+
+// waitConnected waits until every node is connected to its neighbours
+func (tn *TestNetwork) waitConnected() error {
+	deadline := time.Now().Add(10 * time.Second)
+	for i := range tn.Nodes {
+		if err := tn.waitPeers(i, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newService creates the stores and the streamer of a node of the network
+func (tn *TestNetwork) newService(ctx *adapters.ServiceContext) (node.Service, error) {
+	id := ctx.Config.ID
+	addr := network.NewAddrFromNodeID(id)
+	kad := network.NewKademlia(addr.Over(), network.NewKadParams())
+
+	// a restarted node keeps the chunks it stored before it was stopped
+	tn.mu.Lock()
+	old := tn.byID[id]
+	tn.mu.Unlock()
+	var dir string
+	if old != nil {
+		dir = old.dir
+	} else {
+		var err error
+		if dir, err = ioutil.TempDir("", "swarm-test-network"); err != nil {
+			return nil, err
+		}
+	}
+	params := storage.NewDefaultLocalStoreParams()
+	params.Init(dir)
+	params.BaseKey = addr.Over()
+	localStore, err := storage.NewLocalStore(params, tn.store.NewNodeStore(common.BytesToAddress(id.Bytes())))
+	if err != nil {
+		if old == nil {
+			os.RemoveAll(dir)
+		}
+		return nil, err
+	}
+
+	db := storage.NewDBAPI(localStore)
+	delivery := stream.NewDelivery(kad, db)
+	registry := stream.NewRegistry(addr, delivery, db, state.NewInmemoryStore(), &stream.RegistryOptions{
+		SkipCheck:       true,
+		DoSync:          true,
+		DoRetrieve:      true,
+		SyncUpdateDelay: 100 * time.Millisecond,
+	})
+	netStore := storage.NewNetStore(localStore, registry.Retrieve)
+
+	tn.mu.Lock()
+	tn.byID[id] = &TestNode{
+		ID:         id,
+		Addr:       addr,
+		Kademlia:   kad,
+		Registry:   registry,
+		LocalStore: localStore,
+		NetStore:   netStore,
+		FileStore:  storage.NewFileStore(netStore, storage.NewFileStoreParams()),
+		dir:        dir,
+	}
+	tn.mu.Unlock()
+	return registry, nil
+}
+
+// neighbours returns the indexes of the running nodes connected to node i in
+// the topology of the network
+func (tn *TestNetwork) neighbours(i int) []int {
+	var nodes []int
+	for _, conn := range tn.conns {
+		other := conn[0]
+		if other == i {
+			other = conn[1]
+		} else if conn[1] != i {
+			continue
+		}
+		if tn.Up(other) {
+			nodes = append(nodes, other)
+		}
+	}
+	return nodes
+}
+
+// waitPeers waits until node i is connected to its running neighbours and
+// no other nodes both in its kademlia table and in its streamer, so that
+// retrieve requests are not sent to peers which do not run the stream
+// protocol yet or have been stopped
+func (tn *TestNetwork) waitPeers(i int, deadline time.Time) error {
+	n, peers := tn.Nodes[i], len(tn.neighbours(i))
+	for n.Kademlia.Status().Connected != peers || n.Registry.SyncStatus().Peers != peers {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("node %d: timed out waiting for %d peers", i, peers)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// Up reports whether node i is running
+func (tn *TestNetwork) Up(i int) bool {
+	return !tn.Nodes[i].stopped
+}
+
+// StopNode stops node i and waits until its neighbours are disconnected from
+// it, the chunks it stores are kept for its restart
+func (tn *TestNetwork) StopNode(i int) error {
+	n := tn.Nodes[i]
+	if err := tn.Net.Stop(n.ID); err != nil {
+		return err
+	}
+	n.Registry.Close()
+	n.LocalStore.Close()
+	n.stopped = true
+
+	deadline := time.Now().Add(10 * time.Second)
+	for _, other := range tn.neighbours(i) {
+		if err := tn.waitPeers(other, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartNode restarts the stopped node i and waits until it is connected again
+// to its running neighbours in the topology of the network
+func (tn *TestNetwork) StartNode(i int) error {
+	id := tn.Nodes[i].ID
+	if err := tn.Net.Start(id); err != nil {
+		return err
+	}
+	tn.mu.Lock()
+	tn.Nodes[i] = tn.byID[id]
+	tn.mu.Unlock()
+
+	// the restarted node dials its peers itself, as the nodes which dialled
+	// it before it was stopped do not dial it again until their dial
+	// history expires
+	client, err := tn.Net.GetNode(id).Client()
+	if err != nil {
+		return err
+	}
+	for _, other := range tn.neighbours(i) {
+		if err := client.Call(nil, "admin_addPeer", string(tn.Net.GetNode(tn.Nodes[other].ID).Addr())); err != nil {
+			return fmt.Errorf("node %d: could not connect to node %d: %v", i, other, err)
+		}
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	if err := tn.waitPeers(i, deadline); err != nil {
+		return err
+	}
+	for _, other := range tn.neighbours(i) {
+		if err := tn.waitPeers(other, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLinkConditions sets the latency, jitter and loss of the connection
+// between nodes i and j, nil conditions make it perfect again
+func (tn *TestNetwork) SetLinkConditions(i, j int, conditions *adapters.LinkConditions) {
+	tn.adapter.SetLinkConditions(tn.Nodes[i].ID, tn.Nodes[j].ID, conditions)
+}
+
+// Has reports whether node i stores the chunk at addr locally
+func (tn *TestNetwork) Has(i int, addr storage.Address) bool {
+	_, err := tn.Nodes[i].LocalStore.Get(addr)
+	return err == nil
+}
+
+// Upload stores data on node i and returns its root address once all its
+// chunks are stored
+func (tn *TestNetwork) Upload(i int, data []byte) (storage.Address, error) {
+	addr, wait, err := tn.Nodes[i].FileStore.Store(bytes.NewReader(data), int64(len(data)), false)
+	if err != nil {
+		return nil, err
+	}
+	wait()
+	return addr, nil
+}
+
+// Retrieve returns the content at addr as retrieved by node i, fetching the
+// chunks it does not store from its peers
+func (tn *TestNetwork) Retrieve(i int, addr storage.Address) ([]byte, error) {
+	reader, _ := tn.Nodes[i].FileStore.Retrieve(addr)
+	size, err := reader.Size(nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(io.NewSectionReader(reader, 0, size))
+}
+
+// Close shuts down the network and removes the stores of its nodes
+func (tn *TestNetwork) Close() {
+	if tn.Net != nil {
+		tn.Net.Shutdown()
+	}
+	tn.mu.Lock()
+	defer tn.mu.Unlock()
+	for _, n := range tn.byID {
+		if n.stopped {
+			os.RemoveAll(n.dir)
+			continue
+		}
+		n.Registry.Close()
+		n.LocalStore.Close()
+		os.RemoveAll(n.dir)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/tracing"
+)
+
+func TestTopologies(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		topology Topology
+		expected [][2]int
+	}{
+		{"chain", ChainTopology, [][2]int{{0, 1}, {1, 2}, {2, 3}}},
+		{"ring", RingTopology, [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 0}}},
+		{"star", StarTopology, [][2]int{{0, 1}, {0, 2}, {0, 3}}},
+		{"full", FullTopology, [][2]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}},
+	} {
+		if conns := test.topology(4); !reflect.DeepEqual(conns, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, conns)
+		}
+	}
+}
+
+// TestNetworkUploadRetrieve tests that content uploaded on one node of a
+// chain can be retrieved on every other node
+func TestNetworkUploadRetrieve(t *testing.T) {
+	tn := NewTestNetwork(t, 3, ChainTopology)
+	defer tn.Close()
+
+	data := make([]byte, 10000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := tn.Upload(0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(tn.Nodes); i++ {
+		retrieved, err := tn.Retrieve(i, addr)
+		if err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+		if !bytes.Equal(retrieved, data) {
+			t.Fatalf("node %d: retrieved content differs from the uploaded one", i)
+		}
+	}
+}
+
+// TestNetworkDegradedLink tests that a single retrieval attempt times out
+// over a slow link, while retrieval with retries still succeeds
+func TestNetworkDegradedLink(t *testing.T) {
+	tn := NewTestNetwork(t, 2, ChainTopology)
+	defer tn.Close()
+
+	tn.SetLinkConditions(0, 1, &adapters.LinkConditions{
+		Latency: 500 * time.Millisecond,
+		Jitter:  100 * time.Millisecond,
+		Loss:    0.2,
+	})
+	data := make([]byte, 1000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := tn.Upload(0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the request and the delivery take at least a second
+	if _, err := tn.Nodes[1].NetStore.GetWithTimeout(addr, 200*time.Millisecond); err != storage.ErrChunkNotFound {
+		t.Fatalf("expected the retrieval to time out, got %v", err)
+	}
+	start := time.Now()
+	chunk, err := tn.Nodes[1].NetStore.Get(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 500*time.Millisecond {
+		t.Fatalf("expected the retrieval to be delayed by the link, took %v", d)
+	}
+	if !bytes.Equal(chunk.SData[8:], data) {
+		t.Fatal("retrieved chunk differs from the uploaded one")
+	}
+}
+
+// TestNetworkTracing tests that the retrieval of content from a peer is
+// traced down to the stream layer
+func TestNetworkTracing(t *testing.T) {
+	tn := NewTestNetwork(t, 2, ChainTopology)
+	defer tn.Close()
+
+	data := make([]byte, 1000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := tn.Upload(0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &tracing.Recorder{}
+	tracing.SetTracer(tracing.NewTracer(tracing.ConstSampler(true), recorder))
+	defer tracing.SetTracer(nil)
+	ctx, root := tracing.StartSpan(context.Background(), "test")
+	reader, _ := tn.Nodes[1].FileStore.RetrieveContext(ctx, addr)
+	if _, err := reader.ReadAt(make([]byte, len(data)), 0); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	root.Finish()
+
+	names := make(map[string]bool)
+	for _, sp := range recorder.Spans() {
+		if sp.TraceID != root.TraceID {
+			t.Fatalf("span %s: expected the trace of the retrieval", sp.Name)
+		}
+		names[sp.Name] = true
+	}
+	for _, name := range []string{"lcr.read", "netstore.get", "stream.retrieve"} {
+		if !names[name] {
+			t.Errorf("expected a %s span, got %v", name, names)
+		}
+	}
+}
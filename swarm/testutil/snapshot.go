@@ -0,0 +1,175 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/swarm/storage"
+	"github.com/ethereum/go-ethereum/swarm/storage/mock"
+)
+
+// NetworkSnapshot is the state of a TestNetwork, it is saved as JSON so that
+// networks which take long to build can be reused across test runs
+type NetworkSnapshot struct {
+	Nodes  []*adapters.NodeConfig `json:"nodes"`
+	Conns  [][2]int               `json:"conns"`
+	Chunks []byte                 `json:"chunks"` // the chunks of all nodes exported by mock.Exporter
+}
+
+// Snapshot returns the configurations of the nodes of the network, their
+// connections and the chunks they store. All the nodes must be running.
+func (tn *TestNetwork) Snapshot() (*NetworkSnapshot, error) {
+	snap := &NetworkSnapshot{
+		Conns: tn.conns,
+	}
+	for i, n := range tn.Nodes {
+		if !tn.Up(i) {
+			return nil, fmt.Errorf("node %d is stopped", i)
+		}
+		snap.Nodes = append(snap.Nodes, tn.Net.GetNode(n.ID).Config)
+	}
+	var buf bytes.Buffer
+	if _, err := tn.store.Export(&buf); err != nil {
+		return nil, err
+	}
+	snap.Chunks = buf.Bytes()
+	return snap, nil
+}
+
+// SaveSnapshot saves the snapshot of the network to the file at path
+func (tn *TestNetwork) SaveSnapshot(path string) error {
+	snap, err := tn.Snapshot()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// LoadSnapshot reads the network snapshot saved in the file at path
+func LoadSnapshot(path string) (*NetworkSnapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snap := &NetworkSnapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// NewTestNetworkFromSnapshot starts a network with the nodes, connections and
+// chunks of snap and waits until all the connections are established
+func NewTestNetworkFromSnapshot(t *testing.T, snap *NetworkSnapshot) *TestNetwork {
+	tn := newTestNetwork()
+	for _, conf := range snap.Nodes {
+		if _, err := tn.Net.NewNodeWithConfig(conf); err != nil {
+			tn.Close()
+			t.Fatal(err)
+		}
+		if err := tn.Net.Start(conf.ID); err != nil {
+			tn.Close()
+			t.Fatal(err)
+		}
+		tn.mu.Lock()
+		tn.Nodes = append(tn.Nodes, tn.byID[conf.ID])
+		tn.mu.Unlock()
+	}
+
+	// the chunks are stored before the nodes are connected, so that they are
+	// not synced again
+	if err := tn.importChunks(bytes.NewReader(snap.Chunks)); err != nil {
+		tn.Close()
+		t.Fatal(err)
+	}
+
+	tn.conns = snap.Conns
+	for _, conn := range tn.conns {
+		if err := tn.Net.Connect(tn.Nodes[conn[0]].ID, tn.Nodes[conn[1]].ID); err != nil {
+			tn.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := tn.waitConnected(); err != nil {
+		tn.Close()
+		t.Fatal(err)
+	}
+	return tn
+}
+
+// importChunks stores the chunks of a tar archive exported from the global
+// store of a network in the local stores of the nodes which stored them
+func (tn *TestNetwork) importChunks(r io.Reader) error {
+	nodes := make(map[common.Address]*TestNode)
+	for _, n := range tn.Nodes {
+		nodes[common.BytesToAddress(n.ID.Bytes())] = n
+	}
+
+	var chunks []*storage.Chunk
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		var c mock.ExportedChunk
+		if err := json.Unmarshal(data, &c); err != nil {
+			return err
+		}
+		// the stored data is the address of the chunk followed by its data
+		addr := storage.Address(common.FromHex(hdr.Name))
+		if len(c.Data) < len(addr)+8 {
+			return fmt.Errorf("invalid data of chunk %s", hdr.Name)
+		}
+		for _, a := range c.Addrs {
+			n, ok := nodes[a]
+			if !ok {
+				return fmt.Errorf("chunk %s is stored by unknown node %s", hdr.Name, a.Hex())
+			}
+			chunk := storage.NewChunk(addr, nil)
+			chunk.SData = c.Data[len(addr):]
+			n.LocalStore.Put(chunk)
+			chunks = append(chunks, chunk)
+		}
+	}
+	for _, chunk := range chunks {
+		if err := chunk.WaitToStore(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
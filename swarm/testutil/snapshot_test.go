@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package testutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// TestNetworkSnapshot tests that a network loaded from a saved snapshot has
+// the nodes, connections and chunks of the saved network
+func TestNetworkSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarm-test-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+
+	tn := NewTestNetwork(t, 3, ChainTopology)
+	data := make([]byte, 10000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := tn.Upload(0, data)
+	if err != nil {
+		tn.Close()
+		t.Fatal(err)
+	}
+	if _, err := tn.Retrieve(2, addr); err != nil {
+		tn.Close()
+		t.Fatal(err)
+	}
+	var had []bool
+	for i := range tn.Nodes {
+		had = append(had, tn.Has(i, addr))
+	}
+	err = tn.SaveSnapshot(path)
+	ids := make([]discover.NodeID, len(tn.Nodes))
+	for i, n := range tn.Nodes {
+		ids[i] = n.ID
+	}
+	tn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewTestNetworkFromSnapshot(t, snap)
+	defer loaded.Close()
+
+	loadedIDs := make([]discover.NodeID, len(loaded.Nodes))
+	for i, n := range loaded.Nodes {
+		loadedIDs[i] = n.ID
+	}
+	if !reflect.DeepEqual(loadedIDs, ids) {
+		t.Fatalf("expected nodes %v, got %v", ids, loadedIDs)
+	}
+	if !reflect.DeepEqual(loaded.conns, ChainTopology(3)) {
+		t.Fatalf("expected chain connections, got %v", loaded.conns)
+	}
+	for i := range loaded.Nodes {
+		if had[i] && !loaded.Has(i, addr) {
+			t.Fatalf("node %d: expected the loaded node to store the root chunk", i)
+		}
+	}
+	for i := range loaded.Nodes {
+		retrieved, err := loaded.Retrieve(i, addr)
+		if err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+		if !bytes.Equal(retrieved, data) {
+			t.Fatalf("node %d: retrieved content differs from the uploaded one", i)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Config holds the settings of the tracing of swarm requests
+type Config struct {
+	Enabled    bool
+	Endpoint   string  // Zipkin v2 JSON endpoint of the collector
+	Service    string  // service name of the exported spans
+	SampleRate float64 // fraction of the requests which are traced
+}
+
+// DefaultConfig contains the default tracing settings
+var DefaultConfig = Config{
+	Endpoint:   "http://127.0.0.1:9411/api/v2/spans",
+	Service:    "swarm",
+	SampleRate: 1,
+}
+
+var (
+	tracingEnabledFlag = cli.BoolFlag{
+		Name:  "tracing",
+		Usage: "Enable tracing of the upload and retrieval of content",
+	}
+	tracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing.endpoint",
+		Usage: "Tracing collector endpoint accepting Zipkin v2 JSON spans (Zipkin, or Jaeger with its Zipkin collector enabled)",
+		Value: DefaultConfig.Endpoint,
+	}
+	tracingServiceFlag = cli.StringFlag{
+		Name:  "tracing.svc",
+		Usage: "Tracing service name of the exported spans",
+		Value: DefaultConfig.Service,
+	}
+	tracingSampleRateFlag = cli.Float64Flag{
+		Name:  "tracing.samplerate",
+		Usage: "Tracing fraction of the requests which are traced (0-1)",
+		Value: DefaultConfig.SampleRate,
+	}
+)
+
+// Flags holds all command-line flags required for tracing
+var Flags = []cli.Flag{
+	tracingEnabledFlag,
+	tracingEndpointFlag,
+	tracingServiceFlag,
+	tracingSampleRateFlag,
+}
+
+// SetConfig applies the tracing flags set on the command line to cfg
+func SetConfig(ctx *cli.Context, cfg *Config) {
+	if ctx.GlobalIsSet(tracingEnabledFlag.Name) {
+		cfg.Enabled = ctx.GlobalBool(tracingEnabledFlag.Name)
+	}
+	if ctx.GlobalIsSet(tracingEndpointFlag.Name) {
+		cfg.Endpoint = ctx.GlobalString(tracingEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(tracingServiceFlag.Name) {
+		cfg.Service = ctx.GlobalString(tracingServiceFlag.Name)
+	}
+	if ctx.GlobalIsSet(tracingSampleRateFlag.Name) {
+		cfg.SampleRate = ctx.GlobalFloat64(tracingSampleRateFlag.Name)
+	}
+}
+
+// exporter is the exporter started by Setup
+var exporter *ZipkinExporter
+
+// Setup starts exporting the traces of the sampled requests if tracing is
+// enabled
+func Setup(cfg *Config) {
+	if !cfg.Enabled {
+		return
+	}
+	log.Info("Enabling swarm tracing", "endpoint", cfg.Endpoint, "samplerate", cfg.SampleRate)
+	exporter = NewZipkinExporter(cfg.Endpoint, cfg.Service)
+	SetTracer(NewTracer(RateSampler(cfg.SampleRate), exporter))
+}
+
+// Close stops the tracing started by Setup and exports the buffered spans
+func Close() {
+	if exporter == nil {
+		return
+	}
+	SetTracer(nil)
+	if err := exporter.Close(); err != nil {
+		log.Warn("Failed to export tracing spans", "err", err)
+	}
+	exporter = nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing records the spans of the operations of swarm requests, so
+// that the latency of a request can be broken down on a timeline.
+//
+// Spans are passed down the layers of a request in a context.Context. The
+// spans of a trace are recorded only if its root span is sampled, and are
+// passed to an exporter when they finish. Without a tracer, which is the
+// default, starting a span returns a nil span, on which all methods are
+// no-ops.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Span is a timed operation of a trace
+type Span struct {
+	TraceID  uint64
+	ID       uint64
+	ParentID uint64 // zero for the root span of a trace
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+
+	mu     sync.Mutex
+	tags   map[string]string
+	tracer *Tracer
+}
+
+// SetTag sets a tag of the span
+func (s *Span) SetTag(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tags == nil {
+		s.tags = make(map[string]string)
+	}
+	s.tags[key] = fmt.Sprint(value)
+}
+
+// SetError tags the span with err if it is not nil
+func (s *Span) SetError(err error) {
+	if err != nil {
+		s.SetTag("error", err)
+	}
+}
+
+// Tags returns a copy of the tags of the span
+func (s *Span) Tags() map[string]string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tags := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// Finish sets the duration of the span and exports it
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.Duration = time.Since(s.Start)
+	s.tracer.exporter.Export(s)
+}
+
+// Sampler decides whether the trace with the given ID is recorded
+type Sampler interface {
+	Sample(traceID uint64) bool
+}
+
+// ConstSampler records all traces if true and none if false
+type ConstSampler bool
+
+// Sample implements Sampler
+func (s ConstSampler) Sample(uint64) bool {
+	return bool(s)
+}
+
+// RateSampler records the given fraction of the traces
+type RateSampler float64
+
+// Sample implements Sampler
+func (s RateSampler) Sample(traceID uint64) bool {
+	// trace IDs are random, so their range is split by the rate
+	return float64(traceID) < float64(s)*(1<<64)
+}
+
+// Exporter receives the finished spans of the sampled traces
+type Exporter interface {
+	Export(span *Span)
+}
+
+// Tracer starts the spans of traces which are sampled by its sampler and
+// passes them to its exporter when they finish
+type Tracer struct {
+	sampler  Sampler
+	exporter Exporter
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewTracer creates a tracer
+func NewTracer(sampler Sampler, exporter Exporter) *Tracer {
+	return &Tracer{
+		sampler:  sampler,
+		exporter: exporter,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// newID returns a random non-zero ID
+func (t *Tracer) newID() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		if id := t.rand.Uint64(); id != 0 {
+			return id
+		}
+	}
+}
+
+// spanContext is the span of a context, or the trace of a root span which
+// is not sampled, so that the spans of the trace are not sampled either
+type spanContext struct {
+	span    *Span
+	traceID uint64
+}
+
+type spanKey struct{}
+
+// StartSpan starts a span which is a child of the span of ctx or the root
+// span of a new trace, and returns a context with the new span. The span is
+// nil if the trace is not sampled.
+func (t *Tracer) StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{
+		ID:     t.newID(),
+		Name:   name,
+		Start:  time.Now(),
+		tracer: t,
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*spanContext); ok {
+		if parent.span == nil {
+			return ctx, nil
+		}
+		span.TraceID = parent.span.TraceID
+		span.ParentID = parent.span.ID
+	} else {
+		span.TraceID = t.newID()
+		if !t.sampler.Sample(span.TraceID) {
+			return context.WithValue(ctx, spanKey{}, &spanContext{traceID: span.TraceID}), nil
+		}
+	}
+	return context.WithValue(ctx, spanKey{}, &spanContext{span: span, traceID: span.TraceID}), span
+}
+
+// SpanFromContext returns the span of ctx, nil if ctx has no span or its
+// trace is not sampled
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if sc, ok := ctx.Value(spanKey{}).(*spanContext); ok {
+		return sc.span
+	}
+	return nil
+}
+
+var (
+	tracerMu sync.RWMutex
+	tracer   *Tracer
+)
+
+// SetTracer sets the tracer of StartSpan, nil disables tracing
+func SetTracer(t *Tracer) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	tracer = t
+}
+
+// StartSpan starts a span with the tracer set by SetTracer, see
+// Tracer.StartSpan. It returns ctx and a nil span if tracing is disabled.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	tracerMu.RLock()
+	t := tracer
+	tracerMu.RUnlock()
+	if t == nil {
+		return ctx, nil
+	}
+	return t.StartSpan(ctx, name)
+}
+
+// Recorder is an Exporter keeping the finished spans in memory
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// Export implements Exporter
+func (r *Recorder) Export(span *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Spans returns the finished spans in the order they finished
+func (r *Recorder) Spans() []*Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Span{}, r.spans...)
+}
@@ -0,0 +1,134 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	recorder := &Recorder{}
+	tracer := NewTracer(ConstSampler(true), recorder)
+
+	ctx, root := tracer.StartSpan(context.Background(), "root")
+	childCtx, child := tracer.StartSpan(ctx, "child")
+	if SpanFromContext(childCtx) != child {
+		t.Fatal("expected the child span in its context")
+	}
+	child.SetTag("key", 1)
+	child.SetError(errors.New("failed"))
+	child.Finish()
+	root.Finish()
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0] != child || spans[1] != root {
+		t.Fatal("expected the spans in the order they finished")
+	}
+	if root.ParentID != 0 || child.ParentID != root.ID || child.TraceID != root.TraceID {
+		t.Fatalf("expected a child of the root span in its trace, got root %+v child %+v", root, child)
+	}
+	if tags := child.Tags(); tags["key"] != "1" || tags["error"] != "failed" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	recorder := &Recorder{}
+	tracer := NewTracer(ConstSampler(false), recorder)
+
+	ctx, root := tracer.StartSpan(context.Background(), "root")
+	if root != nil {
+		t.Fatal("expected no span for a trace which is not sampled")
+	}
+	// the children of a trace which is not sampled are not sampled either,
+	// and the methods of nil spans are no-ops
+	_, child := tracer.StartSpan(ctx, "child")
+	child.SetTag("key", "value")
+	child.Finish()
+	if child != nil || len(recorder.Spans()) != 0 {
+		t.Fatal("expected no spans in a trace which is not sampled")
+	}
+
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if _, sp := NewTracer(RateSampler(0.3), recorder).StartSpan(nil, "root"); sp != nil {
+			sampled++
+		}
+	}
+	if sampled < 200 || sampled > 400 {
+		t.Fatalf("expected about 300 of 1000 traces to be sampled, got %d", sampled)
+	}
+}
+
+func TestStartSpanDisabled(t *testing.T) {
+	ctx := context.Background()
+	if spanCtx, sp := StartSpan(ctx, "root"); sp != nil || spanCtx != ctx {
+		t.Fatal("expected no span without a tracer")
+	}
+
+	recorder := &Recorder{}
+	SetTracer(NewTracer(ConstSampler(true), recorder))
+	defer SetTracer(nil)
+	_, sp := StartSpan(ctx, "root")
+	sp.Finish()
+	if len(recorder.Spans()) != 1 {
+		t.Fatal("expected a span with the tracer")
+	}
+}
+
+func TestZipkinExporter(t *testing.T) {
+	var received []zipkinSpan
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var spans []zipkinSpan
+		if err := json.NewDecoder(r.Body).Decode(&spans); err != nil {
+			t.Error(err)
+		}
+		received = append(received, spans...)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	exporter := NewZipkinExporter(server.URL, "swarm-test")
+	tracer := NewTracer(ConstSampler(true), exporter)
+	ctx, root := tracer.StartSpan(context.Background(), "root")
+	_, child := tracer.StartSpan(ctx, "child")
+	child.SetTag("key", "value")
+	child.Finish()
+	root.Finish()
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(received))
+	}
+	sp := received[0]
+	if sp.Name != "child" || sp.ParentID != received[1].ID || sp.TraceID != received[1].TraceID || received[1].ParentID != "" {
+		t.Fatalf("unexpected spans %+v", received)
+	}
+	if sp.LocalEndpoint.ServiceName != "swarm-test" || sp.Tags["key"] != "value" || len(sp.TraceID) != 16 {
+		t.Fatalf("unexpected span %+v", sp)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// zipkinBatchSize is the number of spans which triggers a flush
+	zipkinBatchSize = 100
+	// zipkinFlushInterval is the maximum time a span is buffered
+	zipkinFlushInterval = time.Second
+	// zipkinMaxBuffered is the number of buffered spans above which new
+	// spans are dropped while the collector is unreachable
+	zipkinMaxBuffered = 10000
+)
+
+// ZipkinExporter posts spans in the Zipkin v2 JSON format to a collector,
+// such as Zipkin or the Zipkin compatible endpoint of a Jaeger collector
+// (e.g. http://localhost:9411/api/v2/spans). Spans are buffered and posted
+// in batches.
+type ZipkinExporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+
+	mu     sync.Mutex
+	spans  []*Span
+	flushC chan struct{}
+	quitC  chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewZipkinExporter creates an exporter posting the spans of service to
+// the collector at endpoint
+func NewZipkinExporter(endpoint, service string) *ZipkinExporter {
+	e := &ZipkinExporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		flushC:   make(chan struct{}, 1),
+		quitC:    make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.loop()
+	return e
+}
+
+// Export implements Exporter
+func (e *ZipkinExporter) Export(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.spans) >= zipkinMaxBuffered {
+		return
+	}
+	e.spans = append(e.spans, span)
+	if len(e.spans) >= zipkinBatchSize {
+		select {
+		case e.flushC <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close posts the buffered spans and stops the exporter
+func (e *ZipkinExporter) Close() error {
+	close(e.quitC)
+	e.wg.Wait()
+	return e.flush()
+}
+
+func (e *ZipkinExporter) loop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(zipkinFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flushC:
+		case <-e.quitC:
+			return
+		}
+		if err := e.flush(); err != nil {
+			log.Warn("Failed to export tracing spans", "endpoint", e.endpoint, "err", err)
+		}
+	}
+}
+
+// zipkinEndpoint is the service of a Zipkin span
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// zipkinSpan is a span in the Zipkin v2 JSON format, times are in
+// microseconds
+type zipkinSpan struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint zipkinEndpoint    `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+func (e *ZipkinExporter) flush() error {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	batch := make([]zipkinSpan, len(spans))
+	for i, s := range spans {
+		batch[i] = zipkinSpan{
+			TraceID:       fmt.Sprintf("%016x", s.TraceID),
+			ID:            fmt.Sprintf("%016x", s.ID),
+			Name:          s.Name,
+			Timestamp:     s.Start.UnixNano() / int64(time.Microsecond),
+			Duration:      int64(s.Duration / time.Microsecond),
+			LocalEndpoint: zipkinEndpoint{ServiceName: e.service},
+			Tags:          s.Tags(),
+		}
+		if s.ParentID != 0 {
+			batch[i].ParentID = fmt.Sprintf("%016x", s.ParentID)
+		}
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	return nil
+}
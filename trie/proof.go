@@ -151,3 +151,167 @@ func get(tn node, key []byte) ([]byte, node) {
 		}
 	}
 }
+
+// VerifyRangeProof checks whether the given consecutive leaves are all the
+// entries of the trie with the given root hash within the range starting at
+// firstKey and ending at the last of the keys. The proof must contain the nodes
+// on the paths to firstKey and to the last key, proving the edges of the range.
+// If the proof is nil, the leaves must be the entire content of the trie.
+//
+// The keys must be sorted in ascending order and the values must not be empty.
+// VerifyRangeProof returns whether the trie contains more entries after the
+// range, or an error if the proof contains invalid trie nodes or the leaves do
+// not match the trie.
+func VerifyRangeProof(rootHash common.Hash, firstKey []byte, keys [][]byte, values [][]byte, proofDb DatabaseReader) (bool, error) {
+	if len(keys) != len(values) {
+		return false, fmt.Errorf("inconsistent proof data, keys: %d, values: %d", len(keys), len(values))
+	}
+	for i, key := range keys {
+		if i > 0 && bytes.Compare(keys[i-1], key) >= 0 {
+			return false, fmt.Errorf("key %d (%x) not in ascending order", i, key)
+		}
+		if len(values[i]) == 0 {
+			return false, fmt.Errorf("empty value for key %x", key)
+		}
+	}
+	if len(keys) > 0 && bytes.Compare(keys[0], firstKey) < 0 {
+		return false, fmt.Errorf("key %x before range start %x", keys[0], firstKey)
+	}
+	tr := &Trie{db: NewDatabase(ethdb.NewMemDatabase())}
+
+	// Without a proof, the leaves must rebuild the entire trie
+	more := false
+	if proofDb != nil {
+		var right []byte
+		if len(keys) > 0 {
+			right = keybytesToHex(keys[len(keys)-1])
+		}
+		root, rest, err := unsetRange(hashNode(rootHash[:]), keybytesToHex(firstKey), right, proofDb)
+		if err != nil {
+			return false, err
+		}
+		tr.root, more = root, rest
+	}
+	for i, key := range keys {
+		if err := tr.TryUpdate(key, values[i]); err != nil {
+			return false, fmt.Errorf("key %x outside proven range: %v", key, err)
+		}
+	}
+	if hash := tr.Hash(); hash != rootHash {
+		return false, fmt.Errorf("root hash mismatch: have %x, want %x", hash, rootHash)
+	}
+	return more, nil
+}
+
+// unsetRange resolves the nodes on the paths to the left and right edges of a
+// range from the proof, and removes all the nodes between the two paths along
+// with the leaves at the edges. A nil edge stands for a range unbounded on that
+// side. The returned flag reports whether any node remains right of the range.
+func unsetRange(n node, left, right []byte, proofDb DatabaseReader) (node, bool, error) {
+	if left == nil && right == nil {
+		return nil, false, nil
+	}
+	switch n := n.(type) {
+	case nil, valueNode:
+		return nil, false, nil
+
+	case hashNode:
+		buf, _ := proofDb.Get(n)
+		if buf == nil {
+			return nil, false, fmt.Errorf("proof node (hash %064x) missing", []byte(n))
+		}
+		decoded, err := decodeNode(n, buf, 0)
+		if err != nil {
+			return nil, false, fmt.Errorf("bad proof node %x: %v", []byte(n), err)
+		}
+		return unsetRange(decoded, left, right, proofDb)
+
+	case *shortNode:
+		cmpLeft, cmpRight := 1, -1
+		if left != nil {
+			cmpLeft = comparePath(n.Key, left)
+		}
+		if right != nil {
+			cmpRight = comparePath(n.Key, right)
+		}
+		switch {
+		case cmpLeft < 0:
+			return n, false, nil
+		case cmpRight > 0:
+			return n, true, nil
+		case cmpLeft > 0 && cmpRight < 0:
+			return nil, false, nil
+		}
+		var childLeft, childRight []byte
+		if cmpLeft == 0 {
+			childLeft = left[len(n.Key):]
+		}
+		if cmpRight == 0 {
+			childRight = right[len(n.Key):]
+		}
+		child, more, err := unsetRange(n.Val, childLeft, childRight, proofDb)
+		if err != nil || child == nil {
+			return nil, more, err
+		}
+		n = n.copy()
+		n.Val, n.flags = child, nodeFlag{dirty: true}
+		return n, more, nil
+
+	case *fullNode:
+		if (left != nil && len(left) == 0) || (right != nil && len(right) == 0) {
+			return nil, false, fmt.Errorf("proof path ends in a branch node")
+		}
+		lo, hi := -1, len(n.Children)
+		if left != nil {
+			lo = int(left[0])
+		}
+		if right != nil {
+			hi = int(right[0])
+		}
+		n = n.copy()
+		n.flags = nodeFlag{dirty: true}
+
+		more := false
+		for i, child := range n.Children {
+			switch {
+			case i < lo:
+			case i > hi:
+				more = more || child != nil
+			case i == lo || i == hi:
+				var childLeft, childRight []byte
+				if i == lo {
+					childLeft = left[1:]
+				}
+				if i == hi {
+					childRight = right[1:]
+				}
+				var (
+					rest bool
+					err  error
+				)
+				if n.Children[i], rest, err = unsetRange(child, childLeft, childRight, proofDb); err != nil {
+					return nil, false, err
+				}
+				more = more || rest
+			default:
+				n.Children[i] = nil
+			}
+		}
+		return n, more, nil
+
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
+
+// comparePath compares the key of a short node with the corresponding part of
+// a path. A key extending beyond the path lies right of it.
+func comparePath(key, path []byte) int {
+	if len(path) < len(key) {
+		if cmp := bytes.Compare(key[:len(path)], path); cmp != 0 {
+			return cmp
+		}
+		return 1
+	}
+	return bytes.Compare(key, path[:len(key)])
+}
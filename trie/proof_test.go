@@ -20,6 +20,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -143,6 +144,129 @@ func TestMissingKeyProof(t *testing.T) {
 	}
 }
 
+// sortedEntries returns the entries of a random trie sorted by key.
+func sortedEntries(vals map[string]*kv) []*kv {
+	var entries []*kv
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].k, entries[j].k) < 0 })
+	return entries
+}
+
+// rangeData splits a range of entries into its keys and values.
+func rangeData(entries []*kv) (keys [][]byte, values [][]byte) {
+	for _, kv := range entries {
+		keys = append(keys, kv.k)
+		values = append(values, kv.v)
+	}
+	return keys, values
+}
+
+// proveRange creates a merkle proof for the edges of a range.
+func proveRange(trie *Trie, first, last []byte) *ethdb.MemDatabase {
+	proof := ethdb.NewMemDatabase()
+	trie.Prove(first, 0, proof)
+	if last != nil {
+		trie.Prove(last, 0, proof)
+	}
+	return proof
+}
+
+// Tests that random ranges of a trie can be proven, both starting at existing
+// and non-existing keys, and that the proofs report remaining entries.
+func TestRangeProof(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries))
+		end := start + mrand.Intn(len(entries)-start) + 1
+		keys, values := rangeData(entries[start:end])
+
+		first := keys[0]
+		if i%2 == 1 && first[len(first)-1] > 0 {
+			// Start the range before its first key, but after the previous one
+			first = common.CopyBytes(first)
+			first[len(first)-1]--
+			if start > 0 && bytes.Compare(first, entries[start-1].k) <= 0 {
+				first = keys[0]
+			}
+		}
+		more, err := VerifyRangeProof(root, first, keys, values, proveRange(trie, first, keys[len(keys)-1]))
+		if err != nil {
+			t.Fatalf("range %d-%d: failed to verify proof: %v", start, end, err)
+		}
+		if want := end < len(entries); more != want {
+			t.Fatalf("range %d-%d: remaining entries mismatch: have %v, want %v", start, end, more, want)
+		}
+	}
+}
+
+// Tests that the entire trie can be proven without edge proofs, and that an
+// empty range past the last entry can be proven with an edge proof.
+func TestRangeProofEdges(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	keys, values := rangeData(entries)
+	if more, err := VerifyRangeProof(root, nil, keys, values, nil); err != nil || more {
+		t.Fatalf("failed to verify entire trie: more %v, err %v", more, err)
+	}
+	if _, err := VerifyRangeProof(root, nil, keys[1:], values[1:], nil); err == nil {
+		t.Fatalf("verified incomplete trie without proof")
+	}
+	origin := bytes.Repeat([]byte{0xff}, 32)
+	if more, err := VerifyRangeProof(root, origin, nil, nil, proveRange(trie, origin, nil)); err != nil || more {
+		t.Fatalf("failed to verify empty range: more %v, err %v", more, err)
+	}
+	origin = entries[len(entries)-1].k
+	if _, err := VerifyRangeProof(root, origin, nil, nil, proveRange(trie, origin, nil)); err == nil {
+		t.Fatalf("verified empty range hiding the last entry")
+	}
+}
+
+// Tests that ranges with missing, modified or additional entries are rejected.
+func TestBadRangeProof(t *testing.T) {
+	trie, vals := randomTrie(1024)
+	root := trie.Hash()
+	entries := sortedEntries(vals)
+
+	for i := 0; i < 200; i++ {
+		start := mrand.Intn(len(entries) - 2)
+		end := start + mrand.Intn(len(entries)-start-2) + 3
+		keys, values := rangeData(entries[start:end])
+		first, last := keys[0], keys[len(keys)-1]
+
+		index := mrand.Intn(len(keys))
+		switch i % 3 {
+		case 0:
+			// Modify a value
+			values[index] = common.CopyBytes(values[index])
+			mutateByte(values[index])
+		case 1:
+			// Remove an entry from the middle of the range
+			index = mrand.Intn(len(keys)-2) + 1
+			keys = append(keys[:index:index], keys[index+1:]...)
+			values = append(values[:index:index], values[index+1:]...)
+		case 2:
+			// Add an entry within the range
+			key := common.CopyBytes(keys[1])
+			key[len(key)-1]--
+			if bytes.Compare(key, keys[0]) <= 0 || bytes.Compare(key, keys[1]) >= 0 {
+				continue
+			}
+			keys = append([][]byte{keys[0], key}, keys[1:]...)
+			values = append([][]byte{values[0], randBytes(20)}, values[1:]...)
+		}
+		if _, err := VerifyRangeProof(root, first, keys, values, proveRange(trie, first, last)); err == nil {
+			t.Fatalf("range %d-%d, case %d: expected proof to fail", start, end, i%3)
+		}
+	}
+}
+
 // mutateByte changes one byte in b.
 func mutateByte(b []byte) {
 	for r := mrand.Intn(len(b)); ; {
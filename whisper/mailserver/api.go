@@ -0,0 +1,159 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package mailserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
+)
+
+// maxQueryResults is the maximum number of envelopes returned by a single query.
+const maxQueryResults = 1000
+
+// errInvalidRange is returned if the time range of a query is empty.
+var errInvalidRange = errors.New("invalid time range")
+
+// PublicMailServerAPI provides the RPC service to query the envelopes archived
+// by the mail server, allowing clients which were offline to fetch the messages
+// they missed.
+type PublicMailServerAPI struct {
+	s *WMailServer
+}
+
+// NewPublicMailServerAPI creates a new RPC service to query the mail archive.
+func NewPublicMailServerAPI(s *WMailServer) *PublicMailServerAPI {
+	return &PublicMailServerAPI{s: s}
+}
+
+// Query selects archived envelopes by topic and the time they were sent at.
+type Query struct {
+	Topics []whisper.TopicType `json:"topics"` // Topics to match, all of them if empty
+	From   uint32              `json:"from"`   // Lowest send time to match (inclusive)
+	To     uint32              `json:"to"`     // Highest send time to match (exclusive), unbounded if zero
+	Limit  int                 `json:"limit"`  // Maximum number of envelopes to return, capped by the server
+}
+
+// ArchivedEnvelope is an envelope as stored in the mail archive.
+type ArchivedEnvelope struct {
+	Hash      common.Hash       `json:"hash"`
+	Timestamp uint32            `json:"timestamp"`
+	Expiry    uint32            `json:"expiry"`
+	TTL       uint32            `json:"ttl"`
+	Topic     whisper.TopicType `json:"topic"`
+	Data      hexutil.Bytes     `json:"data"`
+	Nonce     uint64            `json:"nonce"`
+}
+
+// Envelopes returns the archived envelopes matching the query, in the order they
+// were sent. The envelopes are returned as is, it's up to the client to decrypt
+// the ones addressed to it.
+func (api *PublicMailServerAPI) Envelopes(query Query) ([]*ArchivedEnvelope, error) {
+	to := query.To
+	if to == 0 {
+		to = math.MaxUint32
+	}
+	if query.From >= to {
+		return nil, errInvalidRange
+	}
+	limit := query.Limit
+	if limit <= 0 || limit > maxQueryResults {
+		limit = maxQueryResults
+	}
+	envelopes, err := api.s.query(query.Topics, query.From, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*ArchivedEnvelope, len(envelopes))
+	for i, env := range envelopes {
+		results[i] = &ArchivedEnvelope{
+			Hash:      env.Hash(),
+			Timestamp: env.Expiry - env.TTL,
+			Expiry:    env.Expiry,
+			TTL:       env.TTL,
+			Topic:     env.Topic,
+			Data:      env.Data,
+			Nonce:     env.Nonce,
+		}
+	}
+	return results, nil
+}
+
+// query retrieves at most limit archived envelopes with the given topics (or
+// any topic if none is given), sent within [lower, upper).
+func (s *WMailServer) query(topics []whisper.TopicType, lower, upper uint32, limit int) ([]*whisper.Envelope, error) {
+	var envelopes []*whisper.Envelope
+	if len(topics) == 0 {
+		s.iterate(lower, upper, func(key []byte, envelope *whisper.Envelope) bool {
+			envelopes = append(envelopes, envelope)
+			return len(envelopes) < limit
+		})
+		return envelopes, nil
+	}
+	// Gather the matching envelope keys of every topic from the index, ordering
+	// them by send time across topics
+	var keys [][]byte
+	for _, topic := range topics {
+		var (
+			prefix = append(append([]byte{}, topicIndexPrefix...), topic[:]...)
+			start  = make([]byte, len(prefix)+4)
+			end    = make([]byte, len(prefix)+4)
+			found  int
+		)
+		copy(start, prefix)
+		copy(end, prefix)
+		binary.BigEndian.PutUint32(start[len(prefix):], lower)
+		binary.BigEndian.PutUint32(end[len(prefix):], upper)
+
+		it := s.db.(iteratee).NewIteratorWithPrefix(prefix)
+		for ok := it.Seek(start); ok && bytes.Compare(it.Key(), end) < 0 && found < limit; ok = it.Next() {
+			keys = append(keys, common.CopyBytes(it.Key()[len(prefix):]))
+			found++
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	for i, key := range keys {
+		// Skip duplicates, and envelopes pruned meanwhile
+		if i > 0 && bytes.Equal(key, keys[i-1]) {
+			continue
+		}
+		blob, err := s.db.Get(key)
+		if err != nil {
+			continue
+		}
+		envelope := new(whisper.Envelope)
+		if err := rlp.DecodeBytes(blob, envelope); err != nil {
+			return nil, err
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes, nil
+}
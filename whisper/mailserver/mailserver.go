@@ -1,39 +1,62 @@
-// Copyright 2017 The go-ethereum Authors
-// This file is part of the go-ethereum library.
-//
-// The go-ethereum library is free software: you can redistribute it and/or modify
-// it under the terms of the GNU Lesser General Public License as published by
-// the Free Software Foundation, either version 3 of the License, or
-// (at your option) any later version.
-//
-// The go-ethereum library is distributed in the hope that it will be useful,
-// but WITHOUT ANY WARRANTY; without even the implied warranty of
-// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
-// GNU Lesser General Public License for more details.
-//
-// You should have received a copy of the GNU Lesser General Public License
-// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
-
 package mailserver
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
+// pruneInterval is the period of deleting the envelopes beyond the retention.
+const pruneInterval = time.Hour
+
+// topicIndexPrefix + topic + timestamp + hash -> nil, indexing the archived
+// envelopes by their topic. The keys are longer than the envelope keys, which
+// is how the two are told apart when iterating over time ranges.
+var topicIndexPrefix = []byte("topic-")
+
+// errNotIterable is returned if the archive database cannot be iterated.
+var errNotIterable = errors.New("database not iterable")
+
+// iteratee is implemented by the databases whose content can be iterated over.
+type iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Config contains the settings of a mail server running as a node service.
+type Config struct {
+	Password  string        // Password to derive the symmetric key of the mail requests from
+	MinPoW    float64       // Minimum proof of work required from the mail requests
+	Retention time.Duration // Duration to keep the archived envelopes for (0 = forever)
+}
+
+// DefaultConfig contains the default settings of a mail server service.
+var DefaultConfig = Config{
+	Retention: 30 * 24 * time.Hour,
+}
+
 type WMailServer struct {
-	db  *leveldb.DB
+	db  ethdb.Database
 	w   *whisper.Whisper
 	pow float64
 	key []byte
+
+	retention time.Duration // Duration to keep the archived envelopes for (0 = forever)
+	quit      chan struct{}
+	wg        sync.WaitGroup
 }
 
 type DBKey struct {
@@ -54,20 +77,50 @@ func NewDbKey(t uint32, h common.Hash) *DBKey {
 }
 
 func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, pow float64) error {
-	var err error
 	if len(path) == 0 {
 		return fmt.Errorf("DB file is not specified")
 	}
-
-	if len(password) == 0 {
-		return fmt.Errorf("password is not specified")
+	db, err := ethdb.NewLDBDatabase(path, 0, 0)
+	if err != nil {
+		return fmt.Errorf("open DB file: %s", err)
+	}
+	if err := s.init(shh, db, password, pow); err != nil {
+		db.Close()
+		return err
 	}
+	return nil
+}
 
-	s.db, err = leveldb.OpenFile(path, nil)
+// NewService creates a mail server archiving the envelopes of the node's whisper
+// service into the node database, and serving them to mail requests as well as
+// through the mailserver RPC API.
+func NewService(ctx *node.ServiceContext, config *Config) (*WMailServer, error) {
+	var shh *whisper.Whisper
+	if err := ctx.Service(&shh); err != nil {
+		return nil, fmt.Errorf("whisper service not running: %v", err)
+	}
+	db, err := ctx.OpenDatabase("mailserver", 0, 0)
 	if err != nil {
-		return fmt.Errorf("open DB file: %s", err)
+		return nil, err
+	}
+	s := &WMailServer{retention: config.Retention}
+	if err := s.init(shh, db, config.Password, config.MinPoW); err != nil {
+		db.Close()
+		return nil, err
 	}
+	shh.RegisterServer(s)
+	return s, nil
+}
 
+// init sets up the mail server on top of the given archive database.
+func (s *WMailServer) init(shh *whisper.Whisper, db ethdb.Database, password string, pow float64) error {
+	if len(password) == 0 {
+		return fmt.Errorf("password is not specified")
+	}
+	if _, ok := db.(iteratee); !ok {
+		return errNotIterable
+	}
+	s.db = db
 	s.w = shh
 	s.pow = pow
 
@@ -82,6 +135,45 @@ func (s *WMailServer) Init(shh *whisper.Whisper, path string, password string, p
 	return nil
 }
 
+// Protocols implements node.Service, the mail server runs over the whisper
+// protocol.
+func (s *WMailServer) Protocols() []p2p.Protocol {
+	return nil
+}
+
+// APIs implements node.Service, returning the archive query API.
+func (s *WMailServer) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "mailserver",
+			Version:   "1.0",
+			Service:   NewPublicMailServerAPI(s),
+			Public:    true,
+		},
+	}
+}
+
+// Start implements node.Service, starting the pruning of the envelopes beyond
+// the retention period.
+func (s *WMailServer) Start(server *p2p.Server) error {
+	s.quit = make(chan struct{})
+	if s.retention > 0 {
+		s.wg.Add(1)
+		go s.pruneLoop()
+	}
+	log.Info("Started whisper mail server", "retention", common.PrettyDuration(s.retention))
+	return nil
+}
+
+// Stop implements node.Service, terminating the pruning and closing the archive.
+func (s *WMailServer) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+	s.Close()
+	log.Info("Whisper mail server stopped")
+	return nil
+}
+
 func (s *WMailServer) Close() {
 	if s.db != nil {
 		s.db.Close()
@@ -93,12 +185,19 @@ func (s *WMailServer) Archive(env *whisper.Envelope) {
 	rawEnvelope, err := rlp.EncodeToBytes(env)
 	if err != nil {
 		log.Error(fmt.Sprintf("rlp.EncodeToBytes failed: %s", err))
-	} else {
-		err = s.db.Put(key.raw, rawEnvelope, nil)
-		if err != nil {
-			log.Error(fmt.Sprintf("Writing to DB failed: %s", err))
-		}
+		return
 	}
+	batch := s.db.NewBatch()
+	batch.Put(key.raw, rawEnvelope)
+	batch.Put(topicIndexKey(env.Topic, key.raw), nil)
+	if err := batch.Write(); err != nil {
+		log.Error(fmt.Sprintf("Writing to DB failed: %s", err))
+	}
+}
+
+// topicIndexKey = topicIndexPrefix + topic + timestamp + hash
+func topicIndexKey(topic whisper.TopicType, key []byte) []byte {
+	return append(append(append([]byte{}, topicIndexPrefix...), topic[:]...), key...)
 }
 
 func (s *WMailServer) DeliverMail(peer *whisper.Peer, request *whisper.Envelope) {
@@ -115,40 +214,90 @@ func (s *WMailServer) DeliverMail(peer *whisper.Peer, request *whisper.Envelope)
 
 func (s *WMailServer) processRequest(peer *whisper.Peer, lower, upper uint32, bloom []byte) []*whisper.Envelope {
 	ret := make([]*whisper.Envelope, 0)
-	var err error
-	var zero common.Hash
-	kl := NewDbKey(lower, zero)
-	ku := NewDbKey(upper, zero)
-	i := s.db.NewIterator(&util.Range{Start: kl.raw, Limit: ku.raw}, nil)
-	defer i.Release()
-
-	for i.Next() {
-		var envelope whisper.Envelope
-		err = rlp.DecodeBytes(i.Value(), &envelope)
-		if err != nil {
-			log.Error(fmt.Sprintf("RLP decoding failed: %s", err))
-		}
-
+	s.iterate(lower, upper, func(key []byte, envelope *whisper.Envelope) bool {
 		if whisper.BloomFilterMatch(bloom, envelope.Bloom()) {
 			if peer == nil {
 				// used for test purposes
-				ret = append(ret, &envelope)
+				ret = append(ret, envelope)
 			} else {
-				err = s.w.SendP2PDirect(peer, &envelope)
-				if err != nil {
+				if err := s.w.SendP2PDirect(peer, envelope); err != nil {
 					log.Error(fmt.Sprintf("Failed to send direct message to peer: %s", err))
-					return nil
+					ret = nil
+					return false
 				}
 			}
 		}
-	}
+		return true
+	})
+	return ret
+}
 
-	err = i.Error()
-	if err != nil {
+// iterate calls fn with the archived envelopes sent within [lower, upper), in
+// ascending timestamp order, until it returns false.
+func (s *WMailServer) iterate(lower, upper uint32, fn func(key []byte, envelope *whisper.Envelope) bool) {
+	var zero common.Hash
+	kl := NewDbKey(lower, zero)
+	ku := NewDbKey(upper, zero)
+
+	it := s.db.(iteratee).NewIteratorWithPrefix(nil)
+	defer it.Release()
+
+	for ok := it.Seek(kl.raw); ok && bytes.Compare(it.Key(), ku.raw) < 0; ok = it.Next() {
+		if len(it.Key()) != len(kl.raw) {
+			continue // topic index entry
+		}
+		var envelope whisper.Envelope
+		if err := rlp.DecodeBytes(it.Value(), &envelope); err != nil {
+			log.Error(fmt.Sprintf("RLP decoding failed: %s", err))
+			continue
+		}
+		if !fn(common.CopyBytes(it.Key()), &envelope) {
+			break
+		}
+	}
+	if err := it.Error(); err != nil {
 		log.Error(fmt.Sprintf("Level DB iterator error: %s", err))
 	}
+}
+
+// pruneLoop periodically deletes the archived envelopes which were sent before
+// the retention period.
+func (s *WMailServer) pruneLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		s.prune(uint32(time.Now().Add(-s.retention).Unix()))
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
 
-	return ret
+// prune deletes the archived envelopes sent before the given time, along with
+// their topic index entries.
+func (s *WMailServer) prune(before uint32) int {
+	var keys [][]byte
+	var topics []whisper.TopicType
+	s.iterate(0, before, func(key []byte, envelope *whisper.Envelope) bool {
+		keys, topics = append(keys, key), append(topics, envelope.Topic)
+		return true
+	})
+	for i, key := range keys {
+		if err := s.db.Delete(key); err != nil {
+			log.Error("Failed to delete archived envelope", "err", err)
+			return i
+		}
+		s.db.Delete(topicIndexKey(topics[i], key))
+	}
+	if len(keys) > 0 {
+		log.Debug("Pruned archived envelopes", "count", len(keys))
+	}
+	return len(keys)
 }
 
 func (s *WMailServer) validateRequest(peerID []byte, request *whisper.Envelope) (bool, uint32, uint32, []byte) {
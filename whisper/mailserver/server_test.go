@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
 )
 
@@ -210,3 +211,66 @@ func createRequest(t *testing.T, p *ServerTestParams) *whisper.Envelope {
 	}
 	return env
 }
+
+// Tests that archived envelopes can be queried by topic and time range, and that
+// the envelopes beyond the retention are pruned along with their index entries.
+func TestMailServerQuery(t *testing.T) {
+	var server WMailServer
+	if err := server.init(whisper.New(&whisper.DefaultConfig), ethdb.NewMemDatabase(), "password_for_this_test", powRequirement); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	topics := []whisper.TopicType{{0x01}, {0x02}}
+	for i := 0; i < 10; i++ {
+		server.Archive(&whisper.Envelope{
+			Expiry: uint32(1000 + i*10 + 50),
+			TTL:    50,
+			Topic:  topics[i%2],
+			Data:   []byte{byte(i)},
+		})
+	}
+	api := NewPublicMailServerAPI(&server)
+
+	tests := []struct {
+		query Query
+		want  []byte // Data of the expected envelopes, in order
+	}{
+		{Query{}, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{Query{From: 1020, To: 1060}, []byte{2, 3, 4, 5}},
+		{Query{Topics: topics[:1]}, []byte{0, 2, 4, 6, 8}},
+		{Query{Topics: topics[1:], From: 1030}, []byte{3, 5, 7, 9}},
+		{Query{Topics: topics, From: 1010, To: 1050}, []byte{1, 2, 3, 4}},
+		{Query{Topics: topics, Limit: 3}, []byte{0, 1, 2}},
+		{Query{Topics: []whisper.TopicType{{0x03}}}, nil},
+	}
+	for i, tt := range tests {
+		envelopes, err := api.Envelopes(tt.query)
+		if err != nil {
+			t.Fatalf("test %d: query failed: %v", i, err)
+		}
+		var have []byte
+		for _, env := range envelopes {
+			have = append(have, env.Data[0])
+		}
+		if !bytes.Equal(have, tt.want) {
+			t.Errorf("test %d: envelopes mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if _, err := api.Envelopes(Query{From: 1050, To: 1050}); err == nil {
+		t.Errorf("empty range accepted")
+	}
+	// Prune the older half and check that neither query returns them anymore
+	if pruned := server.prune(1050); pruned != 5 {
+		t.Fatalf("pruned envelope count mismatch: have %d, want 5", pruned)
+	}
+	if envelopes, _ := api.Envelopes(Query{}); len(envelopes) != 5 {
+		t.Errorf("envelope count after pruning mismatch: have %d, want 5", len(envelopes))
+	}
+	if envelopes, _ := api.Envelopes(Query{Topics: topics}); len(envelopes) != 5 {
+		t.Errorf("indexed envelope count after pruning mismatch: have %d, want 5", len(envelopes))
+	}
+	if keys := len(server.db.(*ethdb.MemDatabase).Keys()); keys != 10 {
+		t.Errorf("database entry count after pruning mismatch: have %d, want 10", keys)
+	}
+}